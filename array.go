@@ -13,16 +13,15 @@ import (
 	"fmt"
 	"io"
 	"math"
-	"reflect"
 )
 
-func makeArrayCodec(st map[string]*Codec, enclosingNamespace string, schemaMap map[string]interface{}) (*Codec, error) {
+func makeArrayCodec(st map[string]*Codec, enclosingNamespace string, schemaMap map[string]interface{}, option *CodecOption) (*Codec, error) {
 	// array type must have items
 	itemSchema, ok := schemaMap["items"]
 	if !ok {
 		return nil, fmt.Errorf("Array ought to have items key")
 	}
-	itemCodec, err := buildCodec(st, enclosingNamespace, itemSchema)
+	itemCodec, err := buildCodec(st, enclosingNamespace, itemSchema, option)
 	if err != nil {
 		return nil, fmt.Errorf("Array items ought to be valid Avro type: %s", err)
 	}
@@ -35,44 +34,48 @@ func makeArrayCodec(st map[string]*Codec, enclosingNamespace string, schemaMap m
 
 			// block count and block size
 			if value, buf, err = longNativeFromBinary(buf); err != nil {
-				return nil, nil, fmt.Errorf("cannot decode binary array block count: %s", err)
+				return nil, nil, decodeError(option, err, "cannot decode binary array block count: %s", err)
 			}
 			blockCount := value.(int64)
+			blockSize := int64(-1)
 			if blockCount < 0 {
 				// NOTE: A negative block count implies there is a long encoded
-				// block size following the negative block count. We have no use
-				// for the block size in this decoder, so we read and discard
-				// the value.
+				// block size following the negative block count. The size of
+				// the first block is used to find the number of items in every
+				// block, so the array may be allocated once.
 				if blockCount == math.MinInt64 {
 					// The minimum number for any signed numerical type can never be made positive
-					return nil, nil, fmt.Errorf("cannot decode binary array with block count: %d", blockCount)
+					return nil, nil, decodeError(option, ErrInvalidBinary, "cannot decode binary array with block count: %d", blockCount)
 				}
 				blockCount = -blockCount // convert to its positive equivalent
-				if _, buf, err = longNativeFromBinary(buf); err != nil {
-					return nil, nil, fmt.Errorf("cannot decode binary array block size: %s", err)
+				if value, buf, err = longNativeFromBinary(buf); err != nil {
+					return nil, nil, decodeError(option, err, "cannot decode binary array block size: %s", err)
 				}
+				blockSize = value.(int64)
 			}
 			// Ensure block count does not exceed some sane value.
 			if blockCount > MaxBlockCount {
-				return nil, nil, fmt.Errorf("cannot decode binary array when block count exceeds MaxBlockCount: %d > %d", blockCount, MaxBlockCount)
+				return nil, nil, decodeError(option, ErrInvalidBinary, "cannot decode binary array when block count exceeds MaxBlockCount: %d > %d", blockCount, MaxBlockCount)
 			}
 			// NOTE: While the attempt of a RAM optimization shown below is not
 			// necessary, many encoders will encode all items in a single block.
 			// We can optimize amount of RAM allocated by runtime for the array
-			// by initializing the array for that number of items.
-			arrayValues := make([]interface{}, 0, blockCount)
+			// by initializing the array for that number of items. When the
+			// blocks have sizes, the number of items in all blocks is known.
+			itemCount := blockCountHint(blockCount, blockSize, buf)
+			arrayValues := make([]interface{}, 0, itemCount)
 
 			for blockCount != 0 {
 				// Decode `blockCount` datum values from buffer
 				for i := int64(0); i < blockCount; i++ {
 					if value, buf, err = itemCodec.nativeFromBinary(buf); err != nil {
-						return nil, nil, fmt.Errorf("cannot decode binary array item %d: %s", i+1, err)
+						return nil, nil, decodeError(option, err, "cannot decode binary array item %d: %s", i+1, err)
 					}
 					arrayValues = append(arrayValues, value)
 				}
 				// Decode next blockCount from buffer, because there may be more blocks
 				if value, buf, err = longNativeFromBinary(buf); err != nil {
-					return nil, nil, fmt.Errorf("cannot decode binary array block count: %s", err)
+					return nil, nil, decodeError(option, err, "cannot decode binary array block count: %s", err)
 				}
 				blockCount = value.(int64)
 				if blockCount < 0 {
@@ -83,16 +86,16 @@ func makeArrayCodec(st map[string]*Codec, enclosingNamespace string, schemaMap m
 					if blockCount == math.MinInt64 {
 						// The minimum number for any signed numerical type can
 						// never be made positive
-						return nil, nil, fmt.Errorf("cannot decode binary array with block count: %d", blockCount)
+						return nil, nil, decodeError(option, ErrInvalidBinary, "cannot decode binary array with block count: %d", blockCount)
 					}
 					blockCount = -blockCount // convert to its positive equivalent
 					if _, buf, err = longNativeFromBinary(buf); err != nil {
-						return nil, nil, fmt.Errorf("cannot decode binary array block size: %s", err)
+						return nil, nil, decodeError(option, err, "cannot decode binary array block size: %s", err)
 					}
 				}
 				// Ensure block count does not exceed some sane value.
 				if blockCount > MaxBlockCount {
-					return nil, nil, fmt.Errorf("cannot decode binary array when block count exceeds MaxBlockCount: %d > %d", blockCount, MaxBlockCount)
+					return nil, nil, decodeError(option, ErrInvalidBinary, "cannot decode binary array when block count exceeds MaxBlockCount: %d > %d", blockCount, MaxBlockCount)
 				}
 			}
 			return arrayValues, buf, nil
@@ -103,28 +106,19 @@ func makeArrayCodec(st map[string]*Codec, enclosingNamespace string, schemaMap m
 				return nil, fmt.Errorf("cannot encode binary array: %s", err)
 			}
 
-			arrayLength := int64(len(arrayValues))
-			var alreadyEncoded, remainingInBlock int64
+			blocks := newBlockEncoder(option, len(arrayValues))
 
 			for i, item := range arrayValues {
-				if remainingInBlock == 0 { // start a new block
-					remainingInBlock = arrayLength - alreadyEncoded
-					if remainingInBlock > MaxBlockCount {
-						// limit block count to MacBlockCount
-						remainingInBlock = MaxBlockCount
-					}
-					buf, _ = longBinaryFromNative(buf, remainingInBlock)
-				}
+				buf = blocks.beginItem(buf)
 
 				if buf, err = itemCodec.binaryFromNative(buf, item); err != nil {
 					return nil, fmt.Errorf("cannot encode binary array item %d: %v: %s", i+1, item, err)
 				}
 
-				remainingInBlock--
-				alreadyEncoded++
+				buf = blocks.endItem(buf)
 			}
 
-			return longBinaryFromNative(buf, 0) // append trailing 0 block count to signal end of Array
+			return blocks.finish(buf) // append trailing 0 block count to signal end of Array
 		},
 		nativeFromTextual: func(buf []byte) (interface{}, []byte, error) {
 			var arrayValues []interface{}
@@ -199,28 +193,3 @@ func makeArrayCodec(st map[string]*Codec, enclosingNamespace string, schemaMap m
 		},
 	}, nil
 }
-
-// convertArray converts interface{} to []interface{} if possible.
-func convertArray(datum interface{}) ([]interface{}, error) {
-	arrayValues, ok := datum.([]interface{})
-	if ok {
-		return arrayValues, nil
-	}
-	// NOTE: When given a slice of any other type, zip values to
-	// items as a convenience to client.
-	v := reflect.ValueOf(datum)
-	if v.Kind() != reflect.Slice {
-		return nil, fmt.Errorf("cannot create []interface{}: expected slice; received: %T", datum)
-	}
-	// NOTE: Two better alternatives to the current algorithm are:
-	//   (1) mutate the reflection tuple underneath to convert the
-	//       []int, for example, to []interface{}, with O(1) complexity
-	//   (2) use copy builtin to zip the data items over with O(n) complexity,
-	//       but more efficient than what's below.
-	// Suggestions?
-	arrayValues = make([]interface{}, v.Len())
-	for idx := 0; idx < v.Len(); idx++ {
-		arrayValues[idx] = v.Index(idx).Interface()
-	}
-	return arrayValues, nil
-}
@@ -0,0 +1,145 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+// Package bench provides representative Avro schemas and datasets, and a
+// harness to measure goavro operations on them, so the available codec
+// configurations may be compared on the hardware where they will be used.
+//
+// Each Dataset is a schema with deterministically generated data, and each
+// Operation measures one way of processing that data, such as encoding to
+// binary, or writing an Object Container File with a particular compression
+// algorithm. Operations are plain values, so configurations not provided by
+// this package may be measured alongside the built-in ones.
+//
+//     results, err := bench.Run(bench.Datasets(1000), bench.Operations())
+//     if err != nil {
+//         log.Fatal(err)
+//     }
+//     bench.WriteReport(os.Stdout, results)
+//
+// The same datasets and operations may be used from ordinary Go benchmarks:
+//
+//     func BenchmarkDecode(b *testing.B) {
+//         for _, ds := range bench.Datasets(100) {
+//             b.Run(ds.Name, func(b *testing.B) {
+//                 bench.Benchmark(b, ds, bench.BinaryDecode)
+//             })
+//         }
+//     }
+package bench
+
+import (
+	"fmt"
+	"io"
+	"testing"
+	"text/tabwriter"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// Dataset is an Avro schema and data that conforms to it, in the native form
+// accepted by goavro.Codec.
+type Dataset struct {
+	Name   string
+	Schema string
+	Data   []interface{}
+}
+
+// Operation is one way of processing the data of a Dataset.
+type Operation struct {
+	Name string
+
+	// Prepare performs any work that ought not to be measured, such as
+	// encoding the data an operation decodes. It returns a function that
+	// processes all data of the dataset once, and the number of encoded bytes
+	// that function processes, which is used to report throughput.
+	Prepare func(codec *goavro.Codec, data []interface{}) (run func() error, bytes int64, err error)
+}
+
+// Result is the measurement of one Operation on one Dataset.
+type Result struct {
+	Dataset   string
+	Operation string
+	Items     int // number of data items processed by each iteration
+	testing.BenchmarkResult
+}
+
+// NsPerItem returns the average time taken to process each data item.
+func (r Result) NsPerItem() float64 {
+	if r.N == 0 || r.Items == 0 {
+		return 0
+	}
+	return float64(r.T.Nanoseconds()) / float64(r.N) / float64(r.Items)
+}
+
+// Benchmark measures the operation on the dataset, as part of a Go benchmark.
+func Benchmark(b *testing.B, ds Dataset, op Operation) {
+	codec, err := goavro.NewCodec(ds.Schema)
+	if err != nil {
+		b.Fatal(err)
+	}
+	run, size, err := op.Prepare(codec, ds.Data)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(size)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err = run(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Run measures every operation on every dataset, using testing.Benchmark to
+// choose the number of iterations. It returns an error when a dataset schema
+// is invalid, or when an operation fails.
+func Run(datasets []Dataset, operations []Operation) ([]Result, error) {
+	var results []Result
+	for _, ds := range datasets {
+		codec, err := goavro.NewCodec(ds.Schema)
+		if err != nil {
+			return nil, fmt.Errorf("cannot benchmark dataset %q: %s", ds.Name, err)
+		}
+		for _, op := range operations {
+			run, size, err := op.Prepare(codec, ds.Data)
+			if err != nil {
+				return nil, fmt.Errorf("cannot benchmark %q on dataset %q: %s", op.Name, ds.Name, err)
+			}
+			var runErr error
+			result := testing.Benchmark(func(b *testing.B) {
+				b.SetBytes(size)
+				b.ReportAllocs()
+				for i := 0; i < b.N && runErr == nil; i++ {
+					runErr = run()
+				}
+			})
+			if runErr != nil {
+				return nil, fmt.Errorf("cannot benchmark %q on dataset %q: %s", op.Name, ds.Name, runErr)
+			}
+			results = append(results, Result{Dataset: ds.Name, Operation: op.Name, Items: len(ds.Data), BenchmarkResult: result})
+		}
+	}
+	return results, nil
+}
+
+// WriteReport writes the results as a table, with one row per result.
+func WriteReport(w io.Writer, results []Result) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "dataset\toperation\tns/item\tMB/s\tB/op\tallocs/op\t")
+	for _, r := range results {
+		var mbps float64
+		if seconds := r.T.Seconds(); seconds > 0 {
+			mbps = float64(r.Bytes) * float64(r.N) / 1e6 / seconds
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%.0f\t%.2f\t%d\t%d\t\n", r.Dataset, r.Operation, r.NsPerItem(), mbps, r.AllocedBytesPerOp(), r.AllocsPerOp())
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,79 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package bench
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+func TestDatasetsAreDeterministic(t *testing.T) {
+	first, second := Datasets(20), Datasets(20)
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("GOT: different datasets; WANT: same datasets")
+	}
+}
+
+func TestOperations(t *testing.T) {
+	for _, ds := range Datasets(10) {
+		codec, err := goavro.NewCodec(ds.Schema)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, op := range Operations() {
+			run, size, err := op.Prepare(codec, ds.Data)
+			if err != nil {
+				t.Errorf("%s %s: %s", ds.Name, op.Name, err)
+				continue
+			}
+			if size <= 0 {
+				t.Errorf("%s %s: GOT: %d; WANT: >0", ds.Name, op.Name, size)
+			}
+			if err = run(); err != nil {
+				t.Errorf("%s %s: %s", ds.Name, op.Name, err)
+			}
+		}
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	results := []Result{{
+		Dataset:         "flat",
+		Operation:       "binary-encode",
+		Items:           10,
+		BenchmarkResult: testing.BenchmarkResult{N: 100, T: time.Millisecond, Bytes: 500, MemAllocs: 200, MemBytes: 6400},
+	}}
+	var buf bytes.Buffer
+	if err := WriteReport(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("GOT: %d lines; WANT: 2", len(lines))
+	}
+	if got, want := strings.Fields(lines[1]), []string{"flat", "binary-encode", "1000", "50.00", "64", "2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GOT: %v; WANT: %v", got, want)
+	}
+}
+
+func BenchmarkOperations(b *testing.B) {
+	for _, ds := range Datasets(100) {
+		for _, op := range Operations() {
+			b.Run(ds.Name+"/"+op.Name, func(b *testing.B) {
+				Benchmark(b, ds, op)
+			})
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package bench
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// datasetSeed seeds data generation, so every run measures the same data.
+const datasetSeed = 20190101
+
+const (
+	// FlatSchema is a record of primitive fields, typical of log and metric
+	// events.
+	FlatSchema = `{
+  "type": "record",
+  "name": "PageView",
+  "namespace": "com.example.bench",
+  "fields": [
+    {"name": "user_id", "type": "long"},
+    {"name": "session", "type": "int"},
+    {"name": "url", "type": "string"},
+    {"name": "referrer", "type": "string"},
+    {"name": "duration", "type": "double"},
+    {"name": "bounced", "type": "boolean"}
+  ]
+}`
+
+	// NestedSchema is a record with nested records, arrays, maps, unions, and
+	// enums, typical of domain entities.
+	NestedSchema = `{
+  "type": "record",
+  "name": "Order",
+  "namespace": "com.example.bench",
+  "fields": [
+    {"name": "id", "type": "string"},
+    {"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["PENDING", "SHIPPED", "DELIVERED", "CANCELLED"]}},
+    {"name": "customer", "type": {
+      "type": "record",
+      "name": "Customer",
+      "fields": [
+        {"name": "name", "type": "string"},
+        {"name": "email", "type": ["null", "string"], "default": null}
+      ]
+    }},
+    {"name": "lines", "type": {"type": "array", "items": {
+      "type": "record",
+      "name": "Line",
+      "fields": [
+        {"name": "sku", "type": "string"},
+        {"name": "quantity", "type": "int"},
+        {"name": "price", "type": "double"}
+      ]
+    }}},
+    {"name": "labels", "type": {"type": "map", "values": "string"}},
+    {"name": "notes", "type": ["null", "string"], "default": null}
+  ]
+}`
+
+	// LogicalSchema is a record of logical type fields, typical of financial
+	// records.
+	LogicalSchema = `{
+  "type": "record",
+  "name": "Payment",
+  "namespace": "com.example.bench",
+  "fields": [
+    {"name": "created", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "settled", "type": {"type": "int", "logicalType": "date"}},
+    {"name": "amount", "type": {"type": "bytes", "logicalType": "decimal", "precision": 12, "scale": 2}},
+    {"name": "account", "type": {"type": "fixed", "name": "Account", "size": 16}}
+  ]
+}`
+)
+
+// Datasets returns the representative datasets, each with count items. The
+// data is generated deterministically, so the same count always returns the
+// same data.
+func Datasets(count int) []Dataset {
+	return []Dataset{
+		{Name: "flat", Schema: FlatSchema, Data: generate(count, flatDatum)},
+		{Name: "nested", Schema: NestedSchema, Data: generate(count, nestedDatum)},
+		{Name: "logical", Schema: LogicalSchema, Data: generate(count, logicalDatum)},
+	}
+}
+
+func generate(count int, datum func(*rand.Rand, int) interface{}) []interface{} {
+	r := rand.New(rand.NewSource(datasetSeed))
+	data := make([]interface{}, count)
+	for i := range data {
+		data[i] = datum(r, i)
+	}
+	return data
+}
+
+var benchWords = []string{"alpha", "bravo", "charlie", "delta", "echo", "foxtrot", "golf", "hotel", "india", "juliet"}
+
+func randomWords(r *rand.Rand, min, max int) string {
+	s := benchWords[r.Intn(len(benchWords))]
+	for i := min + r.Intn(max-min+1) - 1; i > 0; i-- {
+		s += "-" + benchWords[r.Intn(len(benchWords))]
+	}
+	return s
+}
+
+func flatDatum(r *rand.Rand, i int) interface{} {
+	return map[string]interface{}{
+		"user_id":  r.Int63(),
+		"session":  r.Int31(),
+		"url":      "https://example.com/" + randomWords(r, 1, 4),
+		"referrer": "https://search.example.net/?q=" + randomWords(r, 1, 3),
+		"duration": r.Float64() * 300,
+		"bounced":  r.Intn(4) == 0,
+	}
+}
+
+func nestedDatum(r *rand.Rand, i int) interface{} {
+	lines := make([]interface{}, 1+r.Intn(5))
+	for j := range lines {
+		lines[j] = map[string]interface{}{
+			"sku":      fmt.Sprintf("SKU-%06d", r.Intn(1000000)),
+			"quantity": int32(1 + r.Intn(10)),
+			"price":    float64(r.Intn(100000)) / 100,
+		}
+	}
+	labels := make(map[string]interface{})
+	for j := r.Intn(4); j > 0; j-- {
+		labels[randomWords(r, 1, 1)] = randomWords(r, 1, 2)
+	}
+	var email, notes interface{}
+	if r.Intn(2) == 0 {
+		email = goavro.Union("string", randomWords(r, 1, 2)+"@example.com")
+	}
+	if r.Intn(5) == 0 {
+		notes = goavro.Union("string", randomWords(r, 3, 10))
+	}
+	return map[string]interface{}{
+		"id":       fmt.Sprintf("order-%08d", i),
+		"status":   []string{"PENDING", "SHIPPED", "DELIVERED", "CANCELLED"}[r.Intn(4)],
+		"customer": map[string]interface{}{"name": randomWords(r, 2, 2), "email": email},
+		"lines":    lines,
+		"labels":   labels,
+		"notes":    notes,
+	}
+}
+
+func logicalDatum(r *rand.Rand, i int) interface{} {
+	created := time.Unix(1500000000+r.Int63n(100000000), 0).UTC()
+	account := make([]byte, 16)
+	r.Read(account)
+	return map[string]interface{}{
+		"created": created,
+		"settled": created.Truncate(24*time.Hour).AddDate(0, 0, r.Intn(5)),
+		"amount":  big.NewRat(r.Int63n(10000000000), 100),
+		"account": account,
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package bench
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// Operations returns every operation provided by this package.
+func Operations() []Operation {
+	ops := []Operation{BinaryEncode, BinaryDecode, TextualEncode, TextualDecode}
+	for _, compressionName := range []string{goavro.CompressionNullLabel, goavro.CompressionDeflateLabel, goavro.CompressionSnappyLabel} {
+		ops = append(ops, OCFWrite(compressionName), OCFRead(compressionName))
+	}
+	return append(ops, MessagePackTranscode, CBORTranscode)
+}
+
+// BinaryEncode measures encoding each datum to binary Avro, reusing the same
+// output buffer.
+var BinaryEncode = Operation{
+	Name: "binary-encode",
+	Prepare: func(codec *goavro.Codec, data []interface{}) (func() error, int64, error) {
+		encoded, err := encodeAll(codec, data)
+		if err != nil {
+			return nil, 0, err
+		}
+		buf := make([]byte, 0, 1024)
+		return func() error {
+			var err error
+			for _, datum := range data {
+				if buf, err = codec.BinaryFromNative(buf[:0], datum); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, totalSize(encoded), nil
+	},
+}
+
+// BinaryDecode measures decoding each datum from binary Avro.
+var BinaryDecode = Operation{
+	Name: "binary-decode",
+	Prepare: func(codec *goavro.Codec, data []interface{}) (func() error, int64, error) {
+		encoded, err := encodeAll(codec, data)
+		if err != nil {
+			return nil, 0, err
+		}
+		return func() error {
+			for _, buf := range encoded {
+				if _, _, err := codec.NativeFromBinary(buf); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, totalSize(encoded), nil
+	},
+}
+
+// TextualEncode measures encoding each datum to textual Avro, reusing the same
+// output buffer.
+var TextualEncode = Operation{
+	Name: "textual-encode",
+	Prepare: func(codec *goavro.Codec, data []interface{}) (func() error, int64, error) {
+		encoded, err := encodeAllTextual(codec, data)
+		if err != nil {
+			return nil, 0, err
+		}
+		buf := make([]byte, 0, 1024)
+		return func() error {
+			var err error
+			for _, datum := range data {
+				if buf, err = codec.TextualFromNative(buf[:0], datum); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, totalSize(encoded), nil
+	},
+}
+
+// TextualDecode measures decoding each datum from textual Avro.
+var TextualDecode = Operation{
+	Name: "textual-decode",
+	Prepare: func(codec *goavro.Codec, data []interface{}) (func() error, int64, error) {
+		encoded, err := encodeAllTextual(codec, data)
+		if err != nil {
+			return nil, 0, err
+		}
+		return func() error {
+			for _, buf := range encoded {
+				if _, _, err := codec.NativeFromTextual(buf); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, totalSize(encoded), nil
+	},
+}
+
+// OCFWrite returns an operation measuring writing all data to an Object
+// Container File in memory, using the named compression algorithm.
+func OCFWrite(compressionName string) Operation {
+	return Operation{
+		Name: "ocf-write-" + compressionName,
+		Prepare: func(codec *goavro.Codec, data []interface{}) (func() error, int64, error) {
+			file := new(bytes.Buffer)
+			run := func() error {
+				file.Reset()
+				w, err := goavro.NewOCFWriter(goavro.OCFConfig{W: file, Codec: codec, CompressionName: compressionName})
+				if err != nil {
+					return err
+				}
+				return w.Append(data)
+			}
+			if err := run(); err != nil {
+				return nil, 0, err
+			}
+			return run, int64(file.Len()), nil
+		},
+	}
+}
+
+// OCFRead returns an operation measuring reading all data from an Object
+// Container File in memory, written using the named compression algorithm.
+func OCFRead(compressionName string) Operation {
+	return Operation{
+		Name: "ocf-read-" + compressionName,
+		Prepare: func(codec *goavro.Codec, data []interface{}) (func() error, int64, error) {
+			file := new(bytes.Buffer)
+			w, err := goavro.NewOCFWriter(goavro.OCFConfig{W: file, Codec: codec, CompressionName: compressionName})
+			if err != nil {
+				return nil, 0, err
+			}
+			if err = w.Append(data); err != nil {
+				return nil, 0, err
+			}
+			contents := file.Bytes()
+			return func() error {
+				r, err := goavro.NewOCFReader(bytes.NewReader(contents))
+				if err != nil {
+					return err
+				}
+				var count int
+				for r.Scan() {
+					if _, err = r.Read(); err != nil {
+						return err
+					}
+					count++
+				}
+				if err = r.Err(); err != nil {
+					return err
+				}
+				if count != len(data) {
+					return fmt.Errorf("read %d items; expected %d", count, len(data))
+				}
+				return nil
+			}, int64(len(contents)), nil
+		},
+	}
+}
+
+// MessagePackTranscode measures transcoding each datum from binary Avro to
+// MessagePack, reusing the same output buffer.
+var MessagePackTranscode = Operation{
+	Name:    "msgpack-from-binary",
+	Prepare: transcodePreparer(func(c *goavro.Codec) func([]byte, []byte) ([]byte, []byte, error) { return c.MessagePackFromBinary }),
+}
+
+// CBORTranscode measures transcoding each datum from binary Avro to CBOR,
+// reusing the same output buffer.
+var CBORTranscode = Operation{
+	Name:    "cbor-from-binary",
+	Prepare: transcodePreparer(func(c *goavro.Codec) func([]byte, []byte) ([]byte, []byte, error) { return c.CBORFromBinary }),
+}
+
+func transcodePreparer(transcoder func(*goavro.Codec) func([]byte, []byte) ([]byte, []byte, error)) func(*goavro.Codec, []interface{}) (func() error, int64, error) {
+	return func(codec *goavro.Codec, data []interface{}) (func() error, int64, error) {
+		encoded, err := encodeAll(codec, data)
+		if err != nil {
+			return nil, 0, err
+		}
+		transcode := transcoder(codec)
+		buf := make([]byte, 0, 1024)
+		return func() error {
+			var err error
+			for _, b := range encoded {
+				if buf, _, err = transcode(buf[:0], b); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, totalSize(encoded), nil
+	}
+}
+
+func encodeAll(codec *goavro.Codec, data []interface{}) ([][]byte, error) {
+	encoded := make([][]byte, len(data))
+	for i, datum := range data {
+		var err error
+		if encoded[i], err = codec.BinaryFromNative(nil, datum); err != nil {
+			return nil, fmt.Errorf("cannot encode datum %d: %s", i+1, err)
+		}
+	}
+	return encoded, nil
+}
+
+func encodeAllTextual(codec *goavro.Codec, data []interface{}) ([][]byte, error) {
+	encoded := make([][]byte, len(data))
+	for i, datum := range data {
+		var err error
+		if encoded[i], err = codec.TextualFromNative(nil, datum); err != nil {
+			return nil, fmt.Errorf("cannot encode datum %d: %s", i+1, err)
+		}
+	}
+	return encoded, nil
+}
+
+func totalSize(encoded [][]byte) int64 {
+	var size int64
+	for _, buf := range encoded {
+		size += int64(len(buf))
+	}
+	return size
+}
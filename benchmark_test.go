@@ -7,9 +7,13 @@
 // distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
 // WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
 
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
 package goavro
 
 import (
+	"bytes"
 	"io/ioutil"
 	"testing"
 )
@@ -85,3 +89,24 @@ func BenchmarkNativeFromTextualUsingV2(b *testing.B) {
 		_ = nativeFromTextUsingV2(b, codec, textData)
 	}
 }
+
+func nativeFromAvroUsingV2(tb testing.TB, avroBlob []byte) ([]interface{}, *Codec) {
+	tb.Helper()
+	ocf, err := NewOCFReader(bytes.NewReader(avroBlob))
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	var nativeData []interface{}
+	for ocf.Scan() {
+		datum, err := ocf.Read()
+		if err != nil {
+			break // Read error sets OCFReader error
+		}
+		nativeData = append(nativeData, datum)
+	}
+	if err := ocf.Err(); err != nil {
+		tb.Fatal(err)
+	}
+	return nativeData, ocf.Codec()
+}
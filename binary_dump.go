@@ -0,0 +1,350 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// binaryDumpLineBytes is the number of bytes shown per line by
+// BinaryDump.String.
+const binaryDumpLineBytes = 8
+
+// BinaryDumpEntry describes one range of bytes of a binary encoded datum, such
+// as a varint, the bytes of a string, or a union index.
+//
+// Path addresses the value the bytes belong to, using the conventions of
+// ResolutionStep, and is empty for the datum itself.
+type BinaryDumpEntry struct {
+	Offset      int         // offset of the first byte from the start of the buffer
+	Bytes       []byte      // bytes of the range, which alias the buffer
+	Path        string      // value the bytes belong to
+	Description string      // what the bytes encode, such as "string size 3"
+	Value       interface{} // value decoded from the bytes
+}
+
+// BinaryDump holds the entries of an annotated dump of a binary encoded datum,
+// in the order of their bytes, as returned by DumpBinary.
+type BinaryDump struct {
+	// Datum is the decoded datum, or nil when the datum cannot be decoded.
+	Datum interface{}
+
+	Entries []BinaryDumpEntry
+}
+
+// String returns the dump with one entry per line, each line showing the
+// hexadecimal offset and bytes of the entry, its path, and its description.
+// The bytes of entries longer than 8 bytes continue on the following lines.
+//
+//     00000000  06                       name: string size 3
+//     00000001  61 6e 6e                 name: string "ann"
+//     00000004  02                       email: union index 1: string
+func (d *BinaryDump) String() string {
+	var b strings.Builder
+	for _, e := range d.Entries {
+		label := e.Description
+		if e.Path != "" {
+			label = e.Path + ": " + label
+		}
+		for line := 0; line == 0 || line < len(e.Bytes); line += binaryDumpLineBytes {
+			end := line + binaryDumpLineBytes
+			if end > len(e.Bytes) {
+				end = len(e.Bytes)
+			}
+			hex := make([]string, 0, binaryDumpLineBytes)
+			for _, c := range e.Bytes[line:end] {
+				hex = append(hex, fmt.Sprintf("%02x", c))
+			}
+			if line == 0 {
+				fmt.Fprintf(&b, "%08x  %-*s  %s\n", e.Offset, 3*binaryDumpLineBytes-1, strings.Join(hex, " "), label)
+			} else {
+				fmt.Fprintf(&b, "%08x  %s\n", e.Offset+line, strings.Join(hex, " "))
+			}
+		}
+	}
+	return b.String()
+}
+
+// DumpBinary decodes the binary encoded datum at the start of buf, like
+// NativeFromBinary does, and returns an annotated dump of its bytes, which
+// relates each range of bytes to the value it belongs to and to what it
+// encodes. The dump makes wire level issues, such as a varint one byte too
+// short or a wrong union index, diagnosable by reading where the bytes stop
+// making sense.
+//
+//     dump, _, err := codec.DumpBinary(buf)
+//     fmt.Print(dump)
+//     if err != nil {
+//         return err
+//     }
+//
+// When the datum cannot be decoded, the returned dump holds the entries before
+// the bytes that cannot be decoded, and the error names their offset and path.
+func (c *Codec) DumpBinary(buf []byte) (*BinaryDump, []byte, error) {
+	d := &binaryDumper{buf: buf, dump: &BinaryDump{}}
+	n, err := schemaNodeFromCodec(c)
+	if err != nil {
+		return d.dump, buf, fmt.Errorf("cannot dump binary: %s", err)
+	}
+	if err = d.walk(n, ""); err != nil {
+		return d.dump, buf, fmt.Errorf("cannot dump binary: %s", err)
+	}
+	datum, rest, err := c.NativeFromBinary(buf)
+	if err != nil {
+		return d.dump, buf, fmt.Errorf("cannot dump binary: %s", err)
+	}
+	d.dump.Datum = datum
+	return d.dump, rest, nil
+}
+
+// BinaryDecodeError is returned by DecodeBinary when the binary encoded datum
+// cannot be decoded, and locates the bytes that cannot be decoded.
+type BinaryDecodeError struct {
+	Offset int    // offset of the bytes that cannot be decoded from the start of the buffer
+	Path   string // value the bytes belong to, using the conventions of BinaryDumpEntry
+	Err    error
+}
+
+// Error returns the error, prefixed with its offset and path.
+func (e *BinaryDecodeError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("offset %d: %s", e.Offset, e.Err)
+	}
+	return fmt.Sprintf("offset %d: %s: %s", e.Offset, e.Path, e.Err)
+}
+
+// DecodeBinary decodes the binary encoded datum at the start of buf, like
+// NativeFromBinary does, and returns the number of bytes of the datum, so
+// framing layers holding trailing bytes, such as the following data, know
+// exactly where the datum ends.
+//
+// When the datum cannot be decoded, the error is a *BinaryDecodeError, which
+// locates the bytes that cannot be decoded, so a framing layer may report the
+// corruption and resume with the next frame rather than abandon the whole
+// batch. The bytes are located by walking the datum again, so errors cost
+// more than successes.
+//
+//     datum, n, err := codec.DecodeBinary(frame)
+//     if err != nil {
+//         if de, ok := err.(*goavro.BinaryDecodeError); ok {
+//             log.Printf("corrupt record at offset %d of field %q: %s", de.Offset, de.Path, de.Err)
+//             continue
+//         }
+//         return err
+//     }
+//     frame = frame[n:]
+//
+// When the bytes are well formed, but the datum is rejected, such as by field
+// hooks, the error has an Offset of 0 and an empty Path.
+func (c *Codec) DecodeBinary(buf []byte) (interface{}, int, error) {
+	datum, rest, err := c.nativeFromBinaryPrefix(buf)
+	if err == nil {
+		return datum, len(buf) - len(rest), nil
+	}
+	d := &binaryDumper{buf: buf, dump: &BinaryDump{}}
+	if n, nerr := schemaNodeFromCodec(c); nerr == nil {
+		if werr, ok := d.walk(n, "").(*BinaryDecodeError); ok {
+			return nil, 0, werr
+		}
+	}
+	return nil, 0, &BinaryDecodeError{Err: err}
+}
+
+// binaryDumpDecoders decode the primitive types whose bytes are dumped as one
+// entry.
+var binaryDumpDecoders = map[string]func([]byte) (interface{}, []byte, error){
+	"boolean": booleanNativeFromBinary,
+	"int":     intNativeFromBinary,
+	"long":    longNativeFromBinary,
+	"float":   floatNativeFromBinary,
+	"double":  doubleNativeFromBinary,
+}
+
+type binaryDumper struct {
+	buf    []byte
+	offset int // offset of the next byte to dump
+	dump   *BinaryDump
+}
+
+// fail returns err, along with the offset of the next byte and the path.
+func (d *binaryDumper) fail(path string, err error) error {
+	return &BinaryDecodeError{Offset: d.offset, Path: path, Err: err}
+}
+
+// add appends an entry for the next size bytes.
+func (d *binaryDumper) add(size int, path string, value interface{}, format string, a ...interface{}) {
+	d.dump.Entries = append(d.dump.Entries, BinaryDumpEntry{
+		Offset:      d.offset,
+		Bytes:       d.buf[d.offset : d.offset+size],
+		Path:        path,
+		Description: fmt.Sprintf(format, a...),
+		Value:       value,
+	})
+	d.offset += size
+}
+
+// decode decodes the next value using decoder, without adding an entry, and
+// returns the value and its size.
+func (d *binaryDumper) decode(decoder func([]byte) (interface{}, []byte, error)) (interface{}, int, error) {
+	value, rest, err := decoder(d.buf[d.offset:])
+	if err != nil {
+		return nil, 0, err
+	}
+	return value, len(d.buf) - d.offset - len(rest), nil
+}
+
+// long decodes the next long, and adds an entry for it, described by label
+// followed by its value.
+func (d *binaryDumper) long(path, label string) (int64, error) {
+	value, size, err := d.decode(longNativeFromBinary)
+	if err != nil {
+		return 0, d.fail(path, fmt.Errorf("%s: %s", label, err))
+	}
+	d.add(size, path, value, "%s %d", label, value)
+	return value.(int64), nil
+}
+
+// sized checks that the next size bytes hold the content of a bytes, string,
+// or map key.
+func (d *binaryDumper) sized(path, label string, size int64) error {
+	if size < 0 {
+		return d.fail(path, fmt.Errorf("%s: negative size: %d", label, size))
+	}
+	if remaining := int64(len(d.buf) - d.offset); size > remaining {
+		return d.fail(path, fmt.Errorf("%s: size exceeds remaining buffer: %d > %d (%s)", label, size, remaining, io.ErrShortBuffer))
+	}
+	return nil
+}
+
+func (d *binaryDumper) walk(n *schemaNode, path string) error {
+	switch n.typeName {
+	case "null":
+		return nil
+	case "boolean", "int", "long", "float", "double":
+		value, size, err := d.decode(binaryDumpDecoders[n.typeName])
+		if err != nil {
+			return d.fail(path, err)
+		}
+		d.add(size, path, value, "%s %v", n.label(), value)
+		return nil
+	case "bytes", "string":
+		size, err := d.long(path, n.typeName+" size")
+		if err != nil {
+			return err
+		}
+		if err = d.sized(path, n.typeName, size); err != nil {
+			return err
+		}
+		content := d.buf[d.offset : d.offset+int(size)]
+		if n.typeName == "string" {
+			d.add(int(size), path, string(content), "%s %q", n.label(), content)
+		} else {
+			d.add(int(size), path, content, "%s %q", n.label(), content)
+		}
+		return nil
+	case "enum":
+		value, size, err := d.decode(longNativeFromBinary)
+		if err != nil {
+			return d.fail(path, fmt.Errorf("enum index: %s", err))
+		}
+		index := value.(int64)
+		if index < 0 || index >= int64(len(n.symbols)) {
+			return d.fail(path, fmt.Errorf("enum %q index ought to be between 0 and %d; read index: %d", n.fullName, len(n.symbols)-1, index))
+		}
+		d.add(size, path, n.symbols[index], "enum %s index %d: %q", n.fullName, index, n.symbols[index])
+		return nil
+	case "fixed":
+		if err := d.sized(path, fmt.Sprintf("fixed %q", n.fullName), int64(n.size)); err != nil {
+			return err
+		}
+		content := d.buf[d.offset : d.offset+n.size]
+		d.add(n.size, path, content, "%s %q", n.label(), content)
+		return nil
+	case "union":
+		value, size, err := d.decode(longNativeFromBinary)
+		if err != nil {
+			return d.fail(path, fmt.Errorf("union index: %s", err))
+		}
+		index := value.(int64)
+		if index < 0 || index >= int64(len(n.members)) {
+			return d.fail(path, fmt.Errorf("union index ought to be between 0 and %d; read index: %d", len(n.members)-1, index))
+		}
+		member := n.members[index]
+		d.add(size, path, index, "union index %d: %s", index, member.label())
+		return d.walk(member, path)
+	case "record":
+		for _, f := range n.fields {
+			fieldPath := f.name
+			if path != "" {
+				fieldPath = path + "." + f.name
+			}
+			if err := d.walk(f.node, fieldPath); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "array":
+		var i int
+		return d.walkBlocks(path, "array", func() error {
+			err := d.walk(n.items, fmt.Sprintf("%s[%d]", path, i))
+			i++
+			return err
+		})
+	case "map":
+		return d.walkBlocks(path, "map", func() error {
+			size, err := d.long(path, "map key size")
+			if err != nil {
+				return err
+			}
+			if err = d.sized(path, "map key", size); err != nil {
+				return err
+			}
+			key := string(d.buf[d.offset : d.offset+int(size)])
+			d.add(int(size), path, key, "map key %q", key)
+			return d.walk(n.values, path+"{"+key+"}")
+		})
+	default:
+		return d.fail(path, fmt.Errorf("unknown type: %q", n.typeName))
+	}
+}
+
+// walkBlocks dumps the blocks of an array or map, invoking walkItem for each
+// item. Unlike skipping, items of blocks that have a byte size are dumped too.
+func (d *binaryDumper) walkBlocks(path, kind string, walkItem func() error) error {
+	for {
+		blockCount, err := d.long(path, kind+" block count")
+		if err != nil {
+			return err
+		}
+		if blockCount == 0 {
+			return nil
+		}
+		if blockCount < 0 {
+			if blockCount == math.MinInt64 {
+				return d.fail(path, fmt.Errorf("%s with block count: %d", kind, blockCount))
+			}
+			blockCount = -blockCount
+			if _, err = d.long(path, kind+" block size"); err != nil {
+				return err
+			}
+		}
+		if blockCount > MaxBlockCount {
+			return d.fail(path, fmt.Errorf("%s when block count exceeds MaxBlockCount: %d > %d", kind, blockCount, MaxBlockCount))
+		}
+		for i := int64(0); i < blockCount; i++ {
+			if err = walkItem(); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCodecDumpBinary(t *testing.T) {
+	codec := newCodecUsingV2(t, `{"type":"record","name":"User","fields":[
+		{"name":"name","type":"string"},
+		{"name":"email","type":["null","string"]},
+		{"name":"tags","type":{"type":"array","items":"int"}},
+		{"name":"labels","type":{"type":"map","values":"boolean"}},
+		{"name":"suit","type":{"type":"enum","name":"Suit","symbols":["HEARTS","SPADES"]}}
+	]}`)
+	buf, err := codec.BinaryFromNative(nil, map[string]interface{}{
+		"name":   "ann",
+		"email":  Union("string", "ann@example.com"),
+		"tags":   []interface{}{1, -1},
+		"labels": map[string]interface{}{"on": true},
+		"suit":   "SPADES",
+	})
+	ensureError(t, err)
+
+	dump, rest, err := codec.DumpBinary(append(buf, 0xff))
+	ensureError(t, err)
+	if actual, expected := len(rest), 1; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if dump.Datum.(map[string]interface{})["suit"] != "SPADES" {
+		t.Errorf("GOT: %v", dump.Datum)
+	}
+
+	expected := `00000000  06                       name: string size 3
+00000001  61 6e 6e                 name: string "ann"
+00000004  02                       email: union index 1: string
+00000005  1e                       email: string size 15
+00000006  61 6e 6e 40 65 78 61 6d  email: string "ann@example.com"
+0000000e  70 6c 65 2e 63 6f 6d
+00000015  04                       tags: array block count 2
+00000016  02                       tags[0]: int 1
+00000017  01                       tags[1]: int -1
+00000018  00                       tags: array block count 0
+00000019  02                       labels: map block count 1
+0000001a  04                       labels: map key size 2
+0000001b  6f 6e                    labels: map key "on"
+0000001d  01                       labels{on}: boolean true
+0000001e  00                       labels: map block count 0
+0000001f  02                       suit: enum Suit index 1: "SPADES"
+`
+	if actual := dump.String(); actual != expected {
+		t.Errorf("GOT:\n%s\nWANT:\n%s", actual, expected)
+	}
+}
+
+func TestCodecDumpBinaryBlockSizes(t *testing.T) {
+	codec := newCodecUsingV2(t, `{"type":"array","items":"long"}`)
+
+	// one block of two items written with its size in bytes
+	dump, _, err := codec.DumpBinary([]byte{0x03, 0x04, 0x02, 0x04, 0x00})
+	ensureError(t, err)
+
+	var descriptions []string
+	for _, e := range dump.Entries {
+		descriptions = append(descriptions, e.Path+" "+e.Description)
+	}
+	expected := []string{
+		" array block count -2",
+		" array block size 2",
+		"[0] long 1",
+		"[1] long 2",
+		" array block count 0",
+	}
+	if len(descriptions) != len(expected) {
+		t.Fatalf("GOT: %q; WANT: %q", descriptions, expected)
+	}
+	for i := range expected {
+		if descriptions[i] != expected[i] {
+			t.Errorf("GOT: %q; WANT: %q", descriptions[i], expected[i])
+		}
+	}
+}
+
+func TestCodecDumpBinaryError(t *testing.T) {
+	codec := newCodecUsingV2(t, `{"type":"record","name":"R","fields":[
+		{"name":"id","type":"long"},
+		{"name":"value","type":["null","string"]}
+	]}`)
+
+	t.Run("union index", func(t *testing.T) {
+		dump, _, err := codec.DumpBinary([]byte{0x02, 0x04})
+		ensureError(t, err, "offset 1: value: union index ought to be between 0 and 1; read index: 2")
+		if actual, expected := len(dump.Entries), 1; actual != expected {
+			t.Errorf("GOT: %v; WANT: %v", actual, expected)
+		}
+		if dump.Datum != nil {
+			t.Errorf("GOT: %v; WANT: %v", dump.Datum, nil)
+		}
+	})
+
+	t.Run("short string", func(t *testing.T) {
+		_, _, err := codec.DumpBinary([]byte{0x02, 0x02, 0x08, 'a'})
+		ensureError(t, err, "offset 3: value: string: size exceeds remaining buffer: 4 > 1")
+	})
+
+	t.Run("short varint", func(t *testing.T) {
+		_, _, err := codec.DumpBinary([]byte{0x80})
+		ensureError(t, err, "offset 0: id:")
+	})
+}
+
+func TestCodecDecodeBinary(t *testing.T) {
+	codec := newCodecUsingV2(t, `{"type":"record","name":"r","fields":[{"name":"name","type":"string"},{"name":"tags","type":{"type":"array","items":"string"}}]}`)
+	buf, err := codec.BinaryFromNative(nil, map[string]interface{}{"name": "ann", "tags": []interface{}{"a"}})
+	ensureError(t, err)
+
+	datum, n, err := codec.DecodeBinary(append(buf, 0x01, 0x02))
+	ensureError(t, err)
+	if actual, expected := n, len(buf); actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if actual, expected := fmt.Sprint(datum), "map[name:ann tags:[a]]"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	// the tag claims more bytes than remain
+	corrupt := append([]byte(nil), buf...)
+	corrupt[5] = 0x10
+	_, n, err = codec.DecodeBinary(corrupt)
+	de, ok := err.(*BinaryDecodeError)
+	if !ok {
+		t.Fatalf("GOT: %#v; WANT: *BinaryDecodeError", err)
+	}
+	if actual, expected := de.Offset, 6; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if actual, expected := de.Path, "tags[0]"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if n != 0 {
+		t.Errorf("GOT: %v; WANT: %v", n, 0)
+	}
+	ensureError(t, err, "offset 6: tags[0]: string: size exceeds remaining buffer")
+}
@@ -0,0 +1,51 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import "math"
+
+// blockCountHint returns the number of items to allocate for an array or map
+// whose first block has count items and, when size is not negative, size bytes,
+// which are followed by rest. When the blocks have byte sizes, the item counts
+// of the following blocks are read by jumping over each block rather than
+// decoding its items, so the number of items in all blocks is known before
+// decoding the first. The result is only a hint: it never exceeds
+// MaxBlockCount, and a malformed block ends the search rather than causing an
+// error, which is reported when the items are decoded.
+func blockCountHint(count, size int64, rest []byte) int64 {
+	total := count
+	for size >= 0 && size <= int64(len(rest)) && total < MaxBlockCount {
+		value, b, err := longNativeFromBinary(rest[size:])
+		if err != nil {
+			break
+		}
+		next := value.(int64)
+		if next >= 0 {
+			// Either the final block, or a block without a size, beyond which
+			// the search cannot jump.
+			if next <= MaxBlockCount {
+				total += next
+			}
+			break
+		}
+		if next == math.MinInt64 || -next > MaxBlockCount {
+			break
+		}
+		if value, rest, err = longNativeFromBinary(b); err != nil {
+			break
+		}
+		total -= next
+		size = value.(int64)
+	}
+	if total > MaxBlockCount {
+		total = MaxBlockCount
+	}
+	return total
+}
@@ -0,0 +1,41 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import "testing"
+
+func TestBlockCountHint(t *testing.T) {
+	// two blocks with sizes, followed by a block without a size
+	buf := []byte{0x05, 0x06, 0x02, 0x04, 0x06, 0x01, 0x02, 0x08, 0x04, 0x0a, 0x0c, 0x00}
+	codec := newCodecUsingV2(t, `{"type":"array","items":"int"}`)
+	datum, _, err := codec.NativeFromBinary(buf)
+	ensureError(t, err)
+	items := datum.([]interface{})
+	if len(items) != 6 || cap(items) != 6 {
+		t.Errorf("GOT: len %d cap %d; WANT: 6", len(items), cap(items))
+	}
+
+	for _, c := range []struct {
+		count, size int64
+		rest        []byte
+		want        int64
+	}{
+		{3, 3, buf[2:], 6},
+		{3, -1, buf[2:], 3},                 // no size
+		{3, 99, buf[2:], 3},                 // size beyond buffer
+		{3, 3, []byte{0x02, 0x04, 0x06}, 3}, // truncated
+		{3, 0, []byte{0x7f}, 3},             // bad following count
+		{MaxBlockCount, 0, []byte{0x02}, MaxBlockCount},
+	} {
+		if got := blockCountHint(c.count, c.size, c.rest); got != c.want {
+			t.Errorf("%d %d %v: GOT: %d; WANT: %d", c.count, c.size, c.rest, got, c.want)
+		}
+	}
+}
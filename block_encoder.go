@@ -0,0 +1,75 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+// blockEncoder writes the items of an Avro array or map in blocks, each
+// starting with its item count, as specified by the BlockLength and BlockSizes
+// codec options. Callers invoke beginItem before encoding each item, endItem
+// after it, and finish after all items.
+type blockEncoder struct {
+	remainingItems   int64 // items not yet encoded
+	remainingInBlock int64 // items of the current block not yet encoded
+	blockLength      int64 // maximum number of items per block
+	withSizes        bool  // when true, write negative counts and block sizes
+	blockCount       int64 // number of items in the current block
+	blockStart       int   // offset of the first item of the current block
+}
+
+func newBlockEncoder(option *CodecOption, itemCount int) blockEncoder {
+	blockLength := int64(MaxBlockCount)
+	if option.BlockLength > 0 && int64(option.BlockLength) < blockLength {
+		blockLength = int64(option.BlockLength)
+	}
+	return blockEncoder{
+		remainingItems: int64(itemCount),
+		blockLength:    blockLength,
+		withSizes:      option.BlockSizes,
+	}
+}
+
+func (e *blockEncoder) beginItem(buf []byte) []byte {
+	if e.remainingInBlock > 0 {
+		return buf
+	}
+	// start a new block
+	e.blockCount = e.remainingItems
+	if e.blockCount > e.blockLength {
+		e.blockCount = e.blockLength
+	}
+	e.remainingInBlock = e.blockCount
+	if e.withSizes {
+		// The count and size are written once the size is known.
+		e.blockStart = len(buf)
+		return buf
+	}
+	buf, _ = longBinaryFromNative(buf, e.blockCount)
+	return buf
+}
+
+func (e *blockEncoder) endItem(buf []byte) []byte {
+	e.remainingItems--
+	if e.remainingInBlock--; e.remainingInBlock > 0 || !e.withSizes {
+		return buf
+	}
+	// Insert the negative block count and the block size in bytes before the
+	// items of the block.
+	size := len(buf) - e.blockStart
+	var header []byte
+	header, _ = longBinaryFromNative(header, -e.blockCount)
+	header, _ = longBinaryFromNative(header, size)
+	buf = append(buf, header...)
+	copy(buf[e.blockStart+len(header):], buf[e.blockStart:e.blockStart+size])
+	copy(buf[e.blockStart:], header)
+	return buf
+}
+
+func (e *blockEncoder) finish(buf []byte) ([]byte, error) {
+	return longBinaryFromNative(buf, 0) // append trailing 0 block count to signal end of items
+}
@@ -0,0 +1,75 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestBlockEncoderArray(t *testing.T) {
+	datum := []interface{}{"a", "bb", "c"}
+	cases := []struct {
+		option CodecOption
+		want   []byte
+	}{
+		{CodecOption{}, []byte{0x06, 0x02, 'a', 0x04, 'b', 'b', 0x02, 'c', 0x00}},
+		{CodecOption{BlockLength: 2}, []byte{0x04, 0x02, 'a', 0x04, 'b', 'b', 0x02, 0x02, 'c', 0x00}},
+		{CodecOption{BlockSizes: true}, []byte{0x05, 0x0e, 0x02, 'a', 0x04, 'b', 'b', 0x02, 'c', 0x00}},
+		{CodecOption{BlockLength: 2, BlockSizes: true}, []byte{0x03, 0x0a, 0x02, 'a', 0x04, 'b', 'b', 0x01, 0x04, 0x02, 'c', 0x00}},
+	}
+	for _, c := range cases {
+		option := c.option
+		codec := newCodecWithOptionsUsingV2(t, `{"type":"array","items":"string"}`, &option)
+		buf, err := codec.BinaryFromNative([]byte{0xff}, datum)
+		ensureError(t, err)
+		if want := append([]byte{0xff}, c.want...); !bytes.Equal(buf, want) {
+			t.Errorf("%+v: GOT: %#v; WANT: %#v", c.option, buf, want)
+		}
+		decoded, rest, err := codec.NativeFromBinary(buf[1:])
+		ensureError(t, err)
+		if len(rest) != 0 || !reflect.DeepEqual(decoded, datum) {
+			t.Errorf("%+v: GOT: %v, %v; WANT: %v", c.option, decoded, rest, datum)
+		}
+	}
+
+	codec := newCodecWithOptionsUsingV2(t, `{"type":"array","items":"string"}`, &CodecOption{BlockSizes: true})
+	buf, err := codec.BinaryFromNative(nil, []interface{}{})
+	ensureError(t, err)
+	if !bytes.Equal(buf, []byte{0x00}) {
+		t.Errorf("GOT: %#v; WANT: %#v", buf, []byte{0x00})
+	}
+}
+
+func TestBlockEncoderMap(t *testing.T) {
+	codec := newCodecWithOptionsUsingV2(t, `{"type":"map","values":"int"}`, &CodecOption{BlockLength: 1, BlockSizes: true})
+	buf, err := codec.BinaryFromNative(nil, OrderedMap{{Key: "a", Value: 1}, {Key: "b", Value: 2}})
+	ensureError(t, err)
+	if want := []byte{0x01, 0x06, 0x02, 'a', 0x02, 0x01, 0x06, 0x02, 'b', 0x04, 0x00}; !bytes.Equal(buf, want) {
+		t.Errorf("GOT: %#v; WANT: %#v", buf, want)
+	}
+
+	buf, err = codec.BinaryFromNative(nil, map[string]interface{}{"a": 1, "b": 2})
+	ensureError(t, err)
+	decoded, _, err := codec.NativeFromBinary(buf)
+	ensureError(t, err)
+	if want := map[string]interface{}{"a": int32(1), "b": int32(2)}; !reflect.DeepEqual(decoded, want) {
+		t.Errorf("GOT: %v; WANT: %v", decoded, want)
+	}
+	if len(buf) != 11 {
+		t.Errorf("GOT: %d; WANT: %d", len(buf), 11)
+	}
+}
+
+func TestBlockEncoderInvalidOption(t *testing.T) {
+	_, err := NewCodecWithOptions(`{"type":"array","items":"int"}`, &CodecOption{BlockLength: -1})
+	ensureError(t, err, "block length ought to be zero or positive")
+}
@@ -18,6 +18,7 @@ import (
 	"unicode"
 	"unicode/utf16"
 	"unicode/utf8"
+	"unsafe"
 )
 
 ////////////////////////////////////////
@@ -51,6 +52,21 @@ func stringNativeFromBinary(buf []byte) (interface{}, []byte, error) {
 	return string(d.([]byte)), b, nil
 }
 
+// stringNativeFromBinaryNoCopy decodes a string referencing the bytes of buf
+// rather than a copy of them. See CodecOption.ZeroCopyDecoding.
+func stringNativeFromBinaryNoCopy(buf []byte) (interface{}, []byte, error) {
+	d, b, err := bytesNativeFromBinary(buf)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot decode binary string: %s", err)
+	}
+	return stringNoCopy(d.([]byte)), b, nil
+}
+
+// stringNoCopy returns a string referencing the bytes of value.
+func stringNoCopy(value []byte) string {
+	return *(*string)(unsafe.Pointer(&value))
+}
+
 ////////////////////////////////////////
 // Binary Encode
 ////////////////////////////////////////
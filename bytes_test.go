@@ -199,3 +199,61 @@ func TestStringCodecAcceptsBytes(t *testing.T) {
 		testTextEncodePass(t, schema, []byte("abcd"), []byte(`"abcd"`))
 	})
 }
+
+func TestZeroCopyDecoding(t *testing.T) {
+	schema := `{"type":"record","name":"r","fields":[{"name":"s","type":"string"},{"name":"b","type":"bytes"},{"name":"m","type":{"type":"map","values":"string"}}]}`
+	codec, err := NewCodec(schema, WithZeroCopyDecoding(true))
+	ensureError(t, err)
+	buf := []byte("\x06abc\x04de\x02\x02k\x02v\x00")
+
+	datum, rest, err := codec.NativeFromBinary(buf)
+	ensureError(t, err)
+	if len(rest) != 0 {
+		t.Errorf("GOT: %v; WANT: %v", len(rest), 0)
+	}
+	record := datum.(map[string]interface{})
+	if actual, expected := record["s"], "abc"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	// decoded values reference the buffer, except map keys
+	copy(buf, "\x06xyz\x04fg\x02\x02K\x02V")
+	if actual, expected := record["s"], "xyz"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if actual, expected := string(record["b"].([]byte)), "fg"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if actual, expected := record["m"].(map[string]interface{})["k"], "V"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	// without the option, strings are copied
+	codec, err = codec.WithOptions(WithZeroCopyDecoding(false))
+	ensureError(t, err)
+	datum, _, err = codec.NativeFromBinary(buf)
+	ensureError(t, err)
+	copy(buf, "\x06abc")
+	if actual, expected := datum.(map[string]interface{})["s"], "xyz"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	codec, err = NewCodec(`"string"`, WithZeroCopyDecoding(true))
+	ensureError(t, err)
+	_, _, err = codec.NativeFromBinary([]byte("\x06ab"))
+	ensureError(t, err, "cannot decode binary string")
+}
+
+func TestZeroCopyDecodingWithOptions(t *testing.T) {
+	codec, err := NewCodec(`{"type":"array","items":"string"}`)
+	ensureError(t, err)
+	derived, err := codec.WithOptions(WithZeroCopyDecoding(true))
+	ensureError(t, err)
+	buf := []byte("\x02\x06abc\x00")
+	datum, _, err := derived.NativeFromBinary(buf)
+	ensureError(t, err)
+	copy(buf[2:], "xyz")
+	if actual, expected := datum.([]interface{})[0], "xyz"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
@@ -22,15 +22,16 @@ type pcfProcessor func(s interface{}) (string, error)
 
 // parsingCanonialForm returns the "Parsing Canonical Form" (pcf) for a parsed
 // JSON structure of a valid Avro schema, or an error describing the schema
-// error.
-func parsingCanonicalForm(schema interface{}, parentNamespace string, typeLookup map[string]string) (string, error) {
+// error. When retainOrder is true, the order attribute of record fields is
+// kept, unless it is the default ascending order.
+func parsingCanonicalForm(schema interface{}, parentNamespace string, typeLookup map[string]string, retainOrder bool) (string, error) {
 	switch val := schema.(type) {
 	case map[string]interface{}:
 		// JSON objects are decoded as a map of strings to empty interfaces
-		return pcfObject(val, parentNamespace, typeLookup)
+		return pcfObject(val, parentNamespace, typeLookup, retainOrder)
 	case []interface{}:
 		// JSON arrays are decoded as a slice of empty interfaces
-		return pcfArray(val, parentNamespace, typeLookup)
+		return pcfArray(val, parentNamespace, typeLookup, retainOrder)
 	case string:
 		// JSON string values are decoded as a Go string
 		return pcfString(val, typeLookup)
@@ -56,10 +57,10 @@ func pcfString(val string, typeLookup map[string]string) (string, error) {
 }
 
 // pcfArray returns the parsing canonical form for a JSON array.
-func pcfArray(val []interface{}, parentNamespace string, typeLookup map[string]string) (string, error) {
+func pcfArray(val []interface{}, parentNamespace string, typeLookup map[string]string, retainOrder bool) (string, error) {
 	items := make([]string, len(val))
 	for i, el := range val {
-		p, err := parsingCanonicalForm(el, parentNamespace, typeLookup)
+		p, err := parsingCanonicalForm(el, parentNamespace, typeLookup, retainOrder)
 		if err != nil {
 			return "", err
 		}
@@ -69,7 +70,7 @@ func pcfArray(val []interface{}, parentNamespace string, typeLookup map[string]s
 }
 
 // pcfObject returns the parsing canonical form for a JSON object.
-func pcfObject(jsonMap map[string]interface{}, parentNamespace string, typeLookup map[string]string) (string, error) {
+func pcfObject(jsonMap map[string]interface{}, parentNamespace string, typeLookup map[string]string, retainOrder bool) (string, error) {
 	pairs := make(stringPairs, 0, len(jsonMap))
 
 	// Remember the namespace to fully qualify names later
@@ -77,7 +78,7 @@ func pcfObject(jsonMap map[string]interface{}, parentNamespace string, typeLooku
 	if namespaceJSON, ok := jsonMap["namespace"]; ok {
 		if namespaceStr, ok := namespaceJSON.(string); ok {
 			// and it's value is string (otherwise invalid schema)
-			if parentNamespace == "" {
+			if parentNamespace == "" || namespaceStr == "" {
 				namespace = namespaceStr
 			} else {
 				namespace = parentNamespace + "." + namespaceStr
@@ -98,7 +99,11 @@ func pcfObject(jsonMap map[string]interface{}, parentNamespace string, typeLooku
 		}
 
 		// Only keep relevant attributes (strip 'doc', 'alias', 'namespace')
-		if _, ok := fieldOrder[k]; !ok {
+		if k == "order" {
+			if !retainOrder || v == "ascending" {
+				continue
+			}
+		} else if _, ok := fieldOrder[k]; !ok {
 			continue
 		}
 
@@ -124,11 +129,11 @@ func pcfObject(jsonMap map[string]interface{}, parentNamespace string, typeLooku
 			}
 		}
 
-		pk, err := parsingCanonicalForm(k, parentNamespace, typeLookup)
+		pk, err := parsingCanonicalForm(k, parentNamespace, typeLookup, retainOrder)
 		if err != nil {
 			return "", err
 		}
-		pv, err := parsingCanonicalForm(v, parentNamespace, typeLookup)
+		pv, err := parsingCanonicalForm(v, parentNamespace, typeLookup, retainOrder)
 		if err != nil {
 			return "", err
 		}
@@ -160,7 +165,7 @@ func (sp *stringPairs) Bs() []string {
 }
 
 // fieldOrder defines fields that show up in canonical schema and specifies
-// their precedence.
+// their precedence. The order attribute only shows up when it is retained.
 var fieldOrder = map[string]int{
 	"name":    1,
 	"type":    2,
@@ -169,6 +174,7 @@ var fieldOrder = map[string]int{
 	"items":   5,
 	"values":  6,
 	"size":    7,
+	"order":   8,
 }
 
 // byAvroFieldOrder is equipped with a sort order of fields according to the
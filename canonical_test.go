@@ -265,3 +265,55 @@ func TestCanonicalSchema(t *testing.T) {
 		}
 	}
 }
+
+func TestCanonicalSchemaWithOrder(t *testing.T) {
+	cases := []struct {
+		Schema    string
+		Canonical string
+	}{
+		{
+			Schema:    `"int"`,
+			Canonical: `"int"`,
+		},
+		{
+			Schema:    `{"type":"record","name":"foo","fields":[{"name":"a","type":"int","order":"ascending"}]}`,
+			Canonical: `{"name":"foo","type":"record","fields":[{"name":"a","type":"int"}]}`,
+		},
+		{
+			Schema:    `{"type":"record","name":"foo","fields":[{"name":"a","type":"int","order":"descending"},{"name":"b","type":"int","order":"ignore","doc":"b"}]}`,
+			Canonical: `{"name":"foo","type":"record","fields":[{"name":"a","type":"int","order":"descending"},{"name":"b","type":"int","order":"ignore"}]}`,
+		},
+		{
+			Schema:    `{"type":"record","name":"foo","namespace":"bar","fields":[{"name":"a","type":{"type":"record","name":"baz","fields":[{"name":"hi","type":"int","order":"descending"}]}}]}`,
+			Canonical: `{"name":"bar.foo","type":"record","fields":[{"name":"a","type":{"name":"bar.baz","type":"record","fields":[{"name":"hi","type":"int","order":"descending"}]}}]}`,
+		},
+	}
+
+	for _, c := range cases {
+		codec, err := NewCodec(c.Schema)
+		if err != nil {
+			t.Errorf("Unable to create codec for schema: %s\nwith error: %s", c.Schema, err)
+			continue
+		}
+		if got, want := codec.CanonicalSchemaWithOrder(), c.Canonical; got != want {
+			t.Errorf("Test failed for schema: %s\n\tgot canonical:\t\t%s\n\texpected canonical:\t%s", c.Schema, got, want)
+		}
+		if got, want := codec.RabinWithOrder(), rabin([]byte(c.Canonical)); got != want {
+			t.Errorf("GOT: %#x; WANT: %#x", got, want)
+		}
+	}
+
+	ascending, err := NewCodec(`{"type":"record","name":"foo","fields":[{"name":"a","type":"int"}]}`)
+	ensureError(t, err)
+	descending, err := NewCodec(`{"type":"record","name":"foo","fields":[{"name":"a","type":"int","order":"descending"}]}`)
+	ensureError(t, err)
+	if ascending.Rabin != descending.Rabin {
+		t.Errorf("GOT: %#x; WANT: %#x", descending.Rabin, ascending.Rabin)
+	}
+	if ascending.RabinWithOrder() == descending.RabinWithOrder() {
+		t.Errorf("GOT: %#x; WANT: different fingerprints", descending.RabinWithOrder())
+	}
+	if ascending.RabinWithOrder() != ascending.Rabin {
+		t.Errorf("GOT: %#x; WANT: %#x", ascending.RabinWithOrder(), ascending.Rabin)
+	}
+}
@@ -0,0 +1,265 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// CBOR major types, from RFC 8949.
+const (
+	cborUnsigned byte = iota << 5
+	cborNegative
+	cborBytes
+	cborText
+	cborArray
+	cborMap
+	cborTag
+	cborSimple
+)
+
+////////////////////////////////////////
+// CBOR Encode
+////////////////////////////////////////
+
+// appendCBOR appends the CBOR encoding of a plain value to buf.
+func appendCBOR(buf []byte, value interface{}) []byte {
+	switch v := value.(type) {
+	case nil:
+		return append(buf, 0xf6)
+	case bool:
+		if v {
+			return append(buf, 0xf5)
+		}
+		return append(buf, 0xf4)
+	case int64:
+		if v < 0 {
+			return appendCBORHead(buf, cborNegative, uint64(-1-v))
+		}
+		return appendCBORHead(buf, cborUnsigned, uint64(v))
+	case float32:
+		buf = append(buf, 0xfa, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(buf[len(buf)-4:], math.Float32bits(v))
+		return buf
+	case float64:
+		buf = append(buf, 0xfb, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(buf[len(buf)-8:], math.Float64bits(v))
+		return buf
+	case string:
+		buf = appendCBORHead(buf, cborText, uint64(len(v)))
+		return append(buf, v...)
+	case []byte:
+		buf = appendCBORHead(buf, cborBytes, uint64(len(v)))
+		return append(buf, v...)
+	case []interface{}:
+		buf = appendCBORHead(buf, cborArray, uint64(len(v)))
+		for _, item := range v {
+			buf = appendCBOR(buf, item)
+		}
+		return buf
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		buf = appendCBORHead(buf, cborMap, uint64(len(keys)))
+		for _, k := range keys {
+			buf = appendCBOR(buf, k)
+			buf = appendCBOR(buf, v[k])
+		}
+		return buf
+	case *plainRecord:
+		buf = appendCBORHead(buf, cborMap, uint64(len(v.names)))
+		for i, k := range v.names {
+			buf = appendCBOR(buf, k)
+			buf = appendCBOR(buf, v.values[i])
+		}
+		return buf
+	default:
+		// NOTE: plain values are only ever created by plainFromBinary
+		panic(fmt.Errorf("should not get here: cannot encode CBOR: unexpected type: %T", value))
+	}
+}
+
+// appendCBORHead appends the initial byte and argument of a data item of the
+// provided major type, using the shortest encoding of the argument.
+func appendCBORHead(buf []byte, major byte, argument uint64) []byte {
+	switch {
+	case argument < 24:
+		return append(buf, major|byte(argument))
+	case argument <= math.MaxUint8:
+		return append(buf, major|24, byte(argument))
+	case argument <= math.MaxUint16:
+		return append(buf, major|25, byte(argument>>8), byte(argument))
+	case argument <= math.MaxUint32:
+		buf = append(buf, major|26, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(buf[len(buf)-4:], uint32(argument))
+		return buf
+	default:
+		buf = append(buf, major|27, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(buf[len(buf)-8:], argument)
+		return buf
+	}
+}
+
+////////////////////////////////////////
+// CBOR Decode
+////////////////////////////////////////
+
+// plainFromCBOR decodes one CBOR data item from buf to its plain
+// representation. Tags are skipped, and indefinite length items are not
+// supported.
+func plainFromCBOR(buf []byte) (interface{}, []byte, error) {
+	if len(buf) < 1 {
+		return nil, nil, fmt.Errorf("cannot decode CBOR: %s", io.ErrShortBuffer)
+	}
+	major, info := buf[0]&0xe0, buf[0]&0x1f
+
+	if major == cborSimple {
+		buf = buf[1:]
+		switch info {
+		case 20:
+			return false, buf, nil
+		case 21:
+			return true, buf, nil
+		case 22, 23: // null and undefined
+			return nil, buf, nil
+		case 25:
+			if len(buf) < 2 {
+				return nil, nil, fmt.Errorf("cannot decode CBOR float16: %s", io.ErrShortBuffer)
+			}
+			return float32FromFloat16(binary.BigEndian.Uint16(buf)), buf[2:], nil
+		case 26:
+			if len(buf) < 4 {
+				return nil, nil, fmt.Errorf("cannot decode CBOR float32: %s", io.ErrShortBuffer)
+			}
+			return math.Float32frombits(binary.BigEndian.Uint32(buf)), buf[4:], nil
+		case 27:
+			if len(buf) < 8 {
+				return nil, nil, fmt.Errorf("cannot decode CBOR float64: %s", io.ErrShortBuffer)
+			}
+			return math.Float64frombits(binary.BigEndian.Uint64(buf)), buf[8:], nil
+		}
+		return nil, nil, fmt.Errorf("cannot decode CBOR: unsupported simple value: %d", info)
+	}
+
+	argument, buf, err := cborArgument(buf)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch major {
+	case cborUnsigned:
+		if argument <= math.MaxInt64 {
+			return int64(argument), buf, nil
+		}
+		return argument, buf, nil
+	case cborNegative:
+		if argument > math.MaxInt64 {
+			return nil, nil, fmt.Errorf("cannot decode CBOR negative integer: value exceeds int64 range: -1-%d", argument)
+		}
+		return -1 - int64(argument), buf, nil
+	case cborBytes, cborText:
+		if argument > uint64(MaxBlockSize) {
+			return nil, nil, fmt.Errorf("cannot decode CBOR when length exceeds MaxBlockSize: %d > %d", argument, MaxBlockSize)
+		}
+		if uint64(len(buf)) < argument {
+			return nil, nil, fmt.Errorf("cannot decode CBOR string: %s", io.ErrShortBuffer)
+		}
+		if major == cborText {
+			return string(buf[:argument]), buf[argument:], nil
+		}
+		return buf[:argument], buf[argument:], nil
+	case cborArray:
+		if argument > uint64(MaxBlockCount) {
+			return nil, nil, fmt.Errorf("cannot decode CBOR array when length exceeds MaxBlockCount: %d > %d", argument, MaxBlockCount)
+		}
+		length := int(argument)
+		items := make([]interface{}, 0, minInt(length, len(buf)))
+		for i := 0; i < length; i++ {
+			var item interface{}
+			if item, buf, err = plainFromCBOR(buf); err != nil {
+				return nil, nil, fmt.Errorf("cannot decode CBOR array item %d: %s", i+1, err)
+			}
+			items = append(items, item)
+		}
+		return items, buf, nil
+	case cborMap:
+		if argument > uint64(MaxBlockCount) {
+			return nil, nil, fmt.Errorf("cannot decode CBOR map when length exceeds MaxBlockCount: %d > %d", argument, MaxBlockCount)
+		}
+		length := int(argument)
+		values := make(map[string]interface{}, minInt(length, len(buf)))
+		for i := 0; i < length; i++ {
+			var key, value interface{}
+			if key, buf, err = plainFromCBOR(buf); err != nil {
+				return nil, nil, fmt.Errorf("cannot decode CBOR map key: %s", err)
+			}
+			k, ok := key.(string)
+			if !ok {
+				return nil, nil, fmt.Errorf("cannot decode CBOR map key: expected string; received: %T", key)
+			}
+			if value, buf, err = plainFromCBOR(buf); err != nil {
+				return nil, nil, fmt.Errorf("cannot decode CBOR map value for key %q: %s", k, err)
+			}
+			values[k] = value
+		}
+		return values, buf, nil
+	default: // tag: decode the tagged item as though it were not tagged
+		return plainFromCBOR(buf)
+	}
+}
+
+// cborArgument returns the argument of the data item head at the start of buf,
+// and the bytes following the head.
+func cborArgument(buf []byte) (uint64, []byte, error) {
+	info := buf[0] & 0x1f
+	buf = buf[1:]
+	if info < 24 {
+		return uint64(info), buf, nil
+	}
+	if info > 27 {
+		if info == 31 {
+			return 0, nil, fmt.Errorf("cannot decode CBOR: unsupported indefinite length item")
+		}
+		return 0, nil, fmt.Errorf("cannot decode CBOR: invalid additional information: %d", info)
+	}
+	size := 1 << (info - 24)
+	if len(buf) < size {
+		return 0, nil, fmt.Errorf("cannot decode CBOR argument: %s", io.ErrShortBuffer)
+	}
+	return messagePackUint(buf[:size]), buf[size:], nil
+}
+
+// float32FromFloat16 converts an IEEE 754 half precision value to float32.
+func float32FromFloat16(h uint16) float32 {
+	sign := uint32(h>>15) << 31
+	exponent := uint32(h>>10) & 0x1f
+	mantissa := uint32(h) & 0x3ff
+
+	switch exponent {
+	case 0:
+		// zero and subnormal values
+		f := float32(mantissa) / (1 << 24)
+		if sign != 0 {
+			f = -f
+		}
+		return f
+	case 0x1f:
+		// infinity and NaN
+		return math.Float32frombits(sign | 0x7f800000 | mantissa<<13)
+	}
+	return math.Float32frombits(sign | (exponent+112)<<23 | mantissa<<13)
+}
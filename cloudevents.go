@@ -0,0 +1,346 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// CloudEventsAvroContentType is the media type of CloudEvents encoded
+	// using the Avro event format, used as the content type of structured
+	// mode messages.
+	CloudEventsAvroContentType = "application/cloudevents+avro"
+
+	// CloudEventsAvroSchema is the Avro schema of the CloudEvents Avro event
+	// format, version 1.0.
+	CloudEventsAvroSchema = `{"namespace":"io.cloudevents","type":"record","name":"CloudEvent","version":"1.0","doc":"Avro Event Format for CloudEvents","fields":[{"name":"attribute","type":{"type":"map","values":["null","boolean","int","string","bytes"]}},{"name":"data","type":["bytes","null","boolean",{"type":"map","values":["null","boolean",{"type":"record","name":"CloudEventData","doc":"Representation of a JSON Value","fields":[{"name":"value","type":{"type":"map","values":"CloudEventData"}}]},"double","string"]},{"type":"array","items":"CloudEventData"},"double","string"]}]}`
+
+	cloudEventsDataName = "io.cloudevents.CloudEventData"
+)
+
+var cloudEventsCodec *Codec
+
+func init() {
+	cloudEventsCodec, _ = NewCodec(CloudEventsAvroSchema)
+}
+
+// CloudEvent is a CloudEvent in the form used by the Avro event format.
+type CloudEvent struct {
+	// Attributes holds the context attributes of the event, including the
+	// required `specversion`, `id`, `source`, and `type` attributes, and any
+	// optional and extension attributes. Decoded attribute values are nil,
+	// bool, int32, string, or []byte. When encoding, other integer types that
+	// fit in an int32 are also accepted, as are time.Time values, which are
+	// encoded as RFC 3339 strings, and *url.URL values.
+	Attributes map[string]interface{}
+
+	// Data is the event payload. It is either nil, []byte for binary data, or
+	// a JSON value: bool, float64, string, map[string]interface{}, or
+	// []interface{}. Other numeric types, and json.Number, are accepted when
+	// encoding.
+	//
+	// The format represents JSON values with limited nesting: objects nested
+	// within objects or arrays may only contain objects, and arrays may only
+	// contain objects. Such values are decoded as map[string]interface{}.
+	Data interface{}
+}
+
+// cloudEventsRequiredAttributes are the context attributes every CloudEvent
+// ought to have.
+var cloudEventsRequiredAttributes = []string{"specversion", "id", "source", "type"}
+
+// BinaryFromCloudEvent appends the Avro event format encoding of the CloudEvent
+// to buf. It returns an error when a required context attribute is missing, or
+// when an attribute or the data cannot be represented by the event format.
+func BinaryFromCloudEvent(buf []byte, event *CloudEvent) ([]byte, error) {
+	for _, attribute := range cloudEventsRequiredAttributes {
+		if s, _ := event.Attributes[attribute].(string); s == "" {
+			return nil, fmt.Errorf("cannot encode CloudEvent: required attribute ought to be non-empty string: %q", attribute)
+		}
+	}
+	attributes := make(map[string]interface{}, len(event.Attributes))
+	for k, v := range event.Attributes {
+		value, err := cloudEventsAttributeValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("cannot encode CloudEvent attribute %q: %s", k, err)
+		}
+		attributes[k] = value
+	}
+	data, err := cloudEventsDataValue(event.Data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode CloudEvent data: %s", err)
+	}
+	return cloudEventsCodec.BinaryFromNative(buf, map[string]interface{}{"attribute": attributes, "data": data})
+}
+
+// CloudEventFromBinary decodes one CloudEvent from buf, encoded using the Avro
+// event format. On success, it returns the decoded event, the remaining
+// unread bytes of buf, and a nil error value. On error, it returns nil for
+// the event, the original buf, and the error message.
+func CloudEventFromBinary(buf []byte) (*CloudEvent, []byte, error) {
+	datum, newBuf, err := cloudEventsCodec.NativeFromBinary(buf)
+	if err != nil {
+		return nil, buf, fmt.Errorf("cannot decode CloudEvent: %s", err)
+	}
+	record := datum.(map[string]interface{})
+	event := &CloudEvent{Attributes: make(map[string]interface{})}
+	for k, v := range record["attribute"].(map[string]interface{}) {
+		event.Attributes[k] = cloudEventsUnwrap(v)
+	}
+	event.Data = cloudEventsNativeData(cloudEventsUnwrap(record["data"]))
+	return event, newBuf, nil
+}
+
+func cloudEventsAttributeValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case bool:
+		return Union("boolean", v), nil
+	case string:
+		return Union("string", v), nil
+	case []byte:
+		return Union("bytes", v), nil
+	case time.Time:
+		return Union("string", v.Format(time.RFC3339Nano)), nil
+	case *url.URL:
+		return Union("string", v.String()), nil
+	}
+	i, ok := cloudEventsInteger(value)
+	if !ok {
+		return nil, fmt.Errorf("unsupported type: %T", value)
+	}
+	if i < math.MinInt32 || i > math.MaxInt32 {
+		return nil, fmt.Errorf("integer ought to fit in an int32: %d", i)
+	}
+	return Union("int", int32(i)), nil
+}
+
+// cloudEventsInteger returns the value of a Go integer as an int64.
+func cloudEventsInteger(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	}
+	return 0, false
+}
+
+// cloudEventsNumber returns the value of a Go number as a float64.
+func cloudEventsNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	i, ok := cloudEventsInteger(value)
+	return float64(i), ok
+}
+
+// cloudEventsDataValue returns the native value of the data union.
+func cloudEventsDataValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return Union("bytes", v), nil
+	case bool:
+		return Union("boolean", v), nil
+	case string:
+		return Union("string", v), nil
+	case map[string]interface{}:
+		values := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			var err error
+			if values[k], err = cloudEventsObjectValue(item); err != nil {
+				return nil, fmt.Errorf("key %q: %s", k, err)
+			}
+		}
+		return Union("map", values), nil
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			var err error
+			if items[i], err = cloudEventsDataRecord(item); err != nil {
+				return nil, fmt.Errorf("item %d: %s", i+1, err)
+			}
+		}
+		return Union("array", items), nil
+	}
+	if f, ok := cloudEventsNumber(value); ok {
+		return Union("double", f), nil
+	}
+	return nil, fmt.Errorf("unsupported type: %T", value)
+}
+
+// cloudEventsObjectValue returns the native value of a member of the top level
+// data object.
+func cloudEventsObjectValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case bool:
+		return Union("boolean", v), nil
+	case string:
+		return Union("string", v), nil
+	case map[string]interface{}:
+		record, err := cloudEventsDataRecord(v)
+		if err != nil {
+			return nil, err
+		}
+		return Union(cloudEventsDataName, record), nil
+	}
+	if f, ok := cloudEventsNumber(value); ok {
+		return Union("double", f), nil
+	}
+	return nil, fmt.Errorf("unsupported type: %T", value)
+}
+
+// cloudEventsDataRecord returns the CloudEventData record of a nested object.
+func cloudEventsDataRecord(value interface{}) (interface{}, error) {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("nested value ought to be an object; received: %T", value)
+	}
+	values := make(map[string]interface{}, len(m))
+	for k, item := range m {
+		var err error
+		if values[k], err = cloudEventsDataRecord(item); err != nil {
+			return nil, fmt.Errorf("key %q: %s", k, err)
+		}
+	}
+	return map[string]interface{}{"value": values}, nil
+}
+
+// cloudEventsUnwrap returns the member value of a decoded union value.
+func cloudEventsUnwrap(value interface{}) interface{} {
+	if m, ok := value.(map[string]interface{}); ok {
+		for _, v := range m {
+			return v
+		}
+	}
+	return value
+}
+
+// cloudEventsNativeData converts a decoded data value to its plain JSON value.
+func cloudEventsNativeData(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		values := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			m, _ := item.(map[string]interface{}) // nil for null values
+			if record, ok := m[cloudEventsDataName]; ok {
+				values[k] = cloudEventsNativeObject(record)
+			} else {
+				values[k] = cloudEventsUnwrap(item)
+			}
+		}
+		return values
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = cloudEventsNativeObject(item)
+		}
+		return items
+	}
+	return value
+}
+
+// cloudEventsNativeObject converts a decoded CloudEventData record to the
+// object it represents.
+func cloudEventsNativeObject(record interface{}) interface{} {
+	fields := record.(map[string]interface{})["value"].(map[string]interface{})
+	values := make(map[string]interface{}, len(fields))
+	for k, item := range fields {
+		values[k] = cloudEventsNativeObject(item)
+	}
+	return values
+}
+
+// IsCloudEventsAvroContentType returns true when the provided Content-Type
+// header value is the media type of the CloudEvents Avro event format,
+// ignoring any parameters.
+func IsCloudEventsAvroContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == CloudEventsAvroContentType
+}
+
+// NegotiateCloudEventsAvroContentType returns CloudEventsAvroContentType and
+// true when the provided Accept header value permits the CloudEvents Avro
+// event format, and false otherwise. An empty Accept header permits any media
+// type. When several media ranges match, the most specific one determines
+// whether the format is acceptable, so for instance
+// `*/*, application/cloudevents+avro;q=0` does not permit it.
+func NegotiateCloudEventsAvroContentType(accept string) (string, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return CloudEventsAvroContentType, true
+	}
+	specificity, quality := -1, 0.0
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil {
+			continue
+		}
+		var s int
+		switch mediaType {
+		case CloudEventsAvroContentType:
+			s = 2
+		case "application/*":
+			s = 1
+		case "*/*":
+			s = 0
+		default:
+			continue
+		}
+		if s <= specificity {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		specificity, quality = s, q
+	}
+	if quality > 0 {
+		return CloudEventsAvroContentType, true
+	}
+	return "", false
+}
@@ -0,0 +1,115 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCloudEventRoundTrip(t *testing.T) {
+	when := time.Date(2018, 4, 5, 17, 31, 0, 0, time.UTC)
+
+	for _, data := range []interface{}{
+		nil,
+		[]byte("raw"),
+		"text",
+		2.5,
+		map[string]interface{}{
+			"name":    "x",
+			"ok":      true,
+			"count":   3.0,
+			"missing": nil,
+			"nested":  map[string]interface{}{"deeper": map[string]interface{}{}},
+		},
+		[]interface{}{map[string]interface{}{}, map[string]interface{}{"a": map[string]interface{}{}}},
+	} {
+		buf, err := BinaryFromCloudEvent(nil, &CloudEvent{
+			Attributes: map[string]interface{}{
+				"specversion": "1.0",
+				"id":          "A234-1234-1234",
+				"source":      "/mycontext",
+				"type":        "com.example.someevent",
+				"time":        when,
+				"attempt":     2,
+				"sampled":     true,
+				"trace":       []byte{1, 2},
+				"unset":       nil,
+			},
+			Data: data,
+		})
+		ensureError(t, err)
+
+		event, rest, err := CloudEventFromBinary(buf)
+		ensureError(t, err)
+		if len(rest) != 0 {
+			t.Errorf("GOT: %v; WANT: %v", rest, []byte{})
+		}
+		wantAttributes := map[string]interface{}{
+			"specversion": "1.0",
+			"id":          "A234-1234-1234",
+			"source":      "/mycontext",
+			"type":        "com.example.someevent",
+			"time":        "2018-04-05T17:31:00Z",
+			"attempt":     int32(2),
+			"sampled":     true,
+			"trace":       []byte{1, 2},
+			"unset":       nil,
+		}
+		if !reflect.DeepEqual(event.Attributes, wantAttributes) {
+			t.Errorf("GOT: %v; WANT: %v", event.Attributes, wantAttributes)
+		}
+		if !reflect.DeepEqual(event.Data, data) {
+			t.Errorf("GOT: %#v; WANT: %#v", event.Data, data)
+		}
+	}
+}
+
+func TestCloudEventEncodeErrors(t *testing.T) {
+	attributes := map[string]interface{}{"specversion": "1.0", "id": "1", "source": "/s"}
+	_, err := BinaryFromCloudEvent(nil, &CloudEvent{Attributes: attributes})
+	ensureError(t, err, `required attribute`, `"type"`)
+
+	attributes["type"] = "t"
+	_, err = BinaryFromCloudEvent(nil, &CloudEvent{Attributes: attributes, Data: []interface{}{"not an object"}})
+	ensureError(t, err, "item 1", "nested value ought to be an object")
+
+	attributes["big"] = int64(1) << 40
+	_, err = BinaryFromCloudEvent(nil, &CloudEvent{Attributes: attributes})
+	ensureError(t, err, `attribute "big"`, "int32")
+}
+
+func TestCloudEventsContentType(t *testing.T) {
+	if !IsCloudEventsAvroContentType("application/cloudevents+avro; charset=utf-8") {
+		t.Errorf("GOT: %v; WANT: %v", false, true)
+	}
+	if IsCloudEventsAvroContentType("application/cloudevents+json") {
+		t.Errorf("GOT: %v; WANT: %v", true, false)
+	}
+
+	for accept, want := range map[string]bool{
+		"":                                      true,
+		"application/cloudevents+avro":          true,
+		"application/json, application/*;q=0.5": true,
+		"*/*":                                   true,
+		"application/json":                      false,
+		"*/*, application/cloudevents+avro;q=0": false,
+		"application/cloudevents+avro;q=0.1, */*;q=0": true,
+	} {
+		contentType, ok := NegotiateCloudEventsAvroContentType(accept)
+		if ok != want {
+			t.Errorf("Accept: %q; GOT: %v; WANT: %v", accept, ok, want)
+		}
+		if ok && contentType != CloudEventsAvroContentType {
+			t.Errorf("Accept: %q; GOT: %q; WANT: %q", accept, contentType, CloudEventsAvroContentType)
+		}
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"fmt"
 	"math"
 	"strconv"
+	"sync"
 )
 
 var (
@@ -45,7 +46,8 @@ var (
 // Codec supports decoding binary and text Avro data to Go native data types,
 // and conversely encoding Go native data types to binary or text Avro data. A
 // Codec is created as a stateless structure that can be safely used in multiple
-// go routines simultaneously.
+// go routines simultaneously. Its options are captured when it is created, and
+// cannot be changed afterwards; Codec.WithOptions returns a new Codec instead.
 type Codec struct {
 	soeHeader       []byte // single-object-encoding header
 	schemaOriginal  string
@@ -57,6 +59,50 @@ type Codec struct {
 	nativeFromBinary  func([]byte) (interface{}, []byte, error)
 	textualFromNative func([]byte, interface{}) ([]byte, error)
 
+	// recordFields is only populated for record codecs, and describes each
+	// field in the order it was declared in the schema.
+	recordFields []recordField
+
+	// unionMembers is only populated for union codecs, and holds the codec of
+	// each member in the order it was declared in the schema.
+	unionMembers []*Codec
+
+	// symbols is only populated for enum codecs, and lists the enum symbols
+	// in the order they were declared in the schema.
+	symbols []string
+
+	// enums maps the full name of each enum in the schema to its symbols.
+	enums map[string][]string
+
+	// option is a copy of the options used to build the codec, and
+	// parsedSchema is the schema it was built from, which are used to derive
+	// variants of the codec. The parsed schema is not modified once the first
+	// codec is built from it, so variants may be built concurrently.
+	option       CodecOption
+	parsedSchema interface{}
+
+	// schemaTree is the parsed representation of schemaOriginal, lazily built
+	// the first time it is required.
+	schemaTreeOnce sync.Once
+	schemaTree     *schemaNode
+	schemaTreeErr  error
+
+	// validatorRules holds the compiled validation annotations of the
+	// schema, lazily built the first time they are required.
+	validatorOnce  sync.Once
+	validatorRules *validator
+	validatorErr   error
+
+	// schemaCanonicalOrdered is the canonical form retaining the order of
+	// record fields, and rabinOrdered its fingerprint, lazily built the
+	// first time they are required.
+	schemaOrderedOnce      sync.Once
+	schemaCanonicalOrdered string
+	rabinOrdered           uint64
+
+	// fieldHooks applies the field hooks of option, when it has any.
+	fieldHooks *fieldHooks
+
 	Rabin uint64
 }
 
@@ -86,30 +132,94 @@ type Codec struct {
 //     if err != nil {
 //             fmt.Println(err)
 //     }
-func NewCodec(schemaSpecification string) (*Codec, error) {
+//
+// Options change how the Codec translates data, and are applied in order:
+//
+//     codec, err := goavro.NewCodec(`{"type":"map","values":"long"}`,
+//         goavro.WithNumericDecoding(goavro.NumericDecodingJSONNumber),
+//         goavro.WithOrderedMapDecoding(true),
+//     )
+func NewCodec(schemaSpecification string, opts ...Option) (*Codec, error) {
+	option := DefaultCodecOption()
+	for _, opt := range opts {
+		opt(option)
+	}
+	if err := option.validate(); err != nil {
+		return nil, fmt.Errorf("cannot create codec: %s", err)
+	}
+	return newCodec(schemaSpecification, option)
+}
+
+// newOptionSymbolTable returns a symbol table of the primitive and registered
+// logical type codecs, whose translations are modified as specified by option.
+// Both NewCodec and Codec.WithOptions build codecs from it, so the options have
+// the same effect on either.
+func newOptionSymbolTable(option *CodecOption) map[string]*Codec {
+	st := newSymbolTable()
+	addRegisteredLogicalTypes(st)
+	if option.ZeroCopyDecoding {
+		st["string"].nativeFromBinary = stringNativeFromBinaryNoCopy
+	}
+	if option.SentinelErrors {
+		applySentinelErrors(st, option.ZeroCopyDecoding)
+	}
+	applyNumericDecoding(st, option.NumericDecoding)
+	if option.TrustedEncoding {
+		applyTrustedEncoding(st)
+	}
+	return st
+}
+
+func newCodec(schemaSpecification string, option *CodecOption) (*Codec, error) {
 	var schema interface{}
 
+	if err := option.SchemaLimits.checkSize(schemaSpecification); err != nil {
+		return nil, fmt.Errorf("cannot create codec: %s", err)
+	}
 	if err := json.Unmarshal([]byte(schemaSpecification), &schema); err != nil {
 		return nil, fmt.Errorf("cannot unmarshal schema JSON: %s", err)
 	}
+	if err := option.SchemaLimits.checkSchema(schema); err != nil {
+		return nil, fmt.Errorf("cannot create codec: %s", err)
+	}
 
 	// bootstrap a symbol table with primitive type codecs for the new codec
-	st := newSymbolTable()
+	st := newOptionSymbolTable(option)
 
-	c, err := buildCodec(st, nullNamespace, schema)
+	c, err := buildCodec(st, nullNamespace, schema, option)
 	if err != nil {
 		return nil, err
 	}
-	c.schemaCanonical, err = parsingCanonicalForm(schema, "", make(map[string]string))
+	if err = checkDeferredDefaults(st, schema); err != nil {
+		return nil, err
+	}
+	c.schemaCanonical, err = parsingCanonicalForm(schema, "", make(map[string]string), false)
 	if err != nil {
 		return nil, err // should not get here because schema was validated above
 	}
 
+	c.enums = make(map[string][]string)
+	for _, cd := range st {
+		if cd.symbols != nil {
+			c.enums[cd.typeName.fullName] = cd.symbols
+		}
+	}
+
 	c.Rabin = rabin([]byte(c.schemaCanonical))
 	c.soeHeader = []byte{0xC3, 0x01, 0, 0, 0, 0, 0, 0, 0, 0}
 	binary.LittleEndian.PutUint64(c.soeHeader[2:], c.Rabin)
 
 	c.schemaOriginal = schemaSpecification
+	c.option = *option
+	c.parsedSchema = schema
+	if option.ValidateOnEncode {
+		if _, err = c.validator(); err != nil {
+			return nil, fmt.Errorf("cannot create codec: invalid validation annotation: %s", err)
+		}
+	}
+	if err = c.compileFieldHooks(); err != nil {
+		return nil, fmt.Errorf("cannot create codec: %s", err)
+	}
 	return c, nil
 }
 
@@ -278,6 +388,13 @@ func newSymbolTable() map[string]*Codec {
 //         // Output: []byte{0x2, 0x2, 0x0}
 //     }
 func (c *Codec) BinaryFromNative(buf []byte, datum interface{}) ([]byte, error) {
+	if err := c.validateOnEncode(datum); err != nil {
+		return buf, fmt.Errorf("cannot encode binary: %s", err)
+	}
+	datum, err := c.encodeHooks(datum)
+	if err != nil {
+		return buf, fmt.Errorf("cannot encode binary: %s", err)
+	}
 	newBuf, err := c.binaryFromNative(buf, datum)
 	if err != nil {
 		return buf, err // if error, return original byte slice
@@ -289,7 +406,9 @@ func (c *Codec) BinaryFromNative(buf []byte, datum interface{}) ([]byte, error)
 // slice in accordance with the Avro schema supplied when creating the Codec. On
 // success, it returns the decoded datum, a byte slice containing the remaining
 // undecoded bytes, and a nil error value. On error, it returns nil for
-// the datum value, the original byte slice, and the error message.
+// the datum value, the original byte slice, and the error message. Codecs
+// created with the TrailingBytes option may instead reject or discard the
+// remaining bytes.
 //
 //     func ExampleNativeFromBinary() {
 //         codec, err := goavro.NewCodec(`
@@ -316,13 +435,117 @@ func (c *Codec) BinaryFromNative(buf []byte, datum interface{}) ([]byte, error)
 //         // Output: map[next:map[LongList:map[next:map[LongList:map[next:<nil>]]]]]
 //     }
 func (c *Codec) NativeFromBinary(buf []byte) (interface{}, []byte, error) {
+	value, newBuf, err := c.nativeFromBinaryPrefix(buf)
+	if err != nil {
+		return nil, buf, err // if error, return original byte slice
+	}
+	if newBuf, err = c.option.TrailingBytes.handle("binary", newBuf, false); err != nil {
+		return nil, buf, err
+	}
+	return value, newBuf, nil
+}
+
+// nativeFromBinaryPrefix decodes the datum at the start of buf like
+// NativeFromBinary, but always returns the bytes following it, regardless of
+// the TrailingBytes option, for callers decoding successive data from buf.
+func (c *Codec) nativeFromBinaryPrefix(buf []byte) (interface{}, []byte, error) {
+	if err := c.checkDecodeLimits(buf); err != nil {
+		return nil, buf, fmt.Errorf("cannot decode binary: %s", err)
+	}
 	value, newBuf, err := c.nativeFromBinary(buf)
 	if err != nil {
 		return nil, buf, err // if error, return original byte slice
 	}
+	if value, err = c.decodeHooks(value); err != nil {
+		return nil, buf, fmt.Errorf("cannot decode binary: %s", err)
+	}
 	return value, newBuf, nil
 }
 
+// NativeFromBinaryInto decodes the binary encoded record at the start of buf
+// into dest, rather than into a newly allocated map, so consumers decoding many
+// records may recycle the same map between them. Each field of the record is
+// stored in dest, and keys of dest that are not fields of the record are
+// deleted. A field whose type is a record is decoded into the map dest already
+// holds for that field, if any, so the nested maps are recycled as well. On
+// success, it returns the bytes following the record. On error, it returns the
+// original byte slice, and dest may hold some fields of the record.
+//
+//     datum := make(map[string]interface{})
+//     for _, message := range messages {
+//         if _, err := codec.NativeFromBinaryInto(message, datum); err != nil {
+//             return err
+//         }
+//         process(datum) // ought not to retain datum
+//     }
+//
+// The schema of the codec ought to be a record.
+func (c *Codec) NativeFromBinaryInto(buf []byte, dest map[string]interface{}) ([]byte, error) {
+	if c.recordFields == nil {
+		return buf, fmt.Errorf("cannot decode binary into map: schema ought to be a record; received: %q", c.typeName)
+	}
+	if err := c.checkDecodeLimits(buf); err != nil {
+		return buf, fmt.Errorf("cannot decode binary: %s", err)
+	}
+	newBuf, err := c.recordNativeFromBinaryInto(buf, dest)
+	if err != nil {
+		return buf, err
+	}
+	datum, err := c.decodeHooks(dest)
+	if err != nil {
+		return buf, fmt.Errorf("cannot decode binary: %s", err)
+	}
+	if record, ok := datum.(map[string]interface{}); ok {
+		for k, v := range record {
+			dest[k] = v
+		}
+	}
+	if newBuf, err = c.option.TrailingBytes.handle("binary", newBuf, false); err != nil {
+		return buf, err
+	}
+	return newBuf, nil
+}
+
+// NativeFromBinaryFields decodes only the specified fields of the binary
+// encoded record at the start of buf, skipping over the other fields without
+// decoding them into native form, so consumers that need a few fields of large
+// records do not pay for decoding the whole record. The returned map holds the
+// specified fields only. On success, it returns the bytes following the
+// record. On error, it returns the original byte slice.
+//
+//     datum, _, err := codec.NativeFromBinaryFields(buf, []string{"id", "timestamp"})
+//     if err != nil {
+//         return err
+//     }
+//     fmt.Println(datum["id"], datum["timestamp"])
+//
+// The schema of the codec ought to be a record, and each specified field ought
+// to be one of its fields. Array and map fields written with their block sizes
+// are skipped without reading their items. See SkipBinary.
+func (c *Codec) NativeFromBinaryFields(buf []byte, fields []string) (map[string]interface{}, []byte, error) {
+	if c.recordFields == nil {
+		return nil, buf, fmt.Errorf("cannot decode binary fields: schema ought to be a record; received: %q", c.typeName)
+	}
+	if err := c.checkDecodeLimits(buf); err != nil {
+		return nil, buf, fmt.Errorf("cannot decode binary: %s", err)
+	}
+	datum, newBuf, err := c.recordNativeFromBinaryFields(buf, fields)
+	if err != nil {
+		return nil, buf, err
+	}
+	value, err := c.decodeHooks(datum)
+	if err != nil {
+		return nil, buf, fmt.Errorf("cannot decode binary: %s", err)
+	}
+	if record, ok := value.(map[string]interface{}); ok {
+		datum = record
+	}
+	if newBuf, err = c.option.TrailingBytes.handle("binary", newBuf, false); err != nil {
+		return nil, buf, err
+	}
+	return datum, newBuf, nil
+}
+
 // NativeFromSingle converts Avro data from Single-Object-Encoded format from
 // the provided byte slice to Go native data types in accordance with the Avro
 // schema supplied when creating the Codec.  On success, it returns the decoded
@@ -346,10 +569,19 @@ func (c *Codec) NativeFromSingle(buf []byte) (interface{}, []byte, error) {
 	if !bytes.Equal(buf[:len(c.soeHeader)], c.soeHeader) {
 		return nil, buf, ErrWrongCodec(fingerprint)
 	}
+	if err = c.checkDecodeLimits(newBuf); err != nil {
+		return nil, buf, fmt.Errorf("cannot decode single-object: %s", err)
+	}
 	value, newBuf, err := c.nativeFromBinary(newBuf)
 	if err != nil {
 		return nil, buf, err // if error, return original byte slice
 	}
+	if value, err = c.decodeHooks(value); err != nil {
+		return nil, buf, fmt.Errorf("cannot decode single-object: %s", err)
+	}
+	if newBuf, err = c.option.TrailingBytes.handle("single-object", newBuf, false); err != nil {
+		return nil, buf, err
+	}
 	return value, newBuf, nil
 }
 
@@ -358,7 +590,8 @@ func (c *Codec) NativeFromSingle(buf []byte) (interface{}, []byte, error) {
 // when creating the Codec. On success, it returns the decoded datum, along with
 // a new byte slice with the decoded bytes consumed, and a nil error value. On
 // error, it returns nil for the datum value, the original byte slice, and the
-// error message.
+// error message. Codecs created with the TrailingBytes option may instead
+// reject or discard the remaining bytes.
 //
 //     func ExampleNativeFromTextual() {
 //         codec, err := goavro.NewCodec(`
@@ -389,6 +622,12 @@ func (c *Codec) NativeFromTextual(buf []byte) (interface{}, []byte, error) {
 	if err != nil {
 		return nil, buf, err // if error, return original byte slice
 	}
+	if value, err = c.decodeHooks(value); err != nil {
+		return nil, buf, fmt.Errorf("cannot decode textual: %s", err)
+	}
+	if newBuf, err = c.option.TrailingBytes.handle("textual", newBuf, true); err != nil {
+		return nil, buf, err
+	}
 	return value, newBuf, nil
 }
 
@@ -417,6 +656,13 @@ func (c *Codec) NativeFromTextual(buf []byte) (interface{}, []byte, error) {
 //         // Output: [195 1 143 92 57 63 26 213 117 114 6]
 //     }
 func (c *Codec) SingleFromNative(buf []byte, datum interface{}) ([]byte, error) {
+	if err := c.validateOnEncode(datum); err != nil {
+		return buf, fmt.Errorf("cannot encode single-object: %s", err)
+	}
+	datum, err := c.encodeHooks(datum)
+	if err != nil {
+		return buf, fmt.Errorf("cannot encode single-object: %s", err)
+	}
 	newBuf, err := c.binaryFromNative(append(buf, c.soeHeader...), datum)
 	if err != nil {
 		return buf, err
@@ -464,6 +710,13 @@ func (c *Codec) SingleFromNative(buf []byte, datum interface{}) ([]byte, error)
 //         // Output: {"next":{"LongList":{"next":{"LongList":{"next":null}}}}}
 //     }
 func (c *Codec) TextualFromNative(buf []byte, datum interface{}) ([]byte, error) {
+	if err := c.validateOnEncode(datum); err != nil {
+		return buf, fmt.Errorf("cannot encode textual: %s", err)
+	}
+	datum, err := c.encodeHooks(datum)
+	if err != nil {
+		return buf, fmt.Errorf("cannot encode textual: %s", err)
+	}
 	newBuf, err := c.textualFromNative(buf, datum)
 	if err != nil {
 		return buf, err // if error, return original byte slice
@@ -482,6 +735,65 @@ func (c *Codec) CanonicalSchema() string {
 	return c.schemaCanonical
 }
 
+// CanonicalSchemaWithOrder returns the Parsing Canonical Form of the schema,
+// retaining the order attribute of record fields whose order is descending or
+// ignore. The Parsing Canonical Form strips the attribute, so schemas that sort
+// their data differently have the same form, which is not wanted when
+// fingerprinting schemas used to exchange sorted data. Schemas without such
+// fields have the same form as CanonicalSchema.
+//
+//     codec, err := goavro.NewCodec(`{"type":"record","name":"r","fields":[{"name":"f","type":"long","order":"descending"}]}`)
+//     if err != nil {
+//         fmt.Println(err)
+//     }
+//     fmt.Println(codec.CanonicalSchemaWithOrder())
+//     // Output: {"name":"r","type":"record","fields":[{"name":"f","type":"long","order":"descending"}]}
+func (c *Codec) CanonicalSchemaWithOrder() string {
+	c.schemaOrderedOnce.Do(func() {
+		c.schemaCanonicalOrdered = c.schemaCanonical
+		if c.parsedSchema != nil {
+			if form, err := parsingCanonicalForm(c.parsedSchema, "", make(map[string]string), true); err == nil {
+				c.schemaCanonicalOrdered = form
+			}
+		}
+		c.rabinOrdered = rabin([]byte(c.schemaCanonicalOrdered))
+	})
+	return c.schemaCanonicalOrdered
+}
+
+// RabinWithOrder returns the Rabin fingerprint of CanonicalSchemaWithOrder,
+// which is the same as the Rabin field for schemas without fields sorted in
+// descending order or ignored when sorting.
+func (c *Codec) RabinWithOrder() uint64 {
+	c.CanonicalSchemaWithOrder()
+	return c.rabinOrdered
+}
+
+// EnumSymbols returns the symbols of the enum with the specified full name,
+// declared anywhere in the Codec's schema, in the order of their indexes. It
+// returns false when the schema declares no such enum. When the Codec's schema
+// is itself an enum, its symbols are also returned for an empty name.
+//
+// This is the symbol table for codecs created with the EnumIndexDecoding
+// option, which decode enum values as indexes.
+//
+//     codec, err := goavro.NewCodecWithOptions(`{"type":"enum","name":"com.example.Suit","symbols":["SPADES","HEARTS"]}`,
+//         &goavro.CodecOption{EnumIndexDecoding: true})
+//     if err != nil {
+//         fmt.Println(err)
+//     }
+//     symbols, _ := codec.EnumSymbols("com.example.Suit") // [SPADES HEARTS]
+func (c *Codec) EnumSymbols(name string) ([]string, bool) {
+	if name == "" && c.symbols != nil {
+		return append([]string(nil), c.symbols...), true
+	}
+	symbols, ok := c.enums[name]
+	if !ok {
+		return nil, false
+	}
+	return append([]string(nil), symbols...), true
+}
+
 // SchemaCRC64Avro returns a signed 64-bit integer Rabin fingerprint for the
 // canonical schema.  This method returns the signed 64-bit cast of the unsigned
 // 64-bit schema Rabin fingerprint.
@@ -494,21 +806,21 @@ func (c *Codec) SchemaCRC64Avro() int64 {
 
 // convert a schema data structure to a codec, prefixing with specified
 // namespace
-func buildCodec(st map[string]*Codec, enclosingNamespace string, schema interface{}) (*Codec, error) {
+func buildCodec(st map[string]*Codec, enclosingNamespace string, schema interface{}, option *CodecOption) (*Codec, error) {
 	switch schemaType := schema.(type) {
 	case map[string]interface{}:
-		return buildCodecForTypeDescribedByMap(st, enclosingNamespace, schemaType)
+		return buildCodecForTypeDescribedByMap(st, enclosingNamespace, schemaType, option)
 	case string:
-		return buildCodecForTypeDescribedByString(st, enclosingNamespace, schemaType, nil)
+		return buildCodecForTypeDescribedByString(st, enclosingNamespace, schemaType, nil, option)
 	case []interface{}:
-		return buildCodecForTypeDescribedBySlice(st, enclosingNamespace, schemaType)
+		return buildCodecForTypeDescribedBySlice(st, enclosingNamespace, schemaType, option)
 	default:
 		return nil, fmt.Errorf("unknown schema type: %T", schema)
 	}
 }
 
 // Reach into the map, grabbing its "type". Use that to create the codec.
-func buildCodecForTypeDescribedByMap(st map[string]*Codec, enclosingNamespace string, schemaMap map[string]interface{}) (*Codec, error) {
+func buildCodecForTypeDescribedByMap(st map[string]*Codec, enclosingNamespace string, schemaMap map[string]interface{}, option *CodecOption) (*Codec, error) {
 	t, ok := schemaMap["type"]
 	if !ok {
 		return nil, fmt.Errorf("missing type: %v", schemaMap)
@@ -522,17 +834,17 @@ func buildCodecForTypeDescribedByMap(st map[string]*Codec, enclosingNamespace st
 		// EXAMPLE: "type":"int"
 		// EXAMPLE: "type":"record"
 		// EXAMPLE: "type":"somePreviouslyDefinedCustomTypeString"
-		return buildCodecForTypeDescribedByString(st, enclosingNamespace, v, schemaMap)
+		return buildCodecForTypeDescribedByString(st, enclosingNamespace, v, schemaMap, option)
 	case map[string]interface{}:
-		return buildCodecForTypeDescribedByMap(st, enclosingNamespace, v)
+		return buildCodecForTypeDescribedByMap(st, enclosingNamespace, v, option)
 	case []interface{}:
-		return buildCodecForTypeDescribedBySlice(st, enclosingNamespace, v)
+		return buildCodecForTypeDescribedBySlice(st, enclosingNamespace, v, option)
 	default:
 		return nil, fmt.Errorf("type ought to be either string, map[string]interface{}, or []interface{}; received: %T", t)
 	}
 }
 
-func buildCodecForTypeDescribedByString(st map[string]*Codec, enclosingNamespace string, typeName string, schemaMap map[string]interface{}) (*Codec, error) {
+func buildCodecForTypeDescribedByString(st map[string]*Codec, enclosingNamespace string, typeName string, schemaMap map[string]interface{}, option *CodecOption) (*Codec, error) {
 	isLogicalType := false
 	searchType := typeName
 	// logicalType will be non-nil for those fields without a logicalType property set
@@ -557,23 +869,23 @@ func buildCodecForTypeDescribedByString(st map[string]*Codec, enclosingNamespace
 	// There are only a small handful of complex Avro data types.
 	switch searchType {
 	case "array":
-		return makeArrayCodec(st, enclosingNamespace, schemaMap)
+		return makeArrayCodec(st, enclosingNamespace, schemaMap, option)
 	case "enum":
-		return makeEnumCodec(st, enclosingNamespace, schemaMap)
+		return makeEnumCodec(st, enclosingNamespace, schemaMap, option)
 	case "fixed":
-		return makeFixedCodec(st, enclosingNamespace, schemaMap)
+		return makeFixedCodec(st, enclosingNamespace, schemaMap, option)
 	case "map":
-		return makeMapCodec(st, enclosingNamespace, schemaMap)
+		return makeMapCodec(st, enclosingNamespace, schemaMap, option)
 	case "record":
-		return makeRecordCodec(st, enclosingNamespace, schemaMap)
+		return makeRecordCodec(st, enclosingNamespace, schemaMap, option)
 	case "bytes.decimal":
-		return makeDecimalBytesCodec(st, enclosingNamespace, schemaMap)
+		return makeDecimalBytesCodec(st, enclosingNamespace, schemaMap, option)
 	case "fixed.decimal":
-		return makeDecimalFixedCodec(st, enclosingNamespace, schemaMap)
+		return makeDecimalFixedCodec(st, enclosingNamespace, schemaMap, option)
 	default:
 		if isLogicalType {
 			delete(schemaMap, "logicalType")
-			return buildCodecForTypeDescribedByString(st, enclosingNamespace, typeName, schemaMap)
+			return buildCodecForTypeDescribedByString(st, enclosingNamespace, typeName, schemaMap, option)
 		}
 		return nil, fmt.Errorf("unknown type name: %q", searchType)
 	}
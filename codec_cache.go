@@ -0,0 +1,238 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"container/list"
+	"encoding/json"
+	"sync"
+)
+
+// CodecCache holds codecs built from schema specifications, so services that
+// receive the same schemas over and over build each codec once. Schemas that
+// differ only in their doc and aliases attributes, or in their whitespace and
+// the order of their JSON properties, share a single Codec, whose Schema method
+// returns the first such specification requested. When the cache is full, the
+// least recently used codec is evicted. A CodecCache is safe for concurrent use.
+//
+//     cache := goavro.NewCodecCache(1024, goavro.WithOrderedMapDecoding(true))
+//     for _, message := range messages {
+//         codec, err := cache.Codec(message.Schema)
+//         if err != nil {
+//             return err
+//         }
+//         datum, _, err := codec.NativeFromBinary(message.Value)
+//         // ...
+//     }
+//
+// Because aliases are only used when resolving data written with a different
+// schema, codecs used as reader schemas of a Resolution whose fields are
+// renamed ought to be built with NewCodec.
+type CodecCache struct {
+	mu           sync.Mutex
+	capacity     int
+	opts         []Option
+	schemas      map[string]*list.Element // specification to entry
+	forms        map[string]*list.Element // identity form to entry
+	fingerprints map[uint64]*list.Element // Rabin fingerprint to most recently built entry
+	lru          *list.List               // of *codecCacheEntry, most recently used first
+	hits         uint64
+	shared       uint64
+	misses       uint64
+	evictions    uint64
+}
+
+type codecCacheEntry struct {
+	form    string
+	schemas []string // specifications resolved to the codec
+	codec   *Codec
+}
+
+// CodecCacheStats reports the use of a CodecCache.
+type CodecCacheStats struct {
+	Hits      uint64 // requests for a specification or fingerprint already seen
+	Shared    uint64 // requests for a new specification answered by a cached codec
+	Misses    uint64 // requests that built a codec
+	Evictions uint64 // codecs evicted to respect the capacity
+	Len       int    // number of cached codecs
+	Capacity  int    // maximum number of cached codecs, or 0 when unbounded
+}
+
+// NewCodecCache returns a CodecCache holding at most capacity codecs, built
+// using the specified options. When capacity is 0 or less, the cache is
+// unbounded.
+func NewCodecCache(capacity int, opts ...Option) *CodecCache {
+	if capacity < 0 {
+		capacity = 0
+	}
+	return &CodecCache{
+		capacity:     capacity,
+		opts:         append([]Option(nil), opts...),
+		schemas:      make(map[string]*list.Element),
+		forms:        make(map[string]*list.Element),
+		fingerprints: make(map[uint64]*list.Element),
+		lru:          list.New(),
+	}
+}
+
+// Codec returns a Codec for the schema specification, building it when the
+// cache does not hold a codec for an equivalent schema. Errors are not cached.
+func (cc *CodecCache) Codec(schemaSpecification string) (*Codec, error) {
+	cc.mu.Lock()
+	if element, ok := cc.schemas[schemaSpecification]; ok {
+		cc.hits++
+		cc.lru.MoveToFront(element)
+		cc.mu.Unlock()
+		return element.Value.(*codecCacheEntry).codec, nil
+	}
+	cc.mu.Unlock()
+
+	// NOTE: Build without holding the lock, so other schemas are not blocked.
+	// The schema must be parsed to tell whether an equivalent codec exists.
+	codec, err := NewCodec(schemaSpecification, cc.opts...)
+	if err != nil {
+		return nil, err
+	}
+	form, err := codecIdentityForm(codec.parsedSchema)
+	if err != nil {
+		return nil, err // should not get here because schema was parsed from JSON
+	}
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return cc.add(form, schemaSpecification, codec), nil
+}
+
+// add records that the schema specification resolves to the codec, unless the
+// cache already holds a codec of the same identity form, which it then
+// returns. The caller holds the lock.
+func (cc *CodecCache) add(form, schemaSpecification string, codec *Codec) *Codec {
+	if element, ok := cc.forms[form]; ok {
+		entry := element.Value.(*codecCacheEntry)
+		if _, ok := cc.schemas[schemaSpecification]; !ok {
+			entry.schemas = append(entry.schemas, schemaSpecification)
+			cc.schemas[schemaSpecification] = element
+		}
+		cc.shared++
+		cc.lru.MoveToFront(element)
+		return entry.codec
+	}
+	cc.misses++
+	element := cc.lru.PushFront(&codecCacheEntry{form: form, schemas: []string{schemaSpecification}, codec: codec})
+	cc.forms[form] = element
+	cc.schemas[schemaSpecification] = element
+	cc.fingerprints[codec.Rabin] = element
+	if cc.capacity > 0 && cc.lru.Len() > cc.capacity {
+		oldest := cc.lru.Back()
+		cc.lru.Remove(oldest)
+		entry := oldest.Value.(*codecCacheEntry)
+		delete(cc.forms, entry.form)
+		for _, schema := range entry.schemas {
+			delete(cc.schemas, schema)
+		}
+		if cc.fingerprints[entry.codec.Rabin] == oldest {
+			delete(cc.fingerprints, entry.codec.Rabin)
+			// NOTE: Another cached codec may have the fingerprint, in which
+			// case the most recently used one replaces the evicted codec.
+			for element := cc.lru.Front(); element != nil; element = element.Next() {
+				if element.Value.(*codecCacheEntry).codec.Rabin == entry.codec.Rabin {
+					cc.fingerprints[entry.codec.Rabin] = element
+					break
+				}
+			}
+		}
+		cc.evictions++
+	}
+	return codec
+}
+
+// CodecByFingerprint returns the cached codec whose schema has the Rabin
+// fingerprint, such as the fingerprint of single-object encoded data, so data
+// written with a schema seen before may be decoded without querying a schema
+// registry. When several cached codecs have the fingerprint, because their
+// schemas differ only in the attributes the Parsing Canonical Form omits, such
+// as defaults, the most recently built one is returned.
+func (cc *CodecCache) CodecByFingerprint(fingerprint uint64) (*Codec, bool) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	element, ok := cc.fingerprints[fingerprint]
+	if !ok {
+		return nil, false
+	}
+	cc.hits++
+	cc.lru.MoveToFront(element)
+	return element.Value.(*codecCacheEntry).codec, true
+}
+
+// codecs returns the cached codecs, from the most to the least recently used.
+func (cc *CodecCache) codecs() []*Codec {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	codecs := make([]*Codec, 0, cc.lru.Len())
+	for element := cc.lru.Front(); element != nil; element = element.Next() {
+		codecs = append(codecs, element.Value.(*codecCacheEntry).codec)
+	}
+	return codecs
+}
+
+// Stats returns the number of hits, shared codecs, misses, and evictions since
+// the cache was created, along with its current size and capacity.
+func (cc *CodecCache) Stats() CodecCacheStats {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return CodecCacheStats{
+		Hits:      cc.hits,
+		Shared:    cc.shared,
+		Misses:    cc.misses,
+		Evictions: cc.evictions,
+		Len:       cc.lru.Len(),
+		Capacity:  cc.capacity,
+	}
+}
+
+// codecIdentityForm returns the JSON encoding of the parsed schema without its
+// doc and aliases attributes. Unlike the Parsing Canonical Form, it retains
+// defaults and logical types, which change how a Codec translates data, so
+// schemas with the same identity form build interchangeable codecs.
+func codecIdentityForm(schema interface{}) (string, error) {
+	// NOTE: encoding/json sorts the keys of maps, so the form does not depend
+	// on the order of the properties of the specification.
+	buf, err := json.Marshal(stripDocAndAliases(schema))
+	if err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+func stripDocAndAliases(schema interface{}) interface{} {
+	switch val := schema.(type) {
+	case map[string]interface{}:
+		stripped := make(map[string]interface{}, len(val))
+		for k, v := range val {
+			switch k {
+			case "doc", "aliases":
+				continue
+			case "default":
+				stripped[k] = v // a datum rather than a schema
+			default:
+				stripped[k] = stripDocAndAliases(v)
+			}
+		}
+		return stripped
+	case []interface{}:
+		stripped := make([]interface{}, len(val))
+		for i, v := range val {
+			stripped[i] = stripDocAndAliases(v)
+		}
+		return stripped
+	default:
+		return schema
+	}
+}
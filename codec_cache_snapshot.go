@@ -0,0 +1,145 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"fmt"
+	"io"
+)
+
+// CodecCacheSnapshotSchema is the schema of the items of a CodecCache snapshot,
+// written by WriteSnapshot. Each item describes one cached codec: the Rabin
+// fingerprint of its schema, and the schema specifications resolved to it, the
+// first of which the codec was built from.
+const CodecCacheSnapshotSchema = `{
+  "type": "record",
+  "name": "CodecCacheEntry",
+  "namespace": "com.linkedin.goavro",
+  "fields": [
+    {"name": "fingerprint", "type": "long"},
+    {"name": "schemas", "type": {"type": "array", "items": "string"}}
+  ]
+}`
+
+// WriteSnapshot writes the schemas of the cached codecs to w, as an OCF whose
+// items conform to CodecCacheSnapshotSchema, so a later process may warm its
+// cache using LoadSnapshot, and decode the data of those schemas without
+// querying a schema registry, such as while the registry is unavailable.
+//
+//     f, err := os.Create("schemas.avro")
+//     if err != nil {
+//         return err
+//     }
+//     if err = cache.WriteSnapshot(f); err != nil {
+//         f.Close()
+//         return err
+//     }
+//     return f.Close()
+//
+// Codecs are written from the least to the most recently used, so loading the
+// snapshot into a cache of smaller capacity keeps the most recently used ones.
+// The codecs themselves are not written, so options are not recorded.
+func (cc *CodecCache) WriteSnapshot(w io.Writer) error {
+	cc.mu.Lock()
+	items := make([]interface{}, 0, cc.lru.Len())
+	for element := cc.lru.Back(); element != nil; element = element.Prev() {
+		entry := element.Value.(*codecCacheEntry)
+		schemas := make([]interface{}, len(entry.schemas))
+		for i, schema := range entry.schemas {
+			schemas[i] = schema
+		}
+		items = append(items, map[string]interface{}{"fingerprint": int64(entry.codec.Rabin), "schemas": schemas})
+	}
+	cc.mu.Unlock()
+
+	ocfw, err := NewOCFWriter(OCFConfig{W: w, Schema: CodecCacheSnapshotSchema})
+	if err != nil {
+		return fmt.Errorf("cannot write codec cache snapshot: %s", err)
+	}
+	if len(items) > 0 {
+		if err = ocfw.Append(items); err != nil {
+			return fmt.Errorf("cannot write codec cache snapshot: %s", err)
+		}
+	}
+	if err = ocfw.Close(); err != nil {
+		return fmt.Errorf("cannot write codec cache snapshot: %s", err)
+	}
+	return nil
+}
+
+// LoadSnapshot reads a snapshot written by WriteSnapshot from r, and adds the
+// codecs it describes to the cache, building them using the options of the
+// cache. A codec whose schema no longer has the fingerprint recorded in the
+// snapshot is rejected, as it would not decode the data the fingerprint refers
+// to. When an item cannot be loaded, LoadSnapshot returns an error, and the
+// codecs of the items preceding it remain cached. Loaded codecs are not counted
+// as misses, although codecs evicted to respect the capacity are counted as
+// evictions.
+func (cc *CodecCache) LoadSnapshot(r io.Reader) error {
+	ocfr, err := NewOCFReader(r)
+	if err != nil {
+		return fmt.Errorf("cannot load codec cache snapshot: %s", err)
+	}
+	if ocfr.Codec().Rabin != codecCacheSnapshotCodec.Rabin {
+		return fmt.Errorf("cannot load codec cache snapshot: schema ought to be CodecCacheSnapshotSchema; received: %s", ocfr.Codec().Schema())
+	}
+	for item := 0; ocfr.Scan(); item++ {
+		datum, err := ocfr.Read()
+		if err != nil {
+			return fmt.Errorf("cannot load codec cache snapshot item %d: %s", item, err)
+		}
+		if err = cc.loadSnapshotItem(datum.(map[string]interface{})); err != nil {
+			return fmt.Errorf("cannot load codec cache snapshot item %d: %s", item, err)
+		}
+	}
+	if err = ocfr.Err(); err != nil {
+		return fmt.Errorf("cannot load codec cache snapshot: %s", err)
+	}
+	return nil
+}
+
+var codecCacheSnapshotCodec *Codec
+
+func init() {
+	codecCacheSnapshotCodec, _ = NewCodec(CodecCacheSnapshotSchema)
+}
+
+func (cc *CodecCache) loadSnapshotItem(item map[string]interface{}) error {
+	fingerprint := uint64(item["fingerprint"].(int64)) // NOTE: ensured by schema of snapshot
+	schemas := item["schemas"].([]interface{})
+	if len(schemas) == 0 {
+		return fmt.Errorf("fingerprint %#016x ought to have at least one schema", fingerprint)
+	}
+	codec, err := NewCodec(schemas[0].(string), cc.opts...)
+	if err != nil {
+		return err
+	}
+	if codec.Rabin != fingerprint {
+		return fmt.Errorf("schema fingerprint changed: %#016x != %#016x", codec.Rabin, fingerprint)
+	}
+	form, err := codecIdentityForm(codec.parsedSchema)
+	if err != nil {
+		return err // should not get here because schema was parsed from JSON
+	}
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	hits, shared, misses := cc.hits, cc.shared, cc.misses
+	for _, schema := range schemas {
+		// NOTE: The other specifications were resolved to the codec when
+		// the snapshot was written, so are not parsed again.
+		cc.add(form, schema.(string), codec)
+	}
+	cc.hits, cc.shared, cc.misses = hits, shared, misses
+	return nil
+}
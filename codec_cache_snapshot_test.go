@@ -0,0 +1,91 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCodecCacheSnapshot(t *testing.T) {
+	cache := NewCodecCache(0)
+	for _, schema := range []string{`{"type":"int"}`, `{"type":"int","doc":"x"}`, `{"type":"enum","name":"e","symbols":["A","B"]}`, `"string"`} {
+		_, err := cache.Codec(schema)
+		ensureError(t, err)
+	}
+	enum, err := cache.Codec(`{"type":"enum","name":"e","symbols":["A","B"]}`) // most recently used
+	ensureError(t, err)
+
+	var snapshot bytes.Buffer
+	ensureError(t, cache.WriteSnapshot(&snapshot))
+
+	// a smaller cache keeps the most recently used codecs
+	warm := NewCodecCache(2, WithEnumIndexDecoding(true))
+	ensureError(t, warm.LoadSnapshot(bytes.NewReader(snapshot.Bytes())))
+	if actual, expected := warm.Stats(), (CodecCacheStats{Evictions: 1, Len: 2, Capacity: 2}); actual != expected {
+		t.Errorf("GOT: %+v; WANT: %+v", actual, expected)
+	}
+	codec, ok := warm.CodecByFingerprint(enum.Rabin)
+	if !ok {
+		t.Fatalf("GOT: %v; WANT: %v", ok, true)
+	}
+	datum, _, err := codec.NativeFromBinary([]byte{0x02})
+	ensureError(t, err)
+	if actual, expected := datum, 1; actual != expected {
+		t.Errorf("GOT: %v (%T); WANT: %v", actual, actual, expected)
+	}
+	if _, ok = warm.CodecByFingerprint(cachedCodec(t, cache, `{"type":"int"}`).Rabin); ok {
+		t.Errorf("GOT: %v; WANT: %v", ok, false)
+	}
+
+	// every specification resolved to a codec is restored
+	all := NewCodecCache(0)
+	ensureError(t, all.LoadSnapshot(bytes.NewReader(snapshot.Bytes())))
+	first := cachedCodec(t, all, `{"type":"int"}`)
+	if second := cachedCodec(t, all, `{"type":"int","doc":"x"}`); second != first {
+		t.Errorf("GOT: %p; WANT: %p", second, first)
+	}
+	if actual, expected := all.Stats(), (CodecCacheStats{Hits: 2, Len: 3}); actual != expected {
+		t.Errorf("GOT: %+v; WANT: %+v", actual, expected)
+	}
+}
+
+func cachedCodec(t *testing.T, cc *CodecCache, schema string) *Codec {
+	t.Helper()
+	codec, err := cc.Codec(schema)
+	ensureError(t, err)
+	return codec
+}
+
+func TestCodecCacheSnapshotInvalid(t *testing.T) {
+	t.Run("schema", func(t *testing.T) {
+		var buf bytes.Buffer
+		ocfw, err := NewOCFWriter(OCFConfig{W: &buf, Schema: `"long"`})
+		ensureError(t, err)
+		ensureError(t, ocfw.Close())
+		err = NewCodecCache(0).LoadSnapshot(&buf)
+		ensureError(t, err, "schema ought to be CodecCacheSnapshotSchema")
+	})
+
+	t.Run("fingerprint", func(t *testing.T) {
+		var buf bytes.Buffer
+		ocfw, err := NewOCFWriter(OCFConfig{W: &buf, Schema: CodecCacheSnapshotSchema})
+		ensureError(t, err)
+		ensureError(t, ocfw.Append([]interface{}{
+			map[string]interface{}{"fingerprint": int64(1), "schemas": []interface{}{`"int"`}},
+		}))
+		ensureError(t, ocfw.Close())
+		err = NewCodecCache(0).LoadSnapshot(&buf)
+		ensureError(t, err, "item 0: schema fingerprint changed")
+	})
+}
@@ -0,0 +1,124 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestCodecCache(t *testing.T) {
+	const schema = `{"type":"record","name":"r","doc":"first","fields":[{"name":"f","type":"long"}]}`
+	cache := NewCodecCache(2)
+
+	first, err := cache.Codec(schema)
+	ensureError(t, err)
+	second, err := cache.Codec(schema)
+	ensureError(t, err)
+	if first != second {
+		t.Errorf("GOT: %p; WANT: %p", second, first)
+	}
+
+	// schemas differing in doc, aliases, and layout share the codec
+	other, err := cache.Codec(`{"fields":[{"type":"long","name":"f","doc":"a field"}],"name":"r","type":"record","aliases":["s"]}`)
+	ensureError(t, err)
+	if other != first {
+		t.Errorf("GOT: %p; WANT: %p", other, first)
+	}
+	if actual, expected := other.Schema(), schema; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	// defaults change the codec, even though the canonical form omits them
+	defaulted, err := cache.Codec(`{"type":"record","name":"r","fields":[{"name":"f","type":"long","default":3}]}`)
+	ensureError(t, err)
+	if defaulted == first {
+		t.Errorf("GOT: %p; WANT: another codec", defaulted)
+	}
+	if defaulted.Rabin != first.Rabin {
+		t.Errorf("GOT: %v; WANT: %v", defaulted.Rabin, first.Rabin)
+	}
+
+	_, err = cache.Codec(`{"type":"record"}`)
+	ensureError(t, err, "ought to have name")
+
+	if actual, expected := cache.Stats(), (CodecCacheStats{Hits: 1, Shared: 1, Misses: 2, Len: 2, Capacity: 2}); actual != expected {
+		t.Errorf("GOT: %+v; WANT: %+v", actual, expected)
+	}
+
+	// adding a third codec evicts the least recently used one, along with
+	// every specification resolved to it
+	_, err = cache.Codec(`"int"`)
+	ensureError(t, err)
+	again, err := cache.Codec(schema)
+	ensureError(t, err)
+	if again == first {
+		t.Errorf("GOT: %p; WANT: new codec", again)
+	}
+	if actual, expected := cache.Stats(), (CodecCacheStats{Hits: 1, Shared: 1, Misses: 4, Evictions: 2, Len: 2, Capacity: 2}); actual != expected {
+		t.Errorf("GOT: %+v; WANT: %+v", actual, expected)
+	}
+}
+
+func TestCodecCacheOptions(t *testing.T) {
+	cache := NewCodecCache(0, WithEnumIndexDecoding(true))
+	codec, err := cache.Codec(`{"type":"enum","name":"e","symbols":["A","B"]}`)
+	ensureError(t, err)
+	datum, _, err := codec.NativeFromBinary([]byte{0x02})
+	ensureError(t, err)
+	if actual, expected := datum, 1; actual != expected {
+		t.Errorf("GOT: %v (%T); WANT: %v", actual, actual, expected)
+	}
+}
+
+func TestCodecCacheConcurrent(t *testing.T) {
+	cache := NewCodecCache(0)
+	schemas := []string{`"int"`, `{"type":"int"}`, `{"type":"int","doc":"x"}`}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := cache.Codec(schemas[i%len(schemas)]); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if actual, expected := cache.Stats().Len, 2; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
+
+func TestCodecCacheFingerprint(t *testing.T) {
+	cache := NewCodecCache(0)
+	codec, err := cache.Codec(`{"type":"fixed","name":"f","size":4}`)
+	ensureError(t, err)
+
+	found, ok := cache.CodecByFingerprint(codec.Rabin)
+	if !ok || found != codec {
+		t.Errorf("GOT: %p, %v; WANT: %p, true", found, ok, codec)
+	}
+	if _, ok = cache.CodecByFingerprint(codec.Rabin + 1); ok {
+		t.Errorf("GOT: %v; WANT: %v", ok, false)
+	}
+
+	// evicting a codec keeps another codec of the same fingerprint
+	cache = NewCodecCache(1)
+	_, err = cache.Codec(`{"type":"record","name":"r","fields":[{"name":"f","type":"long"}]}`)
+	ensureError(t, err)
+	defaulted, err := cache.Codec(`{"type":"record","name":"r","fields":[{"name":"f","type":"long","default":3}]}`)
+	ensureError(t, err)
+	if found, _ = cache.CodecByFingerprint(defaulted.Rabin); found != defaulted {
+		t.Errorf("GOT: %p; WANT: %p", found, defaulted)
+	}
+}
@@ -0,0 +1,637 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// NumericDecoding specifies the Go types a Codec uses for decoded Avro int,
+// long, float, and double values.
+type NumericDecoding int
+
+const (
+	// NumericDecodingNative decodes int as int32, long as int64, float as
+	// float32, and double as float64. This is the default.
+	NumericDecodingNative NumericDecoding = iota
+
+	// NumericDecodingWide decodes both int and long as int64, and both float
+	// and double as float64, wherever they appear in the schema, including
+	// union members, array items, and map values. Enum indexes decoded with
+	// the EnumIndexDecoding option are also int64. Logical types keep their
+	// own types, such as time.Time for timestamps.
+	NumericDecodingWide
+
+	// NumericDecodingJSONNumber decodes int, long, float, and double as
+	// json.Number, except NaN and infinite values, which cannot be represented
+	// as a JSON number, and are decoded as float64. Enum indexes decoded with
+	// the EnumIndexDecoding option are also json.Number.
+	NumericDecodingJSONNumber
+)
+
+// TrailingBytes specifies what a Codec does with the bytes following a datum
+// decoded from the start of a buffer.
+type TrailingBytes int
+
+const (
+	// TrailingBytesReturn returns the bytes following the datum, so several
+	// data may be decoded from one buffer. This is the default.
+	TrailingBytesReturn TrailingBytes = iota
+
+	// TrailingBytesError fails to decode a buffer holding bytes following
+	// the datum, so messages expected to hold exactly one datum catch framing
+	// bugs, such as a producer writing two data, or a schema shorter than the
+	// one the producer used, rather than silently dropping the extra bytes.
+	// Whitespace following a datum decoded from textual data is allowed.
+	TrailingBytesError
+
+	// TrailingBytesIgnore discards the bytes following the datum, returning
+	// an empty slice in their place.
+	TrailingBytesIgnore
+)
+
+// handle returns the bytes to return following a datum, given the bytes that
+// remained after decoding it, or an error when they ought not to remain. Kind
+// names the encoding in the error.
+func (mode TrailingBytes) handle(kind string, rest []byte, textual bool) ([]byte, error) {
+	switch mode {
+	case TrailingBytesError:
+		if len(rest) == 0 {
+			return rest, nil
+		}
+		if textual {
+			if _, err := advanceToNonWhitespace(rest); err != nil {
+				return rest, nil // only whitespace
+			}
+		}
+		return nil, fmt.Errorf("cannot decode %s: %d bytes following datum", kind, len(rest))
+	case TrailingBytesIgnore:
+		return rest[len(rest):], nil
+	}
+	return rest, nil
+}
+
+// CodecOption specifies how a Codec translates between Avro and native Go data.
+// The zero value provides the same behavior as NewCodec without options. Each
+// field may also be set by the corresponding Option, such as
+// WithNumericDecoding.
+type CodecOption struct {
+	// NumericDecoding specifies the Go types used for decoded Avro numbers.
+	// When it is not NumericDecodingNative, data handed to generic JSON or
+	// templating layers need not assert each integer and floating point field
+	// to its particular width.
+	NumericDecoding NumericDecoding
+
+	// EnumIndexDecoding decodes enum values as the int index of their symbol,
+	// or of the type selected by NumericDecoding, rather than as the symbol
+	// string, which avoids string allocations, and
+	// suits columnar sinks that store enums as integers. Codecs created with
+	// this option also accept indexes when encoding. The symbols of each enum
+	// are available from Codec.EnumSymbols.
+	EnumIndexDecoding bool
+
+	// OrderedMapDecoding decodes Avro maps as OrderedMap values, which keep
+	// the items in the order they were written, so re-encoding a decoded map
+	// produces the same bytes. This matters when comparing round-trip output
+	// with that of another Avro implementation.
+	OrderedMapDecoding bool
+
+	// UnionValueDecoding decodes non-null Avro union values as UnionValue
+	// values, which name the member of the union, rather than as a map with a
+	// single key, so downstream type switches are simpler, and decoding does
+	// not allocate a map for each union value. Every Codec encodes UnionValue
+	// values.
+	UnionValueDecoding bool
+
+	// UnwrappedNullableUnions encodes values of unions of null and one other
+	// type, such as ["null","long"], given as the plain value of the other
+	// type, such as int64(3), rather than wrapped in a map with a single key
+	// naming the type, such as goavro.Union("long", int64(3)), while nil
+	// still encodes null. Wrapped values are still accepted: a map with a
+	// single key naming a member of the union is encoded as a wrapped value.
+	// Decoding is not affected.
+	UnwrappedNullableUnions bool
+
+	// ZeroCopyDecoding decodes Avro string values as Go strings referencing
+	// the bytes of the buffer they are decoded from, rather than copies of
+	// them, for read-only consumers that process and discard each datum
+	// before the buffer is reused. Avro bytes and fixed values always
+	// reference the buffer. The caller owns the buffer, and ought not to
+	// modify it while any decoded string is in use, because the string would
+	// change with it. Buffers of an OCFReader are not reused, while a Decoder
+	// reuses its buffer, so data it decodes are only valid until the next
+	// call to Decode. Map keys are always copied, because Go maps retain
+	// them.
+	ZeroCopyDecoding bool
+
+	// SentinelErrors returns preallocated errors when binary data cannot be
+	// decoded, rather than errors describing where decoding failed, which
+	// are formatted at each level of nesting of the datum. Data ending before
+	// the datum does return io.ErrShortBuffer, such as when decoding
+	// speculatively from a partially received stream, and other invalid data
+	// return ErrInvalidBinary. Errors of logical types, decode limits, and
+	// field hooks are still formatted. It suits decoding dirty streams where
+	// failures are frequent and their details are not needed.
+	SentinelErrors bool
+
+	// SortedMapEncoding encodes the items of Avro maps in the lexicographic
+	// order of their keys, rather than in the random order Go iterates maps,
+	// so encoding the same datum always produces the same bytes, such as for
+	// content addressed storage. OrderedMap values are still encoded in the
+	// order of their items.
+	SortedMapEncoding bool
+
+	// BlockLength limits the number of items written in each block of an
+	// encoded Avro array or map. When zero, each block has up to
+	// MaxBlockCount items, so most arrays and maps are written as a single
+	// block.
+	BlockLength int
+
+	// BlockSizes writes each block of an encoded Avro array or map as a
+	// negative item count followed by the size of the block in bytes, which
+	// lets readers skip the block without decoding its items.
+	BlockSizes bool
+
+	// ValidateOnEncode validates each datum using Codec.Validate before
+	// encoding it, so data that violates the validation annotations of the
+	// schema, such as x-min, is not written. Creating the Codec fails when
+	// the annotations are invalid.
+	ValidateOnEncode bool
+
+	// TrustedEncoding skips the checks encoding makes beyond those needed to
+	// select how each value is encoded, for data already validated upstream,
+	// such as data decoded by a Codec of the same schema. Go numbers are
+	// converted to the width of their Avro int, long, float, or double type
+	// without checking that the conversion is exact, so a value out of range
+	// is encoded truncated rather than rejected. Values whose Go type does not
+	// match their schema are still rejected. It may not be combined with
+	// ValidateOnEncode.
+	TrustedEncoding bool
+
+	// FieldHooks transform the values of selected record fields as they are
+	// encoded and decoded, such as to encrypt or tokenize them, so the
+	// schema only describes the transformed values. Values are validated
+	// before they are transformed. Options are equal when they refer to the
+	// same FieldHooks.
+	FieldHooks *FieldHooks
+
+	// RelativeUnionNames writes the keys of textual union values of named
+	// types declared in the namespace enclosing the union as short names,
+	// as Java implementations do, rather than as full names. Textual
+	// decoding accepts either name, and native data always uses full names.
+	RelativeUnionNames bool
+
+	// FeatureFlags are runtime feature flags, keyed by the full name of the
+	// schema, reported by Codec.FeatureEnabled. They do not change how data
+	// are translated. Options are equal when they refer to the same
+	// FeatureFlags.
+	FeatureFlags *FeatureFlags
+
+	// SchemaLimits bound the size and structure of the schemas accepted,
+	// rejecting pathological schemas from untrusted sources. When zero, any
+	// schema is accepted.
+	SchemaLimits SchemaLimits
+
+	// MaxDecodeDepth is the largest number of records, arrays, maps, and
+	// unions a value decoded from binary may be nested within, bounding the
+	// recursion of decoding data of recursive schemas. When zero, the depth is
+	// not limited.
+	MaxDecodeDepth int
+
+	// MaxBytesPerDatum is the largest number of bytes a datum decoded from
+	// binary may be encoded in, where items of arrays and maps encoded in zero
+	// bytes, such as nulls, count as one byte each. A block count claiming
+	// more items than would fit is rejected before any item is decoded, so
+	// corrupt or hostile data fail fast rather than exhaust memory. When zero,
+	// the size is not limited.
+	//
+	// When either MaxDecodeDepth or MaxBytesPerDatum is set, each datum is
+	// scanned before it is decoded.
+	MaxBytesPerDatum int
+
+	// TrailingBytes specifies what NativeFromBinary, NativeFromBinaryInto,
+	// NativeFromBinaryFields, NativeFromSingle, and NativeFromTextual do
+	// with the bytes following the datum they decode. Strict deployments,
+	// where each message holds exactly one datum, ought to use
+	// TrailingBytesError. OCFReader and Decoder are not affected.
+	TrailingBytes TrailingBytes
+}
+
+// DefaultCodecOption returns the options NewCodec uses.
+func DefaultCodecOption() *CodecOption {
+	return &CodecOption{NumericDecoding: NumericDecodingNative}
+}
+
+// NewCodecWithOptions returns a Codec like NewCodec, which translates data as
+// specified by option. A nil option is the same as DefaultCodecOption(). It is
+// equivalent to NewCodec(schemaSpecification, WithCodecOption(option)).
+//
+//     codec, err := goavro.NewCodecWithOptions(`"int"`, &goavro.CodecOption{
+//         NumericDecoding: goavro.NumericDecodingJSONNumber,
+//     })
+//     if err != nil {
+//             fmt.Println(err)
+//     }
+//     datum, _, err := codec.NativeFromBinary([]byte{0x54})
+//     // datum is json.Number("42")
+func NewCodecWithOptions(schemaSpecification string, option *CodecOption) (*Codec, error) {
+	return NewCodec(schemaSpecification, WithCodecOption(option))
+}
+
+// Options returns a copy of the options used to create the Codec.
+func (c *Codec) Options() CodecOption {
+	return c.option
+}
+
+// WithOptions returns a Codec for the same schema as c, whose options are
+// those of c modified by opts. The new Codec shares the parsed schema, its
+// canonical form, and its fingerprint with c, so deriving it is much cheaper
+// than creating a Codec from the schema text, and one compiled schema may be
+// used with several behaviors. The Codec c is not modified. When opts do not
+// change any option, WithOptions returns c.
+//
+//     variant, err := codec.WithOptions(goavro.WithEnumIndexDecoding(true))
+func (c *Codec) WithOptions(opts ...Option) (*Codec, error) {
+	if c.parsedSchema == nil {
+		return nil, fmt.Errorf("cannot derive codec: codec was not created by NewCodec")
+	}
+	option := c.option
+	for _, opt := range opts {
+		opt(&option)
+	}
+	if option == c.option {
+		return c, nil
+	}
+	if err := option.validate(); err != nil {
+		return nil, fmt.Errorf("cannot derive codec: %s", err)
+	}
+	if err := option.SchemaLimits.checkSize(c.schemaOriginal); err != nil {
+		return nil, fmt.Errorf("cannot derive codec: %s", err)
+	}
+	if err := option.SchemaLimits.checkSchema(c.parsedSchema); err != nil {
+		return nil, fmt.Errorf("cannot derive codec: %s", err)
+	}
+
+	st := newOptionSymbolTable(&option)
+	built, err := buildCodec(st, nullNamespace, c.parsedSchema, &option)
+	if err != nil {
+		return nil, fmt.Errorf("cannot derive codec: %s", err) // should not get here because c was built from the same schema
+	}
+	// NOTE: A top level primitive or logical type is the codec from the symbol
+	// table, which is not shared with other codecs, so it may be modified.
+	built.soeHeader = c.soeHeader
+	built.schemaOriginal = c.schemaOriginal
+	built.schemaCanonical = c.schemaCanonical
+	built.enums = c.enums
+	built.Rabin = c.Rabin
+	built.option = option
+	built.parsedSchema = c.parsedSchema
+	if option.ValidateOnEncode {
+		if _, err = built.validator(); err != nil {
+			return nil, fmt.Errorf("cannot derive codec: invalid validation annotation: %s", err)
+		}
+	}
+	if err = built.compileFieldHooks(); err != nil {
+		return nil, fmt.Errorf("cannot derive codec: %s", err)
+	}
+	return built, nil
+}
+
+// validate returns an error when an option has an invalid value.
+func (option *CodecOption) validate() error {
+	switch option.NumericDecoding {
+	case NumericDecodingNative, NumericDecodingWide, NumericDecodingJSONNumber:
+	default:
+		return fmt.Errorf("unknown numeric decoding: %d", option.NumericDecoding)
+	}
+	if option.BlockLength < 0 {
+		return fmt.Errorf("block length ought to be zero or positive: %d", option.BlockLength)
+	}
+	if option.TrustedEncoding && option.ValidateOnEncode {
+		return fmt.Errorf("trusted encoding ought not to be combined with validate on encode")
+	}
+	if option.MaxDecodeDepth < 0 {
+		return fmt.Errorf("max decode depth ought to be zero or positive: %d", option.MaxDecodeDepth)
+	}
+	if option.MaxBytesPerDatum < 0 {
+		return fmt.Errorf("max bytes per datum ought to be zero or positive: %d", option.MaxBytesPerDatum)
+	}
+	switch option.TrailingBytes {
+	case TrailingBytesReturn, TrailingBytesError, TrailingBytesIgnore:
+	default:
+		return fmt.Errorf("unknown trailing bytes handling: %d", option.TrailingBytes)
+	}
+	return option.SchemaLimits.validate()
+}
+
+// Option is a functional option of NewCodec, which sets one or more fields of
+// the CodecOption used to create the Codec.
+type Option func(*CodecOption)
+
+// WithCodecOption sets every field of the CodecOption, replacing the effect of
+// the preceding options. A nil option sets the fields to their defaults.
+func WithCodecOption(option *CodecOption) Option {
+	return func(o *CodecOption) {
+		if option == nil {
+			*o = *DefaultCodecOption()
+			return
+		}
+		*o = *option
+	}
+}
+
+// WithNumericDecoding sets the Go types used for decoded Avro numbers. See
+// CodecOption.NumericDecoding.
+func WithNumericDecoding(mode NumericDecoding) Option {
+	return func(o *CodecOption) { o.NumericDecoding = mode }
+}
+
+// WithEnumIndexDecoding sets whether enum values are decoded as the index of
+// their symbol. See CodecOption.EnumIndexDecoding.
+func WithEnumIndexDecoding(enabled bool) Option {
+	return func(o *CodecOption) { o.EnumIndexDecoding = enabled }
+}
+
+// WithOrderedMapDecoding sets whether Avro maps are decoded as OrderedMap
+// values. See CodecOption.OrderedMapDecoding.
+func WithOrderedMapDecoding(enabled bool) Option {
+	return func(o *CodecOption) { o.OrderedMapDecoding = enabled }
+}
+
+// WithUnionValueDecoding sets whether non-null Avro union values are decoded
+// as UnionValue values. See CodecOption.UnionValueDecoding.
+func WithUnionValueDecoding(enabled bool) Option {
+	return func(o *CodecOption) { o.UnionValueDecoding = enabled }
+}
+
+// WithUnwrappedNullableUnions sets whether values of unions of null and one
+// other type may be encoded without wrapping them. See
+// CodecOption.UnwrappedNullableUnions.
+func WithUnwrappedNullableUnions(enabled bool) Option {
+	return func(o *CodecOption) { o.UnwrappedNullableUnions = enabled }
+}
+
+// WithZeroCopyDecoding enables or disables decoding strings referencing the
+// buffer they are decoded from. See CodecOption.ZeroCopyDecoding.
+func WithZeroCopyDecoding(enabled bool) Option {
+	return func(o *CodecOption) { o.ZeroCopyDecoding = enabled }
+}
+
+// WithSentinelErrors enables or disables returning preallocated errors when
+// binary data cannot be decoded. See CodecOption.SentinelErrors.
+func WithSentinelErrors(enabled bool) Option {
+	return func(o *CodecOption) { o.SentinelErrors = enabled }
+}
+
+// WithSortedMapEncoding sets whether the items of Avro maps are encoded in the
+// order of their keys. See CodecOption.SortedMapEncoding.
+func WithSortedMapEncoding(enabled bool) Option {
+	return func(o *CodecOption) { o.SortedMapEncoding = enabled }
+}
+
+// WithBlockLength limits the number of items written in each block of an
+// encoded Avro array or map. See CodecOption.BlockLength.
+func WithBlockLength(length int) Option {
+	return func(o *CodecOption) { o.BlockLength = length }
+}
+
+// WithBlockSizes sets whether array and map blocks are written with their size
+// in bytes. See CodecOption.BlockSizes.
+func WithBlockSizes(enabled bool) Option {
+	return func(o *CodecOption) { o.BlockSizes = enabled }
+}
+
+// WithValidateOnEncode sets whether each datum is validated before it is
+// encoded. See CodecOption.ValidateOnEncode.
+func WithValidateOnEncode(enabled bool) Option {
+	return func(o *CodecOption) { o.ValidateOnEncode = enabled }
+}
+
+// WithTrustedEncoding sets whether encoding skips the checks of trusted data.
+// See CodecOption.TrustedEncoding.
+func WithTrustedEncoding(enabled bool) Option {
+	return func(o *CodecOption) { o.TrustedEncoding = enabled }
+}
+
+// WithFieldHooks sets the hooks that transform the values of selected record
+// fields. See CodecOption.FieldHooks.
+func WithFieldHooks(hooks *FieldHooks) Option {
+	return func(o *CodecOption) { o.FieldHooks = hooks }
+}
+
+// WithRelativeUnionNames sets whether textual union keys are written relative
+// to the enclosing namespace. See CodecOption.RelativeUnionNames.
+func WithRelativeUnionNames(enabled bool) Option {
+	return func(o *CodecOption) { o.RelativeUnionNames = enabled }
+}
+
+// WithSchemaLimits sets the limits on the size and structure of the schema.
+// See CodecOption.SchemaLimits.
+func WithSchemaLimits(limits SchemaLimits) Option {
+	return func(o *CodecOption) { o.SchemaLimits = limits }
+}
+
+// WithMaxDecodeDepth limits how deeply values decoded from binary may be
+// nested. See CodecOption.MaxDecodeDepth.
+func WithMaxDecodeDepth(depth int) Option {
+	return func(o *CodecOption) { o.MaxDecodeDepth = depth }
+}
+
+// WithMaxBytesPerDatum limits the size of each datum decoded from binary. See
+// CodecOption.MaxBytesPerDatum.
+func WithMaxBytesPerDatum(size int) Option {
+	return func(o *CodecOption) { o.MaxBytesPerDatum = size }
+}
+
+// WithTrailingBytes sets what decoding does with the bytes following a datum.
+// See CodecOption.TrailingBytes.
+func WithTrailingBytes(mode TrailingBytes) Option {
+	return func(o *CodecOption) { o.TrailingBytes = mode }
+}
+
+// applyNumericDecoding replaces the decoders of the int, long, float, and
+// double codecs in the symbol table, so they return the Go types specified by
+// mode. Logical types built on those primitives are not affected. It also
+// allows the encoders to accept json.Number values, so decoded data may be
+// encoded again. The codecs are left unchanged for NumericDecodingNative.
+func applyNumericDecoding(st map[string]*Codec, mode NumericDecoding) {
+	if mode == NumericDecodingNative {
+		return
+	}
+	for _, typeName := range []string{"int", "long", "float", "double"} {
+		c := st[typeName]
+		c.binaryFromNative = encoderAcceptingJSONNumber(c.binaryFromNative, typeName)
+		c.textualFromNative = encoderAcceptingJSONNumber(c.textualFromNative, typeName)
+		convert := wideNumeric
+		if mode == NumericDecodingJSONNumber {
+			convert = jsonNumeric
+		}
+		c.nativeFromBinary = convertedNative(c.nativeFromBinary, convert)
+		c.nativeFromTextual = convertedNative(c.nativeFromTextual, convert)
+	}
+}
+
+// applyTrustedEncoding replaces the binary encoders of the int, long, float,
+// and double codecs in the symbol table with encoders that convert the common
+// Go numeric types without checking for loss of precision. Other types are
+// handed to the checked encoders.
+func applyTrustedEncoding(st map[string]*Codec) {
+	trusted := map[string]func(buf []byte, datum interface{}) ([]byte, bool){
+		"int": func(buf []byte, datum interface{}) ([]byte, bool) {
+			var value int32
+			switch v := datum.(type) {
+			case int32:
+				value = v
+			case int:
+				value = int32(v)
+			case int64:
+				value = int32(v)
+			default:
+				return buf, false
+			}
+			buf, _ = integerBinaryEncoder(buf, uint64((uint32(value)<<1)^uint32(value>>intDownShift)))
+			return buf, true
+		},
+		"long": func(buf []byte, datum interface{}) ([]byte, bool) {
+			var value int64
+			switch v := datum.(type) {
+			case int64:
+				value = v
+			case int:
+				value = int64(v)
+			case int32:
+				value = int64(v)
+			default:
+				return buf, false
+			}
+			buf, _ = integerBinaryEncoder(buf, (uint64(value)<<1)^uint64(value>>longDownShift))
+			return buf, true
+		},
+		"float": func(buf []byte, datum interface{}) ([]byte, bool) {
+			var value float32
+			switch v := datum.(type) {
+			case float32:
+				value = v
+			case float64:
+				value = float32(v)
+			case int:
+				value = float32(v)
+			case int64:
+				value = float32(v)
+			case int32:
+				value = float32(v)
+			default:
+				return buf, false
+			}
+			buf = append(buf, 0, 0, 0, 0)
+			binary.LittleEndian.PutUint32(buf[len(buf)-floatEncodedLength:], math.Float32bits(value))
+			return buf, true
+		},
+		"double": func(buf []byte, datum interface{}) ([]byte, bool) {
+			var value float64
+			switch v := datum.(type) {
+			case float64:
+				value = v
+			case float32:
+				value = float64(v)
+			case int:
+				value = float64(v)
+			case int64:
+				value = float64(v)
+			case int32:
+				value = float64(v)
+			default:
+				return buf, false
+			}
+			buf = append(buf, 0, 0, 0, 0, 0, 0, 0, 0)
+			binary.LittleEndian.PutUint64(buf[len(buf)-doubleEncodedLength:], math.Float64bits(value))
+			return buf, true
+		},
+	}
+	for typeName, encode := range trusted {
+		c, encode := st[typeName], encode
+		checked := c.binaryFromNative
+		c.binaryFromNative = func(buf []byte, datum interface{}) ([]byte, error) {
+			if newBuf, ok := encode(buf, datum); ok {
+				return newBuf, nil
+			}
+			return checked(buf, datum)
+		}
+	}
+}
+
+func convertedNative(decoder func([]byte) (interface{}, []byte, error), convert func(interface{}) interface{}) func([]byte) (interface{}, []byte, error) {
+	return func(buf []byte) (interface{}, []byte, error) {
+		datum, buf, err := decoder(buf)
+		if err != nil {
+			return nil, nil, err
+		}
+		return convert(datum), buf, nil
+	}
+}
+
+func wideNumeric(datum interface{}) interface{} {
+	switch v := datum.(type) {
+	case int32:
+		return int64(v)
+	case float32:
+		return float64(v)
+	}
+	return datum
+}
+
+func jsonNumeric(datum interface{}) interface{} {
+	switch v := datum.(type) {
+	case int32:
+		return json.Number(strconv.FormatInt(int64(v), 10))
+	case int64:
+		return json.Number(strconv.FormatInt(v, 10))
+	case float32:
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return float64(v)
+		}
+		return json.Number(strconv.FormatFloat(float64(v), 'g', -1, 32))
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return v
+		}
+		return json.Number(strconv.FormatFloat(v, 'g', -1, 64))
+	}
+	return datum
+}
+
+// encoderAcceptingJSONNumber returns an encoder that converts a json.Number
+// datum to int64 or float64, as appropriate for the Avro type, before encoding
+// it.
+func encoderAcceptingJSONNumber(encoder func([]byte, interface{}) ([]byte, error), typeName string) func([]byte, interface{}) ([]byte, error) {
+	return func(buf []byte, datum interface{}) ([]byte, error) {
+		if n, ok := datum.(json.Number); ok {
+			var err error
+			switch typeName {
+			case "int", "long":
+				if datum, err = n.Int64(); err != nil {
+					// allow integral values written with a fraction or
+					// exponent, such as 1.0 or 1e3
+					datum, err = n.Float64()
+				}
+			default:
+				datum, err = n.Float64()
+			}
+			if err != nil {
+				return nil, fmt.Errorf("cannot encode %s: provided json.Number is not valid: %s", typeName, err)
+			}
+		}
+		return encoder(buf, datum)
+	}
+}
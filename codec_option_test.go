@@ -0,0 +1,424 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const numericsSchema = `{
+  "type": "record",
+  "name": "Numerics",
+  "fields": [
+    {"name": "i", "type": "int"},
+    {"name": "l", "type": "long"},
+    {"name": "f", "type": "float"},
+    {"name": "d", "type": "double"},
+    {"name": "u", "type": ["null", "int"]},
+    {"name": "t", "type": {"type": "long", "logicalType": "timestamp-millis"}}
+  ]
+}`
+
+func newCodecWithOptionsUsingV2(t *testing.T, schema string, option *CodecOption) *Codec {
+	t.Helper()
+	codec, err := NewCodecWithOptions(schema, option)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return codec
+}
+
+func TestCodecOptionNumericDecoding(t *testing.T) {
+	when := time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)
+	datum := map[string]interface{}{
+		"i": int32(3),
+		"l": int64(-4),
+		"f": float32(1.5),
+		"d": 0.1,
+		"u": Union("int", int32(7)),
+		"t": when,
+	}
+
+	cases := []struct {
+		mode NumericDecoding
+		want map[string]interface{}
+	}{
+		{NumericDecodingNative, datum},
+		{NumericDecodingWide, map[string]interface{}{
+			"i": int64(3),
+			"l": int64(-4),
+			"f": float64(1.5),
+			"d": 0.1,
+			"u": Union("int", int64(7)),
+			"t": when,
+		}},
+		{NumericDecodingJSONNumber, map[string]interface{}{
+			"i": json.Number("3"),
+			"l": json.Number("-4"),
+			"f": json.Number("1.5"),
+			"d": json.Number("0.1"),
+			"u": Union("int", json.Number("7")),
+			"t": when,
+		}},
+	}
+
+	for _, c := range cases {
+		codec := newCodecWithOptionsUsingV2(t, numericsSchema, &CodecOption{NumericDecoding: c.mode})
+
+		buf, err := codec.BinaryFromNative(nil, datum)
+		ensureError(t, err)
+		got, _, err := codec.NativeFromBinary(buf)
+		ensureError(t, err)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("mode %d binary: GOT: %#v; WANT: %#v", c.mode, got, c.want)
+		}
+
+		// decoded data may be encoded again with the same codec
+		again, err := codec.BinaryFromNative(nil, got)
+		ensureError(t, err)
+		if !reflect.DeepEqual(again, buf) {
+			t.Errorf("mode %d re-encode: GOT: %v; WANT: %v", c.mode, again, buf)
+		}
+
+		text, err := codec.TextualFromNative(nil, datum)
+		ensureError(t, err)
+		got, _, err = codec.NativeFromTextual(text)
+		ensureError(t, err)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("mode %d textual: GOT: %#v; WANT: %#v", c.mode, got, c.want)
+		}
+	}
+}
+
+func TestCodecOptionJSONNumberNonFinite(t *testing.T) {
+	codec := newCodecWithOptionsUsingV2(t, `"double"`, &CodecOption{NumericDecoding: NumericDecodingJSONNumber})
+	buf, err := codec.BinaryFromNative(nil, math.Inf(1))
+	ensureError(t, err)
+	got, _, err := codec.NativeFromBinary(buf)
+	ensureError(t, err)
+	if v, ok := got.(float64); !ok || !math.IsInf(v, 1) {
+		t.Errorf("GOT: %#v; WANT: %v", got, math.Inf(1))
+	}
+}
+
+func TestCodecOptionEncodeJSONNumber(t *testing.T) {
+	option := &CodecOption{NumericDecoding: NumericDecodingWide}
+	cases := []struct {
+		schema string
+		datum  json.Number
+		want   []byte
+	}{
+		{`"int"`, "42", []byte{0x54}},
+		{`"long"`, "1e3", []byte{0xd0, 0x0f}},
+		{`"double"`, "2", []byte{0, 0, 0, 0, 0, 0, 0, 0x40}},
+	}
+	for _, c := range cases {
+		codec := newCodecWithOptionsUsingV2(t, c.schema, option)
+		buf, err := codec.BinaryFromNative(nil, c.datum)
+		ensureError(t, err)
+		if !bytes.Equal(buf, c.want) {
+			t.Errorf("schema: %s; GOT: %v; WANT: %v", c.schema, buf, c.want)
+		}
+	}
+	codec := newCodecWithOptionsUsingV2(t, `"float"`, option)
+	text, err := codec.TextualFromNative(nil, json.Number("1.5"))
+	ensureError(t, err)
+	if actual, expected := string(text), "1.5"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	codec = newCodecWithOptionsUsingV2(t, `"int"`, option)
+	_, err = codec.BinaryFromNative(nil, json.Number("1.5"))
+	ensureError(t, err, "lose precision")
+	codec = newCodecWithOptionsUsingV2(t, `"long"`, option)
+	_, err = codec.BinaryFromNative(nil, json.Number("x"))
+	ensureError(t, err, "json.Number is not valid")
+
+	// codecs using the default options encode numbers as they always have
+	testBinaryEncodeFail(t, `"int"`, json.Number("42"), "received: json.Number")
+}
+
+func TestCodecOptionInvalid(t *testing.T) {
+	_, err := NewCodecWithOptions(`"int"`, &CodecOption{NumericDecoding: NumericDecoding(99)})
+	ensureError(t, err, "unknown numeric decoding")
+
+	codec, err := NewCodecWithOptions(`"int"`, nil)
+	ensureError(t, err)
+	got, _, err := codec.NativeFromBinary([]byte{0x54})
+	ensureError(t, err)
+	if got != int32(42) {
+		t.Errorf("GOT: %#v; WANT: %#v", got, int32(42))
+	}
+}
+
+func TestCodecOptionEnumIndexDecoding(t *testing.T) {
+	schema := `{
+  "type": "record",
+  "name": "Card",
+  "namespace": "com.example",
+  "fields": [
+    {"name": "suit", "type": {"type": "enum", "name": "Suit", "symbols": ["SPADES", "HEARTS", "DIAMONDS", "CLUBS"]}},
+    {"name": "previous", "type": ["null", "Suit"]}
+  ]
+}`
+	codec := newCodecWithOptionsUsingV2(t, schema, &CodecOption{EnumIndexDecoding: true})
+
+	buf, err := codec.BinaryFromNative(nil, map[string]interface{}{"suit": "DIAMONDS", "previous": Union("com.example.Suit", 1)})
+	ensureError(t, err)
+	got, _, err := codec.NativeFromBinary(buf)
+	ensureError(t, err)
+	want := map[string]interface{}{"suit": 2, "previous": Union("com.example.Suit", 1)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GOT: %#v; WANT: %#v", got, want)
+	}
+
+	text, err := codec.TextualFromNative(nil, got)
+	ensureError(t, err)
+	// NOTE: Record fields are encoded in no particular order.
+	for _, expected := range []string{`"suit":"DIAMONDS"`, `"previous":{"com.example.Suit":"HEARTS"}`} {
+		if actual := string(text); !strings.Contains(actual, expected) {
+			t.Errorf("GOT: %v; WANT: %v", actual, expected)
+		}
+	}
+	got, _, err = codec.NativeFromTextual(text)
+	ensureError(t, err)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GOT: %#v; WANT: %#v", got, want)
+	}
+
+	symbols, ok := codec.EnumSymbols("com.example.Suit")
+	if !ok || !reflect.DeepEqual(symbols, []string{"SPADES", "HEARTS", "DIAMONDS", "CLUBS"}) {
+		t.Errorf("GOT: %v, %v; WANT: %v", symbols, ok, []string{"SPADES", "HEARTS", "DIAMONDS", "CLUBS"})
+	}
+	if _, ok = codec.EnumSymbols("Suit"); ok {
+		t.Errorf("GOT: %v; WANT: %v", ok, false)
+	}
+
+	_, err = codec.BinaryFromNative(nil, map[string]interface{}{"suit": 4, "previous": nil})
+	ensureError(t, err, `cannot encode binary enum "com.example.Suit"`, "index ought to be between 0 and 3")
+}
+
+func TestCodecOptionEnumIndexDecodingWide(t *testing.T) {
+	schema := `{"type": "array", "items": {"type": "enum", "name": "Suit", "symbols": ["SPADES", "HEARTS"]}}`
+	for _, tc := range []struct {
+		mode     NumericDecoding
+		expected interface{}
+	}{
+		{NumericDecodingNative, 1},
+		{NumericDecodingWide, int64(1)},
+		{NumericDecodingJSONNumber, json.Number("1")},
+	} {
+		codec := newCodecWithOptionsUsingV2(t, schema, &CodecOption{EnumIndexDecoding: true, NumericDecoding: tc.mode})
+		got, _, err := codec.NativeFromBinary([]byte{0x02, 0x02, 0x00})
+		ensureError(t, err)
+		if want := []interface{}{tc.expected}; !reflect.DeepEqual(got, want) {
+			t.Errorf("GOT: %#v; WANT: %#v", got, want)
+		}
+		got, _, err = codec.NativeFromTextual([]byte(`["HEARTS"]`))
+		ensureError(t, err)
+		if want := []interface{}{tc.expected}; !reflect.DeepEqual(got, want) {
+			t.Errorf("GOT: %#v; WANT: %#v", got, want)
+		}
+		buf, err := codec.BinaryFromNative(nil, got)
+		ensureError(t, err)
+		if !bytes.Equal(buf, []byte{0x02, 0x02, 0x00}) {
+			t.Errorf("GOT: %v; WANT: %v", buf, []byte{0x02, 0x02, 0x00})
+		}
+	}
+}
+
+func TestCodecEnumSymbolsTopLevel(t *testing.T) {
+	codec := newCodecUsingV2(t, `{"type":"enum","name":"e1","symbols":["alpha","bravo"]}`)
+	symbols, ok := codec.EnumSymbols("")
+	if !ok || !reflect.DeepEqual(symbols, []string{"alpha", "bravo"}) {
+		t.Errorf("GOT: %v, %v; WANT: %v", symbols, ok, []string{"alpha", "bravo"})
+	}
+	datum, _, err := codec.NativeFromBinary([]byte{0x02})
+	ensureError(t, err)
+	if datum != "bravo" {
+		t.Errorf("GOT: %#v; WANT: %#v", datum, "bravo")
+	}
+}
+
+func TestCodecFunctionalOptions(t *testing.T) {
+	codec, err := NewCodec(`{"type":"map","values":"int"}`, WithNumericDecoding(NumericDecodingWide), WithOrderedMapDecoding(true))
+	ensureError(t, err)
+	got, _, err := codec.NativeFromBinary([]byte{0x04, 0x02, 'b', 0x02, 0x02, 'a', 0x04, 0x00})
+	ensureError(t, err)
+	want := OrderedMap{{Key: "b", Value: int64(1)}, {Key: "a", Value: int64(2)}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GOT: %#v; WANT: %#v", got, want)
+	}
+
+	// options are applied in order, and WithCodecOption replaces every field
+	codec, err = NewCodec(`"int"`, WithNumericDecoding(NumericDecodingWide), WithCodecOption(nil))
+	ensureError(t, err)
+	if got, _, err = codec.NativeFromBinary([]byte{0x54}); err != nil || got != int32(42) {
+		t.Errorf("GOT: %#v, %v; WANT: %#v", got, err, int32(42))
+	}
+	codec, err = NewCodec(`"int"`, WithCodecOption(&CodecOption{NumericDecoding: NumericDecodingJSONNumber}), WithNumericDecoding(NumericDecodingWide))
+	ensureError(t, err)
+	if got, _, err = codec.NativeFromBinary([]byte{0x54}); err != nil || got != int64(42) {
+		t.Errorf("GOT: %#v, %v; WANT: %#v", got, err, int64(42))
+	}
+
+	_, err = NewCodec(`"int"`, WithBlockLength(-1))
+	ensureError(t, err, "cannot create codec", "block length ought to be zero or positive")
+}
+
+func TestCodecOptionTrustedEncoding(t *testing.T) {
+	schema := `{"type":"record","name":"r","fields":[{"name":"i","type":"int"},{"name":"l","type":"long"},{"name":"f","type":"float"},{"name":"d","type":"double"}]}`
+	checked := newCodecUsingV2(t, schema)
+	trusted := newCodecWithOptionsUsingV2(t, schema, &CodecOption{TrustedEncoding: true})
+
+	datum := map[string]interface{}{"i": 13, "l": int32(-42), "f": 1.5, "d": int64(7)}
+	expected, err := checked.BinaryFromNative(nil, datum)
+	ensureError(t, err)
+	actual, err := trusted.BinaryFromNative(nil, datum)
+	ensureError(t, err)
+	if !bytes.Equal(actual, expected) {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	// values are truncated rather than rejected
+	_, err = checked.BinaryFromNative(nil, map[string]interface{}{"i": int64(1) << 32, "l": 0, "f": 0, "d": 0})
+	ensureError(t, err, "would lose precision")
+	actual, err = trusted.BinaryFromNative(nil, map[string]interface{}{"i": int64(1) << 32, "l": 0, "f": 0, "d": 0})
+	ensureError(t, err)
+	if expected := []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}; !bytes.Equal(actual, expected) {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	// other types are still handled by the checked encoders
+	_, err = trusted.BinaryFromNative(nil, map[string]interface{}{"i": "13", "l": 0, "f": 0, "d": 0})
+	ensureError(t, err, "cannot encode binary int: expected: Go numeric; received: string")
+	wide, err := trusted.WithOptions(WithNumericDecoding(NumericDecodingWide))
+	ensureError(t, err)
+	_, err = wide.BinaryFromNative(nil, map[string]interface{}{"i": json.Number("13"), "l": 0, "f": 0, "d": 0})
+	ensureError(t, err)
+
+	_, err = NewCodec(`"int"`, WithTrustedEncoding(true), WithValidateOnEncode(true))
+	ensureError(t, err, "cannot create codec", "trusted encoding ought not to be combined with validate on encode")
+}
+
+func TestCodecOptionTrailingBytes(t *testing.T) {
+	schema := `{"type":"record","name":"r","fields":[{"name":"n","type":"long"}]}`
+	returning := newCodecUsingV2(t, schema)
+	strict, err := returning.WithOptions(WithTrailingBytes(TrailingBytesError))
+	ensureError(t, err)
+	ignoring, err := returning.WithOptions(WithTrailingBytes(TrailingBytesIgnore))
+	ensureError(t, err)
+	expected := map[string]interface{}{"n": int64(1)}
+
+	buf := []byte{2, 4}
+	_, rest, err := returning.NativeFromBinary(buf)
+	ensureError(t, err)
+	if !bytes.Equal(rest, []byte{4}) {
+		t.Errorf("GOT: %v; WANT: %v", rest, []byte{4})
+	}
+	_, rest, err = strict.NativeFromBinary(buf)
+	ensureError(t, err, "cannot decode binary: 1 bytes following datum")
+	if !bytes.Equal(rest, buf) {
+		t.Errorf("GOT: %v; WANT: %v", rest, buf)
+	}
+	datum, rest, err := strict.NativeFromBinary(buf[:1])
+	ensureError(t, err)
+	if !reflect.DeepEqual(datum, expected) || len(rest) != 0 {
+		t.Errorf("GOT: %v, %v; WANT: %v, []", datum, rest, expected)
+	}
+	datum, rest, err = ignoring.NativeFromBinary(buf)
+	ensureError(t, err)
+	if !reflect.DeepEqual(datum, expected) || len(rest) != 0 {
+		t.Errorf("GOT: %v, %v; WANT: %v, []", datum, rest, expected)
+	}
+
+	_, err = strict.NativeFromBinaryInto(buf, make(map[string]interface{}))
+	ensureError(t, err, "1 bytes following datum")
+	_, _, err = strict.NativeFromBinaryFields(buf, []string{"n"})
+	ensureError(t, err, "1 bytes following datum")
+	single, err := strict.SingleFromNative(nil, expected)
+	ensureError(t, err)
+	_, _, err = strict.NativeFromSingle(append(single, 0))
+	ensureError(t, err, "cannot decode single-object: 1 bytes following datum")
+
+	_, _, err = strict.NativeFromTextual([]byte("{\"n\":1} \n"))
+	ensureError(t, err)
+	_, _, err = strict.NativeFromTextual([]byte(`{"n":1} {"n":2}`))
+	ensureError(t, err, "cannot decode textual: 8 bytes following datum")
+	_, rest, err = ignoring.NativeFromTextual([]byte(`{"n":1} {"n":2}`))
+	ensureError(t, err)
+	if len(rest) != 0 {
+		t.Errorf("GOT: %v; WANT: %v", rest, []byte{})
+	}
+
+	_, err = NewCodec(schema, WithTrailingBytes(TrailingBytes(9)))
+	ensureError(t, err, "cannot create codec", "unknown trailing bytes handling")
+}
+
+func TestCodecWithOptions(t *testing.T) {
+	schema := `{"type":"record","name":"r","fields":[{"name":"e","type":{"type":"enum","name":"e","symbols":["a","b"]}},{"name":"d","type":{"type":"bytes","logicalType":"unknown"}}]}`
+	codec, err := NewCodec(schema, WithBlockLength(2))
+	ensureError(t, err)
+
+	variant, err := codec.WithOptions(WithEnumIndexDecoding(true))
+	ensureError(t, err)
+	if actual, expected := variant.Options(), (CodecOption{BlockLength: 2, EnumIndexDecoding: true}); actual != expected {
+		t.Errorf("GOT: %#v; WANT: %#v", actual, expected)
+	}
+	if actual, expected := codec.Options(), (CodecOption{BlockLength: 2}); actual != expected {
+		t.Errorf("GOT: %#v; WANT: %#v", actual, expected)
+	}
+	if variant.Schema() != codec.Schema() || variant.CanonicalSchema() != codec.CanonicalSchema() || variant.Rabin != codec.Rabin {
+		t.Errorf("GOT: %q; WANT: %q", variant.CanonicalSchema(), codec.CanonicalSchema())
+	}
+
+	buf := []byte{0x02, 0x00}
+	got, _, err := variant.NativeFromBinary(buf)
+	ensureError(t, err)
+	if want := map[string]interface{}{"e": 1, "d": []byte{}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GOT: %#v; WANT: %#v", got, want)
+	}
+	got, _, err = codec.NativeFromBinary(buf)
+	ensureError(t, err)
+	if want := map[string]interface{}{"e": "b", "d": []byte{}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GOT: %#v; WANT: %#v", got, want)
+	}
+
+	same, err := variant.WithOptions(WithEnumIndexDecoding(true))
+	ensureError(t, err)
+	if same != variant {
+		t.Errorf("GOT: %p; WANT: %p", same, variant)
+	}
+
+	_, err = codec.WithOptions(WithNumericDecoding(NumericDecoding(99)))
+	ensureError(t, err, "cannot derive codec", "unknown numeric decoding")
+}
+
+func TestCodecWithOptionsConcurrently(t *testing.T) {
+	codec := newCodecUsingV2(t, `{"type":"record","name":"r","fields":[{"name":"d","type":{"type":"bytes","logicalType":"decimal","precision":4,"scale":2}},{"name":"u","type":{"type":"string","logicalType":"unknown"}}]}`)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := codec.WithOptions(WithBlockLength(i + 1)); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
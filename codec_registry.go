@@ -0,0 +1,347 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// CodecRegistry holds named types defined by schemas added to it, so codecs may
+// be built from schemas that reference those types without defining them, such
+// as when each type is defined in a file of its own. A CodecRegistry is safe for
+// concurrent use.
+//
+//     registry := goavro.NewCodecRegistry()
+//     if _, err := registry.Add(addressSchema); err != nil { // defines com.acme.Address
+//         return err
+//     }
+//     codec, err := registry.Codec(`{"type":"record","name":"com.acme.User","fields":[
+//         {"name":"home","type":"Address"}
+//     ]}`)
+//
+// The definition of each referenced type is written in place of the first
+// reference to it, as Java does when parsing several files with a single
+// Schema.Parser, so the schema of the returned Codec is self-contained, and may
+// be written to an OCF or sent to a schema registry.
+type CodecRegistry struct {
+	mu    sync.RWMutex
+	opts  []Option
+	types map[string]interface{} // full name to definition, referencing the named types it holds by full name
+}
+
+// NewCodecRegistry returns an empty CodecRegistry, which builds codecs using the
+// specified options.
+func NewCodecRegistry(opts ...Option) *CodecRegistry {
+	return &CodecRegistry{
+		opts:  append([]Option(nil), opts...),
+		types: make(map[string]interface{}),
+	}
+}
+
+// Add builds a Codec from the schema specification, which may reference the
+// named types already added to the registry, and adds the named types it
+// defines, including those nested within it, to the registry. Add returns an
+// error when the schema defines a type already added to the registry, in which
+// case no type is added.
+func (cr *CodecRegistry) Add(schemaSpecification string) (*Codec, error) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	e, codec, err := cr.build(schemaSpecification)
+	if err != nil {
+		return nil, err
+	}
+	types := make(map[string]interface{})
+	flattenNamedTypes(e.schema, nullNamespace, types)
+	for fullName := range types {
+		if _, ok := e.inlined[fullName]; ok {
+			delete(types, fullName)
+		} else if _, ok := cr.types[fullName]; ok {
+			return nil, fmt.Errorf("cannot add schema: named type already registered: %q", fullName)
+		}
+	}
+	for fullName, definition := range types {
+		cr.types[fullName] = definition
+	}
+	return codec, nil
+}
+
+// Codec builds a Codec from the schema specification, which may reference the
+// named types added to the registry, without adding the types it defines.
+func (cr *CodecRegistry) Codec(schemaSpecification string) (*Codec, error) {
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+	_, codec, err := cr.build(schemaSpecification)
+	return codec, err
+}
+
+// Names returns the full names of the named types added to the registry, in
+// order.
+func (cr *CodecRegistry) Names() []string {
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+	names := make([]string, 0, len(cr.types))
+	for fullName := range cr.types {
+		names = append(names, fullName)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// build parses the schema specification, writes the definitions of the
+// registered types it references in place, and returns the expander holding
+// the resulting schema, along with its Codec. The caller holds the lock.
+func (cr *CodecRegistry) build(schemaSpecification string) (*registryExpander, *Codec, error) {
+	e := newRegistryExpander(cr.types, nil)
+	codec, err := e.build(schemaSpecification, cr.opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return e, codec, nil
+}
+
+// NewCodecWithResolver returns a Codec for the schema specification, calling
+// resolve with the full name of each named type the schema references without
+// defining it. resolve returns the specification of a schema defining the
+// type, such as the contents of the file of the type, which may itself
+// reference other types, resolved the same way. This lets large schema
+// repositories organized as one type per file build codecs without inlining
+// the definitions of the types they reference.
+//
+//     codec, err := goavro.NewCodecWithResolver(schema, func(fullName string) (string, error) {
+//         buf, err := ioutil.ReadFile(filepath.Join("schemas", fullName+".avsc"))
+//         return string(buf), err
+//     })
+//
+// resolve is called at most once for each full name, and not for names that
+// are defined by the schema before they are referenced. When resolve returns
+// an error, NewCodecWithResolver returns it. As with CodecRegistry, the
+// definition of each resolved type is written in place of the first reference
+// to it, so the schema of the returned Codec is self-contained.
+func NewCodecWithResolver(schemaSpecification string, resolve func(fullName string) (string, error), opts ...Option) (*Codec, error) {
+	if resolve == nil {
+		return nil, errors.New("cannot create codec: resolve is nil")
+	}
+	return newRegistryExpander(nil, resolve).build(schemaSpecification, opts)
+}
+
+// registryExpander writes the definitions of registered named types in place
+// of the first reference to each of them. The definitions of the types
+// resolved while expanding a schema are kept apart from the registered types,
+// which are not modified.
+type registryExpander struct {
+	schema   interface{}
+	types    map[string]interface{}
+	resolve  func(fullName string) (string, error)
+	resolved map[string]interface{} // definitions of the types returned by resolve
+	defined  map[string]struct{}    // named types defined so far
+	inlined  map[string]struct{}    // registered types whose definition was written
+	err      error                  // first error returned by resolve
+}
+
+func newRegistryExpander(types map[string]interface{}, resolve func(fullName string) (string, error)) *registryExpander {
+	return &registryExpander{
+		types:    types,
+		resolve:  resolve,
+		resolved: make(map[string]interface{}),
+		defined:  make(map[string]struct{}),
+		inlined:  make(map[string]struct{}),
+	}
+}
+
+// build parses the schema specification, writes the definitions of the types
+// it references in place, and returns the Codec of the resulting schema.
+func (e *registryExpander) build(schemaSpecification string, opts []Option) (*Codec, error) {
+	var schema interface{}
+	if err := json.Unmarshal([]byte(schemaSpecification), &schema); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal schema JSON: %s", err)
+	}
+	e.schema = e.expand(schema, nullNamespace)
+	if e.err != nil {
+		return nil, fmt.Errorf("cannot create codec: %s", e.err)
+	}
+	if len(e.inlined) > 0 {
+		buf, err := json.Marshal(e.schema)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create codec: %s", err) // should not get here because schema was unmarshaled from JSON
+		}
+		schemaSpecification = string(buf)
+	}
+	return NewCodec(schemaSpecification, opts...)
+}
+
+// expand returns the schema with the references to registered types expanded.
+// Invalid schemas are returned unchanged, so NewCodec reports the error.
+func (e *registryExpander) expand(schema interface{}, namespace string) interface{} {
+	switch v := schema.(type) {
+	case string:
+		return e.expandReference(v, namespace)
+	case []interface{}:
+		for i, member := range v {
+			v[i] = e.expand(member, namespace)
+		}
+	case map[string]interface{}:
+		typeName, ok := v["type"].(string)
+		if !ok {
+			v["type"] = e.expand(v["type"], namespace)
+			return v
+		}
+		switch typeName {
+		case "record", "enum", "fixed":
+			n, err := newNameFromSchemaMap(namespace, v)
+			if err != nil {
+				return v
+			}
+			e.defined[n.fullName] = struct{}{}
+			if typeName != "record" {
+				return v
+			}
+			fields, _ := v["fields"].([]interface{})
+			for _, field := range fields {
+				if fieldMap, ok := field.(map[string]interface{}); ok {
+					// NOTE: fields are within the namespace of their record
+					fieldMap["type"] = e.expand(fieldMap["type"], n.namespace)
+				}
+			}
+		case "array":
+			v["items"] = e.expand(v["items"], namespace)
+		case "map":
+			v["values"] = e.expand(v["values"], namespace)
+		default:
+			v["type"] = e.expandReference(typeName, namespace)
+		}
+	}
+	return schema
+}
+
+// expandReference returns the definition of the referenced registered type,
+// the first time it is referenced, or otherwise the reference. Like
+// NewCodec, it looks up the name as written before looking it up within the
+// namespace.
+func (e *registryExpander) expandReference(reference, namespace string) interface{} {
+	for _, fullName := range []string{reference, namespace + "." + reference} {
+		if _, ok := e.defined[fullName]; ok {
+			return reference
+		}
+		definition, ok := e.types[fullName]
+		if !ok {
+			definition, ok = e.resolved[fullName]
+		}
+		if ok {
+			e.inlined[fullName] = struct{}{}
+			return e.expand(copyNative(definition), namespace)
+		}
+		if namespace == nullNamespace {
+			break
+		}
+	}
+	if e.resolve == nil || e.err != nil {
+		return reference
+	}
+	switch reference {
+	case "null", "boolean", "int", "long", "float", "double", "bytes", "string", "array", "map", "record", "enum", "fixed":
+		return reference
+	}
+	n, err := newName(reference, nullNamespace, namespace)
+	if err != nil {
+		return reference // NOTE: NewCodec reports the invalid name
+	}
+	if e.err = e.resolveType(n.fullName); e.err != nil {
+		return reference
+	}
+	e.inlined[n.fullName] = struct{}{}
+	return e.expand(copyNative(e.resolved[n.fullName]), namespace)
+}
+
+// resolveType calls resolve for the named type, and keeps the definitions of
+// the types defined by the schema it returns.
+func (e *registryExpander) resolveType(fullName string) error {
+	schemaSpecification, err := e.resolve(fullName)
+	if err != nil {
+		return fmt.Errorf("cannot resolve %q: %s", fullName, err)
+	}
+	var schema interface{}
+	if err = json.Unmarshal([]byte(schemaSpecification), &schema); err != nil {
+		return fmt.Errorf("cannot resolve %q: cannot unmarshal schema JSON: %s", fullName, err)
+	}
+	types := make(map[string]interface{})
+	flattenNamedTypes(schema, nullNamespace, types)
+	if _, ok := types[fullName]; !ok {
+		return fmt.Errorf("cannot resolve %q: schema ought to define it", fullName)
+	}
+	for name, definition := range types {
+		if _, ok := e.resolved[name]; !ok {
+			e.resolved[name] = definition
+		}
+	}
+	return nil
+}
+
+// flattenNamedTypes adds the definition of each named type of the schema to
+// types, with its full name, and with the named types it holds replaced by
+// their full names, so each may be written in place of a reference on its
+// own.
+func flattenNamedTypes(schema interface{}, namespace string, types map[string]interface{}) interface{} {
+	switch v := schema.(type) {
+	case []interface{}:
+		flattened := make([]interface{}, len(v))
+		for i, member := range v {
+			flattened[i] = flattenNamedTypes(member, namespace, types)
+		}
+		return flattened
+	case map[string]interface{}:
+		flattened := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			flattened[k] = copyNative(item)
+		}
+		typeName, ok := v["type"].(string)
+		if !ok {
+			flattened["type"] = flattenNamedTypes(v["type"], namespace, types)
+			return flattened
+		}
+		switch typeName {
+		case "record", "enum", "fixed":
+			n, err := newNameFromSchemaMap(namespace, v)
+			if err != nil {
+				return flattened // NOTE: NewCodec reports the invalid name
+			}
+			if typeName == "record" {
+				fields, _ := v["fields"].([]interface{})
+				flattenedFields := make([]interface{}, len(fields))
+				for i, field := range fields {
+					flattenedFields[i] = copyNative(field)
+					if fieldMap, ok := flattenedFields[i].(map[string]interface{}); ok {
+						fieldMap["type"] = flattenNamedTypes(fieldMap["type"], n.namespace, types)
+					}
+				}
+				flattened["fields"] = flattenedFields
+			}
+			flattened["name"] = n.fullName
+			delete(flattened, "namespace")
+			if n.namespace == nullNamespace {
+				// NOTE: prevent the type from inheriting the namespace of the
+				// schema it is written in
+				flattened["namespace"] = nullNamespace
+			}
+			types[n.fullName] = flattened
+			return n.fullName
+		case "array":
+			flattened["items"] = flattenNamedTypes(v["items"], namespace, types)
+		case "map":
+			flattened["values"] = flattenNamedTypes(v["values"], namespace, types)
+		}
+		return flattened
+	}
+	return schema
+}
@@ -0,0 +1,200 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCodecRegistry(t *testing.T) {
+	registry := NewCodecRegistry()
+	_, err := registry.Add(`{"type":"record","name":"Address","namespace":"com.acme","fields":[
+		{"name":"city","type":"string"},
+		{"name":"kind","type":{"type":"enum","name":"Kind","symbols":["HOME","WORK"]}}
+	]}`)
+	ensureError(t, err)
+	_, err = registry.Add(`{"type":"record","name":"com.acme.Customer","fields":[
+		{"name":"address","type":"Address"},
+		{"name":"kind","type":"Kind"}
+	]}`)
+	ensureError(t, err)
+	if actual, expected := fmt.Sprint(registry.Names()), "[com.acme.Address com.acme.Customer com.acme.Kind]"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	// types referenced both directly and through another type are defined once
+	codec, err := registry.Codec(`{"type":"record","name":"Order","namespace":"com.shop","fields":[
+		{"name":"shipping","type":"com.acme.Address"},
+		{"name":"customer","type":"com.acme.Customer"},
+		{"name":"billing","type":["null","com.acme.Address"]}
+	]}`)
+	ensureError(t, err)
+
+	inline := newCodecUsingV2(t, `{"type":"record","name":"Order","namespace":"com.shop","fields":[
+		{"name":"shipping","type":{"type":"record","name":"Address","namespace":"com.acme","fields":[
+			{"name":"city","type":"string"},
+			{"name":"kind","type":{"type":"enum","name":"Kind","symbols":["HOME","WORK"]}}
+		]}},
+		{"name":"customer","type":{"type":"record","name":"com.acme.Customer","fields":[
+			{"name":"address","type":"com.acme.Address"},
+			{"name":"kind","type":"com.acme.Kind"}
+		]}},
+		{"name":"billing","type":["null","com.acme.Address"]}
+	]}`)
+	// the schema of the codec is self-contained
+	_, err = NewCodec(codec.Schema())
+	ensureError(t, err)
+
+	datum := map[string]interface{}{
+		"shipping": map[string]interface{}{"city": "Oslo", "kind": "WORK"},
+		"customer": map[string]interface{}{
+			"address": map[string]interface{}{"city": "Bergen", "kind": "HOME"},
+			"kind":    "HOME",
+		},
+		"billing": nil,
+	}
+	buf, err := codec.BinaryFromNative(nil, datum)
+	ensureError(t, err)
+	expected, err := inline.BinaryFromNative(nil, datum)
+	ensureError(t, err)
+	if string(buf) != string(expected) {
+		t.Errorf("GOT: %v; WANT: %v", buf, expected)
+	}
+}
+
+func TestCodecRegistryErrors(t *testing.T) {
+	registry := NewCodecRegistry()
+	_, err := registry.Add(`{"type":"fixed","name":"com.acme.Hash","size":4}`)
+	ensureError(t, err)
+
+	_, err = registry.Add(`{"type":"record","name":"com.acme.Item","fields":[{"name":"hash","type":{"type":"fixed","name":"Hash","size":8}}]}`)
+	ensureError(t, err, "named type already registered: \"com.acme.Hash\"")
+	if actual, expected := fmt.Sprint(registry.Names()), "[com.acme.Hash]"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	_, err = registry.Codec(`{"type":"record","name":"r","fields":[{"name":"f","type":"Hash"}]}`)
+	ensureError(t, err, "unknown type name")
+}
+
+func TestCodecRegistryRecursive(t *testing.T) {
+	registry := NewCodecRegistry()
+	_, err := registry.Add(`{"type":"record","name":"com.acme.Node","fields":[{"name":"next","type":["null","Node"]}]}`)
+	ensureError(t, err)
+
+	codec, err := registry.Codec(`{"type":"array","items":"com.acme.Node"}`)
+	ensureError(t, err)
+	buf, err := codec.BinaryFromNative(nil, []interface{}{
+		map[string]interface{}{"next": Union("com.acme.Node", map[string]interface{}{"next": nil})},
+	})
+	ensureError(t, err)
+	if actual, expected := fmt.Sprint(buf), "[2 2 0 0]"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
+
+func TestCodecRegistryConcurrent(t *testing.T) {
+	registry := NewCodecRegistry()
+	_, err := registry.Add(`{"type":"enum","name":"com.acme.Color","symbols":["RED","BLUE"]}`)
+	ensureError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := registry.Add(fmt.Sprintf(`{"type":"record","name":"com.acme.R%d","fields":[{"name":"c","type":"com.acme.Color"}]}`, i)); err != nil {
+				t.Error(err)
+			}
+			if _, err := registry.Codec(`{"type":"map","values":"com.acme.Color"}`); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if actual, expected := len(registry.Names()), 9; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
+
+func TestCodecRegistryNullNamespace(t *testing.T) {
+	registry := NewCodecRegistry()
+	_, err := registry.Add(`{"type":"record","name":"Addr","fields":[{"name":"city","type":"string"}]}`)
+	ensureError(t, err)
+	codec, err := registry.Codec(`{"type":"record","name":"Customer","namespace":"com.acme","fields":[
+		{"name":"home","type":"Addr"},
+		{"name":"work","type":["null","Addr"]}
+	]}`)
+	ensureError(t, err)
+	if actual, expected := strings.Count(codec.Schema(), `"fields"`), 2; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v definitions: %s", actual, expected, codec.Schema())
+	}
+	if canonical := codec.CanonicalSchema(); !strings.Contains(canonical, `{"name":"Addr","type":"record"`) {
+		t.Errorf("GOT: %v; WANT: Addr in the null namespace", canonical)
+	}
+
+	datum, _, err := codec.NativeFromTextual([]byte(`{"home":{"city":"Oslo"},"work":{"Addr":{"city":"Bergen"}}}`))
+	ensureError(t, err)
+	work := datum.(map[string]interface{})["work"].(map[string]interface{})
+	if actual, expected := work["Addr"].(map[string]interface{})["city"], "Bergen"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
+
+func TestNewCodecWithResolver(t *testing.T) {
+	files := map[string]string{
+		"com.acme.Address": `{"type":"record","name":"Address","namespace":"com.acme","fields":[
+			{"name":"city","type":"string"},
+			{"name":"country","type":"Country"}
+		]}`,
+		"com.acme.Country": `{"type":"enum","name":"com.acme.Country","symbols":["NO","SE"]}`,
+	}
+	var calls []string
+	resolve := func(fullName string) (string, error) {
+		calls = append(calls, fullName)
+		schema, ok := files[fullName]
+		if !ok {
+			return "", fmt.Errorf("no such file")
+		}
+		return schema, nil
+	}
+
+	codec, err := NewCodecWithResolver(`{"type":"record","name":"User","namespace":"com.acme","fields":[
+		{"name":"home","type":"Address"},
+		{"name":"work","type":["null","com.acme.Address"]},
+		{"name":"citizenship","type":{"type":"array","items":"Country"}}
+	]}`, resolve)
+	ensureError(t, err)
+	if actual, expected := fmt.Sprint(calls), "[com.acme.Address com.acme.Country]"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	buf, err := codec.BinaryFromNative(nil, map[string]interface{}{
+		"home":        map[string]interface{}{"city": "Oslo", "country": "NO"},
+		"work":        nil,
+		"citizenship": []interface{}{"SE"},
+	})
+	ensureError(t, err)
+	if actual, expected := fmt.Sprint(buf), "[8 79 115 108 111 0 0 2 2 0]"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	_, err = NewCodec(codec.Schema())
+	ensureError(t, err)
+
+	_, err = NewCodecWithResolver(`{"type":"array","items":"com.acme.Missing"}`, resolve)
+	ensureError(t, err, `cannot resolve "com.acme.Missing": no such file`)
+
+	_, err = NewCodecWithResolver(`{"type":"array","items":"com.acme.Other"}`, func(string) (string, error) { return `"int"`, nil })
+	ensureError(t, err, `cannot resolve "com.acme.Other": schema ought to define it`)
+}
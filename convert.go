@@ -0,0 +1,73 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// convertArray converts interface{} to []interface{} if possible.
+func convertArray(datum interface{}) ([]interface{}, error) {
+	arrayValues, ok := datum.([]interface{})
+	if ok {
+		return arrayValues, nil
+	}
+	// NOTE: When given a slice of any other type, zip values to
+	// items as a convenience to client.
+	v := reflect.ValueOf(datum)
+	if v.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("cannot create []interface{}: expected slice; received: %T", datum)
+	}
+	// NOTE: Two better alternatives to the current algorithm are:
+	//   (1) mutate the reflection tuple underneath to convert the
+	//       []int, for example, to []interface{}, with O(1) complexity
+	//   (2) use copy builtin to zip the data items over with O(n) complexity,
+	//       but more efficient than what's below.
+	// Suggestions?
+	arrayValues = make([]interface{}, v.Len())
+	for idx := 0; idx < v.Len(); idx++ {
+		arrayValues[idx] = v.Index(idx).Interface()
+	}
+	return arrayValues, nil
+}
+
+// convertMap converts datum to map[string]interface{} if possible.
+func convertMap(datum interface{}) (map[string]interface{}, error) {
+	mapValues, ok := datum.(map[string]interface{})
+	if ok {
+		return mapValues, nil
+	}
+	// NOTE: When given a map of any other type, zip values to items as a
+	// convenience to client.
+	v := reflect.ValueOf(datum)
+	if v.Kind() != reflect.Map {
+		return nil, fmt.Errorf("cannot create map[string]interface{}: expected map[string]...; received: %T", datum)
+	}
+	// NOTE: Two better alternatives to the current algorithm are:
+	//   (1) mutate the reflection tuple underneath to convert the
+	//       map[string]int, for example, to map[string]interface{}, with
+	//       O(1) complexity.
+	//   (2) use copy builtin to zip the data items over with O(n) complexity,
+	//       but more efficient than what's below.
+	mapValues = make(map[string]interface{}, v.Len())
+	for _, key := range v.MapKeys() {
+		k, ok := key.Interface().(string)
+		if !ok {
+			// bail when map key type is not string
+			return nil, fmt.Errorf("cannot create map[string]interface{}: expected map[string]...; received: %T", datum)
+		}
+		mapValues[string(k)] = v.MapIndex(key).Interface()
+	}
+	return mapValues, nil
+}
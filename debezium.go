@@ -0,0 +1,225 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DebeziumOperation is the kind of change described by a Debezium change
+// event, as found in the `op` field of the event envelope.
+type DebeziumOperation string
+
+// The operations reported by Debezium change events.
+const (
+	DebeziumCreate   DebeziumOperation = "c"
+	DebeziumUpdate   DebeziumOperation = "u"
+	DebeziumDelete   DebeziumOperation = "d"
+	DebeziumRead     DebeziumOperation = "r" // row read while taking a snapshot
+	DebeziumTruncate DebeziumOperation = "t"
+	DebeziumMessage  DebeziumOperation = "m"
+)
+
+// DebeziumEvent is a decoded Debezium change event.
+type DebeziumEvent struct {
+	Operation DebeziumOperation
+
+	// Before and After are the row images before and after the change, and
+	// are nil when the envelope does not include them, such as the before
+	// image of a create, or the after image of a delete.
+	Before map[string]interface{}
+	After  map[string]interface{}
+
+	// Source describes the origin of the change, and is nil when the envelope
+	// has no source field.
+	Source map[string]interface{}
+
+	// Timestamp is the time at which the connector processed the change, read
+	// from the `ts_ms` field, and is the zero time when absent.
+	Timestamp time.Time
+}
+
+// Row returns the row image most relevant to the operation: the before image
+// for deletes, and the after image otherwise.
+func (e *DebeziumEvent) Row() map[string]interface{} {
+	if e.Operation == DebeziumDelete {
+		return e.Before
+	}
+	return e.After
+}
+
+// DebeziumEnvelope decodes Debezium change events, the records with `before`,
+// `after`, `op`, `source`, and `ts_ms` fields that Debezium connectors emit
+// for each changed row. It also provides a Codec for the row record alone, so
+// row images may be encoded or decoded without their envelope.
+type DebeziumEnvelope struct {
+	codec      *Codec
+	valueCodec *Codec
+	valueName  string          // full name of the row record, used as its union key
+	unions     map[string]bool // names of envelope fields whose type is a union
+}
+
+// NewDebeziumEnvelope returns a DebeziumEnvelope for change events encoded
+// with the provided Codec. It returns an error when the Codec's schema is not
+// a record with an `op` field and an `after` field whose type is a record, or
+// a union of null and a record.
+//
+//     envelope, err := goavro.NewDebeziumEnvelope(codec)
+//     if err != nil {
+//         return err
+//     }
+//     event, _, err := envelope.EventFromBinary(buf)
+//     if err != nil {
+//         return err
+//     }
+//     if event.Operation == goavro.DebeziumDelete {
+//         return remove(event.Before)
+//     }
+//     return upsert(event.After)
+func NewDebeziumEnvelope(codec *Codec) (*DebeziumEnvelope, error) {
+	root, err := schemaNodeFromCodec(codec)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create Debezium envelope: %s", err)
+	}
+	if root.typeName != "record" {
+		return nil, fmt.Errorf("cannot create Debezium envelope: schema ought to be a record; received: %s", root.label())
+	}
+
+	var value *schemaNode
+	var hasOp bool
+	unions := make(map[string]bool)
+	for _, f := range root.fields {
+		unions[f.name] = f.node.typeName == "union"
+		switch f.name {
+		case "op":
+			hasOp = true
+		case "after":
+			if value = debeziumRowNode(f.node); value == nil {
+				return nil, fmt.Errorf("cannot create Debezium envelope %q: after field ought to be a record or a union of null and a record; received: %s", root.fullName, f.node.label())
+			}
+		}
+	}
+	if !hasOp {
+		return nil, fmt.Errorf("cannot create Debezium envelope %q: schema ought to have op field", root.fullName)
+	}
+	if value == nil {
+		return nil, fmt.Errorf("cannot create Debezium envelope %q: schema ought to have after field", root.fullName)
+	}
+
+	buf, err := json.Marshal(value.schemaValue(make(map[*schemaNode]struct{})))
+	if err != nil {
+		return nil, fmt.Errorf("cannot create Debezium envelope %q: %s", root.fullName, err)
+	}
+	valueCodec, err := NewCodec(string(buf))
+	if err != nil {
+		return nil, fmt.Errorf("cannot create Debezium envelope %q: cannot create row codec: %s", root.fullName, err)
+	}
+	return &DebeziumEnvelope{codec: codec, valueCodec: valueCodec, valueName: value.fullName, unions: unions}, nil
+}
+
+// debeziumRowNode returns the record node of a row image field, or nil when
+// the field is neither a record nor a union of null and a record.
+func debeziumRowNode(n *schemaNode) *schemaNode {
+	if n.typeName == "record" {
+		return n
+	}
+	if n.typeName != "union" || len(n.members) != 2 {
+		return nil
+	}
+	for i, member := range n.members {
+		if member.typeName == "record" && n.members[1-i].typeName == "null" {
+			return member
+		}
+	}
+	return nil
+}
+
+// Codec returns the Codec of the change event envelope.
+func (d *DebeziumEnvelope) Codec() *Codec { return d.codec }
+
+// ValueCodec returns a Codec for the row record of the `before` and `after`
+// fields, suitable for encoding and decoding row images without their
+// envelope.
+func (d *DebeziumEnvelope) ValueCodec() *Codec { return d.valueCodec }
+
+// EventFromBinary decodes one binary change event from buf. On success, it
+// returns the decoded event, the remaining unread bytes of buf, and a nil
+// error value. On error, it returns nil for the event, the original buf, and
+// the error message.
+func (d *DebeziumEnvelope) EventFromBinary(buf []byte) (*DebeziumEvent, []byte, error) {
+	datum, newBuf, err := d.codec.NativeFromBinary(buf)
+	if err != nil {
+		return nil, buf, fmt.Errorf("cannot decode Debezium event: %s", err)
+	}
+	event, err := d.EventFromNative(datum)
+	if err != nil {
+		return nil, buf, err
+	}
+	return event, newBuf, nil
+}
+
+// EventFromNative converts a change event decoded by the envelope Codec into a
+// DebeziumEvent, unwrapping the union values of its fields.
+func (d *DebeziumEnvelope) EventFromNative(datum interface{}) (*DebeziumEvent, error) {
+	fields, ok := datum.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot decode Debezium event: expected map[string]interface{}; received: %T", datum)
+	}
+	event := new(DebeziumEvent)
+
+	op, ok := d.unwrap(fields, "op").(string)
+	if !ok {
+		return nil, fmt.Errorf("cannot decode Debezium event: op ought to be a string; received: %T", fields["op"])
+	}
+	event.Operation = DebeziumOperation(op)
+
+	var err error
+	if event.Before, err = d.row(fields, "before"); err != nil {
+		return nil, err
+	}
+	if event.After, err = d.row(fields, "after"); err != nil {
+		return nil, err
+	}
+	event.Source, _ = d.unwrap(fields, "source").(map[string]interface{})
+
+	switch ts := d.unwrap(fields, "ts_ms").(type) {
+	case int64:
+		event.Timestamp = time.Unix(0, ts*int64(time.Millisecond)).UTC()
+	case time.Time:
+		event.Timestamp = ts // ts_ms declared with the timestamp-millis logical type
+	}
+	return event, nil
+}
+
+func (d *DebeziumEnvelope) row(fields map[string]interface{}, fieldName string) (map[string]interface{}, error) {
+	value := d.unwrap(fields, fieldName)
+	if value == nil {
+		return nil, nil
+	}
+	row, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot decode Debezium event: %s ought to be a %q record; received: %T", fieldName, d.valueName, value)
+	}
+	return row, nil
+}
+
+// unwrap returns the value of the envelope field, without the map that wraps
+// the values of union fields.
+func (d *DebeziumEnvelope) unwrap(fields map[string]interface{}, fieldName string) interface{} {
+	value := fields[fieldName]
+	if m, ok := value.(map[string]interface{}); ok && d.unions[fieldName] && len(m) == 1 {
+		for _, v := range m {
+			return v
+		}
+	}
+	return value
+}
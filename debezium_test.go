@@ -0,0 +1,118 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+const debeziumTestSchema = `{
+  "type": "record",
+  "name": "Envelope",
+  "namespace": "dbserver1.inventory.customers",
+  "fields": [
+    {"name": "before", "type": ["null", {
+      "type": "record",
+      "name": "Value",
+      "fields": [
+        {"name": "id", "type": "int"},
+        {"name": "email", "type": ["null", "string"], "default": null}
+      ]
+    }], "default": null},
+    {"name": "after", "type": ["null", "Value"], "default": null},
+    {"name": "source", "type": {
+      "type": "record",
+      "name": "Source",
+      "namespace": "io.debezium.connector.mysql",
+      "fields": [{"name": "table", "type": ["null", "string"], "default": null}]
+    }},
+    {"name": "op", "type": "string"},
+    {"name": "ts_ms", "type": ["null", "long"], "default": null}
+  ]
+}`
+
+func TestDebeziumEnvelope(t *testing.T) {
+	codec := newCodecUsingV2(t, debeziumTestSchema)
+	envelope, err := NewDebeziumEnvelope(codec)
+	ensureError(t, err)
+
+	const valueName = "dbserver1.inventory.customers.Value"
+	buf, err := codec.BinaryFromNative(nil, map[string]interface{}{
+		"before": nil,
+		"after":  Union(valueName, map[string]interface{}{"id": 1001, "email": Union("string", "a@example.com")}),
+		"source": map[string]interface{}{"table": Union("string", "customers")},
+		"op":     "c",
+		"ts_ms":  Union("long", int64(1500000000123)),
+	})
+	ensureError(t, err)
+
+	event, rest, err := envelope.EventFromBinary(append(buf, 0xff))
+	ensureError(t, err)
+	if len(rest) != 1 {
+		t.Errorf("GOT: %v; WANT: %v", rest, []byte{0xff})
+	}
+	if got, want := event.Operation, DebeziumCreate; got != want {
+		t.Errorf("GOT: %q; WANT: %q", got, want)
+	}
+	if event.Before != nil {
+		t.Errorf("GOT: %v; WANT: %v", event.Before, nil)
+	}
+	wantRow := map[string]interface{}{"id": int32(1001), "email": map[string]interface{}{"string": "a@example.com"}}
+	if !reflect.DeepEqual(event.Row(), wantRow) {
+		t.Errorf("GOT: %v; WANT: %v", event.Row(), wantRow)
+	}
+	if got, want := event.Source["table"], Union("string", "customers"); !reflect.DeepEqual(got, want) {
+		t.Errorf("GOT: %v; WANT: %v", got, want)
+	}
+	if got, want := event.Timestamp, time.Unix(1500000000, 123000000).UTC(); !got.Equal(want) {
+		t.Errorf("GOT: %v; WANT: %v", got, want)
+	}
+
+	// the row codec encodes the after image without its envelope
+	rowBuf, err := envelope.ValueCodec().BinaryFromNative(nil, event.After)
+	ensureError(t, err)
+	if want := []byte{0xd2, 0x0f, 0x02, 0x1a, 'a', '@', 'e', 'x', 'a', 'm', 'p', 'l', 'e', '.', 'c', 'o', 'm'}; !reflect.DeepEqual(rowBuf, want) {
+		t.Errorf("GOT: %v; WANT: %v", rowBuf, want)
+	}
+}
+
+func TestDebeziumEnvelopeDelete(t *testing.T) {
+	codec := newCodecUsingV2(t, debeziumTestSchema)
+	envelope, err := NewDebeziumEnvelope(codec)
+	ensureError(t, err)
+
+	event, err := envelope.EventFromNative(map[string]interface{}{
+		"before": Union("dbserver1.inventory.customers.Value", map[string]interface{}{"id": int32(7), "email": nil}),
+		"after":  nil,
+		"source": map[string]interface{}{"table": nil},
+		"op":     "d",
+		"ts_ms":  nil,
+	})
+	ensureError(t, err)
+	if got, want := event.Row(), map[string]interface{}{"id": int32(7), "email": nil}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GOT: %v; WANT: %v", got, want)
+	}
+	if event.After != nil || !event.Timestamp.IsZero() {
+		t.Errorf("GOT: %v, %v; WANT: nil after image and zero timestamp", event.After, event.Timestamp)
+	}
+}
+
+func TestDebeziumEnvelopeInvalidSchema(t *testing.T) {
+	_, err := NewDebeziumEnvelope(newCodecUsingV2(t, `"string"`))
+	ensureError(t, err, "ought to be a record")
+
+	_, err = NewDebeziumEnvelope(newCodecUsingV2(t, `{"type":"record","name":"r","fields":[{"name":"after","type":"string"},{"name":"op","type":"string"}]}`))
+	ensureError(t, err, "after field ought to be a record")
+
+	_, err = NewDebeziumEnvelope(newCodecUsingV2(t, `{"type":"record","name":"r","fields":[{"name":"op","type":"string"}]}`))
+	ensureError(t, err, "ought to have after field")
+}
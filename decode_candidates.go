@@ -0,0 +1,120 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CandidateDecode describes the attempt to decode a payload using one of the
+// candidate codecs given to DecodeCandidates.
+type CandidateDecode struct {
+	Index int // index of the codec among the candidates
+	Codec *Codec
+
+	// Datum is the decoded datum, when the codec decoded the payload, even
+	// if bytes remain after it.
+	Datum interface{}
+
+	// Text is the Avro JSON textual encoding of Datum, for display.
+	Text string
+
+	// Extra is the number of bytes remaining after the datum.
+	Extra int
+
+	// Err is the error of decoding the payload, or nil.
+	Err error
+}
+
+// Clean returns true when the codec decoded the whole payload.
+func (c CandidateDecode) Clean() bool { return c.Err == nil && c.Extra == 0 }
+
+func (c CandidateDecode) String() string {
+	label := fmt.Sprintf("candidate %d (%s %#016x)", c.Index, c.Codec.typeName, c.Codec.Rabin)
+	switch {
+	case c.Err != nil:
+		return fmt.Sprintf("%s: fails: %s", label, c.Err)
+	case c.Extra > 0:
+		return fmt.Sprintf("%s: %d extra bytes after %s", label, c.Extra, c.Text)
+	default:
+		return fmt.Sprintf("%s: clean: %s", label, c.Text)
+	}
+}
+
+// CandidateDecodes lists the attempts of DecodeCandidates, in the order of the
+// candidates.
+type CandidateDecodes []CandidateDecode
+
+// Clean returns the attempts that decoded the whole payload.
+func (decodes CandidateDecodes) Clean() []CandidateDecode {
+	var clean []CandidateDecode
+	for _, c := range decodes {
+		if c.Clean() {
+			clean = append(clean, c)
+		}
+	}
+	return clean
+}
+
+// String returns the attempts, one per line.
+func (decodes CandidateDecodes) String() string {
+	var b strings.Builder
+	for _, c := range decodes {
+		b.WriteString(c.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// DecodeCandidates attempts to decode the binary payload using each candidate
+// codec, and reports which decode it cleanly, consuming every byte, and where
+// the others fail, to help identify the schema version that produced a
+// payload that does not identify its schema. Since an Avro payload does not
+// describe its own structure, several candidates may decode the payload
+// cleanly, in which case the decoded data tell them apart.
+//
+//     decodes := goavro.DecodeCandidates(payload, []*goavro.Codec{v1, v2, v3})
+//     fmt.Print(decodes)
+//     if clean := decodes.Clean(); len(clean) == 1 {
+//         fmt.Printf("written using %s\n", clean[0].Codec.Schema())
+//     }
+//
+// A payload prefixed with a single-object encoding header is decoded after
+// the header, and only by the candidate whose fingerprint it records.
+func DecodeCandidates(payload []byte, candidates []*Codec) CandidateDecodes {
+	fingerprint, buf, soeErr := FingerprintFromSOE(payload)
+	if soeErr != nil {
+		buf = payload
+	}
+	decodes := make(CandidateDecodes, len(candidates))
+	for i, codec := range candidates {
+		c := CandidateDecode{Index: i, Codec: codec}
+		if soeErr == nil && codec.Rabin != fingerprint {
+			c.Err = ErrWrongCodec(fingerprint)
+			decodes[i] = c
+			continue
+		}
+		datum, rest, err := codec.NativeFromBinary(buf)
+		if err != nil {
+			c.Err = err
+			decodes[i] = c
+			continue
+		}
+		c.Datum, c.Extra = datum, len(rest)
+		if text, err := codec.TextualFromNative(nil, datum); err == nil {
+			c.Text = string(text)
+		} else {
+			c.Text = fmt.Sprintf("%v", datum)
+		}
+		decodes[i] = c
+	}
+	return decodes
+}
@@ -0,0 +1,65 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeCandidates(t *testing.T) {
+	v1, err := NewCodec(`{"type":"record","name":"User","fields":[{"name":"name","type":"string"}]}`)
+	ensureError(t, err)
+	v2, err := NewCodec(`{"type":"record","name":"User","fields":[{"name":"name","type":"string"},{"name":"age","type":"int"}]}`)
+	ensureError(t, err)
+	v3, err := NewCodec(`{"type":"record","name":"User","fields":[{"name":"name","type":"string"},{"name":"email","type":"string"}]}`)
+	ensureError(t, err)
+	candidates := []*Codec{v1, v2, v3}
+
+	payload, err := v2.BinaryFromNative(nil, map[string]interface{}{"name": "ann", "age": 42})
+	ensureError(t, err)
+	decodes := DecodeCandidates(payload, candidates)
+	if actual, expected := len(decodes), 3; actual != expected {
+		t.Fatalf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if decodes[0].Clean() || decodes[0].Extra != 1 {
+		t.Errorf("GOT: %+v", decodes[0])
+	}
+	if !decodes[1].Clean() || !reflect.DeepEqual(decodes[1].Datum, map[string]interface{}{"name": "ann", "age": int32(42)}) {
+		t.Errorf("GOT: %+v", decodes[1])
+	}
+	var text map[string]interface{}
+	ensureError(t, json.Unmarshal([]byte(decodes[1].Text), &text))
+	if actual, expected := text["age"], float64(42); actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	ensureError(t, decodes[2].Err, "short buffer")
+	if clean := decodes.Clean(); len(clean) != 1 || clean[0].Codec != v2 {
+		t.Errorf("GOT: %v", clean)
+	}
+	lines := strings.Split(decodes.String(), "\n")
+	if actual, expected := lines[0], `candidate 0 (User `; !strings.HasPrefix(actual, expected) || !strings.HasSuffix(actual, `): 1 extra bytes after {"name":"ann"}`) {
+		t.Errorf("GOT: %v", actual)
+	}
+	if actual := lines[2]; !strings.Contains(actual, "): fails: ") {
+		t.Errorf("GOT: %v", actual)
+	}
+
+	// single-object encoded payloads are only decoded by their codec
+	payload, err = v1.SingleFromNative(nil, map[string]interface{}{"name": "ann"})
+	ensureError(t, err)
+	decodes = DecodeCandidates(payload, candidates)
+	if !decodes[0].Clean() {
+		t.Errorf("GOT: %+v", decodes[0])
+	}
+	ensureError(t, decodes[1].Err, "wrong codec")
+}
@@ -0,0 +1,154 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// errDecodeMalformed stops scanning a datum the decoder will reject anyway, so
+// it reports the error.
+var errDecodeMalformed = errors.New("malformed datum")
+
+// decodeLimiter scans a binary encoded datum before it is decoded, rejecting
+// data exceeding the MaxDecodeDepth or MaxBytesPerDatum options, so a datum
+// whose block counts claim more items than it could hold fails before any
+// item is allocated.
+type decodeLimiter struct {
+	maxDepth int
+	maxBytes int64
+	start    int   // length of the buffer holding the datum
+	empty    int64 // number of array and map items encoded in zero bytes
+}
+
+// checkDecodeLimits returns an error when the binary encoded datum at the
+// start of buf exceeds the MaxDecodeDepth or MaxBytesPerDatum options of the
+// Codec. Data that cannot be scanned are left for the decoder to reject.
+func (c *Codec) checkDecodeLimits(buf []byte) error {
+	if c.option.MaxDecodeDepth == 0 && c.option.MaxBytesPerDatum == 0 {
+		return nil
+	}
+	n, err := schemaNodeFromCodec(c)
+	if err != nil {
+		return err
+	}
+	l := &decodeLimiter{maxDepth: c.option.MaxDecodeDepth, maxBytes: int64(c.option.MaxBytesPerDatum), start: len(buf)}
+	if _, err = l.scan(n, buf, 0); err != nil && err != errDecodeMalformed {
+		return err
+	}
+	return nil
+}
+
+// cost returns the number of bytes counted against MaxBytesPerDatum once the
+// datum has been scanned up to buf.
+func (l *decodeLimiter) cost(buf []byte) int64 {
+	return int64(l.start-len(buf)) + l.empty
+}
+
+func (l *decodeLimiter) checkBytes(buf []byte, more int64) error {
+	if l.maxBytes > 0 && l.cost(buf)+more > l.maxBytes {
+		return fmt.Errorf("datum size exceeds MaxBytesPerDatum: %d", l.maxBytes)
+	}
+	return nil
+}
+
+func (l *decodeLimiter) scan(n *schemaNode, buf []byte, depth int) ([]byte, error) {
+	if l.maxDepth > 0 && depth > l.maxDepth {
+		return nil, fmt.Errorf("datum depth exceeds MaxDecodeDepth: %d", l.maxDepth)
+	}
+	var value interface{}
+	var err error
+
+	switch n.typeName {
+	case "union":
+		if value, buf, err = longNativeFromBinary(buf); err != nil {
+			return nil, errDecodeMalformed
+		}
+		index := value.(int64)
+		if index < 0 || index >= int64(len(n.members)) {
+			return nil, errDecodeMalformed
+		}
+		return l.scan(n.members[index], buf, depth+1)
+	case "record":
+		for _, f := range n.fields {
+			if buf, err = l.scan(f.node, buf, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	case "array":
+		return l.scanBlocks(buf, func(buf []byte) ([]byte, error) {
+			return l.scan(n.items, buf, depth+1)
+		})
+	case "map":
+		return l.scanBlocks(buf, func(buf []byte) ([]byte, error) {
+			size, buf, err := longNativeFromBinary(buf)
+			if err != nil {
+				return nil, errDecodeMalformed
+			}
+			if buf, err = skipBinaryBytes(buf, size.(int64), "key"); err != nil {
+				return nil, errDecodeMalformed
+			}
+			if err = l.checkBytes(buf, 0); err != nil {
+				return nil, err
+			}
+			return l.scan(n.values, buf, depth+1)
+		})
+	default:
+		if buf, err = skipBinary(n, buf); err != nil {
+			return nil, errDecodeMalformed
+		}
+		return buf, l.checkBytes(buf, 0)
+	}
+}
+
+// scanBlocks scans the items of the blocks of an array or map. Because each
+// item is encoded in at least one byte, or is counted as one byte when it is
+// not, a block count is rejected before its items are scanned when they cannot
+// fit in MaxBytesPerDatum.
+func (l *decodeLimiter) scanBlocks(buf []byte, scanItem func([]byte) ([]byte, error)) ([]byte, error) {
+	for {
+		value, rest, err := longNativeFromBinary(buf)
+		if err != nil {
+			return nil, errDecodeMalformed
+		}
+		buf = rest
+		blockCount := value.(int64)
+		if blockCount == 0 {
+			return buf, nil
+		}
+		if blockCount < 0 {
+			if blockCount == math.MinInt64 {
+				return nil, errDecodeMalformed
+			}
+			blockCount = -blockCount
+			if _, buf, err = longNativeFromBinary(buf); err != nil {
+				return nil, errDecodeMalformed
+			}
+		}
+		if err = l.checkBytes(buf, blockCount); err != nil {
+			return nil, err
+		}
+		for i := int64(0); i < blockCount; i++ {
+			before := len(buf)
+			if buf, err = scanItem(buf); err != nil {
+				return nil, err
+			}
+			if len(buf) == before {
+				l.empty++
+				if err = l.checkBytes(buf, 0); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+}
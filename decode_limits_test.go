@@ -0,0 +1,93 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMaxDecodeDepth(t *testing.T) {
+	schema := `{"type":"record","name":"List","fields":[{"name":"next","type":["null","List"]}]}`
+	codec, err := NewCodec(schema, WithMaxDecodeDepth(4))
+	ensureError(t, err)
+
+	// each List nests the next within its field and union: depth 4
+	datum, _, err := codec.NativeFromBinary([]byte{2, 0})
+	ensureError(t, err)
+	if actual, expected := fmt.Sprint(datum), "map[next:map[List:map[next:<nil>]]]"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	buf := []byte{2, 2, 0}
+	_, rest, err := codec.NativeFromBinary(buf)
+	ensureError(t, err, "datum depth exceeds MaxDecodeDepth: 4")
+	if len(rest) != len(buf) {
+		t.Errorf("GOT: %v; WANT: %v", rest, buf)
+	}
+
+	message, err := codec.SingleFromNative(nil, map[string]interface{}{
+		"next": Union("List", map[string]interface{}{"next": Union("List", map[string]interface{}{"next": nil})}),
+	})
+	ensureError(t, err)
+	_, _, err = codec.NativeFromSingle(message)
+	ensureError(t, err, "cannot decode single-object: datum depth exceeds MaxDecodeDepth")
+	_, err = codec.NativeFromBinaryInto(buf, make(map[string]interface{}))
+	ensureError(t, err, "datum depth exceeds MaxDecodeDepth")
+	_, _, err = codec.NativeFromBinaryFields(buf, []string{"next"})
+	ensureError(t, err, "datum depth exceeds MaxDecodeDepth")
+
+	unlimited, err := codec.WithOptions(WithMaxDecodeDepth(0))
+	ensureError(t, err)
+	_, _, err = unlimited.NativeFromBinary(buf)
+	ensureError(t, err)
+}
+
+func TestMaxBytesPerDatum(t *testing.T) {
+	codec, err := NewCodec(`{"type":"array","items":"string"}`, WithMaxBytesPerDatum(8))
+	ensureError(t, err)
+
+	_, _, err = codec.NativeFromBinary([]byte{4, 2, 'a', 2, 'b', 0})
+	ensureError(t, err)
+
+	_, _, err = codec.NativeFromBinary([]byte{2, 16, 'a', 'b', 'c', 'd', 'e', 'f', 'g', 'h', 0})
+	ensureError(t, err, "cannot decode binary: datum size exceeds MaxBytesPerDatum: 8")
+
+	// a block count of 2^40 fails before any item is decoded
+	_, _, err = codec.NativeFromBinary([]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x40})
+	ensureError(t, err, "datum size exceeds MaxBytesPerDatum: 8")
+}
+
+func TestMaxBytesPerDatumEmptyItems(t *testing.T) {
+	codec, err := NewCodec(`{"type":"map","values":"null"}`, WithMaxBytesPerDatum(8))
+	ensureError(t, err)
+
+	// two keys of one byte each, and two null values counted as one byte each
+	_, _, err = codec.NativeFromBinary([]byte{4, 2, 'a', 2, 'b', 0})
+	ensureError(t, err)
+
+	codec, err = NewCodec(`{"type":"array","items":"null"}`, WithMaxBytesPerDatum(8))
+	ensureError(t, err)
+	_, _, err = codec.NativeFromBinary([]byte{14, 0})
+	ensureError(t, err)
+	_, _, err = codec.NativeFromBinary([]byte{6, 12, 0})
+	ensureError(t, err, "datum size exceeds MaxBytesPerDatum: 8")
+
+	// malformed data are reported by the decoder
+	_, _, err = codec.NativeFromBinary([]byte{2})
+	ensureError(t, err, "cannot decode binary array block count")
+}
+
+func TestDecodeLimitsInvalid(t *testing.T) {
+	_, err := NewCodec(`"int"`, WithMaxDecodeDepth(-1))
+	ensureError(t, err, "max decode depth ought to be zero or positive: -1")
+	_, err = NewCodec(`"int"`, WithMaxBytesPerDatum(-1))
+	ensureError(t, err, "max bytes per datum ought to be zero or positive: -1")
+}
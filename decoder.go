@@ -0,0 +1,283 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// maxLongEncodedLength is the largest number of bytes of a long.
+const maxLongEncodedLength = 10
+
+// Decoder decodes successive binary encoded data of the schema of a Codec from
+// an io.Reader, such as a file or a network stream holding data concatenated
+// without a container, so the stream need not be read into memory first. Only
+// the bytes of one datum are held at a time: the schema is followed to read
+// exactly the bytes of each datum, which are then decoded by the Codec.
+//
+//     decoder := goavro.NewDecoder(codec, r)
+//     for {
+//         datum, err := decoder.Decode()
+//         if err == io.EOF {
+//             break
+//         }
+//         if err != nil {
+//             return err
+//         }
+//         fmt.Println(datum)
+//     }
+//
+// The Decoder may read beyond the last datum it decodes, as it buffers its
+// input. A Decoder ought not to be used by multiple goroutines simultaneously.
+//
+// Each datum is decoded from a buffer of its own, so the bytes and fixed
+// values of a datum remain valid after later calls to Decode. When the Codec
+// was created with the ZeroCopyDecoding option, the buffer is reused instead,
+// and the values of a datum ought not to be used after the next call to
+// Decode.
+type Decoder struct {
+	codec *Codec
+	br    *bufio.Reader
+	buf   []byte // bytes of the datum being read
+	count int64  // number of data decoded
+	err   error  // error that stopped the decoder
+
+	memory memoryAccount // bytes of the datum held in the memory budget, if any
+}
+
+// NewDecoder returns a Decoder that reads data encoded using the schema of the
+// codec from ior.
+func NewDecoder(codec *Codec, ior io.Reader) *Decoder {
+	br, ok := ior.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(ior)
+	}
+	return &Decoder{codec: codec, br: br}
+}
+
+// DecoderConfig is used to specify creation parameters for Decoder.
+type DecoderConfig struct {
+	// MemoryBudget bounds the bytes of data held at once by the Decoders
+	// sharing it, (optional). See MemoryBudget.
+	MemoryBudget *MemoryBudget
+}
+
+// NewDecoderWithConfig returns a new Decoder, like NewDecoder, using the
+// specified configuration.
+func NewDecoderWithConfig(codec *Codec, ior io.Reader, config DecoderConfig) *Decoder {
+	d := NewDecoder(codec, ior)
+	d.memory.budget = config.MemoryBudget
+	return d
+}
+
+// Decode returns the next datum of the stream. It returns io.EOF when the
+// stream ends before the next datum, and io.ErrUnexpectedEOF, wrapped in its
+// error message, when the stream ends within a datum. Once Decode returns an
+// error, it returns the same error on every later call.
+func (d *Decoder) Decode() (interface{}, error) {
+	d.memory.releaseAll() // the previous datum
+	if d.err != nil {
+		return nil, d.err
+	}
+	n, err := schemaNodeFromCodec(d.codec)
+	if err != nil {
+		d.err = fmt.Errorf("cannot decode datum %d: %s", d.count, err)
+		return nil, d.err
+	}
+	if _, err = d.br.Peek(1); err != nil {
+		if err != io.EOF {
+			err = fmt.Errorf("cannot decode datum %d: %s", d.count, err)
+		}
+		d.err = err
+		return nil, d.err
+	}
+	if d.codec.option.ZeroCopyDecoding {
+		d.buf = d.buf[:0]
+	} else {
+		// NOTE: Decoded bytes and fixed values reference the buffer, so
+		// each datum is read into a new one.
+		d.buf = make([]byte, 0, cap(d.buf))
+	}
+	if err = d.read(n); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		d.err = fmt.Errorf("cannot decode datum %d: %s", d.count, err)
+		return nil, d.err
+	}
+	if err = d.memory.hold(int64(len(d.buf))); err != nil {
+		d.err = fmt.Errorf("cannot decode datum %d: %s", d.count, err)
+		return nil, d.err
+	}
+	datum, _, err := d.codec.NativeFromBinary(d.buf)
+	if err != nil {
+		d.memory.releaseAll()
+		d.err = fmt.Errorf("cannot decode datum %d: %s", d.count, err)
+		return nil, d.err
+	}
+	d.count++
+	return datum, nil
+}
+
+// MemoryUsage returns the approximate number of bytes of data held by the
+// decoder, which is the size of the datum it decoded last.
+func (d *Decoder) MemoryUsage() int64 {
+	return d.memory.held
+}
+
+// ReleaseMemory releases the bytes the decoder holds in its MemoryBudget, for
+// decoders abandoned before the end of the stream. The decoder ought not to be
+// used afterwards.
+func (d *Decoder) ReleaseMemory() {
+	d.memory.releaseAll()
+	d.buf = nil
+	if d.err == nil {
+		d.err = errors.New("decoder memory released")
+	}
+}
+
+// read appends the bytes of the datum of the node at the start of the stream
+// to d.buf.
+func (d *Decoder) read(n *schemaNode) error {
+	switch n.typeName {
+	case "null":
+		return nil
+	case "boolean":
+		return d.readBytes(1)
+	case "int", "long", "enum":
+		_, err := d.readLong()
+		return err
+	case "float":
+		return d.readBytes(floatEncodedLength)
+	case "double":
+		return d.readBytes(doubleEncodedLength)
+	case "bytes", "string":
+		size, err := d.readLong()
+		if err != nil {
+			return err
+		}
+		if size < 0 || size > MaxBlockSize {
+			return fmt.Errorf("%s size ought to be from 0 to MaxBlockSize: %d", n.typeName, size)
+		}
+		return d.readBytes(size)
+	case "fixed":
+		return d.readBytes(int64(n.size))
+	case "union":
+		index, err := d.readLong()
+		if err != nil {
+			return err
+		}
+		if index < 0 || index >= int64(len(n.members)) {
+			return fmt.Errorf("union index ought to be between 0 and %d; read index: %d", len(n.members)-1, index)
+		}
+		return d.read(n.members[index])
+	case "record":
+		for _, f := range n.fields {
+			if err := d.read(f.node); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "array":
+		return d.readBlocks("array", func() error { return d.read(n.items) })
+	case "map":
+		return d.readBlocks("map", func() error {
+			size, err := d.readLong()
+			if err != nil {
+				return err
+			}
+			if size < 0 || size > MaxBlockSize {
+				return fmt.Errorf("map key size ought to be from 0 to MaxBlockSize: %d", size)
+			}
+			if err = d.readBytes(size); err != nil {
+				return err
+			}
+			return d.read(n.values)
+		})
+	default:
+		return fmt.Errorf("unknown type: %q", n.typeName)
+	}
+}
+
+// readBlocks appends the blocks of an array or map, copying the blocks that
+// have a byte size, and invoking readItem for each item of those that do not.
+func (d *Decoder) readBlocks(kind string, readItem func() error) error {
+	for {
+		blockCount, err := d.readLong()
+		if err != nil {
+			return err
+		}
+		if blockCount == 0 {
+			return nil
+		}
+		if blockCount < 0 {
+			if blockCount == math.MinInt64 {
+				return fmt.Errorf("%s with block count: %d", kind, blockCount)
+			}
+			size, err := d.readLong()
+			if err != nil {
+				return err
+			}
+			if size < 0 || size > MaxBlockSize {
+				return fmt.Errorf("%s block size ought to be from 0 to MaxBlockSize: %d", kind, size)
+			}
+			if err = d.readBytes(size); err != nil {
+				return err
+			}
+			continue
+		}
+		if blockCount > MaxBlockCount {
+			return fmt.Errorf("%s when block count exceeds MaxBlockCount: %d > %d", kind, blockCount, MaxBlockCount)
+		}
+		for i := int64(0); i < blockCount; i++ {
+			if err = readItem(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// readLong appends a variable length zig-zag encoded long to d.buf, and
+// returns its value.
+func (d *Decoder) readLong() (int64, error) {
+	var value uint64
+	var shift uint
+	for i := 0; i < maxLongEncodedLength; i++ {
+		b, err := d.br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		d.buf = append(d.buf, b)
+		value |= uint64(b&intMask) << shift
+		if b&intFlag == 0 {
+			return int64(value>>1) ^ -int64(value&1), nil
+		}
+		shift += 7
+	}
+	return 0, fmt.Errorf("long ought to have at most %d bytes", maxLongEncodedLength)
+}
+
+// readBytes appends the next size bytes of the stream to d.buf.
+func (d *Decoder) readBytes(size int64) error {
+	start := len(d.buf)
+	if end := start + int(size); end > cap(d.buf) {
+		grown := make([]byte, end, 2*end)
+		copy(grown, d.buf)
+		d.buf = grown
+	} else {
+		d.buf = d.buf[:end]
+	}
+	_, err := io.ReadFull(d.br, d.buf[start:])
+	return err
+}
@@ -0,0 +1,108 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestDecoder(t *testing.T) {
+	codec, err := NewCodec(`{"type":"record","name":"r","fields":[
+		{"name":"id","type":"long"},
+		{"name":"tags","type":{"type":"array","items":"string"}},
+		{"name":"attrs","type":{"type":"map","values":["null","double"]}},
+		{"name":"hash","type":{"type":"fixed","name":"h","size":2}},
+		{"name":"flag","type":"boolean"},
+		{"name":"ratio","type":"float"}
+	]}`)
+	ensureError(t, err)
+	sized, err := codec.WithOptions(WithBlockSizes(true))
+	ensureError(t, err)
+
+	var stream []byte
+	var expected []interface{}
+	// every third datum has arrays and maps written with block sizes
+	for i := 0; i < 100; i++ {
+		datum := map[string]interface{}{
+			"id":    int64(i * 1000),
+			"tags":  []interface{}{strings.Repeat("t", i), "x"},
+			"attrs": map[string]interface{}{"a": nil, "b": Union("double", float64(i))},
+			"hash":  []byte{byte(i), 1},
+			"flag":  i%2 == 0,
+			"ratio": float32(i) / 4,
+		}
+		encoder := codec
+		if i%3 == 0 {
+			encoder = sized
+		}
+		if stream, err = encoder.BinaryFromNative(stream, datum); err != nil {
+			t.Fatal(err)
+		}
+		expected = append(expected, datum)
+	}
+
+	decoder := NewDecoder(codec, iotest.OneByteReader(bytes.NewReader(stream)))
+	for i, want := range expected {
+		datum, err := decoder.Decode()
+		ensureError(t, err)
+		if !reflect.DeepEqual(datum, want) {
+			t.Fatalf("datum %d: GOT: %v; WANT: %v", i, datum, want)
+		}
+	}
+	if _, err = decoder.Decode(); err != io.EOF {
+		t.Errorf("GOT: %v; WANT: %v", err, io.EOF)
+	}
+}
+
+func TestDecoderRetainsData(t *testing.T) {
+	codec, err := NewCodec(`{"type":"record","name":"r","fields":[{"name":"b","type":"bytes"},{"name":"f","type":{"type":"fixed","name":"f","size":3}}]}`)
+	ensureError(t, err)
+	var stream []byte
+	for _, datum := range []map[string]interface{}{{"b": []byte("first"), "f": []byte("AAA")}, {"b": []byte("other"), "f": []byte("BBB")}} {
+		stream, err = codec.BinaryFromNative(stream, datum)
+		ensureError(t, err)
+	}
+
+	decoder := NewDecoder(codec, bytes.NewReader(stream))
+	first, err := decoder.Decode()
+	ensureError(t, err)
+	_, err = decoder.Decode()
+	ensureError(t, err)
+	record := first.(map[string]interface{})
+	if actual, expected := string(record["b"].([]byte))+" "+string(record["f"].([]byte)), "first AAA"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
+
+func TestDecoderErrors(t *testing.T) {
+	codec, err := NewCodec(`{"type":"record","name":"r","fields":[{"name":"s","type":"string"},{"name":"u","type":["null","long"]}]}`)
+	ensureError(t, err)
+
+	_, err = NewDecoder(codec, bytes.NewReader([]byte{0x06, 'a', 'b'})).Decode()
+	ensureError(t, err, "cannot decode datum 0", "unexpected EOF")
+
+	decoder := NewDecoder(codec, bytes.NewReader([]byte{0x02, 'a', 0x00, 0x02, 'b', 0x04}))
+	_, err = decoder.Decode()
+	ensureError(t, err)
+	_, err = decoder.Decode()
+	ensureError(t, err, "cannot decode datum 1", "union index ought to be between 0 and 1; read index: 2")
+	_, err = decoder.Decode()
+	ensureError(t, err, "cannot decode datum 1")
+
+	_, err = NewDecoder(codec, bytes.NewReader([]byte{0x01})).Decode()
+	ensureError(t, err, "string size ought to be from 0 to MaxBlockSize: -1")
+	_, err = NewDecoder(codec, bytes.NewReader(bytes.Repeat([]byte{0xff}, 11))).Decode()
+	ensureError(t, err, "long ought to have at most 10 bytes")
+}
@@ -0,0 +1,97 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import "fmt"
+
+// NewDefaultDatum returns a native datum for the Codec's record schema, where
+// every field is populated using the default value declared in the
+// schema. Default values are converted by their schema to the form
+// NativeFromBinary returns, so a partially specified record default value has
+// its missing fields filled in from that record's own field defaults. It
+// returns an error when the schema is not a record, or when any field lacks a
+// default value.
+//
+// Each invocation returns a newly allocated datum, which the caller may modify
+// without affecting the Codec.
+//
+//     codec, err := goavro.NewCodec(`
+//         {
+//           "type": "record",
+//           "name": "Sample",
+//           "fields" : [
+//             {"name": "count", "type": "long", "default": 0},
+//             {"name": "next", "type": ["null", "string"], "default": null}
+//           ]
+//         }`)
+//     if err != nil {
+//         return err
+//     }
+//     datum, err := codec.NewDefaultDatum()
+//     if err != nil {
+//         return err
+//     }
+//     fmt.Println(datum) // map[count:0 next:<nil>]
+func (c *Codec) NewDefaultDatum() (interface{}, error) {
+	if c.recordFields == nil {
+		return nil, fmt.Errorf("cannot create default datum: schema ought to be a record; received: %q", c.typeName)
+	}
+	datum := make(map[string]interface{}, len(c.recordFields))
+	for _, field := range c.recordFields {
+		if !field.hasDefault {
+			return nil, fmt.Errorf("cannot create default datum for record %q: field %q has no default value", c.typeName, field.name)
+		}
+		value, err := field.codec.nativeFromDefaultValue(field.defaultValue)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create default datum for record %q field %q: %s", c.typeName, field.name, err)
+		}
+		datum[field.name] = value
+	}
+	return datum, nil
+}
+
+// nativeFromDefaultValue returns the default value of a field of the Codec's
+// schema in the native form the Codec decodes. The value is encoded and
+// decoded again, so the values it holds, such as array items, map values, the
+// fields of records that a partial default omits, and logical types, are
+// converted by their schema exactly as decoded data are, and none are shared
+// with the Codec.
+func (c *Codec) nativeFromDefaultValue(defaultValue interface{}) (interface{}, error) {
+	buf, err := c.binaryFromNative(nil, defaultValue)
+	if err != nil {
+		return nil, err
+	}
+	value, _, err := c.nativeFromBinary(buf)
+	return value, err
+}
+
+// copyNative returns a deep copy of the provided native datum, so mutable
+// values held by a Codec, such as default values, are never shared with
+// callers.
+func copyNative(datum interface{}) interface{} {
+	switch v := datum.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			m[k] = copyNative(item)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, item := range v {
+			s[i] = copyNative(item)
+		}
+		return s
+	case []byte:
+		return append([]byte(nil), v...)
+	default:
+		return datum
+	}
+}
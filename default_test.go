@@ -0,0 +1,128 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestNewDefaultDatumNotRecord(t *testing.T) {
+	codec := newCodecUsingV2(t, `"int"`)
+	_, err := codec.NewDefaultDatum()
+	ensureError(t, err, "cannot create default datum", "ought to be a record")
+}
+
+func TestNewDefaultDatumMissingDefault(t *testing.T) {
+	codec := newCodecUsingV2(t, `{"type":"record","name":"r1","fields":[{"name":"f1","type":"int","default":3},{"name":"f2","type":"string"}]}`)
+	_, err := codec.NewDefaultDatum()
+	ensureError(t, err, `record "r1"`, `field "f2" has no default value`)
+}
+
+func TestNewDefaultDatumNested(t *testing.T) {
+	codec := newCodecUsingV2(t, `{
+  "type": "record",
+  "name": "outer",
+  "fields": [
+    {"name": "id", "type": "long", "default": 7},
+    {"name": "blob", "type": "bytes", "default": "abc"},
+    {"name": "label", "type": ["null", "string"], "default": null},
+    {"name": "inner", "type": {"type": "record", "name": "inner", "fields": [
+      {"name": "a", "type": "int", "default": 1},
+      {"name": "b", "type": "string", "default": "bee"}
+    ]}, "default": {"a": 42}}
+  ]
+}`)
+	datum, err := codec.NewDefaultDatum()
+	ensureError(t, err)
+
+	record := datum.(map[string]interface{})
+	if got, want := record["id"], int64(7); got != want {
+		t.Errorf("GOT: %#v; WANT: %#v", got, want)
+	}
+	if got, want := record["blob"], []byte("abc"); !bytes.Equal(got.([]byte), want) {
+		t.Errorf("GOT: %#v; WANT: %#v", got, want)
+	}
+	if got := record["label"]; got != nil {
+		t.Errorf("GOT: %#v; WANT: %#v", got, nil)
+	}
+	inner := record["inner"].(map[string]interface{})
+	if got, want := inner["a"], int32(42); got != want {
+		t.Errorf("GOT: %#v; WANT: %#v", got, want)
+	}
+	if got, want := inner["b"], "bee"; got != want {
+		t.Errorf("GOT: %#v; WANT: %#v", got, want)
+	}
+
+	// materialized datum ought to encode using the codec
+	if _, err = codec.BinaryFromNative(nil, datum); err != nil {
+		t.Fatal(err)
+	}
+
+	// mutating returned datum ought not affect subsequent datums
+	record["blob"].([]byte)[0] = 'X'
+	datum, err = codec.NewDefaultDatum()
+	ensureError(t, err)
+	if got, want := datum.(map[string]interface{})["blob"], []byte("abc"); !bytes.Equal(got.([]byte), want) {
+		t.Errorf("GOT: %#v; WANT: %#v", got, want)
+	}
+}
+
+func TestNewDefaultDatumConvertedBySchema(t *testing.T) {
+	codec := newCodecUsingV2(t, `{
+  "type": "record",
+  "name": "r1",
+  "fields": [
+    {"name": "items", "type": {"type": "array", "items": "int"}, "default": [1, 2]},
+    {"name": "values", "type": {"type": "map", "values": "long"}, "default": {"k": 3}},
+    {"name": "hash", "type": {"type": "fixed", "name": "hash", "size": 2}, "default": "ab"},
+    {"name": "color", "type": {"type": "enum", "name": "color", "symbols": ["RED", "BLUE"]}, "default": "BLUE"},
+    {"name": "when", "type": {"type": "long", "logicalType": "timestamp-millis"}, "default": 1000},
+    {"name": "point", "type": [{"type": "record", "name": "point", "fields": [
+      {"name": "x", "type": "int"},
+      {"name": "y", "type": "int", "default": 5}
+    ]}, "null"], "default": {"x": 4}}
+  ]
+}`)
+	datum, err := codec.NewDefaultDatum()
+	ensureError(t, err)
+	record := datum.(map[string]interface{})
+
+	if got, want := fmt.Sprintf("%#v", record["items"]), fmt.Sprintf("%#v", []interface{}{int32(1), int32(2)}); got != want {
+		t.Errorf("GOT: %v; WANT: %v", got, want)
+	}
+	if got, want := fmt.Sprintf("%#v", record["values"]), fmt.Sprintf("%#v", map[string]interface{}{"k": int64(3)}); got != want {
+		t.Errorf("GOT: %v; WANT: %v", got, want)
+	}
+	if got, want := record["hash"], []byte("ab"); !bytes.Equal(got.([]byte), want) {
+		t.Errorf("GOT: %#v; WANT: %#v", got, want)
+	}
+	if got, want := record["color"], "BLUE"; got != want {
+		t.Errorf("GOT: %#v; WANT: %#v", got, want)
+	}
+	if got, want := record["when"], time.Unix(1, 0).UTC(); got != want {
+		t.Errorf("GOT: %#v; WANT: %#v", got, want)
+	}
+	want := map[string]interface{}{"point": map[string]interface{}{"x": int32(4), "y": int32(5)}}
+	if got := record["point"]; fmt.Sprintf("%#v", got) != fmt.Sprintf("%#v", want) {
+		t.Errorf("GOT: %#v; WANT: %#v", got, want)
+	}
+
+	// datum ought to be in the form decoded from the encoded datum
+	buf, err := codec.BinaryFromNative(nil, datum)
+	ensureError(t, err)
+	decoded, _, err := codec.NativeFromBinary(buf)
+	ensureError(t, err)
+	if got, want := fmt.Sprintf("%#v", datum), fmt.Sprintf("%#v", decoded); got != want {
+		t.Errorf("GOT: %v; WANT: %v", got, want)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// confluentHeaderLen is the length of the header of Confluent framed
+// messages: a zero magic byte, then a 4-byte big-endian schema ID.
+const confluentHeaderLen = 5
+
+const (
+	glueHeaderVersion     = 3  // first byte of AWS Glue Schema Registry framed messages
+	glueCompressionNone   = 0  // second byte of uncompressed Glue framed messages
+	glueCompressionZlib   = 5  // second byte of zlib compressed Glue framed messages
+	glueHeaderLen         = 18 // version, compression, and 16-byte schema version ID
+	glueSchemaVersionSize = 16
+)
+
+// Encoding names a way Avro data is framed.
+type Encoding string
+
+const (
+	// EncodingUnknown payloads are empty.
+	EncodingUnknown Encoding = "unknown"
+
+	// EncodingOCF payloads are Object Container Files, starting with the
+	// bytes "Obj" and 1.
+	EncodingOCF Encoding = "ocf"
+
+	// EncodingSingleObject payloads are single-object encoded, starting
+	// with the bytes C3 01 and the Rabin fingerprint of the writer schema.
+	EncodingSingleObject Encoding = "single-object"
+
+	// EncodingConfluent payloads are framed by the serializers of the
+	// Confluent Schema Registry, starting with a zero byte and the 4-byte
+	// big-endian ID of the writer schema.
+	EncodingConfluent Encoding = "confluent"
+
+	// EncodingGlue payloads are framed by the serializers of the AWS Glue
+	// Schema Registry, starting with the header version 3, a compression
+	// byte, and the 16-byte UUID of the writer schema version.
+	EncodingGlue Encoding = "glue"
+
+	// EncodingBare payloads have none of the other framings, and are
+	// presumably a datum without a header, whose schema is known from
+	// elsewhere.
+	EncodingBare Encoding = "bare"
+)
+
+// DetectedEncoding describes the framing of a payload, as returned by
+// DetectEncoding.
+type DetectedEncoding struct {
+	Encoding Encoding
+
+	// Fingerprint is the Rabin fingerprint of the writer schema of single
+	// object encoded payloads.
+	Fingerprint uint64
+
+	// SchemaID is the schema registry ID of the writer schema of Confluent
+	// framed payloads.
+	SchemaID uint32
+
+	// SchemaVersionID is the UUID of the writer schema version of Glue
+	// framed payloads.
+	SchemaVersionID [glueSchemaVersionSize]byte
+
+	// Compressed is true for Glue framed payloads whose datum is compressed
+	// using zlib.
+	Compressed bool
+
+	// Datum holds the bytes following the header: the encoded datum, or its
+	// compressed form. It holds the whole payload of bare payloads and of
+	// Object Container Files, which ought to be read using an OCFReader.
+	Datum []byte
+}
+
+// DetectEncoding identifies the framing of a payload of unknown origin from
+// its leading bytes, so generic tooling can route it to the right decoder.
+//
+//     switch detected := goavro.DetectEncoding(payload); detected.Encoding {
+//     case goavro.EncodingOCF:
+//         ocfr, err := goavro.NewOCFReader(bytes.NewReader(payload))
+//         ...
+//     case goavro.EncodingConfluent:
+//         codec, err := registry.Codec(detected.SchemaID)
+//         ...
+//         datum, _, err := codec.NativeFromBinary(detected.Datum)
+//     }
+//
+// The framings are recognized by their magic bytes, which a bare datum may
+// also start with by chance, such as a record whose first field is a long
+// zero. A payload is only taken for a framed one when it is long enough to
+// hold the header, and for a Glue framed one when its compression byte is
+// valid, but tooling that knows the framing of its data ought not to rely on
+// detection.
+func DetectEncoding(payload []byte) DetectedEncoding {
+	switch {
+	case len(payload) == 0:
+		return DetectedEncoding{Encoding: EncodingUnknown}
+	case len(payload) >= len(ocfMagicBytes) && bytes.Equal(payload[:len(ocfMagicBytes)], ocfMagicBytes):
+		return DetectedEncoding{Encoding: EncodingOCF, Datum: payload}
+	case len(payload) >= soeHeaderLen && payload[0] == 0xC3 && payload[1] == 0x01:
+		return DetectedEncoding{
+			Encoding:    EncodingSingleObject,
+			Fingerprint: binary.LittleEndian.Uint64(payload[soeMagicPrefix:]),
+			Datum:       payload[soeHeaderLen:],
+		}
+	case len(payload) >= confluentHeaderLen && payload[0] == 0:
+		return DetectedEncoding{
+			Encoding: EncodingConfluent,
+			SchemaID: binary.BigEndian.Uint32(payload[1:confluentHeaderLen]),
+			Datum:    payload[confluentHeaderLen:],
+		}
+	case len(payload) >= glueHeaderLen && payload[0] == glueHeaderVersion && (payload[1] == glueCompressionNone || payload[1] == glueCompressionZlib):
+		detected := DetectedEncoding{
+			Encoding:   EncodingGlue,
+			Compressed: payload[1] == glueCompressionZlib,
+			Datum:      payload[glueHeaderLen:],
+		}
+		copy(detected.SchemaVersionID[:], payload[2:glueHeaderLen])
+		return detected
+	}
+	return DetectedEncoding{Encoding: EncodingBare, Datum: payload}
+}
@@ -0,0 +1,60 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDetectEncoding(t *testing.T) {
+	codec, err := NewCodec(`"string"`)
+	ensureError(t, err)
+	bare, err := codec.BinaryFromNative(nil, "hello")
+	ensureError(t, err)
+
+	soe, err := codec.SingleFromNative(nil, "hello")
+	ensureError(t, err)
+	detected := DetectEncoding(soe)
+	if detected.Encoding != EncodingSingleObject || detected.Fingerprint != codec.Rabin || !bytes.Equal(detected.Datum, bare) {
+		t.Errorf("GOT: %+v", detected)
+	}
+
+	detected = DetectEncoding(append([]byte{0, 0, 0, 1, 2}, bare...))
+	if detected.Encoding != EncodingConfluent || detected.SchemaID != 258 || !bytes.Equal(detected.Datum, bare) {
+		t.Errorf("GOT: %+v", detected)
+	}
+
+	glue := append([]byte{3, 5}, bytes.Repeat([]byte{0xab}, 16)...)
+	detected = DetectEncoding(append(glue, bare...))
+	if detected.Encoding != EncodingGlue || !detected.Compressed || detected.SchemaVersionID[15] != 0xab || !bytes.Equal(detected.Datum, bare) {
+		t.Errorf("GOT: %+v", detected)
+	}
+
+	bb := new(bytes.Buffer)
+	ocfw, err := NewOCFWriter(OCFConfig{W: bb, Codec: codec})
+	ensureError(t, err)
+	ensureError(t, ocfw.Append([]interface{}{"hello"}))
+	if actual, expected := DetectEncoding(bb.Bytes()).Encoding, EncodingOCF; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	for _, payload := range [][]byte{bare, {0, 0}, {3, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, {0xC3, 0x01}} {
+		if actual, expected := DetectEncoding(payload).Encoding, EncodingBare; actual != expected {
+			t.Errorf("%v: GOT: %v; WANT: %v", payload, actual, expected)
+		}
+	}
+	if actual, expected := DetectEncoding(nil).Encoding, EncodingUnknown; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
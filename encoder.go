@@ -0,0 +1,195 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// encoderBufferSize is the number of encoded bytes an Encoder buffers before
+// writing them, when NewEncoderSize is not used.
+const encoderBufferSize = 4096
+
+// Encoder writes successive data, binary encoded using the schema of a Codec,
+// to an io.Writer, such as a network connection or a compressing writer,
+// without a container. Encoded data is buffered, and the buffer is reused, so
+// encoding a stream of data takes constant memory, apart from the largest
+// datum. Data written by an Encoder may be read using a Decoder.
+//
+//     encoder := goavro.NewEncoder(codec, conn)
+//     for _, datum := range data {
+//         if err := encoder.Encode(datum); err != nil {
+//             return err
+//         }
+//     }
+//     return encoder.Flush()
+//
+// An Encoder ought not to be used by multiple goroutines simultaneously.
+type Encoder struct {
+	codec *Codec
+	iow   io.Writer
+	buf   []byte
+	size  int   // number of buffered bytes that causes the buffer to be written
+	err   error // error that stopped the encoder
+}
+
+// NewEncoder returns an Encoder that writes data encoded using the schema of
+// the codec to iow, buffering 4096 bytes.
+func NewEncoder(codec *Codec, iow io.Writer) *Encoder {
+	return NewEncoderSize(codec, iow, encoderBufferSize)
+}
+
+// NewEncoderSize returns an Encoder that writes data encoded using the schema
+// of the codec to iow, buffering at least size bytes before writing them. When
+// size is not positive, each datum is written as it is encoded.
+func NewEncoderSize(codec *Codec, iow io.Writer, size int) *Encoder {
+	if size < 0 {
+		size = 0
+	}
+	return &Encoder{codec: codec, iow: iow, buf: make([]byte, 0, size), size: size}
+}
+
+// Encode encodes the datum into the buffer of the Encoder, and writes the
+// buffer once it holds at least its size. When the datum cannot be encoded,
+// Encode returns the error, and nothing of the datum is written, so later data
+// may still be encoded. Once writing fails, Encode and Flush return the same
+// error on every later call.
+func (e *Encoder) Encode(datum interface{}) error {
+	if e.err != nil {
+		return e.err
+	}
+	buf, err := e.codec.BinaryFromNative(e.buf, datum)
+	if err != nil {
+		return err
+	}
+	e.buf = buf
+	if len(e.buf) >= e.size {
+		return e.Flush()
+	}
+	return nil
+}
+
+// Buffered returns the number of encoded bytes not yet written.
+func (e *Encoder) Buffered() int { return len(e.buf) }
+
+// Flush writes the buffered bytes to the underlying io.Writer.
+func (e *Encoder) Flush() error {
+	if e.err != nil {
+		return e.err
+	}
+	if len(e.buf) == 0 {
+		return nil
+	}
+	n, err := e.iow.Write(e.buf)
+	if err == nil && n < len(e.buf) {
+		err = io.ErrShortWrite
+	}
+	if err != nil {
+		e.err = fmt.Errorf("cannot write encoded data: %s", err)
+		return e.err
+	}
+	e.buf = e.buf[:0]
+	return nil
+}
+
+// EncodeChannel encodes each datum received from ch, in the order received,
+// until ch is closed, then flushes the Encoder. When workers is greater than
+// one, up to that many data are encoded concurrently, which helps when
+// encoding large records is the bottleneck of a pipeline, while the encoded
+// data are still written in the order they were received.
+//
+//     data := make(chan interface{})
+//     go produce(data) // closes data when done
+//     if err := goavro.NewEncoder(codec, conn).EncodeChannel(data, runtime.NumCPU()); err != nil {
+//         return err
+//     }
+//
+// EncodeChannel returns the first error, leaving the data already encoded
+// buffered or written, and stops receiving from ch, so producers ought to stop
+// sending when it returns.
+func (e *Encoder) EncodeChannel(ch <-chan interface{}, workers int) error {
+	if workers <= 1 {
+		for datum := range ch {
+			if err := e.Encode(datum); err != nil {
+				return err
+			}
+		}
+		return e.Flush()
+	}
+	if e.err != nil {
+		return e.err
+	}
+
+	type encoded struct {
+		buf []byte
+		err error
+	}
+	type job struct {
+		datum  interface{}
+		result chan encoded
+	}
+	var bufs sync.Pool
+	jobs := make(chan job)
+	pending := make(chan chan encoded, 2*workers) // results in the order data were received
+	done := make(chan struct{})
+	defer close(done)
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for j := range jobs {
+				buf, _ := bufs.Get().([]byte)
+				buf, err := e.codec.BinaryFromNative(buf[:0], j.datum)
+				j.result <- encoded{buf: buf, err: err}
+			}
+		}()
+	}
+	go func() {
+		defer close(pending)
+		defer close(jobs)
+		for datum := range ch {
+			result := make(chan encoded, 1)
+			select {
+			case pending <- result:
+			case <-done:
+				return
+			}
+			select {
+			case jobs <- job{datum: datum, result: result}:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	for result := range pending {
+		r := <-result
+		if r.err != nil {
+			return r.err
+		}
+		e.buf = append(e.buf, r.buf...)
+		bufs.Put(r.buf)
+		if len(e.buf) >= e.size {
+			if err := e.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return e.Flush()
+}
+
+// BinaryFromNativeChannel encodes each datum received from ch using the schema
+// of the Codec, writing the encoded data to iow, until ch is closed. It is
+// shorthand for creating an Encoder and calling its EncodeChannel method with a
+// single worker, which may be used to encode data concurrently.
+func (c *Codec) BinaryFromNativeChannel(iow io.Writer, ch <-chan interface{}) error {
+	return NewEncoder(c, iow).EncodeChannel(ch, 1)
+}
@@ -0,0 +1,139 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// countingWriter counts the writes made to its buffer.
+type countingWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+func TestEncoder(t *testing.T) {
+	codec, err := NewCodec(`{"type":"record","name":"r","fields":[{"name":"id","type":"long"},{"name":"name","type":"string"}]}`)
+	ensureError(t, err)
+
+	w := new(countingWriter)
+	encoder := NewEncoderSize(codec, w, 64)
+	for i := 0; i < 100; i++ {
+		ensureError(t, encoder.Encode(map[string]interface{}{"id": int64(i), "name": "user"}))
+	}
+	if w.writes == 0 || w.writes > 20 {
+		t.Errorf("GOT: %v writes; WANT: some buffering", w.writes)
+	}
+	ensureError(t, encoder.Encode(map[string]interface{}{"id": "x"}), "cannot encode binary")
+	if encoder.Buffered() == 0 {
+		t.Errorf("GOT: %v; WANT: buffered bytes", encoder.Buffered())
+	}
+	ensureError(t, encoder.Flush())
+	if actual, expected := encoder.Buffered(), 0; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	decoder := NewDecoder(codec, &w.Buffer)
+	for i := 0; i < 100; i++ {
+		datum, err := decoder.Decode()
+		ensureError(t, err)
+		if actual, expected := datum.(map[string]interface{})["id"], int64(i); actual != expected {
+			t.Fatalf("GOT: %v; WANT: %v", actual, expected)
+		}
+	}
+	if _, err = decoder.Decode(); err != io.EOF {
+		t.Errorf("GOT: %v; WANT: %v", err, io.EOF)
+	}
+}
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("connection reset") }
+
+func TestEncoderWriteError(t *testing.T) {
+	codec, err := NewCodec(`"long"`)
+	ensureError(t, err)
+	encoder := NewEncoderSize(codec, failingWriter{}, 0)
+	ensureError(t, encoder.Encode(1), "cannot write encoded data: connection reset")
+	ensureError(t, encoder.Encode(2), "connection reset")
+	ensureError(t, encoder.Flush(), "connection reset")
+}
+
+func TestEncoderEncodeChannel(t *testing.T) {
+	codec, err := NewCodec(`{"type":"record","name":"r","fields":[{"name":"id","type":"long"}]}`)
+	ensureError(t, err)
+
+	for _, workers := range []int{0, 1, 4} {
+		ch := make(chan interface{})
+		go func() {
+			defer close(ch)
+			for i := 0; i < 1000; i++ {
+				ch <- map[string]interface{}{"id": int64(i)}
+			}
+		}()
+		var buf bytes.Buffer
+		ensureError(t, NewEncoderSize(codec, &buf, 128).EncodeChannel(ch, workers))
+
+		// data are written in the order they were received
+		decoder := NewDecoder(codec, &buf)
+		for i := 0; i < 1000; i++ {
+			datum, err := decoder.Decode()
+			ensureError(t, err)
+			if actual, expected := datum.(map[string]interface{})["id"], int64(i); actual != expected {
+				t.Fatalf("workers %d: GOT: %v; WANT: %v", workers, actual, expected)
+			}
+		}
+		if _, err = decoder.Decode(); err != io.EOF {
+			t.Errorf("workers %d: GOT: %v; WANT: %v", workers, err, io.EOF)
+		}
+	}
+}
+
+func TestEncoderEncodeChannelError(t *testing.T) {
+	codec, err := NewCodec(`"long"`)
+	ensureError(t, err)
+
+	ch := make(chan interface{}, 4)
+	ch <- 1
+	ch <- "two"
+	ch <- 3
+	close(ch)
+	var buf bytes.Buffer
+	encoder := NewEncoder(codec, &buf)
+	ensureError(t, encoder.EncodeChannel(ch, 2), "expected: Go numeric")
+	if actual, expected := encoder.Buffered(), 1; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
+
+func TestCodecBinaryFromNativeChannel(t *testing.T) {
+	codec, err := NewCodec(`"long"`)
+	ensureError(t, err)
+
+	ch := make(chan interface{}, 3)
+	ch <- 1
+	ch <- 2
+	ch <- 3
+	close(ch)
+	var buf bytes.Buffer
+	ensureError(t, codec.BinaryFromNativeChannel(&buf, ch))
+	if actual, expected := buf.Bytes(), []byte{0x02, 0x04, 0x06}; !bytes.Equal(actual, expected) {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
@@ -10,13 +10,15 @@
 package goavro
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
+	"strconv"
 )
 
 // enum does not have child objects, therefore whatever namespace it defines is
 // just to store its name in the symbol table.
-func makeEnumCodec(st map[string]*Codec, enclosingNamespace string, schemaMap map[string]interface{}) (*Codec, error) {
+func makeEnumCodec(st map[string]*Codec, enclosingNamespace string, schemaMap map[string]interface{}, option *CodecOption) (*Codec, error) {
 	c, err := registerNewCodec(st, schemaMap, enclosingNamespace)
 	if err != nil {
 		return nil, fmt.Errorf("Enum ought to have valid name: %s", err)
@@ -42,6 +44,7 @@ func makeEnumCodec(st map[string]*Codec, enclosingNamespace string, schemaMap ma
 		}
 		symbols[i] = symbol
 	}
+	c.symbols = symbols
 
 	c.nativeFromBinary = func(buf []byte) (interface{}, []byte, error) {
 		var value interface{}
@@ -49,15 +52,24 @@ func makeEnumCodec(st map[string]*Codec, enclosingNamespace string, schemaMap ma
 		var index int64
 
 		if value, buf, err = longNativeFromBinary(buf); err != nil {
-			return nil, nil, fmt.Errorf("cannot decode binary enum %q index: %s", c.typeName, err)
+			return nil, nil, decodeError(option, err, "cannot decode binary enum %q index: %s", c.typeName, err)
 		}
 		index = value.(int64)
 		if index < 0 || index >= int64(len(symbols)) {
-			return nil, nil, fmt.Errorf("cannot decode binary enum %q: index ought to be between 0 and %d; read index: %d", c.typeName, len(symbols)-1, index)
+			return nil, nil, decodeError(option, ErrInvalidBinary, "cannot decode binary enum %q: index ought to be between 0 and %d; read index: %d", c.typeName, len(symbols)-1, index)
+		}
+		if option.EnumIndexDecoding {
+			return enumIndexNative(option, index), buf, nil
 		}
 		return symbols[index], buf, nil
 	}
 	c.binaryFromNative = func(buf []byte, datum interface{}) ([]byte, error) {
+		if index, ok := enumIndex(datum); ok && option.EnumIndexDecoding {
+			if index < 0 || index >= int64(len(symbols)) {
+				return nil, fmt.Errorf("cannot encode binary enum %q: index ought to be between 0 and %d; received: %d", c.typeName, len(symbols)-1, index)
+			}
+			return longBinaryFromNative(buf, index)
+		}
 		someString, ok := datum.(string)
 		if !ok {
 			return nil, fmt.Errorf("cannot encode binary enum %q: expected string; received: %T", c.typeName, datum)
@@ -81,14 +93,23 @@ func makeEnumCodec(st map[string]*Codec, enclosingNamespace string, schemaMap ma
 			return nil, nil, fmt.Errorf("cannot decode textual enum: expected key: %s", err)
 		}
 		someString := value.(string)
-		for _, symbol := range symbols {
+		for i, symbol := range symbols {
 			if symbol == someString {
+				if option.EnumIndexDecoding {
+					return enumIndexNative(option, int64(i)), buf, nil
+				}
 				return someString, buf, nil
 			}
 		}
 		return nil, nil, fmt.Errorf("cannot decode textual enum %q: value ought to be member of symbols: %v; %q", c.typeName, symbols, someString)
 	}
 	c.textualFromNative = func(buf []byte, datum interface{}) ([]byte, error) {
+		if index, ok := enumIndex(datum); ok && option.EnumIndexDecoding {
+			if index < 0 || index >= int64(len(symbols)) {
+				return nil, fmt.Errorf("cannot encode textual enum %q: index ought to be between 0 and %d; received: %d", c.typeName, len(symbols)-1, index)
+			}
+			return stringTextualFromNative(buf, symbols[index])
+		}
 		someString, ok := datum.(string)
 		if !ok {
 			return nil, fmt.Errorf("cannot encode textual enum %q: expected string; received: %T", c.typeName, datum)
@@ -103,3 +124,33 @@ func makeEnumCodec(st map[string]*Codec, enclosingNamespace string, schemaMap ma
 
 	return c, nil
 }
+
+// enumIndexNative returns the decoded symbol index of the EnumIndexDecoding
+// option, which is an int unless the NumericDecoding option widens integers.
+func enumIndexNative(option *CodecOption, index int64) interface{} {
+	switch option.NumericDecoding {
+	case NumericDecodingWide:
+		return index
+	case NumericDecodingJSONNumber:
+		return json.Number(strconv.FormatInt(index, 10))
+	}
+	return int(index)
+}
+
+// enumIndex returns the symbol index provided as datum, when datum is a Go
+// integer. Codecs created with the EnumIndexDecoding option accept indexes as
+// well as symbols when encoding.
+func enumIndex(datum interface{}) (int64, bool) {
+	switch v := datum.(type) {
+	case int:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case json.Number:
+		i, err := v.Int64()
+		return i, err == nil
+	}
+	return 0, false
+}
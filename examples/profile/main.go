@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+var asJSON = flag.Bool("json", false, "print the profile as JSON, such as for a data quality dashboard")
+
+func usage() {
+	executable, err := os.Executable()
+	if err != nil {
+		executable = os.Args[0]
+	}
+	base := filepath.Base(executable)
+	fmt.Fprintf(os.Stderr, "Usage of %s:\n", base)
+	fmt.Fprintf(os.Stderr, "\t%s [-json] file1.avro [file2.avro...]\n", base)
+	fmt.Fprintf(os.Stderr, "\tReports per-field statistics of the records of OCF files written with\n")
+	fmt.Fprintf(os.Stderr, "\tthe same schema: null rates, estimated distinct counts, minimum and\n")
+	fmt.Fprintf(os.Stderr, "\tmaximum values, and length distributions.\n")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+	}
+
+	var profiler *goavro.Profiler
+	for _, arg := range flag.Args() {
+		fh, err := os.Open(arg)
+		if err != nil {
+			bail(err)
+		}
+		ocfr, err := goavro.NewOCFReader(fh)
+		if err != nil {
+			bail(fmt.Errorf("%s: %s", arg, err))
+		}
+		if profiler == nil {
+			if profiler, err = goavro.NewProfiler(ocfr.Codec()); err != nil {
+				bail(err)
+			}
+		}
+		err = profiler.ReadOCF(ocfr)
+		_ = fh.Close()
+		if err != nil {
+			bail(fmt.Errorf("%s: %s", arg, err))
+		}
+	}
+
+	profile := profiler.Profile()
+	if !*asJSON {
+		fmt.Print(profile)
+		return
+	}
+	buf, err := json.MarshalIndent(profile, "", "  ")
+	if err != nil {
+		bail(err)
+	}
+	fmt.Println(string(buf))
+}
+
+func bail(err error) {
+	fmt.Fprintf(os.Stderr, "%s\n", err)
+	os.Exit(1)
+}
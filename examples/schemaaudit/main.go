@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+var dump = flag.Bool("dump", false, "read registry dumps, which are JSON arrays of {subject, version, id, schema} objects, rather than schema files")
+
+func usage() {
+	executable, err := os.Executable()
+	if err != nil {
+		executable = os.Args[0]
+	}
+	base := filepath.Base(executable)
+	fmt.Fprintf(os.Stderr, "Usage of %s:\n", base)
+	fmt.Fprintf(os.Stderr, "\t%s [-dump] file1 [file2...]\n", base)
+	fmt.Fprintf(os.Stderr, "\tReports fingerprint collisions, schemas registered under several subjects,\n")
+	fmt.Fprintf(os.Stderr, "\tand IDs or versions registered with different schemas. The subject of a\n")
+	fmt.Fprintf(os.Stderr, "\tschema file is its name without extension. Exits with status 1 when\n")
+	fmt.Fprintf(os.Stderr, "\tthere are issues.\n")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+	}
+
+	var schemas []goavro.RegisteredSchema
+	for _, arg := range flag.Args() {
+		if *dump {
+			fh, err := os.Open(arg)
+			if err != nil {
+				bail(err)
+			}
+			some, err := goavro.RegisteredSchemasFromJSON(fh)
+			_ = fh.Close()
+			if err != nil {
+				bail(fmt.Errorf("%s: %s", arg, err))
+			}
+			schemas = append(schemas, some...)
+			continue
+		}
+		buf, err := ioutil.ReadFile(arg)
+		if err != nil {
+			bail(err)
+		}
+		subject := strings.TrimSuffix(filepath.Base(arg), filepath.Ext(arg))
+		schemas = append(schemas, goavro.RegisteredSchema{Subject: subject, Schema: string(buf)})
+	}
+
+	audit := goavro.AuditSchemas(schemas)
+	fmt.Print(audit)
+	fmt.Printf("%d schemas, %d fingerprints, %d issues\n", audit.Schemas, audit.Fingerprints, len(audit.Issues))
+	if len(audit.Issues) > 0 {
+		os.Exit(1)
+	}
+}
+
+func bail(err error) {
+	fmt.Fprintf(os.Stderr, "%s\n", err)
+	os.Exit(1)
+}
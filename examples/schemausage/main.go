@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+var asJSON = flag.Bool("json", false, "print the usage of every field, union, and enum as JSON")
+
+func usage() {
+	executable, err := os.Executable()
+	if err != nil {
+		executable = os.Args[0]
+	}
+	base := filepath.Base(executable)
+	fmt.Fprintf(os.Stderr, "Usage of %s:\n", base)
+	fmt.Fprintf(os.Stderr, "\t%s [-json] file1.avro [file2.avro...]\n", base)
+	fmt.Fprintf(os.Stderr, "\tReports the optional fields, union members, and enum symbols that the\n")
+	fmt.Fprintf(os.Stderr, "\trecords of OCF files written with the same schema do not use, which are\n")
+	fmt.Fprintf(os.Stderr, "\tthe candidates for pruning the schema.\n")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+	}
+
+	var analyzer *goavro.SchemaUsageAnalyzer
+	for _, arg := range flag.Args() {
+		fh, err := os.Open(arg)
+		if err != nil {
+			bail(err)
+		}
+		ocfr, err := goavro.NewOCFReader(fh)
+		if err != nil {
+			bail(fmt.Errorf("%s: %s", arg, err))
+		}
+		if analyzer == nil {
+			if analyzer, err = goavro.NewSchemaUsageAnalyzer(ocfr.Codec()); err != nil {
+				bail(err)
+			}
+		}
+		err = analyzer.ReadOCF(ocfr)
+		_ = fh.Close()
+		if err != nil {
+			bail(fmt.Errorf("%s: %s", arg, err))
+		}
+	}
+
+	report := analyzer.Usage()
+	if !*asJSON {
+		fmt.Print(report)
+		return
+	}
+	buf, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		bail(err)
+	}
+	fmt.Println(string(buf))
+}
+
+func bail(err error) {
+	fmt.Fprintf(os.Stderr, "%s\n", err)
+	os.Exit(1)
+}
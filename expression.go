@@ -0,0 +1,221 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ExpressionProgram evaluates a compiled expression against the native form
+// of a datum, and returns its result.
+type ExpressionProgram func(datum interface{}) (interface{}, error)
+
+// ExpressionEngine describes a language of expressions that filter and
+// transform decoded data, such as CEL or Starlark, registered using
+// RegisterExpressionEngine. goavro does not depend on any expression
+// language, so an engine is provided by a plugin package adapting one:
+//
+//     goavro.RegisterExpressionEngine(goavro.ExpressionEngine{
+//         Name: "cel",
+//         Compile: func(input, result *goavro.Codec, expression string) (goavro.ExpressionProgram, error) {
+//             env, err := cel.NewEnv(celDeclarations(input.Schema()))
+//             if err != nil {
+//                 return nil, err
+//             }
+//             ast, issues := env.Compile(expression)
+//             if issues.Err() != nil {
+//                 return nil, issues.Err()
+//             }
+//             if !celTypeMatches(ast.OutputType(), result) {
+//                 return nil, fmt.Errorf("expression returns %s", ast.OutputType())
+//             }
+//             program, err := env.Program(ast)
+//             if err != nil {
+//                 return nil, err
+//             }
+//             return func(datum interface{}) (interface{}, error) {
+//                 value, _, err := program.Eval(map[string]interface{}{"record": datum})
+//                 if err != nil {
+//                     return nil, err
+//                 }
+//                 return value.Value(), nil
+//             }, nil
+//         },
+//     })
+type ExpressionEngine struct {
+	// Name identifies the engine, and is used as the Engine of
+	// RecordTransformConfig.
+	Name string
+
+	// Compile parses the expression, and type checks it against the schema
+	// of the input codec, whose data the program evaluates, returning an
+	// error when the expression is invalid for that schema. When result is
+	// nil, the program ought to return a bool; otherwise it ought to return
+	// data of the schema of the result codec.
+	Compile func(input, result *Codec, expression string) (ExpressionProgram, error)
+}
+
+// RegisterExpressionEngine makes an expression engine available to
+// RecordTransform. It panics when Name is empty, when Compile is nil, or when
+// the engine is already registered.
+func RegisterExpressionEngine(engine ExpressionEngine) {
+	if engine.Name == "" || engine.Compile == nil {
+		panic("goavro: RegisterExpressionEngine requires Name and Compile")
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := registry.engines[engine.Name]; ok {
+		panic(fmt.Sprintf("goavro: RegisterExpressionEngine called twice for %q", engine.Name))
+	}
+	registry.engines[engine.Name] = engine
+}
+
+// registeredExpressionEngine returns the named expression engine.
+func registeredExpressionEngine(name string) (ExpressionEngine, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	engine, ok := registry.engines[name]
+	return engine, ok
+}
+
+// RecordTransformConfig is used to specify the parameters of
+// NewRecordTransform.
+type RecordTransformConfig struct {
+	// Engine names the registered expression engine that compiles the
+	// expressions, (required).
+	Engine string
+
+	// Filter is an expression returning true for the data items that ought
+	// to be kept, (optional). When empty, all data items are kept.
+	Filter string
+
+	// Transform is an expression returning the data item that replaces each
+	// kept data item, (optional). When empty, kept data items are returned
+	// unchanged.
+	Transform string
+
+	// Output is the codec of the data items returned by Transform,
+	// (optional), against whose schema the expression is type checked. When
+	// nil, Transform ought to return data of the input schema.
+	Output *Codec
+}
+
+// RecordTransform filters and transforms decoded data items using
+// expressions compiled against their schema, for lightweight pipelines
+// configured at runtime rather than in code.
+//
+//     transform, err := goavro.NewRecordTransform(ocfr.Codec(), goavro.RecordTransformConfig{
+//         Engine: "cel",
+//         Filter: `record.country == "NO"`,
+//     })
+//     if err != nil {
+//         return err
+//     }
+//     err = transform.ReadOCF(ocfr, func(datum interface{}) error {
+//         return ocfw.Append([]interface{}{datum})
+//     })
+type RecordTransform struct {
+	input, output *Codec
+	filter        ExpressionProgram
+	transform     ExpressionProgram
+}
+
+// NewRecordTransform returns a RecordTransform for data of the schema of the
+// codec, compiling the expressions of the config using its engine.
+func NewRecordTransform(codec *Codec, config RecordTransformConfig) (*RecordTransform, error) {
+	if codec == nil {
+		return nil, errors.New("cannot create RecordTransform: codec is nil")
+	}
+	engine, ok := registeredExpressionEngine(config.Engine)
+	if !ok {
+		return nil, fmt.Errorf("cannot create RecordTransform: unrecognized expression engine: %q", config.Engine)
+	}
+	rt := &RecordTransform{input: codec, output: codec}
+	if config.Output != nil {
+		if config.Transform == "" {
+			return nil, errors.New("cannot create RecordTransform: Output requires Transform")
+		}
+		rt.output = config.Output
+	}
+	var err error
+	if config.Filter != "" {
+		if rt.filter, err = engine.Compile(codec, nil, config.Filter); err != nil {
+			return nil, fmt.Errorf("cannot create RecordTransform: cannot compile filter: %s", err)
+		}
+	}
+	if config.Transform != "" {
+		if rt.transform, err = engine.Compile(codec, rt.output, config.Transform); err != nil {
+			return nil, fmt.Errorf("cannot create RecordTransform: cannot compile transform: %s", err)
+		}
+	}
+	return rt, nil
+}
+
+// Codec returns the codec of the data items returned by Apply.
+func (rt *RecordTransform) Codec() *Codec { return rt.output }
+
+// Apply evaluates the filter and transform against the datum, and returns
+// the transformed datum, and true, when the filter keeps it.
+func (rt *RecordTransform) Apply(datum interface{}) (interface{}, bool, error) {
+	if rt.filter != nil {
+		result, err := rt.filter(datum)
+		if err != nil {
+			return nil, false, fmt.Errorf("cannot evaluate filter: %s", err)
+		}
+		keep, ok := result.(bool)
+		if !ok {
+			return nil, false, fmt.Errorf("cannot evaluate filter: expected bool; received: %T", result)
+		}
+		if !keep {
+			return nil, false, nil
+		}
+	}
+	if rt.transform != nil {
+		var err error
+		if datum, err = rt.transform(datum); err != nil {
+			return nil, false, fmt.Errorf("cannot evaluate transform: %s", err)
+		}
+	}
+	return datum, true, nil
+}
+
+// ReadOCF applies the transform to each data item of the OCF, and calls emit
+// with each kept data item. The schema of the OCF ought to be the schema the
+// transform was created for.
+func (rt *RecordTransform) ReadOCF(ocfr *OCFReader, emit func(datum interface{}) error) error {
+	if ocfr.Codec().Rabin != rt.input.Rabin {
+		return errors.New("cannot transform OCF: schema of OCF differs from schema of transform")
+	}
+	var i int64
+	for ocfr.Scan() {
+		datum, err := ocfr.Read()
+		if err != nil {
+			return fmt.Errorf("cannot transform OCF: %s", err)
+		}
+		datum, keep, err := rt.Apply(datum)
+		if err != nil {
+			return fmt.Errorf("cannot transform OCF: data item %d: %s", i, err)
+		}
+		if keep {
+			if err = emit(datum); err != nil {
+				return err
+			}
+		}
+		i++
+	}
+	if err := ocfr.Err(); err != nil {
+		return fmt.Errorf("cannot transform OCF: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// testFieldEngine is a test expression engine, whose expressions name a field
+// of the input record. As a filter, that field ought to be a boolean. As a
+// transform, the record is projected to that field, which ought to be the
+// only field of the result schema.
+func testFieldEngine(input, result *Codec, expression string) (ExpressionProgram, error) {
+	field, err := testFieldNode(input, expression)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		if field.node.typeName != "boolean" {
+			return nil, fmt.Errorf("field %q is not a boolean: %s", expression, field.node.label())
+		}
+		return func(datum interface{}) (interface{}, error) {
+			return datum.(map[string]interface{})[expression], nil
+		}, nil
+	}
+	n, err := schemaNodeFromCodec(result)
+	if err != nil {
+		return nil, err
+	}
+	if len(n.fields) != 1 || n.fields[0].name != expression || n.fields[0].node.label() != field.node.label() {
+		return nil, fmt.Errorf("field %q does not match result schema", expression)
+	}
+	return func(datum interface{}) (interface{}, error) {
+		return map[string]interface{}{expression: datum.(map[string]interface{})[expression]}, nil
+	}, nil
+}
+
+func testFieldNode(codec *Codec, name string) (*schemaNodeField, error) {
+	n, err := schemaNodeFromCodec(codec)
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range n.fields {
+		if field.name == name {
+			return field, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown field: %q", name)
+}
+
+func init() {
+	RegisterExpressionEngine(ExpressionEngine{Name: "test-field", Compile: testFieldEngine})
+}
+
+const expressionSchema = `{"type":"record","name":"User","fields":[{"name":"name","type":"string"},{"name":"active","type":"boolean"}]}`
+
+func TestRecordTransform(t *testing.T) {
+	codec, err := NewCodec(expressionSchema)
+	ensureError(t, err)
+	output, err := NewCodec(`{"type":"record","name":"Name","fields":[{"name":"name","type":"string"}]}`)
+	ensureError(t, err)
+
+	bb := new(bytes.Buffer)
+	ocfw, err := NewOCFWriter(OCFConfig{W: bb, Codec: codec})
+	ensureError(t, err)
+	ensureError(t, ocfw.Append([]interface{}{
+		map[string]interface{}{"name": "ann", "active": true},
+		map[string]interface{}{"name": "bob", "active": false},
+		map[string]interface{}{"name": "cat", "active": true},
+	}))
+
+	rt, err := NewRecordTransform(codec, RecordTransformConfig{Engine: "test-field", Filter: "active", Transform: "name", Output: output})
+	ensureError(t, err)
+	if rt.Codec() != output {
+		t.Errorf("GOT: %v; WANT: %v", rt.Codec(), output)
+	}
+	ocfr, err := NewOCFReader(bb)
+	ensureError(t, err)
+	var data []interface{}
+	ensureError(t, rt.ReadOCF(ocfr, func(datum interface{}) error {
+		if _, err := output.BinaryFromNative(nil, datum); err != nil {
+			return err
+		}
+		data = append(data, datum)
+		return nil
+	}))
+	expected := []interface{}{map[string]interface{}{"name": "ann"}, map[string]interface{}{"name": "cat"}}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("GOT: %v; WANT: %v", data, expected)
+	}
+
+	// without expressions, data items are kept unchanged
+	rt, err = NewRecordTransform(codec, RecordTransformConfig{Engine: "test-field"})
+	ensureError(t, err)
+	datum, keep, err := rt.Apply(expected[0])
+	ensureError(t, err)
+	if !keep || !reflect.DeepEqual(datum, expected[0]) {
+		t.Errorf("GOT: %v, %v; WANT: %v, %v", datum, keep, expected[0], true)
+	}
+}
+
+func TestRecordTransformErrors(t *testing.T) {
+	codec, err := NewCodec(expressionSchema)
+	ensureError(t, err)
+
+	_, err = NewRecordTransform(codec, RecordTransformConfig{Engine: "missing"})
+	ensureError(t, err, `unrecognized expression engine: "missing"`)
+	_, err = NewRecordTransform(codec, RecordTransformConfig{Engine: "test-field", Filter: "name"})
+	ensureError(t, err, "cannot compile filter", `field "name" is not a boolean: string`)
+	_, err = NewRecordTransform(codec, RecordTransformConfig{Engine: "test-field", Transform: "name"})
+	ensureError(t, err, "cannot compile transform", "does not match result schema")
+	_, err = NewRecordTransform(codec, RecordTransformConfig{Engine: "test-field", Output: codec})
+	ensureError(t, err, "Output requires Transform")
+	_, err = NewRecordTransform(nil, RecordTransformConfig{Engine: "test-field"})
+	ensureError(t, err, "codec is nil")
+
+	rt, err := NewRecordTransform(codec, RecordTransformConfig{Engine: "test-field", Filter: "active"})
+	ensureError(t, err)
+	_, _, err = rt.Apply(map[string]interface{}{"name": "ann"})
+	ensureError(t, err, "cannot evaluate filter: expected bool; received: <nil>")
+
+	bb := new(bytes.Buffer)
+	ocfw, err := NewOCFWriter(OCFConfig{W: bb, Schema: `"long"`})
+	ensureError(t, err)
+	ensureError(t, ocfw.Append([]interface{}{int64(1)}))
+	ocfr, err := NewOCFReader(bb)
+	ensureError(t, err)
+	ensureError(t, rt.ReadOCF(ocfr, nil), "schema of OCF differs from schema of transform")
+}
@@ -0,0 +1,140 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// FeatureFlags is a central configuration of runtime feature flags, keyed by
+// the full name of the schema of a codec, so features such as zero-copy or
+// lazy decoding may be rolled out to one data stream at a time rather than to
+// all of them at once. Codecs created with the WithFeatureFlags option report
+// whether a flag is enabled for their schema with Codec.FeatureEnabled, which
+// consults the configuration each time, so a configuration reloaded at
+// runtime takes effect without creating the codecs again. A FeatureFlags is
+// safe for concurrent use.
+//
+//     flags := goavro.NewFeatureFlags()
+//     flags.Set("com.example.Click", "lazy-decode", true)
+//     codec, err := goavro.NewCodec(schema, goavro.WithFeatureFlags(flags))
+//     if err != nil {
+//         return err
+//     }
+//     if codec.FeatureEnabled("lazy-decode") {
+//         record, _, err := codec.LazyRecordFromBinary(buf)
+//         // ...
+//     }
+type FeatureFlags struct {
+	mu     sync.RWMutex
+	config featureFlagsConfig
+}
+
+// featureFlagsConfig is the JSON form of a FeatureFlags configuration.
+type featureFlagsConfig struct {
+	// Defaults holds the flags of every schema, unless set for the schema.
+	Defaults map[string]bool `json:"defaults,omitempty"`
+
+	// Schemas holds the flags of each schema, by full name, which override
+	// the defaults.
+	Schemas map[string]map[string]bool `json:"schemas,omitempty"`
+}
+
+// NewFeatureFlags returns a FeatureFlags with every flag disabled.
+func NewFeatureFlags() *FeatureFlags {
+	return &FeatureFlags{config: featureFlagsConfig{Defaults: make(map[string]bool), Schemas: make(map[string]map[string]bool)}}
+}
+
+// Set enables or disables the flag for the schema with the full name. The
+// full name of a schema that is not a named type is its type name, such as
+// "array".
+func (f *FeatureFlags) Set(fullName, flag string, enabled bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	flags, ok := f.config.Schemas[fullName]
+	if !ok {
+		flags = make(map[string]bool)
+		f.config.Schemas[fullName] = flags
+	}
+	flags[flag] = enabled
+}
+
+// SetDefault enables or disables the flag for every schema that does not set
+// the flag itself.
+func (f *FeatureFlags) SetDefault(flag string, enabled bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.config.Defaults[flag] = enabled
+}
+
+// Enabled returns whether the flag is enabled for the schema with the full
+// name, which is the value set for the schema, if any, and otherwise the
+// default value, if any, and otherwise false.
+func (f *FeatureFlags) Enabled(fullName, flag string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if enabled, ok := f.config.Schemas[fullName][flag]; ok {
+		return enabled
+	}
+	return f.config.Defaults[flag]
+}
+
+// Load replaces the whole configuration with the one read from r, such as
+// when the central configuration file changes. The configuration is a JSON
+// object whose optional defaults property maps flags to whether they are
+// enabled, and whose optional schemas property maps full names of schemas to
+// such objects:
+//
+//     {
+//       "defaults": {"zero-copy": true},
+//       "schemas": {"com.example.Click": {"lazy-decode": true, "zero-copy": false}}
+//     }
+//
+// When the configuration cannot be read, the current configuration is kept.
+func (f *FeatureFlags) Load(r io.Reader) error {
+	var config featureFlagsConfig
+	if err := json.NewDecoder(r).Decode(&config); err != nil {
+		return fmt.Errorf("cannot load feature flags: %s", err)
+	}
+	if config.Defaults == nil {
+		config.Defaults = make(map[string]bool)
+	}
+	if config.Schemas == nil {
+		config.Schemas = make(map[string]map[string]bool)
+	}
+	for fullName, flags := range config.Schemas {
+		if flags == nil {
+			return fmt.Errorf("cannot load feature flags: schema %q ought to map flags to booleans", fullName)
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.config = config
+	return nil
+}
+
+// WithFeatureFlags sets the feature flags the codec consults. See
+// CodecOption.FeatureFlags.
+func WithFeatureFlags(flags *FeatureFlags) Option {
+	return func(o *CodecOption) { o.FeatureFlags = flags }
+}
+
+// FeatureEnabled returns whether the flag is enabled for the schema of the
+// codec by the FeatureFlags option, or false when the codec has no
+// FeatureFlags.
+func (c *Codec) FeatureEnabled(flag string) bool {
+	if c.option.FeatureFlags == nil {
+		return false
+	}
+	return c.option.FeatureFlags.Enabled(c.typeName.fullName, flag)
+}
@@ -0,0 +1,71 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFeatureFlags(t *testing.T) {
+	flags := NewFeatureFlags()
+	flags.SetDefault("zero-copy", true)
+	flags.Set("com.example.Click", "lazy-decode", true)
+	flags.Set("com.example.Click", "zero-copy", false)
+
+	click, err := NewCodec(`{"type":"record","name":"Click","namespace":"com.example","fields":[]}`, WithFeatureFlags(flags))
+	ensureError(t, err)
+	view, err := NewCodec(`{"type":"record","name":"com.example.View","fields":[]}`, WithFeatureFlags(flags))
+	ensureError(t, err)
+	plain := newCodecUsingV2(t, `{"type":"record","name":"com.example.Click","fields":[]}`)
+
+	for _, tc := range []struct {
+		codec    *Codec
+		flag     string
+		expected bool
+	}{
+		{click, "lazy-decode", true},
+		{click, "zero-copy", false},
+		{view, "lazy-decode", false},
+		{view, "zero-copy", true},
+		{plain, "lazy-decode", false},
+	} {
+		if actual := tc.codec.FeatureEnabled(tc.flag); actual != tc.expected {
+			t.Errorf("%s %s: GOT: %v; WANT: %v", tc.codec.typeName, tc.flag, actual, tc.expected)
+		}
+	}
+
+	// codecs consult the configuration each time
+	ensureError(t, flags.Load(strings.NewReader(`{"schemas":{"com.example.View":{"lazy-decode":true}}}`)))
+	if actual, expected := view.FeatureEnabled("lazy-decode"), true; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if actual, expected := click.FeatureEnabled("lazy-decode"), false; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	// derived codecs keep the flags
+	derived, err := view.WithOptions(WithEnumIndexDecoding(true))
+	ensureError(t, err)
+	if actual, expected := derived.FeatureEnabled("lazy-decode"), true; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
+
+func TestFeatureFlagsLoadInvalid(t *testing.T) {
+	flags := NewFeatureFlags()
+	flags.SetDefault("zero-copy", true)
+
+	ensureError(t, flags.Load(strings.NewReader(`{"defaults":{"zero-copy":"yes"}}`)), "cannot load feature flags")
+	ensureError(t, flags.Load(strings.NewReader(`{"schemas":{"com.example.Click":null}}`)), `schema "com.example.Click" ought to map flags to booleans`)
+	if actual, expected := flags.Enabled("com.example.Click", "zero-copy"), true; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
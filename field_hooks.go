@@ -0,0 +1,503 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"fmt"
+	"sort"
+)
+
+// FieldHookInfo describes the record field whose value is passed to a
+// FieldHook function.
+type FieldHookInfo struct {
+	// Path is the path of the field, using the conventions of
+	// SchemaFieldDoc.
+	Path string
+
+	// Annotation is the name of the schema annotation that selected the
+	// hook, and Value is its value in the field declaration, such as the
+	// name of a key. Both are empty when the hook was selected by path.
+	Annotation string
+	Value      interface{}
+}
+
+// FieldHook transforms the value of a record field as it is encoded and
+// decoded, such as to encrypt or tokenize it. Encode receives the native value
+// provided by the caller, and returns the value to encode, which ought to be
+// the native form of the field's type in the schema, usually bytes or string.
+// Decode reverses Encode. Either function may be nil, leaving the value
+// unchanged in that direction.
+type FieldHook struct {
+	Encode func(field FieldHookInfo, value interface{}) (interface{}, error)
+	Decode func(field FieldHookInfo, value interface{}) (interface{}, error)
+}
+
+// FieldHooks select the FieldHook of record fields, either by the path of the
+// field, using the conventions of SchemaFieldDoc, or by the name of an
+// annotation in the field declaration, such as "x-encrypt". A hook selected by
+// path takes precedence over one selected by annotation. Hooks are not called
+// for missing or null field values, and are called with unions in their native
+// form, wrapped in a map naming their member.
+//
+//     hooks := &goavro.FieldHooks{
+//         Annotations: map[string]goavro.FieldHook{
+//             "x-encrypt": {
+//                 Encode: func(field goavro.FieldHookInfo, value interface{}) (interface{}, error) {
+//                     return kms.Encrypt(field.Value.(string), []byte(value.(string)))
+//                 },
+//                 Decode: func(field goavro.FieldHookInfo, value interface{}) (interface{}, error) {
+//                     plaintext, err := kms.Decrypt(field.Value.(string), value.([]byte))
+//                     return string(plaintext), err
+//                 },
+//             },
+//         },
+//     }
+//     codec, err := goavro.NewCodec(schema, goavro.WithFieldHooks(hooks))
+//
+// A FieldHooks ought not to be modified after a Codec is created with it.
+type FieldHooks struct {
+	Paths       map[string]FieldHook
+	Annotations map[string]FieldHook
+
+	// Deprecated is called each time a value of a field declared with the
+	// DeprecatedAnnotation is encoded or decoded, (optional), such as to
+	// count or log the uses of soft deprecated fields, and so learn when
+	// they may be removed from the schema. The annotation and its value,
+	// such as the name of the replacing field, are in the FieldHookInfo. It
+	// is called before the FieldHook of the field, if any, and like hooks, is
+	// not called for missing or null field values.
+	//
+	//     hooks := &goavro.FieldHooks{
+	//         Deprecated: func(field goavro.FieldHookInfo, encode bool) {
+	//             deprecatedFieldUses.WithLabelValues(field.Path).Inc()
+	//         },
+	//     }
+	Deprecated func(field FieldHookInfo, encode bool)
+
+	// OnEncode and OnDecode intercept the value of every record field,
+	// (optional), identified by its path, such as for redaction or metrics.
+	// OnEncode is called before the field is encoded, with the value
+	// provided by the caller, before the FieldHook of the field, if any, and
+	// returns the value to encode. OnDecode is called after the field is
+	// decoded, with the value returned by the FieldHook of the field, if any,
+	// and returns the value to return to the caller. A field whose type is
+	// a record is intercepted as a whole, as well as each of its fields. They
+	// are not called for missing or null field values. See Codec.OnEncode
+	// and Codec.OnDecode.
+	OnEncode func(path string, value interface{}) interface{}
+	OnDecode func(path string, value interface{}) interface{}
+}
+
+// DeprecatedAnnotation is the annotation of record fields that are soft
+// deprecated, whose uses are reported to FieldHooks.Deprecated, such as:
+//
+//     {"name": "email", "type": "string", "x-deprecated": "use contact.email"}
+const DeprecatedAnnotation = "x-deprecated"
+
+// fieldHooks applies the FieldHooks of a Codec to native data.
+type fieldHooks struct {
+	root     *schemaNode
+	hooks    *FieldHooks
+	prefixes map[string]struct{} // paths of the values containing fields hooked by path
+	reaches  map[*schemaNode]bool
+}
+
+func newFieldHooks(root *schemaNode, hooks *FieldHooks) (*fieldHooks, error) {
+	h := &fieldHooks{root: root, hooks: hooks, prefixes: make(map[string]struct{}), reaches: make(map[*schemaNode]bool)}
+
+	// NOTE: Only the values whose paths are prefixes of hooked paths are
+	// visited, so the paths of recursive schemas are expanded finitely.
+	if len(hooks.Paths) > 0 {
+		h.prefixes[""] = struct{}{}
+	}
+	for path := range hooks.Paths {
+		for i, r := range path {
+			if r == '.' || r == '[' || r == '{' || r == '(' {
+				h.prefixes[path[:i]] = struct{}{}
+			}
+		}
+	}
+	used := make(map[string]struct{}, len(hooks.Paths))
+	var visit func(n *schemaNode, path string)
+	visit = func(n *schemaNode, path string) {
+		if _, ok := h.prefixes[path]; !ok {
+			return
+		}
+		switch n.typeName {
+		case "record":
+			for _, f := range n.fields {
+				fieldPath := joinFieldPath(path, f.name)
+				if _, ok := hooks.Paths[fieldPath]; ok {
+					used[fieldPath] = struct{}{}
+				}
+				visit(f.node, fieldPath)
+			}
+		case "array":
+			visit(n.items, path+"[]")
+		case "map":
+			visit(n.values, path+"{}")
+		case "union":
+			for _, member := range n.members {
+				visit(member, unionMemberPath(n, member, path))
+			}
+		}
+	}
+	visit(root, "")
+	if len(used) < len(hooks.Paths) {
+		var unused []string
+		for path := range hooks.Paths {
+			if _, ok := used[path]; !ok {
+				unused = append(unused, path)
+			}
+		}
+		sort.Strings(unused)
+		return nil, fmt.Errorf("field hook ought to name the path of a record field: %q", unused)
+	}
+
+	var nodes []*schemaNode
+	seen := make(map[*schemaNode]struct{})
+	var collect func(n *schemaNode)
+	collect = func(n *schemaNode) {
+		if _, ok := seen[n]; ok {
+			return
+		}
+		seen[n] = struct{}{}
+		nodes = append(nodes, n)
+		for _, child := range schemaNodeChildren(n) {
+			collect(child)
+		}
+	}
+	collect(root)
+	for _, n := range nodes {
+		for _, f := range n.fields {
+			if _, _, ok := h.annotated(f); ok || h.deprecated(f) || hooks.OnEncode != nil || hooks.OnDecode != nil {
+				h.reaches[n] = true
+			}
+		}
+	}
+	// NOTE: Recursive schemas have cycles, so propagate until nothing changes.
+	for changed := true; changed; {
+		changed = false
+		for _, n := range nodes {
+			if h.reaches[n] {
+				continue
+			}
+			for _, child := range schemaNodeChildren(n) {
+				if h.reaches[child] {
+					h.reaches[n], changed = true, true
+					break
+				}
+			}
+		}
+	}
+	return h, nil
+}
+
+// annotated returns the hook selected by an annotation of the field, along
+// with the name of the annotation. When several annotations of the field select
+// a hook, the one whose name sorts first is used.
+func (h *fieldHooks) annotated(f *schemaNodeField) (FieldHook, string, bool) {
+	var names []string
+	for name := range f.attributes {
+		if _, ok := h.hooks.Annotations[name]; ok {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return FieldHook{}, "", false
+	}
+	sort.Strings(names)
+	return h.hooks.Annotations[names[0]], names[0], true
+}
+
+// deprecated returns true when uses of the field are reported to the
+// Deprecated function of the hooks.
+func (h *fieldHooks) deprecated(f *schemaNodeField) bool {
+	if h.hooks.Deprecated == nil {
+		return false
+	}
+	_, ok := f.attributes[DeprecatedAnnotation]
+	return ok
+}
+
+// apply returns the native datum with the hooked fields transformed in the
+// specified direction, and whether it changed. Containers of transformed values
+// are copied rather than modified, so data provided by the caller is left
+// intact.
+func (h *fieldHooks) apply(n *schemaNode, datum interface{}, path string, encode bool) (interface{}, bool, error) {
+	_, prefix := h.prefixes[path]
+	if datum == nil || !(prefix || h.reaches[n]) {
+		return datum, false, nil
+	}
+	switch n.typeName {
+	case "record":
+		record, ok := datum.(map[string]interface{})
+		if !ok {
+			return datum, false, nil // left for the encoder to report
+		}
+		var out map[string]interface{}
+		for _, f := range n.fields {
+			value, ok := record[f.name]
+			if !ok || value == nil {
+				continue
+			}
+			fieldPath := joinFieldPath(path, f.name)
+			if h.deprecated(f) {
+				h.hooks.Deprecated(FieldHookInfo{Path: fieldPath, Annotation: DeprecatedAnnotation, Value: f.attributes[DeprecatedAnnotation]}, encode)
+			}
+			var newValue interface{}
+			var changed, nested bool
+			var err error
+			if encode && h.hooks.OnEncode != nil {
+				value, changed = h.hooks.OnEncode(fieldPath, value), true
+			}
+			if fn, info, ok := h.hook(f, fieldPath, encode); ok && value != nil {
+				if newValue, err = fn(info, value); err != nil {
+					return nil, false, fmt.Errorf("field %q: %s", fieldPath, err)
+				}
+				changed = true
+			} else if newValue, nested, err = h.apply(f.node, value, fieldPath, encode); err != nil {
+				return nil, false, err
+			}
+			changed = changed || nested
+			if !encode && h.hooks.OnDecode != nil {
+				newValue, changed = h.hooks.OnDecode(fieldPath, newValue), true
+			}
+			if !changed {
+				continue
+			}
+			if out == nil {
+				out = make(map[string]interface{}, len(record))
+				for k, v := range record {
+					out[k] = v
+				}
+			}
+			out[f.name] = newValue
+		}
+		if out == nil {
+			return datum, false, nil
+		}
+		return out, true, nil
+	case "array":
+		items, ok := datum.([]interface{})
+		if !ok {
+			return datum, false, nil
+		}
+		var out []interface{}
+		for i, item := range items {
+			newItem, changed, err := h.apply(n.items, item, path+"[]", encode)
+			if err != nil {
+				return nil, false, err
+			}
+			if !changed {
+				continue
+			}
+			if out == nil {
+				out = append([]interface{}(nil), items...)
+			}
+			out[i] = newItem
+		}
+		if out == nil {
+			return datum, false, nil
+		}
+		return out, true, nil
+	case "map":
+		switch values := datum.(type) {
+		case map[string]interface{}:
+			var out map[string]interface{}
+			for k, v := range values {
+				newValue, changed, err := h.apply(n.values, v, path+"{}", encode)
+				if err != nil {
+					return nil, false, err
+				}
+				if !changed {
+					continue
+				}
+				if out == nil {
+					out = make(map[string]interface{}, len(values))
+					for k, v := range values {
+						out[k] = v
+					}
+				}
+				out[k] = newValue
+			}
+			if out == nil {
+				return datum, false, nil
+			}
+			return out, true, nil
+		case OrderedMap:
+			var out OrderedMap
+			for i, item := range values {
+				newValue, changed, err := h.apply(n.values, item.Value, path+"{}", encode)
+				if err != nil {
+					return nil, false, err
+				}
+				if !changed {
+					continue
+				}
+				if out == nil {
+					out = append(OrderedMap(nil), values...)
+				}
+				out[i].Value = newValue
+			}
+			if out == nil {
+				return datum, false, nil
+			}
+			return out, true, nil
+		}
+	case "union":
+		name, v, ok := unwrapUnion(datum)
+		if !ok {
+			return datum, false, nil
+		}
+		for _, member := range n.members {
+			if unionMemberName(member) != name {
+				continue
+			}
+			newValue, changed, err := h.apply(member, v, unionMemberPath(n, member, path), encode)
+			if err != nil || !changed {
+				return datum, false, err
+			}
+			if _, ok := datum.(UnionValue); ok {
+				return UnionValue{Type: name, Value: newValue}, true, nil
+			}
+			return map[string]interface{}{name: newValue}, true, nil
+		}
+	}
+	return datum, false, nil
+}
+
+// hook returns the function of the hook of the field in the specified
+// direction, along with the description of the field it is called with.
+func (h *fieldHooks) hook(f *schemaNodeField, path string, encode bool) (func(FieldHookInfo, interface{}) (interface{}, error), FieldHookInfo, bool) {
+	info := FieldHookInfo{Path: path}
+	hook, ok := h.hooks.Paths[path]
+	if !ok {
+		if hook, info.Annotation, ok = h.annotated(f); !ok {
+			return nil, info, false
+		}
+		info.Value = f.attributes[info.Annotation]
+	}
+	fn := hook.Decode
+	if encode {
+		fn = hook.Encode
+	}
+	return fn, info, fn != nil
+}
+
+// OnEncode returns a Codec like c, whose options are those of c, which calls fn
+// with the path and value of every record field before it is encoded, and
+// encodes the value fn returns, such as to mask personal data, or to collect
+// metrics, without forking the encoding loop. When c already intercepts
+// encoded values, the values are intercepted by the function of c first, and
+// then by fn. The Codec c is not modified. See FieldHooks.OnEncode.
+//
+//     masked, err := codec.OnEncode(func(path string, value interface{}) interface{} {
+//         if path == "customer.email" {
+//             return "redacted"
+//         }
+//         return value
+//     })
+func (c *Codec) OnEncode(fn func(path string, value interface{}) interface{}) (*Codec, error) {
+	hooks := c.copyFieldHooks()
+	if previous := hooks.OnEncode; previous != nil {
+		hooks.OnEncode = func(path string, value interface{}) interface{} {
+			return fn(path, previous(path, value))
+		}
+	} else {
+		hooks.OnEncode = fn
+	}
+	return c.WithOptions(WithFieldHooks(hooks))
+}
+
+// OnDecode returns a Codec like c, whose options are those of c, which calls fn
+// with the path and value of every record field after it is decoded, and
+// returns the value fn returns, such as to mask personal data, or to collect
+// metrics, without forking the decoding loop. When c already intercepts
+// decoded values, the values are intercepted by the function of c first, and
+// then by fn. The Codec c is not modified. See FieldHooks.OnDecode.
+func (c *Codec) OnDecode(fn func(path string, value interface{}) interface{}) (*Codec, error) {
+	hooks := c.copyFieldHooks()
+	if previous := hooks.OnDecode; previous != nil {
+		hooks.OnDecode = func(path string, value interface{}) interface{} {
+			return fn(path, previous(path, value))
+		}
+	} else {
+		hooks.OnDecode = fn
+	}
+	return c.WithOptions(WithFieldHooks(hooks))
+}
+
+// copyFieldHooks returns a copy of the FieldHooks of the options of the Codec,
+// or new FieldHooks when it has none.
+func (c *Codec) copyFieldHooks() *FieldHooks {
+	if c.option.FieldHooks == nil {
+		return new(FieldHooks)
+	}
+	hooks := *c.option.FieldHooks
+	return &hooks
+}
+
+// joinFieldPath returns the path of the named field of the record at path.
+func joinFieldPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// unionMemberPath returns the path of the values of the union at path that use
+// member, which names the member when the union has more than one record.
+func unionMemberPath(n, member *schemaNode, path string) string {
+	if member.typeName != "record" {
+		return path
+	}
+	var records int
+	for _, m := range n.members {
+		if m.typeName == "record" {
+			records++
+		}
+	}
+	if records > 1 {
+		return path + "(" + member.fullName + ")"
+	}
+	return path
+}
+
+// encodeHooks returns the datum with the encode hooks of the Codec applied.
+func (c *Codec) encodeHooks(datum interface{}) (interface{}, error) {
+	if c.fieldHooks == nil {
+		return datum, nil
+	}
+	datum, _, err := c.fieldHooks.apply(c.fieldHooks.root, datum, "", true)
+	return datum, err
+}
+
+// decodeHooks returns the datum with the decode hooks of the Codec applied.
+func (c *Codec) decodeHooks(datum interface{}) (interface{}, error) {
+	if c.fieldHooks == nil {
+		return datum, nil
+	}
+	datum, _, err := c.fieldHooks.apply(c.fieldHooks.root, datum, "", false)
+	return datum, err
+}
+
+// compileFieldHooks prepares the field hooks of the options of the Codec.
+func (c *Codec) compileFieldHooks() error {
+	if c.option.FieldHooks == nil {
+		return nil
+	}
+	n, err := schemaNodeFromCodec(c)
+	if err != nil {
+		return err
+	}
+	c.fieldHooks, err = newFieldHooks(n, c.option.FieldHooks)
+	return err
+}
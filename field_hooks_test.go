@@ -0,0 +1,315 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+const customerSchema = `{
+  "type": "record",
+  "name": "Customer",
+  "namespace": "com.example",
+  "fields": [
+    {"name": "name", "type": "string"},
+    {"name": "ssn", "type": "bytes", "x-encrypt": "pii-key"},
+    {"name": "card", "type": ["null", "string"], "default": null},
+    {"name": "addresses", "type": {"type": "array", "items": {
+      "type": "record",
+      "name": "Address",
+      "fields": [
+        {"name": "street", "type": "bytes", "x-encrypt": "address-key"},
+        {"name": "city", "type": "string"}
+      ]
+    }}}
+  ]
+}`
+
+// xorHook is a reversible stand-in for a KMS backed encryption hook, which
+// records the key names it is called with.
+func xorHook(keys *[]string) FieldHook {
+	xor := func(key string, someBytes []byte) []byte {
+		out := make([]byte, len(someBytes))
+		for i, b := range someBytes {
+			out[i] = b ^ key[i%len(key)]
+		}
+		return out
+	}
+	return FieldHook{
+		Encode: func(field FieldHookInfo, value interface{}) (interface{}, error) {
+			*keys = append(*keys, field.Path+"="+field.Value.(string))
+			return xor(field.Value.(string), []byte(value.(string))), nil
+		},
+		Decode: func(field FieldHookInfo, value interface{}) (interface{}, error) {
+			return string(xor(field.Value.(string), value.([]byte))), nil
+		},
+	}
+}
+
+func TestFieldHooksAnnotation(t *testing.T) {
+	var keys []string
+	codec, err := NewCodec(customerSchema, WithFieldHooks(&FieldHooks{
+		Annotations: map[string]FieldHook{"x-encrypt": xorHook(&keys)},
+	}))
+	ensureError(t, err)
+	datum := map[string]interface{}{
+		"name": "Ada",
+		"ssn":  "123-45-6789",
+		"addresses": []interface{}{
+			map[string]interface{}{"street": "1 Main St", "city": "Springfield"},
+		},
+	}
+	buf, err := codec.BinaryFromNative(nil, datum)
+	ensureError(t, err)
+	if actual, expected := fmt.Sprint(keys), "[ssn=pii-key addresses[].street=address-key]"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if bytes.Contains(buf, []byte("123-45-6789")) || bytes.Contains(buf, []byte("1 Main St")) {
+		t.Errorf("GOT: %q; WANT: encrypted fields", buf)
+	}
+	if actual, expected := datum["ssn"], "123-45-6789"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected) // caller data is not modified
+	}
+
+	// the plain codec reads the ciphertext
+	plain, err := NewCodec(customerSchema)
+	ensureError(t, err)
+	native, _, err := plain.NativeFromBinary(buf)
+	ensureError(t, err)
+	if _, ok := native.(map[string]interface{})["ssn"].([]byte); !ok {
+		t.Errorf("GOT: %T; WANT: []byte", native.(map[string]interface{})["ssn"])
+	}
+
+	native, _, err = codec.NativeFromBinary(buf)
+	ensureError(t, err)
+	record := native.(map[string]interface{})
+	if actual, expected := record["ssn"], "123-45-6789"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	address := record["addresses"].([]interface{})[0].(map[string]interface{})
+	if actual, expected := address["street"], "1 Main St"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	text, err := codec.TextualFromNative(nil, datum)
+	ensureError(t, err)
+	native, _, err = codec.NativeFromTextual(text)
+	ensureError(t, err)
+	if actual, expected := native.(map[string]interface{})["ssn"], "123-45-6789"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
+
+func TestFieldHooksPath(t *testing.T) {
+	tokens := make(map[string]string)
+	tokenize := FieldHook{
+		Encode: func(field FieldHookInfo, value interface{}) (interface{}, error) {
+			s := value.(map[string]interface{})["string"].(string)
+			token := fmt.Sprintf("tok_%d", len(tokens))
+			tokens[token] = s
+			return map[string]interface{}{"string": token}, nil
+		},
+		Decode: func(field FieldHookInfo, value interface{}) (interface{}, error) {
+			return map[string]interface{}{"string": tokens[value.(map[string]interface{})["string"].(string)]}, nil
+		},
+	}
+	codec, err := NewCodec(customerSchema, WithFieldHooks(&FieldHooks{
+		Paths: map[string]FieldHook{"card": tokenize},
+	}))
+	ensureError(t, err)
+
+	datum := map[string]interface{}{"name": "Ada", "ssn": []byte("x"), "card": Union("string", "4111111111111111"), "addresses": []interface{}{}}
+	buf, err := codec.SingleFromNative(nil, datum)
+	ensureError(t, err)
+	if !bytes.Contains(buf, []byte("tok_0")) || bytes.Contains(buf, []byte("4111")) {
+		t.Errorf("GOT: %q; WANT: tokenized card", buf)
+	}
+	native, _, err := codec.NativeFromSingle(buf)
+	ensureError(t, err)
+	if actual, expected := fmt.Sprint(native.(map[string]interface{})["card"]), "map[string:4111111111111111]"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	// null values are not passed to hooks
+	datum["card"] = nil
+	_, err = codec.BinaryFromNative(nil, datum)
+	ensureError(t, err)
+	if len(tokens) != 1 {
+		t.Errorf("GOT: %d; WANT: %d", len(tokens), 1)
+	}
+}
+
+func TestFieldHooksErrors(t *testing.T) {
+	_, err := NewCodec(customerSchema, WithFieldHooks(&FieldHooks{
+		Paths: map[string]FieldHook{"addresses.street": {}, "addresses[].street": {}},
+	}))
+	ensureError(t, err, "field hook ought to name the path of a record field", "addresses.street")
+
+	codec, err := NewCodec(customerSchema, WithFieldHooks(&FieldHooks{
+		Annotations: map[string]FieldHook{"x-encrypt": {
+			Encode: func(field FieldHookInfo, value interface{}) (interface{}, error) {
+				return nil, errors.New("key unavailable")
+			},
+		}},
+	}))
+	ensureError(t, err)
+	_, err = codec.BinaryFromNative(nil, map[string]interface{}{"name": "Ada", "ssn": "123", "addresses": []interface{}{}})
+	ensureError(t, err, "cannot encode binary", `field "ssn"`, "key unavailable")
+}
+
+func TestFieldHooksWithOptions(t *testing.T) {
+	var keys []string
+	hooks := &FieldHooks{Annotations: map[string]FieldHook{"x-encrypt": xorHook(&keys)}}
+	plain, err := NewCodec(customerSchema)
+	ensureError(t, err)
+	codec, err := plain.WithOptions(WithFieldHooks(hooks))
+	ensureError(t, err)
+	if same, err := codec.WithOptions(WithFieldHooks(hooks)); err != nil || same != codec {
+		t.Errorf("GOT: %v, %v; WANT: same codec", same == codec, err)
+	}
+	buf, err := codec.BinaryFromNative(nil, map[string]interface{}{"name": "Ada", "ssn": "123", "addresses": []interface{}{}})
+	ensureError(t, err)
+	native, _, err := plain.NativeFromBinary(buf)
+	ensureError(t, err)
+	if actual := native.(map[string]interface{})["ssn"]; bytes.Equal(actual.([]byte), []byte("123")) {
+		t.Errorf("GOT: %q; WANT: encrypted value", actual)
+	}
+}
+
+func TestFieldHooksDeprecated(t *testing.T) {
+	var uses []string
+	var keys []string
+	codec, err := NewCodec(`{"type":"record","name":"Account","fields":[
+		{"name":"email","type":["null","string"],"default":null,"x-deprecated":"use contact.email"},
+		{"name":"pin","type":"bytes","x-deprecated":true,"x-encrypt":"pin-key"},
+		{"name":"contact","type":{"type":"record","name":"Contact","fields":[
+			{"name":"email","type":"string"},
+			{"name":"fax","type":["null","string"],"x-deprecated":"no one faxes"}
+		]}}
+	]}`, WithFieldHooks(&FieldHooks{
+		Annotations: map[string]FieldHook{"x-encrypt": xorHook(&keys)},
+		Deprecated: func(field FieldHookInfo, encode bool) {
+			uses = append(uses, fmt.Sprintf("%s:%v:%v", field.Path, field.Value, encode))
+		},
+	}))
+	ensureError(t, err)
+
+	buf, err := codec.BinaryFromNative(nil, map[string]interface{}{
+		"email":   Union("string", "ada@example.com"),
+		"pin":     "1234",
+		"contact": map[string]interface{}{"email": "ada@example.com", "fax": nil},
+	})
+	ensureError(t, err)
+	if actual, expected := fmt.Sprint(uses), "[email:use contact.email:true pin:true:true]"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	// deprecated fields are still transformed by their hooks
+	if actual, expected := fmt.Sprint(keys), "[pin=pin-key]"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	uses = nil
+	datum, _, err := codec.NativeFromBinary(buf)
+	ensureError(t, err)
+	if actual, expected := fmt.Sprint(uses), "[email:use contact.email:false pin:true:false]"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if actual, expected := datum.(map[string]interface{})["pin"], "1234"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	uses = nil
+	_, err = codec.BinaryFromNative(nil, map[string]interface{}{
+		"pin":     "0000",
+		"contact": map[string]interface{}{"email": "ada@example.com", "fax": Union("string", "555-0100")},
+	})
+	ensureError(t, err)
+	if actual, expected := fmt.Sprint(uses), "[pin:true:true contact.fax:no one faxes:true]"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
+
+func TestCodecOnEncodeOnDecode(t *testing.T) {
+	var keys []string
+	codec, err := NewCodec(customerSchema, WithFieldHooks(&FieldHooks{
+		Annotations: map[string]FieldHook{"x-encrypt": xorHook(&keys)},
+	}))
+	ensureError(t, err)
+
+	var encoded []string
+	masked, err := codec.OnEncode(func(path string, value interface{}) interface{} {
+		encoded = append(encoded, path)
+		if path == "name" {
+			return "***"
+		}
+		return value
+	})
+	ensureError(t, err)
+	counted, err := masked.OnEncode(func(path string, value interface{}) interface{} {
+		if path == "name" && value != "***" {
+			t.Errorf("GOT: %v; WANT: %v", value, "***") // intercepted in order of registration
+		}
+		return value
+	})
+	ensureError(t, err)
+
+	datum := map[string]interface{}{
+		"name": "Ada",
+		"ssn":  "123-45-6789",
+		"card": nil,
+		"addresses": []interface{}{
+			map[string]interface{}{"street": "1 Main St", "city": "Springfield"},
+		},
+	}
+	buf, err := counted.BinaryFromNative(nil, datum)
+	ensureError(t, err)
+	if actual, expected := fmt.Sprint(encoded), "[name ssn addresses addresses[].street addresses[].city]"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	// hooks of the codec still apply
+	if actual, expected := fmt.Sprint(keys), "[ssn=pii-key addresses[].street=address-key]"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	decoded := make(map[string]interface{})
+	observed, err := codec.OnDecode(func(path string, value interface{}) interface{} {
+		decoded[path] = value
+		if path == "addresses" {
+			return len(value.([]interface{}))
+		}
+		return value
+	})
+	ensureError(t, err)
+	native, _, err := observed.NativeFromBinary(buf)
+	ensureError(t, err)
+	record := native.(map[string]interface{})
+	if actual, expected := fmt.Sprintf("%v %v %v", record["name"], record["ssn"], record["addresses"]), "*** 123-45-6789 1"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	// decoded fields are intercepted after their hooks
+	if actual, expected := decoded["addresses[].street"], "1 Main St"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	// the original codec is not modified
+	native, _, err = codec.NativeFromBinary(buf)
+	ensureError(t, err)
+	if actual, expected := native.(map[string]interface{})["name"], "***"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	buf, err = codec.BinaryFromNative(nil, datum)
+	ensureError(t, err)
+	if !bytes.Contains(buf, []byte("Ada")) {
+		t.Errorf("GOT: %q; WANT: unmasked name", buf)
+	}
+}
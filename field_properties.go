@@ -0,0 +1,178 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FieldProperties returns the properties the schema of the Codec declares for
+// the record field at path, other than its name and type, as decoded from the
+// schema JSON. These are the properties the canonical form of the schema
+// omits: "doc", "aliases", "order", and "default" when declared, and custom
+// properties, such as "sensitivity", so tools may enforce policies driven by
+// annotations of the schema. The returned map is a copy, which the caller may
+// modify, but the values it holds are shared with the Codec, and ought not to
+// be modified.
+//
+// The path has the syntax of SchemaFieldDoc paths: field names separated by
+// dots, where "[]" follows the name of a field holding an array to select its
+// items, "{}" follows the name of a field holding a map to select its values,
+// and the full name of a record in parentheses follows the name of a field
+// holding a union of several records to select that member.
+//
+//     properties, err := codec.FieldProperties("contacts[].email")
+//     if err != nil {
+//         return err
+//     }
+//     if properties["sensitivity"] == "pii" {
+//         // mask the field
+//     }
+func (c *Codec) FieldProperties(path string) (map[string]interface{}, error) {
+	root, err := schemaNodeFromCodec(c)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get properties of field %q: %s", path, err)
+	}
+	_, f, err := schemaFieldAtPath(root, path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get properties of field %q: %s", path, err)
+	}
+	properties := make(map[string]interface{}, len(f.attributes)+4)
+	for k, v := range f.attributes {
+		properties[k] = v
+	}
+	if f.doc != "" {
+		properties["doc"] = f.doc
+	}
+	if len(f.aliases) > 0 {
+		aliases := make([]interface{}, len(f.aliases))
+		for i, alias := range f.aliases {
+			aliases[i] = alias
+		}
+		properties["aliases"] = aliases
+	}
+	if f.order != "" {
+		properties["order"] = f.order
+	}
+	if f.hasDefault {
+		properties["default"] = f.defaultValue
+	}
+	return properties, nil
+}
+
+// schemaFieldAtPath returns the record field at the path, using the syntax of
+// SchemaFieldDoc paths, of the record described by n, along with the record
+// that declares the field.
+func schemaFieldAtPath(n *schemaNode, path string) (*schemaNode, *schemaNodeField, error) {
+	if path == "" {
+		return nil, nil, errors.New("path ought to be non-empty")
+	}
+	var record *schemaNode
+	var field *schemaNodeField
+	var trailing bool // whether the last component selects within its field
+	for _, component := range splitFieldPath(path) {
+		end := strings.IndexAny(component, "[{(")
+		if end < 0 {
+			end = len(component)
+		}
+		fieldName, selectors := component[:end], component[end:]
+		trailing = selectors != ""
+		var err error
+		if record, err = unionMemberOfType(n, "record", ""); err != nil {
+			return nil, nil, fmt.Errorf("cannot select field %q: %s", fieldName, err)
+		}
+		field = nil
+		for _, f := range record.fields {
+			if f.name == fieldName {
+				field = f
+				break
+			}
+		}
+		if field == nil {
+			return nil, nil, fmt.Errorf("record %q has no field %q", record.fullName, fieldName)
+		}
+		n = field.node
+		for selectors != "" {
+			switch {
+			case strings.HasPrefix(selectors, "[]"):
+				if n, err = unionMemberOfType(n, "array", ""); err != nil {
+					return nil, nil, fmt.Errorf("cannot select items of field %q: %s", fieldName, err)
+				}
+				n, selectors = n.items, selectors[2:]
+			case strings.HasPrefix(selectors, "{}"):
+				if n, err = unionMemberOfType(n, "map", ""); err != nil {
+					return nil, nil, fmt.Errorf("cannot select values of field %q: %s", fieldName, err)
+				}
+				n, selectors = n.values, selectors[2:]
+			case strings.HasPrefix(selectors, "(") && strings.Contains(selectors, ")"):
+				fullName := selectors[1:strings.Index(selectors, ")")]
+				if n, err = unionMemberOfType(n, "record", fullName); err != nil {
+					return nil, nil, fmt.Errorf("cannot select member of field %q: %s", fieldName, err)
+				}
+				selectors = selectors[len(fullName)+2:]
+			default:
+				return nil, nil, fmt.Errorf("cannot parse path component: %q", component)
+			}
+		}
+	}
+	if trailing {
+		return nil, nil, errors.New("path ought to end with a field name")
+	}
+	return record, field, nil
+}
+
+// splitFieldPath splits the path at the dots that are not within the full name
+// of a union member in parentheses.
+func splitFieldPath(path string) []string {
+	var components []string
+	var depth, start int
+	for i, r := range path {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case '.':
+			if depth == 0 {
+				components = append(components, path[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(components, path[start:])
+}
+
+// unionMemberOfType returns n when it has the type name, and otherwise the
+// member of the union n that has the type name, and the full name when not
+// empty. A union of several records requires the full name.
+func unionMemberOfType(n *schemaNode, typeName, fullName string) (*schemaNode, error) {
+	if n.typeName == typeName && (fullName == "" || n.fullName == fullName) {
+		return n, nil
+	}
+	if n.typeName != "union" {
+		return nil, fmt.Errorf("expected %s; schema type: %s", typeName, n.label())
+	}
+	var found *schemaNode
+	for _, member := range n.members {
+		if member.typeName != typeName || (fullName != "" && member.fullName != fullName) {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("union has several %s members: %s; path ought to name one in parentheses", typeName, n.label())
+		}
+		found = member
+	}
+	if found == nil {
+		return nil, fmt.Errorf("expected %s; schema type: %s", typeName, n.label())
+	}
+	return found, nil
+}
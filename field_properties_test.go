@@ -0,0 +1,82 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestFieldProperties(t *testing.T) {
+	codec, err := NewCodec(`{"type":"record","name":"com.example.Person","fields":[
+		{"name":"name","type":"string","doc":"full name","aliases":["fullName"],"order":"descending","sensitivity":"pii"},
+		{"name":"age","type":["null","int"],"default":null},
+		{"name":"contacts","type":{"type":"array","items":{"type":"record","name":"Contact","fields":[
+			{"name":"email","type":"string","sensitivity":"pii","x-retention":{"days":30}}
+		]}}},
+		{"name":"tags","type":["null",{"type":"map","values":{"type":"record","name":"Tag","fields":[{"name":"label","type":"string","sensitivity":"public"}]}}]},
+		{"name":"payment","type":[
+			{"type":"record","name":"Card","fields":[{"name":"number","type":"string","sensitivity":"pci"}]},
+			{"type":"record","name":"com.other.Transfer","fields":[{"name":"iban","type":"string"}]}
+		]},
+		{"name":"next","type":["null","Person"]}
+	]}`)
+	ensureError(t, err)
+
+	cases := map[string]string{
+		"name":                             "map[aliases:[fullName] doc:full name order:descending sensitivity:pii]",
+		"age":                              "map[default:<nil>]",
+		"contacts[].email":                 "map[sensitivity:pii x-retention:map[days:30]]",
+		"tags{}.label":                     "map[sensitivity:public]",
+		"payment(com.example.Card).number": "map[sensitivity:pci]",
+		"payment(com.other.Transfer).iban": "map[]",
+		"next.next.contacts[].email":       "map[sensitivity:pii x-retention:map[days:30]]",
+	}
+	for path, expected := range cases {
+		properties, err := codec.FieldProperties(path)
+		ensureError(t, err)
+		if actual := fmt.Sprint(properties); actual != expected {
+			t.Errorf("%s: GOT: %v; WANT: %v", path, actual, expected)
+		}
+	}
+
+	// the returned map is a copy
+	properties, err := codec.FieldProperties("name")
+	ensureError(t, err)
+	properties["sensitivity"] = "public"
+	properties, err = codec.FieldProperties("name")
+	ensureError(t, err)
+	if actual, expected := properties["sensitivity"], "pii"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
+
+func TestFieldPropertiesErrors(t *testing.T) {
+	codec, err := NewCodec(`{"type":"record","name":"r","fields":[
+		{"name":"a","type":{"type":"array","items":"string"}},
+		{"name":"u","type":[{"type":"record","name":"x","fields":[]},{"type":"record","name":"y","fields":[]}]}
+	]}`)
+	ensureError(t, err)
+
+	cases := map[string]string{
+		"":       "path ought to be non-empty",
+		"b":      `record "r" has no field "b"`,
+		"a{}":    `cannot select values of field "a": expected map; schema type: array<string>`,
+		"a[].b":  `cannot select field "b": expected record; schema type: string`,
+		"a[]":    "path ought to end with a field name",
+		"u.b":    "union has several record members",
+		"u(z).b": `cannot select member of field "u": expected record`,
+		"a[x]":   `cannot parse path component: "a[x]"`,
+	}
+	for path, expected := range cases {
+		_, err = codec.FieldProperties(path)
+		ensureError(t, err, fmt.Sprintf("cannot get properties of field %q", path), expected)
+	}
+}
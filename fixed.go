@@ -11,12 +11,13 @@ package goavro
 
 import (
 	"fmt"
+	"io"
 	"strconv"
 )
 
 // Fixed does not have child objects, therefore whatever namespace it defines is
 // just to store its name in the symbol table.
-func makeFixedCodec(st map[string]*Codec, enclosingNamespace string, schemaMap map[string]interface{}) (*Codec, error) {
+func makeFixedCodec(st map[string]*Codec, enclosingNamespace string, schemaMap map[string]interface{}, option *CodecOption) (*Codec, error) {
 	c, err := registerNewCodec(st, schemaMap, enclosingNamespace)
 	if err != nil {
 		return nil, fmt.Errorf("Fixed ought to have valid name: %s", err)
@@ -28,7 +29,7 @@ func makeFixedCodec(st map[string]*Codec, enclosingNamespace string, schemaMap m
 
 	c.nativeFromBinary = func(buf []byte) (interface{}, []byte, error) {
 		if buflen := uint(len(buf)); size > buflen {
-			return nil, nil, fmt.Errorf("cannot decode binary fixed %q: schema size exceeds remaining buffer size: %d > %d (short buffer)", c.typeName, size, buflen)
+			return nil, nil, decodeError(option, io.ErrShortBuffer, "cannot decode binary fixed %q: schema size exceeds remaining buffer size: %d > %d (short buffer)", c.typeName, size, buflen)
 		}
 		return buf[:size], buf[size:], nil
 	}
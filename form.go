@@ -0,0 +1,304 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+)
+
+// NativeFromForm converts URL query or form values, such as those of
+// http.Request.Form, to the native datum of the record schema of the Codec,
+// parsing each value as the type of its field, so services may accept
+// entities modelled in Avro over legacy form endpoints.
+//
+//     datum, err := codec.NativeFromForm(r.Form)
+//     if err != nil {
+//         http.Error(w, err.Error(), http.StatusBadRequest)
+//         return
+//     }
+//
+// The record ought to be flat: the type of each field ought to be a primitive
+// type other than null, an enum, or a fixed, which is given by one value; a
+// union of null and one such type, which is null when the field has no value,
+// or an empty value and the other type is not string or bytes; or an array of
+// such types, which is given by a value for each item, in order. Values of
+// booleans are parsed by strconv.ParseBool, and values of bytes and fixed are
+// their bytes. Values of logical types are given as the values of their
+// underlying type, such as milliseconds for timestamp-millis. A field without
+// values takes its default value, if any, and otherwise is null when it may
+// be, or empty when it is an array. Keys that do not name a field are
+// ignored, such as those of submit buttons.
+func (c *Codec) NativeFromForm(values url.Values) (interface{}, error) {
+	fields, err := c.formFields()
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode form: %s", err)
+	}
+	record := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		value, err := f.native(values[f.field.name])
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode form field %q: %s", f.field.name, err)
+		}
+		record[f.field.name] = value
+	}
+	value, err := c.decodeHooks(record)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode form: %s", err)
+	}
+	return value, nil
+}
+
+// FormFromNative converts the native datum of the record schema of the Codec
+// to URL query or form values, the inverse of NativeFromForm. Null values are
+// omitted, and arrays have a value for each item.
+//
+//     values, err := codec.FormFromNative(datum)
+//     if err != nil {
+//         return err
+//     }
+//     query := values.Encode()
+func (c *Codec) FormFromNative(datum interface{}) (url.Values, error) {
+	fields, err := c.formFields()
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode form: %s", err)
+	}
+	if datum, err = c.encodeHooks(datum); err != nil {
+		return nil, fmt.Errorf("cannot encode form: %s", err)
+	}
+	record, ok := datum.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot encode form: expected map[string]interface{}; received: %T", datum)
+	}
+	values := make(url.Values, len(fields))
+	for _, f := range fields {
+		value, ok := record[f.field.name]
+		if !ok {
+			if !f.field.hasDefault {
+				return nil, fmt.Errorf("cannot encode form field %q: schema does not specify default value and no value provided", f.field.name)
+			}
+			value = f.field.defaultValue
+		}
+		if err = f.addValues(values, value); err != nil {
+			return nil, fmt.Errorf("cannot encode form field %q: %s", f.field.name, err)
+		}
+	}
+	return values, nil
+}
+
+// formField is a field of a record converted to and from form values. Values
+// are converted to and from the Avro JSON encoding of the field, so the codec
+// of the field parses and checks them.
+type formField struct {
+	field  recordField
+	node   *schemaNode // of the item type when an array, or of the type other than null when a union
+	member string      // name of the member of the union other than null, or empty
+	array  bool
+}
+
+// formFields returns the fields of the record schema of the Codec, or an error
+// when the schema is not a flat record.
+func (c *Codec) formFields() ([]formField, error) {
+	root, err := schemaNodeFromCodec(c)
+	if err != nil {
+		return nil, err
+	}
+	if root.typeName != "record" {
+		return nil, fmt.Errorf("schema ought to be a record; received: %s", root.label())
+	}
+	fields := make([]formField, len(root.fields))
+	for i, f := range root.fields {
+		ff := formField{field: c.recordFields[i], node: f.node}
+		switch f.node.typeName {
+		case "array":
+			ff.node, ff.array = f.node.items, true
+		case "union":
+			if members := f.node.members; len(members) == 2 && (members[0].typeName == "null") != (members[1].typeName == "null") {
+				j := 0
+				if members[0].typeName == "null" {
+					j = 1
+				}
+				ff.node, ff.member = members[j], c.recordFields[i].codec.unionMembers[j].typeName.fullName
+			}
+		}
+		if !isFormScalar(ff.node) {
+			return nil, fmt.Errorf("field %q ought to be a primitive, enum, or fixed, a union of null and one of those, or an array of those; received: %s", f.name, f.node.label())
+		}
+		fields[i] = ff
+	}
+	return fields, nil
+}
+
+func isFormScalar(n *schemaNode) bool {
+	switch n.typeName {
+	case "boolean", "int", "long", "float", "double", "string", "bytes", "enum", "fixed":
+		return true
+	}
+	return false
+}
+
+// native returns the native value of the field given by the form values.
+func (f formField) native(values []string) (interface{}, error) {
+	if len(values) == 0 {
+		switch {
+		case f.field.hasDefault:
+			return f.field.defaultValue, nil
+		case f.member != "":
+			return nil, nil
+		case f.array:
+			return []interface{}{}, nil
+		}
+		return nil, fmt.Errorf("schema does not specify default value and no value provided")
+	}
+	if !f.array && len(values) > 1 {
+		return nil, fmt.Errorf("expected one value; received: %d", len(values))
+	}
+	var buf []byte
+	if f.array {
+		buf = append(buf, '[')
+	}
+	for i, value := range values {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		if f.member != "" {
+			if value == "" && f.node.typeName != "string" && f.node.typeName != "bytes" {
+				return nil, nil
+			}
+			buf = append(buf, '{')
+			buf = strconv.AppendQuote(buf, f.member)
+			buf = append(buf, ':')
+		}
+		var err error
+		if buf, err = appendFormJSON(buf, f.node, value); err != nil {
+			return nil, err
+		}
+		if f.member != "" {
+			buf = append(buf, '}')
+		}
+	}
+	if f.array {
+		buf = append(buf, ']')
+	}
+	value, _, err := f.field.codec.nativeFromTextual(buf)
+	return value, err
+}
+
+// appendFormJSON appends the Avro JSON encoding of the form value of the
+// type of the node.
+func appendFormJSON(buf []byte, n *schemaNode, value string) ([]byte, error) {
+	switch n.typeName {
+	case "boolean":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse boolean: %q", value)
+		}
+		return strconv.AppendBool(buf, b), nil
+	case "int", "long":
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse %s: %q", n.typeName, value)
+		}
+		return strconv.AppendInt(buf, i, 10), nil
+	case "float", "double":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse %s: %q", n.typeName, value)
+		}
+		switch {
+		case math.IsNaN(f):
+			return append(buf, "null"...), nil
+		case math.IsInf(f, 1):
+			return append(buf, "1e999"...), nil
+		case math.IsInf(f, -1):
+			return append(buf, "-1e999"...), nil
+		}
+		return strconv.AppendFloat(buf, f, 'g', -1, 64), nil
+	case "bytes", "fixed":
+		return bytesTextualFromNative(buf, []byte(value))
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, err // should not get here
+	}
+	return append(buf, b...), nil
+}
+
+// addValues adds the form values of the native value of the field.
+func (f formField) addValues(values url.Values, value interface{}) error {
+	buf, err := f.field.codec.textualFromNative(nil, value)
+	if err != nil {
+		return err
+	}
+	items := []json.RawMessage{buf}
+	if f.array {
+		if err = json.Unmarshal(buf, &items); err != nil {
+			return err // should not get here
+		}
+	}
+	for _, item := range items {
+		if f.member != "" {
+			if string(item) == "null" {
+				continue
+			}
+			var union map[string]json.RawMessage
+			if err = json.Unmarshal(item, &union); err != nil || len(union) != 1 {
+				return fmt.Errorf("cannot decode union value: %s", item) // should not get here
+			}
+			for _, v := range union {
+				item = v
+			}
+		}
+		s, err := formValue(f.node, item)
+		if err != nil {
+			return err
+		}
+		values.Add(f.field.name, s)
+	}
+	return nil
+}
+
+// formValue returns the form value of the Avro JSON encoding of a value of the
+// type of the node.
+func formValue(n *schemaNode, buf json.RawMessage) (string, error) {
+	switch n.typeName {
+	case "boolean", "int", "long":
+		return string(buf), nil
+	case "float", "double":
+		switch string(buf) {
+		case "null":
+			return "NaN", nil
+		case "1e999":
+			return "+Inf", nil
+		case "-1e999":
+			return "-Inf", nil
+		}
+		return string(buf), nil
+	}
+	var s string
+	if err := json.Unmarshal(buf, &s); err != nil {
+		return "", err // should not get here
+	}
+	if n.typeName == "bytes" || n.typeName == "fixed" {
+		// each code point of the Avro JSON encoding is a byte
+		b := make([]byte, 0, len(s))
+		for _, r := range s {
+			b = append(b, byte(r))
+		}
+		s = string(b)
+	}
+	return s, nil
+}
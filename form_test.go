@@ -0,0 +1,134 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+)
+
+const formTestSchema = `{"type":"record","name":"Signup","fields":[
+	{"name":"email","type":"string"},
+	{"name":"age","type":["null","int"]},
+	{"name":"newsletter","type":"boolean","default":false},
+	{"name":"score","type":"double"},
+	{"name":"plan","type":{"type":"enum","name":"Plan","symbols":["FREE","PRO"]}},
+	{"name":"topics","type":{"type":"array","items":"string"}},
+	{"name":"nickname","type":["null","string"]},
+	{"name":"token","type":{"type":"fixed","name":"Token","size":2}},
+	{"name":"since","type":{"type":"long","logicalType":"timestamp-millis"}}
+]}`
+
+func TestNativeFromForm(t *testing.T) {
+	codec, err := NewCodec(formTestSchema)
+	ensureError(t, err)
+
+	values, err := url.ParseQuery("email=ada%40example.com&age=&score=1.5&plan=PRO&topics=go&topics=avro&nickname=&token=%FF%00&since=1000&submit=Send")
+	ensureError(t, err)
+	datum, err := codec.NativeFromForm(values)
+	ensureError(t, err)
+	record := datum.(map[string]interface{})
+	actual := fmt.Sprintf("%v %v %v %v %v %v %q %q %v", record["email"], record["age"], record["newsletter"], record["score"], record["plan"], record["topics"], record["nickname"], record["token"], record["since"].(time.Time).UTC().Format(time.RFC3339Nano))
+	if expected := `ada@example.com <nil> false 1.5 PRO [go avro] map["string":""] "\xff\x00" 1970-01-01T00:00:01Z`; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if _, err = codec.BinaryFromNative(nil, datum); err != nil {
+		t.Errorf("GOT: %v; WANT: %v", err, nil)
+	}
+
+	// absent nullable values are null, and absent arrays are empty
+	values.Set("age", "42")
+	values.Set("newsletter", "1")
+	values.Del("topics")
+	values.Del("nickname")
+	datum, err = codec.NativeFromForm(values)
+	ensureError(t, err)
+	record = datum.(map[string]interface{})
+	if actual, expected := fmt.Sprint(record["age"], record["newsletter"], record["topics"], record["nickname"]), "map[int:42] true [] <nil>"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
+
+func TestNativeFromFormErrors(t *testing.T) {
+	codec, err := NewCodec(formTestSchema)
+	ensureError(t, err)
+	valid := url.Values{"email": {"a"}, "score": {"1"}, "plan": {"FREE"}, "token": {"ab"}, "since": {"0"}}
+	_, err = codec.NativeFromForm(valid)
+	ensureError(t, err)
+
+	cases := []struct {
+		key, value, expected string
+	}{
+		{"age", "forty", `cannot decode form field "age": cannot parse int: "forty"`},
+		{"age", "4294967296", `cannot decode form field "age"`},
+		{"newsletter", "maybe", "cannot parse boolean"},
+		{"plan", "GOLD", `cannot decode form field "plan"`},
+		{"token", "abc", `cannot decode form field "token"`},
+	}
+	for _, c := range cases {
+		values := url.Values{}
+		for k, v := range valid {
+			values[k] = v
+		}
+		values.Set(c.key, c.value)
+		_, err = codec.NativeFromForm(values)
+		ensureError(t, err, c.expected)
+	}
+
+	values := url.Values{"score": {"1", "2"}}
+	_, err = codec.NativeFromForm(values)
+	ensureError(t, err, `cannot decode form field "email": schema does not specify default value`)
+	values.Set("email", "a")
+	_, err = codec.NativeFromForm(values)
+	ensureError(t, err, `cannot decode form field "score": expected one value; received: 2`)
+
+	codec, err = NewCodec(`{"type":"record","name":"r","fields":[{"name":"nested","type":{"type":"map","values":"int"}}]}`)
+	ensureError(t, err)
+	_, err = codec.NativeFromForm(url.Values{})
+	ensureError(t, err, `cannot decode form: field "nested" ought to be a primitive`)
+}
+
+func TestFormFromNative(t *testing.T) {
+	codec, err := NewCodec(formTestSchema)
+	ensureError(t, err)
+	values, err := codec.FormFromNative(map[string]interface{}{
+		"email":    "ada@example.com",
+		"age":      Union("int", int32(42)),
+		"score":    2.5,
+		"plan":     "PRO",
+		"topics":   []interface{}{"go", "avro"},
+		"nickname": nil,
+		"token":    []byte{0xff, 0x00},
+		"since":    time.Unix(1, 0),
+	})
+	ensureError(t, err)
+	if actual, expected := values.Encode(), "age=42&email=ada%40example.com&newsletter=false&plan=PRO&score=2.5&since=1000&token=%FF%00&topics=go&topics=avro"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	// round trip
+	datum, err := codec.NativeFromForm(values)
+	ensureError(t, err)
+	again, err := codec.FormFromNative(datum)
+	ensureError(t, err)
+	if actual, expected := again.Encode(), values.Encode(); actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	_, err = codec.FormFromNative(map[string]interface{}{"email": "a"})
+	ensureError(t, err, `cannot encode form field "age": schema does not specify default value`)
+	_, err = codec.FormFromNative("a")
+	ensureError(t, err, "cannot encode form: expected map[string]interface{}")
+}
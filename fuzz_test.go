@@ -7,6 +7,9 @@
 // distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
 // WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
 
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
 package goavro
 
 import (
@@ -0,0 +1,240 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// GraphQLSchema returns GraphQL SDL type definitions equivalent to the named
+// types of the Codec's schema. It returns an error when the schema does not
+// declare at least one record.
+//
+// Records become object types and enums become enum types. Fields are
+// non-nullable, unless their Avro type is a union that includes null, in
+// which case the field is nullable and typed by the remaining member. Unions
+// of several records become GraphQL unions. Arrays become lists. Because
+// GraphQL has no equivalent for the remaining Avro types, the following
+// custom scalars are declared as needed: `Long` for long values, `Bytes` for
+// bytes and fixed values, `DateTime`, `Date`, and `Time` for the temporal
+// logical types, `Decimal` for the decimal logical type, and `JSON` for maps
+// and unions that cannot be represented by a GraphQL union.
+//
+// Type names are the short names of the Avro named types, unless two named
+// types share the same short name, in which case their full names are used
+// with periods replaced by underscores.
+func (c *Codec) GraphQLSchema() (string, error) {
+	root, err := schemaNodeFromCodec(c)
+	if err != nil {
+		return "", fmt.Errorf("cannot generate GraphQL schema: %s", err)
+	}
+	g := &graphQLGenerator{
+		names:   make(map[*schemaNode]string),
+		scalars: make(map[string]struct{}),
+		unions:  make(map[string]struct{}),
+	}
+	g.collect(root, make(map[*schemaNode]struct{}))
+	if g.records == 0 {
+		return "", fmt.Errorf("cannot generate GraphQL schema without a record: %s", root.label())
+	}
+	return g.generate(), nil
+}
+
+type graphQLGenerator struct {
+	named     []*schemaNode          // named types in order of declaration
+	names     map[*schemaNode]string // GraphQL name of each named type
+	records   int
+	scalars   map[string]struct{} // custom scalars referenced by the definitions
+	unions    map[string]struct{} // GraphQL unions already emitted
+	body      strings.Builder     // object and enum type definitions
+	unionDefs strings.Builder     // union type definitions
+}
+
+// collect gathers the named types reachable from the node.
+func (g *graphQLGenerator) collect(n *schemaNode, seen map[*schemaNode]struct{}) {
+	switch n.typeName {
+	case "array":
+		g.collect(n.items, seen)
+	case "map":
+		g.collect(n.values, seen)
+	case "union":
+		for _, member := range n.members {
+			g.collect(member, seen)
+		}
+	case "record", "enum", "fixed":
+		if _, ok := seen[n]; ok {
+			return
+		}
+		seen[n] = struct{}{}
+		g.named = append(g.named, n)
+		if n.typeName == "record" {
+			g.records++
+		}
+		for _, f := range n.fields {
+			g.collect(f.node, seen)
+		}
+	}
+}
+
+func (g *graphQLGenerator) generate() string {
+	shortCount := make(map[string]int)
+	for _, n := range g.named {
+		shortCount[(&name{n.fullName, n.namespace}).short()]++
+	}
+	for _, n := range g.named {
+		if short := (&name{n.fullName, n.namespace}).short(); shortCount[short] == 1 {
+			g.names[n] = short
+		} else {
+			g.names[n] = strings.Replace(n.fullName, ".", "_", -1)
+		}
+	}
+
+	for _, n := range g.named {
+		switch n.typeName {
+		case "record":
+			g.writeDescription(n.doc, "")
+			fmt.Fprintf(&g.body, "type %s {\n", g.names[n])
+			for _, f := range n.fields {
+				g.writeDescription(f.doc, "  ")
+				fmt.Fprintf(&g.body, "  %s: %s\n", f.name, g.fieldType(f.node))
+			}
+			g.body.WriteString("}\n\n")
+		case "enum":
+			g.writeDescription(n.doc, "")
+			fmt.Fprintf(&g.body, "enum %s {\n", g.names[n])
+			for _, symbol := range n.symbols {
+				fmt.Fprintf(&g.body, "  %s\n", symbol)
+			}
+			g.body.WriteString("}\n\n")
+		}
+	}
+
+	var sb strings.Builder
+	scalars := make([]string, 0, len(g.scalars))
+	for scalar := range g.scalars {
+		scalars = append(scalars, scalar)
+	}
+	sort.Strings(scalars)
+	for _, scalar := range scalars {
+		sb.WriteString("scalar " + scalar + "\n")
+	}
+	if len(scalars) > 0 {
+		sb.WriteString("\n")
+	}
+	sb.WriteString(g.body.String())
+	sb.WriteString(g.unionDefs.String())
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// fieldType returns the GraphQL type reference for a value of the node's type,
+// including the non-null marker when appropriate.
+func (g *graphQLGenerator) fieldType(n *schemaNode) string {
+	if n.typeName != "union" {
+		return g.typeName(n) + "!"
+	}
+	var nullable bool
+	var members []*schemaNode
+	for _, member := range n.members {
+		if member.typeName == "null" {
+			nullable = true
+			continue
+		}
+		members = append(members, member)
+	}
+	var t string
+	switch {
+	case len(members) == 0:
+		return g.scalar("JSON") // union of only null
+	case len(members) == 1:
+		t = g.typeName(members[0])
+	default:
+		t = g.unionType(members)
+	}
+	if !nullable {
+		t += "!"
+	}
+	return t
+}
+
+// unionType returns the name of a GraphQL union of the provided members,
+// emitting its definition the first time it is used. GraphQL unions may only
+// have object type members, so unions with other members are typed as JSON.
+func (g *graphQLGenerator) unionType(members []*schemaNode) string {
+	names := make([]string, len(members))
+	for i, member := range members {
+		if member.typeName != "record" {
+			return g.scalar("JSON")
+		}
+		names[i] = g.names[member]
+	}
+	unionName := strings.Join(names, "Or")
+	if _, ok := g.unions[unionName]; !ok {
+		g.unions[unionName] = struct{}{}
+		fmt.Fprintf(&g.unionDefs, "union %s = %s\n\n", unionName, strings.Join(names, " | "))
+	}
+	return unionName
+}
+
+// typeName returns the GraphQL named type or list type for the node, without a
+// trailing non-null marker.
+func (g *graphQLGenerator) typeName(n *schemaNode) string {
+	switch n.typeName {
+	case "record", "enum":
+		return g.names[n]
+	case "array":
+		return "[" + g.fieldType(n.items) + "]"
+	case "map":
+		return g.scalar("JSON")
+	case "union":
+		return strings.TrimSuffix(g.fieldType(n), "!")
+	}
+	switch n.logicalType {
+	case "timestamp-millis", "timestamp-micros":
+		return g.scalar("DateTime")
+	case "date":
+		return g.scalar("Date")
+	case "time-millis", "time-micros":
+		return g.scalar("Time")
+	case "decimal":
+		return g.scalar("Decimal")
+	case "uuid":
+		return "ID"
+	}
+	switch n.typeName {
+	case "boolean":
+		return "Boolean"
+	case "int":
+		return "Int"
+	case "long":
+		return g.scalar("Long")
+	case "float", "double":
+		return "Float"
+	case "string":
+		return "String"
+	case "null":
+		return g.scalar("JSON")
+	default: // bytes and fixed
+		return g.scalar("Bytes")
+	}
+}
+
+func (g *graphQLGenerator) scalar(s string) string {
+	g.scalars[s] = struct{}{}
+	return s
+}
+
+func (g *graphQLGenerator) writeDescription(doc, indent string) {
+	if doc == "" {
+		return
+	}
+	fmt.Fprintf(&g.body, "%s\"\"\"%s\"\"\"\n", indent, strings.Replace(doc, `"""`, `\"""`, -1))
+}
@@ -0,0 +1,76 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import "testing"
+
+func TestGraphQLSchemaRequiresRecord(t *testing.T) {
+	codec := newCodecUsingV2(t, `{"type":"enum","name":"e1","symbols":["A"]}`)
+	_, err := codec.GraphQLSchema()
+	ensureError(t, err, "cannot generate GraphQL schema without a record")
+}
+
+func TestGraphQLSchema(t *testing.T) {
+	codec := newCodecUsingV2(t, `{
+  "type": "record",
+  "name": "Event",
+  "namespace": "com.example",
+  "doc": "An event.",
+  "fields": [
+    {"name": "id", "type": "long"},
+    {"name": "label", "type": ["null", "string"]},
+    {"name": "ts", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "kind", "type": {"type": "enum", "name": "Kind", "symbols": ["A", "B"]}},
+    {"name": "tags", "type": {"type": "array", "items": ["null", "string"]}},
+    {"name": "actor", "type": [
+      {"type": "record", "name": "User", "fields": [{"name": "name", "type": "string", "doc": "User name."}]},
+      {"type": "record", "name": "Bot", "fields": [{"name": "serial", "type": "int"}]}
+    ]},
+    {"name": "attrs", "type": {"type": "map", "values": "string"}}
+  ]
+}`)
+	sdl, err := codec.GraphQLSchema()
+	ensureError(t, err)
+
+	want := `scalar DateTime
+scalar JSON
+scalar Long
+
+"""An event."""
+type Event {
+  id: Long!
+  label: String
+  ts: DateTime!
+  kind: Kind!
+  tags: [String]!
+  actor: UserOrBot!
+  attrs: JSON!
+}
+
+enum Kind {
+  A
+  B
+}
+
+type User {
+  """User name."""
+  name: String!
+}
+
+type Bot {
+  serial: Int!
+}
+
+union UserOrBot = User | Bot
+`
+	if sdl != want {
+		t.Errorf("GOT:\n%s\nWANT:\n%s", sdl, want)
+	}
+}
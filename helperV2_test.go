@@ -10,7 +10,6 @@
 package goavro
 
 import (
-	"bytes"
 	"testing"
 )
 
@@ -23,27 +22,6 @@ func newCodecUsingV2(tb testing.TB, schema string) *Codec {
 	return codec
 }
 
-func nativeFromAvroUsingV2(tb testing.TB, avroBlob []byte) ([]interface{}, *Codec) {
-	tb.Helper()
-	ocf, err := NewOCFReader(bytes.NewReader(avroBlob))
-	if err != nil {
-		tb.Fatal(err)
-	}
-
-	var nativeData []interface{}
-	for ocf.Scan() {
-		datum, err := ocf.Read()
-		if err != nil {
-			break // Read error sets OCFReader error
-		}
-		nativeData = append(nativeData, datum)
-	}
-	if err := ocf.Err(); err != nil {
-		tb.Fatal(err)
-	}
-	return nativeData, ocf.Codec()
-}
-
 func binaryFromNativeUsingV2(tb testing.TB, codec *Codec, nativeData []interface{}) [][]byte {
 	tb.Helper()
 	binaryData := make([][]byte, len(nativeData))
@@ -0,0 +1,205 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// ingestMaxMessageSize is the largest message accepted by an IngestHandler
+// when IngestConfig does not specify one.
+const ingestMaxMessageSize = 1 << 20
+
+// IngestConfig is used to specify the parameters of NewIngestHandler.
+type IngestConfig struct {
+	// Codecs are accepted for single-object encoded messages, which are
+	// matched to a codec by the Rabin fingerprint of its schema, (optional).
+	Codecs []*Codec
+
+	// SchemaIDs maps the schema registry IDs accepted for Confluent framed
+	// messages to the codec of each schema, (optional).
+	SchemaIDs map[uint32]*Codec
+
+	// Bundle specifies a pinned schema bundle, whose schemas are accepted
+	// for both single-object encoded and Confluent framed messages,
+	// (optional). Codecs and SchemaIDs take precedence over the bundle.
+	Bundle *SchemaBundle
+
+	// Handle is called with each decoded data item, and the codec of the
+	// schema that encoded it, (required). When it returns an error, the
+	// request fails with 500 Internal Server Error.
+	Handle func(r *http.Request, codec *Codec, datum interface{}) error
+
+	// MaxMessageSize specifies the largest message accepted, in bytes,
+	// (optional). When zero, messages of up to 1 MiB are accepted.
+	MaxMessageSize int64
+}
+
+// IngestHandler is an http.Handler accepting Avro data items POSTed as the
+// body of a request, either single-object encoded, or framed the way the
+// Confluent Schema Registry serializers frame them, with a zero magic byte and
+// a 4-byte big-endian schema ID. Each message is validated by decoding it
+// with the codec of its registered schema, and the decoded data item is
+// handed to the Handle function of the config, so a service receiving Avro
+// need only provide that function.
+//
+//     handler, err := goavro.NewIngestHandler(goavro.IngestConfig{
+//         SchemaIDs: map[uint32]*goavro.Codec{42: codec},
+//         Handle: func(r *http.Request, codec *goavro.Codec, datum interface{}) error {
+//             return queue.Put(r.Context(), datum)
+//         },
+//     })
+//     if err != nil {
+//         return err
+//     }
+//     http.Handle("/ingest", handler)
+//
+// Successful requests receive 204 No Content. Requests whose message is not
+// single-object encoded or Confluent framed receive 415 Unsupported Media
+// Type, those whose schema is not registered 422 Unprocessable Entity, and
+// those whose message cannot be decoded 400 Bad Request.
+//
+// The transport of the messages is not tied to HTTP: other servers, such as
+// gRPC services, may validate the messages they receive using Decode.
+type IngestHandler struct {
+	fingerprints   map[uint64]*Codec
+	schemaIDs      map[uint32]*Codec
+	handle         func(r *http.Request, codec *Codec, datum interface{}) error
+	maxMessageSize int64
+}
+
+// NewIngestHandler returns an IngestHandler for the schemas and the Handle
+// function of the config.
+func NewIngestHandler(config IngestConfig) (*IngestHandler, error) {
+	if config.Handle == nil {
+		return nil, errors.New("cannot create IngestHandler: Handle is nil")
+	}
+	if len(config.Codecs) == 0 && len(config.SchemaIDs) == 0 && (config.Bundle == nil || len(config.Bundle.ids) == 0) {
+		return nil, errors.New("cannot create IngestHandler: no schemas")
+	}
+	if config.MaxMessageSize < 0 {
+		return nil, fmt.Errorf("cannot create IngestHandler: MaxMessageSize ought to be zero or positive: %d", config.MaxMessageSize)
+	}
+	if config.MaxMessageSize == 0 {
+		config.MaxMessageSize = ingestMaxMessageSize
+	}
+	h := &IngestHandler{
+		fingerprints:   make(map[uint64]*Codec, len(config.Codecs)),
+		schemaIDs:      make(map[uint32]*Codec, len(config.SchemaIDs)),
+		handle:         config.Handle,
+		maxMessageSize: config.MaxMessageSize,
+	}
+	if config.Bundle != nil {
+		for id, codec := range config.Bundle.ids {
+			h.schemaIDs[id] = codec
+		}
+		for fingerprint, codec := range config.Bundle.fingerprints {
+			h.fingerprints[fingerprint] = codec
+		}
+	}
+	for i, codec := range config.Codecs {
+		if codec == nil {
+			return nil, fmt.Errorf("cannot create IngestHandler: codec %d is nil", i)
+		}
+		h.fingerprints[codec.Rabin] = codec
+	}
+	for id, codec := range config.SchemaIDs {
+		if codec == nil {
+			return nil, fmt.Errorf("cannot create IngestHandler: codec of schema ID %d is nil", id)
+		}
+		h.schemaIDs[id] = codec
+	}
+	return h, nil
+}
+
+// IngestError is returned by Decode when a message is rejected, and describes
+// the HTTP status with which IngestHandler rejects it.
+type IngestError struct {
+	Status int // HTTP status code of the rejection
+	Err    error
+}
+
+func (e *IngestError) Error() string { return e.Err.Error() }
+
+// Decode returns the codec of the schema that encoded the single-object
+// encoded or Confluent framed message, and the data item it holds. The data
+// item ought to be the only content of the message. When the message is
+// rejected, the error is an *IngestError.
+func (h *IngestHandler) Decode(message []byte) (*Codec, interface{}, error) {
+	var codec *Codec
+	var buf []byte
+	switch {
+	case len(message) >= soeMagicPrefix && message[0] == 0xC3 && message[1] == 0x01:
+		fingerprint, rest, err := FingerprintFromSOE(message)
+		if err != nil {
+			return nil, nil, &IngestError{http.StatusBadRequest, fmt.Errorf("cannot decode message: %s", err)}
+		}
+		if codec = h.fingerprints[fingerprint]; codec == nil {
+			return nil, nil, &IngestError{http.StatusUnprocessableEntity, fmt.Errorf("cannot decode message: unknown schema fingerprint: %#016x", fingerprint)}
+		}
+		buf = rest
+	case len(message) > 0 && message[0] == 0:
+		if len(message) < confluentHeaderLen {
+			return nil, nil, &IngestError{http.StatusBadRequest, fmt.Errorf("cannot decode message: cannot read schema ID: %s", io.ErrShortBuffer)}
+		}
+		id := binary.BigEndian.Uint32(message[1:confluentHeaderLen])
+		if codec = h.schemaIDs[id]; codec == nil {
+			return nil, nil, &IngestError{http.StatusUnprocessableEntity, fmt.Errorf("cannot decode message: unknown schema ID: %d", id)}
+		}
+		buf = message[confluentHeaderLen:]
+	default:
+		return nil, nil, &IngestError{http.StatusUnsupportedMediaType, errors.New("cannot decode message: neither single-object encoded nor Confluent framed")}
+	}
+	datum, rest, err := codec.NativeFromBinary(buf)
+	if err != nil {
+		return nil, nil, &IngestError{http.StatusBadRequest, fmt.Errorf("cannot decode message: %s", err)}
+	}
+	if len(rest) > 0 {
+		return nil, nil, &IngestError{http.StatusBadRequest, fmt.Errorf("cannot decode message: extra bytes after data item: %d", len(rest))}
+	}
+	return codec, datum, nil
+}
+
+// ServeHTTP decodes the message of the body of a POST request, and calls the
+// Handle function with its data item.
+func (h *IngestHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method ought to be POST", http.StatusMethodNotAllowed)
+		return
+	}
+	message, err := ioutil.ReadAll(io.LimitReader(r.Body, h.maxMessageSize+1))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("cannot read message: %s", err), http.StatusBadRequest)
+		return
+	}
+	if int64(len(message)) > h.maxMessageSize {
+		http.Error(w, fmt.Sprintf("message exceeds the limit of %d bytes", h.maxMessageSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+	codec, datum, err := h.Decode(message)
+	if err != nil {
+		http.Error(w, err.Error(), err.(*IngestError).Status)
+		return
+	}
+	if err = h.handle(r, codec, datum); err != nil {
+		http.Error(w, fmt.Sprintf("cannot handle data item: %s", err), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,118 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIngestHandler(t *testing.T) {
+	codec, err := NewCodec(`"string"`)
+	ensureError(t, err)
+	other, err := NewCodec(`"long"`)
+	ensureError(t, err)
+
+	var received []interface{}
+	h, err := NewIngestHandler(IngestConfig{
+		Codecs:    []*Codec{codec},
+		SchemaIDs: map[uint32]*Codec{7: other},
+		Handle: func(r *http.Request, c *Codec, datum interface{}) error {
+			if datum == "fail" {
+				return errors.New("queue is full")
+			}
+			received = append(received, datum)
+			return nil
+		},
+		MaxMessageSize: 16,
+	})
+	ensureError(t, err)
+
+	soe, err := codec.SingleFromNative(nil, "hello")
+	ensureError(t, err)
+	failing, err := codec.SingleFromNative(nil, "fail")
+	ensureError(t, err)
+	unknownSOE, err := other.SingleFromNative(nil, int64(1))
+	ensureError(t, err)
+
+	for _, tc := range []struct {
+		method string
+		body   []byte
+		status int
+		reason string
+	}{
+		{http.MethodPost, soe, http.StatusNoContent, ""},
+		{http.MethodPost, []byte{0, 0, 0, 0, 7, 0x54}, http.StatusNoContent, ""},
+		{http.MethodGet, nil, http.StatusMethodNotAllowed, "method ought to be POST"},
+		{http.MethodPost, []byte("{}"), http.StatusUnsupportedMediaType, "neither single-object encoded nor Confluent framed"},
+		{http.MethodPost, []byte{0, 0, 0, 0, 8, 0x54}, http.StatusUnprocessableEntity, "unknown schema ID: 8"},
+		{http.MethodPost, unknownSOE, http.StatusUnprocessableEntity, "unknown schema fingerprint"},
+		{http.MethodPost, []byte{0, 0, 0}, http.StatusBadRequest, "cannot read schema ID"},
+		{http.MethodPost, []byte{0, 0, 0, 0, 7, 0x54, 0x00}, http.StatusBadRequest, "extra bytes after data item: 1"},
+		{http.MethodPost, []byte{0, 0, 0, 0, 7, 0x80}, http.StatusBadRequest, "cannot decode message"},
+		{http.MethodPost, make([]byte, 17), http.StatusRequestEntityTooLarge, "exceeds the limit of 16 bytes"},
+		{http.MethodPost, failing, http.StatusInternalServerError, "cannot handle data item: queue is full"},
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(tc.method, "/ingest", bytes.NewReader(tc.body)))
+		if actual, expected := w.Code, tc.status; actual != expected {
+			t.Errorf("%v: GOT: %v; WANT: %v", tc.body, actual, expected)
+		}
+		if !strings.Contains(w.Body.String(), tc.reason) {
+			t.Errorf("%v: GOT: %v; WANT: %v", tc.body, w.Body.String(), tc.reason)
+		}
+	}
+	if actual, expected := len(received), 2; actual != expected {
+		t.Fatalf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if received[0] != "hello" || received[1] != int64(42) {
+		t.Errorf("GOT: %v", received)
+	}
+}
+
+func TestNewIngestHandlerErrors(t *testing.T) {
+	codec, err := NewCodec(`"string"`)
+	ensureError(t, err)
+	handle := func(*http.Request, *Codec, interface{}) error { return nil }
+
+	_, err = NewIngestHandler(IngestConfig{Codecs: []*Codec{codec}})
+	ensureError(t, err, "Handle is nil")
+	_, err = NewIngestHandler(IngestConfig{Handle: handle})
+	ensureError(t, err, "no schemas")
+	_, err = NewIngestHandler(IngestConfig{Codecs: []*Codec{nil}, Handle: handle})
+	ensureError(t, err, "codec 0 is nil")
+	_, err = NewIngestHandler(IngestConfig{Codecs: []*Codec{codec}, Handle: handle, MaxMessageSize: -1})
+	ensureError(t, err, "MaxMessageSize ought to be zero or positive: -1")
+}
+
+func TestIngestHandlerBundle(t *testing.T) {
+	bundle, err := ReadSchemaBundle(strings.NewReader(`[{"subject":"s","version":1,"id":5,"schema":"\"long\""}]`))
+	ensureError(t, err)
+	h, err := NewIngestHandler(IngestConfig{
+		Bundle: bundle,
+		Handle: func(r *http.Request, c *Codec, datum interface{}) error { return nil },
+	})
+	ensureError(t, err)
+
+	_, datum, err := h.Decode([]byte{0, 0, 0, 0, 5, 0x54})
+	ensureError(t, err)
+	if actual, expected := datum, int64(42); actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	_, _, err = h.Decode([]byte{0, 0, 0, 0, 6, 0x54})
+	ensureError(t, err, "unknown schema ID: 6")
+}
@@ -0,0 +1,263 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// interleavedMagic starts every interleaved stream. It is not the magic of an
+// Avro Object Container File, so Avro tools reject the stream rather than
+// misread it.
+var interleavedMagic = []byte("GAv\x01")
+
+// Kinds of the frames of an interleaved stream.
+const (
+	interleavedSchemaFrame byte = 0 // schema text, before the first datum using it
+	interleavedDatumFrame  byte = 1 // single-object encoded datum
+)
+
+// InterleavedWriter writes data of several schemas to a single stream, such as
+// when archiving several topics together. This is not a standard Avro format,
+// and may only be read by an InterleavedReader.
+//
+// The stream starts with a magic number, followed by frames, each a kind byte
+// followed by an Avro bytes value. Each datum is written in a frame of its own,
+// single-object encoded, so it starts with the Rabin fingerprint of its schema.
+// The first datum of each schema is preceded by a frame holding the schema
+// text, so the stream describes itself. Data following a schema frame are read
+// using that schema, until another schema of the same fingerprint is written.
+//
+//	iw, err := goavro.NewInterleavedWriter(bw)
+//	if err != nil {
+//	    return err
+//	}
+//	if err = iw.Append(clickCodec, click); err != nil {
+//	    return err
+//	}
+//	if err = iw.Append(viewCodec, view); err != nil {
+//	    return err
+//	}
+//
+// An InterleavedWriter does not buffer its frames, so ought to be given a
+// buffered io.Writer, and ought not to be used by multiple goroutines
+// simultaneously.
+type InterleavedWriter struct {
+	iow     io.Writer
+	buf     []byte            // reused for each frame
+	datum   []byte            // reused for each encoded datum
+	written map[uint64]string // schema text last written for each fingerprint
+	err     error             // error that stopped the writer
+}
+
+// NewInterleavedWriter returns an InterleavedWriter that writes to iow, after
+// writing the magic number starting the stream.
+func NewInterleavedWriter(iow io.Writer) (*InterleavedWriter, error) {
+	if _, err := iow.Write(interleavedMagic); err != nil {
+		return nil, fmt.Errorf("cannot create InterleavedWriter: %s", err)
+	}
+	return &InterleavedWriter{iow: iow, written: make(map[uint64]string)}, nil
+}
+
+// Append encodes the datum using the schema of the codec, and writes it to the
+// stream, preceded by the schema when the stream does not have it yet. When
+// the datum cannot be encoded, Append returns the error, and nothing is
+// written, so later data may still be appended. Once writing fails, Append
+// returns the same error on every later call.
+func (iw *InterleavedWriter) Append(codec *Codec, datum interface{}) error {
+	if iw.err != nil {
+		return iw.err
+	}
+	var err error
+	if iw.datum, err = codec.SingleFromNative(iw.datum[:0], datum); err != nil {
+		return err
+	}
+
+	iw.buf = iw.buf[:0]
+	// NOTE: Canonical forms omit defaults and logical types, so a schema of
+	// the same fingerprint as one already written may still differ from it.
+	if iw.written[codec.Rabin] != codec.Schema() {
+		iw.buf = append(iw.buf, interleavedSchemaFrame)
+		iw.buf, _ = bytesBinaryFromNative(iw.buf, []byte(codec.Schema()))
+	}
+	iw.buf = append(iw.buf, interleavedDatumFrame)
+	iw.buf, _ = bytesBinaryFromNative(iw.buf, iw.datum)
+	if _, err = iw.iow.Write(iw.buf); err != nil {
+		iw.err = fmt.Errorf("cannot write interleaved datum: %s", err)
+		return iw.err
+	}
+	iw.written[codec.Rabin] = codec.Schema()
+	return nil
+}
+
+// InterleavedReader reads a stream written by an InterleavedWriter, returning
+// each datum along with the Codec of its schema, so data of several schemas may
+// be demultiplexed. An InterleavedReader ought not to be used by multiple
+// goroutines simultaneously.
+//
+//	ir, err := goavro.NewInterleavedReader(br)
+//	if err != nil {
+//	    return err
+//	}
+//	for ir.Scan() {
+//	    codec, datum, err := ir.Read()
+//	    if err != nil {
+//	        return err
+//	    }
+//	    handlers[codec.Rabin](datum)
+//	}
+//	return ir.Err()
+type InterleavedReader struct {
+	ior          io.Reader
+	opts         []Option
+	err          error  // error that stopped the reader
+	buf          []byte // reused for each frame
+	message      []byte // most recently scanned datum, single-object encoded
+	codec        *Codec // codec of message
+	frames       uint64 // number of frames read
+	codecs       map[uint64]*Codec
+	fingerprints []uint64 // in the order their schemas were read
+}
+
+// NewInterleavedReader returns an InterleavedReader that reads the stream from
+// ior. The codecs of the schemas found in the stream are created with the
+// specified options.
+func NewInterleavedReader(ior io.Reader, opts ...Option) (*InterleavedReader, error) {
+	magic := make([]byte, len(interleavedMagic))
+	if _, err := io.ReadFull(ior, magic); err != nil {
+		return nil, fmt.Errorf("cannot create InterleavedReader: cannot read magic number: %s", err)
+	}
+	if !bytes.Equal(magic, interleavedMagic) {
+		return nil, fmt.Errorf("cannot create InterleavedReader: invalid magic number: %#q", magic)
+	}
+	return &InterleavedReader{ior: ior, opts: opts, codecs: make(map[uint64]*Codec)}, nil
+}
+
+// Scan reads the next datum of the stream, along with the schemas preceding
+// it, returning false when the end of the stream is reached, or when an error
+// occurs while reading it.
+func (ir *InterleavedReader) Scan() bool {
+	ir.message, ir.codec = nil, nil
+	for ir.err == nil {
+		kind, frame, err := ir.readFrame()
+		if err != nil {
+			if err != io.EOF {
+				ir.err = fmt.Errorf("cannot read interleaved frame %d: %s", ir.frames, err)
+			}
+			return false
+		}
+		switch kind {
+		case interleavedSchemaFrame:
+			codec, err := NewCodec(string(frame), ir.opts...)
+			if err != nil {
+				ir.err = fmt.Errorf("cannot read interleaved frame %d: %s", ir.frames, err)
+				return false
+			}
+			if _, ok := ir.codecs[codec.Rabin]; !ok {
+				ir.fingerprints = append(ir.fingerprints, codec.Rabin)
+			}
+			ir.codecs[codec.Rabin] = codec
+		case interleavedDatumFrame:
+			fingerprint, _, err := FingerprintFromSOE(frame)
+			if err != nil {
+				ir.err = fmt.Errorf("cannot read interleaved frame %d: %s", ir.frames, err)
+				return false
+			}
+			codec, ok := ir.codecs[fingerprint]
+			if !ok {
+				ir.err = fmt.Errorf("cannot read interleaved frame %d: unknown schema fingerprint: %#016x", ir.frames, fingerprint)
+				return false
+			}
+			ir.message, ir.codec = frame, codec
+			ir.frames++
+			return true
+		default:
+			ir.err = fmt.Errorf("cannot read interleaved frame %d: unknown frame kind: %d", ir.frames, kind)
+			return false
+		}
+		ir.frames++
+	}
+	return false
+}
+
+// readFrame reads the kind and payload of the next frame into the buffer of
+// the reader. It returns io.EOF when the stream ends before the frame.
+func (ir *InterleavedReader) readFrame() (byte, []byte, error) {
+	var kind [1]byte
+	if _, err := io.ReadFull(ir.ior, kind[:]); err != nil {
+		return 0, nil, err
+	}
+	size, err := longBinaryReader(ir.ior)
+	if err != nil {
+		return 0, nil, fmt.Errorf("cannot read size: %s", unexpectedEOF(err))
+	}
+	if size < 0 {
+		return 0, nil, fmt.Errorf("size is negative: %d", size)
+	}
+	if size > MaxBlockSize {
+		return 0, nil, fmt.Errorf("size exceeds MaxBlockSize: %d > %d", size, MaxBlockSize)
+	}
+	if int64(cap(ir.buf)) < size {
+		ir.buf = make([]byte, size)
+	}
+	ir.buf = ir.buf[:size]
+	if _, err = io.ReadFull(ir.ior, ir.buf); err != nil {
+		return 0, nil, unexpectedEOF(err)
+	}
+	return kind[0], ir.buf, nil
+}
+
+// unexpectedEOF returns io.ErrUnexpectedEOF for io.EOF, as a stream that ends
+// within a frame is truncated.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Read decodes the most recently scanned datum, returning it along with the
+// Codec of its schema.
+func (ir *InterleavedReader) Read() (*Codec, interface{}, error) {
+	if ir.codec == nil {
+		return nil, nil, fmt.Errorf("cannot read interleaved datum: Read called without successful Scan")
+	}
+	datum, _, err := ir.codec.NativeFromSingle(ir.message)
+	if err != nil {
+		return ir.codec, nil, fmt.Errorf("cannot read interleaved datum: %s", err)
+	}
+	return ir.codec, datum, nil
+}
+
+// Message returns the most recently scanned datum, single-object encoded,
+// along with the Codec of its schema, without decoding it, so data may be
+// routed by their schema without decoding them. The returned slice is only
+// valid until the next call to Scan.
+func (ir *InterleavedReader) Message() (*Codec, []byte) {
+	return ir.codec, ir.message
+}
+
+// Codecs returns the codecs of the schemas read so far, in the order their
+// schemas were read.
+func (ir *InterleavedReader) Codecs() []*Codec {
+	codecs := make([]*Codec, len(ir.fingerprints))
+	for i, fingerprint := range ir.fingerprints {
+		codecs[i] = ir.codecs[fingerprint]
+	}
+	return codecs
+}
+
+// Err returns the error that stopped the reader, or nil when the end of the
+// stream was reached.
+func (ir *InterleavedReader) Err() error {
+	return ir.err
+}
@@ -0,0 +1,102 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestInterleavedRoundTrip(t *testing.T) {
+	clicks := newCodecUsingV2(t, `{"type":"record","name":"Click","fields":[{"name":"x","type":"int"}]}`)
+	views := newCodecUsingV2(t, `{"type":"record","name":"View","fields":[{"name":"page","type":"string"}]}`)
+
+	bb := new(bytes.Buffer)
+	iw, err := NewInterleavedWriter(bb)
+	ensureError(t, err)
+	ensureError(t, iw.Append(clicks, map[string]interface{}{"x": 1}))
+	ensureError(t, iw.Append(views, map[string]interface{}{"page": "home"}))
+	ensureError(t, iw.Append(clicks, map[string]interface{}{"x": 2}))
+	ensureError(t, iw.Append(views, map[string]interface{}{"page": 3}), "cannot encode binary record")
+
+	// each schema is written once
+	if actual, expected := bytes.Count(bb.Bytes(), []byte(`"name":"Click"`)), 1; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	ir, err := NewInterleavedReader(bb, WithNumericDecoding(NumericDecodingWide))
+	ensureError(t, err)
+	var got []string
+	for ir.Scan() {
+		codec, datum, err := ir.Read()
+		ensureError(t, err)
+		got = append(got, fmt.Sprintf("%s:%v", codec.typeName, datum))
+	}
+	ensureError(t, ir.Err())
+	if actual, expected := fmt.Sprint(got), "[Click:map[x:1] View:map[page:home] Click:map[x:2]]"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if actual, expected := len(ir.Codecs()), 2; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if actual, expected := ir.Codecs()[0].Options().NumericDecoding, NumericDecodingWide; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
+
+func TestInterleavedSameFingerprint(t *testing.T) {
+	// canonical forms omit defaults, so both schemas have the same fingerprint
+	first := newCodecUsingV2(t, `{"type":"record","name":"r","fields":[{"name":"f","type":"int","default":1}]}`)
+	second := newCodecUsingV2(t, `{"type":"record","name":"r","fields":[{"name":"f","type":"int","default":2}]}`)
+
+	bb := new(bytes.Buffer)
+	iw, err := NewInterleavedWriter(bb)
+	ensureError(t, err)
+	ensureError(t, iw.Append(first, map[string]interface{}{"f": 1}))
+	ensureError(t, iw.Append(second, map[string]interface{}{"f": 2}))
+
+	ir, err := NewInterleavedReader(bb)
+	ensureError(t, err)
+	for _, expected := range []*Codec{first, second} {
+		if !ir.Scan() {
+			t.Fatalf("GOT: %v; WANT: %v", ir.Err(), true)
+		}
+		codec, message := ir.Message()
+		if codec.Schema() != expected.Schema() {
+			t.Errorf("GOT: %v; WANT: %v", codec.Schema(), expected.Schema())
+		}
+		if _, _, err := FingerprintFromSOE(message); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestInterleavedReaderErrors(t *testing.T) {
+	_, err := NewInterleavedReader(bytes.NewReader([]byte("Obj\x01")))
+	ensureError(t, err, "invalid magic number")
+
+	ir, err := NewInterleavedReader(bytes.NewReader(append(append([]byte(nil), interleavedMagic...), 1, 0x14, 0xc3, 0x01, 1, 2, 3, 4, 5, 6, 7, 8)))
+	ensureError(t, err)
+	if ir.Scan() {
+		t.Fatalf("GOT: %v; WANT: %v", true, false)
+	}
+	ensureError(t, ir.Err(), "unknown schema fingerprint")
+
+	ir, err = NewInterleavedReader(bytes.NewReader(append(append([]byte(nil), interleavedMagic...), 0, 0x10, '"')))
+	ensureError(t, err)
+	if ir.Scan() {
+		t.Fatalf("GOT: %v; WANT: %v", true, false)
+	}
+	ensureError(t, ir.Err(), "unexpected EOF")
+
+	_, _, err = ir.Read()
+	ensureError(t, err, "without successful Scan")
+}
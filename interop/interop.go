@@ -0,0 +1,369 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+// Package interop verifies that goavro reads and writes data byte-compatibly
+// with the other Avro implementations, using the interop data files the Avro
+// project shares between its implementations.
+//
+// Each implementation generates Object Container Files named after itself and
+// their compression codec, such as "java.avro" or "py_deflate.avro", holding
+// data of the shared interop schema, and every implementation reads the files
+// of the others. Generate writes the files of goavro, and VerifyDir checks the
+// files of the other implementations:
+//
+//     if err := interop.Generate("build/interop/data"); err != nil {
+//         log.Fatal(err)
+//     }
+//     results, err := interop.VerifyDir("build/interop/data")
+//     if err != nil {
+//         log.Fatal(err)
+//     }
+//     for _, result := range results {
+//         fmt.Println(result)
+//     }
+//
+// A file is verified by decoding each of its data using the writer schema
+// recorded in the file, encoding the decoded datum again, and comparing the
+// encoded bytes with the bytes of the file. Files of any schema may be
+// verified this way, including files exercising logical types.
+//
+// Vectors and WriteVectors provide a corpus of single data, with their schema,
+// Avro JSON encoding, binary encoding, and schema fingerprint, covering edge
+// cases such as extreme numbers, non-ASCII text, and empty union branches, so
+// other implementations may be validated datum by datum:
+//
+//     fh, err := os.Create("build/interop/vectors.jsonl")
+//     if err != nil {
+//         log.Fatal(err)
+//     }
+//     if err = interop.WriteVectors(fh); err != nil {
+//         log.Fatal(err)
+//     }
+package interop
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/golang/snappy"
+	"github.com/linkedin/goavro/v2"
+)
+
+// Schema is the interop schema shared by the Avro implementations.
+const Schema = `{
+  "type": "record",
+  "name": "Interop",
+  "namespace": "org.apache.avro",
+  "fields": [
+    {"name": "intField", "type": "int"},
+    {"name": "longField", "type": "long"},
+    {"name": "stringField", "type": "string"},
+    {"name": "boolField", "type": "boolean"},
+    {"name": "floatField", "type": "float"},
+    {"name": "doubleField", "type": "double"},
+    {"name": "bytesField", "type": "bytes"},
+    {"name": "nullField", "type": "null"},
+    {"name": "arrayField", "type": {"type": "array", "items": "double"}},
+    {"name": "mapField", "type": {"type": "map", "values": {"type": "record", "name": "Foo", "fields": [{"name": "label", "type": "string"}]}}},
+    {"name": "unionField", "type": ["boolean", "double", {"type": "array", "items": "bytes"}]},
+    {"name": "enumField", "type": {"type": "enum", "name": "Kind", "symbols": ["A", "B", "C"]}},
+    {"name": "fixedField", "type": {"type": "fixed", "name": "MD5", "size": 16}},
+    {"name": "recordField", "type": {"type": "record", "name": "Node", "fields": [
+      {"name": "label", "type": "string"},
+      {"name": "children", "type": {"type": "array", "items": "Node"}}
+    ]}}
+  ]
+}`
+
+// Compressions lists the compression codecs of the files written by Generate.
+var Compressions = []string{goavro.CompressionNullLabel, goavro.CompressionDeflateLabel, goavro.CompressionSnappyLabel}
+
+// Datum returns the datum of the interop schema written by Generate, which is
+// the datum the Python implementation writes.
+func Datum() map[string]interface{} {
+	return map[string]interface{}{
+		"intField":    int32(12),
+		"longField":   int64(15234324),
+		"stringField": "hey",
+		"boolField":   true,
+		"floatField":  float32(1234.0),
+		"doubleField": float64(-1234.0),
+		"bytesField":  []byte("12312adf"),
+		"nullField":   nil,
+		"arrayField":  []interface{}{5.0, 0.0, 12.0},
+		"mapField": map[string]interface{}{
+			"a":   map[string]interface{}{"label": "a"},
+			"bee": map[string]interface{}{"label": "cee"},
+		},
+		"unionField": goavro.Union("double", 12.0),
+		"enumField":  "C",
+		"fixedField": []byte("1019181716151413"),
+		"recordField": map[string]interface{}{
+			"label": "blah",
+			"children": []interface{}{
+				map[string]interface{}{"label": "inner", "children": []interface{}{}},
+			},
+		},
+	}
+}
+
+// FileName returns the name of the interop data file of the implementation
+// and the compression codec, such as "go_deflate.avro".
+func FileName(implementation, compression string) string {
+	if compression == goavro.CompressionNullLabel {
+		return implementation + ".avro"
+	}
+	return implementation + "_" + compression + ".avro"
+}
+
+// Generate writes the interop data files of goavro to the directory, one for
+// each of the Compressions, each holding Datum.
+func Generate(dir string) error {
+	codec, err := goavro.NewCodec(Schema)
+	if err != nil {
+		return fmt.Errorf("cannot generate interop data: %s", err)
+	}
+	for _, compression := range Compressions {
+		if err = generate(filepath.Join(dir, FileName("go", compression)), codec, compression); err != nil {
+			return fmt.Errorf("cannot generate interop data: %s", err)
+		}
+	}
+	return nil
+}
+
+func generate(path string, codec *goavro.Codec, compression string) error {
+	fh, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	ocfw, err := goavro.NewOCFWriter(goavro.OCFConfig{W: fh, Codec: codec, CompressionName: compression})
+	if err == nil {
+		err = ocfw.Append([]interface{}{Datum()})
+	}
+	if cerr := fh.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Result describes the verification of one interop data file.
+type Result struct {
+	File        string // path of the file
+	Compression string // compression codec of the file
+	Count       int    // number of data verified
+
+	// Reordered is the number of data, among those verified, whose encoding
+	// differs from the file only in the order of map entries, which Avro
+	// leaves to each implementation.
+	Reordered int
+
+	// Skipped is the reason the data of the file were not verified, such as
+	// a compression codec goavro does not support, or empty.
+	Skipped string
+
+	// Err is the error that stopped the verification, such as a datum that
+	// cannot be decoded, or whose encoding differs from the file, or nil.
+	Err error
+}
+
+func (r Result) String() string {
+	switch {
+	case r.Err != nil:
+		return fmt.Sprintf("%s: FAIL after %d data: %s", r.File, r.Count, r.Err)
+	case r.Skipped != "":
+		return fmt.Sprintf("%s: SKIP: %s", r.File, r.Skipped)
+	default:
+		return fmt.Sprintf("%s: ok: %d data (%s), %d with reordered map entries", r.File, r.Count, r.Compression, r.Reordered)
+	}
+}
+
+// VerifyDir verifies each file of the directory whose name ends with ".avro",
+// in the order of their names.
+func VerifyDir(dir string) ([]Result, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.avro"))
+	if err != nil {
+		return nil, fmt.Errorf("cannot verify interop data: %s", err)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("cannot verify interop data: no files in %q", dir)
+	}
+	sort.Strings(paths)
+	results := make([]Result, len(paths))
+	for i, path := range paths {
+		results[i] = VerifyFile(path)
+	}
+	return results, nil
+}
+
+// VerifyFile verifies the data of the Object Container File.
+func VerifyFile(path string) Result {
+	result := Result{File: path}
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	verify(&result, buf)
+	return result
+}
+
+// headerCodec decodes the header of Object Container Files.
+var headerCodec, _ = goavro.NewCodec(`{"type":"record","name":"org.apache.avro.file.Header","fields":[
+	{"name":"magic","type":{"type":"fixed","name":"Magic","size":4}},
+	{"name":"meta","type":{"type":"map","values":"bytes"}},
+	{"name":"sync","type":{"type":"fixed","name":"Sync","size":16}}
+]}`)
+
+var longCodec, _ = goavro.NewCodec(`"long"`)
+
+func verify(result *Result, buf []byte) {
+	value, buf, err := headerCodec.NativeFromBinary(buf)
+	if err != nil {
+		result.Err = fmt.Errorf("cannot read header: %s", err)
+		return
+	}
+	header := value.(map[string]interface{})
+	if !bytes.Equal(header["magic"].([]byte), []byte("Obj\x01")) {
+		result.Err = errors.New("cannot read header: invalid magic bytes")
+		return
+	}
+	meta := header["meta"].(map[string]interface{})
+	result.Compression = goavro.CompressionNullLabel
+	if compression, ok := meta["avro.codec"].([]byte); ok && len(compression) > 0 {
+		result.Compression = string(compression)
+	}
+	decompress, ok := decompressors[result.Compression]
+	if !ok {
+		result.Skipped = fmt.Sprintf("unsupported compression codec: %q", result.Compression)
+		return
+	}
+	schema, _ := meta["avro.schema"].([]byte)
+	// NOTE: Sorting map entries makes encoding deterministic, so whether a
+	// datum is reordered does not depend on the order of Go map iteration.
+	codec, err := goavro.NewCodec(string(schema), goavro.WithSortedMapEncoding(true))
+	if err != nil {
+		result.Err = fmt.Errorf("cannot read header: %s", err)
+		return
+	}
+	sync := header["sync"].([]byte)
+
+	for block := 0; len(buf) > 0; block++ {
+		var count, size interface{}
+		if count, buf, err = longCodec.NativeFromBinary(buf); err != nil {
+			result.Err = fmt.Errorf("block %d: cannot read count: %s", block, err)
+			return
+		}
+		if size, buf, err = longCodec.NativeFromBinary(buf); err != nil {
+			result.Err = fmt.Errorf("block %d: cannot read size: %s", block, err)
+			return
+		}
+		if size.(int64) < 0 || size.(int64)+int64(len(sync)) > int64(len(buf)) {
+			result.Err = fmt.Errorf("block %d: size exceeds remaining file: %d", block, size)
+			return
+		}
+		data, err := decompress(buf[:size.(int64)])
+		if err != nil {
+			result.Err = fmt.Errorf("block %d: cannot decompress: %s", block, err)
+			return
+		}
+		buf = buf[size.(int64):]
+		if !bytes.Equal(buf[:len(sync)], sync) {
+			result.Err = fmt.Errorf("block %d: sync marker mismatch", block)
+			return
+		}
+		buf = buf[len(sync):]
+
+		for i := int64(0); i < count.(int64); i++ {
+			var reordered bool
+			if data, reordered, err = verifyDatum(codec, data); err != nil {
+				result.Err = fmt.Errorf("datum %d: %s", result.Count, err)
+				return
+			}
+			result.Count++
+			if reordered {
+				result.Reordered++
+			}
+		}
+		if len(data) > 0 {
+			result.Err = fmt.Errorf("block %d: extra bytes after data: %d", block, len(data))
+			return
+		}
+	}
+}
+
+// verifyDatum decodes the datum at the start of buf, and checks that encoding
+// it again produces the same bytes, or bytes that only differ in the order of
+// map entries, in which case it returns true. It returns the bytes following
+// the datum.
+func verifyDatum(codec *goavro.Codec, buf []byte) ([]byte, bool, error) {
+	datum, rest, err := codec.NativeFromBinary(buf)
+	if err != nil {
+		return nil, false, err
+	}
+	expected := buf[:len(buf)-len(rest)]
+	actual, err := codec.BinaryFromNative(nil, datum)
+	if err != nil {
+		return nil, false, fmt.Errorf("cannot encode decoded datum: %s", err)
+	}
+	if bytes.Equal(actual, expected) {
+		return rest, false, nil
+	}
+	if len(actual) == len(expected) {
+		// Map entries are encoded in the lexicographic order of their keys,
+		// so an encoding of the same length that decodes to the same datum
+		// only differs in that order.
+		if reencoded, _, err := codec.NativeFromBinary(actual); err == nil && reflect.DeepEqual(reencoded, datum) {
+			return rest, true, nil
+		}
+	}
+	return nil, false, fmt.Errorf("encoding differs from file: %s; file: %s", hexBytes(actual), hexBytes(expected))
+}
+
+func hexBytes(buf []byte) string {
+	hex := make([]string, len(buf))
+	for i, b := range buf {
+		hex[i] = fmt.Sprintf("%02x", b)
+	}
+	return strings.Join(hex, " ")
+}
+
+// decompressors decompress the blocks of the compression codecs goavro
+// supports without registering additional ones.
+var decompressors = map[string]func([]byte) ([]byte, error){
+	goavro.CompressionNullLabel: func(block []byte) ([]byte, error) { return block, nil },
+	goavro.CompressionDeflateLabel: func(block []byte) ([]byte, error) {
+		rc := flate.NewReader(bytes.NewReader(block))
+		defer rc.Close()
+		return ioutil.ReadAll(rc)
+	},
+	goavro.CompressionSnappyLabel: func(block []byte) ([]byte, error) {
+		index := len(block) - 4 // last 4 bytes is crc32 of decoded block
+		if index <= 0 {
+			return nil, fmt.Errorf("cannot decompress snappy without CRC32 checksum: %d", len(block))
+		}
+		decoded, err := snappy.Decode(nil, block[:index])
+		if err != nil {
+			return nil, err
+		}
+		if actual, expected := crc32.ChecksumIEEE(decoded), binary.BigEndian.Uint32(block[index:]); actual != expected {
+			return nil, fmt.Errorf("snappy CRC32 checksum mismatch: %x != %x", actual, expected)
+		}
+		return decoded, nil
+	},
+}
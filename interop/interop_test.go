@@ -0,0 +1,183 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package interop
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+func TestGenerateAndVerify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "interop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err = Generate(dir); err != nil {
+		t.Fatal(err)
+	}
+	results, err := VerifyDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := len(results), len(Compressions); actual != expected {
+		t.Fatalf("GOT: %v; WANT: %v", actual, expected)
+	}
+	for _, result := range results {
+		if result.Err != nil || result.Skipped != "" || result.Count != 1 {
+			t.Errorf("GOT: %s", result)
+		}
+	}
+
+	fh, err := os.Open(filepath.Join(dir, FileName("go", goavro.CompressionDeflateLabel)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+	ocfr, err := goavro.NewOCFReader(fh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ocfr.Scan() {
+		t.Fatalf("GOT: %v; WANT: one datum", ocfr.Err())
+	}
+	datum, err := ocfr.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := Datum(); !reflect.DeepEqual(datum, map[string]interface{}(expected)) {
+		t.Errorf("GOT: %v; WANT: %v", datum, expected)
+	}
+}
+
+// writeFile writes an Object Container File holding one block of the encoded
+// data, with the compression codec recorded in its header.
+func writeFile(t *testing.T, dir, schema, compression string, count int64, data []byte) string {
+	t.Helper()
+	sync := []byte("0123456789abcdef")
+	buf, err := headerCodec.BinaryFromNative(nil, map[string]interface{}{
+		"magic": []byte("Obj\x01"),
+		"meta":  map[string]interface{}{"avro.schema": []byte(schema), "avro.codec": []byte(compression)},
+		"sync":  sync,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf, err = longCodec.BinaryFromNative(buf, count); err != nil {
+		t.Fatal(err)
+	}
+	if buf, err = longCodec.BinaryFromNative(buf, len(data)); err != nil {
+		t.Fatal(err)
+	}
+	buf = append(append(buf, data...), sync...)
+	path := filepath.Join(dir, "other.avro")
+	if err = ioutil.WriteFile(path, buf, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestVerifyFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "interop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	t.Run("logical type", func(t *testing.T) {
+		schema := `{"type":"long","logicalType":"timestamp-millis"}`
+		result := VerifyFile(writeFile(t, dir, schema, "null", 2, []byte{0x02, 0xfe, 0x03}))
+		if result.Err != nil || result.Count != 2 {
+			t.Errorf("GOT: %s", result)
+		}
+	})
+
+	t.Run("reordered map entries", func(t *testing.T) {
+		schema := `{"type":"map","values":"int"}`
+		// the map {"a": 1, "b": 2}, whose entries are sorted by key
+		sorted := []byte{0x04, 0x02, 'a', 0x02, 0x02, 'b', 0x04, 0x00}
+		result := VerifyFile(writeFile(t, dir, schema, "null", 1, sorted))
+		if result.Err != nil || result.Count != 1 || result.Reordered != 0 {
+			t.Errorf("GOT: %s", result)
+		}
+		// the same map, whose entries are in the reverse order
+		reversed := []byte{0x04, 0x02, 'b', 0x04, 0x02, 'a', 0x02, 0x00}
+		result = VerifyFile(writeFile(t, dir, schema, "null", 1, reversed))
+		if result.Err != nil || result.Count != 1 || result.Reordered != 1 {
+			t.Errorf("GOT: %s", result)
+		}
+	})
+
+	t.Run("non-minimal varint", func(t *testing.T) {
+		result := VerifyFile(writeFile(t, dir, `"long"`, "null", 1, []byte{0x82, 0x00}))
+		if result.Err == nil || !strings.Contains(result.Err.Error(), "datum 0: encoding differs from file: 02; file: 82 00") {
+			t.Errorf("GOT: %s", result)
+		}
+	})
+
+	t.Run("unsupported compression", func(t *testing.T) {
+		result := VerifyFile(writeFile(t, dir, `"long"`, "bzip2", 1, []byte{0x02}))
+		if result.Skipped == "" {
+			t.Errorf("GOT: %s", result)
+		}
+	})
+}
+
+func TestVectors(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := WriteVectors(buf); err != nil {
+		t.Fatal(err)
+	}
+	decoder := json.NewDecoder(buf)
+	var count int
+	for decoder.More() {
+		var v Vector
+		if err := decoder.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+		if err := VerifyVector(v); err != nil {
+			t.Error(err)
+		}
+		count++
+	}
+	if actual, expected := count, len(vectorCases); actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
+
+func TestVerifyVectorMismatch(t *testing.T) {
+	vectors, err := Vectors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := vectors[0]
+	for _, vector := range vectors {
+		if vector.Name == "int-max" {
+			v = vector
+		}
+	}
+	v.Binary = "feffffff07"
+	if err = VerifyVector(v); err == nil || !strings.Contains(err.Error(), "binary decodes to 1073741823; datum decodes to 2147483647") {
+		t.Errorf("GOT: %v; WANT: mismatch", err)
+	}
+	v.Fingerprint = "0000000000000000"
+	if err = VerifyVector(v); err == nil || !strings.Contains(err.Error(), "fingerprint differs: 7275d51a3f395c8f") {
+		t.Errorf("GOT: %v; WANT: fingerprint mismatch", err)
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package interop
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// Vector is a test vector for validating another Avro implementation against
+// goavro: decoding Binary using Schema ought to produce the datum whose Avro
+// JSON encoding is Datum, encoding that datum ought to produce Binary, and the
+// CRC-64-AVRO fingerprint of the canonical form of Schema ought to be
+// Fingerprint.
+type Vector struct {
+	Name        string          `json:"name"`
+	Schema      string          `json:"schema"`
+	Canonical   string          `json:"canonical"`
+	Fingerprint string          `json:"fingerprint"` // 16 hexadecimal digits, most significant first
+	Datum       json.RawMessage `json:"datum"`       // Avro JSON encoding
+	Binary      string          `json:"binary"`      // hexadecimal
+}
+
+// vectorCase is a datum of a schema from which a Vector is generated.
+type vectorCase struct {
+	name   string
+	schema string
+	datum  interface{}
+}
+
+// vectorCases cover the edge cases implementations most often disagree on.
+// Avro names are restricted to ASCII by the specification, so non-ASCII text
+// is exercised by strings and map keys. Floating point NaN and infinities are
+// omitted, because the Avro JSON encoding cannot represent them, and maps have
+// at most one entry, because Avro leaves the order of map entries to each
+// implementation.
+var vectorCases = []vectorCase{
+	{"null", `"null"`, nil},
+	{"boolean-false", `"boolean"`, false},
+	{"boolean-true", `"boolean"`, true},
+	{"int-zero", `"int"`, int32(0)},
+	{"int-minus-one", `"int"`, int32(-1)},
+	{"int-max", `"int"`, int32(math.MaxInt32)},
+	{"int-min", `"int"`, int32(math.MinInt32)},
+	{"long-one-byte-max", `"long"`, int64(63)},
+	{"long-two-bytes-min", `"long"`, int64(64)},
+	{"long-max", `"long"`, int64(math.MaxInt64)},
+	{"long-min", `"long"`, int64(math.MinInt64)},
+	{"float-negative-zero", `"float"`, float32(math.Copysign(0, -1))},
+	{"float-max", `"float"`, float32(math.MaxFloat32)},
+	{"float-smallest-subnormal", `"float"`, float32(math.SmallestNonzeroFloat32)},
+	{"double-negative-zero", `"double"`, math.Copysign(0, -1)},
+	{"double-max", `"double"`, math.MaxFloat64},
+	{"double-smallest-subnormal", `"double"`, math.SmallestNonzeroFloat64},
+	{"double-max-safe-integer", `"double"`, float64(1<<53 - 1)},
+	{"string-empty", `"string"`, ""},
+	{"string-unicode", `"string"`, "héllo, 世界 \U0001f389"},
+	{"string-escapes", `"string"`, "\"\\/\b\f\n\r\t\x00\x1f"},
+	{"bytes-empty", `"bytes"`, []byte{}},
+	{"bytes-all-values", `"bytes"`, allByteValues()},
+	{"fixed", `{"type":"fixed","name":"com.example.Hash","size":4}`, []byte{0x00, 0x7f, 0x80, 0xff}},
+	{"enum-first", `{"type":"enum","name":"Suit","symbols":["SPADES","HEARTS","_1"]}`, "SPADES"},
+	{"enum-last", `{"type":"enum","name":"Suit","symbols":["SPADES","HEARTS","_1"]}`, "_1"},
+	{"array-empty", `{"type":"array","items":"long"}`, []interface{}{}},
+	{"array", `{"type":"array","items":"long"}`, []interface{}{int64(-64), int64(0), int64(64)}},
+	{"array-of-null", `{"type":"array","items":"null"}`, []interface{}{nil, nil, nil}},
+	{"map-empty", `{"type":"map","values":"int"}`, map[string]interface{}{}},
+	{"map-unicode-key", `{"type":"map","values":"int"}`, map[string]interface{}{"über": int32(1)}},
+	{"union-null-branch", `["null","string"]`, nil},
+	{"union-string-branch", `["null","string"]`, goavro.Union("string", "")},
+	{"union-single-null", `["null"]`, nil},
+	{"union-empty-record-branch", `["null",{"type":"record","name":"Empty","fields":[]}]`, goavro.Union("Empty", map[string]interface{}{})},
+	{"union-named-branch", `["null",{"type":"enum","name":"com.example.Color","symbols":["RED"]}]`, goavro.Union("com.example.Color", "RED")},
+	{"record-empty", `{"type":"record","name":"Empty","fields":[]}`, map[string]interface{}{}},
+	{"record-namespace", `{"type":"record","name":"Point","namespace":"com.example","fields":[{"name":"x","type":"int"},{"name":"y","type":"int"}]}`, map[string]interface{}{"x": int32(-1), "y": int32(1)}},
+	{"record-recursive", `{"type":"record","name":"List","fields":[{"name":"value","type":"int"},{"name":"next","type":["null","List"]}]}`, map[string]interface{}{
+		"value": int32(1),
+		"next":  goavro.Union("List", map[string]interface{}{"value": int32(2), "next": nil}),
+	}},
+	{"record-nested-collections", `{"type":"record","name":"Nested","fields":[{"name":"matrix","type":{"type":"array","items":{"type":"array","items":"int"}}},{"name":"index","type":{"type":"map","values":{"type":"array","items":"string"}}}]}`, map[string]interface{}{
+		"matrix": []interface{}{[]interface{}{}, []interface{}{int32(1)}},
+		"index":  map[string]interface{}{"": []interface{}{""}},
+	}},
+}
+
+func allByteValues() []byte {
+	buf := make([]byte, 256)
+	for i := range buf {
+		buf[i] = byte(i)
+	}
+	return buf
+}
+
+// Vectors returns the test vectors generated by goavro.
+func Vectors() ([]Vector, error) {
+	vectors := make([]Vector, len(vectorCases))
+	for i, c := range vectorCases {
+		codec, err := goavro.NewCodec(c.schema)
+		if err != nil {
+			return nil, fmt.Errorf("cannot generate test vector %q: %s", c.name, err)
+		}
+		binary, err := codec.BinaryFromNative(nil, c.datum)
+		if err != nil {
+			return nil, fmt.Errorf("cannot generate test vector %q: %s", c.name, err)
+		}
+		textual, err := codec.TextualFromNative(nil, c.datum)
+		if err != nil {
+			return nil, fmt.Errorf("cannot generate test vector %q: %s", c.name, err)
+		}
+		vectors[i] = Vector{
+			Name:        c.name,
+			Schema:      c.schema,
+			Canonical:   codec.CanonicalSchema(),
+			Fingerprint: fmt.Sprintf("%016x", codec.Rabin),
+			Datum:       textual,
+			Binary:      hex.EncodeToString(binary),
+		}
+	}
+	return vectors, nil
+}
+
+// WriteVectors writes the test vectors generated by goavro to w, as one JSON
+// object per line.
+func WriteVectors(w io.Writer) error {
+	vectors, err := Vectors()
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, v := range vectors {
+		if err = encoder.Encode(v); err != nil {
+			return fmt.Errorf("cannot write test vector %q: %s", v.Name, err)
+		}
+	}
+	return nil
+}
+
+// VerifyVector checks that goavro agrees with the test vector, such as one
+// generated by another implementation.
+func VerifyVector(v Vector) error {
+	codec, err := goavro.NewCodec(v.Schema)
+	if err != nil {
+		return fmt.Errorf("test vector %q: %s", v.Name, err)
+	}
+	if actual := codec.CanonicalSchema(); actual != v.Canonical {
+		return fmt.Errorf("test vector %q: canonical schema differs: %s", v.Name, actual)
+	}
+	if actual := fmt.Sprintf("%016x", codec.Rabin); actual != v.Fingerprint {
+		return fmt.Errorf("test vector %q: fingerprint differs: %s", v.Name, actual)
+	}
+	binary, err := hex.DecodeString(v.Binary)
+	if err != nil {
+		return fmt.Errorf("test vector %q: cannot decode binary: %s", v.Name, err)
+	}
+	fromBinary, rest, err := codec.NativeFromBinary(binary)
+	if err != nil {
+		return fmt.Errorf("test vector %q: cannot decode binary: %s", v.Name, err)
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("test vector %q: extra bytes after datum: %d", v.Name, len(rest))
+	}
+	fromTextual, _, err := codec.NativeFromTextual(v.Datum)
+	if err != nil {
+		return fmt.Errorf("test vector %q: cannot decode datum: %s", v.Name, err)
+	}
+	// Compare the data by their encoding, because decoding an empty array or
+	// bytes from the Avro JSON encoding returns nil.
+	expected, err := codec.TextualFromNative(nil, fromTextual)
+	if err != nil {
+		return fmt.Errorf("test vector %q: cannot encode datum: %s", v.Name, err)
+	}
+	textual, err := codec.TextualFromNative(nil, fromBinary)
+	if err != nil {
+		return fmt.Errorf("test vector %q: cannot encode decoded binary: %s", v.Name, err)
+	}
+	if !bytes.Equal(textual, expected) {
+		return fmt.Errorf("test vector %q: binary decodes to %s; datum decodes to %s", v.Name, textual, expected)
+	}
+	actual, err := codec.BinaryFromNative(nil, fromTextual)
+	if err != nil {
+		return fmt.Errorf("test vector %q: cannot encode datum: %s", v.Name, err)
+	}
+	if !bytes.Equal(actual, binary) {
+		return fmt.Errorf("test vector %q: encoding differs from binary: %s", v.Name, hexBytes(actual))
+	}
+	return nil
+}
@@ -0,0 +1,270 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+)
+
+// JSONHintBase64 is the hint for bytes and fixed values encoded as base64
+// strings, rather than as strings whose code points are the byte values, as
+// described by the Avro specification for its JSON encoding.
+const JSONHintBase64 = "base64"
+
+// JSONHints resolve the values of standard JSON that cannot be decoded using
+// the schema alone, keyed by the path of the value, using the conventions of
+// SchemaFieldDoc, where the empty path is the top level value.
+//
+// The hint of a union is the name of the member used to decode the value: the
+// full or unqualified name of a named type, or the type name of other types,
+// such as "string" or "map". Unions without a hint use the first member that
+// can encode the value, preferring records whose fields match the keys of a
+// JSON object. The hint of bytes and fixed values is JSONHintBase64 when they
+// are encoded as base64 strings; as the hint of a union, it selects the first
+// bytes or fixed member, decoding the value from base64.
+//
+//     hints := goavro.JSONHints{
+//         "payload":                          "com.example.Click",
+//         "payload(com.example.Click).token": goavro.JSONHintBase64,
+//     }
+//     datum, _, err := codec.NativeFromJSON([]byte(`{"payload":{"token":"AAE="}}`), hints)
+type JSONHints map[string]string
+
+// NativeFromJSON decodes a standard JSON value, where unions are not wrapped in
+// objects naming their member, from the start of buf to the native form of the
+// Codec, using the hints to resolve ambiguous values. Record fields missing
+// from the JSON object are given their default values. On success, it returns
+// the decoded datum, the remaining bytes of buf, and a nil error value. On
+// error, it returns nil for the datum, the original buf slice, and the error
+// message.
+func (c *Codec) NativeFromJSON(buf []byte, hints JSONHints) (interface{}, []byte, error) {
+	binary, rest, err := c.BinaryFromJSON(nil, buf, hints)
+	if err != nil {
+		return nil, buf, err
+	}
+	datum, _, err := c.NativeFromBinary(binary)
+	if err != nil {
+		return nil, buf, fmt.Errorf("cannot decode JSON: %s", err)
+	}
+	return datum, rest, nil
+}
+
+// BinaryFromJSON encodes a standard JSON value from the start of buf using the
+// schema of the Codec, as described for NativeFromJSON, and appends its binary
+// encoding to dst. On success, it returns the new dst slice, the remaining
+// bytes of buf, and a nil error value. On error, it returns the original dst
+// and buf slices, and the error message.
+func (c *Codec) BinaryFromJSON(dst, buf []byte, hints JSONHints) ([]byte, []byte, error) {
+	n, err := schemaNodeFromCodec(c)
+	if err != nil {
+		return dst, buf, fmt.Errorf("cannot decode JSON: %s", err)
+	}
+	value, rest, err := plainFromJSON(buf)
+	if err != nil {
+		return dst, buf, fmt.Errorf("cannot decode JSON: %s", err)
+	}
+	newDst, err := binaryFromHintedJSON(n, dst, value, "", hints)
+	if err != nil {
+		return dst, buf, fmt.Errorf("cannot decode JSON: %s", err)
+	}
+	return newDst, rest, nil
+}
+
+// plainFromJSON decodes the first JSON value of buf, converting numbers to
+// int64, or uint64, when they are integers, and to float64 otherwise.
+func plainFromJSON(buf []byte) (interface{}, []byte, error) {
+	cr := &countingReader{r: bytes.NewReader(buf)}
+	decoder := json.NewDecoder(cr)
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, nil, err
+	}
+	// NOTE: The decoder reads ahead, so the bytes it buffered were not used.
+	buffered, _ := io.Copy(ioutil.Discard, decoder.Buffered())
+	return plainNumbers(value), buf[cr.n-buffered:], nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
+func plainNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(string(v), 10, 64); err == nil {
+			return u
+		}
+		f, _ := strconv.ParseFloat(string(v), 64)
+		return f
+	case []interface{}:
+		for i, item := range v {
+			v[i] = plainNumbers(item)
+		}
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = plainNumbers(item)
+		}
+	}
+	return value
+}
+
+// binaryFromHintedJSON appends the binary encoding of the plain JSON value,
+// found at path, to buf.
+func binaryFromHintedJSON(n *schemaNode, buf []byte, value interface{}, path string, hints JSONHints) ([]byte, error) {
+	var err error
+	switch n.typeName {
+	case "union":
+		index := -1
+		if hint, ok := hints[path]; ok {
+			if index = hintedUnionMember(n, hint); index < 0 {
+				return nil, fmt.Errorf("%s: hint %q ought to name a member of %s", hintPath(path), hint, n.label())
+			}
+		} else if index = plainUnionIndex(n, value); index < 0 {
+			return nil, fmt.Errorf("%s: no member of %s supports value; received: %T", hintPath(path), n.label(), value)
+		}
+		member := n.members[index]
+		memberPath := path
+		if member.typeName == "record" {
+			var records int
+			for _, m := range n.members {
+				if m.typeName == "record" {
+					records++
+				}
+			}
+			if records > 1 {
+				memberPath += "(" + member.fullName + ")"
+			}
+		}
+		buf, _ = longBinaryFromNative(buf, index)
+		return binaryFromHintedJSON(member, buf, value, memberPath, hints)
+	case "bytes", "fixed":
+		s, ok := value.(string)
+		if !ok {
+			break
+		}
+		if hints[path] == JSONHintBase64 {
+			if value, err = base64.StdEncoding.DecodeString(s); err != nil {
+				return nil, fmt.Errorf("%s: cannot decode base64: %s", hintPath(path), err)
+			}
+			break
+		}
+		runes := []rune(s)
+		someBytes := make([]byte, len(runes))
+		for i, r := range runes {
+			if r > math.MaxUint8 {
+				return nil, fmt.Errorf("%s: %s ought to have code points less than 256: %q", hintPath(path), n.typeName, s)
+			}
+			someBytes[i] = byte(r)
+		}
+		value = someBytes
+	case "record":
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: record %q ought to be an object; received: %T", hintPath(path), n.fullName, value)
+		}
+		for _, f := range n.fields {
+			fieldPath := f.name
+			if path != "" {
+				fieldPath = path + "." + f.name
+			}
+			fieldValue, ok := fields[f.name]
+			if !ok {
+				if !f.hasDefault {
+					return nil, fmt.Errorf("%s: schema does not specify default value and no value provided", hintPath(fieldPath))
+				}
+				if buf, err = binaryFromDefault(f.node, buf, f.defaultValue); err != nil {
+					return nil, fmt.Errorf("%s: cannot encode default value: %s", hintPath(fieldPath), err)
+				}
+				continue
+			}
+			if buf, err = binaryFromHintedJSON(f.node, buf, fieldValue, fieldPath, hints); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: array ought to be an array; received: %T", hintPath(path), value)
+		}
+		if len(items) > 0 {
+			buf, _ = longBinaryFromNative(buf, len(items))
+		}
+		for _, item := range items {
+			if buf, err = binaryFromHintedJSON(n.items, buf, item, path+"[]", hints); err != nil {
+				return nil, err
+			}
+		}
+		return longBinaryFromNative(buf, 0)
+	case "map":
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: map ought to be an object; received: %T", hintPath(path), value)
+		}
+		if len(values) > 0 {
+			buf, _ = longBinaryFromNative(buf, len(values))
+		}
+		for k, v := range values {
+			buf, _ = stringBinaryFromNative(buf, k)
+			if buf, err = binaryFromHintedJSON(n.values, buf, v, path+"{}", hints); err != nil {
+				return nil, err
+			}
+		}
+		return longBinaryFromNative(buf, 0)
+	}
+	if buf, err = binaryFromPlain(n, buf, value); err != nil {
+		return nil, fmt.Errorf("%s: %s", hintPath(path), err)
+	}
+	return buf, nil
+}
+
+// hintedUnionMember returns the index of the union member named by the hint,
+// or -1.
+func hintedUnionMember(n *schemaNode, hint string) int {
+	for i, member := range n.members {
+		if member.isNamed() {
+			if hint == member.fullName || hint == unqualifiedName(member.fullName) {
+				return i
+			}
+		} else if hint == member.typeName {
+			return i
+		}
+		if hint == JSONHintBase64 && (member.typeName == "bytes" || member.typeName == "fixed") {
+			return i
+		}
+	}
+	return -1
+}
+
+// hintPath returns the path used in error messages.
+func hintPath(path string) string {
+	if path == "" {
+		return "value"
+	}
+	return strconv.Quote(path)
+}
@@ -0,0 +1,92 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"reflect"
+	"testing"
+)
+
+const hintedEventSchema = `{"type":"record","name":"event","namespace":"com.example","fields":[
+	{"name":"id","type":"long"},
+	{"name":"payload","type":[
+		"null",
+		{"type":"record","name":"Click","fields":[{"name":"target","type":"string"},{"name":"token","type":"bytes","default":""}]},
+		{"type":"record","name":"View","fields":[{"name":"target","type":"string"}]}
+	]},
+	{"name":"tags","type":{"type":"map","values":["string","bytes"]},"default":{}},
+	{"name":"raw","type":["null","bytes"],"default":null}
+]}`
+
+func TestCodecNativeFromJSON(t *testing.T) {
+	codec := newCodecUsingV2(t, hintedEventSchema)
+
+	// without hints, the first member that can encode each value is used
+	datum, rest, err := codec.NativeFromJSON([]byte(`{"id":1,"payload":{"target":"home"}} {}`), nil)
+	ensureError(t, err)
+	expected := map[string]interface{}{
+		"id":      int64(1),
+		"payload": Union("com.example.Click", map[string]interface{}{"target": "home", "token": []byte{}}),
+		"tags":    map[string]interface{}{},
+		"raw":     nil,
+	}
+	if !reflect.DeepEqual(datum, expected) {
+		t.Errorf("GOT: %#v; WANT: %#v", datum, expected)
+	}
+	if string(rest) != " {}" {
+		t.Errorf("GOT: %q; WANT: %q", rest, " {}")
+	}
+
+	hints := JSONHints{
+		"payload": "View",
+		"tags{}":  "bytes",
+		"raw":     JSONHintBase64,
+	}
+	datum, _, err = codec.NativeFromJSON([]byte(`{"id":2,"payload":{"target":"home"},"tags":{"a":"ÿ"},"raw":"AAE="}`), hints)
+	ensureError(t, err)
+	expected = map[string]interface{}{
+		"id":      int64(2),
+		"payload": Union("com.example.View", map[string]interface{}{"target": "home"}),
+		"tags":    map[string]interface{}{"a": Union("bytes", []byte{0xff})},
+		"raw":     Union("bytes", []byte{0x00, 0x01}),
+	}
+	if !reflect.DeepEqual(datum, expected) {
+		t.Errorf("GOT: %#v; WANT: %#v", datum, expected)
+	}
+}
+
+func TestCodecNativeFromJSONBase64(t *testing.T) {
+	codec := newCodecUsingV2(t, `{"type":"record","name":"r","fields":[{"name":"b","type":"bytes"},{"name":"f","type":{"type":"fixed","name":"f","size":2}}]}`)
+	datum, _, err := codec.NativeFromJSON([]byte(`{"b":"AAE=","f":"//8="}`), JSONHints{"b": JSONHintBase64, "f": JSONHintBase64})
+	ensureError(t, err)
+	expected := map[string]interface{}{"b": []byte{0x00, 0x01}, "f": []byte{0xff, 0xff}}
+	if !reflect.DeepEqual(datum, expected) {
+		t.Errorf("GOT: %#v; WANT: %#v", datum, expected)
+	}
+
+	_, _, err = codec.NativeFromJSON([]byte(`{"b":"!","f":"//8="}`), JSONHints{"b": JSONHintBase64})
+	ensureError(t, err, `cannot decode JSON: "b": cannot decode base64`)
+}
+
+func TestCodecNativeFromJSONFail(t *testing.T) {
+	codec := newCodecUsingV2(t, hintedEventSchema)
+
+	_, _, err := codec.NativeFromJSON([]byte(`{"id":1,"payload":null}`), JSONHints{"payload": "Other"})
+	ensureError(t, err, `"payload": hint "Other" ought to name a member of union<null,com.example.Click,com.example.View>`)
+
+	_, _, err = codec.NativeFromJSON([]byte(`{"payload":null}`), nil)
+	ensureError(t, err, `"id": schema does not specify default value and no value provided`)
+
+	_, _, err = codec.NativeFromJSON([]byte(`{"id":1.5,"payload":null}`), nil)
+	ensureError(t, err, `"id"`, "lose precision")
+
+	_, _, err = codec.NativeFromJSON([]byte(`{"id":`), nil)
+	ensureError(t, err, "cannot decode JSON")
+}
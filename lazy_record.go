@@ -0,0 +1,201 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LazyRecord is a binary encoded record whose fields are decoded the first
+// time they are requested, so consumers that inspect a few fields of each
+// record, such as routing layers reading a header field, do not decode the
+// fields they never touch. Fields preceding a requested field are skipped
+// without being decoded. A LazyRecord ought not to be used by multiple
+// goroutines simultaneously.
+//
+//     record, _, err := codec.LazyRecordFromBinary(message)
+//     if err != nil {
+//         return err
+//     }
+//     city, err := record.Get("user.address.city")
+//     if err != nil {
+//         return err
+//     }
+type LazyRecord struct {
+	codec   *Codec
+	node    *schemaNode
+	buf     []byte        // the encoded record
+	offsets []int         // offset of each field skipped to so far, starting with the first field
+	values  []interface{} // decoded values, by field index
+	decoded []bool
+	nested  map[int]*LazyRecord // records of nested fields requested so far
+}
+
+// LazyRecordFromBinary returns a LazyRecord for the binary encoded record at
+// the start of buf, along with the bytes following the record. The record is
+// walked to find its end, without decoding its fields. The LazyRecord refers
+// to buf, which ought not to be modified while the record is used.
+//
+// The schema of the codec ought to be a record, and the codec ought not to
+// have field hooks, as fields decoded lazily cannot be transformed.
+func (c *Codec) LazyRecordFromBinary(buf []byte) (*LazyRecord, []byte, error) {
+	if c.recordFields == nil {
+		return nil, buf, fmt.Errorf("cannot decode lazy record: schema ought to be a record; received: %q", c.typeName)
+	}
+	if c.fieldHooks != nil {
+		return nil, buf, fmt.Errorf("cannot decode lazy record %q: codec ought not to have field hooks", c.typeName)
+	}
+	n, err := schemaNodeFromCodec(c)
+	if err != nil {
+		return nil, buf, fmt.Errorf("cannot decode lazy record %q: %s", c.typeName, err)
+	}
+	rest, err := skipBinary(n, buf)
+	if err != nil {
+		return nil, buf, fmt.Errorf("cannot decode lazy record %q: %s", c.typeName, err)
+	}
+	return newLazyRecord(c, n, buf[:len(buf)-len(rest)]), rest, nil
+}
+
+func newLazyRecord(c *Codec, n *schemaNode, buf []byte) *LazyRecord {
+	return &LazyRecord{
+		codec:   c,
+		node:    n,
+		buf:     buf,
+		offsets: []int{0},
+		values:  make([]interface{}, len(c.recordFields)),
+		decoded: make([]bool, len(c.recordFields)),
+	}
+}
+
+// Get returns the value of the field at the dot separated path, such as
+// "user.address.city", decoding it the first time it is requested. Each
+// component of the path but the last names a field whose type is a record, or
+// a union whose value is a record. When such a union holds null, Get returns
+// nil. Values are returned as NativeFromBinary returns them, so union values
+// are wrapped in a map.
+func (r *LazyRecord) Get(path string) (interface{}, error) {
+	record := r
+	names := strings.Split(path, ".")
+	for i, fieldName := range names {
+		index := record.fieldIndex(fieldName)
+		if index < 0 {
+			return nil, fmt.Errorf("cannot get %q: record %q has no field %q", path, record.codec.typeName, fieldName)
+		}
+		if i == len(names)-1 {
+			value, err := record.value(index)
+			if err != nil {
+				return nil, fmt.Errorf("cannot get %q: %s", path, err)
+			}
+			return value, nil
+		}
+		nested, err := record.record(index)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get %q: %s", path, err)
+		}
+		if nested == nil {
+			return nil, nil
+		}
+		record = nested
+	}
+	return nil, nil // should not get here because strings.Split returns at least one name
+}
+
+// Map decodes every field of the record, and returns the record as
+// NativeFromBinary returns it.
+func (r *LazyRecord) Map() (map[string]interface{}, error) {
+	datum := make(map[string]interface{}, len(r.codec.recordFields))
+	for i, field := range r.codec.recordFields {
+		value, err := r.value(i)
+		if err != nil {
+			return nil, err
+		}
+		datum[field.name] = value
+	}
+	return datum, nil
+}
+
+func (r *LazyRecord) fieldIndex(fieldName string) int {
+	for i, field := range r.codec.recordFields {
+		if field.name == fieldName {
+			return i
+		}
+	}
+	return -1
+}
+
+// fieldBytes returns the encoded bytes of the field, skipping the fields
+// preceding it the first time it is requested.
+func (r *LazyRecord) fieldBytes(index int) ([]byte, error) {
+	for len(r.offsets) <= index+1 {
+		i := len(r.offsets) - 1
+		offset := r.offsets[i]
+		rest, err := skipBinary(r.node.fields[i].node, r.buf[offset:])
+		if err != nil {
+			return nil, fmt.Errorf("record %q field %q: %s", r.codec.typeName, r.codec.recordFields[i].name, err)
+		}
+		r.offsets = append(r.offsets, len(r.buf)-len(rest))
+	}
+	return r.buf[r.offsets[index]:r.offsets[index+1]], nil
+}
+
+func (r *LazyRecord) value(index int) (interface{}, error) {
+	if r.decoded[index] {
+		return r.values[index], nil
+	}
+	buf, err := r.fieldBytes(index)
+	if err != nil {
+		return nil, err
+	}
+	field := r.codec.recordFields[index]
+	value, _, err := field.codec.nativeFromBinary(buf)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode binary record %q field %q: %s", r.codec.typeName, field.name, err)
+	}
+	r.values[index], r.decoded[index] = value, true
+	return value, nil
+}
+
+// record returns the nested record held by the field, or nil when the field is
+// a union holding null.
+func (r *LazyRecord) record(index int) (*LazyRecord, error) {
+	if nested, ok := r.nested[index]; ok {
+		return nested, nil
+	}
+	buf, err := r.fieldBytes(index)
+	if err != nil {
+		return nil, err
+	}
+	field := r.codec.recordFields[index]
+	codec, n := field.codec, r.node.fields[index].node
+	if n.typeName == "union" {
+		value, rest, err := longNativeFromBinary(buf)
+		if err != nil {
+			return nil, fmt.Errorf("record %q field %q: union index: %s", r.codec.typeName, field.name, err)
+		}
+		member := value.(int64) // longDecoder always returns int64, so elide error checking
+		if member < 0 || member >= int64(len(n.members)) {
+			return nil, fmt.Errorf("record %q field %q: union index ought to be between 0 and %d; read index: %d", r.codec.typeName, field.name, len(n.members)-1, member)
+		}
+		codec, n, buf = codec.unionMembers[member], n.members[member], rest
+		if n.typeName == "null" {
+			return nil, nil
+		}
+	}
+	if n.typeName != "record" {
+		return nil, fmt.Errorf("record %q field %q ought to be a record; received: %q", r.codec.typeName, field.name, n.label())
+	}
+	nested := newLazyRecord(codec, n, buf)
+	if r.nested == nil {
+		r.nested = make(map[int]*LazyRecord)
+	}
+	r.nested[index] = nested
+	return nested, nil
+}
@@ -0,0 +1,76 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestLazyRecord(t *testing.T) {
+	codec := newCodecUsingV2(t, `{"type":"record","name":"Message","fields":[
+		{"name":"route","type":"string"},
+		{"name":"payload","type":{"type":"array","items":"long"}},
+		{"name":"user","type":{"type":"record","name":"User","fields":[
+			{"name":"name","type":"string"},
+			{"name":"address","type":["null",{"type":"record","name":"Address","fields":[{"name":"city","type":"string"}]}]}
+		]}},
+		{"name":"sender","type":["null","User"]}
+	]}`)
+	datum := map[string]interface{}{
+		"route":   "eu",
+		"payload": []interface{}{int64(1), int64(2)},
+		"user":    map[string]interface{}{"name": "ann", "address": Union("Address", map[string]interface{}{"city": "Oslo"})},
+		"sender":  nil,
+	}
+	buf, err := codec.BinaryFromNative(nil, datum)
+	ensureError(t, err)
+
+	record, rest, err := codec.LazyRecordFromBinary(append(buf, 0xff))
+	ensureError(t, err)
+	if actual, expected := len(rest), 1; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	for path, expected := range map[string]interface{}{
+		"user.address.city": "Oslo",
+		"route":             "eu",
+		"user.name":         "ann",
+		"sender.name":       nil, // sender is null
+	} {
+		actual, err := record.Get(path)
+		ensureError(t, err)
+		if actual != expected {
+			t.Errorf("%s: GOT: %v; WANT: %v", path, actual, expected)
+		}
+	}
+	address, err := record.Get("user.address")
+	ensureError(t, err)
+	if actual, expected := fmt.Sprint(address), "map[Address:map[city:Oslo]]"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	_, err = record.Get("user.missing")
+	ensureError(t, err, `record "User" has no field "missing"`)
+	_, err = record.Get("route.name")
+	ensureError(t, err, `field "route" ought to be a record`)
+
+	all, err := record.Map()
+	ensureError(t, err)
+	if !reflect.DeepEqual(all, datum) {
+		t.Errorf("GOT: %v; WANT: %v", all, datum)
+	}
+
+	_, _, err = codec.LazyRecordFromBinary(buf[:len(buf)-3])
+	ensureError(t, err, "cannot decode lazy record")
+	_, _, err = newCodecUsingV2(t, `"long"`).LazyRecordFromBinary(buf)
+	ensureError(t, err, "schema ought to be a record")
+}
@@ -256,7 +256,7 @@ func precisionAndScaleFromSchemaMap(schemaMap map[string]interface{}) (int, int,
 
 var one = big.NewInt(1)
 
-func makeDecimalBytesCodec(st map[string]*Codec, enclosingNamespace string, schemaMap map[string]interface{}) (*Codec, error) {
+func makeDecimalBytesCodec(st map[string]*Codec, enclosingNamespace string, schemaMap map[string]interface{}, option *CodecOption) (*Codec, error) {
 	precision, scale, err := precisionAndScaleFromSchemaMap(schemaMap)
 	if err != nil {
 		return nil, err
@@ -318,7 +318,7 @@ func decimalBytesFromNative(fromNativeFn fromNativeFn, toBytesFn toBytesFn, prec
 	}
 }
 
-func makeDecimalFixedCodec(st map[string]*Codec, enclosingNamespace string, schemaMap map[string]interface{}) (*Codec, error) {
+func makeDecimalFixedCodec(st map[string]*Codec, enclosingNamespace string, schemaMap map[string]interface{}, option *CodecOption) (*Codec, error) {
 	precision, scale, err := precisionAndScaleFromSchemaMap(schemaMap)
 	if err != nil {
 		return nil, err
@@ -326,7 +326,7 @@ func makeDecimalFixedCodec(st map[string]*Codec, enclosingNamespace string, sche
 	if _, ok := schemaMap["name"]; !ok {
 		schemaMap["name"] = "fixed.decimal"
 	}
-	c, err := makeFixedCodec(st, enclosingNamespace, schemaMap)
+	c, err := makeFixedCodec(st, enclosingNamespace, schemaMap, option)
 	if err != nil {
 		return nil, err
 	}
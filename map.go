@@ -14,19 +14,23 @@ import (
 	"fmt"
 	"io"
 	"math"
-	"reflect"
 )
 
-func makeMapCodec(st map[string]*Codec, namespace string, schemaMap map[string]interface{}) (*Codec, error) {
+func makeMapCodec(st map[string]*Codec, namespace string, schemaMap map[string]interface{}, option *CodecOption) (*Codec, error) {
 	// map type must have values
 	valueSchema, ok := schemaMap["values"]
 	if !ok {
 		return nil, errors.New("Map ought to have values key")
 	}
-	valueCodec, err := buildCodec(st, namespace, valueSchema)
+	valueCodec, err := buildCodec(st, namespace, valueSchema, option)
 	if err != nil {
 		return nil, fmt.Errorf("Map values ought to be valid Avro type: %s", err)
 	}
+	// keys are always copied, because the map retains them
+	keyNativeFromBinary := stringNativeFromBinary
+	if option.SentinelErrors {
+		keyNativeFromBinary = stringNativeFromBinarySentinel
+	}
 
 	return &Codec{
 		typeName: &name{"map", nullNamespace},
@@ -36,54 +40,69 @@ func makeMapCodec(st map[string]*Codec, namespace string, schemaMap map[string]i
 
 			// block count and block size
 			if value, buf, err = longNativeFromBinary(buf); err != nil {
-				return nil, nil, fmt.Errorf("cannot decode binary map block count: %s", err)
+				return nil, nil, decodeError(option, err, "cannot decode binary map block count: %s", err)
 			}
 			blockCount := value.(int64)
+			blockSize := int64(-1)
 			if blockCount < 0 {
 				// NOTE: A negative block count implies there is a long encoded
-				// block size following the negative block count. We have no use
-				// for the block size in this decoder, so we read and discard
-				// the value.
+				// block size following the negative block count. The size of
+				// the first block is used to find the number of items in every
+				// block, so the map may be allocated once.
 				if blockCount == math.MinInt64 {
 					// The minimum number for any signed numerical type can
 					// never be made positive
-					return nil, nil, fmt.Errorf("cannot decode binary map with block count: %d", blockCount)
+					return nil, nil, decodeError(option, ErrInvalidBinary, "cannot decode binary map with block count: %d", blockCount)
 				}
 				blockCount = -blockCount // convert to its positive equivalent
-				if _, buf, err = longNativeFromBinary(buf); err != nil {
-					return nil, nil, fmt.Errorf("cannot decode binary map block size: %s", err)
+				if value, buf, err = longNativeFromBinary(buf); err != nil {
+					return nil, nil, decodeError(option, err, "cannot decode binary map block size: %s", err)
 				}
+				blockSize = value.(int64)
 			}
 			// Ensure block count does not exceed some sane value.
 			if blockCount > MaxBlockCount {
-				return nil, nil, fmt.Errorf("cannot decode binary map when block count exceeds MaxBlockCount: %d > %d", blockCount, MaxBlockCount)
+				return nil, nil, decodeError(option, ErrInvalidBinary, "cannot decode binary map when block count exceeds MaxBlockCount: %d > %d", blockCount, MaxBlockCount)
 			}
 			// NOTE: While the attempt of a RAM optimization shown below is not
 			// necessary, many encoders will encode all items in a single block.
 			// We can optimize amount of RAM allocated by runtime for the array
-			// by initializing the array for that number of items.
-			mapValues := make(map[string]interface{}, blockCount)
+			// by initializing the array for that number of items. When the
+			// blocks have sizes, the number of items in all blocks is known.
+			itemCount := blockCountHint(blockCount, blockSize, buf)
+			mapValues := make(map[string]interface{}, itemCount)
+			var items OrderedMap
+			if option.OrderedMapDecoding {
+				items = make(OrderedMap, 0, itemCount)
+			}
 
 			for blockCount != 0 {
 				// Decode `blockCount` datum values from buffer
 				for i := int64(0); i < blockCount; i++ {
 					// first decode the key string
-					if value, buf, err = stringNativeFromBinary(buf); err != nil {
-						return nil, nil, fmt.Errorf("cannot decode binary map key: %s", err)
+					if value, buf, err = keyNativeFromBinary(buf); err != nil {
+						return nil, nil, decodeError(option, err, "cannot decode binary map key: %s", err)
 					}
 					key := value.(string) // string decoder always returns a string
 					if _, ok := mapValues[key]; ok {
-						return nil, nil, fmt.Errorf("cannot decode binary map: duplicate key: %q", key)
+						return nil, nil, decodeError(option, ErrInvalidBinary, "cannot decode binary map: duplicate key: %q", key)
 					}
 					// then decode the value
 					if value, buf, err = valueCodec.nativeFromBinary(buf); err != nil {
-						return nil, nil, fmt.Errorf("cannot decode binary map value for key %q: %s", key, err)
+						return nil, nil, decodeError(option, err, "cannot decode binary map value for key %q: %s", key, err)
+					}
+					if option.OrderedMapDecoding {
+						// only the presence of key is required to detect
+						// duplicates
+						mapValues[key] = nil
+						items = append(items, MapItem{Key: key, Value: value})
+						continue
 					}
 					mapValues[key] = value
 				}
 				// Decode next blockCount from buffer, because there may be more blocks
 				if value, buf, err = longNativeFromBinary(buf); err != nil {
-					return nil, nil, fmt.Errorf("cannot decode binary map block count: %s", err)
+					return nil, nil, decodeError(option, err, "cannot decode binary map block count: %s", err)
 				}
 				blockCount = value.(int64)
 				if blockCount < 0 {
@@ -94,38 +113,39 @@ func makeMapCodec(st map[string]*Codec, namespace string, schemaMap map[string]i
 					if blockCount == math.MinInt64 {
 						// The minimum number for any signed numerical type can
 						// never be made positive
-						return nil, nil, fmt.Errorf("cannot decode binary map with block count: %d", blockCount)
+						return nil, nil, decodeError(option, ErrInvalidBinary, "cannot decode binary map with block count: %d", blockCount)
 					}
 					blockCount = -blockCount // convert to its positive equivalent
 					if _, buf, err = longNativeFromBinary(buf); err != nil {
-						return nil, nil, fmt.Errorf("cannot decode binary map block size: %s", err)
+						return nil, nil, decodeError(option, err, "cannot decode binary map block size: %s", err)
 					}
 				}
 				// Ensure block count does not exceed some sane value.
 				if blockCount > MaxBlockCount {
-					return nil, nil, fmt.Errorf("cannot decode binary map when block count exceeds MaxBlockCount: %d > %d", blockCount, MaxBlockCount)
+					return nil, nil, decodeError(option, ErrInvalidBinary, "cannot decode binary map when block count exceeds MaxBlockCount: %d > %d", blockCount, MaxBlockCount)
 				}
 			}
+			if option.OrderedMapDecoding {
+				return items, buf, nil
+			}
 			return mapValues, buf, nil
 		},
 		binaryFromNative: func(buf []byte, datum interface{}) ([]byte, error) {
+			if items, ok := datum.(OrderedMap); ok {
+				return orderedMapBinaryFromNative(buf, items, valueCodec, option)
+			}
 			mapValues, err := convertMap(datum)
 			if err != nil {
 				return nil, fmt.Errorf("cannot encode binary map: %s", err)
 			}
+			if option.SortedMapEncoding {
+				return orderedMapBinaryFromNative(buf, sortedMapItems(mapValues), valueCodec, option)
+			}
 
-			keyCount := int64(len(mapValues))
-			var alreadyEncoded, remainingInBlock int64
+			blocks := newBlockEncoder(option, len(mapValues))
 
 			for k, v := range mapValues {
-				if remainingInBlock == 0 { // start a new block
-					remainingInBlock = keyCount - alreadyEncoded
-					if remainingInBlock > MaxBlockCount {
-						// limit block count to MacBlockCount
-						remainingInBlock = MaxBlockCount
-					}
-					buf, _ = longBinaryFromNative(buf, remainingInBlock)
-				}
+				buf = blocks.beginItem(buf)
 
 				// only fails when given non string, so elide error checking
 				buf, _ = stringBinaryFromNative(buf, k)
@@ -135,15 +155,27 @@ func makeMapCodec(st map[string]*Codec, namespace string, schemaMap map[string]i
 					return nil, fmt.Errorf("cannot encode binary map value for key %q: %v: %s", k, v, err)
 				}
 
-				remainingInBlock--
-				alreadyEncoded++
+				buf = blocks.endItem(buf)
 			}
-			return longBinaryFromNative(buf, 0) // append tailing 0 block count to signal end of Map
+			return blocks.finish(buf) // append tailing 0 block count to signal end of Map
 		},
 		nativeFromTextual: func(buf []byte) (interface{}, []byte, error) {
+			if option.OrderedMapDecoding {
+				return orderedMapTextDecoder(buf, valueCodec)
+			}
 			return genericMapTextDecoder(buf, valueCodec, nil) // codecFromKey == nil
 		},
 		textualFromNative: func(buf []byte, datum interface{}) ([]byte, error) {
+			if items, ok := datum.(OrderedMap); ok {
+				return orderedMapTextEncoder(buf, items, valueCodec)
+			}
+			if option.SortedMapEncoding {
+				mapValues, err := convertMap(datum)
+				if err != nil {
+					return nil, fmt.Errorf("cannot encode textual map: %s", err)
+				}
+				return orderedMapTextEncoder(buf, sortedMapItems(mapValues), valueCodec)
+			}
 			return genericMapTextEncoder(buf, datum, valueCodec, nil)
 		},
 	}, nil
@@ -156,6 +188,12 @@ func makeMapCodec(st map[string]*Codec, namespace string, schemaMap map[string]i
 // codecFromKey is nil, every map value will be decoded using defaultCodec, if
 // possible.
 func genericMapTextDecoder(buf []byte, defaultCodec *Codec, codecFromKey map[string]*Codec) (map[string]interface{}, []byte, error) {
+	return orderedGenericMapTextDecoder(buf, defaultCodec, codecFromKey, nil)
+}
+
+// orderedGenericMapTextDecoder decodes like genericMapTextDecoder, and if keys
+// is not nil, also appends each key to keys in the order it was decoded.
+func orderedGenericMapTextDecoder(buf []byte, defaultCodec *Codec, codecFromKey map[string]*Codec, keys *[]string) (map[string]interface{}, []byte, error) {
 	var value interface{}
 	var err error
 	var b byte
@@ -208,6 +246,9 @@ func genericMapTextDecoder(buf []byte, defaultCodec *Codec, codecFromKey map[str
 		}
 		// set map value for key
 		mapValues[key] = value
+		if keys != nil {
+			*keys = append(*keys, key)
+		}
 		// either comma or closing curly brace
 		if buf, _ = advanceToNonWhitespace(buf); len(buf) == 0 {
 			return nil, nil, io.ErrShortBuffer
@@ -275,33 +316,3 @@ func genericMapTextEncoder(buf []byte, datum interface{}, defaultCodec *Codec, c
 	}
 	return append(buf, '}'), nil
 }
-
-// convertMap converts datum to map[string]interface{} if possible.
-func convertMap(datum interface{}) (map[string]interface{}, error) {
-	mapValues, ok := datum.(map[string]interface{})
-	if ok {
-		return mapValues, nil
-	}
-	// NOTE: When given a map of any other type, zip values to items as a
-	// convenience to client.
-	v := reflect.ValueOf(datum)
-	if v.Kind() != reflect.Map {
-		return nil, fmt.Errorf("cannot create map[string]interface{}: expected map[string]...; received: %T", datum)
-	}
-	// NOTE: Two better alternatives to the current algorithm are:
-	//   (1) mutate the reflection tuple underneath to convert the
-	//       map[string]int, for example, to map[string]interface{}, with
-	//       O(1) complexity.
-	//   (2) use copy builtin to zip the data items over with O(n) complexity,
-	//       but more efficient than what's below.
-	mapValues = make(map[string]interface{}, v.Len())
-	for _, key := range v.MapKeys() {
-		k, ok := key.Interface().(string)
-		if !ok {
-			// bail when map key type is not string
-			return nil, fmt.Errorf("cannot create map[string]interface{}: expected map[string]...; received: %T", datum)
-		}
-		mapValues[string(k)] = v.MapIndex(key).Interface()
-	}
-	return mapValues, nil
-}
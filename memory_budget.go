@@ -0,0 +1,145 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"fmt"
+	"sync"
+)
+
+// MemoryBudget bounds the approximate number of bytes of data held at once by
+// the OCFReaders and Decoders sharing it, so in a service reading many streams,
+// one huge stream cannot starve the others. An OCFReader holds the bytes of
+// its decompressed block until it has read every datum of the block, and a
+// Decoder holds the bytes of the datum it decoded last until it decodes the
+// next one. When holding more bytes would exceed the limit, the reader either
+// waits for other readers to release theirs, which applies back-pressure to
+// its stream, or fails, as specified by the policy. Readers abandoned before
+// the end of their stream ought to call their ReleaseMemory method. A
+// MemoryBudget is safe for concurrent use.
+//
+//     budget, err := goavro.NewMemoryBudget(256<<20, goavro.MemoryBudgetBlock)
+//     if err != nil {
+//         return err
+//     }
+//     for _, r := range streams {
+//         go func(r io.Reader) {
+//             ocfr, err := goavro.NewOCFReaderWithConfig(r, goavro.OCFReaderConfig{MemoryBudget: budget})
+//             // ...
+//         }(r)
+//     }
+type MemoryBudget struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	limit  int64
+	used   int64
+	policy MemoryBudgetPolicy
+}
+
+// MemoryBudgetPolicy specifies what a reader does when holding more bytes
+// would exceed its MemoryBudget.
+type MemoryBudgetPolicy int
+
+const (
+	// MemoryBudgetBlock waits until enough bytes are released by other
+	// readers sharing the budget.
+	MemoryBudgetBlock MemoryBudgetPolicy = iota
+
+	// MemoryBudgetError fails with an error, leaving the reader unusable.
+	MemoryBudgetError
+)
+
+// NewMemoryBudget returns a MemoryBudget of limit bytes, which ought to be
+// positive, applying the policy when it would be exceeded.
+func NewMemoryBudget(limit int64, policy MemoryBudgetPolicy) (*MemoryBudget, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("cannot create memory budget: limit ought to be positive: %d", limit)
+	}
+	switch policy {
+	case MemoryBudgetBlock, MemoryBudgetError:
+	default:
+		return nil, fmt.Errorf("cannot create memory budget: unknown policy: %d", policy)
+	}
+	b := &MemoryBudget{limit: limit, policy: policy}
+	b.cond = sync.NewCond(&b.mu)
+	return b, nil
+}
+
+// Limit returns the number of bytes of the budget.
+func (b *MemoryBudget) Limit() int64 {
+	return b.limit
+}
+
+// Used returns the number of bytes held by the readers sharing the budget.
+func (b *MemoryBudget) Used() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.used
+}
+
+// acquire holds size more bytes of the budget, waiting for them to be
+// released when the policy is MemoryBudgetBlock. A size exceeding the limit of
+// the budget can never be held, and fails regardless of the policy.
+func (b *MemoryBudget) acquire(size int64) error {
+	if size > b.limit {
+		return fmt.Errorf("memory budget exceeded: %d > %d", size, b.limit)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for b.used+size > b.limit {
+		if b.policy == MemoryBudgetError {
+			return fmt.Errorf("memory budget exceeded: %d + %d > %d", b.used, size, b.limit)
+		}
+		b.cond.Wait()
+	}
+	b.used += size
+	return nil
+}
+
+// release returns size bytes to the budget, waking the readers waiting for
+// them.
+func (b *MemoryBudget) release(size int64) {
+	if size == 0 {
+		return
+	}
+	b.mu.Lock()
+	b.used -= size
+	b.mu.Unlock()
+	b.cond.Broadcast()
+}
+
+// memoryAccount tracks the bytes of a MemoryBudget held by one reader. The zero
+// value, without a budget, only tracks them.
+type memoryAccount struct {
+	budget *MemoryBudget
+	held   int64
+}
+
+// hold replaces the bytes held by the reader with size bytes, releasing the
+// bytes it held before acquiring the new ones, so a reader never waits for
+// bytes it holds itself.
+func (a *memoryAccount) hold(size int64) error {
+	a.releaseAll()
+	if a.budget != nil {
+		if err := a.budget.acquire(size); err != nil {
+			return err
+		}
+	}
+	a.held = size
+	return nil
+}
+
+// releaseAll releases the bytes held by the reader.
+func (a *memoryAccount) releaseAll() {
+	if a.budget != nil {
+		a.budget.release(a.held)
+	}
+	a.held = 0
+}
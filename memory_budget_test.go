@@ -0,0 +1,57 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestMemoryBudgetDecoder(t *testing.T) {
+	budget, err := NewMemoryBudget(6, MemoryBudgetError)
+	ensureError(t, err)
+	codec := newCodecUsingV2(t, `"string"`)
+
+	// "abcd", "ef", and "ghijklm"
+	decoder := NewDecoderWithConfig(codec, bytes.NewReader([]byte("\x08abcd\x04ef\x0eghijklm")), DecoderConfig{MemoryBudget: budget})
+	_, err = decoder.Decode()
+	ensureError(t, err)
+	if actual, expected := decoder.MemoryUsage(), int64(5); actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	_, err = decoder.Decode()
+	ensureError(t, err)
+	if actual, expected := budget.Used(), int64(3); actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	_, err = decoder.Decode()
+	ensureError(t, err, "cannot decode datum 2: memory budget exceeded: 8 > 6")
+	if actual, expected := budget.Used(), int64(0); actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	decoder = NewDecoderWithConfig(codec, bytes.NewReader([]byte("\x08abcd")), DecoderConfig{MemoryBudget: budget})
+	_, err = decoder.Decode()
+	ensureError(t, err)
+	if _, err = decoder.Decode(); err != io.EOF {
+		t.Errorf("GOT: %v; WANT: %v", err, io.EOF)
+	}
+	if actual, expected := budget.Used(), int64(0); actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
+
+func TestNewMemoryBudgetInvalid(t *testing.T) {
+	_, err := NewMemoryBudget(0, MemoryBudgetBlock)
+	ensureError(t, err, "limit ought to be positive: 0")
+	_, err = NewMemoryBudget(1, MemoryBudgetPolicy(7))
+	ensureError(t, err, "unknown policy: 7")
+}
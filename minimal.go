@@ -0,0 +1,123 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build goavro_minimal
+// +build goavro_minimal
+
+package goavro
+
+// The goavro_minimal build tag selects a reduced profile of the package, for
+// targets such as TinyGo and WebAssembly, where binary size matters and the
+// reflect package is only partially supported. The profile provides codecs,
+// with their options, but omits Object Container Files and plugin
+// registration. Codecs encode arrays and maps only from the slice and map
+// types supported by convertArray and convertMap below, rather than from any
+// slice or map with string keys.
+
+import "fmt"
+
+// addRegisteredLogicalTypes does nothing, because the minimal profile has no
+// plugin registration.
+func addRegisteredLogicalTypes(_ map[string]*Codec) {}
+
+// convertArray converts interface{} to []interface{} if possible, without
+// using reflection.
+func convertArray(datum interface{}) ([]interface{}, error) {
+	var arrayValues []interface{}
+	switch v := datum.(type) {
+	case []interface{}:
+		return v, nil
+	case []map[string]interface{}:
+		arrayValues = make([]interface{}, len(v))
+		for i, item := range v {
+			arrayValues[i] = item
+		}
+	case []string:
+		arrayValues = make([]interface{}, len(v))
+		for i, item := range v {
+			arrayValues[i] = item
+		}
+	case [][]byte:
+		arrayValues = make([]interface{}, len(v))
+		for i, item := range v {
+			arrayValues[i] = item
+		}
+	case []bool:
+		arrayValues = make([]interface{}, len(v))
+		for i, item := range v {
+			arrayValues[i] = item
+		}
+	case []int:
+		arrayValues = make([]interface{}, len(v))
+		for i, item := range v {
+			arrayValues[i] = item
+		}
+	case []int32:
+		arrayValues = make([]interface{}, len(v))
+		for i, item := range v {
+			arrayValues[i] = item
+		}
+	case []int64:
+		arrayValues = make([]interface{}, len(v))
+		for i, item := range v {
+			arrayValues[i] = item
+		}
+	case []float32:
+		arrayValues = make([]interface{}, len(v))
+		for i, item := range v {
+			arrayValues[i] = item
+		}
+	case []float64:
+		arrayValues = make([]interface{}, len(v))
+		for i, item := range v {
+			arrayValues[i] = item
+		}
+	default:
+		return nil, fmt.Errorf("cannot create []interface{}: expected slice; received: %T", datum)
+	}
+	return arrayValues, nil
+}
+
+// convertMap converts datum to map[string]interface{} if possible, without
+// using reflection.
+func convertMap(datum interface{}) (map[string]interface{}, error) {
+	var mapValues map[string]interface{}
+	switch v := datum.(type) {
+	case map[string]interface{}:
+		return v, nil
+	case map[string]string:
+		mapValues = make(map[string]interface{}, len(v))
+		for k, value := range v {
+			mapValues[k] = value
+		}
+	case map[string]int:
+		mapValues = make(map[string]interface{}, len(v))
+		for k, value := range v {
+			mapValues[k] = value
+		}
+	case map[string]int64:
+		mapValues = make(map[string]interface{}, len(v))
+		for k, value := range v {
+			mapValues[k] = value
+		}
+	case map[string]float64:
+		mapValues = make(map[string]interface{}, len(v))
+		for k, value := range v {
+			mapValues[k] = value
+		}
+	case map[string]bool:
+		mapValues = make(map[string]interface{}, len(v))
+		for k, value := range v {
+			mapValues[k] = value
+		}
+	default:
+		return nil, fmt.Errorf("cannot create map[string]interface{}: expected map[string]...; received: %T", datum)
+	}
+	return mapValues, nil
+}
@@ -0,0 +1,297 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+////////////////////////////////////////
+// MessagePack Encode
+////////////////////////////////////////
+
+// appendMessagePack appends the MessagePack encoding of a plain value to buf.
+func appendMessagePack(buf []byte, value interface{}) []byte {
+	switch v := value.(type) {
+	case nil:
+		return append(buf, 0xc0)
+	case bool:
+		if v {
+			return append(buf, 0xc3)
+		}
+		return append(buf, 0xc2)
+	case int64:
+		return appendMessagePackInt(buf, v)
+	case float32:
+		buf = append(buf, 0xca, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(buf[len(buf)-4:], math.Float32bits(v))
+		return buf
+	case float64:
+		buf = append(buf, 0xcb, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(buf[len(buf)-8:], math.Float64bits(v))
+		return buf
+	case string:
+		buf = appendMessagePackHeader(buf, len(v), 0xa0, 32, 0xd9, 0xda, 0xdb)
+		return append(buf, v...)
+	case []byte:
+		buf = appendMessagePackHeader(buf, len(v), 0, 0, 0xc4, 0xc5, 0xc6)
+		return append(buf, v...)
+	case []interface{}:
+		buf = appendMessagePackHeader(buf, len(v), 0x90, 16, 0, 0xdc, 0xdd)
+		for _, item := range v {
+			buf = appendMessagePack(buf, item)
+		}
+		return buf
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		buf = appendMessagePackHeader(buf, len(keys), 0x80, 16, 0, 0xde, 0xdf)
+		for _, k := range keys {
+			buf = appendMessagePack(buf, k)
+			buf = appendMessagePack(buf, v[k])
+		}
+		return buf
+	case *plainRecord:
+		buf = appendMessagePackHeader(buf, len(v.names), 0x80, 16, 0, 0xde, 0xdf)
+		for i, k := range v.names {
+			buf = appendMessagePack(buf, k)
+			buf = appendMessagePack(buf, v.values[i])
+		}
+		return buf
+	default:
+		// NOTE: plain values are only ever created by plainFromBinary
+		panic(fmt.Errorf("should not get here: cannot encode MessagePack: unexpected type: %T", value))
+	}
+}
+
+func appendMessagePackInt(buf []byte, v int64) []byte {
+	switch {
+	case v >= 0 && v < 128:
+		return append(buf, byte(v))
+	case v >= -32 && v < 0:
+		return append(buf, byte(v))
+	case v >= 0 && v <= math.MaxUint8:
+		return append(buf, 0xcc, byte(v))
+	case v >= 0 && v <= math.MaxUint16:
+		return append(buf, 0xcd, byte(v>>8), byte(v))
+	case v >= 0 && v <= math.MaxUint32:
+		buf = append(buf, 0xce, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(buf[len(buf)-4:], uint32(v))
+		return buf
+	case v >= 0:
+		buf = append(buf, 0xcf, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(buf[len(buf)-8:], uint64(v))
+		return buf
+	case v >= math.MinInt8:
+		return append(buf, 0xd0, byte(v))
+	case v >= math.MinInt16:
+		return append(buf, 0xd1, byte(v>>8), byte(v))
+	case v >= math.MinInt32:
+		buf = append(buf, 0xd2, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(buf[len(buf)-4:], uint32(v))
+		return buf
+	default:
+		buf = append(buf, 0xd3, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(buf[len(buf)-8:], uint64(v))
+		return buf
+	}
+}
+
+// appendMessagePackHeader appends the header of a string, binary, array, or
+// map value of the provided length. When fixMax is non-zero, lengths below it
+// use the fix prefix. When prefix8 is zero, the format has no 8-bit length
+// variant.
+func appendMessagePackHeader(buf []byte, length int, fixPrefix byte, fixMax int, prefix8, prefix16, prefix32 byte) []byte {
+	switch {
+	case length < fixMax:
+		return append(buf, fixPrefix|byte(length))
+	case prefix8 != 0 && length <= math.MaxUint8:
+		return append(buf, prefix8, byte(length))
+	case length <= math.MaxUint16:
+		return append(buf, prefix16, byte(length>>8), byte(length))
+	default:
+		buf = append(buf, prefix32, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(buf[len(buf)-4:], uint32(length))
+		return buf
+	}
+}
+
+////////////////////////////////////////
+// MessagePack Decode
+////////////////////////////////////////
+
+// plainFromMessagePack decodes one MessagePack value from buf to its plain
+// representation. Extension types are not supported.
+func plainFromMessagePack(buf []byte) (interface{}, []byte, error) {
+	if len(buf) < 1 {
+		return nil, nil, fmt.Errorf("cannot decode MessagePack: %s", io.ErrShortBuffer)
+	}
+	b, buf := buf[0], buf[1:]
+
+	switch {
+	case b <= 0x7f:
+		return int64(b), buf, nil
+	case b >= 0xe0:
+		return int64(int8(b)), buf, nil
+	case b&0xf0 == 0x80:
+		return plainMapFromMessagePack(buf, int(b&0x0f))
+	case b&0xf0 == 0x90:
+		return plainArrayFromMessagePack(buf, int(b&0x0f))
+	case b&0xe0 == 0xa0:
+		return plainStringFromMessagePack(buf, int(b&0x1f))
+	}
+
+	switch b {
+	case 0xc0:
+		return nil, buf, nil
+	case 0xc2:
+		return false, buf, nil
+	case 0xc3:
+		return true, buf, nil
+	case 0xc4, 0xc5, 0xc6:
+		length, buf, err := messagePackLength(buf, 1<<(b-0xc4))
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(buf) < length {
+			return nil, nil, fmt.Errorf("cannot decode MessagePack bin: %s", io.ErrShortBuffer)
+		}
+		return buf[:length], buf[length:], nil
+	case 0xca:
+		if len(buf) < 4 {
+			return nil, nil, fmt.Errorf("cannot decode MessagePack float32: %s", io.ErrShortBuffer)
+		}
+		return math.Float32frombits(binary.BigEndian.Uint32(buf)), buf[4:], nil
+	case 0xcb:
+		if len(buf) < 8 {
+			return nil, nil, fmt.Errorf("cannot decode MessagePack float64: %s", io.ErrShortBuffer)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(buf)), buf[8:], nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		size := 1 << (b - 0xcc)
+		if len(buf) < size {
+			return nil, nil, fmt.Errorf("cannot decode MessagePack uint: %s", io.ErrShortBuffer)
+		}
+		v := messagePackUint(buf[:size])
+		if v <= math.MaxInt64 {
+			return int64(v), buf[size:], nil
+		}
+		return v, buf[size:], nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (b - 0xd0)
+		if len(buf) < size {
+			return nil, nil, fmt.Errorf("cannot decode MessagePack int: %s", io.ErrShortBuffer)
+		}
+		v := messagePackUint(buf[:size])
+		shift := uint(64 - 8*size)
+		return int64(v<<shift) >> shift, buf[size:], nil // sign extend
+	case 0xd9, 0xda, 0xdb:
+		length, buf, err := messagePackLength(buf, 1<<(b-0xd9))
+		if err != nil {
+			return nil, nil, err
+		}
+		return plainStringFromMessagePack(buf, length)
+	case 0xdc, 0xdd:
+		length, buf, err := messagePackLength(buf, 2<<(b-0xdc))
+		if err != nil {
+			return nil, nil, err
+		}
+		return plainArrayFromMessagePack(buf, length)
+	case 0xde, 0xdf:
+		length, buf, err := messagePackLength(buf, 2<<(b-0xde))
+		if err != nil {
+			return nil, nil, err
+		}
+		return plainMapFromMessagePack(buf, length)
+	}
+	return nil, nil, fmt.Errorf("cannot decode MessagePack: unsupported format: 0x%02x", b)
+}
+
+func messagePackUint(buf []byte) uint64 {
+	var v uint64
+	for _, b := range buf {
+		v = v<<8 | uint64(b)
+	}
+	return v
+}
+
+// messagePackLength reads a big-endian length of size bytes from buf.
+func messagePackLength(buf []byte, size int) (int, []byte, error) {
+	if len(buf) < size {
+		return 0, nil, fmt.Errorf("cannot decode MessagePack length: %s", io.ErrShortBuffer)
+	}
+	length := messagePackUint(buf[:size])
+	if length > uint64(MaxBlockSize) {
+		return 0, nil, fmt.Errorf("cannot decode MessagePack when length exceeds MaxBlockSize: %d > %d", length, MaxBlockSize)
+	}
+	return int(length), buf[size:], nil
+}
+
+func plainStringFromMessagePack(buf []byte, length int) (interface{}, []byte, error) {
+	if len(buf) < length {
+		return nil, nil, fmt.Errorf("cannot decode MessagePack str: %s", io.ErrShortBuffer)
+	}
+	return string(buf[:length]), buf[length:], nil
+}
+
+func plainArrayFromMessagePack(buf []byte, length int) (interface{}, []byte, error) {
+	if int64(length) > MaxBlockCount {
+		return nil, nil, fmt.Errorf("cannot decode MessagePack array when length exceeds MaxBlockCount: %d > %d", length, MaxBlockCount)
+	}
+	items := make([]interface{}, 0, minInt(length, len(buf)))
+	for i := 0; i < length; i++ {
+		var item interface{}
+		var err error
+		if item, buf, err = plainFromMessagePack(buf); err != nil {
+			return nil, nil, fmt.Errorf("cannot decode MessagePack array item %d: %s", i+1, err)
+		}
+		items = append(items, item)
+	}
+	return items, buf, nil
+}
+
+func plainMapFromMessagePack(buf []byte, length int) (interface{}, []byte, error) {
+	if int64(length) > MaxBlockCount {
+		return nil, nil, fmt.Errorf("cannot decode MessagePack map when length exceeds MaxBlockCount: %d > %d", length, MaxBlockCount)
+	}
+	values := make(map[string]interface{}, minInt(length, len(buf)))
+	for i := 0; i < length; i++ {
+		var key, value interface{}
+		var err error
+		if key, buf, err = plainFromMessagePack(buf); err != nil {
+			return nil, nil, fmt.Errorf("cannot decode MessagePack map key: %s", err)
+		}
+		k, ok := key.(string)
+		if !ok {
+			return nil, nil, fmt.Errorf("cannot decode MessagePack map key: expected string; received: %T", key)
+		}
+		if value, buf, err = plainFromMessagePack(buf); err != nil {
+			return nil, nil, fmt.Errorf("cannot decode MessagePack map value for key %q: %s", k, err)
+		}
+		values[k] = value
+	}
+	return values, buf, nil
+}
+
+// minInt returns the smaller of its arguments, and is used to bound
+// allocations by the number of bytes actually available.
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
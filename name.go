@@ -124,6 +124,11 @@ func newNameFromSchemaMap(enclosingNamespace string, schemaMap map[string]interf
 		if !ok {
 			return nil, fmt.Errorf("schema namespace, if provided, ought to be a string; received: %T: %v", namespace, namespace)
 		}
+		if namespaceString == nullNamespace {
+			// NOTE: An empty namespace is the null namespace, so the type
+			// does not inherit the enclosing namespace.
+			enclosingNamespace = nullNamespace
+		}
 	}
 
 	return newName(nameString, namespaceString, enclosingNamespace)
@@ -0,0 +1,237 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	cryptorand "crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+)
+
+// NoiseAnnotation is the name of the field annotation that selects the fields
+// perturbed by the hooks returned by NoiseHooks, and describes the noise added
+// to them.
+const NoiseAnnotation = "x-noise"
+
+// NoiseConfig is used to specify the parameters of NoiseHooks.
+type NoiseConfig struct {
+	// Source provides the random numbers of the noise, (optional). When nil,
+	// the random numbers are read from crypto/rand, as noise that may be
+	// predicted does not protect the data. Tests may provide a seeded
+	// source to perturb data reproducibly.
+	Source rand.Source
+}
+
+// NoiseHooks returns FieldHooks that add random noise to the numeric fields
+// annotated with NoiseAnnotation as they are encoded, such as to produce a
+// shareable copy of a dataset whose aggregates remain useful while individual
+// values are obscured. The noise is drawn from the Laplace or Gaussian
+// distribution named by the annotation, whose scale is either given directly,
+// or derived from the privacy budget epsilon, the sensitivity of the field,
+// and for Gaussian noise, delta:
+//
+//     {"name": "salary", "type": "long",
+//      "x-noise": {"mechanism": "laplace", "epsilon": 0.5, "sensitivity": 1000, "min": 0}}
+//     {"name": "age", "type": ["null", "int"],
+//      "x-noise": {"mechanism": "gaussian", "sigma": 2, "min": 0, "max": 150}}
+//
+// Laplace noise has the scale sensitivity/epsilon, and Gaussian noise has the
+// standard deviation sensitivity*sqrt(2*ln(1.25/delta))/epsilon. Perturbed
+// values keep the type of the value, so int and long values are rounded to
+// the nearest integer, and all values are clamped to the optional min and max
+// of the annotation, and to the range of their type, keeping the output valid
+// for the schema. Data is perturbed while it is re-encoded by a Codec using
+// the hooks:
+//
+//     noisy, err := codec.WithOptions(goavro.WithFieldHooks(goavro.NoiseHooks(goavro.NoiseConfig{})))
+//     if err != nil {
+//         return err
+//     }
+//     datum, _, err := codec.NativeFromBinary(buf)
+//     if err != nil {
+//         return err
+//     }
+//     shareable, err := noisy.BinaryFromNative(nil, datum)
+//
+// The noise is not removed when decoding. The hooks may be used by multiple
+// goroutines simultaneously.
+func NoiseHooks(config NoiseConfig) *FieldHooks {
+	source := config.Source
+	if source == nil {
+		source = cryptoSource{}
+	}
+	n := &noiseHook{rng: rand.New(source)}
+	return &FieldHooks{Annotations: map[string]FieldHook{NoiseAnnotation: {Encode: n.encode}}}
+}
+
+// noiseHook is the encode hook returned by NoiseHooks.
+type noiseHook struct {
+	mu  sync.Mutex // protects rng, which is not safe for concurrent use
+	rng *rand.Rand
+}
+
+// noiseParams describes the noise of one annotated field.
+type noiseParams struct {
+	gaussian bool
+	scale    float64 // scale of Laplace noise, or standard deviation of Gaussian noise
+	min, max float64
+}
+
+func newNoiseParams(annotation interface{}) (noiseParams, error) {
+	p := noiseParams{min: math.Inf(-1), max: math.Inf(1)}
+	m, ok := annotation.(map[string]interface{})
+	if !ok {
+		return p, fmt.Errorf("%s ought to be an object; received: %T", NoiseAnnotation, annotation)
+	}
+	numbers := make(map[string]float64)
+	for _, key := range []string{"scale", "sigma", "epsilon", "delta", "sensitivity", "min", "max"} {
+		value, ok := m[key]
+		if !ok {
+			continue
+		}
+		f, ok := value.(float64)
+		if !ok {
+			return p, fmt.Errorf("%s %s ought to be a number; received: %T", NoiseAnnotation, key, value)
+		}
+		numbers[key] = f
+	}
+	if v, ok := numbers["min"]; ok {
+		p.min = v
+	}
+	if v, ok := numbers["max"]; ok {
+		p.max = v
+	}
+	if p.min > p.max {
+		return p, fmt.Errorf("%s min ought to be at most max: %g > %g", NoiseAnnotation, p.min, p.max)
+	}
+
+	mechanism, _ := m["mechanism"].(string)
+	direct := "scale"
+	switch mechanism {
+	case "laplace":
+	case "gaussian":
+		p.gaussian, direct = true, "sigma"
+	default:
+		return p, fmt.Errorf("%s mechanism ought to be \"laplace\" or \"gaussian\"; received: %v", NoiseAnnotation, m["mechanism"])
+	}
+	if scale, ok := numbers[direct]; ok {
+		if !(scale > 0) {
+			return p, fmt.Errorf("%s %s ought to be greater than 0: %g", NoiseAnnotation, direct, scale)
+		}
+		p.scale = scale
+		return p, nil
+	}
+
+	epsilon, sensitivity := numbers["epsilon"], numbers["sensitivity"]
+	if !(epsilon > 0) || !(sensitivity > 0) {
+		return p, fmt.Errorf("%s ought to have a %s, or an epsilon and sensitivity greater than 0", NoiseAnnotation, direct)
+	}
+	p.scale = sensitivity / epsilon
+	if p.gaussian {
+		delta := numbers["delta"]
+		if !(delta > 0 && delta < 1) {
+			return p, fmt.Errorf("%s delta ought to be greater than 0 and less than 1: %g", NoiseAnnotation, delta)
+		}
+		p.scale *= math.Sqrt(2 * math.Log(1.25/delta))
+	}
+	return p, nil
+}
+
+func (n *noiseHook) encode(field FieldHookInfo, value interface{}) (interface{}, error) {
+	p, err := newNoiseParams(field.Value)
+	if err != nil {
+		return nil, err
+	}
+	return n.perturb(p, value)
+}
+
+// perturb returns the value with noise added, of the same type as the value.
+func (n *noiseHook) perturb(p noiseParams, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 1 {
+			for name, member := range v {
+				if member == nil {
+					return value, nil
+				}
+				perturbed, err := n.perturb(p, member)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{name: perturbed}, nil
+			}
+		}
+	case float64:
+		return p.clamp(v+n.sample(p), -math.MaxFloat64, math.MaxFloat64), nil
+	case float32:
+		return float32(p.clamp(float64(v)+n.sample(p), -math.MaxFloat32, math.MaxFloat32)), nil
+	case int32:
+		return int32(p.clamp(math.Round(float64(v)+n.sample(p)), math.MinInt32, math.MaxInt32)), nil
+	case int64:
+		return noiseInt64(p.clamp(math.Round(float64(v)+n.sample(p)), math.MinInt64, math.MaxInt64)), nil
+	case int:
+		return int(noiseInt64(p.clamp(math.Round(float64(v)+n.sample(p)), math.MinInt64, math.MaxInt64))), nil
+	}
+	return nil, fmt.Errorf("%s requires a numeric value; received: %T", NoiseAnnotation, value)
+}
+
+// sample returns a random number from the distribution of the noise.
+func (n *noiseHook) sample(p noiseParams) float64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if p.gaussian {
+		return n.rng.NormFloat64() * p.scale
+	}
+	// NOTE: Inverse transform of a uniform number in (-0.5, 0.5), excluding
+	// -0.5, whose transform is infinite.
+	f := n.rng.Float64()
+	for f == 0 {
+		f = n.rng.Float64()
+	}
+	u := f - 0.5
+	if u < 0 {
+		return p.scale * math.Log(1+2*u)
+	}
+	return -p.scale * math.Log(1-2*u)
+}
+
+// clamp returns f limited to the bounds of the annotation and of its type.
+func (p noiseParams) clamp(f, lower, upper float64) float64 {
+	return math.Max(math.Max(lower, p.min), math.Min(math.Min(upper, p.max), f))
+}
+
+// noiseInt64 converts a float64 in the range of int64 to int64, whose maximum
+// is not exactly representable as a float64.
+func noiseInt64(f float64) int64 {
+	if f >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return int64(f)
+}
+
+// cryptoSource is a rand.Source reading from crypto/rand.
+type cryptoSource struct{}
+
+func (cryptoSource) Int63() int64 {
+	return int64(cryptoSource{}.Uint64() &^ (1 << 63))
+}
+
+func (cryptoSource) Uint64() uint64 {
+	var buf [8]byte
+	if _, err := cryptorand.Read(buf[:]); err != nil {
+		panic(fmt.Errorf("should not get here: cannot read random numbers: %s", err))
+	}
+	return binary.LittleEndian.Uint64(buf[:])
+}
+
+func (cryptoSource) Seed(int64) {}
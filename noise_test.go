@@ -0,0 +1,118 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+const noiseSchema = `{
+  "type": "record",
+  "name": "Employee",
+  "fields": [
+    {"name": "name", "type": "string"},
+    {"name": "salary", "type": "long",
+     "x-noise": {"mechanism": "laplace", "epsilon": 0.5, "sensitivity": 1000, "min": 0}},
+    {"name": "age", "type": ["null", "int"],
+     "x-noise": {"mechanism": "gaussian", "sigma": 2, "min": 0, "max": 150}},
+    {"name": "rating", "type": "float",
+     "x-noise": {"mechanism": "gaussian", "epsilon": 0.5, "delta": 0.00001, "sensitivity": 0.1}}
+  ]
+}`
+
+func TestNoiseHooks(t *testing.T) {
+	codec, err := NewCodec(noiseSchema)
+	ensureError(t, err)
+	noisy, err := codec.WithOptions(WithFieldHooks(NoiseHooks(NoiseConfig{Source: rand.NewSource(1)})))
+	ensureError(t, err)
+
+	const n = 2000
+	var salaries, ages float64
+	var zeros int
+	for i := 0; i < n; i++ {
+		buf, err := codec.BinaryFromNative(nil, map[string]interface{}{
+			"name":   "Alice",
+			"salary": int64(50000),
+			"age":    Union("int", int32(1)),
+			"rating": float32(4.5),
+		})
+		ensureError(t, err)
+		datum, _, err := codec.NativeFromBinary(buf)
+		ensureError(t, err)
+
+		// re-encode with noise, and decode using the schema
+		buf, err = noisy.BinaryFromNative(nil, datum)
+		ensureError(t, err)
+		datum, _, err = codec.NativeFromBinary(buf)
+		ensureError(t, err)
+		record := datum.(map[string]interface{})
+		if actual, expected := record["name"], "Alice"; actual != expected {
+			t.Fatalf("GOT: %v; WANT: %v", actual, expected)
+		}
+		salaries += float64(record["salary"].(int64))
+		age := record["age"].(map[string]interface{})["int"].(int32)
+		if age < 0 || age > 150 {
+			t.Fatalf("GOT: %v; WANT: from 0 to 150", age)
+		}
+		if age == 0 {
+			zeros++
+		}
+		ages += float64(age)
+		if rating := record["rating"].(float32); rating == 4.5 {
+			t.Fatalf("GOT: %v; WANT: perturbed rating", rating)
+		}
+	}
+	// Laplace noise with scale 2000 has a standard deviation of about 2828,
+	// so the mean of 2000 samples is within 200 of the value.
+	if mean := salaries / n; math.Abs(mean-50000) > 200 {
+		t.Errorf("GOT: %v; WANT: about 50000", mean)
+	}
+	// Ages below zero are clamped to zero.
+	if zeros < n/4 {
+		t.Errorf("GOT: %v; WANT: at least %v", zeros, n/4)
+	}
+
+	// null values are not perturbed
+	buf, err := noisy.BinaryFromNative(nil, map[string]interface{}{"name": "Bob", "salary": int64(1), "age": nil, "rating": float32(1)})
+	ensureError(t, err)
+	datum, _, err := codec.NativeFromBinary(buf)
+	ensureError(t, err)
+	if actual := datum.(map[string]interface{})["age"]; actual != nil {
+		t.Errorf("GOT: %v; WANT: %v", actual, nil)
+	}
+}
+
+func TestNoiseHooksErrors(t *testing.T) {
+	for _, tc := range []struct {
+		annotation, fieldType, message string
+	}{
+		{`"laplace"`, `"int"`, "x-noise ought to be an object"},
+		{`{"mechanism": "uniform", "scale": 1}`, `"int"`, `x-noise mechanism ought to be "laplace" or "gaussian"; received: uniform`},
+		{`{"mechanism": "laplace"}`, `"int"`, "x-noise ought to have a scale, or an epsilon and sensitivity greater than 0"},
+		{`{"mechanism": "laplace", "scale": -1}`, `"int"`, "x-noise scale ought to be greater than 0: -1"},
+		{`{"mechanism": "gaussian", "epsilon": 1, "sensitivity": 1}`, `"int"`, "x-noise delta ought to be greater than 0 and less than 1: 0"},
+		{`{"mechanism": "laplace", "scale": "1"}`, `"int"`, "x-noise scale ought to be a number; received: string"},
+		{`{"mechanism": "laplace", "scale": 1, "min": 2, "max": 1}`, `"int"`, "x-noise min ought to be at most max: 2 > 1"},
+		{`{"mechanism": "laplace", "scale": 1}`, `"string"`, "x-noise requires a numeric value; received: string"},
+	} {
+		codec, err := NewCodec(`{"type": "record", "name": "r", "fields": [{"name": "f", "type": ` + tc.fieldType + `, "x-noise": ` + tc.annotation + `}]}`)
+		ensureError(t, err)
+		noisy, err := codec.WithOptions(WithFieldHooks(NoiseHooks(NoiseConfig{})))
+		ensureError(t, err)
+		var value interface{} = 1
+		if tc.fieldType == `"string"` {
+			value = "one"
+		}
+		_, err = noisy.BinaryFromNative(nil, map[string]interface{}{"f": value})
+		ensureError(t, err, `cannot encode binary: field "f": `+tc.message)
+	}
+}
@@ -7,14 +7,24 @@
 // distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
 // WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
 
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
 package goavro
 
 import (
 	"bytes"
+	"compress/flate"
 	"crypto/rand"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
+	"io/ioutil"
+	"sort"
+
+	"github.com/golang/snappy"
 )
 
 const (
@@ -38,6 +48,7 @@ const (
 	compressionNull compressionID = iota
 	compressionDeflate
 	compressionSnappy
+	compressionRegistered // first compressionID of the registered compression algorithms
 )
 
 const (
@@ -72,18 +83,16 @@ func newOCFHeader(config OCFConfig) (*ocfHeader, error) {
 	//
 	// avro.codec
 	//
-	switch config.CompressionName {
-	case "":
+	if config.CompressionName == "" {
 		header.compressionID = compressionNull
-	case CompressionNullLabel:
-		header.compressionID = compressionNull
-	case CompressionDeflateLabel:
-		header.compressionID = compressionDeflate
-	case CompressionSnappyLabel:
-		header.compressionID = compressionSnappy
-	default:
+	} else if cID, ok := lookupCompression(config.CompressionName); ok {
+		header.compressionID = cID
+	} else {
 		return nil, fmt.Errorf("cannot create OCF header using unrecognized compression algorithm: %q", config.CompressionName)
 	}
+	if header.compressionID, err = selectCompressionBackend(header.compressionID, config.CompressionBackend); err != nil {
+		return nil, fmt.Errorf("cannot create OCF header: %s", err)
+	}
 
 	//
 	// avro.schema
@@ -100,6 +109,22 @@ func newOCFHeader(config OCFConfig) (*ocfHeader, error) {
 
 	header.metadata = config.MetaData
 
+	//
+	// goavro.checksum
+	//
+	if config.Checksum != "" {
+		h, err := newOCFChecksum(config.Checksum)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create OCF header: %s", err)
+		}
+		// NOTE: Copy the metadata rather than modify the caller's map.
+		header.metadata = make(map[string][]byte, len(config.MetaData)+1)
+		for k, v := range config.MetaData {
+			header.metadata[k] = v
+		}
+		header.metadata[ocfChecksumKey] = ocfChecksumPlaceholder(config.Checksum, h)
+	}
+
 	//
 	// The 16-byte, randomly-generated sync marker for this file.
 	//
@@ -146,15 +171,8 @@ func readOCFHeader(ior io.Reader) (*ocfHeader, error) {
 	var cID compressionID
 	value, ok := metadata["avro.codec"]
 	if ok {
-		switch avroCodec := string(value); avroCodec {
-		case CompressionNullLabel:
-			cID = compressionNull
-		case CompressionDeflateLabel:
-			cID = compressionDeflate
-		case CompressionSnappyLabel:
-			cID = compressionSnappy
-		default:
-			return nil, fmt.Errorf("cannot read OCF header using unrecognized compression algorithm from avro.codec: %q", avroCodec)
+		if cID, ok = lookupCompression(string(value)); !ok {
+			return nil, fmt.Errorf("cannot read OCF header using unrecognized compression algorithm from avro.codec: %q", value)
 		}
 	}
 
@@ -185,20 +203,14 @@ func readOCFHeader(ior io.Reader) (*ocfHeader, error) {
 	return header, nil
 }
 
-func writeOCFHeader(header *ocfHeader, iow io.Writer) (err error) {
+// writeOCFHeader writes the header, and returns the number of bytes written.
+func writeOCFHeader(header *ocfHeader, iow io.Writer) (n int, err error) {
 	//
 	// avro.codec
 	//
-	var avroCodec string
-	switch header.compressionID {
-	case compressionNull:
-		avroCodec = CompressionNullLabel
-	case compressionDeflate:
-		avroCodec = CompressionDeflateLabel
-	case compressionSnappy:
-		avroCodec = CompressionSnappyLabel
-	default:
-		return fmt.Errorf("should not get here: cannot write OCF header using unrecognized compression algorithm: %d", header.compressionID)
+	avroCodec, ok := compressionName(header.compressionID)
+	if !ok {
+		return 0, fmt.Errorf("should not get here: cannot write OCF header using unrecognized compression algorithm: %d", header.compressionID)
 	}
 
 	//
@@ -214,16 +226,28 @@ func writeOCFHeader(header *ocfHeader, iow io.Writer) (err error) {
 	//
 	// file metadata, including the schema
 	//
-	meta := make(map[string]interface{})
-	for k, v := range header.metadata {
-		meta[k] = v
+	// NOTE: Items are written in the order of their keys, except the
+	// checksum, which is written last, so it may be overwritten in place when
+	// the OCFWriter is closed.
+	keys := make([]string, 0, len(header.metadata)+2)
+	for k := range header.metadata {
+		if k != "avro.schema" && k != "avro.codec" && k != ocfChecksumKey {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	meta := make(OrderedMap, 0, len(keys)+3)
+	for _, k := range keys {
+		meta = append(meta, MapItem{Key: k, Value: header.metadata[k]})
+	}
+	meta = append(meta, MapItem{Key: "avro.schema", Value: []byte(schema)}, MapItem{Key: "avro.codec", Value: []byte(avroCodec)})
+	if checksum, ok := header.metadata[ocfChecksumKey]; ok {
+		meta = append(meta, MapItem{Key: ocfChecksumKey, Value: checksum})
 	}
-	meta["avro.schema"] = []byte(schema)
-	meta["avro.codec"] = []byte(avroCodec)
 
 	buf, err = ocfMetadataCodec.BinaryFromNative(buf, meta)
 	if err != nil {
-		return fmt.Errorf("should not get here: cannot write OCF header: %s", err)
+		return 0, fmt.Errorf("should not get here: cannot write OCF header: %s", err)
 	}
 
 	//
@@ -232,9 +256,101 @@ func writeOCFHeader(header *ocfHeader, iow io.Writer) (err error) {
 	buf = append(buf, header.syncMarker[:]...)
 
 	// emit OCF header
-	_, err = iow.Write(buf)
+	n, err = iow.Write(buf)
 	if err != nil {
-		return fmt.Errorf("cannot write OCF header: %s", err)
+		return n, fmt.Errorf("cannot write OCF header: %s", err)
+	}
+	return n, nil
+}
+
+// compressOCFBlock returns the block compressed using the specified
+// compression algorithm.
+func compressOCFBlock(cID compressionID, block []byte) ([]byte, error) {
+	switch cID {
+	case compressionNull:
+		return block, nil
+
+	case compressionDeflate:
+		// compress into new bytes buffer.
+		bb := bytes.NewBuffer(make([]byte, 0, len(block)))
+
+		cw, _ := flate.NewWriter(bb, flate.DefaultCompression)
+		// writing bytes to cw will compress bytes and send to bb.
+		if _, err := cw.Write(block); err != nil {
+			return nil, err
+		}
+		if err := cw.Close(); err != nil {
+			return nil, err
+		}
+		return bb.Bytes(), nil
+
+	case compressionSnappy:
+		compressed := snappy.Encode(nil, block)
+
+		// OCF requires snappy to have CRC32 checksum after each snappy block
+		compressed = append(compressed, 0, 0, 0, 0)                                           // expand slice by 4 bytes so checksum will fit
+		binary.BigEndian.PutUint32(compressed[len(compressed)-4:], crc32.ChecksumIEEE(block)) // checksum of decompressed block
+		return compressed, nil
+
+	default:
+		compression, ok := registeredCompression(cID)
+		if !ok {
+			return nil, fmt.Errorf("should not get here: cannot compress block using unrecognized compression: %d", cID)
+		}
+		compressed, err := compression.Compress(block)
+		if err != nil {
+			return nil, fmt.Errorf("cannot compress block using %s: %s", compression.Name, err)
+		}
+		return compressed, nil
+	}
+}
+
+// decompressOCFBlock returns the block decompressed using the specified
+// compression algorithm.
+func decompressOCFBlock(cID compressionID, block []byte) ([]byte, error) {
+	switch cID {
+	case compressionNull:
+		return block, nil
+
+	case compressionDeflate:
+		// NOTE: flate.NewReader wraps with io.ByteReader if argument does
+		// not implement that interface.
+		rc := flate.NewReader(bytes.NewBuffer(block))
+		decompressed, err := ioutil.ReadAll(rc)
+		if err != nil {
+			_ = rc.Close()
+			return nil, err
+		}
+		if err = rc.Close(); err != nil {
+			return nil, err
+		}
+		return decompressed, nil
+
+	case compressionSnappy:
+		index := len(block) - 4 // last 4 bytes is crc32 of decoded block
+		if index <= 0 {
+			return nil, fmt.Errorf("cannot decompress snappy without CRC32 checksum: %d", len(block))
+		}
+		decoded, err := snappy.Decode(nil, block[:index])
+		if err != nil {
+			return nil, fmt.Errorf("cannot decompress: %s", err)
+		}
+		actualCRC := crc32.ChecksumIEEE(decoded)
+		expectedCRC := binary.BigEndian.Uint32(block[index : index+4])
+		if actualCRC != expectedCRC {
+			return nil, fmt.Errorf("snappy CRC32 checksum mismatch: %x != %x", actualCRC, expectedCRC)
+		}
+		return decoded, nil
+
+	default:
+		compression, ok := registeredCompression(cID)
+		if !ok {
+			return nil, fmt.Errorf("should not get here: cannot decompress block using unrecognized compression: %d", cID)
+		}
+		decompressed, err := compression.Decompress(block)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decompress using %s: %s", compression.Name, err)
+		}
+		return decompressed, nil
 	}
-	return nil
 }
@@ -0,0 +1,178 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"bytes"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// OCFBlock is a block of an OCF as it is stored, neither decompressed nor
+// decoded.
+type OCFBlock struct {
+	Count      int64    // number of data items in the block
+	Data       []byte   // compressed binary encoding of the data items
+	SyncMarker [16]byte // sync marker following the block
+}
+
+// OCFBlockReader reads the blocks of an OCF without decompressing or decoding
+// them, for tools that operate on whole blocks, such as concatenating OCF
+// files that share a schema and compression algorithm, repartitioning files by
+// block, or transcoding the compression of blocks, which are much faster when
+// the data items are not decoded and encoded again.
+type OCFBlockReader struct {
+	header   *ocfHeader
+	ior      io.Reader
+	block    OCFBlock
+	count    int64 // number of blocks read
+	rerr     error
+	checksum hash.Hash // checksum of the blocks read, when the OCF has one
+	recorded []byte    // checksum recorded in the OCF metadata
+}
+
+// NewOCFBlockReader reads the header of the OCF from ior, and returns an
+// OCFBlockReader that reads its blocks.
+//
+//     obr, err := goavro.NewOCFBlockReader(bufio.NewReader(file))
+//     if err != nil {
+//         return err
+//     }
+//     for obr.Scan() {
+//         block := obr.Block()
+//         fmt.Println(block.Count, len(block.Data))
+//     }
+//     return obr.Err()
+func NewOCFBlockReader(ior io.Reader) (*OCFBlockReader, error) {
+	header, err := readOCFHeader(ior)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create OCFBlockReader: %s", err)
+	}
+	obr := &OCFBlockReader{header: header, ior: ior}
+	if value, ok := header.metadata[ocfChecksumKey]; ok {
+		if obr.checksum, obr.recorded, err = parseOCFChecksum(value); err != nil {
+			return nil, fmt.Errorf("cannot create OCFBlockReader: %s", err)
+		}
+	}
+	return obr, nil
+}
+
+// Codec returns the codec of the schema of the OCF.
+func (obr *OCFBlockReader) Codec() *Codec {
+	return obr.header.codec
+}
+
+// CompressionName returns the name of the compression algorithm of the blocks
+// of the OCF.
+func (obr *OCFBlockReader) CompressionName() string {
+	if name, ok := compressionName(obr.header.compressionID); ok {
+		return name
+	}
+	return "should not get here: unrecognized compression algorithm"
+}
+
+// MetaData returns the metadata of the OCF header. The returned map ought not
+// to be modified.
+func (obr *OCFBlockReader) MetaData() map[string][]byte {
+	return obr.header.metadata
+}
+
+// SyncMarker returns the sync marker of the OCF header, which follows each of
+// its blocks.
+func (obr *OCFBlockReader) SyncMarker() [16]byte {
+	return obr.header.syncMarker
+}
+
+// Scan reads the next block of the OCF, returning true when a block was read,
+// and false at the end of the OCF or after an error, which Err returns. The
+// block count and size are checked against MaxBlockCount and MaxBlockSize,
+// and the sync marker of the block against the sync marker of the header. When
+// the OCF records a checksum, such as OCFs written with OCFConfig.Checksum, the
+// checksum of the blocks is verified once the end of the OCF is reached.
+func (obr *OCFBlockReader) Scan() bool {
+	if obr.rerr != nil {
+		return false
+	}
+	obr.block = OCFBlock{}
+
+	count, err := longBinaryReader(obr.ior)
+	if err != nil {
+		if err == io.EOF {
+			if obr.checksum != nil {
+				obr.rerr = verifyOCFChecksum(obr.checksum, obr.recorded)
+			}
+			return false
+		}
+		obr.rerr = fmt.Errorf("cannot read block %d: cannot read block count: %s", obr.count, err)
+		return false
+	}
+	if count <= 0 || count > MaxBlockCount {
+		obr.rerr = fmt.Errorf("cannot read block %d: block count ought to be from 1 to MaxBlockCount: %d", obr.count, count)
+		return false
+	}
+	size, err := longBinaryReader(obr.ior)
+	if err != nil {
+		obr.rerr = fmt.Errorf("cannot read block %d: cannot read block size: %s", obr.count, err)
+		return false
+	}
+	if size <= 0 || size > MaxBlockSize {
+		obr.rerr = fmt.Errorf("cannot read block %d: block size ought to be from 1 to MaxBlockSize: %d", obr.count, size)
+		return false
+	}
+	data := make([]byte, size)
+	if _, err = io.ReadFull(obr.ior, data); err != nil {
+		obr.rerr = fmt.Errorf("cannot read block %d: %s", obr.count, err)
+		return false
+	}
+	var sync [ocfSyncLength]byte
+	if _, err = io.ReadFull(obr.ior, sync[:]); err != nil {
+		obr.rerr = fmt.Errorf("cannot read block %d: cannot read sync marker: %s", obr.count, err)
+		return false
+	}
+	if !bytes.Equal(sync[:], obr.header.syncMarker[:]) {
+		obr.rerr = fmt.Errorf("cannot read block %d: sync marker mismatch: %v != %v", obr.count, sync, obr.header.syncMarker)
+		return false
+	}
+	if obr.checksum != nil {
+		hashOCFBlockPrefix(obr.checksum, count, size)
+		_, _ = obr.checksum.Write(data)
+		_, _ = obr.checksum.Write(sync[:])
+	}
+	obr.block = OCFBlock{Count: count, Data: data, SyncMarker: sync}
+	obr.count++
+	return true
+}
+
+// Block returns the block read by the most recent call to Scan. The Data of
+// each block is a new slice, which the caller may retain.
+func (obr *OCFBlockReader) Block() OCFBlock {
+	return obr.block
+}
+
+// Decompress returns the binary encoding of the data items of the block, as
+// decompressed by the compression algorithm of the OCF. When the OCF is not
+// compressed, the returned slice is the Data of the block.
+func (obr *OCFBlockReader) Decompress(block OCFBlock) ([]byte, error) {
+	decompressed, err := decompressOCFBlock(obr.header.compressionID, block.Data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decompress block: %s", err)
+	}
+	return decompressed, nil
+}
+
+// Err returns the error that stopped Scan, if any. Reaching the end of the OCF
+// is not an error.
+func (obr *OCFBlockReader) Err() error {
+	return obr.rerr
+}
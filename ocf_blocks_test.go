@@ -0,0 +1,116 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestOCFBlockReader(t *testing.T) {
+	bb := new(bytes.Buffer)
+	ocfw, err := NewOCFWriter(OCFConfig{W: bb, Schema: `"long"`, CompressionName: CompressionDeflateLabel})
+	ensureError(t, err)
+	ensureError(t, ocfw.Append([]interface{}{1, 2}))
+	ensureError(t, ocfw.Append([]interface{}{3}))
+
+	obr, err := NewOCFBlockReader(bytes.NewReader(bb.Bytes()))
+	ensureError(t, err)
+	if actual, expected := obr.CompressionName(), CompressionDeflateLabel; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if actual, expected := obr.Codec().Schema(), `"long"`; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	var counts []int64
+	var data []interface{}
+	for obr.Scan() {
+		block := obr.Block()
+		if block.SyncMarker != obr.SyncMarker() {
+			t.Errorf("GOT: %v; WANT: %v", block.SyncMarker, obr.SyncMarker())
+		}
+		counts = append(counts, block.Count)
+		buf, err := obr.Decompress(block)
+		ensureError(t, err)
+		for i := int64(0); i < block.Count; i++ {
+			var datum interface{}
+			datum, buf, err = obr.Codec().NativeFromBinary(buf)
+			ensureError(t, err)
+			data = append(data, datum)
+		}
+		if len(buf) != 0 {
+			t.Errorf("GOT: %v; WANT: %v", len(buf), 0)
+		}
+	}
+	ensureError(t, obr.Err())
+	if actual, expected := counts, []int64{2, 1}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if actual, expected := data, []interface{}{int64(1), int64(2), int64(3)}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if obr.Scan() {
+		t.Errorf("GOT: %v; WANT: %v", true, false)
+	}
+}
+
+func TestOCFBlockReaderCorruption(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goavro")
+	ensureError(t, err)
+	defer os.RemoveAll(dir)
+
+	contents := writeChecksumOCF(t, dir, OCFChecksumCRC32, true, []interface{}{1, 2}, []interface{}{3})
+	// block 1 is the final 19 bytes: count, size, the datum, and the sync marker
+	secondBlock := len(contents) - 19
+
+	readBlocks := func(contents []byte) (int, error) {
+		obr, err := NewOCFBlockReader(bytes.NewReader(contents))
+		ensureError(t, err)
+		var blocks int
+		for obr.Scan() {
+			blocks++
+		}
+		return blocks, obr.Err()
+	}
+
+	blocks, err := readBlocks(contents)
+	ensureError(t, err)
+	if actual, expected := blocks, 2; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	corrupted := append([]byte(nil), contents...)
+	corrupted[secondBlock+2] = 8
+	blocks, err = readBlocks(corrupted)
+	ensureError(t, err, "checksum")
+	if actual, expected := blocks, 2; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	corrupted = append([]byte(nil), contents...)
+	corrupted[len(corrupted)-1] ^= 0xff
+	blocks, err = readBlocks(corrupted)
+	ensureError(t, err, "cannot read block 1", "sync marker mismatch")
+	if actual, expected := blocks, 1; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	blocks, err = readBlocks(contents[:len(contents)-4])
+	ensureError(t, err, "cannot read block 1", "cannot read sync marker")
+	if actual, expected := blocks, 1; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
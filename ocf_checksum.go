@@ -0,0 +1,93 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"hash/crc32"
+)
+
+const (
+	// OCFChecksumCRC32 is used to checksum OCF blocks using the IEEE CRC-32
+	// algorithm. The hash/crc32 package uses the CRC instructions of the
+	// processor when they are available, detected at runtime.
+	OCFChecksumCRC32 = "crc32"
+
+	// OCFChecksumSHA256 is used to checksum OCF blocks using the SHA-256
+	// algorithm.
+	OCFChecksumSHA256 = "sha256"
+
+	// ocfChecksumKey is the metadata key of the checksum, whose value is the
+	// algorithm name, a colon, and the digest of every block of the OCF.
+	ocfChecksumKey = "goavro.checksum"
+)
+
+// newOCFChecksum returns a new hash for the named checksum algorithm.
+func newOCFChecksum(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case OCFChecksumCRC32:
+		return crc32.NewIEEE(), nil
+	case OCFChecksumSHA256:
+		return sha256.New(), nil
+	default:
+		return nil, fmt.Errorf("unrecognized checksum algorithm: %q", algorithm)
+	}
+}
+
+// ocfChecksumPlaceholder returns the metadata value recorded in the header of a
+// new OCF, which is overwritten with the same number of bytes when the
+// OCFWriter is closed.
+func ocfChecksumPlaceholder(algorithm string, h hash.Hash) []byte {
+	return append([]byte(algorithm+":"), make([]byte, h.Size())...)
+}
+
+// parseOCFChecksum returns a new hash for the algorithm named by the checksum
+// metadata value, along with the recorded digest.
+func parseOCFChecksum(value []byte) (hash.Hash, []byte, error) {
+	i := bytes.IndexByte(value, ':')
+	if i < 0 {
+		return nil, nil, fmt.Errorf("cannot read OCF checksum without algorithm: %q", value)
+	}
+	h, err := newOCFChecksum(string(value[:i]))
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot read OCF checksum: %s", err)
+	}
+	digest := value[i+1:]
+	if len(digest) != h.Size() {
+		return nil, nil, fmt.Errorf("cannot read OCF checksum: digest ought to have %d bytes: %d", h.Size(), len(digest))
+	}
+	return h, digest, nil
+}
+
+// hashOCFBlockPrefix adds the block count and block size of a block to the
+// checksum, encoded as they are written by OCFWriter.
+func hashOCFBlockPrefix(h hash.Hash, blockCount, blockSize int64) {
+	buf, _ := longBinaryFromNative(make([]byte, 0, 20), blockCount)
+	buf, _ = longBinaryFromNative(buf, blockSize)
+	_, _ = h.Write(buf)
+}
+
+// verifyOCFChecksum compares the digest of the hash with the recorded digest.
+func verifyOCFChecksum(h hash.Hash, recorded []byte) error {
+	actual := h.Sum(nil)
+	if bytes.Equal(actual, recorded) {
+		return nil
+	}
+	if bytes.Equal(recorded, make([]byte, len(recorded))) {
+		return fmt.Errorf("OCF checksum was not recorded, possibly because the OCFWriter was not closed")
+	}
+	return fmt.Errorf("OCF checksum mismatch: %x != %x", actual, recorded)
+}
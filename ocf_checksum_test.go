@@ -0,0 +1,128 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// writeChecksumOCF writes data to a new OCF file in dir, optionally closing
+// the OCFWriter, and returns the contents of the file.
+func writeChecksumOCF(t *testing.T, dir, algorithm string, close bool, data ...[]interface{}) []byte {
+	t.Helper()
+	file, err := os.Create(dir + "/data.avro")
+	ensureError(t, err)
+	defer file.Close()
+	ocfw, err := NewOCFWriter(OCFConfig{W: file, Schema: `"long"`, Checksum: algorithm})
+	ensureError(t, err)
+	for _, items := range data {
+		ensureError(t, ocfw.Append(items))
+	}
+	if close {
+		ensureError(t, ocfw.Close())
+	}
+	contents, err := ioutil.ReadFile(dir + "/data.avro")
+	ensureError(t, err)
+	return contents
+}
+
+func readChecksumOCF(t *testing.T, contents []byte) ([]interface{}, error) {
+	t.Helper()
+	ocfr, err := NewOCFReader(bytes.NewReader(contents))
+	ensureError(t, err)
+	var data []interface{}
+	for ocfr.Scan() {
+		datum, err := ocfr.Read()
+		ensureError(t, err)
+		data = append(data, datum)
+	}
+	return data, ocfr.Err()
+}
+
+func TestOCFChecksumRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goavro")
+	ensureError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, algorithm := range []string{OCFChecksumCRC32, OCFChecksumSHA256} {
+		contents := writeChecksumOCF(t, dir, algorithm, true, []interface{}{1, 2}, []interface{}{3})
+		data, err := readChecksumOCF(t, contents)
+		ensureError(t, err)
+		if actual, expected := len(data), 3; actual != expected {
+			t.Errorf("%s: GOT: %v; WANT: %v", algorithm, actual, expected)
+		}
+
+		// empty OCF
+		contents = writeChecksumOCF(t, dir, algorithm, true)
+		_, err = readChecksumOCF(t, contents)
+		ensureError(t, err)
+	}
+}
+
+func TestOCFChecksumNotRecorded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goavro")
+	ensureError(t, err)
+	defer os.RemoveAll(dir)
+
+	contents := writeChecksumOCF(t, dir, OCFChecksumCRC32, false, []interface{}{1, 2})
+	_, err = readChecksumOCF(t, contents)
+	ensureError(t, err, "checksum was not recorded")
+}
+
+func TestOCFChecksumMismatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goavro")
+	ensureError(t, err)
+	defer os.RemoveAll(dir)
+
+	contents := writeChecksumOCF(t, dir, OCFChecksumSHA256, true, []interface{}{1, 2})
+	// NOTE: Without compression, changing the final datum from 2 to 3 is
+	// only detected by the checksum.
+	contents[len(contents)-ocfSyncLength-1] ^= 0x02
+	_, err = readChecksumOCF(t, contents)
+	ensureError(t, err, "checksum mismatch")
+}
+
+func TestOCFChecksumAppendToFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goavro")
+	ensureError(t, err)
+	defer os.RemoveAll(dir)
+
+	writeChecksumOCF(t, dir, OCFChecksumCRC32, true, []interface{}{1, 2})
+
+	file, err := os.OpenFile(dir+"/data.avro", os.O_RDWR, 0666)
+	ensureError(t, err)
+	ocfw, err := NewOCFWriter(OCFConfig{W: file})
+	ensureError(t, err)
+	ensureError(t, ocfw.Append([]interface{}{3}))
+	ensureError(t, ocfw.Close())
+	ensureError(t, file.Close())
+
+	contents, err := ioutil.ReadFile(dir + "/data.avro")
+	ensureError(t, err)
+	data, err := readChecksumOCF(t, contents)
+	ensureError(t, err)
+	if actual, expected := len(data), 3; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
+
+func TestOCFChecksumConfig(t *testing.T) {
+	_, err := NewOCFWriter(OCFConfig{W: new(bytes.Buffer), Schema: `"long"`, Checksum: "md4"})
+	ensureError(t, err, "cannot create OCFWriter", "unrecognized checksum algorithm")
+
+	_, err = NewOCFWriter(OCFConfig{W: new(bytes.Buffer), Schema: `"long"`, Checksum: OCFChecksumCRC32})
+	ensureError(t, err, "cannot create OCFWriter", "io.WriterAt")
+}
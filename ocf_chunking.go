@@ -0,0 +1,148 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// ocfChunkerMinimumSize is the smallest average block size accepted, below
+// which blocks would be dominated by their prefix and sync marker.
+const ocfChunkerMinimumSize = 64
+
+// ocfGear maps each byte to a random value, for the gear rolling hash. As each
+// byte is shifted out of the hash after 64 more bytes, the hash only depends on
+// the final 64 bytes hashed. The values ought never to change, or the blocks
+// of OCF files written by different versions of this library would differ.
+var ocfGear [256]uint64
+
+func init() {
+	// NOTE: splitmix64, from a fixed seed
+	x := uint64(0x676f6176726f4344) // "goavroCD"
+	for i := range ocfGear {
+		x += 0x9e3779b97f4a7c15
+		z := x
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		ocfGear[i] = z ^ (z >> 31)
+	}
+}
+
+// ocfChunker accumulates the encoded data of the pending block of an OCFWriter
+// whose block boundaries are chosen by content, or by the size and count of
+// the data of the block.
+type ocfChunker struct {
+	mask     uint64 // a boundary is found where the hash has none of these bits, unless zero
+	min, max int    // size limits of a block, without an upper limit when max is zero
+	maxCount int    // count limit of a block, other than MaxBlockCount, unless zero
+	hash     uint64
+	block    []byte  // encoded data of the pending block
+	offsets  []int64 // offset of each datum in block
+	boundary bool    // whether a boundary was found in the data of block
+}
+
+func newOCFChunker(size int) (*ocfChunker, error) {
+	if size < ocfChunkerMinimumSize {
+		return nil, fmt.Errorf("content defined block size ought to be at least %d; received: %d", ocfChunkerMinimumSize, size)
+	}
+	if int64(size) > MaxBlockSize/4 {
+		return nil, fmt.Errorf("content defined block size ought not to exceed a quarter of MaxBlockSize: %d > %d", size, MaxBlockSize/4)
+	}
+	min := size / 4
+	// NOTE: Boundaries are not searched for in the first min bytes, so the
+	// mask makes boundaries as frequent as the remainder of the size.
+	return &ocfChunker{
+		mask: 1<<uint(bits.Len(uint(size-min))-1) - 1,
+		min:  min,
+		max:  size * 4,
+	}, nil
+}
+
+// newOCFSizedChunker returns a chunker that ends a block once its encoded data
+// reaches size bytes, or count data items, ignoring either limit when zero.
+func newOCFSizedChunker(size, count int) (*ocfChunker, error) {
+	if size < 0 || int64(size) > MaxBlockSize {
+		return nil, fmt.Errorf("block size bytes ought to be between 0 and MaxBlockSize: %d", size)
+	}
+	if count < 0 || int64(count) > MaxBlockCount {
+		return nil, fmt.Errorf("block record count ought to be between 0 and MaxBlockCount: %d", count)
+	}
+	return &ocfChunker{max: size, maxCount: count}, nil
+}
+
+// scan hashes the bytes of block starting at offset, recording whether they
+// contain a boundary.
+func (c *ocfChunker) scan(offset int) {
+	if c.mask == 0 {
+		return
+	}
+	h := c.hash
+	for i, b := range c.block[offset:] {
+		h = h<<1 + ocfGear[b]
+		if h&c.mask == 0 && offset+i >= c.min {
+			c.boundary = true
+		}
+	}
+	c.hash = h
+}
+
+// full returns whether the pending block ought to be written.
+func (c *ocfChunker) full() bool {
+	return c.boundary || (c.max > 0 && len(c.block) >= c.max) || (c.maxCount > 0 && len(c.offsets) >= c.maxCount) || int64(len(c.offsets)) >= MaxBlockCount
+}
+
+func (c *ocfChunker) reset() {
+	c.block = c.block[:0]
+	c.offsets = nil
+	c.boundary = false
+}
+
+// appendDataIntoChunks encodes each datum into the pending block, writing the
+// block after each datum that ends it.
+func (ocfw *OCFWriter) appendDataIntoChunks(data []interface{}) error {
+	c := ocfw.chunker
+	for _, datum := range data {
+		offset := len(c.block)
+		block, err := ocfw.header.codec.BinaryFromNative(c.block, datum)
+		if err != nil {
+			return fmt.Errorf("cannot translate datum to binary: %v; %s", datum, err)
+		}
+		c.block = block
+		c.offsets = append(c.offsets, int64(offset))
+		c.scan(offset)
+		if c.full() {
+			if err = ocfw.flushChunk(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// flushChunk writes the pending block, if any.
+func (ocfw *OCFWriter) flushChunk() error {
+	c := ocfw.chunker
+	if len(c.offsets) == 0 {
+		return nil
+	}
+	var recordOffsets []int64
+	if ocfw.index != nil {
+		recordOffsets = c.offsets
+	}
+	if err := ocfw.writeBlock(int64(len(c.offsets)), c.block, recordOffsets); err != nil {
+		return err
+	}
+	c.reset()
+	return nil
+}
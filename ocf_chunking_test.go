@@ -0,0 +1,184 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// writeChunkedOCF writes the data in batches of the specified size, and returns
+// the data of each block of the OCF, without its sync marker.
+func writeChunkedOCF(t *testing.T, data []interface{}, batch int) [][]byte {
+	t.Helper()
+	var ocf, index bytes.Buffer
+	ocfw, err := NewOCFWriter(OCFConfig{
+		W:                       &ocf,
+		Schema:                  `{"type":"record","name":"r","fields":[{"name":"id","type":"long"},{"name":"name","type":"string"}]}`,
+		Index:                   &index,
+		ContentDefinedBlockSize: 256,
+	})
+	ensureError(t, err)
+	for len(data) > 0 {
+		n := batch
+		if n > len(data) {
+			n = len(data)
+		}
+		ensureError(t, ocfw.Append(data[:n]))
+		data = data[n:]
+	}
+	ensureError(t, ocfw.Close())
+
+	entries, err := ReadOCFIndex(&index)
+	ensureError(t, err)
+	blocks := make([][]byte, len(entries))
+	for i, entry := range entries {
+		end := int64(ocf.Len())
+		if i < len(entries)-1 {
+			end = entries[i+1].Offset
+		}
+		blocks[i] = ocf.Bytes()[entry.Offset : end-ocfSyncLength]
+	}
+	return blocks
+}
+
+func TestOCFWriterContentDefinedBlocks(t *testing.T) {
+	var data []interface{}
+	for i := 0; i < 2000; i++ {
+		data = append(data, map[string]interface{}{"id": int64(i), "name": fmt.Sprintf("name %d", i)})
+	}
+	first := writeChunkedOCF(t, data, 7)
+	if len(first) < 20 {
+		t.Fatalf("GOT: %v blocks; WANT: at least 20", len(first))
+	}
+
+	seen := make(map[string]bool)
+	for _, block := range first {
+		seen[string(block)] = true
+	}
+
+	// insert data at the start, and batch the data differently
+	var inserted []interface{}
+	for i := 0; i < 5; i++ {
+		inserted = append(inserted, map[string]interface{}{"id": int64(-i), "name": "new"})
+	}
+	second := writeChunkedOCF(t, append(inserted, data...), 100)
+	var shared int
+	for _, block := range second {
+		if seen[string(block)] {
+			shared++
+		}
+	}
+	if shared < len(first)-2 {
+		t.Errorf("GOT: %v shared blocks; WANT: at least %v", shared, len(first)-2)
+	}
+}
+
+func TestOCFWriterContentDefinedBlocksRead(t *testing.T) {
+	var ocf bytes.Buffer
+	ocfw, err := NewOCFWriter(OCFConfig{W: &ocf, Schema: `"long"`, ContentDefinedBlockSize: 64})
+	ensureError(t, err)
+	for i := int64(0); i < 500; i++ {
+		ensureError(t, ocfw.Append([]interface{}{i}))
+	}
+	ensureError(t, ocfw.Close())
+
+	ocfr, err := NewOCFReader(&ocf)
+	ensureError(t, err)
+	var expected int64
+	for ocfr.Scan() {
+		datum, err := ocfr.Read()
+		ensureError(t, err)
+		if datum != expected {
+			t.Fatalf("GOT: %v; WANT: %v", datum, expected)
+		}
+		expected++
+	}
+	ensureError(t, ocfr.Err())
+	if expected != 500 {
+		t.Errorf("GOT: %v; WANT: %v", expected, 500)
+	}
+}
+
+func TestOCFWriterContentDefinedBlockSizeInvalid(t *testing.T) {
+	_, err := NewOCFWriter(OCFConfig{W: new(bytes.Buffer), Schema: `"long"`, ContentDefinedBlockSize: 16})
+	ensureError(t, err, "content defined block size ought to be at least 64")
+}
+
+func TestOCFWriterBlockSizeBytesAndRecordCount(t *testing.T) {
+	blockCounts := func(config OCFConfig, batch int) []int {
+		var ocf, index bytes.Buffer
+		config.W, config.Index, config.Schema = &ocf, &index, `"string"`
+		ocfw, err := NewOCFWriter(config)
+		ensureError(t, err)
+		var data []interface{}
+		for i := 0; i < 25; i++ {
+			data = append(data, "0123456789") // 11 bytes encoded
+		}
+		for len(data) > 0 {
+			n := batch
+			if n > len(data) {
+				n = len(data)
+			}
+			ensureError(t, ocfw.Append(data[:n]))
+			data = data[n:]
+		}
+		ensureError(t, ocfw.Close())
+
+		entries, err := ReadOCFIndex(&index)
+		ensureError(t, err)
+		counts := make([]int, len(entries))
+		for i, entry := range entries {
+			counts[i] = len(entry.RecordOffsets)
+		}
+		ocfr, err := NewOCFReader(bytes.NewReader(ocf.Bytes()))
+		ensureError(t, err)
+		var items int
+		for ocfr.Scan() {
+			_, err = ocfr.Read()
+			ensureError(t, err)
+			items++
+		}
+		ensureError(t, ocfr.Err())
+		if items != 25 {
+			t.Errorf("GOT: %v; WANT: %v", items, 25)
+		}
+		return counts
+	}
+
+	cases := []struct {
+		config   OCFConfig
+		batch    int
+		expected []int
+	}{
+		{OCFConfig{BlockRecordCount: 10}, 3, []int{10, 10, 5}},
+		{OCFConfig{BlockRecordCount: 10}, 25, []int{10, 10, 5}},
+		{OCFConfig{BlockSizeBytes: 100}, 7, []int{10, 10, 5}}, // the tenth datum brings a block to 110 bytes
+		{OCFConfig{BlockSizeBytes: 100, BlockRecordCount: 4}, 1, []int{4, 4, 4, 4, 4, 4, 1}},
+		{OCFConfig{BlockSizeBytes: 33, BlockRecordCount: 4}, 25, []int{3, 3, 3, 3, 3, 3, 3, 3, 1}},
+	}
+	for _, c := range cases {
+		if actual := blockCounts(c.config, c.batch); !reflect.DeepEqual(actual, c.expected) {
+			t.Errorf("%+v: GOT: %v; WANT: %v", c.config, actual, c.expected)
+		}
+	}
+}
+
+func TestOCFWriterBlockSizeBytesInvalid(t *testing.T) {
+	_, err := NewOCFWriter(OCFConfig{W: new(bytes.Buffer), Schema: `"long"`, BlockRecordCount: -1})
+	ensureError(t, err, "block record count ought to be between 0 and MaxBlockCount")
+	_, err = NewOCFWriter(OCFConfig{W: new(bytes.Buffer), Schema: `"long"`, BlockSizeBytes: 1024, ContentDefinedBlockSize: 1024})
+	ensureError(t, err, "ought not to be used with block size bytes")
+}
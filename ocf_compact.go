@@ -0,0 +1,171 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// compactBlockLength is the number of data items written in each block of a
+// compacted OCF when CompactConfig does not specify one.
+const compactBlockLength = 1000
+
+// CompactConfig is used to specify the parameters of CompactOCF.
+type CompactConfig struct {
+	// Field specifies the name of the timestamp field of the records whose
+	// age determines whether they expire, (required when TTL is used). Its
+	// value ought to be a time.Time, such as the native form of the
+	// timestamp-millis logical type, or a union wrapping one. Records whose
+	// timestamp is null do not expire.
+	Field string
+
+	// TTL specifies the age after which records expire, (optional). Records
+	// whose timestamp is before Now minus TTL are not written. When zero,
+	// records do not expire.
+	TTL time.Duration
+
+	// Now specifies the time from which the age of records is measured,
+	// (optional), so a compaction may be repeated with the same result.
+	// When zero, the time CompactOCF is called is used.
+	Now time.Time
+
+	// Keep returns false for other records that ought not to be written,
+	// (optional), such as the records of deleted users.
+	Keep func(datum interface{}) (bool, error)
+
+	// BlockLength specifies the number of data items written in each block,
+	// (optional). When zero, blocks of 1000 data items are written, so the
+	// small blocks of the sources are combined.
+	BlockLength int
+}
+
+// CompactStats describes the data items read and written by CompactOCF.
+type CompactStats struct {
+	Read    int64 // data items read from the sources
+	Expired int64 // data items older than the TTL
+	Dropped int64 // data items for which Keep returned false
+	Written int64 // data items written to the destination
+}
+
+// CompactOCF copies the data items of each source OCF to the destination OCF,
+// dropping the records that expired and those that ought not to be kept, so
+// retention is enforced in the same pass that compacts small files or
+// migrates data to a new schema. When the schema of a source differs from the
+// schema of the destination, its data is resolved to the schema of the
+// destination, following the rules of Resolution, and the timestamp field is
+// that of the destination schema.
+//
+//     ocfw, err := goavro.NewOCFWriter(goavro.OCFConfig{W: compacted, Codec: codec, CompressionName: goavro.CompressionSnappyLabel})
+//     if err != nil {
+//         return err
+//     }
+//     stats, err := goavro.CompactOCF(ocfw, goavro.CompactConfig{Field: "timestamp", TTL: 30 * 24 * time.Hour}, sources...)
+//     if err != nil {
+//         return err
+//     }
+//     log.Printf("%d records expired", stats.Expired)
+//
+// The destination is not closed. When CompactOCF returns an error, the blocks
+// written before the error remain in the destination.
+func CompactOCF(ocfw *OCFWriter, config CompactConfig, sources ...*OCFReader) (CompactStats, error) {
+	var stats CompactStats
+	if config.TTL < 0 {
+		return stats, fmt.Errorf("cannot compact OCF: TTL ought to be zero or positive: %s", config.TTL)
+	}
+	if config.TTL > 0 && config.Field == "" {
+		return stats, errors.New("cannot compact OCF: TTL requires Field")
+	}
+	if config.BlockLength < 0 {
+		return stats, fmt.Errorf("cannot compact OCF: block length ought to be zero or positive: %d", config.BlockLength)
+	}
+	if config.BlockLength == 0 {
+		config.BlockLength = compactBlockLength
+	}
+	if config.Now.IsZero() {
+		config.Now = time.Now()
+	}
+	expiry := config.Now.Add(-config.TTL)
+
+	block := make([]interface{}, 0, config.BlockLength)
+	for i, ocfr := range sources {
+		var resolution *Resolution
+		if ocfr.Codec().Rabin != ocfw.Codec().Rabin {
+			var err error
+			if resolution, err = NewResolution(ocfr.Codec(), ocfw.Codec()); err != nil {
+				return stats, fmt.Errorf("cannot compact OCF: source %d: %s", i, err)
+			}
+		}
+		for ocfr.Scan() {
+			datum, err := compactRead(ocfr, resolution)
+			if err != nil {
+				return stats, fmt.Errorf("cannot compact OCF: source %d: %s", i, err)
+			}
+			stats.Read++
+			if config.TTL > 0 {
+				timestamp, err := recordTimestamp(datum, config.Field)
+				if err != nil {
+					return stats, fmt.Errorf("cannot compact OCF: source %d: %s", i, err)
+				}
+				if timestamp != nil && timestamp.Before(expiry) {
+					stats.Expired++
+					continue
+				}
+			}
+			if config.Keep != nil {
+				keep, err := config.Keep(datum)
+				if err != nil {
+					return stats, fmt.Errorf("cannot compact OCF: source %d: %s", i, err)
+				}
+				if !keep {
+					stats.Dropped++
+					continue
+				}
+			}
+			if block = append(block, datum); len(block) == config.BlockLength {
+				if err = ocfw.Append(block); err != nil {
+					return stats, fmt.Errorf("cannot compact OCF: %s", err)
+				}
+				stats.Written += int64(len(block))
+				block = block[:0]
+			}
+		}
+		if err := ocfr.Err(); err != nil {
+			return stats, fmt.Errorf("cannot compact OCF: source %d: %s", i, err)
+		}
+	}
+	if len(block) > 0 {
+		if err := ocfw.Append(block); err != nil {
+			return stats, fmt.Errorf("cannot compact OCF: %s", err)
+		}
+		stats.Written += int64(len(block))
+	}
+	return stats, nil
+}
+
+// compactRead reads the next datum of the OCF reader, resolving it to the
+// schema of the reader of the resolution when it is not nil.
+func compactRead(ocfr *OCFReader, resolution *Resolution) (interface{}, error) {
+	if resolution == nil {
+		return ocfr.Read()
+	}
+	datum, rest, err := resolution.NativeFromBinary(ocfr.block)
+	if err != nil {
+		return nil, err
+	}
+	ocfr.block = rest
+	ocfr.remainingBlockItems--
+	ocfr.readReady = false
+	return datum, nil
+}
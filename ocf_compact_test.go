@@ -0,0 +1,121 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+const compactSchemaV1 = `{"type": "record", "name": "Session", "fields": [
+  {"name": "user", "type": "string"},
+  {"name": "ts", "type": {"type": "long", "logicalType": "timestamp-millis"}}
+]}`
+
+const compactSchemaV2 = `{"type": "record", "name": "Session", "fields": [
+  {"name": "user", "type": "string"},
+  {"name": "ts", "type": ["null", {"type": "long", "logicalType": "timestamp-millis"}]},
+  {"name": "device", "type": "string", "default": "unknown"}
+]}`
+
+func compactSource(t *testing.T, schema string, data []interface{}) *OCFReader {
+	t.Helper()
+	bb := new(bytes.Buffer)
+	ocfw, err := NewOCFWriter(OCFConfig{W: bb, Schema: schema})
+	ensureError(t, err)
+	for _, datum := range data {
+		ensureError(t, ocfw.Append([]interface{}{datum}))
+	}
+	ocfr, err := NewOCFReader(bb)
+	ensureError(t, err)
+	return ocfr
+}
+
+func TestCompactOCF(t *testing.T) {
+	now := time.Date(2019, 6, 30, 0, 0, 0, 0, time.UTC)
+	old := now.Add(-40 * 24 * time.Hour)
+	recent := now.Add(-time.Hour)
+
+	v1 := compactSource(t, compactSchemaV1, []interface{}{
+		map[string]interface{}{"user": "a", "ts": old},
+		map[string]interface{}{"user": "b", "ts": recent},
+		map[string]interface{}{"user": "deleted", "ts": recent},
+	})
+	v2 := compactSource(t, compactSchemaV2, []interface{}{
+		map[string]interface{}{"user": "c", "ts": Union("long.timestamp-millis", recent), "device": "phone"},
+		map[string]interface{}{"user": "d", "ts": nil, "device": "tablet"},
+		map[string]interface{}{"user": "e", "ts": Union("long.timestamp-millis", old), "device": "phone"},
+	})
+
+	bb := new(bytes.Buffer)
+	ocfw, err := NewOCFWriter(OCFConfig{W: bb, Schema: compactSchemaV2})
+	ensureError(t, err)
+	stats, err := CompactOCF(ocfw, CompactConfig{
+		Field: "ts",
+		TTL:   30 * 24 * time.Hour,
+		Now:   now,
+		Keep: func(datum interface{}) (bool, error) {
+			return datum.(map[string]interface{})["user"] != "deleted", nil
+		},
+		BlockLength: 2,
+	}, v1, v2)
+	ensureError(t, err)
+	if actual, expected := stats, (CompactStats{Read: 6, Expired: 2, Dropped: 1, Written: 3}); actual != expected {
+		t.Errorf("GOT: %+v; WANT: %+v", actual, expected)
+	}
+
+	ocfr, err := NewOCFReader(bb)
+	ensureError(t, err)
+	var users, devices []string
+	var blocks int
+	for ocfr.Scan() {
+		if ocfr.RemainingBlockItems() == 2 {
+			blocks++
+		}
+		datum, err := ocfr.Read()
+		ensureError(t, err)
+		record := datum.(map[string]interface{})
+		users = append(users, record["user"].(string))
+		devices = append(devices, record["device"].(string))
+	}
+	ensureError(t, ocfr.Err())
+	if actual, expected := len(users), 3; actual != expected {
+		t.Fatalf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if users[0] != "b" || users[1] != "c" || users[2] != "d" || devices[0] != "unknown" || devices[1] != "phone" {
+		t.Errorf("GOT: %v %v", users, devices)
+	}
+	if actual, expected := blocks, 1; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
+
+func TestCompactOCFErrors(t *testing.T) {
+	bb := new(bytes.Buffer)
+	ocfw, err := NewOCFWriter(OCFConfig{W: bb, Schema: compactSchemaV1})
+	ensureError(t, err)
+
+	_, err = CompactOCF(ocfw, CompactConfig{TTL: time.Hour})
+	ensureError(t, err, "cannot compact OCF: TTL requires Field")
+	_, err = CompactOCF(ocfw, CompactConfig{BlockLength: -1})
+	ensureError(t, err, "block length ought to be zero or positive")
+
+	source := compactSource(t, compactSchemaV1, []interface{}{map[string]interface{}{"user": "a", "ts": time.Now()}})
+	_, err = CompactOCF(ocfw, CompactConfig{Field: "user", TTL: time.Hour}, source)
+	ensureError(t, err, `cannot compact OCF: source 0: field "user" ought to be a time.Time; received: string`)
+
+	source = compactSource(t, `{"type": "record", "name": "Session", "fields": [{"name": "user", "type": "long"}]}`, nil)
+	_, err = CompactOCF(ocfw, CompactConfig{}, source)
+	ensureError(t, err, "cannot compact OCF: source 0:")
+}
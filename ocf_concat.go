@@ -0,0 +1,109 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ConcatOCF writes to w a single OCF holding the blocks of each OCF read from
+// sources, in order, such as when compacting the many small files written by a
+// batch job. The OCFs ought to have schemas whose canonical forms are equal.
+// The OCF written uses the schema, the compression algorithm, and the metadata
+// of the first OCF, apart from its checksum. Blocks are copied without being
+// decoded, and the blocks of OCFs using another compression algorithm are
+// decompressed and compressed again using the compression algorithm of the
+// first OCF.
+//
+//     stats, err := goavro.ConcatOCF(output, first, second)
+//     if err != nil {
+//         return err
+//     }
+//     log.Printf("concatenated %d data items", stats.Items)
+//
+// The sync marker of each block is verified, and when an OCF records a
+// checksum, such as OCFs written with OCFConfig.Checksum, its checksum is
+// verified once the end of the OCF is reached, after its blocks were written,
+// so when ConcatOCF returns an error, the OCF written to w ought to be
+// discarded. The Checksum of the returned stats is true when the checksum of
+// every OCF was verified.
+func ConcatOCF(w io.Writer, sources ...io.Reader) (OCFCopyStats, error) {
+	var stats OCFCopyStats
+	if len(sources) == 0 {
+		return stats, errors.New("cannot concatenate OCF without sources")
+	}
+
+	var header *ocfHeader
+	var canonical string
+	stats.Checksum = true
+	for i, source := range sources {
+		obr, err := NewOCFBlockReader(source)
+		if err != nil {
+			return stats, fmt.Errorf("cannot concatenate OCF: source %d: %s", i, err)
+		}
+		if header == nil {
+			header = &ocfHeader{codec: obr.header.codec, compressionID: obr.header.compressionID, metadata: make(map[string][]byte, len(obr.header.metadata))}
+			for k, v := range obr.header.metadata {
+				if k != ocfChecksumKey {
+					header.metadata[k] = v
+				}
+			}
+			if _, err = rand.Read(header.syncMarker[:]); err != nil {
+				return stats, fmt.Errorf("cannot concatenate OCF: %s", err)
+			}
+			n, err := writeOCFHeader(header, w)
+			stats.Bytes += int64(n)
+			if err != nil {
+				return stats, fmt.Errorf("cannot concatenate OCF: %s", err)
+			}
+			canonical = header.codec.CanonicalSchema()
+		} else if actual := obr.Codec().CanonicalSchema(); actual != canonical {
+			return stats, fmt.Errorf("cannot concatenate OCF: source %d: schema ought to match schema of source 0: %s != %s", i, actual, canonical)
+		}
+		if obr.checksum == nil {
+			stats.Checksum = false
+		}
+
+		for obr.Scan() {
+			block := obr.Block()
+			data := block.Data
+			if obr.header.compressionID != header.compressionID {
+				if data, err = obr.Decompress(block); err != nil {
+					return stats, fmt.Errorf("cannot concatenate OCF: source %d: block %d: %s", i, obr.count-1, err)
+				}
+				if data, err = compressOCFBlock(header.compressionID, data); err != nil {
+					return stats, fmt.Errorf("cannot concatenate OCF: source %d: block %d: %s", i, obr.count-1, err)
+				}
+			}
+			buf := make([]byte, 0, len(data)+ocfBlockConst)
+			buf, _ = longBinaryFromNative(buf, block.Count)
+			buf, _ = longBinaryFromNative(buf, len(data))
+			buf = append(buf, data...)
+			buf = append(buf, header.syncMarker[:]...)
+			n, err := w.Write(buf)
+			stats.Bytes += int64(n)
+			if err != nil {
+				return stats, fmt.Errorf("cannot concatenate OCF: %s", err)
+			}
+			stats.Blocks++
+			stats.Items += block.Count
+		}
+		if err = obr.Err(); err != nil {
+			return stats, fmt.Errorf("cannot concatenate OCF: source %d: %s", i, err)
+		}
+	}
+	return stats, nil
+}
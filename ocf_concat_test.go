@@ -0,0 +1,96 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestConcatOCF(t *testing.T) {
+	writeOCF := func(config OCFConfig, data ...[]interface{}) []byte {
+		bb := new(bytes.Buffer)
+		config.W = bb
+		ocfw, err := NewOCFWriter(config)
+		ensureError(t, err)
+		for _, items := range data {
+			ensureError(t, ocfw.Append(items))
+		}
+		return bb.Bytes()
+	}
+	schema := `{"type":"record","name":"r","fields":[{"name":"n","type":"long"}]}`
+	item := func(n int64) interface{} { return map[string]interface{}{"n": n} }
+	first := writeOCF(OCFConfig{Schema: schema, CompressionName: CompressionDeflateLabel, MetaData: map[string][]byte{"origin": []byte("batch")}}, []interface{}{item(1), item(2)}, []interface{}{item(3)})
+	second := writeOCF(OCFConfig{Schema: `{"type":"record","name":"r","doc":"counts","fields":[{"name":"n","type":"long","doc":"count"}]}`, CompressionName: CompressionSnappyLabel}, []interface{}{item(4)})
+	third := writeOCF(OCFConfig{Schema: schema, CompressionName: CompressionDeflateLabel}, []interface{}{item(5), item(6)})
+
+	bb := new(bytes.Buffer)
+	stats, err := ConcatOCF(bb, bytes.NewReader(first), bytes.NewReader(second), bytes.NewReader(third))
+	ensureError(t, err)
+	if actual, expected := stats, (OCFCopyStats{Bytes: int64(bb.Len()), Blocks: 4, Items: 6}); actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	ocfr, err := NewOCFReader(bytes.NewReader(bb.Bytes()))
+	ensureError(t, err)
+	if actual, expected := ocfr.CompressionName(), CompressionDeflateLabel; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if actual, expected := string(ocfr.MetaData()["origin"]), "batch"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	var data []interface{}
+	for ocfr.Scan() {
+		datum, err := ocfr.Read()
+		ensureError(t, err)
+		data = append(data, datum)
+	}
+	ensureError(t, ocfr.Err())
+	if actual, expected := data, []interface{}{item(1), item(2), item(3), item(4), item(5), item(6)}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	other := writeOCF(OCFConfig{Schema: `"long"`}, []interface{}{7})
+	_, err = ConcatOCF(ioutil.Discard, bytes.NewReader(first), bytes.NewReader(other))
+	ensureError(t, err, "cannot concatenate OCF", "source 1", "schema ought to match")
+
+	_, err = ConcatOCF(ioutil.Discard)
+	ensureError(t, err, "without sources")
+}
+
+func TestConcatOCFChecksum(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goavro")
+	ensureError(t, err)
+	defer os.RemoveAll(dir)
+
+	contents := writeChecksumOCF(t, dir, OCFChecksumSHA256, true, []interface{}{1}, []interface{}{2})
+	bb := new(bytes.Buffer)
+	stats, err := ConcatOCF(bb, bytes.NewReader(contents), bytes.NewReader(contents))
+	ensureError(t, err)
+	if actual, expected := stats.Checksum, true; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	data, err := readChecksumOCF(t, bb.Bytes())
+	ensureError(t, err)
+	if actual, expected := data, []interface{}{int64(1), int64(2), int64(1), int64(2)}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	corrupted := append([]byte(nil), contents...)
+	corrupted[len(corrupted)-ocfSyncLength-1] ^= 0x01 // datum of the final block
+	_, err = ConcatOCF(ioutil.Discard, bytes.NewReader(contents), bytes.NewReader(corrupted))
+	ensureError(t, err, "cannot concatenate OCF", "source 1", "checksum")
+}
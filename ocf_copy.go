@@ -0,0 +1,157 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"bytes"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// OCFCopyStats describes an OCF copied by CopyOCF, or concatenated by
+// ConcatOCF.
+type OCFCopyStats struct {
+	Bytes    int64 // bytes written to the destination
+	Blocks   int64 // blocks copied
+	Items    int64 // data items of the blocks copied
+	Checksum bool  // true when the checksum recorded in the OCF was verified
+}
+
+// CopyOCF copies the OCF read from src to dst, byte for byte, while verifying
+// it, such as when moving OCF files between storage tiers, so a corrupted file
+// is detected rather than propagated. The header and the sync marker of each
+// block are verified, and each block is decompressed, and its data items
+// skipped, to verify that the block holds its count of data items, and no
+// other bytes. When the OCF records a checksum, such as OCFs written with
+// OCFConfig.Checksum, the checksum of the blocks is verified once the end of
+// the OCF is reached.
+//
+// Each block is verified before it is written to dst, but the checksum can
+// only be verified after every block was written, so when CopyOCF returns an
+// error, dst ought to be discarded, such as by aborting the upload it writes.
+//
+//     stats, err := goavro.CopyOCF(upload, download)
+//     if err != nil {
+//         upload.Abort()
+//         return err
+//     }
+//     if !stats.Checksum {
+//         log.Print("OCF has no checksum; only its structure was verified")
+//     }
+//     return upload.Close()
+func CopyOCF(dst io.Writer, src io.Reader) (OCFCopyStats, error) {
+	var stats OCFCopyStats
+	var buf bytes.Buffer
+
+	header, err := readOCFHeader(io.TeeReader(src, &buf))
+	if err != nil {
+		return stats, fmt.Errorf("cannot copy OCF: %s", err)
+	}
+	var checksum hash.Hash // checksum of the blocks copied, when the OCF has one
+	var recorded []byte
+	if value, ok := header.metadata[ocfChecksumKey]; ok {
+		if checksum, recorded, err = parseOCFChecksum(value); err != nil {
+			return stats, fmt.Errorf("cannot copy OCF: %s", err)
+		}
+	}
+	if err = copyOCFBytes(dst, &buf, &stats); err != nil {
+		return stats, err
+	}
+
+	tee := io.TeeReader(src, &buf)
+	for {
+		blockCount, err := longBinaryReader(tee)
+		if err == io.EOF && buf.Len() == 0 {
+			break
+		}
+		if err != nil {
+			return stats, fmt.Errorf("cannot copy OCF: block %d: cannot read block count: %s", stats.Blocks, err)
+		}
+		if blockCount <= 0 || blockCount > MaxBlockCount {
+			return stats, fmt.Errorf("cannot copy OCF: block %d: block count ought to be from 1 to MaxBlockCount: %d", stats.Blocks, blockCount)
+		}
+		blockSize, err := longBinaryReader(tee)
+		if err != nil {
+			return stats, fmt.Errorf("cannot copy OCF: block %d: cannot read block size: %s", stats.Blocks, err)
+		}
+		if blockSize <= 0 || blockSize > MaxBlockSize {
+			return stats, fmt.Errorf("cannot copy OCF: block %d: block size ought to be from 1 to MaxBlockSize: %d", stats.Blocks, blockSize)
+		}
+		block := make([]byte, blockSize)
+		if _, err = io.ReadFull(tee, block); err != nil {
+			return stats, fmt.Errorf("cannot copy OCF: block %d: cannot read block: %s", stats.Blocks, err)
+		}
+		sync := make([]byte, ocfSyncLength)
+		if _, err = io.ReadFull(tee, sync); err != nil {
+			return stats, fmt.Errorf("cannot copy OCF: block %d: cannot read sync marker: %s", stats.Blocks, err)
+		}
+		if !bytes.Equal(sync, header.syncMarker[:]) {
+			return stats, fmt.Errorf("cannot copy OCF: block %d: sync marker mismatch: %v != %v", stats.Blocks, sync, header.syncMarker)
+		}
+		if err = verifyOCFBlock(header, blockCount, block); err != nil {
+			return stats, fmt.Errorf("cannot copy OCF: block %d: %s", stats.Blocks, err)
+		}
+		if checksum != nil {
+			hashOCFBlockPrefix(checksum, blockCount, blockSize)
+			_, _ = checksum.Write(block)
+			_, _ = checksum.Write(sync)
+		}
+		if err = copyOCFBytes(dst, &buf, &stats); err != nil {
+			return stats, err
+		}
+		stats.Blocks++
+		stats.Items += blockCount
+	}
+
+	if checksum != nil {
+		if err := verifyOCFChecksum(checksum, recorded); err != nil {
+			return stats, fmt.Errorf("cannot copy OCF: %s", err)
+		}
+		stats.Checksum = true
+	}
+	return stats, nil
+}
+
+// copyOCFBytes writes the verified bytes of buf to dst, and resets buf.
+func copyOCFBytes(dst io.Writer, buf *bytes.Buffer, stats *OCFCopyStats) error {
+	n, err := dst.Write(buf.Bytes())
+	stats.Bytes += int64(n)
+	buf.Reset()
+	if err != nil {
+		return fmt.Errorf("cannot copy OCF: %s", err)
+	}
+	return nil
+}
+
+// verifyOCFBlock returns an error unless the compressed block holds exactly
+// blockCount data items of the schema of the OCF.
+func verifyOCFBlock(header *ocfHeader, blockCount int64, block []byte) error {
+	decompressed, err := decompressOCFBlock(header.compressionID, block)
+	if err != nil {
+		return err
+	}
+	n, err := schemaNodeFromCodec(header.codec)
+	if err != nil {
+		return err
+	}
+	for i := int64(0); i < blockCount; i++ {
+		if decompressed, err = skipBinary(n, decompressed); err != nil {
+			return fmt.Errorf("data item %d: %s", i, err)
+		}
+	}
+	if len(decompressed) > 0 {
+		return fmt.Errorf("extra bytes after final data item: %d", len(decompressed))
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestCopyOCF(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goavro")
+	ensureError(t, err)
+	defer os.RemoveAll(dir)
+
+	contents := writeChecksumOCF(t, dir, OCFChecksumSHA256, true, []interface{}{1, 2}, []interface{}{3})
+	dst := new(bytes.Buffer)
+	stats, err := CopyOCF(dst, bytes.NewReader(contents))
+	ensureError(t, err)
+	if actual, expected := stats, (OCFCopyStats{Bytes: int64(len(contents)), Blocks: 2, Items: 3, Checksum: true}); actual != expected {
+		t.Errorf("GOT: %+v; WANT: %+v", actual, expected)
+	}
+	if !bytes.Equal(dst.Bytes(), contents) {
+		t.Errorf("GOT: %v; WANT: %v", dst.Bytes(), contents)
+	}
+
+	// without a checksum, only the structure is verified
+	bb := new(bytes.Buffer)
+	ocfw, err := NewOCFWriter(OCFConfig{W: bb, Schema: `"string"`, CompressionName: CompressionDeflateLabel})
+	ensureError(t, err)
+	ensureError(t, ocfw.Append([]interface{}{"hello", "world"}))
+	dst.Reset()
+	stats, err = CopyOCF(dst, bytes.NewReader(bb.Bytes()))
+	ensureError(t, err)
+	if stats.Checksum || stats.Items != 2 || !bytes.Equal(dst.Bytes(), bb.Bytes()) {
+		t.Errorf("GOT: %+v", stats)
+	}
+}
+
+func TestCopyOCFCorruption(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goavro")
+	ensureError(t, err)
+	defer os.RemoveAll(dir)
+
+	contents := writeChecksumOCF(t, dir, OCFChecksumCRC32, true, []interface{}{1, 2}, []interface{}{3})
+	// block 1 is the final 19 bytes: count, size, the datum, and the sync marker
+	secondBlock := len(contents) - 19
+
+	t.Run("checksum", func(t *testing.T) {
+		corrupted := append([]byte(nil), contents...)
+		corrupted[secondBlock+2] = 8 // 3 becomes 4, which the structure permits
+		_, err := CopyOCF(ioutil.Discard, bytes.NewReader(corrupted))
+		ensureError(t, err, "cannot copy OCF: OCF checksum mismatch")
+	})
+	t.Run("sync", func(t *testing.T) {
+		corrupted := append([]byte(nil), contents...)
+		corrupted[len(corrupted)-1] ^= 0xff
+		dst := new(bytes.Buffer)
+		stats, err := CopyOCF(dst, bytes.NewReader(corrupted))
+		ensureError(t, err, "cannot copy OCF: block 1: sync marker mismatch")
+		// the corrupted block is not written
+		if actual, expected := dst.Len(), secondBlock; actual != expected || stats.Bytes != int64(expected) {
+			t.Errorf("GOT: %v; WANT: %v", actual, expected)
+		}
+	})
+	t.Run("items", func(t *testing.T) {
+		corrupted := append([]byte(nil), contents...)
+		corrupted[secondBlock] = 4 // count of 2 items in a block of 1
+		_, err := CopyOCF(ioutil.Discard, bytes.NewReader(corrupted))
+		ensureError(t, err, "cannot copy OCF: block 1: data item 1: long: short buffer")
+	})
+	t.Run("truncated", func(t *testing.T) {
+		_, err := CopyOCF(ioutil.Discard, bytes.NewReader(contents[:len(contents)-5]))
+		ensureError(t, err, "cannot copy OCF: block 1: cannot read sync marker")
+	})
+	t.Run("unclosed", func(t *testing.T) {
+		unclosed := writeChecksumOCF(t, dir, OCFChecksumCRC32, false, []interface{}{1})
+		_, err := CopyOCF(ioutil.Discard, bytes.NewReader(unclosed))
+		ensureError(t, err, "OCF checksum was not recorded")
+	})
+	t.Run("header", func(t *testing.T) {
+		_, err := CopyOCF(ioutil.Discard, bytes.NewReader([]byte("nope")))
+		ensureError(t, err, "cannot copy OCF: cannot read OCF header with invalid magic bytes")
+	})
+}
@@ -0,0 +1,203 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// OCFIndexSchema is the schema of the items of an OCF block index, written by
+// an OCFWriter created with the Index configuration parameter. Each item
+// describes one block of the indexed OCF: the offset of the block from the
+// start of the file, and the offset of each datum from the start of the
+// decompressed block.
+const OCFIndexSchema = `{
+  "type": "record",
+  "name": "OCFBlockIndex",
+  "namespace": "com.linkedin.goavro",
+  "fields": [
+    {"name": "offset", "type": "long"},
+    {"name": "recordOffsets", "type": {"type": "array", "items": "long"}}
+  ]
+}`
+
+// OCFBlockIndex describes the position of one block of an OCF, and the position
+// of each datum in that block.
+type OCFBlockIndex struct {
+	Offset        int64   // offset of the block from the start of the OCF
+	RecordOffsets []int64 // offset of each datum from the start of the decompressed block
+}
+
+// createIndex creates the OCFWriter used to write the block index.
+func (ocfw *OCFWriter) createIndex(iow io.Writer, syncOnFlush bool) error {
+	var err error
+	ocfw.index, err = NewOCFWriter(OCFConfig{W: iow, Schema: OCFIndexSchema, SyncOnFlush: syncOnFlush})
+	if err != nil {
+		return fmt.Errorf("cannot create index: %s", err)
+	}
+	if ocfw.index.header.codec.Schema() != OCFIndexSchema {
+		return errors.New("cannot create index: existing index OCF has a different schema")
+	}
+	return nil
+}
+
+// ReadOCFIndex reads an OCF block index, written by an OCFWriter created with
+// the Index configuration parameter.
+func ReadOCFIndex(ior io.Reader) ([]OCFBlockIndex, error) {
+	ocfr, err := NewOCFReader(ior)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read OCF index: %s", err)
+	}
+	var index []OCFBlockIndex
+	for ocfr.Scan() {
+		datum, err := ocfr.Read()
+		if err != nil {
+			return nil, fmt.Errorf("cannot read OCF index: %s", err)
+		}
+		entry, ok := datum.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("cannot read OCF index: expected record; received: %T", datum)
+		}
+		offset, _ := entry["offset"].(int64)
+		items, _ := entry["recordOffsets"].([]interface{})
+		recordOffsets := make([]int64, len(items))
+		for i, item := range items {
+			recordOffsets[i], _ = item.(int64)
+		}
+		index = append(index, OCFBlockIndex{Offset: offset, RecordOffsets: recordOffsets})
+	}
+	if err = ocfr.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read OCF index: %s", err)
+	}
+	return index, nil
+}
+
+// OCFIndexedReader reads any datum of an OCF, located using the OCF's block
+// index, without decoding the data that precede it. This allows paginating
+// through large archives. The most recently read block is retained, so reading
+// consecutive data of a block decompresses that block once. An
+// OCFIndexedReader ought not to be used by multiple goroutines simultaneously.
+//
+//     func page(f *os.File, index []goavro.OCFBlockIndex, first, count int64) ([]interface{}, error) {
+//         ocfr, err := goavro.NewOCFIndexedReader(f, index)
+//         if err != nil {
+//             return nil, err
+//         }
+//         var data []interface{}
+//         for i := first; i < first+count && i < ocfr.Len(); i++ {
+//             datum, err := ocfr.Read(i)
+//             if err != nil {
+//                 return nil, err
+//             }
+//             data = append(data, datum)
+//         }
+//         return data, nil
+//     }
+type OCFIndexedReader struct {
+	header      *ocfHeader
+	ra          io.ReaderAt
+	index       []OCFBlockIndex
+	first       []int64 // position of the first datum of each block
+	count       int64   // number of data in all blocks
+	cachedIndex int     // index of the block in cachedBlock, or -1
+	cachedBlock []byte  // most recently read decompressed block
+}
+
+// NewOCFIndexedReader returns an OCFIndexedReader that reads the OCF from ra,
+// using its block index.
+func NewOCFIndexedReader(ra io.ReaderAt, index []OCFBlockIndex) (*OCFIndexedReader, error) {
+	header, err := readOCFHeader(io.NewSectionReader(ra, 0, math.MaxInt64))
+	if err != nil {
+		return nil, fmt.Errorf("cannot create OCFIndexedReader: %s", err)
+	}
+	ocfr := &OCFIndexedReader{header: header, ra: ra, index: index, first: make([]int64, len(index)), cachedIndex: -1}
+	for i, entry := range index {
+		ocfr.first[i] = ocfr.count
+		ocfr.count += int64(len(entry.RecordOffsets))
+	}
+	return ocfr, nil
+}
+
+// Codec returns the codec found within the OCF file.
+func (ocfr *OCFIndexedReader) Codec() *Codec {
+	return ocfr.header.codec
+}
+
+// Len returns the number of data in the indexed blocks.
+func (ocfr *OCFIndexedReader) Len() int64 {
+	return ocfr.count
+}
+
+// Read returns the datum at the specified position, counting from zero for the
+// first datum of the first indexed block.
+func (ocfr *OCFIndexedReader) Read(position int64) (interface{}, error) {
+	if position < 0 || position >= ocfr.count {
+		return nil, fmt.Errorf("cannot read datum %d: position ought to be between 0 and %d", position, ocfr.count-1)
+	}
+	// find the last block whose first datum is not after position
+	i := sort.Search(len(ocfr.first), func(i int) bool { return ocfr.first[i] > position }) - 1
+	for len(ocfr.index[i].RecordOffsets) == 0 {
+		i-- // NOTE: skip back over empty blocks that share the same first position
+	}
+	if i != ocfr.cachedIndex {
+		block, err := ocfr.readBlock(ocfr.index[i])
+		if err != nil {
+			return nil, fmt.Errorf("cannot read datum %d: %s", position, err)
+		}
+		ocfr.cachedIndex, ocfr.cachedBlock = i, block
+	}
+	offset := ocfr.index[i].RecordOffsets[position-ocfr.first[i]]
+	if offset < 0 || offset >= int64(len(ocfr.cachedBlock)) {
+		return nil, fmt.Errorf("cannot read datum %d: offset ought to be between 0 and %d; index offset: %d", position, len(ocfr.cachedBlock)-1, offset)
+	}
+	datum, _, err := ocfr.header.codec.nativeFromBinaryPrefix(ocfr.cachedBlock[offset:])
+	if err != nil {
+		return nil, fmt.Errorf("cannot read datum %d: %s", position, err)
+	}
+	return datum, nil
+}
+
+// readBlock reads, validates, and decompresses the block described by entry.
+func (ocfr *OCFIndexedReader) readBlock(entry OCFBlockIndex) ([]byte, error) {
+	ior := io.NewSectionReader(ocfr.ra, entry.Offset, math.MaxInt64-entry.Offset)
+	blockCount, err := longBinaryReader(ior)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read block count: %s", err)
+	}
+	if blockCount != int64(len(entry.RecordOffsets)) {
+		return nil, fmt.Errorf("block count does not match index: %d != %d", blockCount, len(entry.RecordOffsets))
+	}
+	blockSize, err := longBinaryReader(ior)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read block size: %s", err)
+	}
+	if blockSize <= 0 {
+		return nil, fmt.Errorf("cannot decode when block size is not greater than 0: %d", blockSize)
+	}
+	if blockSize > MaxBlockSize {
+		return nil, fmt.Errorf("cannot decode when block size exceeds MaxBlockSize: %d > %d", blockSize, MaxBlockSize)
+	}
+	buf := make([]byte, blockSize+ocfSyncLength)
+	if _, err = io.ReadFull(ior, buf); err != nil {
+		return nil, fmt.Errorf("cannot read block: %s", err)
+	}
+	if !bytes.Equal(buf[blockSize:], ocfr.header.syncMarker[:]) {
+		return nil, fmt.Errorf("sync marker mismatch: %v != %v", buf[blockSize:], ocfr.header.syncMarker)
+	}
+	return decompressOCFBlock(ocfr.header.compressionID, buf[:blockSize])
+}
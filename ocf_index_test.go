@@ -0,0 +1,121 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestOCFIndexedReader(t *testing.T) {
+	for _, compressionName := range []string{CompressionNullLabel, CompressionDeflateLabel, CompressionSnappyLabel} {
+		file, index := new(bytes.Buffer), new(bytes.Buffer)
+		ocfw, err := NewOCFWriter(OCFConfig{W: file, Index: index, Schema: `"string"`, CompressionName: compressionName})
+		ensureError(t, err)
+		ensureError(t, ocfw.Append([]string{"a", "bb", "ccc"}))
+		ensureError(t, ocfw.Append([]string{}))
+		ensureError(t, ocfw.Append([]string{"dddd", "e"}))
+
+		entries, err := ReadOCFIndex(index)
+		ensureError(t, err)
+		if len(entries) != 3 {
+			t.Fatalf("%s: GOT: %d entries; WANT: %d", compressionName, len(entries), 3)
+		}
+		if actual, expected := entries[0].RecordOffsets, []int64{0, 2, 5}; !int64sEqual(actual, expected) {
+			t.Errorf("%s: GOT: %v; WANT: %v", compressionName, actual, expected)
+		}
+
+		ocfr, err := NewOCFIndexedReader(bytes.NewReader(file.Bytes()), entries)
+		ensureError(t, err)
+		if actual, expected := ocfr.Len(), int64(5); actual != expected {
+			t.Errorf("%s: GOT: %v; WANT: %v", compressionName, actual, expected)
+		}
+		expected := []string{"a", "bb", "ccc", "dddd", "e"}
+		// read in reverse, so blocks are not read in order
+		for position := int64(4); position >= 0; position-- {
+			datum, err := ocfr.Read(position)
+			ensureError(t, err)
+			if datum != expected[position] {
+				t.Errorf("%s: GOT: %v; WANT: %v", compressionName, datum, expected[position])
+			}
+		}
+		_, err = ocfr.Read(5)
+		ensureError(t, err, "cannot read datum 5", "between 0 and 4")
+	}
+}
+
+func TestOCFIndexAppendToFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goavro")
+	ensureError(t, err)
+	defer os.RemoveAll(dir)
+
+	write := func(data []interface{}) {
+		t.Helper()
+		file, err := os.OpenFile(dir+"/data.avro", os.O_CREATE|os.O_RDWR, 0666)
+		ensureError(t, err)
+		defer file.Close()
+		index, err := os.OpenFile(dir+"/data.avro.index", os.O_CREATE|os.O_RDWR, 0666)
+		ensureError(t, err)
+		defer index.Close()
+		ocfw, err := NewOCFWriter(OCFConfig{W: file, Index: index, Schema: `"long"`})
+		ensureError(t, err)
+		ensureError(t, ocfw.Append(data))
+	}
+	write([]interface{}{1, 2})
+	write([]interface{}{3})
+
+	index, err := os.Open(dir + "/data.avro.index")
+	ensureError(t, err)
+	defer index.Close()
+	entries, err := ReadOCFIndex(index)
+	ensureError(t, err)
+
+	file, err := os.Open(dir + "/data.avro")
+	ensureError(t, err)
+	defer file.Close()
+	ocfr, err := NewOCFIndexedReader(file, entries)
+	ensureError(t, err)
+	for position, expected := range []int64{1, 2, 3} {
+		datum, err := ocfr.Read(int64(position))
+		ensureError(t, err)
+		if datum != expected {
+			t.Errorf("GOT: %v; WANT: %v", datum, expected)
+		}
+	}
+}
+
+func TestOCFIndexedReaderMismatch(t *testing.T) {
+	file := new(bytes.Buffer)
+	ocfw, err := NewOCFWriter(OCFConfig{W: file, Schema: `"long"`})
+	ensureError(t, err)
+	ensureError(t, ocfw.Append([]interface{}{1, 2}))
+
+	ocfr, err := NewOCFIndexedReader(bytes.NewReader(file.Bytes()), []OCFBlockIndex{{Offset: 0, RecordOffsets: []int64{0}}})
+	ensureError(t, err)
+	_, err = ocfr.Read(0)
+	ensureError(t, err, "cannot read datum 0", "block count")
+}
+
+func int64sEqual(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
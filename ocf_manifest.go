@@ -0,0 +1,114 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ocfManifest records the hash of every block of an OCF, so an OCFWriter may
+// skip the blocks it is asked to append again. Each line of the manifest has
+// the offset of the end of a block in the OCF, a space, and the hex encoded
+// SHA-256 hash of the block count and the compressed block data.
+type ocfManifest struct {
+	w      io.Writer
+	syncer syncer // syncs w after each line, when configured
+	hashes map[[sha256.Size]byte]struct{}
+	end    int64 // offset of the end of the final block recorded
+	lines  int64
+}
+
+// readOCFManifest reads the lines of the manifest, after which new lines are
+// written to it.
+func readOCFManifest(rw io.ReadWriter, syncOnFlush bool) (*ocfManifest, error) {
+	m := &ocfManifest{w: rw, hashes: make(map[[sha256.Size]byte]struct{})}
+	if syncOnFlush {
+		var ok bool
+		if m.syncer, ok = rw.(syncer); !ok {
+			return nil, fmt.Errorf("cannot read manifest: sync on flush requires manifest to have a Sync method; received: %T", rw)
+		}
+	}
+	scanner := bufio.NewScanner(rw)
+	for scanner.Scan() {
+		m.lines++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("cannot read manifest line %d: expected offset and hash: %q", m.lines, scanner.Text())
+		}
+		end, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil || end <= m.end {
+			return nil, fmt.Errorf("cannot read manifest line %d: offset ought to be greater than %d: %q", m.lines, m.end, fields[0])
+		}
+		var sum [sha256.Size]byte
+		if n, err := hex.Decode(sum[:], []byte(fields[1])); err != nil || n != sha256.Size {
+			return nil, fmt.Errorf("cannot read manifest line %d: hash ought to be %d hex encoded bytes: %q", m.lines, sha256.Size, fields[1])
+		}
+		m.hashes[sum] = struct{}{}
+		m.end = end
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read manifest: %s", err)
+	}
+	return m, nil
+}
+
+// has returns true when the manifest records a block with the hash.
+func (m *ocfManifest) has(sum [sha256.Size]byte) bool {
+	_, ok := m.hashes[sum]
+	return ok
+}
+
+// record appends the line of a block ending at the offset to the manifest.
+func (m *ocfManifest) record(sum [sha256.Size]byte, end int64) error {
+	if _, err := fmt.Fprintf(m.w, "%d %x\n", end, sum); err != nil {
+		return fmt.Errorf("cannot write manifest: %s", err)
+	}
+	if m.syncer != nil {
+		if err := m.syncer.Sync(); err != nil {
+			return fmt.Errorf("cannot sync manifest: %s", err)
+		}
+	}
+	m.hashes[sum] = struct{}{}
+	m.end = end
+	m.lines++
+	return nil
+}
+
+// ocfBlockHash returns the hash of a block used by the manifest.
+func ocfBlockHash(blockCount int64, block []byte) [sha256.Size]byte {
+	h := newOCFBlockHash(blockCount)
+	_, _ = h.Write(block)
+	return sumOCFBlockHash(h)
+}
+
+// newOCFBlockHash returns the hash of a block, to which its compressed data is
+// written.
+func newOCFBlockHash(blockCount int64) hash.Hash {
+	h := sha256.New()
+	buf, _ := longBinaryFromNative(nil, blockCount)
+	_, _ = h.Write(buf)
+	return h
+}
+
+func sumOCFBlockHash(h hash.Hash) [sha256.Size]byte {
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
@@ -0,0 +1,126 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+// appendManifestOCF opens the OCF and manifest files in dir, and appends each
+// batch of data, returning the number of blocks skipped.
+func appendManifestOCF(t *testing.T, dir string, manifest bool, data ...[]interface{}) int64 {
+	t.Helper()
+	file, err := os.OpenFile(dir+"/data.avro", os.O_RDWR|os.O_CREATE, 0644)
+	ensureError(t, err)
+	defer file.Close()
+	config := OCFConfig{W: file, Schema: `"long"`, CompressionName: CompressionDeflateLabel}
+	if manifest {
+		mf, err := os.OpenFile(dir+"/data.manifest", os.O_RDWR|os.O_CREATE, 0644)
+		ensureError(t, err)
+		defer mf.Close()
+		config.Manifest = mf
+	}
+	ocfw, err := NewOCFWriter(config)
+	ensureError(t, err)
+	for _, items := range data {
+		ensureError(t, ocfw.Append(items))
+	}
+	ensureError(t, ocfw.Close())
+	return ocfw.SkippedBlocks()
+}
+
+func readManifestOCF(t *testing.T, dir string) string {
+	t.Helper()
+	contents, err := ioutil.ReadFile(dir + "/data.avro")
+	ensureError(t, err)
+	ocfr, err := NewOCFReader(bytes.NewReader(contents))
+	ensureError(t, err)
+	var data []interface{}
+	for ocfr.Scan() {
+		datum, err := ocfr.Read()
+		ensureError(t, err)
+		data = append(data, datum)
+	}
+	ensureError(t, ocfr.Err())
+	return fmt.Sprint(data)
+}
+
+func TestOCFManifestSkipsRecordedBlocks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goavro")
+	ensureError(t, err)
+	defer os.RemoveAll(dir)
+
+	if skipped := appendManifestOCF(t, dir, true, []interface{}{1, 2}, []interface{}{3}); skipped != 0 {
+		t.Errorf("GOT: %v; WANT: %v", skipped, 0)
+	}
+	// retried job appends the same batches, followed by new ones
+	if skipped := appendManifestOCF(t, dir, true, []interface{}{1, 2}, []interface{}{3}, []interface{}{4}); skipped != 2 {
+		t.Errorf("GOT: %v; WANT: %v", skipped, 2)
+	}
+	if actual, expected := readManifestOCF(t, dir), "[1 2 3 4]"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	manifest, err := ioutil.ReadFile(dir + "/data.manifest")
+	ensureError(t, err)
+	if actual, expected := strings.Count(string(manifest), "\n"), 3; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
+
+func TestOCFManifestRecordsUnrecordedBlocks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goavro")
+	ensureError(t, err)
+	defer os.RemoveAll(dir)
+
+	appendManifestOCF(t, dir, true, []interface{}{1})
+	// block written without updating the manifest, as when a job crashes
+	// after writing a block
+	appendManifestOCF(t, dir, false, []interface{}{2, 3})
+	if skipped := appendManifestOCF(t, dir, true, []interface{}{1}, []interface{}{2, 3}, []interface{}{4}); skipped != 2 {
+		t.Errorf("GOT: %v; WANT: %v", skipped, 2)
+	}
+	if actual, expected := readManifestOCF(t, dir), "[1 2 3 4]"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
+
+func TestOCFManifestErrors(t *testing.T) {
+	t.Run("new OCF", func(t *testing.T) {
+		_, err := NewOCFWriter(OCFConfig{W: new(bytes.Buffer), Schema: `"long"`, Manifest: bytes.NewBufferString("10 " + strings.Repeat("00", 32) + "\n")})
+		ensureError(t, err, "manifest ought to be empty for a new OCF")
+	})
+	t.Run("malformed", func(t *testing.T) {
+		_, err := NewOCFWriter(OCFConfig{W: new(bytes.Buffer), Schema: `"long"`, Manifest: bytes.NewBufferString("10 abc\n")})
+		ensureError(t, err, "cannot read manifest line 1", "hash ought to be 32 hex encoded bytes")
+	})
+	t.Run("mismatch", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "goavro")
+		ensureError(t, err)
+		defer os.RemoveAll(dir)
+		appendManifestOCF(t, dir, false, []interface{}{1})
+		ensureError(t, ioutil.WriteFile(dir+"/data.manifest", []byte("7 "+strings.Repeat("00", 32)+"\n"), 0644))
+		file, err := os.OpenFile(dir+"/data.avro", os.O_RDWR, 0644)
+		ensureError(t, err)
+		defer file.Close()
+		mf, err := os.OpenFile(dir+"/data.manifest", os.O_RDWR, 0644)
+		ensureError(t, err)
+		defer mf.Close()
+		_, err = NewOCFWriter(OCFConfig{W: file, Manifest: mf})
+		ensureError(t, err, "manifest records a block ending at offset 7")
+	})
+}
@@ -0,0 +1,159 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// OCFSourceOffsetsKey is the OCF metadata key of the source offsets of the
+// data of the OCF, whose value is the JSON encoding of a SourceOffsets.
+const OCFSourceOffsetsKey = "goavro.source.offsets"
+
+// SourceOffsetRange is a range of offsets of a partitioned source, such as a
+// Kafka topic, from First to Last inclusive.
+type SourceOffsetRange struct {
+	Topic     string `json:"topic"`
+	Partition int32  `json:"partition"`
+	First     int64  `json:"first"`
+	Last      int64  `json:"last"`
+}
+
+// SourceOffsets lists the ranges of source offsets whose data an OCF holds,
+// so a pipeline that re-ingests a source may determine which offsets were
+// already written, and skip them. The offsets are recorded in the OCF header
+// when the OCF is created, so they describe the data of OCFs written for a
+// known batch of source records.
+//
+//     metadata := make(map[string][]byte)
+//     offsets := goavro.SourceOffsets{{Topic: "clicks", Partition: 3, First: 1000, Last: 1999}}
+//     if err := offsets.AddTo(metadata); err != nil {
+//         return err
+//     }
+//     ocfw, err := goavro.NewOCFWriter(goavro.OCFConfig{W: file, Codec: codec, MetaData: metadata})
+//
+// When reading the OCF, or listing the OCFs already written:
+//
+//     offsets, err := ocfr.SourceOffsets()
+//     if err != nil {
+//         return err
+//     }
+//     if offsets.Contains("clicks", 3, 1500) {
+//         // offset 1500 was already written
+//     }
+type SourceOffsets []SourceOffsetRange
+
+// SourceOffsetsFromMetaData returns the source offsets recorded in OCF
+// metadata, or nil when none are recorded.
+func SourceOffsetsFromMetaData(metadata map[string][]byte) (SourceOffsets, error) {
+	value, ok := metadata[OCFSourceOffsetsKey]
+	if !ok {
+		return nil, nil
+	}
+	var offsets SourceOffsets
+	if err := json.Unmarshal(value, &offsets); err != nil {
+		return nil, fmt.Errorf("cannot read source offsets: %s", err)
+	}
+	if err := offsets.validate(); err != nil {
+		return nil, fmt.Errorf("cannot read source offsets: %s", err)
+	}
+	return offsets, nil
+}
+
+// SourceOffsets returns the source offsets recorded in the metadata of the
+// OCF, or nil when none are recorded.
+func (ocfr *OCFReader) SourceOffsets() (SourceOffsets, error) {
+	return SourceOffsetsFromMetaData(ocfr.header.metadata)
+}
+
+// AddTo records the source offsets in the metadata used to create an OCF,
+// replacing the offsets it already records.
+func (offsets SourceOffsets) AddTo(metadata map[string][]byte) error {
+	if err := offsets.validate(); err != nil {
+		return fmt.Errorf("cannot record source offsets: %s", err)
+	}
+	value, err := json.Marshal(offsets.Merge())
+	if err != nil {
+		return fmt.Errorf("cannot record source offsets: %s", err) // should not get here
+	}
+	metadata[OCFSourceOffsetsKey] = value
+	return nil
+}
+
+// Contains returns true when a range includes the offset of the partition of
+// the topic.
+func (offsets SourceOffsets) Contains(topic string, partition int32, offset int64) bool {
+	for _, r := range offsets {
+		if r.Topic == topic && r.Partition == partition && r.First <= offset && offset <= r.Last {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the offset following the last offset of the partition of the
+// topic, which is where a pipeline resumes consuming the partition, and false
+// when no range has the partition.
+func (offsets SourceOffsets) Next(topic string, partition int32) (int64, bool) {
+	var next int64
+	var ok bool
+	for _, r := range offsets {
+		if r.Topic == topic && r.Partition == partition && (!ok || r.Last+1 > next) {
+			next, ok = r.Last+1, true
+		}
+	}
+	return next, ok
+}
+
+// Merge returns the ranges sorted by topic, partition, and offset, where
+// overlapping and adjacent ranges are combined, such as the offsets of several
+// OCFs. The receiver is not modified.
+func (offsets SourceOffsets) Merge() SourceOffsets {
+	sorted := append(SourceOffsets(nil), offsets...)
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Topic != b.Topic {
+			return a.Topic < b.Topic
+		}
+		if a.Partition != b.Partition {
+			return a.Partition < b.Partition
+		}
+		return a.First < b.First
+	})
+	var merged SourceOffsets
+	for _, r := range sorted {
+		if n := len(merged); n > 0 {
+			last := &merged[n-1]
+			if last.Topic == r.Topic && last.Partition == r.Partition && r.First <= last.Last+1 {
+				if r.Last > last.Last {
+					last.Last = r.Last
+				}
+				continue
+			}
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// validate returns an error when a range is empty or has a negative offset.
+func (offsets SourceOffsets) validate() error {
+	for _, r := range offsets {
+		if r.First < 0 || r.Last < r.First {
+			return fmt.Errorf("range of %q partition %d ought to have offsets such that 0 <= first <= last: %d, %d", r.Topic, r.Partition, r.First, r.Last)
+		}
+	}
+	return nil
+}
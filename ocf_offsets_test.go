@@ -0,0 +1,70 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestOCFSourceOffsetsRoundTrip(t *testing.T) {
+	metadata := map[string][]byte{"foo": []byte("bar")}
+	offsets := SourceOffsets{
+		{Topic: "clicks", Partition: 1, First: 10, Last: 19},
+		{Topic: "clicks", Partition: 0, First: 100, Last: 199},
+		{Topic: "clicks", Partition: 1, First: 20, Last: 29},
+	}
+	ensureError(t, offsets.AddTo(metadata))
+
+	buf := new(bytes.Buffer)
+	ocfw, err := NewOCFWriter(OCFConfig{W: buf, Schema: `"long"`, MetaData: metadata})
+	ensureError(t, err)
+	ensureError(t, ocfw.Append([]interface{}{1}))
+
+	ocfr, err := NewOCFReader(buf)
+	ensureError(t, err)
+	read, err := ocfr.SourceOffsets()
+	ensureError(t, err)
+	if actual, expected := fmt.Sprint(read), "[{clicks 0 100 199} {clicks 1 10 29}]"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if !read.Contains("clicks", 1, 25) || read.Contains("clicks", 1, 30) || read.Contains("views", 0, 150) {
+		t.Errorf("GOT: %v; WANT: offsets 10 to 29 of partition 1", read)
+	}
+	if next, ok := read.Next("clicks", 0); !ok || next != 200 {
+		t.Errorf("GOT: %v, %v; WANT: %v, %v", next, ok, 200, true)
+	}
+	if _, ok := read.Next("clicks", 2); ok {
+		t.Errorf("GOT: %v; WANT: %v", ok, false)
+	}
+}
+
+func TestOCFSourceOffsetsMissing(t *testing.T) {
+	offsets, err := SourceOffsetsFromMetaData(map[string][]byte{})
+	ensureError(t, err)
+	if offsets != nil {
+		t.Errorf("GOT: %v; WANT: %v", offsets, nil)
+	}
+}
+
+func TestOCFSourceOffsetsErrors(t *testing.T) {
+	err := SourceOffsets{{Topic: "clicks", First: 10, Last: 9}}.AddTo(map[string][]byte{})
+	ensureError(t, err, "cannot record source offsets", "0 <= first <= last")
+
+	_, err = SourceOffsetsFromMetaData(map[string][]byte{OCFSourceOffsetsKey: []byte("{")})
+	ensureError(t, err, "cannot read source offsets")
+
+	_, err = SourceOffsetsFromMetaData(map[string][]byte{OCFSourceOffsetsKey: []byte(`[{"topic":"clicks","first":-1,"last":3}]`)})
+	ensureError(t, err, "cannot read source offsets", "0 <= first <= last")
+}
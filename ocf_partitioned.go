@@ -0,0 +1,346 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// PartitionFunc returns the partition of a datum, from zero to one less than
+// the number of partitions.
+type PartitionFunc func(datum interface{}, partitions int) (int, error)
+
+// PartitionByFieldHash returns a PartitionFunc that partitions records by the
+// hash of the value of the named field, so the records with the same value are
+// written to the same partition. A union value is hashed as the value of its
+// member.
+func PartitionByFieldHash(field string) PartitionFunc {
+	return func(datum interface{}, partitions int) (int, error) {
+		record, ok := datum.(map[string]interface{})
+		if !ok {
+			return 0, fmt.Errorf("cannot partition by field %q: expected map[string]interface{}; received: %T", field, datum)
+		}
+		value := record[field]
+		if _, v, ok := unwrapUnion(value); ok {
+			value = v
+		}
+		h := fnv.New32a()
+		switch v := value.(type) {
+		case string:
+			_, _ = h.Write([]byte(v))
+		case []byte:
+			_, _ = h.Write(v)
+		default:
+			_, _ = fmt.Fprint(h, v)
+		}
+		return int(h.Sum32() % uint32(partitions)), nil
+	}
+}
+
+// PartitionedWriterConfig is used to specify creation parameters for
+// PartitionedWriter.
+type PartitionedWriterConfig struct {
+	// Dir specifies the directory in which to create the OCF files,
+	// (required).
+	Dir string
+
+	// Name returns the name of the file created in Dir for the specified
+	// partition, and the specified sequence number of the file in that
+	// partition, which counts from zero, (optional). Files are not
+	// overwritten, so a writer restarted in the same Dir ought to use
+	// different names. When omitted, files are named like
+	// "part-00003-000001.avro".
+	Name func(partition, sequence int) string
+
+	// Partitions specifies the number of partitions, (required unless Spec
+	// is used).
+	Partitions int
+
+	// Partition returns the partition of each datum, (required unless Spec
+	// is used), such as the function returned by PartitionByFieldHash.
+	Partition PartitionFunc
+
+	// Spec routes each datum to the partition of its partition path, such as
+	// "dt=2019-06-01/hour=13", rather than by Partition, (optional). The
+	// files of each partition are created in the directory of its path in
+	// Dir, and partitions are numbered in the order their first datum is
+	// appended. When Partitions is not zero, it limits the number of
+	// partitions.
+	Spec *PartitionSpec
+
+	// OCF specifies the configuration of each OCFWriter, such as its Codec
+	// or Schema, and its CompressionName. Its W, Index, and Manifest are
+	// ignored, because they describe a single file.
+	OCF OCFConfig
+
+	// RollRecords specifies the number of data items after which the file of
+	// a partition is closed, and the following items are written to a new
+	// file, (optional). When zero, files are not rolled by count.
+	RollRecords int64
+
+	// RollBytes specifies the size of a file after which it is closed, and
+	// the following items are written to a new file, (optional). The size is
+	// checked after each block, so files may exceed RollBytes by the size of
+	// one block. When zero, files are not rolled by size.
+	RollBytes int64
+}
+
+// PartitionedWriter routes data items to one of several OCF files by a
+// partition function, such as the hash of a field, creating, naming, and
+// rolling the files of each partition. It may be used by multiple goroutines
+// simultaneously, and data items of different partitions are written
+// concurrently.
+//
+//     pw, err := goavro.NewPartitionedWriter(goavro.PartitionedWriterConfig{
+//         Dir:         "/var/data/clicks",
+//         Partitions:  8,
+//         Partition:   goavro.PartitionByFieldHash("user_id"),
+//         OCF:         goavro.OCFConfig{Codec: codec, CompressionName: goavro.CompressionSnappyLabel},
+//         RollRecords: 1000000,
+//     })
+//     if err != nil {
+//         return err
+//     }
+//     if err = pw.Append(records); err != nil {
+//         return err
+//     }
+//     return pw.Close()
+type PartitionedWriter struct {
+	config PartitionedWriterConfig
+
+	mu         sync.Mutex
+	partitions []*writerPartition
+	paths      map[string]*writerPartition // partitions of each path, when Spec is used
+	files      []string                    // files created, in the order they were created
+	closed     bool
+}
+
+// writerPartition is the file of one partition of a PartitionedWriter.
+type writerPartition struct {
+	mu       sync.Mutex
+	index    int
+	dir      string // directory of the files of the partition
+	sequence int    // sequence number of the next file
+	file     *os.File
+	ocfw     *OCFWriter
+	records  int64 // number of data items written to file
+}
+
+// NewPartitionedWriter returns a PartitionedWriter. Files are created when the
+// first data item of their partition is appended.
+func NewPartitionedWriter(config PartitionedWriterConfig) (*PartitionedWriter, error) {
+	if config.Dir == "" {
+		return nil, errors.New("cannot create PartitionedWriter: Dir is empty")
+	}
+	if config.Spec == nil {
+		if config.Partitions <= 0 {
+			return nil, fmt.Errorf("cannot create PartitionedWriter: partitions ought to be greater than 0: %d", config.Partitions)
+		}
+		if config.Partition == nil {
+			return nil, errors.New("cannot create PartitionedWriter: Partition is nil")
+		}
+	} else if config.Partitions < 0 {
+		return nil, fmt.Errorf("cannot create PartitionedWriter: partitions ought to be zero or positive: %d", config.Partitions)
+	}
+	if config.RollRecords < 0 || config.RollBytes < 0 {
+		return nil, fmt.Errorf("cannot create PartitionedWriter: roll limits ought to be zero or positive: %d, %d", config.RollRecords, config.RollBytes)
+	}
+	if config.Name == nil {
+		config.Name = func(partition, sequence int) string {
+			return fmt.Sprintf("part-%05d-%06d.avro", partition, sequence)
+		}
+	}
+	pw := &PartitionedWriter{config: config}
+	if config.Spec != nil {
+		pw.paths = make(map[string]*writerPartition)
+		return pw, nil
+	}
+	pw.partitions = make([]*writerPartition, config.Partitions)
+	for i := range pw.partitions {
+		pw.partitions[i] = &writerPartition{index: i, dir: config.Dir}
+	}
+	return pw, nil
+}
+
+// Append appends one or more data items, routing each to the file of its
+// partition. The data items of each partition are appended in the order they
+// are provided, and when Append returns an error, the items of other
+// partitions may have been written.
+func (pw *PartitionedWriter) Append(data interface{}) error {
+	values, err := convertArray(data)
+	if err != nil {
+		return err
+	}
+	batches := make(map[*writerPartition][]interface{})
+	var touched []*writerPartition
+	for _, datum := range values {
+		wp, err := pw.partition(datum)
+		if err != nil {
+			return fmt.Errorf("cannot append: %s", err)
+		}
+		if _, ok := batches[wp]; !ok {
+			touched = append(touched, wp)
+		}
+		batches[wp] = append(batches[wp], datum)
+	}
+	sort.Slice(touched, func(i, j int) bool { return touched[i].index < touched[j].index })
+	for _, wp := range touched {
+		if err = wp.append(pw, batches[wp]); err != nil {
+			return fmt.Errorf("cannot append to partition %d: %s", wp.index, err)
+		}
+	}
+	return nil
+}
+
+// partition returns the partition of the datum.
+func (pw *PartitionedWriter) partition(datum interface{}) (*writerPartition, error) {
+	if pw.config.Spec == nil {
+		p, err := pw.config.Partition(datum, pw.config.Partitions)
+		if err != nil {
+			return nil, err
+		}
+		if p < 0 || p >= pw.config.Partitions {
+			return nil, fmt.Errorf("partition ought to be from 0 to %d: %d", pw.config.Partitions-1, p)
+		}
+		return pw.partitions[p], nil
+	}
+	path, err := pw.config.Spec.Path(datum)
+	if err != nil {
+		return nil, err
+	}
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	wp, ok := pw.paths[path]
+	if !ok {
+		if pw.config.Partitions > 0 && len(pw.partitions) >= pw.config.Partitions {
+			return nil, fmt.Errorf("partition path %q exceeds the limit of %d partitions", path, pw.config.Partitions)
+		}
+		wp = &writerPartition{index: len(pw.partitions), dir: filepath.Join(pw.config.Dir, filepath.FromSlash(path))}
+		pw.partitions = append(pw.partitions, wp)
+		pw.paths[path] = wp
+	}
+	return wp, nil
+}
+
+func (wp *writerPartition) append(pw *PartitionedWriter, data []interface{}) error {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	for len(data) > 0 {
+		if wp.ocfw == nil {
+			if err := wp.open(pw); err != nil {
+				return err
+			}
+		}
+		batch := data
+		if limit := pw.config.RollRecords; limit > 0 && wp.records+int64(len(batch)) > limit {
+			batch = batch[:limit-wp.records]
+		}
+		if err := wp.ocfw.Append(batch); err != nil {
+			return err
+		}
+		wp.records += int64(len(batch))
+		data = data[len(batch):]
+		if (pw.config.RollRecords > 0 && wp.records >= pw.config.RollRecords) ||
+			(pw.config.RollBytes > 0 && wp.ocfw.offset >= pw.config.RollBytes) {
+			if err := wp.close(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// open creates the next file of the partition.
+func (wp *writerPartition) open(pw *PartitionedWriter) error {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	if pw.closed {
+		return errors.New("PartitionedWriter is closed")
+	}
+	if err := os.MkdirAll(wp.dir, 0755); err != nil {
+		return fmt.Errorf("cannot create directory: %s", err)
+	}
+	name := filepath.Join(wp.dir, pw.config.Name(wp.index, wp.sequence))
+	file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("cannot create file: %s", err)
+	}
+	config := pw.config.OCF
+	config.W, config.Index, config.Manifest = file, nil, nil
+	ocfw, err := NewOCFWriter(config)
+	if err != nil {
+		_ = file.Close()
+		_ = os.Remove(name)
+		return err
+	}
+	wp.file, wp.ocfw, wp.records = file, ocfw, 0
+	wp.sequence++
+	pw.files = append(pw.files, name)
+	return nil
+}
+
+// close closes the current file of the partition, if any.
+func (wp *writerPartition) close() error {
+	if wp.ocfw == nil {
+		return nil
+	}
+	var errs []error
+	if err := wp.ocfw.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := wp.file.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	name := wp.file.Name()
+	wp.file, wp.ocfw = nil, nil
+	if len(errs) > 0 {
+		return fmt.Errorf("cannot close %q: %s", name, joinErrors(errs))
+	}
+	return nil
+}
+
+// Files returns the names of the files created so far, in the order they were
+// created.
+func (pw *PartitionedWriter) Files() []string {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	return append([]string(nil), pw.files...)
+}
+
+// Close closes the files of every partition, after which data items may no
+// longer be appended. It attempts to close every file, even after one of them
+// fails, and returns an error describing every failure.
+func (pw *PartitionedWriter) Close() error {
+	pw.mu.Lock()
+	pw.closed = true
+	partitions := pw.partitions
+	pw.mu.Unlock()
+	var errs []error
+	for _, wp := range partitions {
+		wp.mu.Lock()
+		if err := wp.close(); err != nil {
+			errs = append(errs, err)
+		}
+		wp.mu.Unlock()
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("cannot close PartitionedWriter: %s", joinErrors(errs))
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+const clickSchema = `{"type":"record","name":"Click","fields":[{"name":"user","type":"string"},{"name":"n","type":"long"}]}`
+
+// readPartitionedFiles returns the data items of each file.
+func readPartitionedFiles(t *testing.T, files []string) [][]interface{} {
+	t.Helper()
+	var contents [][]interface{}
+	for _, name := range files {
+		fh, err := os.Open(name)
+		ensureError(t, err)
+		ocfr, err := NewOCFReader(fh)
+		ensureError(t, err)
+		var data []interface{}
+		for ocfr.Scan() {
+			datum, err := ocfr.Read()
+			ensureError(t, err)
+			data = append(data, datum)
+		}
+		ensureError(t, ocfr.Err())
+		ensureError(t, fh.Close())
+		contents = append(contents, data)
+	}
+	return contents
+}
+
+func TestPartitionedWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goavro")
+	ensureError(t, err)
+	defer os.RemoveAll(dir)
+
+	pw, err := NewPartitionedWriter(PartitionedWriterConfig{
+		Dir:         dir,
+		Partitions:  2,
+		Partition:   PartitionByFieldHash("user"),
+		OCF:         OCFConfig{Schema: clickSchema},
+		RollRecords: 2,
+	})
+	ensureError(t, err)
+	var data []interface{}
+	for i := 0; i < 10; i++ {
+		data = append(data, map[string]interface{}{"user": fmt.Sprintf("user%d", i%3), "n": int64(i)})
+	}
+	ensureError(t, pw.Append(data[:4]))
+	ensureError(t, pw.Append(data[4:]))
+	ensureError(t, pw.Close())
+
+	files := pw.Files()
+	partitionOfUser := make(map[string]string)
+	var total int
+	for i, items := range readPartitionedFiles(t, files) {
+		if len(items) == 0 || len(items) > 2 {
+			t.Errorf("%s: GOT: %d items; WANT: 1 or 2", files[i], len(items))
+		}
+		partition := filepath.Base(files[i])[:len("part-00000")]
+		for _, item := range items {
+			user := item.(map[string]interface{})["user"].(string)
+			if p, ok := partitionOfUser[user]; ok && p != partition {
+				t.Errorf("GOT: %s in %s and %s; WANT: one partition", user, p, partition)
+			}
+			partitionOfUser[user] = partition
+			total++
+		}
+	}
+	if total != len(data) {
+		t.Errorf("GOT: %v; WANT: %v", total, len(data))
+	}
+
+	err = pw.Append(data[:1])
+	ensureError(t, err, "PartitionedWriter is closed")
+}
+
+func TestPartitionedWriterConcurrent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goavro")
+	ensureError(t, err)
+	defer os.RemoveAll(dir)
+
+	pw, err := NewPartitionedWriter(PartitionedWriterConfig{
+		Dir:        dir,
+		Partitions: 4,
+		Partition: func(datum interface{}, partitions int) (int, error) {
+			return int(datum.(map[string]interface{})["n"].(int64)) % partitions, nil
+		},
+		OCF:       OCFConfig{Schema: clickSchema},
+		RollBytes: 100,
+	})
+	ensureError(t, err)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				if err := pw.Append([]interface{}{map[string]interface{}{"user": "u", "n": int64(g*50 + i)}}); err != nil {
+					t.Error(err)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	ensureError(t, pw.Close())
+
+	var total int
+	for _, items := range readPartitionedFiles(t, pw.Files()) {
+		total += len(items)
+	}
+	if total != 400 {
+		t.Errorf("GOT: %v; WANT: %v", total, 400)
+	}
+}
+
+func TestPartitionByFieldHashUnionValue(t *testing.T) {
+	partition := PartitionByFieldHash("user")
+	wrapped, err := partition(map[string]interface{}{"user": map[string]interface{}{"string": "ada"}}, 16)
+	ensureError(t, err)
+	unwrapped, err := partition(map[string]interface{}{"user": UnionValue{Type: "string", Value: "ada"}}, 16)
+	ensureError(t, err)
+	if wrapped != unwrapped {
+		t.Errorf("GOT: %v; WANT: %v", unwrapped, wrapped)
+	}
+}
+
+func TestPartitionedWriterErrors(t *testing.T) {
+	_, err := NewPartitionedWriter(PartitionedWriterConfig{Dir: "x", Partitions: 0, Partition: PartitionByFieldHash("user")})
+	ensureError(t, err, "partitions ought to be greater than 0")
+
+	dir, err := ioutil.TempDir("", "goavro")
+	ensureError(t, err)
+	defer os.RemoveAll(dir)
+	pw, err := NewPartitionedWriter(PartitionedWriterConfig{
+		Dir:        dir,
+		Partitions: 2,
+		Partition:  func(interface{}, int) (int, error) { return 2, nil },
+		OCF:        OCFConfig{Schema: clickSchema},
+	})
+	ensureError(t, err)
+	err = pw.Append([]interface{}{map[string]interface{}{"user": "u", "n": int64(1)}})
+	ensureError(t, err, "partition ought to be from 0 to 1: 2")
+
+	// files are not overwritten
+	ensureError(t, ioutil.WriteFile(filepath.Join(dir, "part-00000-000000.avro"), nil, 0644))
+	pw, err = NewPartitionedWriter(PartitionedWriterConfig{
+		Dir:        dir,
+		Partitions: 1,
+		Partition:  PartitionByFieldHash("user"),
+		OCF:        OCFConfig{Schema: clickSchema},
+	})
+	ensureError(t, err)
+	err = pw.Append([]interface{}{map[string]interface{}{"user": "u", "n": int64(1)}})
+	ensureError(t, err, "cannot append to partition 0", "cannot create file")
+}
+
+func TestPartitionedWriterSpec(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goavro")
+	ensureError(t, err)
+	defer os.RemoveAll(dir)
+
+	spec, err := NewPartitionSpec("user=user")
+	ensureError(t, err)
+	pw, err := NewPartitionedWriter(PartitionedWriterConfig{
+		Dir:        dir,
+		Spec:       spec,
+		Partitions: 2,
+		OCF:        OCFConfig{Schema: clickSchema},
+	})
+	ensureError(t, err)
+	ensureError(t, pw.Append([]interface{}{
+		map[string]interface{}{"user": "b", "n": int64(1)},
+		map[string]interface{}{"user": "a", "n": int64(2)},
+		map[string]interface{}{"user": "b", "n": int64(3)},
+	}))
+	err = pw.Append([]interface{}{map[string]interface{}{"user": "c", "n": int64(4)}})
+	ensureError(t, err, `partition path "user=c" exceeds the limit of 2 partitions`)
+	ensureError(t, pw.Close())
+
+	files := pw.Files()
+	var names []string
+	for _, name := range files {
+		rel, err := filepath.Rel(dir, name)
+		ensureError(t, err)
+		names = append(names, filepath.ToSlash(rel))
+	}
+	if actual, expected := fmt.Sprint(names), "[user=b/part-00000-000000.avro user=a/part-00001-000000.avro]"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if actual, expected := len(readPartitionedFiles(t, files)[0]), 2; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
@@ -7,29 +7,33 @@
 // distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
 // WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
 
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
 package goavro
 
 import (
 	"bytes"
-	"compress/flate"
-	"encoding/binary"
 	"errors"
 	"fmt"
-	"hash/crc32"
+	"hash"
 	"io"
-	"io/ioutil"
-
-	"github.com/golang/snappy"
 )
 
 // OCFReader structure is used to read Object Container Files (OCF).
 type OCFReader struct {
 	header              *ocfHeader
-	block               []byte // buffer from which decoding takes place
-	rerr                error  // most recent error that took place while reading bytes (unrecoverable)
+	resolution          *Resolution // resolves data to the reader schema, when one is configured
+	block               []byte      // buffer from which decoding takes place
+	rerr                error       // most recent error that took place while reading bytes (unrecoverable)
 	ior                 io.Reader
-	readReady           bool  // true after Scan and before Read
-	remainingBlockItems int64 // count of encoded data items remaining in block buffer to be decoded
+	readReady           bool          // true after Scan and before Read
+	remainingBlockItems int64         // count of encoded data items remaining in block buffer to be decoded
+	checksum            hash.Hash     // checksum of the blocks read, when the OCF has one
+	recordedChecksum    []byte        // checksum recorded in the OCF metadata
+	memory              memoryAccount // bytes of the decompressed block held in the memory budget, if any
+	blockOffsets        []int64       // offset of each block, when the reader is an io.ReadSeeker
+	blockIndexErr       error         // why the blocks could not be indexed, if any
 }
 
 // NewOCFReader initializes and returns a new structure used to read an Avro
@@ -53,11 +57,75 @@ type OCFReader struct {
 //         return ocfr.Err()
 //     }
 func NewOCFReader(ior io.Reader) (*OCFReader, error) {
+	return NewOCFReaderWithConfig(ior, OCFReaderConfig{})
+}
+
+// OCFReaderConfig is used to specify creation parameters for OCFReader.
+type OCFReaderConfig struct {
+	// CompressionBackends specifies, by compression algorithm name, the
+	// registered backend used to decompress blocks, (optional). Algorithms
+	// without an entry use their default implementation.
+	//
+	//     config := goavro.OCFReaderConfig{
+	//         CompressionBackends: map[string]string{"deflate": "zlib-cgo"},
+	//     }
+	CompressionBackends map[string]string
+
+	// CodecOption specifies the options of the Codec that decodes the data
+	// items, (optional), such as NumericDecodingWide, so generic code need
+	// not switch on the type of each numeric field. When nil, the data items
+	// are decoded like NewCodec decodes them.
+	CodecOption *CodecOption
+
+	// ReaderCodec specifies the schema the data items are read as,
+	// (optional), resolved from the schema of the OCF using the rules of
+	// NewResolution. This projects the data items, whatever their top level
+	// type, to the parts the reader needs, such as the fields of the records
+	// in a top level array, or the members of a top level union. When
+	// CodecOption is also specified, it applies to ReaderCodec.
+	ReaderCodec *Codec
+
+	// MemoryBudget bounds the bytes of decompressed blocks held at once by
+	// the OCFReaders sharing it, (optional). See MemoryBudget.
+	MemoryBudget *MemoryBudget
+}
+
+// NewOCFReaderWithConfig returns a new OCFReader, like NewOCFReader, using the
+// specified configuration.
+func NewOCFReaderWithConfig(ior io.Reader, config OCFReaderConfig) (*OCFReader, error) {
 	header, err := readOCFHeader(ior)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create OCFReader: %s", err)
 	}
-	return &OCFReader{header: header, ior: ior}, nil
+	if name, ok := compressionName(header.compressionID); ok {
+		if header.compressionID, err = selectCompressionBackend(header.compressionID, config.CompressionBackends[name]); err != nil {
+			return nil, fmt.Errorf("cannot create OCFReader: %s", err)
+		}
+	}
+	reader := config.ReaderCodec
+	if config.CodecOption != nil {
+		if reader != nil {
+			if reader, err = reader.WithOptions(WithCodecOption(config.CodecOption)); err != nil {
+				return nil, fmt.Errorf("cannot create OCFReader: %s", err)
+			}
+		} else if header.codec, err = header.codec.WithOptions(WithCodecOption(config.CodecOption)); err != nil {
+			return nil, fmt.Errorf("cannot create OCFReader: %s", err)
+		}
+	}
+	ocfr := &OCFReader{header: header, ior: ior}
+	ocfr.memory.budget = config.MemoryBudget
+	if reader != nil {
+		if ocfr.resolution, err = NewResolution(header.codec, reader); err != nil {
+			return nil, fmt.Errorf("cannot create OCFReader: %s", err)
+		}
+	}
+	if value, ok := header.metadata[ocfChecksumKey]; ok {
+		if ocfr.checksum, ocfr.recordedChecksum, err = parseOCFChecksum(value); err != nil {
+			return nil, fmt.Errorf("cannot create OCFReader: %s", err)
+		}
+	}
+	ocfr.indexBlocks()
+	return ocfr, nil
 }
 
 //MetaData returns the file metadata map found within the OCF file
@@ -65,24 +133,39 @@ func (ocfr *OCFReader) MetaData() map[string][]byte {
 	return ocfr.header.metadata
 }
 
-// Codec returns the codec found within the OCF file.
+// SchemaGovernance returns the governance metadata recorded in the metadata of
+// the OCF, or when none are recorded, the governance metadata embedded in the
+// schema of the OCF.
+func (ocfr *OCFReader) SchemaGovernance() (SchemaGovernance, error) {
+	g, err := SchemaGovernanceFromMetaData(ocfr.header.metadata)
+	if err != nil || !g.IsZero() {
+		return g, err
+	}
+	return ocfr.header.codec.SchemaGovernance()
+}
+
+// Codec returns the codec found within the OCF file. When the OCFReader was
+// created with a ReaderCodec, the data items are decoded by that codec
+// instead.
 func (ocfr *OCFReader) Codec() *Codec {
 	return ocfr.header.codec
 }
 
+// readerCodec returns the codec that decodes the data items.
+func (ocfr *OCFReader) readerCodec() *Codec {
+	if ocfr.resolution != nil {
+		return ocfr.resolution.Reader()
+	}
+	return ocfr.header.codec
+}
+
 // CompressionName returns the name of the compression algorithm found within
 // the OCF file.
 func (ocfr *OCFReader) CompressionName() string {
-	switch ocfr.header.compressionID {
-	case compressionNull:
-		return CompressionNullLabel
-	case compressionDeflate:
-		return CompressionDeflateLabel
-	case compressionSnappy:
-		return CompressionSnappyLabel
-	default:
-		return "should not get here: unrecognized compression algorithm"
+	if name, ok := compressionName(ocfr.header.compressionID); ok {
+		return name
 	}
+	return "should not get here: unrecognized compression algorithm"
 }
 
 // Err returns the last error encountered while reading the OCF file.  See
@@ -108,7 +191,11 @@ func (ocfr *OCFReader) Read() (interface{}, error) {
 
 	// decode one datum value from block
 	var datum interface{}
-	datum, ocfr.block, ocfr.rerr = ocfr.header.codec.NativeFromBinary(ocfr.block)
+	if ocfr.resolution != nil {
+		datum, ocfr.block, ocfr.rerr = ocfr.resolution.NativeFromBinary(ocfr.block)
+	} else {
+		datum, ocfr.block, ocfr.rerr = ocfr.header.codec.nativeFromBinaryPrefix(ocfr.block)
+	}
 	if ocfr.rerr != nil {
 		return false, ocfr.rerr
 	}
@@ -117,6 +204,82 @@ func (ocfr *OCFReader) Read() (interface{}, error) {
 	return datum, nil
 }
 
+// ReadArray reads one datum like Read, for OCF files whose data items are
+// arrays, and returns its items. It returns an error without reading the
+// datum when the data items are not arrays.
+func (ocfr *OCFReader) ReadArray() ([]interface{}, error) {
+	if err := ocfr.checkTopLevelType("array"); err != nil {
+		return nil, err
+	}
+	datum, err := ocfr.Read()
+	if err != nil {
+		return nil, err
+	}
+	return datum.([]interface{}), nil
+}
+
+// ReadMap reads one datum like Read, for OCF files whose data items are maps,
+// and returns it as a Go map, even when the reading Codec decodes maps as
+// OrderedMap values. It returns an error without reading the datum when the
+// data items are not maps.
+func (ocfr *OCFReader) ReadMap() (map[string]interface{}, error) {
+	if err := ocfr.checkTopLevelType("map"); err != nil {
+		return nil, err
+	}
+	datum, err := ocfr.Read()
+	if err != nil {
+		return nil, err
+	}
+	if items, ok := datum.(OrderedMap); ok {
+		return items.Map(), nil
+	}
+	return datum.(map[string]interface{}), nil
+}
+
+// ReadUnion reads one datum like Read, for OCF files whose data items are
+// unions, and returns the name of the member type of the datum, such as
+// "null", "long", or the full name of a record, along with the unwrapped
+// value of the datum. It returns an error without reading the datum when the
+// data items are not unions.
+//
+//     for ocfr.Scan() {
+//         name, value, err := ocfr.ReadUnion()
+//         if err != nil {
+//             return err
+//         }
+//         switch name {
+//         case "com.example.Click":
+//             clicks = append(clicks, value.(map[string]interface{}))
+//         case "com.example.View":
+//             views = append(views, value.(map[string]interface{}))
+//         }
+//     }
+func (ocfr *OCFReader) ReadUnion() (string, interface{}, error) {
+	if err := ocfr.checkTopLevelType("union"); err != nil {
+		return "", nil, err
+	}
+	datum, err := ocfr.Read()
+	if err != nil {
+		return "", nil, err
+	}
+	if datum == nil {
+		return "null", nil, nil
+	}
+	if name, value, ok := unwrapUnion(datum); ok {
+		return name, value, nil
+	}
+	return "", nil, fmt.Errorf("cannot read union: expected map[string]interface{} or UnionValue; received: %T", datum)
+}
+
+// checkTopLevelType returns an error when the data items are not of the
+// specified type, without recording it as the error of the OCFReader.
+func (ocfr *OCFReader) checkTopLevelType(typeName string) error {
+	if actual := ocfr.readerCodec().typeName.fullName; actual != typeName {
+		return fmt.Errorf("cannot read %s: OCF data items are %s", typeName, actual)
+	}
+	return nil
+}
+
 // RemainingBlockItems returns the number of items remaining in the block being
 // processed.
 func (ocfr *OCFReader) RemainingBlockItems() int64 {
@@ -141,6 +304,7 @@ func (ocfr *OCFReader) Scan() bool {
 			ocfr.rerr = fmt.Errorf("extra bytes between final datum in previous block and block sync marker: %d", count)
 			return false
 		}
+		ocfr.memory.releaseAll()
 
 		// Read the block count and update the number of remaining items for
 		// this block
@@ -148,6 +312,9 @@ func (ocfr *OCFReader) Scan() bool {
 		if ocfr.rerr != nil {
 			if ocfr.rerr == io.EOF {
 				ocfr.rerr = nil // merely end of file, rather than error
+				if ocfr.checksum != nil {
+					ocfr.rerr = verifyOCFChecksum(ocfr.checksum, ocfr.recordedChecksum)
+				}
 			} else {
 				ocfr.rerr = fmt.Errorf("cannot read block count: %s", ocfr.rerr)
 			}
@@ -183,47 +350,17 @@ func (ocfr *OCFReader) Scan() bool {
 			ocfr.rerr = fmt.Errorf("cannot read block: %s", ocfr.rerr)
 			return false
 		}
+		if ocfr.checksum != nil {
+			hashOCFBlockPrefix(ocfr.checksum, ocfr.remainingBlockItems, blockSize)
+			_, _ = ocfr.checksum.Write(ocfr.block)
+		}
 
-		switch ocfr.header.compressionID {
-		case compressionNull:
-			// no-op
-
-		case compressionDeflate:
-			// NOTE: flate.NewReader wraps with io.ByteReader if argument does
-			// not implement that interface.
-			rc := flate.NewReader(bytes.NewBuffer(ocfr.block))
-			ocfr.block, ocfr.rerr = ioutil.ReadAll(rc)
-			if ocfr.rerr != nil {
-				_ = rc.Close()
-				return false
-			}
-			if ocfr.rerr = rc.Close(); ocfr.rerr != nil {
-				return false
-			}
-
-		case compressionSnappy:
-			index := len(ocfr.block) - 4 // last 4 bytes is crc32 of decoded block
-			if index <= 0 {
-				ocfr.rerr = fmt.Errorf("cannot decompress snappy without CRC32 checksum: %d", len(ocfr.block))
-				return false
-			}
-			decoded, err := snappy.Decode(nil, ocfr.block[:index])
-			if err != nil {
-				ocfr.rerr = fmt.Errorf("cannot decompress: %s", err)
-				return false
-			}
-			actualCRC := crc32.ChecksumIEEE(decoded)
-			expectedCRC := binary.BigEndian.Uint32(ocfr.block[index : index+4])
-			if actualCRC != expectedCRC {
-				ocfr.rerr = fmt.Errorf("snappy CRC32 checksum mismatch: %x != %x", actualCRC, expectedCRC)
-				return false
-			}
-			ocfr.block = decoded
-
-		default:
-			ocfr.rerr = fmt.Errorf("should not get here: cannot compress block using unrecognized compression: %d", ocfr.header.compressionID)
+		if ocfr.block, ocfr.rerr = decompressOCFBlock(ocfr.header.compressionID, ocfr.block); ocfr.rerr != nil {
+			return false
+		}
+		if ocfr.rerr = ocfr.memory.hold(int64(len(ocfr.block))); ocfr.rerr != nil {
+			ocfr.rerr = fmt.Errorf("cannot read block: %s", ocfr.rerr)
 			return false
-
 		}
 
 		// read and ensure sync marker matches
@@ -237,6 +374,9 @@ func (ocfr *OCFReader) Scan() bool {
 			ocfr.rerr = fmt.Errorf("sync marker mismatch: %v != %v", sync, ocfr.header.syncMarker)
 			return false
 		}
+		if ocfr.checksum != nil {
+			_, _ = ocfr.checksum.Write(sync)
+		}
 	}
 
 	ocfr.readReady = true
@@ -260,4 +400,24 @@ func (ocfr *OCFReader) SkipThisBlockAndReset() {
 	ocfr.remainingBlockItems = 0
 	ocfr.block = ocfr.block[:0]
 	ocfr.rerr = nil
+	ocfr.memory.releaseAll()
+}
+
+// MemoryUsage returns the approximate number of bytes of data held by the
+// reader, which is the size of its decompressed block.
+func (ocfr *OCFReader) MemoryUsage() int64 {
+	return ocfr.memory.held
+}
+
+// ReleaseMemory releases the bytes the reader holds in its MemoryBudget, for
+// readers abandoned before the end of the OCF. The reader ought not to be used
+// afterwards.
+func (ocfr *OCFReader) ReleaseMemory() {
+	ocfr.memory.releaseAll()
+	ocfr.block = nil
+	ocfr.remainingBlockItems = 0
+	ocfr.readReady = false
+	if ocfr.rerr == nil {
+		ocfr.rerr = errors.New("reader memory released")
+	}
 }
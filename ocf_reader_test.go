@@ -7,11 +7,16 @@
 // distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
 // WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
 
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
 package goavro
 
 import (
 	"bytes"
+	"fmt"
 	"testing"
+	"time"
 )
 
 // readOCFHeader, magic bytes
@@ -100,3 +105,264 @@ func TestReadOCFHeaderMetadataSyncMarker(t *testing.T) {
 // func TestOCFReaderRead(t *testing.T) {
 // 	testOCFReader(t,
 // }
+
+func TestOCFReaderCodecOption(t *testing.T) {
+	bb := new(bytes.Buffer)
+	ocfw, err := NewOCFWriter(OCFConfig{W: bb, Schema: `{"type":"record","name":"r","fields":[{"name":"i","type":"int"},{"name":"f","type":"float"}]}`})
+	ensureError(t, err)
+	ensureError(t, ocfw.Append([]interface{}{map[string]interface{}{"i": 3, "f": 1.5}}))
+
+	ocfr, err := NewOCFReaderWithConfig(bb, OCFReaderConfig{CodecOption: &CodecOption{NumericDecoding: NumericDecodingWide}})
+	ensureError(t, err)
+	if !ocfr.Scan() {
+		t.Fatalf("GOT: %v; WANT: %v", false, true)
+	}
+	datum, err := ocfr.Read()
+	ensureError(t, err)
+	record := datum.(map[string]interface{})
+	if actual, expected := record["i"], int64(3); actual != expected {
+		t.Errorf("GOT: %#v; WANT: %#v", actual, expected)
+	}
+	if actual, expected := record["f"], float64(1.5); actual != expected {
+		t.Errorf("GOT: %#v; WANT: %#v", actual, expected)
+	}
+}
+
+func TestOCFReaderTopLevelTypes(t *testing.T) {
+	newReader := func(schema string, data []interface{}, config OCFReaderConfig) *OCFReader {
+		t.Helper()
+		bb := new(bytes.Buffer)
+		ocfw, err := NewOCFWriter(OCFConfig{W: bb, Schema: schema})
+		ensureError(t, err)
+		ensureError(t, ocfw.Append(data))
+		ocfr, err := NewOCFReaderWithConfig(bb, config)
+		ensureError(t, err)
+		if !ocfr.Scan() {
+			t.Fatalf("GOT: %v; WANT: %v", false, true)
+		}
+		return ocfr
+	}
+
+	items, err := newReader(`{"type":"array","items":"long"}`, []interface{}{[]interface{}{1, 2}}, OCFReaderConfig{}).ReadArray()
+	ensureError(t, err)
+	if actual, expected := len(items), 2; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	values, err := newReader(`{"type":"map","values":"long"}`, []interface{}{map[string]interface{}{"a": 1}},
+		OCFReaderConfig{CodecOption: &CodecOption{OrderedMapDecoding: true}}).ReadMap()
+	ensureError(t, err)
+	if actual, expected := values["a"], int64(1); actual != expected {
+		t.Errorf("GOT: %#v; WANT: %#v", actual, expected)
+	}
+
+	ocfr := newReader(`["null","long",{"type":"record","name":"r","fields":[]}]`, []interface{}{nil, Union("long", 3)}, OCFReaderConfig{})
+	_, err = ocfr.ReadArray()
+	ensureError(t, err, "cannot read array: OCF data items are union")
+	name, value, err := ocfr.ReadUnion()
+	ensureError(t, err)
+	if name != "null" || value != nil {
+		t.Errorf("GOT: %v %v; WANT: null <nil>", name, value)
+	}
+	if !ocfr.Scan() {
+		t.Fatalf("GOT: %v; WANT: %v", false, true)
+	}
+	name, value, err = ocfr.ReadUnion()
+	ensureError(t, err)
+	if name != "long" || value != int64(3) {
+		t.Errorf("GOT: %v %#v; WANT: long 3", name, value)
+	}
+
+	ocfr = newReader(`["null","long"]`, []interface{}{Union("long", 3)}, OCFReaderConfig{CodecOption: &CodecOption{UnionValueDecoding: true}})
+	name, value, err = ocfr.ReadUnion()
+	ensureError(t, err)
+	if name != "long" || value != int64(3) {
+		t.Errorf("GOT: %v %#v; WANT: long 3", name, value)
+	}
+}
+
+func TestOCFReaderReaderCodec(t *testing.T) {
+	bb := new(bytes.Buffer)
+	ocfw, err := NewOCFWriter(OCFConfig{W: bb, Schema: `{"type":"array","items":{"type":"record","name":"r","fields":[{"name":"id","type":"int"},{"name":"payload","type":"string"}]}}`})
+	ensureError(t, err)
+	ensureError(t, ocfw.Append([]interface{}{[]interface{}{map[string]interface{}{"id": 3, "payload": "large"}}}))
+
+	encoded := append([]byte(nil), bb.Bytes()...)
+
+	// the reader only needs the id of each record
+	reader := newCodecUsingV2(t, `{"type":"array","items":{"type":"record","name":"r","fields":[{"name":"id","type":"int"}]}}`)
+	ocfr, err := NewOCFReaderWithConfig(bb, OCFReaderConfig{ReaderCodec: reader, CodecOption: &CodecOption{NumericDecoding: NumericDecodingWide}})
+	ensureError(t, err)
+	if !ocfr.Scan() {
+		t.Fatalf("GOT: %v; WANT: %v", false, true)
+	}
+	items, err := ocfr.ReadArray()
+	ensureError(t, err)
+	record := items[0].(map[string]interface{})
+	if actual, expected := len(record), 1; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", record, expected)
+	}
+	if actual, expected := record["id"], int64(3); actual != expected {
+		t.Errorf("GOT: %#v; WANT: %#v", actual, expected)
+	}
+
+	_, err = NewOCFReaderWithConfig(bytes.NewReader(encoded), OCFReaderConfig{ReaderCodec: newCodecUsingV2(t, `"string"`)})
+	ensureError(t, err, "cannot create OCFReader", "string")
+}
+
+func TestOCFReaderTrailingBytes(t *testing.T) {
+	bb := new(bytes.Buffer)
+	ocfw, err := NewOCFWriter(OCFConfig{W: bb, Schema: `{"type":"record","name":"r","fields":[{"name":"n","type":"long"}]}`})
+	ensureError(t, err)
+	ensureError(t, ocfw.Append([]interface{}{map[string]interface{}{"n": 1}, map[string]interface{}{"n": 2}, map[string]interface{}{"n": 3}}))
+	readerCodec, err := NewCodec(`{"type":"record","name":"r","fields":[{"name":"n","type":"long"}]}`)
+	ensureError(t, err)
+
+	for _, policy := range []TrailingBytes{TrailingBytesReturn, TrailingBytesError, TrailingBytesIgnore} {
+		for _, reader := range []*Codec{nil, readerCodec} {
+			ocfr, err := NewOCFReaderWithConfig(bytes.NewReader(bb.Bytes()), OCFReaderConfig{CodecOption: &CodecOption{TrailingBytes: policy}, ReaderCodec: reader})
+			ensureError(t, err)
+			var values []int64
+			for ocfr.Scan() {
+				datum, err := ocfr.Read()
+				ensureError(t, err)
+				values = append(values, datum.(map[string]interface{})["n"].(int64))
+			}
+			ensureError(t, ocfr.Err())
+			if actual, expected := values, []int64{1, 2, 3}; !int64sEqual(actual, expected) {
+				t.Errorf("policy %d; reader codec %t: GOT: %v; WANT: %v", policy, reader != nil, actual, expected)
+			}
+		}
+	}
+}
+
+func TestOCFSchemaGovernance(t *testing.T) {
+	metadata := map[string][]byte{OCFSchemaOwnerKey: []byte("stale")}
+	g := SchemaGovernance{Version: "7", Changelog: []SchemaChange{{Version: "7", Description: "initial"}}}
+	ensureError(t, g.AddTo(metadata))
+	if _, ok := metadata[OCFSchemaOwnerKey]; ok {
+		t.Errorf("GOT: %q; WANT: no owner", metadata[OCFSchemaOwnerKey])
+	}
+
+	buf := new(bytes.Buffer)
+	ocfw, err := NewOCFWriter(OCFConfig{W: buf, Schema: `{"type":"long","x-owner":"ops"}`, MetaData: metadata})
+	ensureError(t, err)
+	ensureError(t, ocfw.Append([]interface{}{1}))
+
+	// the header takes precedence over the schema
+	ocfr, err := NewOCFReader(bytes.NewReader(buf.Bytes()))
+	ensureError(t, err)
+	read, err := ocfr.SchemaGovernance()
+	ensureError(t, err)
+	if actual, expected := fmt.Sprint(read), "{ 7 [{7  initial}]}"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	buf.Reset()
+	ocfw, err = NewOCFWriter(OCFConfig{W: buf, Schema: `{"type":"long","x-owner":"ops"}`})
+	ensureError(t, err)
+	ensureError(t, ocfw.Append([]interface{}{1}))
+	ocfr, err = NewOCFReader(buf)
+	ensureError(t, err)
+	read, err = ocfr.SchemaGovernance()
+	ensureError(t, err)
+	if actual, expected := read.Owner, "ops"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
+
+// testMemoryBudgetOCF returns an OCF of strings with a block of two 4 byte
+// strings, holding 10 bytes, and a block of one, holding 5 bytes.
+func testMemoryBudgetOCF(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	ocfw, err := NewOCFWriter(OCFConfig{W: &buf, Schema: `"string"`})
+	ensureError(t, err)
+	ensureError(t, ocfw.Append([]interface{}{"abcd", "efgh"}))
+	ensureError(t, ocfw.Append([]interface{}{"ijkl"}))
+	return buf.Bytes()
+}
+
+func TestMemoryBudgetOCFReader(t *testing.T) {
+	budget, err := NewMemoryBudget(12, MemoryBudgetError)
+	ensureError(t, err)
+	ocf := testMemoryBudgetOCF(t)
+
+	first, err := NewOCFReaderWithConfig(bytes.NewReader(ocf), OCFReaderConfig{MemoryBudget: budget})
+	ensureError(t, err)
+	second, err := NewOCFReaderWithConfig(bytes.NewReader(ocf), OCFReaderConfig{MemoryBudget: budget})
+	ensureError(t, err)
+
+	if !first.Scan() {
+		t.Fatal(first.Err())
+	}
+	if actual, expected := first.MemoryUsage(), int64(10); actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if second.Scan() {
+		t.Fatal("GOT: true; WANT: false")
+	}
+	ensureError(t, second.Err(), "cannot read block: memory budget exceeded: 10 + 10 > 12")
+
+	var count int
+	for first.Scan() {
+		_, err := first.Read()
+		ensureError(t, err)
+		count++
+	}
+	ensureError(t, first.Err())
+	if actual, expected := count, 3; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if actual, expected := budget.Used(), int64(0); actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	small, err := NewMemoryBudget(8, MemoryBudgetBlock)
+	ensureError(t, err)
+	third, err := NewOCFReaderWithConfig(bytes.NewReader(ocf), OCFReaderConfig{MemoryBudget: small})
+	ensureError(t, err)
+	if third.Scan() {
+		t.Fatal("GOT: true; WANT: false")
+	}
+	ensureError(t, third.Err(), "memory budget exceeded: 10 > 8")
+}
+
+func TestMemoryBudgetBlock(t *testing.T) {
+	budget, err := NewMemoryBudget(12, MemoryBudgetBlock)
+	ensureError(t, err)
+	ocf := testMemoryBudgetOCF(t)
+
+	first, err := NewOCFReaderWithConfig(bytes.NewReader(ocf), OCFReaderConfig{MemoryBudget: budget})
+	ensureError(t, err)
+	if !first.Scan() {
+		t.Fatal(first.Err())
+	}
+
+	done := make(chan error)
+	go func() {
+		second, err := NewOCFReaderWithConfig(bytes.NewReader(ocf), OCFReaderConfig{MemoryBudget: budget})
+		if err == nil && second.Scan() {
+			_, err = second.Read()
+			second.ReleaseMemory()
+		} else if err == nil {
+			err = second.Err()
+		}
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("GOT: %v; WANT: blocked reader", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	first.ReleaseMemory()
+	ensureError(t, <-done)
+	if actual, expected := budget.Used(), int64(0); actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if first.Scan() {
+		t.Fatal("GOT: true; WANT: false")
+	}
+	ensureError(t, first.Err(), "reader memory released")
+}
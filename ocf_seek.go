@@ -0,0 +1,136 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// BlockCount returns the number of blocks of the OCF, as indexed when the
+// OCFReader was created. The block index is built when the io.Reader provided
+// to NewOCFReader is also an io.Seeker, such as an os.File or a bytes.Reader,
+// by reading the count, size, and sync marker of each block, and seeking past
+// its data, so it neither decompresses nor decodes the blocks. Blocks appended
+// to the OCF after the OCFReader was created are not indexed.
+func (ocfr *OCFReader) BlockCount() (int, error) {
+	if ocfr.blockIndexErr != nil {
+		return 0, fmt.Errorf("cannot count blocks: %s", ocfr.blockIndexErr)
+	}
+	return len(ocfr.blockOffsets), nil
+}
+
+// SeekToBlock positions the OCFReader at the start of block n, counting from
+// 0, so the next call to Scan reads the first data item of that block. This
+// allows tools to resume reading a large OCF from a recorded block, or to read
+// its blocks in parallel, each OCFReader of the same file seeking to the first
+// block of its share.
+//
+//     count, err := ocfr.BlockCount()
+//     if err != nil {
+//         return err
+//     }
+//     if err = ocfr.SeekToBlock(count / 2); err != nil {
+//         return err
+//     }
+//     for ocfr.Scan() {
+//         // reads the second half of the blocks
+//     }
+//
+// Seeking discards the data items remaining in the current block, and clears
+// any error from reading it. As the skipped blocks are not read, the checksum
+// of an OCF written with OCFConfig.Checksum is not verified once a reader
+// seeks.
+func (ocfr *OCFReader) SeekToBlock(n int) error {
+	if ocfr.blockIndexErr != nil {
+		return fmt.Errorf("cannot seek to block %d: %s", n, ocfr.blockIndexErr)
+	}
+	if n < 0 || n >= len(ocfr.blockOffsets) {
+		return fmt.Errorf("cannot seek to block %d: block ought to be in range [0, %d)", n, len(ocfr.blockOffsets))
+	}
+	if _, err := ocfr.ior.(io.Seeker).Seek(ocfr.blockOffsets[n], io.SeekStart); err != nil {
+		return fmt.Errorf("cannot seek to block %d: %s", n, err)
+	}
+	ocfr.memory.releaseAll()
+	ocfr.block = nil
+	ocfr.remainingBlockItems = 0
+	ocfr.readReady = false
+	ocfr.rerr = nil
+	ocfr.checksum = nil
+	return nil
+}
+
+// indexBlocks records the offset of each block of the OCF, starting at the
+// current offset of the reader, then seeks back to it. Errors are recorded
+// rather than returned, so an OCF that cannot be indexed, such as one still
+// being written, may still be read from start to end.
+func (ocfr *OCFReader) indexBlocks() {
+	rs, ok := ocfr.ior.(io.ReadSeeker)
+	if !ok {
+		ocfr.blockIndexErr = errors.New("reader is not an io.Seeker")
+		return
+	}
+	start, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		ocfr.blockIndexErr = err
+		return
+	}
+	ocfr.blockOffsets, ocfr.blockIndexErr = readBlockOffsets(rs, start, ocfr.header.syncMarker[:])
+	if _, err = rs.Seek(start, io.SeekStart); err != nil && ocfr.blockIndexErr == nil {
+		// NOTE: The reader is no longer at the first block, so reading fails
+		// too.
+		ocfr.blockIndexErr = err
+		ocfr.rerr = fmt.Errorf("cannot seek to first block: %s", err)
+	}
+}
+
+// readBlockOffsets returns the offsets of the blocks read from rs, whose first
+// block starts at offset.
+func readBlockOffsets(rs io.ReadSeeker, offset int64, syncMarker []byte) ([]int64, error) {
+	var offsets []int64
+	sync := make([]byte, ocfSyncLength)
+	for {
+		count, err := longBinaryReader(rs)
+		if err != nil {
+			if err == io.EOF {
+				return offsets, nil
+			}
+			return nil, fmt.Errorf("cannot read block count: %s", err)
+		}
+		if count <= 0 {
+			return nil, fmt.Errorf("cannot index when block count is not greater than 0: %d", count)
+		}
+		size, err := longBinaryReader(rs)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read block size: %s", err)
+		}
+		if size <= 0 {
+			return nil, fmt.Errorf("cannot index when block size is not greater than 0: %d", size)
+		}
+		if _, err = rs.Seek(size, io.SeekCurrent); err != nil {
+			return nil, fmt.Errorf("cannot seek past block: %s", err)
+		}
+		if n, err := io.ReadFull(rs, sync); err != nil {
+			return nil, fmt.Errorf("cannot read sync marker: read %d out of %d bytes: %s", n, ocfSyncLength, err)
+		}
+		if !bytes.Equal(sync, syncMarker) {
+			return nil, fmt.Errorf("sync marker mismatch: %v != %v", sync, syncMarker)
+		}
+		offsets = append(offsets, offset)
+		if offset, err = rs.Seek(0, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+	}
+}
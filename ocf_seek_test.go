@@ -0,0 +1,107 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"bufio"
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestOCFReaderSeekToBlock(t *testing.T) {
+	bb := new(bytes.Buffer)
+	ocfw, err := NewOCFWriter(OCFConfig{W: bb, Schema: `"long"`, CompressionName: CompressionDeflateLabel})
+	ensureError(t, err)
+	ensureError(t, ocfw.Append([]interface{}{1, 2}))
+	ensureError(t, ocfw.Append([]interface{}{3}))
+	ensureError(t, ocfw.Append([]interface{}{4, 5, 6}))
+
+	readAll := func(ocfr *OCFReader) []interface{} {
+		var data []interface{}
+		for ocfr.Scan() {
+			datum, err := ocfr.Read()
+			ensureError(t, err)
+			data = append(data, datum)
+		}
+		ensureError(t, ocfr.Err())
+		return data
+	}
+
+	ocfr, err := NewOCFReader(bytes.NewReader(bb.Bytes()))
+	ensureError(t, err)
+	count, err := ocfr.BlockCount()
+	ensureError(t, err)
+	if actual, expected := count, 3; actual != expected {
+		t.Fatalf("GOT: %v; WANT: %v", actual, expected)
+	}
+	// indexing leaves the reader at the first block
+	if actual, expected := readAll(ocfr), []interface{}{int64(1), int64(2), int64(3), int64(4), int64(5), int64(6)}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	ensureError(t, ocfr.SeekToBlock(2))
+	if actual, expected := readAll(ocfr), []interface{}{int64(4), int64(5), int64(6)}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	// seeking within a block discards its remaining data items
+	ensureError(t, ocfr.SeekToBlock(0))
+	if !ocfr.Scan() {
+		t.Fatalf("GOT: %v; WANT: %v", false, true)
+	}
+	_, err = ocfr.Read()
+	ensureError(t, err)
+	ensureError(t, ocfr.SeekToBlock(1))
+	if actual, expected := readAll(ocfr), []interface{}{int64(3), int64(4), int64(5), int64(6)}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	ensureError(t, ocfr.SeekToBlock(3), "cannot seek to block 3", "[0, 3)")
+	ensureError(t, ocfr.SeekToBlock(-1), "cannot seek to block -1")
+}
+
+func TestOCFReaderSeekToBlockNotSeekable(t *testing.T) {
+	bb := new(bytes.Buffer)
+	ocfw, err := NewOCFWriter(OCFConfig{W: bb, Schema: `"long"`})
+	ensureError(t, err)
+	ensureError(t, ocfw.Append([]interface{}{1}))
+
+	ocfr, err := NewOCFReader(bufio.NewReader(bytes.NewReader(bb.Bytes())))
+	ensureError(t, err)
+	_, err = ocfr.BlockCount()
+	ensureError(t, err, "cannot count blocks", "not an io.Seeker")
+	ensureError(t, ocfr.SeekToBlock(0), "cannot seek to block 0", "not an io.Seeker")
+}
+
+func TestOCFReaderSeekToBlockTruncated(t *testing.T) {
+	bb := new(bytes.Buffer)
+	ocfw, err := NewOCFWriter(OCFConfig{W: bb, Schema: `"long"`})
+	ensureError(t, err)
+	ensureError(t, ocfw.Append([]interface{}{1}))
+	ensureError(t, ocfw.Append([]interface{}{2}))
+
+	// the index is not built, but the blocks before the truncation are read
+	ocfr, err := NewOCFReader(bytes.NewReader(bb.Bytes()[:bb.Len()-1]))
+	ensureError(t, err)
+	_, err = ocfr.BlockCount()
+	ensureError(t, err, "cannot count blocks", "cannot read sync marker")
+	if !ocfr.Scan() {
+		t.Fatalf("GOT: %v; WANT: %v", false, true)
+	}
+	datum, err := ocfr.Read()
+	ensureError(t, err)
+	if actual, expected := datum, int64(1); actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
@@ -0,0 +1,368 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TimeBucketWriterConfig is used to specify creation parameters for
+// TimeBucketWriter.
+type TimeBucketWriterConfig struct {
+	// Dir specifies the directory in which to create the OCF files,
+	// (required).
+	Dir string
+
+	// Name returns the name of the file created in Dir for the data items of
+	// the bucket starting at the specified time, or for its late data items,
+	// (optional). The name may include directories, which are created as
+	// required, such as "dt=2019-06-01/hour=13/part.avro". Files are not
+	// overwritten, so a writer restarted in the same Dir ought to use
+	// different names. When omitted, files are named like
+	// "20190601T130000Z.avro" and "20190601T130000Z-late.avro".
+	Name func(bucket time.Time, late bool) string
+
+	// Field specifies the name of the timestamp field of the records that
+	// determines the bucket of each record, (required). Its value ought to be
+	// a time.Time, such as the native form of the timestamp-millis logical
+	// type, or a union wrapping one.
+	Field string
+
+	// Bucket specifies the duration of each bucket, such as time.Hour or 24 *
+	// time.Hour, (required). Buckets start at multiples of the duration since
+	// the zero time, so daily buckets start at midnight UTC.
+	Bucket time.Duration
+
+	// Grace specifies how long the file of a bucket remains open after the
+	// end of the bucket, measured by the event time watermark, which is the
+	// latest timestamp of the records appended so far, (optional). Records
+	// whose bucket was closed are late, and are written to a separate late
+	// file of their bucket. When zero, a bucket is closed as soon as a record
+	// of a later bucket is appended.
+	Grace time.Duration
+
+	// Spec specifies the partition path of each record, such as
+	// "country=NL", (optional). When used, the records of each bucket are
+	// written to a separate file for each partition path, created in the
+	// directory of the path in Dir, such as
+	// "country=NL/20190601T130000Z.avro".
+	Spec *PartitionSpec
+
+	// OCF specifies the configuration of each OCFWriter, such as its Codec
+	// or Schema, and its CompressionName. Its W, Index, and Manifest are
+	// ignored, because they describe a single file.
+	OCF OCFConfig
+
+	// OnClose is called with the name of each file after it is closed,
+	// (optional), such as to register it with a data catalog. An error it
+	// returns is returned by the Append or Close that closed the file.
+	OnClose func(name string, bucket time.Time, late bool) error
+}
+
+// TimeBucketWriter writes records to OCF files by the event time bucket of a
+// timestamp field of each record, such as hourly or daily, for data lake
+// layouts partitioned by time. The file of a bucket is closed once the event
+// time watermark passes the end of the bucket and the grace window that
+// follows it, and records that arrive for a closed bucket are written to a
+// late file of the bucket, which is closed by Close. It may be used by
+// multiple goroutines simultaneously.
+//
+//     tbw, err := goavro.NewTimeBucketWriter(goavro.TimeBucketWriterConfig{
+//         Dir:    "/var/data/clicks",
+//         Field:  "timestamp",
+//         Bucket: time.Hour,
+//         Grace:  10 * time.Minute,
+//         OCF:    goavro.OCFConfig{Codec: codec},
+//         Name: func(bucket time.Time, late bool) string {
+//             name := bucket.Format("dt=2006-01-02/hour=15/") + runID
+//             if late {
+//                 name += "-late"
+//             }
+//             return name + ".avro"
+//         },
+//     })
+type TimeBucketWriter struct {
+	config    TimeBucketWriterConfig
+	mu        sync.Mutex
+	watermark time.Time // latest timestamp appended
+	open      map[timeBucketKey]*timeBucketFile
+	files     []string // files created, in the order they were created
+	closed    bool
+}
+
+type timeBucketKey struct {
+	bucket int64 // Unix time of the start of the bucket, in nanoseconds
+	late   bool
+	path   string // partition path, when Spec is used
+}
+
+type timeBucketFile struct {
+	bucket  time.Time
+	late    bool
+	file    *os.File
+	ocfw    *OCFWriter
+	pending []interface{} // data items of the current Append
+}
+
+// NewTimeBucketWriter returns a TimeBucketWriter. Files are created when the
+// first record of their bucket is appended.
+func NewTimeBucketWriter(config TimeBucketWriterConfig) (*TimeBucketWriter, error) {
+	if config.Dir == "" {
+		return nil, errors.New("cannot create TimeBucketWriter: Dir is empty")
+	}
+	if config.Field == "" {
+		return nil, errors.New("cannot create TimeBucketWriter: Field is empty")
+	}
+	if config.Bucket <= 0 {
+		return nil, fmt.Errorf("cannot create TimeBucketWriter: bucket ought to be greater than 0: %s", config.Bucket)
+	}
+	if config.Grace < 0 {
+		return nil, fmt.Errorf("cannot create TimeBucketWriter: grace ought to be zero or positive: %s", config.Grace)
+	}
+	if config.Name == nil {
+		config.Name = func(bucket time.Time, late bool) string {
+			name := bucket.UTC().Format("20060102T150405Z")
+			if late {
+				name += "-late"
+			}
+			return name + ".avro"
+		}
+	}
+	return &TimeBucketWriter{config: config, open: make(map[timeBucketKey]*timeBucketFile)}, nil
+}
+
+// Append appends one or more records to the files of their buckets, and closes
+// the files of the buckets whose grace window has passed. The records of each
+// file are appended in the order they are provided.
+func (tbw *TimeBucketWriter) Append(data interface{}) error {
+	values, err := convertArray(data)
+	if err != nil {
+		return err
+	}
+	tbw.mu.Lock()
+	defer tbw.mu.Unlock()
+	if tbw.closed {
+		return errors.New("cannot append: TimeBucketWriter is closed")
+	}
+
+	var touched []*timeBucketFile
+	var errs []error
+	for _, datum := range values {
+		timestamp, err := tbw.timestamp(datum)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cannot append: %s", err))
+			break
+		}
+		if timestamp.After(tbw.watermark) {
+			tbw.watermark = timestamp
+			// NOTE: Buckets are closed before the datum that passes their
+			// grace window is routed, so the data items preceding it are
+			// written to the file that is being closed.
+			if err = tbw.closeExpired(false); err != nil {
+				errs = append(errs, err)
+				break
+			}
+		}
+		bucket := timestamp.Truncate(tbw.config.Bucket)
+		key := timeBucketKey{bucket: bucket.UnixNano(), late: tbw.expired(bucket)}
+		if tbw.config.Spec != nil {
+			if key.path, err = tbw.config.Spec.Path(datum); err != nil {
+				errs = append(errs, fmt.Errorf("cannot append: %s", err))
+				break
+			}
+		}
+		f, ok := tbw.open[key]
+		if !ok {
+			if f, err = tbw.create(bucket, key.late, key.path); err != nil {
+				errs = append(errs, fmt.Errorf("cannot append: %s", err))
+				break
+			}
+			tbw.open[key] = f
+		}
+		if len(f.pending) == 0 {
+			touched = append(touched, f)
+		}
+		f.pending = append(f.pending, datum)
+	}
+	// NOTE: Data items routed before an error are still written.
+	for _, f := range touched {
+		if err = f.flush(); err != nil {
+			errs = append(errs, fmt.Errorf("cannot append: %s", err))
+		}
+	}
+	if len(errs) > 0 {
+		return joinErrors(errs)
+	}
+	return nil
+}
+
+// timestamp returns the event time of the datum.
+func (tbw *TimeBucketWriter) timestamp(datum interface{}) (time.Time, error) {
+	timestamp, err := recordTimestamp(datum, tbw.config.Field)
+	if err == nil && timestamp == nil {
+		err = fmt.Errorf("field %q ought to be a time.Time; received: %T", tbw.config.Field, nil)
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return *timestamp, nil
+}
+
+// recordTimestamp returns the value of the named timestamp field of a record,
+// or of the union wrapping it, or nil when the value is null.
+func recordTimestamp(datum interface{}, field string) (*time.Time, error) {
+	record, ok := datum.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected map[string]interface{}; received: %T", datum)
+	}
+	value := record[field]
+	if _, v, ok := unwrapUnion(value); ok {
+		value = v
+	}
+	if value == nil {
+		return nil, nil
+	}
+	timestamp, ok := value.(time.Time)
+	if !ok {
+		return nil, fmt.Errorf("field %q ought to be a time.Time; received: %T", field, value)
+	}
+	return &timestamp, nil
+}
+
+// expired returns true when the grace window of the bucket has passed.
+func (tbw *TimeBucketWriter) expired(bucket time.Time) bool {
+	return !bucket.Add(tbw.config.Bucket + tbw.config.Grace).After(tbw.watermark)
+}
+
+// closeExpired closes the files of the buckets whose grace window has passed,
+// and when all is true, every file, in the order of their buckets.
+func (tbw *TimeBucketWriter) closeExpired(all bool) error {
+	var keys []timeBucketKey
+	for key, f := range tbw.open {
+		if all || (!key.late && tbw.expired(f.bucket)) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].bucket != keys[j].bucket {
+			return keys[i].bucket < keys[j].bucket
+		}
+		if keys[i].late != keys[j].late {
+			return !keys[i].late
+		}
+		return keys[i].path < keys[j].path
+	})
+	var errs []error
+	for _, key := range keys {
+		f := tbw.open[key]
+		delete(tbw.open, key)
+		if err := f.close(); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if tbw.config.OnClose != nil {
+			if err := tbw.config.OnClose(f.file.Name(), f.bucket, f.late); err != nil {
+				errs = append(errs, fmt.Errorf("cannot close %q: %s", f.file.Name(), err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return joinErrors(errs)
+	}
+	return nil
+}
+
+// create creates the file of the bucket, in the directory of the partition
+// path.
+func (tbw *TimeBucketWriter) create(bucket time.Time, late bool, path string) (*timeBucketFile, error) {
+	name := filepath.Join(tbw.config.Dir, filepath.FromSlash(path), tbw.config.Name(bucket, late))
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return nil, fmt.Errorf("cannot create directory: %s", err)
+	}
+	file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create file: %s", err)
+	}
+	config := tbw.config.OCF
+	config.W, config.Index, config.Manifest = file, nil, nil
+	ocfw, err := NewOCFWriter(config)
+	if err != nil {
+		_ = file.Close()
+		_ = os.Remove(name)
+		return nil, err
+	}
+	tbw.files = append(tbw.files, name)
+	return &timeBucketFile{bucket: bucket, late: late, file: file, ocfw: ocfw}, nil
+}
+
+// flush appends the pending data items to the file.
+func (f *timeBucketFile) flush() error {
+	if len(f.pending) == 0 {
+		return nil
+	}
+	err := f.ocfw.Append(f.pending)
+	f.pending = nil
+	return err
+}
+
+// close flushes and closes the file.
+func (f *timeBucketFile) close() error {
+	var errs []error
+	if err := f.flush(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := f.ocfw.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := f.file.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("cannot close %q: %s", f.file.Name(), joinErrors(errs))
+	}
+	return nil
+}
+
+// Watermark returns the latest timestamp of the records appended so far.
+func (tbw *TimeBucketWriter) Watermark() time.Time {
+	tbw.mu.Lock()
+	defer tbw.mu.Unlock()
+	return tbw.watermark
+}
+
+// Files returns the names of the files created so far, in the order they were
+// created.
+func (tbw *TimeBucketWriter) Files() []string {
+	tbw.mu.Lock()
+	defer tbw.mu.Unlock()
+	return append([]string(nil), tbw.files...)
+}
+
+// Close closes every open file, including the late files, after which records
+// may no longer be appended. It attempts to close every file, even after one
+// of them fails, and returns an error describing every failure.
+func (tbw *TimeBucketWriter) Close() error {
+	tbw.mu.Lock()
+	defer tbw.mu.Unlock()
+	tbw.closed = true
+	if err := tbw.closeExpired(true); err != nil {
+		return fmt.Errorf("cannot close TimeBucketWriter: %s", err)
+	}
+	return nil
+}
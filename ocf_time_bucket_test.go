@@ -0,0 +1,143 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const eventSchema = `{"type":"record","name":"Event","fields":[{"name":"id","type":"long"},{"name":"ts","type":{"type":"long","logicalType":"timestamp-millis"}}]}`
+
+func TestTimeBucketWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goavro")
+	ensureError(t, err)
+	defer os.RemoveAll(dir)
+
+	var closed []string
+	tbw, err := NewTimeBucketWriter(TimeBucketWriterConfig{
+		Dir:    dir,
+		Field:  "ts",
+		Bucket: time.Hour,
+		Grace:  10 * time.Minute,
+		OCF:    OCFConfig{Schema: eventSchema},
+		Name: func(bucket time.Time, late bool) string {
+			name := bucket.Format("dt=2006-01-02/hour=15/part")
+			if late {
+				name += "-late"
+			}
+			return name + ".avro"
+		},
+		OnClose: func(name string, bucket time.Time, late bool) error {
+			rel, _ := filepath.Rel(dir, name)
+			closed = append(closed, rel)
+			return nil
+		},
+	})
+	ensureError(t, err)
+
+	at := func(hour, minute int) time.Time { return time.Date(2019, 6, 1, hour, minute, 0, 0, time.UTC) }
+	event := func(id int64, ts time.Time) map[string]interface{} {
+		return map[string]interface{}{"id": id, "ts": ts}
+	}
+	ensureError(t, tbw.Append([]interface{}{event(1, at(10, 5)), event(2, at(10, 30)), event(3, at(11, 5)), event(4, at(10, 50))}))
+	if len(closed) != 0 {
+		t.Errorf("GOT: %v; WANT: no closed files within grace window", closed)
+	}
+	ensureError(t, tbw.Append([]interface{}{event(5, at(11, 15)), event(6, at(10, 55))}))
+	if actual, expected := fmt.Sprint(closed), "[dt=2019-06-01/hour=10/part.avro]"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if actual, expected := tbw.Watermark(), at(11, 15); !actual.Equal(expected) {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	ensureError(t, tbw.Close())
+	if actual, expected := fmt.Sprint(closed), "[dt=2019-06-01/hour=10/part.avro dt=2019-06-01/hour=10/part-late.avro dt=2019-06-01/hour=11/part.avro]"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	ids := make(map[string]string)
+	files := tbw.Files()
+	for i, items := range readPartitionedFiles(t, files) {
+		var some []string
+		for _, item := range items {
+			some = append(some, fmt.Sprint(item.(map[string]interface{})["id"]))
+		}
+		rel, _ := filepath.Rel(dir, files[i])
+		ids[rel] = strings.Join(some, " ")
+	}
+	for name, expected := range map[string]string{
+		"dt=2019-06-01/hour=10/part.avro":      "1 2 4",
+		"dt=2019-06-01/hour=11/part.avro":      "3 5",
+		"dt=2019-06-01/hour=10/part-late.avro": "6",
+	} {
+		if actual := ids[name]; actual != expected {
+			t.Errorf("%s: GOT: %q; WANT: %q", name, actual, expected)
+		}
+	}
+
+	err = tbw.Append([]interface{}{event(7, at(12, 0))})
+	ensureError(t, err, "TimeBucketWriter is closed")
+}
+
+func TestTimeBucketWriterErrors(t *testing.T) {
+	_, err := NewTimeBucketWriter(TimeBucketWriterConfig{Dir: "x", Field: "ts"})
+	ensureError(t, err, "bucket ought to be greater than 0")
+
+	dir, err := ioutil.TempDir("", "goavro")
+	ensureError(t, err)
+	defer os.RemoveAll(dir)
+	tbw, err := NewTimeBucketWriter(TimeBucketWriterConfig{Dir: dir, Field: "ts", Bucket: time.Hour, OCF: OCFConfig{Schema: eventSchema}})
+	ensureError(t, err)
+	err = tbw.Append([]interface{}{map[string]interface{}{"id": int64(1), "ts": int64(1)}})
+	ensureError(t, err, `field "ts" ought to be a time.Time`)
+	ensureError(t, tbw.Close())
+}
+
+func TestTimeBucketWriterSpec(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goavro")
+	ensureError(t, err)
+	defer os.RemoveAll(dir)
+
+	spec, err := NewPartitionSpec("dt=date(ts)/parity=bucket(id,2)")
+	ensureError(t, err)
+	tbw, err := NewTimeBucketWriter(TimeBucketWriterConfig{Dir: dir, Field: "ts", Bucket: time.Hour, Spec: spec, OCF: OCFConfig{Schema: eventSchema}})
+	ensureError(t, err)
+	ts := time.Date(2019, 6, 1, 13, 0, 0, 0, time.UTC)
+	var data []interface{}
+	for i := int64(0); i < 6; i++ {
+		data = append(data, map[string]interface{}{"id": i, "ts": ts})
+	}
+	ensureError(t, tbw.Append(data))
+	ensureError(t, tbw.Close())
+
+	var total int
+	for _, name := range tbw.Files() {
+		rel, err := filepath.Rel(dir, name)
+		ensureError(t, err)
+		if !strings.HasPrefix(filepath.ToSlash(rel), "dt=2019-06-01/parity=") || !strings.HasSuffix(rel, "20190601T130000Z.avro") {
+			t.Errorf("GOT: %q; WANT: dt=2019-06-01/parity=N/20190601T130000Z.avro", rel)
+		}
+	}
+	for _, items := range readPartitionedFiles(t, tbw.Files()) {
+		total += len(items)
+	}
+	if total != len(data) {
+		t.Errorf("GOT: %v; WANT: %v", total, len(data))
+	}
+}
@@ -7,20 +7,21 @@
 // distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
 // WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
 
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
 package goavro
 
 import (
 	"bytes"
-	"compress/flate"
-	"encoding/binary"
+	"crypto/sha256"
 	"errors"
 	"fmt"
-	"hash/crc32"
+	"hash"
 	"io"
 	"io/ioutil"
 	"os"
-
-	"github.com/golang/snappy"
+	"strings"
 )
 
 // OCFConfig is used to specify creation parameters for OCFWriter.
@@ -52,10 +53,118 @@ type OCFConfig struct {
 	// this field is ignored.
 	CompressionName string
 
+	// CompressionBackend specifies the registered backend used to compress
+	// blocks, (optional). If omitted, the default implementation of the
+	// compression algorithm is used. When appending to an existing OCF, the
+	// backend ought to be registered for the compression algorithm of that
+	// OCF.
+	CompressionBackend string
+
 	//MetaData specifies application specific meta data to be added to
 	//the OCF file.  When appending to an existing OCF, this field
 	//is ignored
 	MetaData map[string][]byte
+
+	// Index specifies where to write an index of the blocks written by the
+	// OCFWriter, (optional). The index is itself an OCF, whose items conform
+	// to OCFIndexSchema, and record the position of each block in the file and
+	// of each datum inside the decompressed block. It lets an
+	// OCFIndexedReader read any datum without decoding the data that precedes
+	// it. When appending to an existing OCF, Index ought to append to the
+	// index of that OCF.
+	Index io.Writer
+
+	// Checksum specifies the algorithm used to compute a checksum of every
+	// block written to a new OCF, (optional). Either OCFChecksumCRC32 or
+	// OCFChecksumSHA256. The checksum is recorded in the OCF metadata by
+	// Close, which therefore requires W to be either an io.WriterAt, such as
+	// an `*os.File`, or an io.WriteSeeker. OCFReader verifies the checksum
+	// after reading the final block, which detects corruption that is not
+	// detected by the compression algorithm. When appending to an existing
+	// OCF, this field is ignored, and the checksum of the existing OCF, if
+	// any, is updated.
+	Checksum string
+
+	// SyncOnFlush specifies whether each block is synced to stable storage
+	// after it is written to W, (optional). When true, W ought to have a
+	// `Sync() error` method, such as `*os.File`, and Close completes the OCF
+	// in two phases: it syncs the blocks written to W, then writes the final
+	// metadata, such as the checksum, and syncs W again. This lets long
+	// running archivers produce OCF files that remain readable after a crash,
+	// up to the final synced block, without a separate journal. When Index is
+	// also used, the index is synced after each block it refers to, and ought
+	// to also have a Sync method.
+	SyncOnFlush bool
+
+	// Manifest specifies where to record the hash of every block written to
+	// W, (optional). Append skips the blocks whose hash the manifest already
+	// records, rather than appending them again, so a retried backfill job
+	// that appends the same batches of data to the same OCF does not
+	// duplicate the blocks written before it failed. Blocks are hashed
+	// after compression, so the job ought to use the same batches and
+	// compression algorithm. The manifest is read before appending: it ought
+	// to be empty for a new OCF, and when appending to an existing OCF, the
+	// blocks found after the final recorded block, such as a block written
+	// just before a crash, are recorded. When SyncOnFlush is also used, the
+	// manifest is synced after each block, and ought to also have a Sync
+	// method.
+	Manifest io.ReadWriter
+
+	// ContentDefinedBlockSize specifies the average size, in bytes, of the
+	// blocks written when block boundaries are chosen by the content of the
+	// data, (optional). When zero, Append writes the data of each call in a
+	// block of its own. Otherwise, Append encodes the data into a pending
+	// block, and ends the block after a datum whose encoded bytes contain a
+	// boundary found by a rolling hash, so blocks start and end at the same
+	// data regardless of how the data is batched, and regardless of the data
+	// inserted or removed elsewhere in the stream. Backup systems storing many
+	// similar OCF files, such as daily snapshots, then find more identical
+	// blocks to deduplicate. Blocks are between a quarter and four times this
+	// size, but always end after a datum, so a larger datum makes a larger
+	// block. Close writes the pending block. It ought to be at least 64.
+	ContentDefinedBlockSize int
+
+	// BlockSizeBytes specifies the size, in bytes, of the encoded data of the
+	// blocks written, (optional). When either BlockSizeBytes or
+	// BlockRecordCount is not zero, Append encodes the data into a pending
+	// block, and ends the block after the datum that brings the size of its
+	// encoded data, before compression, to BlockSizeBytes, or its count of
+	// data items to BlockRecordCount, whichever comes first, regardless of
+	// how the data is batched. Readers such as Hive and Spark split and
+	// process OCF files by block, so they perform better with blocks of a
+	// tuned size than with many small blocks. Close writes the pending block.
+	// When zero, blocks are not limited by size. It ought not to be used with
+	// ContentDefinedBlockSize.
+	BlockSizeBytes int
+
+	// BlockRecordCount specifies the number of data items of the blocks
+	// written, (optional). See BlockSizeBytes. When zero, blocks are not
+	// limited by count, other than by MaxBlockCount.
+	BlockRecordCount int
+
+	// VerifyAppendSchema specifies whether to verify, when appending to an
+	// existing OCF, that the schema of the Codec or Schema parameter above is
+	// the schema of the OCF, (optional). Otherwise the schema of the OCF is
+	// used regardless of those parameters, so a job appending data of a newer
+	// schema would write blocks readers cannot decode. The schemas match when
+	// they are identical, or when their Parsing Canonical Forms are equal,
+	// such as when they only differ by whitespace or documentation. When
+	// true, either Codec or Schema ought to be specified.
+	VerifyAppendSchema bool
+
+	// DeferHeader specifies whether to write the header of a new OCF when
+	// the first block is written, or by Close when no block is, rather than
+	// when the OCFWriter is created, (optional). Until then, SetMetaData may
+	// add metadata known only after creating the OCFWriter, such as the
+	// provenance of the data. When appending to an existing OCF, this field
+	// is ignored.
+	DeferHeader bool
+}
+
+// syncer is implemented by writers, such as `*os.File`, that can commit the
+// data written to them to stable storage.
+type syncer interface {
+	Sync() error
 }
 
 // OCFWriter is used to create a new or append to an existing Avro Object
@@ -63,6 +172,21 @@ type OCFConfig struct {
 type OCFWriter struct {
 	header *ocfHeader
 	iow    io.Writer
+	index  *OCFWriter // writes the block index, when configured
+	offset int64      // number of bytes in the OCF
+	err    error      // ErrOCFTruncated, after a block was partially written
+
+	checksum       hash.Hash // checksum of every block, when configured
+	checksumOffset int64     // offset of the checksum digest in the header
+	headerStart    int64     // offset of the header in W, when checksum is configured
+	headerPending  bool      // true until the header of a new OCF created with DeferHeader is written
+
+	syncer syncer // syncs W after each block, when configured
+
+	manifest *ocfManifest // hashes of the blocks of the OCF, when configured
+	skipped  int64        // number of blocks skipped because of the manifest
+
+	chunker *ocfChunker // chooses block boundaries by content, size, or count, when configured
 }
 
 // NewOCFWriter returns a new OCFWriter instance that may be used for appending
@@ -72,6 +196,32 @@ func NewOCFWriter(config OCFConfig) (*OCFWriter, error) {
 	var err error
 	ocf := &OCFWriter{iow: config.W}
 
+	if config.ContentDefinedBlockSize != 0 {
+		if config.BlockSizeBytes != 0 || config.BlockRecordCount != 0 {
+			return nil, errors.New("cannot create OCFWriter: content defined block size ought not to be used with block size bytes or block record count")
+		}
+		if ocf.chunker, err = newOCFChunker(config.ContentDefinedBlockSize); err != nil {
+			return nil, fmt.Errorf("cannot create OCFWriter: %s", err)
+		}
+	} else if config.BlockSizeBytes != 0 || config.BlockRecordCount != 0 {
+		if ocf.chunker, err = newOCFSizedChunker(config.BlockSizeBytes, config.BlockRecordCount); err != nil {
+			return nil, fmt.Errorf("cannot create OCFWriter: %s", err)
+		}
+	}
+
+	if config.SyncOnFlush && config.W != nil {
+		var ok bool
+		if ocf.syncer, ok = config.W.(syncer); !ok {
+			return nil, fmt.Errorf("cannot create OCFWriter: sync on flush requires W to have a Sync method; received: %T", config.W)
+		}
+	}
+
+	if config.Manifest != nil && config.W != nil {
+		if ocf.manifest, err = readOCFManifest(config.Manifest, config.SyncOnFlush); err != nil {
+			return nil, fmt.Errorf("cannot create OCFWriter: %s", err)
+		}
+	}
+
 	switch config.W.(type) {
 	case nil:
 		return nil, errors.New("cannot create OCFWriter when W is nil")
@@ -88,36 +238,311 @@ func NewOCFWriter(config OCFConfig) (*OCFWriter, error) {
 			if ocf.header, err = readOCFHeader(file); err != nil {
 				return nil, fmt.Errorf("cannot create OCFWriter: %s", err)
 			}
+			if ocf.header.compressionID, err = selectCompressionBackend(ocf.header.compressionID, config.CompressionBackend); err != nil {
+				return nil, fmt.Errorf("cannot create OCFWriter: %s", err)
+			}
+			if config.VerifyAppendSchema {
+				if err = verifyAppendSchema(ocf.header.codec, config); err != nil {
+					return nil, fmt.Errorf("cannot create OCFWriter: %s", err)
+				}
+			}
+			if value, ok := ocf.header.metadata[ocfChecksumKey]; ok {
+				if err = ocf.resumeChecksum(file, value); err != nil {
+					return nil, fmt.Errorf("cannot create OCFWriter: %s", err)
+				}
+			}
 			// prepare for appending data to existing OCF
+			if ocf.offset, err = file.Seek(0, io.SeekCurrent); err != nil {
+				return nil, fmt.Errorf("cannot create OCFWriter: %s", err)
+			}
 			if err = ocf.quickScanToTail(file); err != nil {
 				return nil, fmt.Errorf("cannot create OCFWriter: %s", err)
 			}
+			if config.Index != nil {
+				if err = ocf.createIndex(config.Index, config.SyncOnFlush); err != nil {
+					return nil, fmt.Errorf("cannot create OCFWriter: %s", err)
+				}
+			}
 			return ocf, nil // happy case for appending to existing OCF
 		}
 	}
 
+	if ocf.manifest != nil && ocf.manifest.lines > 0 {
+		return nil, errors.New("cannot create OCFWriter: manifest ought to be empty for a new OCF")
+	}
+
 	// create new OCF header based on configuration parameters
 	if ocf.header, err = newOCFHeader(config); err != nil {
 		return nil, fmt.Errorf("cannot create OCFWriter: %s", err)
 	}
-	if err = writeOCFHeader(ocf.header, config.W); err != nil {
+	if config.Index != nil {
+		if err = ocf.createIndex(config.Index, config.SyncOnFlush); err != nil {
+			return nil, fmt.Errorf("cannot create OCFWriter: %s", err)
+		}
+	}
+	var headerStart int64
+	if config.Checksum != "" {
+		switch w := config.W.(type) {
+		case io.Seeker:
+			if headerStart, err = w.Seek(0, io.SeekCurrent); err != nil {
+				return nil, fmt.Errorf("cannot create OCFWriter: %s", err)
+			}
+		case io.WriterAt:
+			// NOTE: W is assumed to be empty, because it cannot report its
+			// position.
+		default:
+			return nil, fmt.Errorf("cannot create OCFWriter: checksum requires W to be an io.WriterAt or an io.WriteSeeker; received: %T", config.W)
+		}
+		ocf.checksum, _ = newOCFChecksum(config.Checksum) // NOTE: algorithm validated by newOCFHeader
+		ocf.headerStart = headerStart
+	}
+	if config.DeferHeader {
+		ocf.headerPending = true
+		return ocf, nil
+	}
+	if err = ocf.writeHeader(); err != nil {
 		return nil, fmt.Errorf("cannot create OCFWriter: %s", err)
 	}
 	return ocf, nil // another happy case for creation of new OCF
 }
 
+// writeHeader writes the header of a new OCF. When the header is only
+// partially written, the OCFWriter fails with ErrOCFTruncated.
+func (ocfw *OCFWriter) writeHeader() error {
+	n, err := writeOCFHeader(ocfw.header, ocfw.iow)
+	if err != nil {
+		if n > 0 {
+			ocfw.err = ErrOCFTruncated{Offset: 0, Err: err}
+			return ocfw.err
+		}
+		return err
+	}
+	ocfw.headerPending = false
+	ocfw.offset = int64(n)
+	if ocfw.syncer != nil {
+		if err = ocfw.syncer.Sync(); err != nil {
+			return fmt.Errorf("cannot sync header: %s", err)
+		}
+	}
+	if ocfw.checksum != nil {
+		// NOTE: The checksum is the final metadata item, followed by the
+		// metadata terminating block count, and the sync marker.
+		ocfw.checksumOffset = ocfw.headerStart + int64(n) - ocfSyncLength - 1 - int64(ocfw.checksum.Size())
+	}
+	return nil
+}
+
+// MetaData returns a copy of the metadata of the OCF header, including the
+// avro.schema and avro.codec keys, of either the existing OCF appended to, or
+// the new OCF.
+func (ocfw *OCFWriter) MetaData() map[string][]byte {
+	metadata := make(map[string][]byte, len(ocfw.header.metadata)+2)
+	for k, v := range ocfw.header.metadata {
+		metadata[k] = v
+	}
+	metadata["avro.schema"] = []byte(ocfw.header.codec.Schema())
+	metadata["avro.codec"] = []byte(ocfw.CompressionName())
+	return metadata
+}
+
+// SetMetaData sets the value of an application specific key of the metadata of
+// a new OCF, such as the job or the source system that produced its data, so
+// provenance information travels inside the file, where OCFReader.MetaData
+// returns it. The OCFWriter ought to have been created with
+// OCFConfig.DeferHeader, and SetMetaData returns an error once the header was
+// written. Keys starting with "avro." are reserved by the Avro specification,
+// and may not be set.
+func (ocfw *OCFWriter) SetMetaData(key string, value []byte) error {
+	if !ocfw.headerPending {
+		return errors.New("cannot set OCF metadata: header already written")
+	}
+	if strings.HasPrefix(key, "avro.") || key == ocfChecksumKey {
+		return fmt.Errorf("cannot set OCF metadata: key is reserved: %q", key)
+	}
+	// NOTE: Copy the metadata rather than modify the caller's map.
+	metadata := make(map[string][]byte, len(ocfw.header.metadata)+1)
+	for k, v := range ocfw.header.metadata {
+		metadata[k] = v
+	}
+	metadata[key] = value
+	ocfw.header.metadata = metadata
+	return nil
+}
+
+// verifyAppendSchema returns an error unless the schema specified by the
+// configuration matches the schema of the existing OCF.
+func verifyAppendSchema(existing *Codec, config OCFConfig) error {
+	codec := config.Codec
+	if codec == nil {
+		if config.Schema == "" {
+			return errors.New("cannot verify append schema without either Codec or Schema specified")
+		}
+		var err error
+		if codec, err = NewCodec(config.Schema); err != nil {
+			return fmt.Errorf("cannot verify append schema: %s", err)
+		}
+	}
+	if codec.Schema() != existing.Schema() && codec.CanonicalSchema() != existing.CanonicalSchema() {
+		return fmt.Errorf("append schema ought to match schema of existing OCF: %s != %s", codec.CanonicalSchema(), existing.CanonicalSchema())
+	}
+	return nil
+}
+
+// resumeChecksum prepares to update the checksum of an existing OCF, whose
+// header has just been read from file. The blocks of the OCF are added to the
+// checksum by quickScanToTail.
+func (ocfw *OCFWriter) resumeChecksum(file *os.File, value []byte) error {
+	h, digest, err := parseOCFChecksum(value)
+	if err != nil {
+		return err
+	}
+	headerEnd, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	// Ensure the checksum is the final metadata item, as written by
+	// OCFWriter, so it may be overwritten in place.
+	offset := headerEnd - ocfSyncLength - 1 - int64(len(digest))
+	recorded := make([]byte, len(digest))
+	if _, err = file.ReadAt(recorded, offset); err != nil {
+		return fmt.Errorf("cannot read OCF checksum: %s", err)
+	}
+	if !bytes.Equal(recorded, digest) {
+		return errors.New("cannot update OCF checksum which is not the final metadata item")
+	}
+	ocfw.checksum, ocfw.checksumOffset = h, offset
+	return nil
+}
+
+// Close finishes writing the OCF. Unless the OCFWriter was created with
+// ContentDefinedBlockSize, BlockSizeBytes, or BlockRecordCount, Append writes
+// each block to W before it returns, so no data remains to be written;
+// otherwise Close writes the pending block. When the OCFWriter was created with
+// DeferHeader, and no block was written, Close writes the header, so the OCF is
+// valid, though empty. When the OCF has a checksum, Close
+// records the checksum of every block written so far in the OCF metadata. When
+// the OCFWriter was created with SyncOnFlush, Close syncs the blocks before
+// recording the checksum, and syncs W again afterwards. Close does not close W,
+// and data may be appended after Close, provided Close is invoked again
+// afterwards.
+//
+// Close attempts every step, including closing the index, even after one of
+// them fails, and returns an error describing every failure. When a block was
+// only partially written to W, the OCF is not valid, and Close returns an
+// ErrOCFTruncated, which reports the size of the valid OCF that precedes the
+// partial block. Otherwise, when Close returns nil, W holds a valid OCF.
+func (ocfw *OCFWriter) Close() error {
+	var errs []error
+	if ocfw.headerPending && ocfw.err == nil {
+		if err := ocfw.writeHeader(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if ocfw.chunker != nil && ocfw.err == nil {
+		if err := ocfw.flushChunk(); err != nil {
+			errs = append(errs, fmt.Errorf("cannot write pending block: %s", err))
+		}
+	}
+	if ocfw.checksum != nil && ocfw.err == nil {
+		// NOTE: Ensure every block is on stable storage before the final
+		// metadata that describes them, so a crash between the two leaves a
+		// file whose checksum was not recorded, rather than a checksum of
+		// blocks that were lost.
+		if ocfw.syncer != nil {
+			if err := ocfw.syncer.Sync(); err != nil {
+				errs = append(errs, fmt.Errorf("cannot sync blocks: %s", err))
+			}
+		}
+		if len(errs) == 0 {
+			if err := ocfw.recordChecksum(); err != nil {
+				errs = append(errs, fmt.Errorf("cannot record OCF checksum: %s", err))
+			} else if ocfw.syncer != nil {
+				if err := ocfw.syncer.Sync(); err != nil {
+					errs = append(errs, fmt.Errorf("cannot sync OCF checksum: %s", err))
+				}
+			}
+		}
+	}
+	if ocfw.index != nil {
+		if err := ocfw.index.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("cannot close index: %s", err))
+		}
+	}
+	if ocfw.err != nil {
+		truncated := ocfw.err.(ErrOCFTruncated)
+		truncated.Err = joinErrors(append([]error{truncated.Err}, errs...))
+		return truncated
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("cannot close OCFWriter: %s", joinErrors(errs))
+	}
+	return nil
+}
+
+// ErrOCFTruncated is the error returned by OCFWriter after a block was only
+// partially written to W, so W ends with an incomplete block. Offset is the
+// size of the valid OCF preceding the incomplete block, to which W may be
+// truncated to recover the data written before it. Once a block is partially
+// written, Append and Close return this error.
+type ErrOCFTruncated struct {
+	Offset int64 // number of bytes of the valid OCF before the incomplete block
+	Err    error // error returned while writing the incomplete block
+}
+
+func (e ErrOCFTruncated) Error() string {
+	return fmt.Sprintf("OCF truncated after %d bytes: %s", e.Offset, e.Err)
+}
+
+// joinErrors returns an error whose message lists the messages of errs.
+func joinErrors(errs []error) error {
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return errors.New(strings.Join(messages, "; "))
+}
+
+// recordChecksum overwrites the checksum digest in the header. It prefers
+// io.WriterAt, which leaves the position of W unchanged, and otherwise seeks
+// back to the end of W after writing the digest.
+func (ocfw *OCFWriter) recordChecksum() error {
+	digest := ocfw.checksum.Sum(nil)
+	if w, ok := ocfw.iow.(io.WriterAt); ok {
+		_, err := w.WriteAt(digest, ocfw.checksumOffset)
+		return err
+	}
+	w := ocfw.iow.(io.WriteSeeker) // NOTE: ensured by NewOCFWriter
+	if _, err := w.Seek(ocfw.checksumOffset, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := w.Write(digest); err != nil {
+		return err
+	}
+	_, err := w.Seek(0, io.SeekEnd)
+	return err
+}
+
 // quickScanToTail advances the stream reader to the tail end of the
 // file. Rather than reading each encoded block, optionally decompressing it,
 // and then decoding it, this method reads the block count, ignoring it, then
 // reads the block size, then skips ahead to the followig block. It does this
-// repeatedly until attempts to read the file return io.EOF.
+// repeatedly until attempts to read the file return io.EOF. The offset of the
+// writer is advanced to the tail, and when the writer has a manifest, the
+// blocks following the final recorded block are recorded.
 func (ocfw *OCFWriter) quickScanToTail(ior io.Reader) error {
 	sync := make([]byte, ocfSyncLength)
+	matched := ocfw.manifest == nil || ocfw.manifest.end == 0
 	for {
 		// Read and validate block count
 		blockCount, err := longBinaryReader(ior)
 		if err != nil {
 			if err == io.EOF {
+				if !matched {
+					return fmt.Errorf("manifest records a block ending at offset %d, which is not the end of a block of the OCF", ocfw.manifest.end)
+				}
 				return nil // merely end of file, rather than error
 			}
 			return fmt.Errorf("cannot read block count: %s", err)
@@ -140,7 +565,20 @@ func (ocfw *OCFWriter) quickScanToTail(ior io.Reader) error {
 			return fmt.Errorf("cannot read when block size exceeds MaxBlockSize: %d > %d", blockSize, MaxBlockSize)
 		}
 		// Advance reader to end of block
-		if _, err = io.CopyN(ioutil.Discard, ior, blockSize); err != nil {
+		var discard io.Writer = ioutil.Discard
+		if ocfw.checksum != nil {
+			hashOCFBlockPrefix(ocfw.checksum, blockCount, blockSize)
+			discard = ocfw.checksum
+		}
+		countBytes, _ := longBinaryFromNative(nil, blockCount)
+		sizeBytes, _ := longBinaryFromNative(nil, blockSize)
+		end := ocfw.offset + int64(len(countBytes)+len(sizeBytes)) + blockSize + ocfSyncLength
+		var blockHash hash.Hash
+		if matched && ocfw.manifest != nil && end > ocfw.manifest.end {
+			blockHash = newOCFBlockHash(blockCount)
+			discard = io.MultiWriter(discard, blockHash)
+		}
+		if _, err = io.CopyN(discard, ior, blockSize); err != nil {
 			return fmt.Errorf("cannot seek to next block: %s", err)
 		}
 		// Read and validate sync marker
@@ -151,18 +589,42 @@ func (ocfw *OCFWriter) quickScanToTail(ior io.Reader) error {
 		if !bytes.Equal(sync, ocfw.header.syncMarker[:]) {
 			return fmt.Errorf("sync marker mismatch: %v != %v", sync, ocfw.header.syncMarker)
 		}
+		if ocfw.checksum != nil {
+			_, _ = ocfw.checksum.Write(sync)
+		}
+		if blockHash != nil {
+			if err = ocfw.manifest.record(sumOCFBlockHash(blockHash), end); err != nil {
+				return err
+			}
+		}
+		if !matched {
+			if end == ocfw.manifest.end {
+				matched = true
+			} else if end > ocfw.manifest.end {
+				return fmt.Errorf("manifest records a block ending at offset %d, which is not the end of a block of the OCF", ocfw.manifest.end)
+			}
+		}
+		ocfw.offset = end
 	}
 }
 
 // Append appends one or more data items to an OCF file in a block. If there are
 // more data items in the slice than MaxBlockCount allows, the data slice will
 // be chunked into multiple blocks, each not having more than MaxBlockCount
-// items.
+// items. When the OCFWriter was created with ContentDefinedBlockSize,
+// BlockSizeBytes, or BlockRecordCount, the data is instead added to the pending
+// block, and only the blocks that end by the final datum are written.
 func (ocfw *OCFWriter) Append(data interface{}) error {
+	if ocfw.err != nil {
+		return ocfw.err
+	}
 	arrayValues, err := convertArray(data)
 	if err != nil {
 		return err
 	}
+	if ocfw.chunker != nil {
+		return ocfw.appendDataIntoChunks(arrayValues)
+	}
 
 	// Chunk data so no block has more than MaxBlockCount items.
 	for int64(len(arrayValues)) > MaxBlockCount {
@@ -178,54 +640,97 @@ func (ocfw *OCFWriter) appendDataIntoBlock(data []interface{}) error {
 	var block []byte // working buffer for encoding data values
 	var err error
 
+	var recordOffsets []int64
+	if ocfw.index != nil {
+		recordOffsets = make([]int64, len(data))
+	}
+
 	// Encode and concatenate each data item into the block
-	for _, datum := range data {
+	for i, datum := range data {
+		if recordOffsets != nil {
+			recordOffsets[i] = int64(len(block))
+		}
 		if block, err = ocfw.header.codec.BinaryFromNative(block, datum); err != nil {
 			return fmt.Errorf("cannot translate datum to binary: %v; %s", datum, err)
 		}
 	}
+	return ocfw.writeBlock(int64(len(data)), block, recordOffsets)
+}
 
-	switch ocfw.header.compressionID {
-	case compressionNull:
-		// no-op
-
-	case compressionDeflate:
-		// compress into new bytes buffer.
-		bb := bytes.NewBuffer(make([]byte, 0, len(block)))
-
-		cw, _ := flate.NewWriter(bb, flate.DefaultCompression)
-		// writing bytes to cw will compress bytes and send to bb.
-		if _, err := cw.Write(block); err != nil {
-			return err
-		}
-		if err := cw.Close(); err != nil {
+// writeBlock compresses the encoded data of a block, and writes the block,
+// along with its entries in the index and manifest, when configured.
+func (ocfw *OCFWriter) writeBlock(count int64, block []byte, recordOffsets []int64) error {
+	var err error
+	if ocfw.headerPending {
+		if err = ocfw.writeHeader(); err != nil {
 			return err
 		}
-		block = bb.Bytes()
-
-	case compressionSnappy:
-		compressed := snappy.Encode(nil, block)
-
-		// OCF requires snappy to have CRC32 checksum after each snappy block
-		compressed = append(compressed, 0, 0, 0, 0)                                           // expand slice by 4 bytes so checksum will fit
-		binary.BigEndian.PutUint32(compressed[len(compressed)-4:], crc32.ChecksumIEEE(block)) // checksum of decompressed block
-
-		block = compressed
-
-	default:
-		return fmt.Errorf("should not get here: cannot compress block using unrecognized compression: %d", ocfw.header.compressionID)
+	}
+	if block, err = compressOCFBlock(ocfw.header.compressionID, block); err != nil {
+		return err
+	}
 
+	var blockHash [sha256.Size]byte
+	if ocfw.manifest != nil {
+		if blockHash = ocfBlockHash(count, block); ocfw.manifest.has(blockHash) {
+			ocfw.skipped++
+			return nil
+		}
 	}
 
 	// create file data block
 	buf := make([]byte, 0, len(block)+ocfBlockConst) // pre-allocate block bytes
-	buf, _ = longBinaryFromNative(buf, len(data))    // block count (number of data items)
+	buf, _ = longBinaryFromNative(buf, count)        // block count (number of data items)
 	buf, _ = longBinaryFromNative(buf, len(block))   // block size (number of bytes in block)
 	buf = append(buf, block...)                      // serialized objects
 	buf = append(buf, ocfw.header.syncMarker[:]...)  // sync marker
 
-	_, err = ocfw.iow.Write(buf)
-	return err
+	if n, err := ocfw.iow.Write(buf); err != nil {
+		if n > 0 {
+			ocfw.err = ErrOCFTruncated{Offset: ocfw.offset, Err: err}
+			return ocfw.err
+		}
+		return err
+	}
+	if ocfw.checksum != nil {
+		_, _ = ocfw.checksum.Write(buf)
+	}
+	// NOTE: Once the block is written, it is part of the OCF even when it
+	// cannot be synced, so the offset advances and the block is indexed and
+	// recorded before a sync error is returned, lest later blocks be indexed
+	// at the wrong offsets.
+	var syncErr error
+	if ocfw.syncer != nil {
+		syncErr = ocfw.syncer.Sync()
+	}
+	offset := ocfw.offset
+	ocfw.offset += int64(len(buf))
+	if ocfw.index != nil {
+		// NOTE: The index entry is written after the block, so the index
+		// never refers to a block that was not written.
+		entry := map[string]interface{}{"offset": offset, "recordOffsets": recordOffsets}
+		if err = ocfw.index.Append([]interface{}{entry}); err != nil {
+			return fmt.Errorf("cannot write index: %s", err)
+		}
+	}
+	if ocfw.manifest != nil {
+		// NOTE: The block is recorded after it is written, so the manifest
+		// never records a block that was not written. A block written but
+		// not recorded is recorded when appending to the OCF again.
+		if err = ocfw.manifest.record(blockHash, ocfw.offset); err != nil {
+			return err
+		}
+	}
+	if syncErr != nil {
+		return fmt.Errorf("cannot sync block: %s", syncErr)
+	}
+	return nil
+}
+
+// SkippedBlocks returns the number of blocks Append did not write because the
+// manifest of the OCFWriter records them.
+func (ocfw *OCFWriter) SkippedBlocks() int64 {
+	return ocfw.skipped
 }
 
 // Codec returns the codec used by OCFWriter. This function provided because
@@ -240,14 +745,8 @@ func (ocfw *OCFWriter) Codec() *Codec {
 // existing OCF which uses a different compression algorithm than requested
 // during instantiation.  the OCF file.
 func (ocfw *OCFWriter) CompressionName() string {
-	switch ocfw.header.compressionID {
-	case compressionNull:
-		return CompressionNullLabel
-	case compressionDeflate:
-		return CompressionDeflateLabel
-	case compressionSnappy:
-		return CompressionSnappyLabel
-	default:
-		return "should not get here: unrecognized compression algorithm"
+	if name, ok := compressionName(ocfw.header.compressionID); ok {
+		return name
 	}
+	return "should not get here: unrecognized compression algorithm"
 }
@@ -7,12 +7,20 @@
 // distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
 // WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
 
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
 package goavro
 
 import (
 	"bytes"
+	"errors"
+	"hash/crc32"
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -295,3 +303,298 @@ func TestOCFWriterAppendSomeItemsToSomeItems(t *testing.T) {
 		t.Errorf("GOT: %v; WANT: %v", actual, expected)
 	}
 }
+
+func TestOCFWriterAppendVerifySchema(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goavro")
+	ensureError(t, err)
+	defer os.RemoveAll(dir)
+
+	pathname := filepath.Join(dir, "append.avro")
+	file, err := os.Create(pathname)
+	ensureError(t, err)
+	defer file.Close()
+	ocfw, err := NewOCFWriter(OCFConfig{W: file, Schema: `{"type":"record","name":"r","fields":[{"name":"a","type":"long"}]}`})
+	ensureError(t, err)
+	ensureError(t, ocfw.Append([]interface{}{map[string]interface{}{"a": 1}}))
+
+	// the Parsing Canonical Forms of the schemas match
+	_, err = file.Seek(0, io.SeekStart)
+	ensureError(t, err)
+	ocfw, err = NewOCFWriter(OCFConfig{W: file, Schema: `{"type": "record", "name": "r", "doc": "rows", "fields": [{"name": "a", "type": "long"}]}`, VerifyAppendSchema: true})
+	ensureError(t, err)
+	ensureError(t, ocfw.Append([]interface{}{map[string]interface{}{"a": 2}}))
+
+	for _, config := range []OCFConfig{
+		{Schema: `{"type":"record","name":"r","fields":[{"name":"a","type":"int"}]}`, VerifyAppendSchema: true},
+		{Schema: `{"type":"record","name":"r","fields":[{"name":"a","type":"int"}]}`},
+		{VerifyAppendSchema: true},
+	} {
+		_, err = file.Seek(0, io.SeekStart)
+		ensureError(t, err)
+		config.W = file
+		_, err = NewOCFWriter(config)
+		switch {
+		case config.Schema == "":
+			ensureError(t, err, "cannot create OCFWriter", "without either Codec or Schema")
+		case config.VerifyAppendSchema:
+			ensureError(t, err, "cannot create OCFWriter", `append schema ought to match schema of existing OCF`, `"type":"int"`)
+		default:
+			ensureError(t, err) // the schema of the OCF is used
+		}
+	}
+
+	reader, err := os.Open(pathname)
+	ensureError(t, err)
+	defer reader.Close()
+	ocfr, err := NewOCFReader(reader)
+	ensureError(t, err)
+	var count int
+	for ocfr.Scan() {
+		_, err = ocfr.Read()
+		ensureError(t, err)
+		count++
+	}
+	ensureError(t, ocfr.Err())
+	if actual, expected := count, 2; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
+
+// syncRecorder is an in-memory io.WriterAt with a Sync method, which records
+// the order in which it is written and synced.
+type syncRecorder struct {
+	buf    []byte
+	events []string
+}
+
+func (sr *syncRecorder) Write(p []byte) (int, error) {
+	sr.buf = append(sr.buf, p...)
+	sr.events = append(sr.events, "write")
+	return len(p), nil
+}
+
+func (sr *syncRecorder) WriteAt(p []byte, off int64) (int, error) {
+	copy(sr.buf[off:], p)
+	sr.events = append(sr.events, "writeAt")
+	return len(p), nil
+}
+
+func (sr *syncRecorder) Sync() error {
+	sr.events = append(sr.events, "sync")
+	return nil
+}
+
+func TestOCFWriterSyncOnFlush(t *testing.T) {
+	sr := new(syncRecorder)
+	ocfw, err := NewOCFWriter(OCFConfig{W: sr, Schema: `"long"`, Checksum: OCFChecksumCRC32, SyncOnFlush: true})
+	ensureError(t, err)
+	ensureError(t, ocfw.Append([]interface{}{13, 42}))
+	ensureError(t, ocfw.Append([]interface{}{-10}))
+	ensureError(t, ocfw.Close())
+
+	expected := "write,sync,write,sync,write,sync,sync,writeAt,sync"
+	if actual := strings.Join(sr.events, ","); actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	ocfr, err := NewOCFReader(bytes.NewReader(sr.buf))
+	ensureError(t, err)
+	var count int
+	for ocfr.Scan() {
+		_, err := ocfr.Read()
+		ensureError(t, err)
+		count++
+	}
+	ensureError(t, ocfr.Err())
+	if actual, expected := count, 3; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
+
+func TestOCFWriterSyncOnFlushRequiresSync(t *testing.T) {
+	_, err := NewOCFWriter(OCFConfig{W: new(bytes.Buffer), Schema: `"long"`, SyncOnFlush: true})
+	ensureError(t, err, "cannot create OCFWriter", "Sync method")
+}
+
+func TestOCFWriterTruncated(t *testing.T) {
+	bb := new(bytes.Buffer)
+	ocfw, err := NewOCFWriter(OCFConfig{W: ShortWriter(bb, 1<<10), Schema: `"long"`})
+	ensureError(t, err)
+	ensureError(t, ocfw.Append([]interface{}{13, 42}))
+	valid := int64(bb.Len())
+
+	data := make([]interface{}, 1<<10)
+	for i := range data {
+		data[i] = i
+	}
+	err = ocfw.Append(data)
+	truncated, ok := err.(ErrOCFTruncated)
+	if !ok {
+		t.Fatalf("GOT: %#v; WANT: %T", err, truncated)
+	}
+	if actual, expected := truncated.Offset, valid; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	ensureError(t, ocfw.Append([]interface{}{-10}), "OCF truncated after", "short write")
+
+	err = ocfw.Close()
+	if _, ok := err.(ErrOCFTruncated); !ok {
+		t.Fatalf("GOT: %#v; WANT: %T", err, truncated)
+	}
+
+	// the prefix before the incomplete block is a valid OCF
+	ocfr, err := NewOCFReader(bytes.NewReader(bb.Bytes()[:truncated.Offset]))
+	ensureError(t, err)
+	var count int
+	for ocfr.Scan() {
+		_, err := ocfr.Read()
+		ensureError(t, err)
+		count++
+	}
+	ensureError(t, ocfr.Err())
+	if actual, expected := count, 2; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
+
+// failingSyncer is a syncRecorder whose Sync fails once fail is set.
+type failingSyncer struct {
+	syncRecorder
+	fail bool
+}
+
+func (fs *failingSyncer) Sync() error {
+	if fs.fail {
+		return errors.New("sync failed")
+	}
+	return fs.syncRecorder.Sync()
+}
+
+func TestOCFWriterCloseReportsEveryError(t *testing.T) {
+	file, index := new(failingSyncer), new(failingSyncer)
+	ocfw, err := NewOCFWriter(OCFConfig{W: file, Index: index, Schema: `"long"`, Checksum: OCFChecksumCRC32, SyncOnFlush: true})
+	ensureError(t, err)
+	ensureError(t, ocfw.Append([]interface{}{13}))
+	// NOTE: Give the index a checksum, so closing it also syncs.
+	ocfw.index.checksum = crc32.NewIEEE()
+
+	file.fail, index.fail = true, true
+	ensureError(t, ocfw.Close(), "cannot close OCFWriter", "cannot sync blocks: sync failed", "cannot close index: cannot close OCFWriter: cannot sync blocks: sync failed")
+}
+
+func TestOCFWriterBlockSyncFailure(t *testing.T) {
+	file, index := new(failingSyncer), new(failingSyncer)
+	ocfw, err := NewOCFWriter(OCFConfig{W: file, Index: index, Schema: `"long"`, SyncOnFlush: true})
+	ensureError(t, err)
+	ensureError(t, ocfw.Append([]interface{}{13}))
+	file.fail = true
+	ensureError(t, ocfw.Append([]interface{}{42}), "cannot sync block: sync failed")
+	file.fail = false
+	ensureError(t, ocfw.Append([]interface{}{-10}))
+	ensureError(t, ocfw.Close())
+
+	// the block that was written but not synced is indexed, so the index
+	// locates the following block
+	entries, err := ReadOCFIndex(bytes.NewReader(index.buf))
+	ensureError(t, err)
+	ocfr, err := NewOCFIndexedReader(bytes.NewReader(file.buf), entries)
+	ensureError(t, err)
+	for position, expected := range []int64{13, 42, -10} {
+		datum, err := ocfr.Read(int64(position))
+		ensureError(t, err)
+		if datum != expected {
+			t.Errorf("GOT: %v; WANT: %v", datum, expected)
+		}
+	}
+}
+
+func TestOCFWriterSetMetaData(t *testing.T) {
+	bb := new(bytes.Buffer)
+	config := OCFConfig{W: bb, Schema: `"long"`, MetaData: map[string][]byte{"owner": []byte("ingest")}, DeferHeader: true}
+	ocfw, err := NewOCFWriter(config)
+	ensureError(t, err)
+	if actual, expected := bb.Len(), 0; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	ensureError(t, ocfw.SetMetaData("source", []byte("job-42")))
+	ensureError(t, ocfw.SetMetaData("avro.schema", []byte(`"int"`)), "cannot set OCF metadata", "reserved")
+	if _, ok := config.MetaData["source"]; ok {
+		t.Errorf("GOT: %v; WANT: %v", config.MetaData, "unmodified")
+	}
+	metadata := ocfw.MetaData()
+	for key, expected := range map[string]string{"owner": "ingest", "source": "job-42", "avro.schema": `"long"`, "avro.codec": "null"} {
+		if actual := string(metadata[key]); actual != expected {
+			t.Errorf("%s: GOT: %v; WANT: %v", key, actual, expected)
+		}
+	}
+
+	ensureError(t, ocfw.Append([]interface{}{1, 2}))
+	ensureError(t, ocfw.SetMetaData("late", nil), "cannot set OCF metadata", "header already written")
+
+	ocfr, err := NewOCFReader(bytes.NewReader(bb.Bytes()))
+	ensureError(t, err)
+	if actual, expected := string(ocfr.MetaData()["source"]), "job-42"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	var count int
+	for ocfr.Scan() {
+		_, err := ocfr.Read()
+		ensureError(t, err)
+		count++
+	}
+	ensureError(t, ocfr.Err())
+	if actual, expected := count, 2; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	ocfw, err = NewOCFWriter(OCFConfig{W: new(bytes.Buffer), Schema: `"long"`})
+	ensureError(t, err)
+	ensureError(t, ocfw.SetMetaData("source", nil), "header already written")
+}
+
+func TestOCFWriterDeferHeaderClose(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goavro")
+	ensureError(t, err)
+	defer os.RemoveAll(dir)
+
+	// An empty OCF with a checksum: Close writes the header, then records
+	// the checksum in it.
+	file, err := os.Create(filepath.Join(dir, "empty.avro"))
+	ensureError(t, err)
+	defer file.Close()
+	ocfw, err := NewOCFWriter(OCFConfig{W: file, Schema: `"long"`, Checksum: OCFChecksumCRC32, DeferHeader: true})
+	ensureError(t, err)
+	ensureError(t, ocfw.SetMetaData("source", []byte("empty")))
+	ensureError(t, ocfw.Close())
+
+	contents, err := ioutil.ReadFile(filepath.Join(dir, "empty.avro"))
+	ensureError(t, err)
+	data, err := readChecksumOCF(t, contents)
+	ensureError(t, err)
+	if len(data) != 0 {
+		t.Errorf("GOT: %v; WANT: %v", data, []interface{}{})
+	}
+	ocfr, err := NewOCFReader(bytes.NewReader(contents))
+	ensureError(t, err)
+	if actual, expected := string(ocfr.MetaData()["source"]), "empty"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	// A deferred header with a checksum and blocks.
+	file, err = os.Create(filepath.Join(dir, "data.avro"))
+	ensureError(t, err)
+	defer file.Close()
+	ocfw, err = NewOCFWriter(OCFConfig{W: file, Schema: `"long"`, Checksum: OCFChecksumSHA256, DeferHeader: true})
+	ensureError(t, err)
+	ensureError(t, ocfw.SetMetaData("source", []byte("data")))
+	ensureError(t, ocfw.Append([]interface{}{1}))
+	ensureError(t, ocfw.Append([]interface{}{2}))
+	ensureError(t, ocfw.Close())
+	contents, err = ioutil.ReadFile(filepath.Join(dir, "data.avro"))
+	ensureError(t, err)
+	data, err = readChecksumOCF(t, contents)
+	ensureError(t, err)
+	if actual, expected := len(data), 2; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
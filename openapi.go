@@ -0,0 +1,168 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import "fmt"
+
+// openAPIRefPrefix is the JSON pointer prefix of OpenAPI component schemas.
+const openAPIRefPrefix = "#/components/schemas/"
+
+// OpenAPIComponentSchemas returns OpenAPI 3.1 component schemas equivalent to
+// the Codec's schema, keyed by the full name of each named type. References
+// between named types use `$ref` pointers into `#/components/schemas/`, so the
+// returned map may be used directly as the `components.schemas` object of an
+// OpenAPI document. When the top level schema is not a named type, it is
+// returned under the provided rootName.
+//
+// Avro unions are translated to `oneOf`, record fields without default values
+// are listed as `required`, and logical types are translated to the closest
+// OpenAPI format, such as `date-time` for timestamps. Because OpenAPI 3.1 uses
+// JSON Schema, the Avro null type is translated to `{"type":"null"}`.
+//
+//     schemas, err := codec.OpenAPIComponentSchemas("Root")
+//     if err != nil {
+//         return err
+//     }
+//     buf, err := json.Marshal(map[string]interface{}{"schemas": schemas})
+func (c *Codec) OpenAPIComponentSchemas(rootName string) (map[string]interface{}, error) {
+	root, err := schemaNodeFromCodec(c)
+	if err != nil {
+		return nil, fmt.Errorf("cannot generate OpenAPI schemas: %s", err)
+	}
+	components := make(map[string]interface{})
+	schema := openAPISchema(root, components)
+	if !root.isNamed() {
+		if rootName == "" {
+			return nil, fmt.Errorf("cannot generate OpenAPI schemas: root name required when schema is not a named type: %s", root.label())
+		}
+		components[rootName] = schema
+	}
+	return components, nil
+}
+
+// openAPISchema returns the OpenAPI schema object for the node, adding named
+// types to components the first time they are encountered.
+func openAPISchema(n *schemaNode, components map[string]interface{}) map[string]interface{} {
+	if n.isNamed() {
+		if _, ok := components[n.fullName]; !ok {
+			// NOTE: reserve the name before building the schema to support
+			// recursive types.
+			components[n.fullName] = nil
+			components[n.fullName] = openAPINamedSchema(n, components)
+		}
+		return map[string]interface{}{"$ref": openAPIRefPrefix + n.fullName}
+	}
+
+	switch n.typeName {
+	case "array":
+		return map[string]interface{}{"type": "array", "items": openAPISchema(n.items, components)}
+	case "map":
+		return map[string]interface{}{"type": "object", "additionalProperties": openAPISchema(n.values, components)}
+	case "union":
+		members := make([]interface{}, len(n.members))
+		for i, member := range n.members {
+			members[i] = openAPISchema(member, components)
+		}
+		return map[string]interface{}{"oneOf": members}
+	}
+
+	schema := openAPIPrimitive(n.typeName)
+	switch n.logicalType {
+	case "timestamp-millis", "timestamp-micros":
+		schema = map[string]interface{}{"type": "string", "format": "date-time"}
+	case "date":
+		schema = map[string]interface{}{"type": "string", "format": "date"}
+	case "time-millis", "time-micros":
+		schema = map[string]interface{}{"type": "string", "format": "time"}
+	case "uuid":
+		schema = map[string]interface{}{"type": "string", "format": "uuid"}
+	case "decimal":
+		schema = openAPIDecimal(n)
+	}
+	if n.doc != "" {
+		schema["description"] = n.doc
+	}
+	return schema
+}
+
+func openAPIPrimitive(typeName string) map[string]interface{} {
+	switch typeName {
+	case "boolean":
+		return map[string]interface{}{"type": "boolean"}
+	case "int":
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case "long":
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case "float":
+		return map[string]interface{}{"type": "number", "format": "float"}
+	case "double":
+		return map[string]interface{}{"type": "number", "format": "double"}
+	case "bytes":
+		return map[string]interface{}{"type": "string", "format": "byte"}
+	case "string":
+		return map[string]interface{}{"type": "string"}
+	default: // null
+		return map[string]interface{}{"type": "null"}
+	}
+}
+
+func openAPIDecimal(n *schemaNode) map[string]interface{} {
+	return map[string]interface{}{
+		"type":             "string",
+		"format":           "decimal",
+		"x-avro-precision": n.precision,
+		"x-avro-scale":     n.scale,
+	}
+}
+
+// openAPINamedSchema returns the component schema for a named type.
+func openAPINamedSchema(n *schemaNode, components map[string]interface{}) map[string]interface{} {
+	var schema map[string]interface{}
+
+	switch n.typeName {
+	case "enum":
+		symbols := make([]interface{}, len(n.symbols))
+		for i, symbol := range n.symbols {
+			symbols[i] = symbol
+		}
+		schema = map[string]interface{}{"type": "string", "enum": symbols}
+	case "fixed":
+		if n.logicalType == "decimal" {
+			schema = openAPIDecimal(n)
+		} else {
+			schema = map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		schema["x-avro-size"] = n.size
+	default: // record
+		properties := make(map[string]interface{}, len(n.fields))
+		var required []interface{}
+		for _, f := range n.fields {
+			property := openAPISchema(f.node, components)
+			if f.doc != "" {
+				property["description"] = f.doc
+			}
+			if f.hasDefault {
+				property["default"] = copyNative(f.defaultValue)
+			} else {
+				required = append(required, f.name)
+			}
+			properties[f.name] = property
+		}
+		schema = map[string]interface{}{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+	}
+
+	if n.doc != "" {
+		schema["description"] = n.doc
+	}
+	return schema
+}
@@ -0,0 +1,78 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestOpenAPIComponentSchemasRecord(t *testing.T) {
+	codec := newCodecUsingV2(t, `{
+  "type": "record",
+  "name": "Event",
+  "namespace": "com.example",
+  "doc": "An event.",
+  "fields": [
+    {"name": "id", "type": "string"},
+    {"name": "ts", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "kind", "type": {"type": "enum", "name": "Kind", "symbols": ["A", "B"]}, "default": "A"},
+    {"name": "counts", "type": {"type": "map", "values": "int"}},
+    {"name": "next", "type": ["null", "Event"], "default": null}
+  ]
+}`)
+	schemas, err := codec.OpenAPIComponentSchemas("")
+	ensureError(t, err)
+
+	buf, err := json.Marshal(schemas)
+	ensureError(t, err)
+
+	want := `{"com.example.Event":{"description":"An event.","properties":{` +
+		`"counts":{"additionalProperties":{"format":"int32","type":"integer"},"type":"object"},` +
+		`"id":{"type":"string"},` +
+		`"kind":{"$ref":"#/components/schemas/com.example.Kind","default":"A"},` +
+		`"next":{"default":null,"oneOf":[{"type":"null"},{"$ref":"#/components/schemas/com.example.Event"}]},` +
+		`"ts":{"format":"date-time","type":"string"}},` +
+		`"required":["id","ts","counts"],"type":"object"},` +
+		`"com.example.Kind":{"enum":["A","B"],"type":"string"}}`
+	if got := string(buf); got != want {
+		t.Errorf("GOT: %s; WANT: %s", got, want)
+	}
+}
+
+func TestOpenAPIComponentSchemasUnnamedRoot(t *testing.T) {
+	codec := newCodecUsingV2(t, `{"type":"array","items":"bytes"}`)
+
+	_, err := codec.OpenAPIComponentSchemas("")
+	ensureError(t, err, "root name required")
+
+	schemas, err := codec.OpenAPIComponentSchemas("Blobs")
+	ensureError(t, err)
+	buf, err := json.Marshal(schemas)
+	ensureError(t, err)
+	if got, want := string(buf), `{"Blobs":{"items":{"format":"byte","type":"string"},"type":"array"}}`; got != want {
+		t.Errorf("GOT: %s; WANT: %s", got, want)
+	}
+}
+
+func TestOpenAPIComponentSchemasCopiesDefaults(t *testing.T) {
+	codec := newCodecUsingV2(t, `{"type":"record","name":"r","fields":[{"name":"m","type":{"type":"map","values":"long"},"default":{"a":1}}]}`)
+	schemas, err := codec.OpenAPIComponentSchemas("")
+	ensureError(t, err)
+	properties := schemas["r"].(map[string]interface{})["properties"].(map[string]interface{})
+	properties["m"].(map[string]interface{})["default"].(map[string]interface{})["a"] = "modified"
+
+	schemas, err = codec.OpenAPIComponentSchemas("")
+	ensureError(t, err)
+	properties = schemas["r"].(map[string]interface{})["properties"].(map[string]interface{})
+	if got, want := properties["m"].(map[string]interface{})["default"].(map[string]interface{})["a"], float64(1); got != want {
+		t.Errorf("GOT: %v; WANT: %v", got, want)
+	}
+}
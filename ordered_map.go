@@ -0,0 +1,117 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"fmt"
+	"sort"
+)
+
+// MapItem is one key and its value in an OrderedMap.
+type MapItem struct {
+	Key   string
+	Value interface{}
+}
+
+// OrderedMap is the native form of an Avro map that preserves the order of its
+// items. Codecs created with the OrderedMapDecoding option decode Avro maps as
+// OrderedMap values, with the items in the order they were written, and every
+// Codec encodes an OrderedMap in the order of its items. Re-encoding a decoded
+// OrderedMap therefore reproduces the original bytes, which is not the case for
+// a Go map, whose iteration order is unspecified.
+//
+//     datum := goavro.OrderedMap{
+//         {Key: "first", Value: int32(1)},
+//         {Key: "second", Value: int32(2)},
+//     }
+//     buf, err := codec.BinaryFromNative(nil, datum) // "first" is encoded before "second"
+type OrderedMap []MapItem
+
+// Map returns the items as a Go map.
+func (m OrderedMap) Map() map[string]interface{} {
+	values := make(map[string]interface{}, len(m))
+	for _, item := range m {
+		values[item.Key] = item.Value
+	}
+	return values
+}
+
+// sortedMapItems returns the items of values in the lexicographic order of
+// their keys.
+func sortedMapItems(values map[string]interface{}) OrderedMap {
+	items := make(OrderedMap, 0, len(values))
+	for k, v := range values {
+		items = append(items, MapItem{Key: k, Value: v})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Key < items[j].Key })
+	return items
+}
+
+// duplicateKey returns the first key that appears more than once, if any.
+func (m OrderedMap) duplicateKey() (string, bool) {
+	seen := make(map[string]struct{}, len(m))
+	for _, item := range m {
+		if _, ok := seen[item.Key]; ok {
+			return item.Key, true
+		}
+		seen[item.Key] = struct{}{}
+	}
+	return "", false
+}
+
+func orderedMapBinaryFromNative(buf []byte, items OrderedMap, valueCodec *Codec, option *CodecOption) ([]byte, error) {
+	if key, ok := items.duplicateKey(); ok {
+		return nil, fmt.Errorf("cannot encode binary map: duplicate key: %q", key)
+	}
+	blocks := newBlockEncoder(option, len(items))
+	for _, item := range items {
+		buf = blocks.beginItem(buf)
+		// only fails when given non string, so elide error checking
+		buf, _ = stringBinaryFromNative(buf, item.Key)
+		var err error
+		if buf, err = valueCodec.binaryFromNative(buf, item.Value); err != nil {
+			return nil, fmt.Errorf("cannot encode binary map value for key %q: %v: %s", item.Key, item.Value, err)
+		}
+		buf = blocks.endItem(buf)
+	}
+	return blocks.finish(buf) // append tailing 0 block count to signal end of Map
+}
+
+func orderedMapTextDecoder(buf []byte, valueCodec *Codec) (interface{}, []byte, error) {
+	var keys []string
+	mapValues, buf, err := orderedGenericMapTextDecoder(buf, valueCodec, nil, &keys)
+	if err != nil {
+		return nil, nil, err
+	}
+	items := make(OrderedMap, len(keys))
+	for i, key := range keys {
+		items[i] = MapItem{Key: key, Value: mapValues[key]}
+	}
+	return items, buf, nil
+}
+
+func orderedMapTextEncoder(buf []byte, items OrderedMap, valueCodec *Codec) ([]byte, error) {
+	if key, ok := items.duplicateKey(); ok {
+		return nil, fmt.Errorf("cannot encode textual map: duplicate key: %q", key)
+	}
+	buf = append(buf, '{')
+	for i, item := range items {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf, _ = stringTextualFromNative(buf, item.Key)
+		buf = append(buf, ':')
+		var err error
+		if buf, err = valueCodec.textualFromNative(buf, item.Value); err != nil {
+			return nil, fmt.Errorf("cannot encode textual map: value for %q does not match its schema: %s", item.Key, err)
+		}
+	}
+	return append(buf, '}'), nil
+}
@@ -0,0 +1,107 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestOrderedMapEncode(t *testing.T) {
+	datum := OrderedMap{{Key: "b", Value: int32(2)}, {Key: "a", Value: int32(1)}}
+	testBinaryEncodePass(t, `{"type":"map","values":"int"}`, datum, []byte{0x04, 0x02, 'b', 0x04, 0x02, 'a', 0x02, 0x00})
+	testTextEncodePass(t, `{"type":"map","values":"int"}`, datum, []byte(`{"b":2,"a":1}`))
+	testTextEncodePass(t, `{"type":"map","values":"int"}`, OrderedMap{}, []byte(`{}`))
+
+	testBinaryEncodeFail(t, `{"type":"map","values":"int"}`, OrderedMap{{Key: "a", Value: 1}, {Key: "a", Value: 2}}, `duplicate key: "a"`)
+	testTextEncodeFail(t, `{"type":"map","values":"int"}`, OrderedMap{{Key: "a", Value: "x"}}, `value for "a" does not match its schema`)
+}
+
+func TestCodecOptionOrderedMapDecoding(t *testing.T) {
+	schema := `{"type":"record","name":"r1","fields":[{"name":"m","type":{"type":"map","values":["null","long"]}}]}`
+	codec := newCodecWithOptionsUsingV2(t, schema, &CodecOption{OrderedMapDecoding: true})
+
+	// written by another implementation in two blocks, the second with a
+	// byte size
+	buf := []byte{
+		0x04, 0x02, 'z', 0x02, 0x02, 0x02, 'y', 0x00,
+		0x01, 0x08, 0x02, 'x', 0x02, 0x06,
+		0x00,
+	}
+	datum, rest, err := codec.NativeFromBinary(buf)
+	ensureError(t, err)
+	if len(rest) != 0 {
+		t.Errorf("GOT: %v; WANT: %v", rest, []byte{})
+	}
+	want := map[string]interface{}{"m": OrderedMap{
+		{Key: "z", Value: Union("long", int64(1))},
+		{Key: "y", Value: nil},
+		{Key: "x", Value: Union("long", int64(3))},
+	}}
+	if !reflect.DeepEqual(datum, want) {
+		t.Fatalf("GOT: %#v; WANT: %#v", datum, want)
+	}
+
+	again, err := codec.BinaryFromNative(nil, datum)
+	ensureError(t, err)
+	if expected := []byte{0x06, 0x02, 'z', 0x02, 0x02, 0x02, 'y', 0x00, 0x02, 'x', 0x02, 0x06, 0x00}; !bytes.Equal(again, expected) {
+		t.Errorf("GOT: %v; WANT: %v", again, expected)
+	}
+
+	text, err := codec.TextualFromNative(nil, datum)
+	ensureError(t, err)
+	if expected := `{"m":{"z":{"long":1},"y":null,"x":{"long":3}}}`; string(text) != expected {
+		t.Errorf("GOT: %s; WANT: %s", text, expected)
+	}
+	datum, _, err = codec.NativeFromTextual(text)
+	ensureError(t, err)
+	if !reflect.DeepEqual(datum, want) {
+		t.Errorf("GOT: %#v; WANT: %#v", datum, want)
+	}
+
+	_, _, err = codec.NativeFromBinary([]byte{0x04, 0x02, 'a', 0x00, 0x02, 'a', 0x00, 0x00})
+	ensureError(t, err, `duplicate key: "a"`)
+
+	if got := want["m"].(OrderedMap).Map(); len(got) != 3 || got["y"] != nil {
+		t.Errorf("GOT: %v; WANT: 3 items", got)
+	}
+}
+
+func TestCodecOptionSortedMapEncoding(t *testing.T) {
+	codec := newCodecWithOptionsUsingV2(t, `{"type":"map","values":"int"}`, &CodecOption{SortedMapEncoding: true})
+	datum := map[string]interface{}{"c": 3, "a": 1, "b": 2, "": 0}
+	expected := []byte{0x08, 0x00, 0x00, 0x02, 'a', 0x02, 0x02, 'b', 0x04, 0x02, 'c', 0x06, 0x00}
+
+	// every encoding of the datum is identical
+	for i := 0; i < 16; i++ {
+		buf, err := codec.BinaryFromNative(nil, datum)
+		ensureError(t, err)
+		if !bytes.Equal(buf, expected) {
+			t.Fatalf("GOT: %v; WANT: %v", buf, expected)
+		}
+	}
+
+	text, err := codec.TextualFromNative(nil, datum)
+	ensureError(t, err)
+	if expected := `{"":0,"a":1,"b":2,"c":3}`; string(text) != expected {
+		t.Errorf("GOT: %s; WANT: %s", text, expected)
+	}
+
+	// ordered maps keep the order of their items
+	buf, err := codec.BinaryFromNative(nil, OrderedMap{{Key: "b", Value: 2}, {Key: "a", Value: 1}})
+	ensureError(t, err)
+	if expected := []byte{0x04, 0x02, 'b', 0x04, 0x02, 'a', 0x02, 0x00}; !bytes.Equal(buf, expected) {
+		t.Errorf("GOT: %v; WANT: %v", buf, expected)
+	}
+
+	_, err = codec.BinaryFromNative(nil, map[string]interface{}{"a": "x"})
+	ensureError(t, err, `value for key "a"`)
+}
@@ -0,0 +1,291 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PartitionNull is the partition value of a missing or null field, which is
+// the value Hive uses for the default partition.
+const PartitionNull = "__HIVE_DEFAULT_PARTITION__"
+
+// PartitionSpec computes the partition directory paths of records, such as
+// "dt=2019-06-01/hour=13", so files land in the layouts expected by query
+// engines such as Hive, Spark, Trino, and Iceberg tables using Hive style
+// paths.
+type PartitionSpec struct {
+	spec   string
+	fields []partitionField
+}
+
+type partitionField struct {
+	name  string   // name of the directory level
+	path  []string // names of the record fields leading to the value
+	value func(interface{}) (string, error)
+}
+
+// NewPartitionSpec parses a partition spec, which lists the levels of the
+// partition path separated by slashes. Each level is the name of the
+// directory level, an equal sign, and a transform of a record field, whose
+// fields are separated by periods when it is nested:
+//
+//     identity(field)      the value of the field; "field" alone is the same
+//     year(field)          year of a time.Time field, in UTC, such as 2019
+//     month(field)         month of the year, such as 06
+//     day(field)           day of the month, such as 01
+//     hour(field)          hour of the day, such as 13
+//     date(field)          date, such as 2019-06-01
+//     format(field,layout) time formatted using the layout of time.Format
+//     bucket(field,N)      hash of the value, from 0 to N-1
+//     truncate(field,W)    string truncated to W runes, or integer rounded
+//                          down to a multiple of W
+//
+// For instance:
+//
+//     spec, err := goavro.NewPartitionSpec("dt=date(event.ts)/hour=hour(event.ts)/country=country")
+//     if err != nil {
+//         return err
+//     }
+//     path, err := spec.Path(record) // dt=2019-06-01/hour=13/country=NL
+//
+// Values are escaped like Hive partition values, and missing or null values
+// are PartitionNull. Union values are transformed as the value of their
+// member.
+func NewPartitionSpec(spec string) (*PartitionSpec, error) {
+	ps := &PartitionSpec{spec: spec}
+	names := make(map[string]struct{})
+	for _, level := range strings.Split(spec, "/") {
+		f, err := newPartitionField(strings.TrimSpace(level))
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse partition spec %q: %s", spec, err)
+		}
+		if _, ok := names[f.name]; ok {
+			return nil, fmt.Errorf("cannot parse partition spec %q: duplicate level name: %q", spec, f.name)
+		}
+		names[f.name] = struct{}{}
+		ps.fields = append(ps.fields, f)
+	}
+	return ps, nil
+}
+
+func newPartitionField(level string) (partitionField, error) {
+	eq := strings.IndexByte(level, '=')
+	if eq <= 0 {
+		return partitionField{}, fmt.Errorf("level ought to be name=transform(field): %q", level)
+	}
+	f := partitionField{name: strings.TrimSpace(level[:eq])}
+	expression := strings.TrimSpace(level[eq+1:])
+	transform, field, arg := "identity", expression, ""
+	if open := strings.IndexByte(expression, '('); open >= 0 {
+		if !strings.HasSuffix(expression, ")") {
+			return partitionField{}, fmt.Errorf("transform ought to end with a parenthesis: %q", expression)
+		}
+		transform = strings.TrimSpace(expression[:open])
+		args := strings.SplitN(expression[open+1:len(expression)-1], ",", 2)
+		field = strings.TrimSpace(args[0])
+		if len(args) == 2 {
+			arg = strings.TrimSpace(args[1])
+		}
+	}
+	if field == "" {
+		return partitionField{}, fmt.Errorf("level %q ought to name a field", f.name)
+	}
+	f.path = strings.Split(field, ".")
+
+	needsArg := transform == "format" || transform == "bucket" || transform == "truncate"
+	if needsArg != (arg != "") {
+		if needsArg {
+			return partitionField{}, fmt.Errorf("transform %s of level %q ought to have an argument", transform, f.name)
+		}
+		return partitionField{}, fmt.Errorf("transform %s of level %q ought not to have an argument", transform, f.name)
+	}
+	switch transform {
+	case "identity":
+		f.value = partitionIdentity
+	case "year":
+		f.value = partitionTime("2006")
+	case "month":
+		f.value = partitionTime("01")
+	case "day":
+		f.value = partitionTime("02")
+	case "hour":
+		f.value = partitionTime("15")
+	case "date":
+		f.value = partitionTime("2006-01-02")
+	case "format":
+		f.value = partitionTime(arg)
+	case "bucket", "truncate":
+		n, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil || n <= 0 {
+			return partitionField{}, fmt.Errorf("argument of transform %s of level %q ought to be a positive integer: %q", transform, f.name, arg)
+		}
+		if transform == "bucket" {
+			f.value = partitionBucket(n)
+		} else {
+			f.value = partitionTruncate(n)
+		}
+	default:
+		return partitionField{}, fmt.Errorf("unknown transform of level %q: %q", f.name, transform)
+	}
+	return f, nil
+}
+
+// String returns the partition spec.
+func (ps *PartitionSpec) String() string {
+	return ps.spec
+}
+
+// Path returns the partition path of the record, without leading or trailing
+// slashes.
+func (ps *PartitionSpec) Path(record interface{}) (string, error) {
+	levels := make([]string, len(ps.fields))
+	for i, f := range ps.fields {
+		value, err := partitionFieldValue(record, f.path)
+		if err != nil {
+			return "", fmt.Errorf("cannot compute partition path: level %q: %s", f.name, err)
+		}
+		s := PartitionNull
+		if value != nil {
+			if s, err = f.value(value); err != nil {
+				return "", fmt.Errorf("cannot compute partition path: level %q: %s", f.name, err)
+			}
+			s = escapePartitionValue(s)
+		}
+		levels[i] = f.name + "=" + s
+	}
+	return strings.Join(levels, "/"), nil
+}
+
+// partitionFieldValue returns the value of the nested field of the record, or
+// nil when it is missing or null.
+func partitionFieldValue(record interface{}, path []string) (interface{}, error) {
+	value := record
+	for i, name := range path {
+		if value == nil {
+			return nil, nil
+		}
+		if member, ok := value.(UnionValue); ok {
+			value = member.Value
+		}
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("field %q ought to be a record; received: %T", strings.Join(path[:i], "."), value)
+		}
+		if _, ok = m[name]; !ok {
+			// NOTE: The record may be the member of a union, such as
+			// {"com.example.Event": {"ts": ...}}.
+			if member, ok := unwrapPartitionUnion(m).(map[string]interface{}); ok {
+				m = member
+			}
+		}
+		value = m[name]
+	}
+	return unwrapPartitionUnion(value), nil
+}
+
+// unwrapPartitionUnion returns the value of the member of a union value, or
+// the value itself.
+func unwrapPartitionUnion(value interface{}) interface{} {
+	if _, v, ok := unwrapUnion(value); ok {
+		return v
+	}
+	return value
+}
+
+func partitionIdentity(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano), nil
+	}
+	return fmt.Sprint(value), nil
+}
+
+func partitionTime(layout string) func(interface{}) (string, error) {
+	return func(value interface{}) (string, error) {
+		t, ok := value.(time.Time)
+		if !ok {
+			return "", fmt.Errorf("time transform requires a time.Time; received: %T", value)
+		}
+		return t.UTC().Format(layout), nil
+	}
+}
+
+func partitionBucket(n int64) func(interface{}) (string, error) {
+	return func(value interface{}) (string, error) {
+		s, err := partitionIdentity(value)
+		if err != nil {
+			return "", err
+		}
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(s))
+		return strconv.FormatInt(int64(h.Sum32())%n, 10), nil
+	}
+}
+
+func partitionTruncate(w int64) func(interface{}) (string, error) {
+	return func(value interface{}) (string, error) {
+		var i int64
+		switch v := value.(type) {
+		case string:
+			if runes := []rune(v); int64(len(runes)) > w {
+				return string(runes[:w]), nil
+			}
+			return v, nil
+		case []byte:
+			if int64(len(v)) > w {
+				return string(v[:w]), nil
+			}
+			return string(v), nil
+		case int:
+			i = int64(v)
+		case int32:
+			i = int64(v)
+		case int64:
+			i = v
+		default:
+			return "", fmt.Errorf("truncate transform requires a string, bytes, or integer; received: %T", value)
+		}
+		// NOTE: Round toward negative infinity, so negative values are
+		// grouped like positive ones.
+		r := i % w
+		if r < 0 {
+			r += w
+		}
+		return strconv.FormatInt(i-r, 10), nil
+	}
+}
+
+// escapePartitionValue escapes the characters Hive escapes in partition
+// values as a percent sign followed by two upper case hex digits.
+func escapePartitionValue(s string) string {
+	const special = "\"#%'*/:=?\\{[]^\x7f"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 0x20 || strings.IndexByte(special, c) >= 0 {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	if b.Len() == 0 {
+		return PartitionNull
+	}
+	return b.String()
+}
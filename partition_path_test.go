@@ -0,0 +1,89 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPartitionSpecPath(t *testing.T) {
+	ts := time.Date(2019, 6, 1, 13, 45, 0, 0, time.FixedZone("CEST", 2*3600))
+	record := map[string]interface{}{
+		"event":   map[string]interface{}{"com.example.Event": map[string]interface{}{"ts": ts}},
+		"country": map[string]interface{}{"string": "NL"},
+		"user":    "ada/lovelace",
+		"amount":  int64(-7),
+		"note":    nil,
+	}
+	cases := []struct {
+		spec, path string
+	}{
+		{"dt=date(event.ts)/hour=hour(event.ts)", "dt=2019-06-01/hour=11"},
+		{"year=year(event.ts)/month=month(event.ts)/day=day(event.ts)", "year=2019/month=06/day=01"},
+		{"ym=format(event.ts,2006-01)", "ym=2019-06"},
+		{"country=country", "country=NL"},
+		{"user=identity(user)", "user=ada%2Flovelace"},
+		{"prefix=truncate(user,3)", "prefix=ada"},
+		{"amount=truncate(amount,5)", "amount=-10"},
+		{"note=note/missing=missing", "note=__HIVE_DEFAULT_PARTITION__/missing=__HIVE_DEFAULT_PARTITION__"},
+	}
+	for _, c := range cases {
+		spec, err := NewPartitionSpec(c.spec)
+		ensureError(t, err)
+		path, err := spec.Path(record)
+		ensureError(t, err)
+		if path != c.path {
+			t.Errorf("%s: GOT: %q; WANT: %q", c.spec, path, c.path)
+		}
+	}
+
+	spec, err := NewPartitionSpec("b=bucket(user,4)")
+	ensureError(t, err)
+	first, err := spec.Path(record)
+	ensureError(t, err)
+	second, err := spec.Path(map[string]interface{}{"user": "ada/lovelace"})
+	ensureError(t, err)
+	if first != second {
+		t.Errorf("GOT: %q; WANT: %q", second, first)
+	}
+
+	// union values decoded with the UnionValueDecoding option
+	spec, err = NewPartitionSpec("dt=date(event.ts)/country=country")
+	ensureError(t, err)
+	path, err := spec.Path(map[string]interface{}{
+		"event":   UnionValue{Type: "com.example.Event", Value: map[string]interface{}{"ts": ts}},
+		"country": UnionValue{Type: "string", Value: "NL"},
+	})
+	ensureError(t, err)
+	if expected := "dt=2019-06-01/country=NL"; path != expected {
+		t.Errorf("GOT: %q; WANT: %q", path, expected)
+	}
+}
+
+func TestPartitionSpecErrors(t *testing.T) {
+	for spec, substring := range map[string]string{
+		"dt":                    "level ought to be name=transform(field)",
+		"dt=date(ts":            "transform ought to end with a parenthesis",
+		"dt=week(ts)":           `unknown transform of level "dt": "week"`,
+		"b=bucket(user)":        "ought to have an argument",
+		"b=bucket(user,0)":      "ought to be a positive integer",
+		"dt=date(ts,2006)":      "ought not to have an argument",
+		"dt=date(ts)/dt=day(x)": "duplicate level name",
+	} {
+		_, err := NewPartitionSpec(spec)
+		ensureError(t, err, "cannot parse partition spec", substring)
+	}
+
+	spec, err := NewPartitionSpec("dt=date(ts)")
+	ensureError(t, err)
+	_, err = spec.Path(map[string]interface{}{"ts": "2019-06-01"})
+	ensureError(t, err, "cannot compute partition path", "time transform requires a time.Time")
+}
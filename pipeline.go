@@ -0,0 +1,337 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Pipeline runs concurrent stages of Avro processing, each in its own
+// goroutines, connected by channels buffering up to the size given to
+// NewPipeline. Each stage receives from the channel returned by the stage
+// before it, until that channel is closed, and closes its own channel when it
+// is done, so the stages of a pipeline finish in order once its source channel
+// is closed.
+//
+//     p := goavro.NewPipeline(64)
+//     data := p.Decode(codec, messages) // messages is a chan []byte
+//     data = p.Filter(data, func(datum interface{}) (bool, error) {
+//         return datum.(map[string]interface{})["valid"].(bool), nil
+//     })
+//     data = p.Transform(data, runtime.NumCPU(), enrich)
+//     p.WriteOCF(ocfw, data, 1000)
+//     if err := p.Wait(); err != nil {
+//         return err
+//     }
+//
+// When a stage fails, the pipeline stops: every stage stops receiving and
+// sending, and Wait returns the error. Producers sending to the source channel
+// ought to stop sending once Done is closed.
+type Pipeline struct {
+	buffer int
+	wg     sync.WaitGroup
+	done   chan struct{}
+	once   sync.Once
+	mu     sync.Mutex
+	err    error // first error of a stage
+}
+
+// NewPipeline returns a Pipeline whose stages buffer up to buffer data items
+// in the channels they return. When buffer is not positive, the channels are
+// unbuffered.
+func NewPipeline(buffer int) *Pipeline {
+	if buffer < 0 {
+		buffer = 0
+	}
+	return &Pipeline{buffer: buffer, done: make(chan struct{})}
+}
+
+// Done returns a channel that is closed when the pipeline stops, after a stage
+// fails or Stop is called.
+func (p *Pipeline) Done() <-chan struct{} { return p.done }
+
+// Stop stops the pipeline without an error. Data items not yet received by the
+// final stage are discarded.
+func (p *Pipeline) Stop() { p.stop(nil) }
+
+// Wait waits for every stage of the pipeline to return, and returns the error
+// of the first stage that failed, if any.
+func (p *Pipeline) Wait() error {
+	p.wg.Wait()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// stop stops the pipeline, recording err when it is the first reason.
+func (p *Pipeline) stop(err error) {
+	p.once.Do(func() {
+		p.mu.Lock()
+		p.err = err
+		p.mu.Unlock()
+		close(p.done)
+	})
+}
+
+// run runs the stage function in a new goroutine, stopping the pipeline when
+// it returns an error.
+func (p *Pipeline) run(stage func() error) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		if err := stage(); err != nil {
+			p.stop(err)
+		}
+	}()
+}
+
+// receive returns the next datum from in, and false once in is closed or the
+// pipeline stops.
+func (p *Pipeline) receive(in <-chan interface{}) (interface{}, bool) {
+	select {
+	case datum, ok := <-in:
+		return datum, ok
+	case <-p.done:
+		return nil, false
+	}
+}
+
+// send sends the datum to out, returning false when the pipeline stops first.
+func (p *Pipeline) send(out chan<- interface{}, datum interface{}) bool {
+	select {
+	case out <- datum:
+		return true
+	case <-p.done:
+		return false
+	}
+}
+
+// Decode returns a channel of the data items decoded from each binary encoded
+// message received from in, using the schema of the codec. Each message ought
+// to hold exactly one data item.
+func (p *Pipeline) Decode(codec *Codec, in <-chan []byte) <-chan interface{} {
+	out := make(chan interface{}, p.buffer)
+	p.run(func() error {
+		defer close(out)
+		for {
+			var buf []byte
+			var ok bool
+			select {
+			case buf, ok = <-in:
+			case <-p.done:
+				return nil
+			}
+			if !ok {
+				return nil
+			}
+			datum, rest, err := codec.NativeFromBinary(buf)
+			if err != nil {
+				return fmt.Errorf("cannot decode: %s", err)
+			}
+			if len(rest) > 0 {
+				return fmt.Errorf("cannot decode: message has %d bytes following the data item", len(rest))
+			}
+			if !p.send(out, datum) {
+				return nil
+			}
+		}
+	})
+	return out
+}
+
+// Transform returns a channel of the data items returned by the transform
+// function for each data item received from in. When workers is greater than
+// one, up to that many data items are transformed concurrently, which helps
+// when the transform is the bottleneck of a pipeline, while the transformed
+// data items are still sent in the order they were received. The transform
+// function ought to be safe to call from several goroutines when workers is
+// greater than one.
+func (p *Pipeline) Transform(in <-chan interface{}, workers int, transform func(datum interface{}) (interface{}, error)) <-chan interface{} {
+	out := make(chan interface{}, p.buffer)
+	if workers <= 1 {
+		p.run(func() error {
+			defer close(out)
+			for {
+				datum, ok := p.receive(in)
+				if !ok {
+					return nil
+				}
+				datum, err := transform(datum)
+				if err != nil {
+					return fmt.Errorf("cannot transform: %s", err)
+				}
+				if !p.send(out, datum) {
+					return nil
+				}
+			}
+		})
+		return out
+	}
+
+	type result struct {
+		datum interface{}
+		err   error
+	}
+	type job struct {
+		datum  interface{}
+		result chan result
+	}
+	jobs := make(chan job)
+	pending := make(chan chan result, 2*workers) // results in the order data were received
+
+	for i := 0; i < workers; i++ {
+		p.run(func() error {
+			for j := range jobs {
+				transformed, err := transform(j.datum)
+				j.result <- result{datum: transformed, err: err}
+			}
+			return nil
+		})
+	}
+	p.run(func() error {
+		defer close(pending)
+		defer close(jobs)
+		for {
+			datum, ok := p.receive(in)
+			if !ok {
+				return nil
+			}
+			r := make(chan result, 1)
+			select {
+			case pending <- r:
+			case <-p.done:
+				return nil
+			}
+			select {
+			case jobs <- job{datum: datum, result: r}:
+			case <-p.done:
+				return nil
+			}
+		}
+	})
+	p.run(func() error {
+		defer close(out)
+		for r := range pending {
+			var transformed result
+			select {
+			case transformed = <-r:
+			case <-p.done:
+				return nil
+			}
+			if transformed.err != nil {
+				return fmt.Errorf("cannot transform: %s", transformed.err)
+			}
+			if !p.send(out, transformed.datum) {
+				return nil
+			}
+		}
+		return nil
+	})
+	return out
+}
+
+// Filter returns a channel of the data items received from in for which the
+// keep function returns true.
+func (p *Pipeline) Filter(in <-chan interface{}, keep func(datum interface{}) (bool, error)) <-chan interface{} {
+	out := make(chan interface{}, p.buffer)
+	p.run(func() error {
+		defer close(out)
+		for {
+			datum, ok := p.receive(in)
+			if !ok {
+				return nil
+			}
+			kept, err := keep(datum)
+			if err != nil {
+				return fmt.Errorf("cannot filter: %s", err)
+			}
+			if kept && !p.send(out, datum) {
+				return nil
+			}
+		}
+	})
+	return out
+}
+
+// Encode returns a channel of the binary encoding of each data item received
+// from in, using the schema of the codec. Each message is a new slice, which
+// the receiver may retain.
+func (p *Pipeline) Encode(codec *Codec, in <-chan interface{}) <-chan []byte {
+	out := make(chan []byte, p.buffer)
+	p.run(func() error {
+		defer close(out)
+		for {
+			datum, ok := p.receive(in)
+			if !ok {
+				return nil
+			}
+			buf, err := codec.BinaryFromNative(nil, datum)
+			if err != nil {
+				return fmt.Errorf("cannot encode: %s", err)
+			}
+			select {
+			case out <- buf:
+			case <-p.done:
+				return nil
+			}
+		}
+	})
+	return out
+}
+
+// WriteOCF appends the data items received from in to the OCFWriter, and
+// closes it once in is closed. Data items are appended in batches of up to
+// batch items, each batch holding the data items received without waiting, so
+// blocks are large when data arrive quickly, while data arriving slowly are
+// still written promptly. When batch is not positive, each data item is
+// appended by itself.
+func (p *Pipeline) WriteOCF(ocfw *OCFWriter, in <-chan interface{}, batch int) {
+	if batch < 1 {
+		batch = 1
+	}
+	p.run(func() error {
+		data := make([]interface{}, 0, batch)
+		for {
+			datum, ok := p.receive(in)
+			if !ok {
+				break
+			}
+			data = append(data[:0], datum)
+		more:
+			for len(data) < batch {
+				select {
+				case datum, ok := <-in:
+					if !ok {
+						break more
+					}
+					data = append(data, datum)
+				default:
+					break more
+				}
+			}
+			if err := ocfw.Append(data); err != nil {
+				return fmt.Errorf("cannot write OCF: %s", err)
+			}
+		}
+		select {
+		case <-p.done:
+			return nil
+		default:
+		}
+		if err := ocfw.Close(); err != nil {
+			return fmt.Errorf("cannot write OCF: %s", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,148 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPipeline(t *testing.T) {
+	codec, err := NewCodec(`"long"`)
+	ensureError(t, err)
+
+	messages := make(chan []byte)
+	go func() {
+		defer close(messages)
+		for i := int64(0); i < 100; i++ {
+			buf, err := codec.BinaryFromNative(nil, i)
+			if err != nil {
+				panic(err)
+			}
+			messages <- buf
+		}
+	}()
+
+	bb := new(bytes.Buffer)
+	ocfw, err := NewOCFWriter(OCFConfig{W: bb, Codec: codec})
+	ensureError(t, err)
+
+	p := NewPipeline(4)
+	data := p.Decode(codec, messages)
+	data = p.Filter(data, func(datum interface{}) (bool, error) {
+		return datum.(int64)%2 == 0, nil
+	})
+	data = p.Transform(data, 4, func(datum interface{}) (interface{}, error) {
+		return datum.(int64) * 10, nil
+	})
+	p.WriteOCF(ocfw, data, 8)
+	ensureError(t, p.Wait())
+
+	ocfr, err := NewOCFReader(bytes.NewReader(bb.Bytes()))
+	ensureError(t, err)
+	var actual []interface{}
+	for ocfr.Scan() {
+		datum, err := ocfr.Read()
+		ensureError(t, err)
+		actual = append(actual, datum)
+	}
+	ensureError(t, ocfr.Err())
+	var expected []interface{}
+	for i := int64(0); i < 100; i += 2 {
+		expected = append(expected, i*10)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
+
+func TestPipelineEncode(t *testing.T) {
+	codec, err := NewCodec(`"string"`)
+	ensureError(t, err)
+
+	data := make(chan interface{}, 2)
+	data <- "a"
+	data <- "bc"
+	close(data)
+
+	p := NewPipeline(0)
+	var actual [][]byte
+	for buf := range p.Encode(codec, p.Transform(data, 1, func(datum interface{}) (interface{}, error) {
+		return datum.(string) + "!", nil
+	})) {
+		actual = append(actual, buf)
+	}
+	ensureError(t, p.Wait())
+	if expected := [][]byte{{4, 'a', '!'}, {6, 'b', 'c', '!'}}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
+
+func TestPipelineError(t *testing.T) {
+	codec, err := NewCodec(`"long"`)
+	ensureError(t, err)
+
+	// The source never closes, so the pipeline only returns because the
+	// failing stage stops it.
+	p := NewPipeline(2)
+	messages := make(chan []byte)
+	go func() {
+		for i := int64(0); ; i++ {
+			buf, _ := codec.BinaryFromNative(nil, i)
+			select {
+			case messages <- buf:
+			case <-p.Done():
+				return
+			}
+		}
+	}()
+
+	data := p.Transform(p.Decode(codec, messages), 3, func(datum interface{}) (interface{}, error) {
+		if datum.(int64) == 10 {
+			return nil, errors.New("ten")
+		}
+		return datum, nil
+	})
+	count := 0
+	for range data {
+		count++
+	}
+	ensureError(t, p.Wait(), "cannot transform", "ten")
+	if count > 10 {
+		t.Errorf("GOT: %v; WANT: <= %v", count, 10)
+	}
+	select {
+	case <-p.Done():
+	default:
+		t.Errorf("GOT: %v; WANT: %v", "running", "stopped")
+	}
+
+	trailing := NewPipeline(0)
+	in := make(chan []byte, 1)
+	in <- []byte{2, 0}
+	for range trailing.Decode(codec, in) {
+	}
+	ensureError(t, trailing.Wait(), "cannot decode", "1 bytes following")
+}
+
+func TestPipelineStop(t *testing.T) {
+	p := NewPipeline(0)
+	in := make(chan interface{}) // never closed
+	out := p.Filter(in, func(interface{}) (bool, error) { return true, nil })
+	p.Stop()
+	for range out {
+	}
+	ensureError(t, p.Wait())
+}
@@ -0,0 +1,536 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/big"
+	"math/bits"
+	"sort"
+	"time"
+	"unicode/utf8"
+)
+
+// FieldProfile describes the values found at one path of the data items of a
+// profile. Paths are formatted like those of SchemaFieldDoc: a period
+// separates the names of nested fields, "[]" follows an array whose items are
+// described, "{}" follows a map whose values are described, and the full name
+// of a record member of a union follows the union in parentheses when the
+// union has more than one record member. The data items themselves have the
+// empty path when they are not records.
+type FieldProfile struct {
+	Path     string      `json:"path"`
+	Type     string      `json:"type"`              // label of the schema at the path, such as "union<null,string>"
+	Count    int64       `json:"count"`             // number of values, including nulls
+	Nulls    int64       `json:"nulls"`             // number of null values
+	Distinct uint64      `json:"distinct"`          // estimated number of distinct values, other than null, record, array, and map values
+	Min      interface{} `json:"min,omitempty"`     // least number, string, time, or decimal value
+	Max      interface{} `json:"max,omitempty"`     // greatest number, string, time, or decimal value
+	Lengths  *Lengths    `json:"lengths,omitempty"` // lengths of string, bytes, fixed, array, and map values
+}
+
+// NullRate returns the fraction of the values that are null.
+func (fp *FieldProfile) NullRate() float64 {
+	if fp.Count == 0 {
+		return 0
+	}
+	return float64(fp.Nulls) / float64(fp.Count)
+}
+
+// Lengths describes the distribution of the lengths of values, which count
+// the characters of strings, the bytes of bytes and fixed values, the items
+// of arrays, and the entries of maps.
+type Lengths struct {
+	Count int64 `json:"count"`
+	Min   int64 `json:"min"`
+	Max   int64 `json:"max"`
+	Sum   int64 `json:"sum"`
+
+	// Histogram counts the lengths by powers of two: its first element
+	// counts the lengths of zero, and element i counts the lengths from
+	// 2^(i-1) to 2^i - 1.
+	Histogram []int64 `json:"histogram"`
+}
+
+// Mean returns the average length.
+func (l *Lengths) Mean() float64 {
+	if l.Count == 0 {
+		return 0
+	}
+	return float64(l.Sum) / float64(l.Count)
+}
+
+func (l *Lengths) add(length int64) {
+	if l.Count == 0 || length < l.Min {
+		l.Min = length
+	}
+	if length > l.Max {
+		l.Max = length
+	}
+	l.Count++
+	l.Sum += length
+	i := bits.Len64(uint64(length))
+	for len(l.Histogram) <= i {
+		l.Histogram = append(l.Histogram, 0)
+	}
+	l.Histogram[i]++
+}
+
+// DataProfile describes the data items added to a Profiler.
+type DataProfile struct {
+	Records int64           `json:"records"` // number of data items
+	Fields  []*FieldProfile `json:"fields"`  // sorted by path
+}
+
+// String returns a table of the field profiles, with one line for each path.
+func (dp *DataProfile) String() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%d records\n", dp.Records)
+	for _, fp := range dp.Fields {
+		path := fp.Path
+		if path == "" {
+			path = "."
+		}
+		fmt.Fprintf(&b, "%s %s: count=%d nulls=%.2f%% distinct~%d", path, fp.Type, fp.Count, 100*fp.NullRate(), fp.Distinct)
+		if fp.Min != nil {
+			fmt.Fprintf(&b, " min=%v max=%v", fp.Min, fp.Max)
+		}
+		if l := fp.Lengths; l != nil {
+			fmt.Fprintf(&b, " length=%d..%d mean=%.1f", l.Min, l.Max, l.Mean())
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// Profiler accumulates per-field statistics of data items, such as the
+// records of OCF files, for data quality checks and dashboards. Distinct
+// counts are estimated with HyperLogLog sketches, whose standard error is
+// about 1.6 percent, so profiling uses a bounded amount of memory for each
+// path regardless of the number of data items. A Profiler ought not to be
+// used by multiple goroutines simultaneously.
+//
+//	profiler, err := goavro.NewProfiler(ocfr.Codec())
+//	if err != nil {
+//	    return err
+//	}
+//	if err = profiler.ReadOCF(ocfr); err != nil {
+//	    return err
+//	}
+//	for _, fp := range profiler.Profile().Fields {
+//	    fmt.Printf("%s: %.2f%% null, about %d distinct\n", fp.Path, 100*fp.NullRate(), fp.Distinct)
+//	}
+type Profiler struct {
+	codec   *Codec
+	root    *schemaNode
+	records int64
+	fields  map[string]*fieldProfiler
+}
+
+type fieldProfiler struct {
+	profile  FieldProfile
+	distinct *hyperLogLog
+}
+
+// NewProfiler returns a Profiler of data items of the schema of the Codec.
+func NewProfiler(codec *Codec) (*Profiler, error) {
+	if codec == nil {
+		return nil, errors.New("cannot create Profiler: codec is nil")
+	}
+	root, err := schemaNodeFromCodec(codec)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create Profiler: %s", err)
+	}
+	return &Profiler{codec: codec, root: root, fields: make(map[string]*fieldProfiler)}, nil
+}
+
+// Add adds the statistics of one data item, in the native form decoded by the
+// Codec, to the profile.
+func (p *Profiler) Add(datum interface{}) error {
+	var fp *fieldProfiler
+	if p.root.typeName != "record" {
+		fp = p.field(p.root, "")
+		fp.profile.Count++
+	}
+	if err := p.describe(fp, p.root, datum, ""); err != nil {
+		return fmt.Errorf("cannot profile data item %d: %s", p.records, err)
+	}
+	p.records++
+	return nil
+}
+
+// ReadOCF adds the data items of the OCF reader to the profile, until the end
+// of the OCF. The OCF ought to be written with the schema of the Codec of the
+// Profiler.
+func (p *Profiler) ReadOCF(ocfr *OCFReader) error {
+	if ocfr.Codec().Rabin != p.codec.Rabin {
+		return fmt.Errorf("cannot profile OCF: schema of OCF differs from schema of profiler: %s", ocfr.Codec().Schema())
+	}
+	for ocfr.Scan() {
+		datum, err := ocfr.Read()
+		if err != nil {
+			return fmt.Errorf("cannot profile OCF: %s", err)
+		}
+		if err = p.Add(datum); err != nil {
+			return fmt.Errorf("cannot profile OCF: %s", err)
+		}
+	}
+	if err := ocfr.Err(); err != nil {
+		return fmt.Errorf("cannot profile OCF: %s", err)
+	}
+	return nil
+}
+
+// Profile returns the statistics of the data items added so far.
+func (p *Profiler) Profile() *DataProfile {
+	dp := &DataProfile{Records: p.records, Fields: make([]*FieldProfile, 0, len(p.fields))}
+	for _, fp := range p.fields {
+		profile := fp.profile
+		if fp.distinct != nil {
+			profile.Distinct = fp.distinct.estimate()
+		}
+		if profile.Lengths != nil {
+			lengths := *profile.Lengths
+			lengths.Histogram = append([]int64(nil), lengths.Histogram...)
+			profile.Lengths = &lengths
+		}
+		dp.Fields = append(dp.Fields, &profile)
+	}
+	sort.Slice(dp.Fields, func(i, j int) bool { return dp.Fields[i].Path < dp.Fields[j].Path })
+	return dp
+}
+
+// field returns the statistics of the values at the path.
+func (p *Profiler) field(n *schemaNode, path string) *fieldProfiler {
+	fp, ok := p.fields[path]
+	if !ok {
+		fp = &fieldProfiler{profile: FieldProfile{Path: path, Type: n.label()}}
+		p.fields[path] = fp
+	}
+	return fp
+}
+
+// add counts the value at the path, and describes it.
+func (p *Profiler) add(n *schemaNode, datum interface{}, path string) error {
+	fp := p.field(n, path)
+	fp.profile.Count++
+	return p.describe(fp, n, datum, path)
+}
+
+// describe records the statistics of the value at the path, whose count is
+// kept by fp, which is nil for a record at the root, as its fields are
+// profiled rather than the record itself.
+func (p *Profiler) describe(fp *fieldProfiler, n *schemaNode, datum interface{}, path string) error {
+	if datum == nil {
+		if fp != nil {
+			fp.profile.Nulls++
+		}
+		return nil
+	}
+	switch n.typeName {
+	case "record":
+		record, ok := datum.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("path %q: expected map[string]interface{}; received: %T", path, datum)
+		}
+		for _, f := range n.fields {
+			if err := p.add(f.node, record[f.name], joinFieldPath(path, f.name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "array":
+		items, err := convertArray(datum)
+		if err != nil {
+			return fmt.Errorf("path %q: %s", path, err)
+		}
+		fp.lengths(int64(len(items)))
+		for _, item := range items {
+			if err = p.add(n.items, item, path+"[]"); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "map":
+		switch values := datum.(type) {
+		case map[string]interface{}:
+			fp.lengths(int64(len(values)))
+			for _, value := range values {
+				if err := p.add(n.values, value, path+"{}"); err != nil {
+					return err
+				}
+			}
+			return nil
+		case OrderedMap:
+			fp.lengths(int64(len(values)))
+			for _, item := range values {
+				if err := p.add(n.values, item.Value, path+"{}"); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return fmt.Errorf("path %q: expected map[string]interface{}; received: %T", path, datum)
+	case "union":
+		wrapped, ok := datum.(map[string]interface{})
+		if !ok || len(wrapped) != 1 {
+			return fmt.Errorf("path %q: expected map[string]interface{} with one member; received: %T", path, datum)
+		}
+		for _, member := range n.members {
+			value, ok := wrapped[unionMemberName(member)]
+			if !ok {
+				continue
+			}
+			if member.typeName == "null" {
+				value = nil
+			}
+			// NOTE: A record member of a union with several record members
+			// has its own path, which counts how often the member is used.
+			// Other members are described by the profile of the union.
+			if memberPath := unionMemberPath(n, member, path); memberPath != path {
+				return p.add(member, value, memberPath)
+			}
+			return p.describe(fp, member, value, path)
+		}
+		for name := range wrapped {
+			return fmt.Errorf("path %q: unknown member of union: %q", path, name)
+		}
+	}
+	if fp != nil {
+		fp.value(datum)
+	}
+	return nil
+}
+
+// lengths records the length of a value.
+func (fp *fieldProfiler) lengths(length int64) {
+	if fp.profile.Lengths == nil {
+		fp.profile.Lengths = new(Lengths)
+	}
+	fp.profile.Lengths.add(length)
+}
+
+// value records the statistics of a value other than null.
+func (fp *fieldProfiler) value(datum interface{}) {
+	switch v := datum.(type) {
+	case string:
+		fp.lengths(int64(utf8.RuneCountInString(v)))
+	case []byte:
+		fp.lengths(int64(len(v)))
+	case map[string]interface{}, []interface{}, OrderedMap:
+		return // described by the paths of their values
+	}
+	if fp.distinct == nil {
+		fp.distinct = newHyperLogLog()
+	}
+	fp.distinct.add(profileHash(datum))
+
+	if f, ok := datum.(float64); ok && math.IsNaN(f) {
+		return
+	}
+	if f, ok := datum.(float32); ok && math.IsNaN(float64(f)) {
+		return
+	}
+	if fp.profile.Min == nil {
+		if _, ok := profileCompare(datum, datum); ok {
+			fp.profile.Min, fp.profile.Max = datum, datum
+		}
+		return
+	}
+	if c, ok := profileCompare(datum, fp.profile.Min); ok && c < 0 {
+		fp.profile.Min = datum
+	}
+	if c, ok := profileCompare(datum, fp.profile.Max); ok && c > 0 {
+		fp.profile.Max = datum
+	}
+}
+
+// profileCompare returns -1, 0, or 1 when a is less than, equal to, or
+// greater than b, and false when the values are not ordered, such as bytes,
+// or are not of the same kind.
+func profileCompare(a, b interface{}) (int, bool) {
+	switch x := a.(type) {
+	case string:
+		y, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	case time.Time:
+		y, ok := b.(time.Time)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case x.Before(y):
+			return -1, true
+		case x.After(y):
+			return 1, true
+		}
+		return 0, true
+	case *big.Rat:
+		y, ok := b.(*big.Rat)
+		if !ok {
+			return 0, false
+		}
+		return x.Cmp(y), true
+	case float32, float64:
+		x64, _ := profileFloat(a)
+		y, ok := profileFloat(b)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case x64 < y:
+			return -1, true
+		case x64 > y:
+			return 1, true
+		}
+		return 0, true
+	}
+	x, ok := profileInt(a)
+	if !ok {
+		return 0, false
+	}
+	y, ok := profileInt(b)
+	if !ok {
+		return 0, false
+	}
+	switch {
+	case x < y:
+		return -1, true
+	case x > y:
+		return 1, true
+	}
+	return 0, true
+}
+
+func profileFloat(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case float32:
+		return float64(x), true
+	case float64:
+		return x, true
+	}
+	return 0, false
+}
+
+func profileInt(v interface{}) (int64, bool) {
+	switch x := v.(type) {
+	case int:
+		return int64(x), true
+	case int32:
+		return int64(x), true
+	case int64:
+		return x, true
+	case time.Duration:
+		return int64(x), true
+	}
+	return 0, false
+}
+
+// profileHash returns the hash of a value used to estimate distinct counts.
+func profileHash(datum interface{}) uint64 {
+	h := fnv.New64a()
+	var buf [8]byte
+	switch v := datum.(type) {
+	case string:
+		_, _ = h.Write([]byte(v))
+	case []byte:
+		_, _ = h.Write(v)
+	case bool:
+		if v {
+			buf[0] = 1
+		}
+		_, _ = h.Write(buf[:1])
+	case float32:
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(float64(v)))
+		_, _ = h.Write(buf[:])
+	case float64:
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+		_, _ = h.Write(buf[:])
+	case time.Time:
+		binary.LittleEndian.PutUint64(buf[:], uint64(v.UnixNano()))
+		_, _ = h.Write(buf[:])
+	case *big.Rat:
+		_, _ = h.Write([]byte(v.String()))
+	default:
+		if i, ok := profileInt(datum); ok {
+			binary.LittleEndian.PutUint64(buf[:], uint64(i))
+			_, _ = h.Write(buf[:])
+		} else {
+			_, _ = fmt.Fprint(h, datum)
+		}
+	}
+	// NOTE: Mix the bits of the hash, because HyperLogLog uses its high bits
+	// to choose a register, and FNV mixes the final bytes of short values
+	// into its low bits only.
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// hyperLogLogPrecision is the number of bits of the hash that choose a
+// register of a hyperLogLog.
+const hyperLogLogPrecision = 12
+
+// hyperLogLog estimates the number of distinct hashes added to it, as
+// described by Flajolet et al., using linear counting for small counts.
+type hyperLogLog struct {
+	registers [1 << hyperLogLogPrecision]uint8
+}
+
+func newHyperLogLog() *hyperLogLog {
+	return new(hyperLogLog)
+}
+
+func (hll *hyperLogLog) add(x uint64) {
+	i := x >> (64 - hyperLogLogPrecision)
+	rank := uint8(bits.LeadingZeros64(x<<hyperLogLogPrecision|1<<(hyperLogLogPrecision-1)) + 1)
+	if rank > hll.registers[i] {
+		hll.registers[i] = rank
+	}
+}
+
+func (hll *hyperLogLog) estimate() uint64 {
+	const m = float64(len(hll.registers))
+	var sum float64
+	var zeros int
+	for _, r := range hll.registers {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+	alpha := 0.7213 / (1 + 1.079/m)
+	estimate := alpha * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(estimate + 0.5)
+}
@@ -0,0 +1,184 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+const profileSchema = `{
+  "type": "record",
+  "name": "Visit",
+  "fields": [
+    {"name": "id", "type": "long"},
+    {"name": "page", "type": ["null", "string"]},
+    {"name": "tags", "type": {"type": "array", "items": "string"}},
+    {"name": "ts", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "source", "type": ["null",
+      {"type": "record", "name": "Web", "fields": [{"name": "url", "type": "string"}]},
+      {"type": "record", "name": "App", "fields": [{"name": "version", "type": "int"}]}
+    ]}
+  ]
+}`
+
+func profileFields(dp *DataProfile) map[string]*FieldProfile {
+	fields := make(map[string]*FieldProfile)
+	for _, fp := range dp.Fields {
+		fields[fp.Path] = fp
+	}
+	return fields
+}
+
+func TestProfilerOCF(t *testing.T) {
+	codec, err := NewCodec(profileSchema)
+	ensureError(t, err)
+	start := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	bb := new(bytes.Buffer)
+	ocfw, err := NewOCFWriter(OCFConfig{W: bb, Codec: codec})
+	ensureError(t, err)
+	for i := 0; i < 1000; i++ {
+		record := map[string]interface{}{
+			"id":     int64(i),
+			"page":   nil,
+			"tags":   []interface{}{},
+			"ts":     start.Add(time.Duration(i) * time.Second),
+			"source": nil,
+		}
+		if i%4 != 0 {
+			record["page"] = Union("string", strings.Repeat("p", i%10))
+		}
+		for j := 0; j < i%3; j++ {
+			record["tags"] = append(record["tags"].([]interface{}), fmt.Sprintf("tag%d", j))
+		}
+		switch i % 5 {
+		case 1:
+			record["source"] = Union("Web", map[string]interface{}{"url": "https://example.com"})
+		case 2:
+			record["source"] = Union("App", map[string]interface{}{"version": int32(i % 7)})
+		}
+		ensureError(t, ocfw.Append([]interface{}{record}))
+	}
+
+	profiler, err := NewProfiler(codec)
+	ensureError(t, err)
+	ocfr, err := NewOCFReader(bb)
+	ensureError(t, err)
+	ensureError(t, profiler.ReadOCF(ocfr))
+	dp := profiler.Profile()
+	if actual, expected := dp.Records, int64(1000); actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	var paths []string
+	for _, fp := range dp.Fields {
+		paths = append(paths, fp.Path)
+	}
+	if actual, expected := strings.Join(paths, " "), "id page source source(App) source(App).version source(Web) source(Web).url tags tags[] ts"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	fields := profileFields(dp)
+	id := fields["id"]
+	if id.Count != 1000 || id.Nulls != 0 || id.Min != int64(0) || id.Max != int64(999) || id.Type != "long" {
+		t.Errorf("GOT: %+v", id)
+	}
+	if id.Distinct < 970 || id.Distinct > 1030 {
+		t.Errorf("GOT: %v; WANT: about 1000", id.Distinct)
+	}
+
+	page := fields["page"]
+	if actual, expected := page.NullRate(), 0.25; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if page.Distinct < 9 || page.Distinct > 10 || page.Min != "" || page.Max != "ppppppppp" || page.Type != "union<null,string>" {
+		t.Errorf("GOT: %+v", page)
+	}
+	if l := page.Lengths; l.Count != 750 || l.Min != 0 || l.Max != 9 || len(l.Histogram) != 5 {
+		t.Errorf("GOT: %+v", l)
+	}
+
+	tags := fields["tags"]
+	if l := tags.Lengths; l.Count != 1000 || l.Max != 2 || fmt.Sprint(l.Histogram) != "[334 333 333]" {
+		t.Errorf("GOT: %+v", l)
+	}
+	if actual, expected := fields["tags[]"].Count, int64(999); actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if actual, expected := fields["tags[]"].Distinct, uint64(2); actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	if ts := fields["ts"]; !ts.Min.(time.Time).Equal(start) || !ts.Max.(time.Time).Equal(start.Add(999*time.Second)) {
+		t.Errorf("GOT: %v, %v", ts.Min, ts.Max)
+	}
+
+	if source := fields["source"]; source.Count != 1000 || source.Nulls != 600 {
+		t.Errorf("GOT: %+v", source)
+	}
+	if web := fields["source(Web)"]; web.Count != 200 || web.Nulls != 0 || web.Type != "Web" {
+		t.Errorf("GOT: %+v", web)
+	}
+	if version := fields["source(App).version"]; version.Count != 200 || version.Min != int32(0) || version.Max != int32(6) {
+		t.Errorf("GOT: %+v", version)
+	}
+	if url := fields["source(Web).url"]; url.Distinct != 1 {
+		t.Errorf("GOT: %+v", url)
+	}
+
+	if actual := dp.String(); !strings.Contains(actual, "page union<null,string>: count=1000 nulls=25.00% distinct~") {
+		t.Errorf("GOT: %v", actual)
+	}
+}
+
+func TestProfilerPrimitive(t *testing.T) {
+	codec, err := NewCodec(`{"type": "map", "values": ["null", "double"]}`)
+	ensureError(t, err)
+	profiler, err := NewProfiler(codec)
+	ensureError(t, err)
+	ensureError(t, profiler.Add(map[string]interface{}{"a": Union("double", 1.5), "b": nil}))
+	ensureError(t, profiler.Add(map[string]interface{}{"a": Union("double", -2.0)}))
+
+	fields := profileFields(profiler.Profile())
+	if root := fields[""]; root.Count != 2 || root.Lengths.Sum != 3 || root.Type != "map<union<null,double>>" {
+		t.Errorf("GOT: %+v", root)
+	}
+	if values := fields["{}"]; values.Count != 3 || values.Nulls != 1 || values.Min != -2.0 || values.Max != 1.5 {
+		t.Errorf("GOT: %+v", values)
+	}
+}
+
+func TestProfilerErrors(t *testing.T) {
+	codec, err := NewCodec(profileSchema)
+	ensureError(t, err)
+	profiler, err := NewProfiler(codec)
+	ensureError(t, err)
+	ensureError(t, profiler.Add(map[string]interface{}{"id": int64(1), "page": "home"}), `cannot profile data item 0: path "page": expected map[string]interface{} with one member`)
+	ensureError(t, profiler.Add(map[string]interface{}{"id": int64(1), "page": Union("bytes", "home")}), `path "page": unknown member of union: "bytes"`)
+	ensureError(t, profiler.Add("home"), `path "": expected map[string]interface{}; received: string`)
+
+	_, err = NewProfiler(nil)
+	ensureError(t, err, "codec is nil")
+
+	bb := new(bytes.Buffer)
+	ocfw, err := NewOCFWriter(OCFConfig{W: bb, Schema: `"long"`})
+	ensureError(t, err)
+	ensureError(t, ocfw.Append([]interface{}{int64(1)}))
+	ocfr, err := NewOCFReader(bb)
+	ensureError(t, err)
+	ensureError(t, profiler.ReadOCF(ocfr), "cannot profile OCF: schema of OCF differs from schema of profiler")
+}
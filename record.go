@@ -11,15 +11,36 @@ package goavro
 
 import (
 	"fmt"
+	"sort"
 )
 
-func makeRecordCodec(st map[string]*Codec, enclosingNamespace string, schemaMap map[string]interface{}) (*Codec, error) {
+// recordField describes a single field of a record schema.
+type recordField struct {
+	name         string
+	codec        *Codec
+	defaultValue interface{}
+	hasDefault   bool
+
+	// deferDefault is true when the default value could not be encoded while
+	// the record was being built, because it may hold records of the schema
+	// whose codecs were not yet built, and is checked once the whole schema
+	// is built.
+	deferDefault bool
+}
+
+func makeRecordCodec(st map[string]*Codec, enclosingNamespace string, schemaMap map[string]interface{}, option *CodecOption) (*Codec, error) {
 	// NOTE: To support recursive data types, create the codec and register it
 	// using the specified name, and fill in the codec functions later.
 	c, err := registerNewCodec(st, schemaMap, enclosingNamespace)
 	if err != nil {
 		return nil, fmt.Errorf("Record ought to have valid name: %s", err)
 	}
+	// NOTE: Until the codec functions are filled in, encoding the record
+	// fails, rather than dereferencing a nil function, when checking default
+	// values of fields whose type refers to this record.
+	c.binaryFromNative = func([]byte, interface{}) ([]byte, error) {
+		return nil, fmt.Errorf("cannot encode binary record %q: codec not yet built", c.typeName)
+	}
 
 	fields, ok := schemaMap["fields"]
 	if !ok {
@@ -34,6 +55,8 @@ func makeRecordCodec(st map[string]*Codec, enclosingNamespace string, schemaMap
 	codecFromIndex := make([]*Codec, len(fieldSchemas))
 	nameFromIndex := make([]string, len(fieldSchemas))
 	defaultValueFromName := make(map[string]interface{}, len(fieldSchemas))
+	recordFields := make([]recordField, len(fieldSchemas))
+	deferDefault := make(map[string]bool)
 
 	for i, fieldSchema := range fieldSchemas {
 		fieldSchemaMap, ok := fieldSchema.(map[string]interface{})
@@ -44,7 +67,7 @@ func makeRecordCodec(st map[string]*Codec, enclosingNamespace string, schemaMap
 		// NOTE: field names are not registered in the symbol table, because
 		// field names are not individually addressable codecs.
 
-		fieldCodec, err := buildCodecForTypeDescribedByMap(st, c.typeName.namespace, fieldSchemaMap)
+		fieldCodec, err := buildCodecForTypeDescribedByMap(st, c.typeName.namespace, fieldSchemaMap, option)
 		if err != nil {
 			return nil, fmt.Errorf("Record %q field %d ought to be valid Avro named type: %s", c.typeName, i+1, err)
 		}
@@ -61,69 +84,16 @@ func makeRecordCodec(st map[string]*Codec, enclosingNamespace string, schemaMap
 		}
 
 		if defaultValue, ok := fieldSchemaMap["default"]; ok {
-			typeNameShort := fieldCodec.typeName.short()
-			switch typeNameShort {
-			case "boolean":
-				v, ok := defaultValue.(bool)
-				if !ok {
-					return nil, fmt.Errorf("Record %q field %q: default value ought to encode using field schema: %s", c.typeName, fieldName, err)
-				}
-				defaultValue = bool(v)
-			case "bytes":
-				v, ok := defaultValue.(string)
-				if !ok {
-					return nil, fmt.Errorf("Record %q field %q: default value ought to encode using field schema: %s", c.typeName, fieldName, err)
-				}
-				defaultValue = []byte(v)
-			case "double":
-				v, ok := defaultValue.(float64)
-				if !ok {
-					return nil, fmt.Errorf("Record %q field %q: default value ought to encode using field schema: %s", c.typeName, fieldName, err)
-				}
-				defaultValue = float64(v)
-			case "float":
-				v, ok := defaultValue.(float64)
-				if !ok {
-					return nil, fmt.Errorf("Record %q field %q: default value ought to encode using field schema: %s", c.typeName, fieldName, err)
-				}
-				defaultValue = float32(v)
-			case "int":
-				v, ok := defaultValue.(float64)
-				if !ok {
-					return nil, fmt.Errorf("Record %q field %q: default value ought to encode using field schema: %s", c.typeName, fieldName, err)
-				}
-				defaultValue = int32(v)
-			case "long":
-				v, ok := defaultValue.(float64)
-				if !ok {
-					return nil, fmt.Errorf("Record %q field %q: default value ought to encode using field schema: %s", c.typeName, fieldName, err)
-				}
-				defaultValue = int64(v)
-			case "string":
-				v, ok := defaultValue.(string)
-				if !ok {
-					return nil, fmt.Errorf("Record %q field %q: default value ought to encode using field schema: %s", c.typeName, fieldName, err)
-				}
-				defaultValue = string(v)
-			case "union":
-				// When codec is union, then default value ought to encode using
-				// first schema in union.  NOTE: To support a null default
-				// value, the string literal "null" must be coerced to a `nil`
-				if defaultValue == "null" {
-					defaultValue = nil
-				}
-				// NOTE: To support record field default values, union schema
-				// set to the type name of first member
-				// TODO: change to schemaCanonical below
-				defaultValue = Union(fieldCodec.schemaOriginal, defaultValue)
-			default:
-				debug("fieldName: %q; type: %q; defaultValue: %T(%#v)\n", fieldName, c.typeName, defaultValue, defaultValue)
+			if defaultValue, ok = nativeFromDefault(fieldCodec, defaultValue); !ok {
+				return nil, fmt.Errorf("Record %q field %q: default value ought to encode using field schema: %s", c.typeName, fieldName, err)
 			}
 
-			// attempt to encode default value using codec
-			_, err = fieldCodec.binaryFromNative(nil, defaultValue)
-			if err != nil {
-				return nil, fmt.Errorf("Record %q field %q: default value ought to encode using field schema: %s", c.typeName, fieldName, err)
+			// attempt to encode default value using codec; when this fails,
+			// the default value may hold this record or an enclosing record,
+			// whose codecs are not yet built, so it is checked again by
+			// checkDeferredDefaults once the whole schema is built.
+			if _, err = fieldCodec.binaryFromNative(nil, defaultValue); err != nil {
+				deferDefault[fieldName] = true
 			}
 			defaultValueFromName[fieldName] = defaultValue
 		}
@@ -131,7 +101,10 @@ func makeRecordCodec(st map[string]*Codec, enclosingNamespace string, schemaMap
 		nameFromIndex[i] = fieldName
 		codecFromIndex[i] = fieldCodec
 		codecFromFieldName[fieldName] = fieldCodec
+		defaultValue, hasDefault := defaultValueFromName[fieldName]
+		recordFields[i] = recordField{name: fieldName, codec: fieldCodec, defaultValue: defaultValue, hasDefault: hasDefault, deferDefault: deferDefault[fieldName]}
 	}
+	c.recordFields = recordFields
 
 	c.binaryFromNative = func(buf []byte, datum interface{}) ([]byte, error) {
 		valueMap, ok := datum.(map[string]interface{})
@@ -170,7 +143,7 @@ func makeRecordCodec(st map[string]*Codec, enclosingNamespace string, schemaMap
 			var err error
 			value, buf, err = fieldCodec.nativeFromBinary(buf)
 			if err != nil {
-				return nil, nil, fmt.Errorf("cannot decode binary record %q field %q: %s", c.typeName, name, err)
+				return nil, nil, decodeError(option, err, "cannot decode binary record %q field %q: %s", c.typeName, name, err)
 			}
 			recordMap[name] = value
 		}
@@ -210,24 +183,255 @@ func makeRecordCodec(st map[string]*Codec, enclosingNamespace string, schemaMap
 		if !ok {
 			return nil, fmt.Errorf("cannot encode textual record %q: expected map[string]interface{}; received: %T", c.typeName, datum)
 		}
-		destMap := make(map[string]interface{}, len(codecFromIndex))
-		for fieldName := range codecFromFieldName {
-			fieldValue, ok := sourceMap[fieldName]
+		// NOTE: Fields are written in the order they are declared in the
+		// schema, rather than the order Go iterates the datum, so the same
+		// datum is always encoded as the same text.
+		buf = append(buf, '{')
+		for i, field := range c.recordFields {
+			fieldValue, ok := sourceMap[field.name]
 			if !ok {
-				defaultValue, ok := defaultValueFromName[fieldName]
-				if !ok {
-					return nil, fmt.Errorf("cannot encode textual record %q field %q: schema does not specify default value and no value provided", c.typeName, fieldName)
+				if !field.hasDefault {
+					return nil, fmt.Errorf("cannot encode textual record %q field %q: schema does not specify default value and no value provided", c.typeName, field.name)
 				}
-				fieldValue = defaultValue
+				fieldValue = field.defaultValue
+			}
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			buf, _ = stringTextualFromNative(buf, field.name)
+			buf = append(buf, ':')
+			var err error
+			if buf, err = field.codec.textualFromNative(buf, fieldValue); err != nil {
+				return nil, fmt.Errorf("cannot encode textual record %q field %q: value does not match its schema: %s", c.typeName, field.name, err)
 			}
-			destMap[fieldName] = fieldValue
 		}
-		datum = destMap
-		// NOTE: Setting `defaultCodec == nil` instructs genericMapTextEncoder
-		// to return an error when a field name is not found in the
-		// codecFromFieldName map.
-		return genericMapTextEncoder(buf, datum, nil, codecFromFieldName)
+		return append(buf, '}'), nil
 	}
 
 	return c, nil
 }
+
+// recordNativeFromBinaryInto decodes the fields of the binary encoded record
+// into dest, recycling the maps dest holds for fields whose type is a record.
+func (c *Codec) recordNativeFromBinaryInto(buf []byte, dest map[string]interface{}) ([]byte, error) {
+	for _, field := range c.recordFields {
+		var err error
+		if nested, ok := dest[field.name].(map[string]interface{}); ok && field.codec.recordFields != nil {
+			if buf, err = field.codec.recordNativeFromBinaryInto(buf, nested); err != nil {
+				return nil, fmt.Errorf("cannot decode binary record %q field %q: %s", c.typeName, field.name, err)
+			}
+			continue
+		}
+		var value interface{}
+		if value, buf, err = field.codec.nativeFromBinary(buf); err != nil {
+			return nil, fmt.Errorf("cannot decode binary record %q field %q: %s", c.typeName, field.name, err)
+		}
+		dest[field.name] = value
+	}
+	if len(dest) > len(c.recordFields) {
+		for key := range dest {
+			if !c.hasRecordField(key) {
+				delete(dest, key)
+			}
+		}
+	}
+	return buf, nil
+}
+
+// recordNativeFromBinaryFields decodes the specified fields of the binary
+// encoded record, and skips the others.
+func (c *Codec) recordNativeFromBinaryFields(buf []byte, fields []string) (map[string]interface{}, []byte, error) {
+	selected := make(map[string]struct{}, len(fields))
+	for _, name := range fields {
+		if !c.hasRecordField(name) {
+			return nil, nil, fmt.Errorf("cannot decode binary record %q: unknown field %q", c.typeName, name)
+		}
+		selected[name] = struct{}{}
+	}
+	n, err := schemaNodeFromCodec(c)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot decode binary record %q: %s", c.typeName, err)
+	}
+
+	datum := make(map[string]interface{}, len(selected))
+	for i, field := range c.recordFields {
+		if _, ok := selected[field.name]; !ok {
+			if buf, err = skipBinary(n.fields[i].node, buf); err != nil {
+				return nil, nil, fmt.Errorf("cannot decode binary record %q field %q: %s", c.typeName, field.name, err)
+			}
+			continue
+		}
+		var value interface{}
+		if value, buf, err = field.codec.nativeFromBinary(buf); err != nil {
+			return nil, nil, fmt.Errorf("cannot decode binary record %q field %q: %s", c.typeName, field.name, err)
+		}
+		datum[field.name] = value
+	}
+	return datum, buf, nil
+}
+
+// hasRecordField returns true when the record has a field of the name.
+func (c *Codec) hasRecordField(name string) bool {
+	for _, field := range c.recordFields {
+		if field.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// checkDeferredDefaults checks the default values of record fields that could
+// not be encoded while their record was being built. Such default values may
+// hold records of the schema, including the record declaring the field, and
+// are rejected when filling in the fields they omit with their own default
+// values never ends, as in a record whose field defaults to an empty record of
+// its own type.
+func checkDeferredDefaults(st map[string]*Codec, schema interface{}) error {
+	var names []string
+	for fullName, cd := range st {
+		for _, field := range cd.recordFields {
+			if field.deferDefault {
+				names = append(names, fullName)
+				break
+			}
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+
+	nodes := make(map[string]*schemaNode)
+	if _, err := buildSchemaNode(nodes, nullNamespace, schema); err != nil {
+		return err // should not get here because schema was built above
+	}
+	for _, fullName := range names {
+		cd := st[fullName]
+		for i, field := range cd.recordFields {
+			if !field.deferDefault {
+				continue
+			}
+			if node, ok := nodes[fullName]; ok && i < len(node.fields) {
+				f := node.fields[i]
+				if err := checkDefaultExpansion(f, map[*schemaNodeField]bool{}); err != nil {
+					return fmt.Errorf("Record %q field %q: default value ought to be finite: %s", cd.typeName, field.name, err)
+				}
+			}
+			if _, err := field.codec.binaryFromNative(nil, field.defaultValue); err != nil {
+				return fmt.Errorf("Record %q field %q: default value ought to encode using field schema: %s", cd.typeName, field.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkDefaultExpansion returns an error when filling in the default value of
+// the field requires the default value of a field whose default value is
+// already being filled in.
+func checkDefaultExpansion(f *schemaNodeField, active map[*schemaNodeField]bool) error {
+	if active[f] {
+		return fmt.Errorf("default value of field %q holds itself", f.name)
+	}
+	n := f.node
+	if n.typeName == "union" && len(n.members) > 0 {
+		n = n.members[0] // default value of a union field is of its first member
+	}
+	active[f] = true
+	err := checkDefaultValue(n, f.defaultValue, active)
+	delete(active, f)
+	return err
+}
+
+// checkDefaultValue checks the records held by the value of a default value,
+// filling in the fields they omit using checkDefaultExpansion.
+func checkDefaultValue(n *schemaNode, value interface{}, active map[*schemaNodeField]bool) error {
+	switch n.typeName {
+	case "record":
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil // reported when encoding the default value
+		}
+		for _, f := range n.fields {
+			var err error
+			if v, ok := m[f.name]; ok {
+				err = checkDefaultValue(f.node, v, active)
+			} else if f.hasDefault {
+				err = checkDefaultExpansion(f, active)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	case "array":
+		items, _ := value.([]interface{})
+		for _, item := range items {
+			if err := checkDefaultValue(n.items, item, active); err != nil {
+				return err
+			}
+		}
+	case "map":
+		values, _ := value.(map[string]interface{})
+		for _, v := range values {
+			if err := checkDefaultValue(n.values, v, active); err != nil {
+				return err
+			}
+		}
+	case "union":
+		// NOTE: Union values held by default values are wrapped like native
+		// union values, in a map whose only key is the name of the member.
+		m, _ := value.(map[string]interface{})
+		for _, member := range n.members {
+			memberName := member.fullName
+			if memberName == "" {
+				memberName = member.typeName
+			}
+			if v, ok := m[memberName]; ok && len(m) == 1 {
+				return checkDefaultValue(member, v, active)
+			}
+		}
+	}
+	return nil
+}
+
+// nativeFromDefault converts the default value of a field of the Codec, as
+// decoded from the JSON schema, to the native form of the Codec. It returns
+// false when the JSON value is not of the type the Codec requires.
+func nativeFromDefault(fieldCodec *Codec, defaultValue interface{}) (interface{}, bool) {
+	switch fieldCodec.typeName.short() {
+	case "boolean":
+		v, ok := defaultValue.(bool)
+		return v, ok
+	case "bytes":
+		v, ok := defaultValue.(string)
+		return []byte(v), ok
+	case "double":
+		v, ok := defaultValue.(float64)
+		return v, ok
+	case "float":
+		v, ok := defaultValue.(float64)
+		return float32(v), ok
+	case "int":
+		v, ok := defaultValue.(float64)
+		return int32(v), ok
+	case "long":
+		v, ok := defaultValue.(float64)
+		return int64(v), ok
+	case "string":
+		v, ok := defaultValue.(string)
+		return v, ok
+	case "union":
+		// When codec is union, then default value ought to encode using
+		// first schema in union.  NOTE: To support a null default
+		// value, the string literal "null" must be coerced to a `nil`
+		if defaultValue == "null" {
+			defaultValue = nil
+		}
+		// NOTE: To support record field default values, union schema
+		// set to the type name of first member
+		// TODO: change to schemaCanonical below
+		return Union(fieldCodec.schemaOriginal, defaultValue), true
+	default:
+		debug("type: %q; defaultValue: %T(%#v)\n", fieldCodec.typeName, defaultValue, defaultValue)
+	}
+	return defaultValue, true
+}
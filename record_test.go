@@ -13,6 +13,7 @@ import (
 	"bytes"
 	"fmt"
 	"testing"
+	"time"
 )
 
 func TestRecordName(t *testing.T) {
@@ -384,6 +385,17 @@ func TestRecordTextCodecPass(t *testing.T) {
 	testTextDecodePass(t, `{"name":"r1","type":"record","fields":[{"name":"string","type":"string"},{"name":"bytes","type":"bytes"}]}`, map[string]interface{}{"string": silly, "bytes": []byte(silly)}, []byte(` { "string" : "\u0001\u2318 " , "bytes" : "\u0001\u00E2\u008C\u0098 " }`))
 }
 
+func TestRecordTextEncodeFieldOrder(t *testing.T) {
+	// fields are written in the order they are declared, with defaults for
+	// missing fields
+	schema := `{"name":"r1","type":"record","fields":[{"name":"z","type":"int"},{"name":"a","type":"string","default":"x"},{"name":"m","type":"boolean"}]}`
+	for i := 0; i < 16; i++ {
+		testTextEncodePass(t, schema, map[string]interface{}{"m": true, "z": 1}, []byte(`{"z":1,"a":"x","m":true}`))
+	}
+	testTextEncodeFail(t, schema, map[string]interface{}{"a": "y", "z": 1}, `field "m": schema does not specify default value`)
+	testTextEncodeFail(t, schema, map[string]interface{}{"m": 3, "z": 1}, `field "m": value does not match its schema`)
+}
+
 func TestRecordFieldDefaultValue(t *testing.T) {
 	testSchemaValid(t, `{"type":"record","name":"r1","fields":[{"name":"f1","type":"int","default":13}]}`)
 	testSchemaValid(t, `{"type":"record","name":"r1","fields":[{"name":"f1","type":"string","default":"foo"}]}`)
@@ -445,6 +457,48 @@ func TestRecordRecursiveRoundTrip(t *testing.T) {
 	}
 }
 
+func TestRecordMutuallyRecursiveDefaultValues(t *testing.T) {
+	codec := newCodecUsingV2(t, `{"type":"record","name":"Tree","fields":[
+		{"name":"root","type":{"type":"record","name":"Branch","fields":[
+			{"name":"label","type":"string","default":"root"},
+			{"name":"children","type":{"type":"array","items":"Tree"},"default":[]},
+			{"name":"parent","type":["null","Tree"],"default":null}
+		]},"default":{"children":[{"root":{"label":"leaf"}}]}}
+	]}`)
+
+	buf, err := codec.BinaryFromNative(nil, map[string]interface{}{})
+	ensureError(t, err)
+	// root: "root", children: [{root: "leaf", [], null}], parent: null
+	if actual, expected := fmt.Sprint(buf), "[8 114 111 111 116 2 8 108 101 97 102 0 0 0 0]"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	datum, _, err := codec.NativeFromBinary(buf)
+	ensureError(t, err)
+	if actual, expected := fmt.Sprint(datum), "map[root:map[children:[map[root:map[children:[] label:leaf parent:<nil>]]] label:root parent:<nil>]]"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	datum, err = codec.NewDefaultDatum()
+	ensureError(t, err)
+	if actual, expected := fmt.Sprint(datum), "map[root:map[children:[map[root:map[children:[] label:leaf parent:<nil>]]] label:root parent:<nil>]]"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
+
+func TestRecordRecursiveDefaultValuesInvalid(t *testing.T) {
+	// filling in the default value of b requires the default value of a,
+	// which requires the default value of b again
+	testSchemaInvalid(t,
+		`{"type":"record","name":"A","fields":[{"name":"b","type":{"type":"record","name":"B","fields":[{"name":"a","type":"A","default":{}}]},"default":{}}]}`,
+		`Record "A" field "b": default value ought to be finite: default value of field "b" holds itself`)
+	testSchemaInvalid(t,
+		`{"type":"record","name":"A","fields":[{"name":"kids","type":{"type":"array","items":"A"},"default":[]},{"name":"next","type":["A","null"],"default":{"kids":[{}]}}]}`,
+		`Record "A" field "next": default value ought to be finite`)
+	testSchemaInvalid(t,
+		`{"type":"record","name":"A","fields":[{"name":"b","type":{"type":"record","name":"B","fields":[{"name":"a","type":"A"}]},"default":{"a":"bad"}}]}`,
+		`Record "A" field "b": default value ought to encode using field schema`)
+}
+
 func ExampleRecordRecursiveRoundTrip() {
 	codec, err := NewCodec(`
 {
@@ -717,3 +771,94 @@ func TestRecordFieldDefaultValueTypes(t *testing.T) {
 		}
 	})
 }
+
+func TestRecordNativeFromBinaryInto(t *testing.T) {
+	codec := newCodecUsingV2(t, `{"type":"record","name":"Event","fields":[
+		{"name":"id","type":"long"},
+		{"name":"user","type":{"type":"record","name":"User","fields":[{"name":"name","type":"string"}]}}
+	]}`)
+
+	first, err := codec.BinaryFromNative(nil, map[string]interface{}{"id": 1, "user": map[string]interface{}{"name": "ann"}})
+	ensureError(t, err)
+	second, err := codec.BinaryFromNative(nil, map[string]interface{}{"id": 2, "user": map[string]interface{}{"name": "bob"}})
+	ensureError(t, err)
+
+	dest := map[string]interface{}{"stale": true}
+	rest, err := codec.NativeFromBinaryInto(append(first, 0xff), dest)
+	ensureError(t, err)
+	if actual, expected := len(rest), 1; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if actual, expected := fmt.Sprint(dest), "map[id:1 user:map[name:ann]]"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	user := dest["user"].(map[string]interface{})
+	_, err = codec.NativeFromBinaryInto(second, dest)
+	ensureError(t, err)
+	if actual, expected := fmt.Sprint(dest), "map[id:2 user:map[name:bob]]"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if user["name"] != "bob" {
+		t.Errorf("GOT: %v; WANT: nested map to be recycled", user)
+	}
+
+	rest, err = codec.NativeFromBinaryInto(first[:1], dest)
+	ensureError(t, err, "cannot decode binary record \"Event\" field \"user\"")
+	if actual, expected := len(rest), 1; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	_, err = newCodecUsingV2(t, `"long"`).NativeFromBinaryInto(first, dest)
+	ensureError(t, err, "schema ought to be a record")
+}
+
+func TestRecordNativeFromBinaryFields(t *testing.T) {
+	codec := newCodecUsingV2(t, `{"type":"record","name":"Event","fields":[
+		{"name":"id","type":"long"},
+		{"name":"tags","type":{"type":"array","items":"string"}},
+		{"name":"user","type":["null",{"type":"record","name":"User","fields":[{"name":"name","type":"string"}]}]},
+		{"name":"timestamp","type":{"type":"long","logicalType":"timestamp-millis"}},
+		{"name":"payload","type":"bytes"}
+	]}`)
+
+	buf, err := codec.BinaryFromNative(nil, map[string]interface{}{
+		"id":        7,
+		"tags":      []interface{}{"a", "b"},
+		"user":      Union("User", map[string]interface{}{"name": "ann"}),
+		"timestamp": int64(1500),
+		"payload":   []byte("large"),
+	})
+	ensureError(t, err)
+
+	datum, rest, err := codec.NativeFromBinaryFields(append(buf, 0xff), []string{"timestamp", "id"})
+	ensureError(t, err)
+	if actual, expected := len(rest), 1; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if actual, expected := len(datum), 2; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v fields", datum, expected)
+	}
+	if actual, expected := datum["id"], int64(7); actual != expected {
+		t.Errorf("GOT: %#v; WANT: %#v", actual, expected)
+	}
+	if actual, expected := datum["timestamp"].(time.Time).UnixNano(), int64(1500*time.Millisecond); actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	datum, _, err = codec.NativeFromBinaryFields(buf, []string{"user"})
+	ensureError(t, err)
+	if actual, expected := fmt.Sprint(datum), "map[user:map[User:map[name:ann]]]"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	_, _, err = codec.NativeFromBinaryFields(buf, []string{"missing"})
+	ensureError(t, err, `unknown field "missing"`)
+	_, rest, err = codec.NativeFromBinaryFields(buf[:len(buf)-2], []string{"id"})
+	ensureError(t, err, `field "payload"`)
+	if actual, expected := len(rest), len(buf)-2; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	_, _, err = newCodecUsingV2(t, `"long"`).NativeFromBinaryFields(buf, []string{"id"})
+	ensureError(t, err, "schema ought to be a record")
+}
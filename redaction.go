@@ -0,0 +1,459 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RedactionAction is what a RedactionPolicy does to the values of a record
+// field.
+type RedactionAction string
+
+// The actions of a RedactionPolicy.
+const (
+	// RedactDrop removes the field from the schema and the data.
+	RedactDrop RedactionAction = "drop"
+
+	// RedactHash replaces the values of the field with the hexadecimal
+	// SHA-256 hash of their bytes, keyed by RedactionPolicy.HashKey, when
+	// set, using HMAC. The type of the field becomes string, or a union of
+	// null and string when the field may be null, and null values remain
+	// null. Equal values have equal hashes, so hashed fields may still be
+	// joined and counted. It applies to fields whose type is a primitive
+	// type other than null, an enum, or a fixed, or a union of null and one
+	// of those. Values are hashed as the bytes of strings, bytes, and fixed,
+	// the symbols of enums, and the Avro JSON encoding of other types.
+	RedactHash RedactionAction = "hash"
+
+	// RedactNull replaces the values of the field with null. The type of
+	// the field becomes a union of null and its type, unless it already is
+	// one, whose default is null.
+	RedactNull RedactionAction = "null"
+)
+
+// RedactionPolicy specifies the RedactionAction of record fields by their
+// path, using the conventions of SchemaFieldDoc, so privacy policies may live
+// in configuration rather than code. A policy document is the JSON encoding
+// of the policy:
+//
+//     {
+//         "fields": {
+//             "customer.email": "hash",
+//             "customer.ssn": "drop",
+//             "payments[].card": "null"
+//         }
+//     }
+type RedactionPolicy struct {
+	Fields map[string]RedactionAction `json:"fields"`
+
+	// HashKey keys the hashes of RedactHash using HMAC-SHA256, (optional).
+	// It is not read from policy documents, so the key may be kept apart
+	// from the policy. Without a key, the hashes of values from a small set,
+	// such as phone numbers, are easily reversed by hashing every possible
+	// value.
+	HashKey []byte `json:"-"`
+}
+
+// ReadRedactionPolicy reads a policy document from r.
+func ReadRedactionPolicy(r io.Reader) (*RedactionPolicy, error) {
+	d := json.NewDecoder(r)
+	d.DisallowUnknownFields()
+	policy := new(RedactionPolicy)
+	if err := d.Decode(policy); err != nil {
+		return nil, fmt.Errorf("cannot read redaction policy: %s", err)
+	}
+	for path, action := range policy.Fields {
+		switch action {
+		case RedactDrop, RedactHash, RedactNull:
+		default:
+			return nil, fmt.Errorf("cannot read redaction policy: field %q: action ought to be %q, %q, or %q; received: %q", path, RedactDrop, RedactHash, RedactNull, action)
+		}
+	}
+	return policy, nil
+}
+
+// Redactor applies a RedactionPolicy to the data of a Codec, producing data of
+// the redacted schema, which is valid Avro data that no longer holds the
+// redacted values.
+//
+// The policy applies to the record that declares each field, so when a named
+// record is used at several paths, a field redacted at one of them is redacted
+// at every one of them.
+//
+//     policy, err := goavro.ReadRedactionPolicy(file)
+//     if err != nil {
+//         return err
+//     }
+//     redactor, err := goavro.NewRedactor(codec, policy)
+//     if err != nil {
+//         return err
+//     }
+//     datum, _, err := redactor.NativeFromBinary(buf)
+type Redactor struct {
+	input, output *Codec
+	root          *schemaNode
+	actions       map[*schemaNode]map[string]RedactionAction // by record, then field name
+	hashKey       []byte
+}
+
+// NewRedactor returns a Redactor applying the policy to the data of the codec.
+// It returns an error when a path of the policy does not name a record field,
+// when RedactHash applies to a field whose type cannot be hashed, or when
+// several paths name the same field of a named record with different actions.
+func NewRedactor(codec *Codec, policy *RedactionPolicy) (*Redactor, error) {
+	root, err := schemaNodeFromCodec(codec)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create Redactor: %s", err)
+	}
+	r := &Redactor{input: codec, root: root, actions: make(map[*schemaNode]map[string]RedactionAction), hashKey: policy.HashKey}
+
+	paths := make([]string, 0, len(policy.Fields))
+	for path := range policy.Fields {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		action := policy.Fields[path]
+		record, f, err := schemaFieldAtPath(root, path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create Redactor: field %q: %s", path, err)
+		}
+		switch action {
+		case RedactDrop, RedactNull:
+		case RedactHash:
+			if n, _ := nonNullMember(f.node); !isFormScalar(n) {
+				return nil, fmt.Errorf("cannot create Redactor: field %q: cannot hash values of type: %s", path, f.node.label())
+			}
+		default:
+			return nil, fmt.Errorf("cannot create Redactor: field %q: unknown action: %q", path, action)
+		}
+		fields, ok := r.actions[record]
+		if !ok {
+			fields = make(map[string]RedactionAction)
+			r.actions[record] = fields
+		}
+		if previous, ok := fields[f.name]; ok && previous != action {
+			return nil, fmt.Errorf("cannot create Redactor: field %q: record %q is used at several paths, whose field %q ought to have the same action: %q != %q", path, record.fullName, f.name, action, previous)
+		}
+		fields[f.name] = action
+	}
+
+	redacted := r.redactNode(root, make(map[*schemaNode]*schemaNode))
+	schema, err := json.Marshal(redacted.schemaValue(make(map[*schemaNode]struct{})))
+	if err != nil {
+		return nil, fmt.Errorf("cannot create Redactor: %s", err) // should not get here
+	}
+	if r.output, err = NewCodec(string(schema)); err != nil {
+		return nil, fmt.Errorf("cannot create Redactor: cannot create redacted codec: %s", err)
+	}
+	return r, nil
+}
+
+// nonNullMember returns the member of a union of null and one other type that
+// is not null, and true; or otherwise n, and false.
+func nonNullMember(n *schemaNode) (*schemaNode, bool) {
+	if n.typeName == "union" && len(n.members) == 2 && (n.members[0].typeName == "null") != (n.members[1].typeName == "null") {
+		if n.members[0].typeName == "null" {
+			return n.members[1], true
+		}
+		return n.members[0], true
+	}
+	return n, false
+}
+
+// redactNode returns a copy of the schema of n with the actions of the policy
+// applied. Nodes shared by several parents remain shared in the copy.
+func (r *Redactor) redactNode(n *schemaNode, copies map[*schemaNode]*schemaNode) *schemaNode {
+	if c, ok := copies[n]; ok {
+		return c
+	}
+	c := new(schemaNode)
+	*c = *n
+	copies[n] = c
+	switch n.typeName {
+	case "array":
+		c.items = r.redactNode(n.items, copies)
+	case "map":
+		c.values = r.redactNode(n.values, copies)
+	case "union":
+		c.members = make([]*schemaNode, len(n.members))
+		for i, member := range n.members {
+			c.members[i] = r.redactNode(member, copies)
+		}
+	case "record":
+		c.fields = make([]*schemaNodeField, 0, len(n.fields))
+		for _, f := range n.fields {
+			action := r.actions[n][f.name]
+			if action == RedactDrop {
+				continue
+			}
+			cf := new(schemaNodeField)
+			*cf = *f
+			cf.node = r.redactNode(f.node, copies)
+			switch action {
+			case RedactNull:
+				members := []*schemaNode{{typeName: "null"}}
+				if cf.node.typeName == "union" {
+					for _, member := range cf.node.members {
+						if member.typeName != "null" {
+							members = append(members, member)
+						}
+					}
+				} else {
+					members = append(members, cf.node)
+				}
+				cf.node = &schemaNode{typeName: "union", members: members}
+				cf.defaultValue, cf.hasDefault = nil, true
+			case RedactHash:
+				if _, nullable := nonNullMember(f.node); nullable {
+					cf.node = &schemaNode{typeName: "union", members: []*schemaNode{{typeName: "null"}, {typeName: "string"}}}
+					cf.hasDefault = f.hasDefault && f.defaultValue == nil
+				} else {
+					cf.node = &schemaNode{typeName: "string"}
+					cf.hasDefault = false
+				}
+				cf.defaultValue = nil
+			}
+			c.fields = append(c.fields, cf)
+		}
+	}
+	return c
+}
+
+// Codec returns the Codec of the redacted schema, which encodes and decodes
+// the data returned by the Redactor.
+func (r *Redactor) Codec() *Codec {
+	return r.output
+}
+
+// Redact returns the redacted native datum of the redacted schema, from the
+// native datum of the schema of the Codec of the Redactor. The provided datum
+// is not modified.
+func (r *Redactor) Redact(datum interface{}) (interface{}, error) {
+	buf, err := r.input.TextualFromNative(nil, datum)
+	if err != nil {
+		return nil, fmt.Errorf("cannot redact: %s", err)
+	}
+	d := json.NewDecoder(bytes.NewReader(buf))
+	d.UseNumber()
+	var value interface{}
+	if err = d.Decode(&value); err != nil {
+		return nil, fmt.Errorf("cannot redact: %s", err) // should not get here
+	}
+	if value, err = r.redactValue(r.root, value); err != nil {
+		return nil, fmt.Errorf("cannot redact: %s", err)
+	}
+	if buf, err = json.Marshal(value); err != nil {
+		return nil, fmt.Errorf("cannot redact: %s", err) // should not get here
+	}
+	redacted, _, err := r.output.NativeFromTextual(buf)
+	if err != nil {
+		return nil, fmt.Errorf("cannot redact: %s", err) // should not get here
+	}
+	return redacted, nil
+}
+
+// NativeFromBinary decodes one binary datum of the schema of the Codec of the
+// Redactor from buf, like Codec.NativeFromBinary, and returns it redacted,
+// along with the remaining bytes of buf.
+func (r *Redactor) NativeFromBinary(buf []byte) (interface{}, []byte, error) {
+	datum, newBuf, err := r.input.NativeFromBinary(buf)
+	if err != nil {
+		return nil, buf, err
+	}
+	if datum, err = r.Redact(datum); err != nil {
+		return nil, buf, err
+	}
+	return datum, newBuf, nil
+}
+
+// RedactOCF writes to w an OCF of the redacted data of each remaining data item
+// read from the OCF, using the redacted schema and the compression algorithm
+// of the OCF read, and returns the number of data items written. The data
+// items are written in blocks of the same counts as the blocks read. The
+// metadata of the OCF read is not copied, as it may describe the redacted
+// data. The data items ought to be of the schema of the Codec of the
+// Redactor.
+func (r *Redactor) RedactOCF(w io.Writer, ocfr *OCFReader) (int64, error) {
+	if reader := ocfr.readerCodec(); reader.CanonicalSchema() != r.input.CanonicalSchema() {
+		return 0, fmt.Errorf("cannot redact OCF: schema of OCF ought to match schema of Redactor: %s != %s", reader.CanonicalSchema(), r.input.CanonicalSchema())
+	}
+	ocfw, err := NewOCFWriter(OCFConfig{W: w, Codec: r.output, CompressionName: ocfr.CompressionName()})
+	if err != nil {
+		return 0, fmt.Errorf("cannot redact OCF: %s", err)
+	}
+	var count int64
+	var block []interface{}
+	for ocfr.Scan() {
+		datum, err := ocfr.Read()
+		if err != nil {
+			return count, fmt.Errorf("cannot redact OCF: %s", err)
+		}
+		if datum, err = r.Redact(datum); err != nil {
+			return count, fmt.Errorf("cannot redact OCF: %s", err)
+		}
+		block = append(block, datum)
+		if ocfr.RemainingBlockItems() == 0 {
+			if err = ocfw.Append(block); err != nil {
+				return count, fmt.Errorf("cannot redact OCF: %s", err)
+			}
+			count += int64(len(block))
+			block = block[:0]
+		}
+	}
+	if err = ocfr.Err(); err != nil {
+		return count, fmt.Errorf("cannot redact OCF: %s", err)
+	}
+	if len(block) > 0 {
+		if err = ocfw.Append(block); err != nil {
+			return count, fmt.Errorf("cannot redact OCF: %s", err)
+		}
+		count += int64(len(block))
+	}
+	if err = ocfw.Close(); err != nil {
+		return count, fmt.Errorf("cannot redact OCF: %s", err)
+	}
+	return count, nil
+}
+
+// redactValue applies the actions of the policy to the value, decoded from
+// the Avro JSON encoding of a value of the type of the node.
+func (r *Redactor) redactValue(n *schemaNode, value interface{}) (interface{}, error) {
+	var err error
+	switch n.typeName {
+	case "record":
+		record, ok := value.(map[string]interface{})
+		if !ok {
+			return value, nil
+		}
+		for _, f := range n.fields {
+			v, ok := record[f.name]
+			if !ok {
+				continue
+			}
+			switch r.actions[n][f.name] {
+			case RedactDrop:
+				delete(record, f.name)
+			case RedactNull:
+				record[f.name] = nil
+			case RedactHash:
+				record[f.name] = r.hashValue(f.node, v)
+			default:
+				if record[f.name], err = r.redactValue(f.node, v); err != nil {
+					return nil, err
+				}
+			}
+		}
+	case "array":
+		items, _ := value.([]interface{})
+		for i, item := range items {
+			if items[i], err = r.redactValue(n.items, item); err != nil {
+				return nil, err
+			}
+		}
+	case "map":
+		values, _ := value.(map[string]interface{})
+		for k, v := range values {
+			if values[k], err = r.redactValue(n.values, v); err != nil {
+				return nil, err
+			}
+		}
+	case "union":
+		wrapped, ok := value.(map[string]interface{})
+		if !ok || len(wrapped) != 1 {
+			return value, nil
+		}
+		for name, v := range wrapped {
+			member := unionMemberByTextName(n, name)
+			if member == nil {
+				return nil, fmt.Errorf("union has no member: %q", name) // should not get here
+			}
+			if wrapped[name], err = r.redactValue(member, v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return value, nil
+}
+
+// unionMemberByTextName returns the member of the union that the Avro JSON
+// encoding of union values names, by its full name, or by its short name when
+// the Codec uses relative union names.
+func unionMemberByTextName(n *schemaNode, name string) *schemaNode {
+	for _, member := range n.members {
+		if unionMemberName(member) == name {
+			return member
+		}
+	}
+	for _, member := range n.members {
+		if member.isNamed() && member.fullName[strings.LastIndexByte(member.fullName, '.')+1:] == name {
+			return member
+		}
+	}
+	return nil
+}
+
+// hashValue returns the hash of the value, decoded from the Avro JSON
+// encoding of a value of the type of the node, in the Avro JSON encoding of
+// the type of the hashed field.
+func (r *Redactor) hashValue(n *schemaNode, value interface{}) interface{} {
+	n, nullable := nonNullMember(n)
+	if value == nil {
+		return nil
+	}
+	if nullable {
+		for _, v := range value.(map[string]interface{}) {
+			value = v
+		}
+	}
+	var data []byte
+	switch v := value.(type) {
+	case string:
+		if n.typeName == "bytes" || n.typeName == "fixed" {
+			// NOTE: each code point of the Avro JSON encoding is a byte
+			data = make([]byte, 0, len(v))
+			for _, r := range v {
+				data = append(data, byte(r))
+			}
+		} else {
+			data = []byte(v)
+		}
+	case json.Number:
+		data = []byte(v)
+	case bool:
+		data = strconv.AppendBool(data, v)
+	}
+	var h hash.Hash
+	if len(r.hashKey) > 0 {
+		h = hmac.New(sha256.New, r.hashKey)
+	} else {
+		h = sha256.New()
+	}
+	_, _ = h.Write(data)
+	sum := hex.EncodeToString(h.Sum(nil))
+	if nullable {
+		return map[string]interface{}{"string": sum}
+	}
+	return sum
+}
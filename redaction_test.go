@@ -0,0 +1,185 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const redactionTestSchema = `{"type":"record","name":"com.example.order","fields":[
+	{"name":"id","type":"long"},
+	{"name":"customer","type":{"type":"record","name":"customer","fields":[
+		{"name":"name","type":"string"},
+		{"name":"email","type":["null","string"],"default":null},
+		{"name":"ssn","type":"string"}
+	]}},
+	{"name":"card","type":"long","default":0},
+	{"name":"payer","type":"customer"}
+]}`
+
+func redactionTestDatum() map[string]interface{} {
+	return map[string]interface{}{
+		"id": int64(1),
+		"customer": map[string]interface{}{
+			"name":  "Ann",
+			"email": Union("string", "ann@example.com"),
+			"ssn":   "123-45-6789",
+		},
+		"card": int64(4111111111111111),
+		"payer": map[string]interface{}{
+			"name":  "Bo",
+			"email": nil,
+			"ssn":   "987-65-4321",
+		},
+	}
+}
+
+func TestReadRedactionPolicy(t *testing.T) {
+	policy, err := ReadRedactionPolicy(strings.NewReader(`{"fields":{"customer.ssn":"drop","card":"null"}}`))
+	ensureError(t, err)
+	if actual, expected := policy.Fields, map[string]RedactionAction{"customer.ssn": RedactDrop, "card": RedactNull}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	_, err = ReadRedactionPolicy(strings.NewReader(`{"fields":{"card":"mask"}}`))
+	ensureError(t, err, "cannot read redaction policy", `field "card"`, `"mask"`)
+
+	_, err = ReadRedactionPolicy(strings.NewReader(`{"field":{}}`))
+	ensureError(t, err, "cannot read redaction policy")
+}
+
+func TestRedactor(t *testing.T) {
+	codec, err := NewCodec(redactionTestSchema)
+	ensureError(t, err)
+	key := []byte("secret")
+	r, err := NewRedactor(codec, &RedactionPolicy{
+		Fields:  map[string]RedactionAction{"customer.ssn": RedactDrop, "customer.email": RedactHash, "card": RedactNull, "id": RedactHash},
+		HashKey: key,
+	})
+	ensureError(t, err)
+
+	expected := `{"fields":[{"name":"id","type":"string"},{"name":"customer","type":{"fields":[{"name":"name","type":"string"},{"default":null,"name":"email","type":["null","string"]}],"name":"com.example.customer","type":"record"}},{"default":null,"name":"card","type":["null","long"]},{"name":"payer","type":"com.example.customer"}],"name":"com.example.order","type":"record"}`
+	if actual := r.Codec().Schema(); actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	hmacHex := func(data string) string {
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(data))
+		return hex.EncodeToString(h.Sum(nil))
+	}
+	datum := redactionTestDatum()
+	buf, err := codec.BinaryFromNative(nil, datum)
+	ensureError(t, err)
+	redacted, rest, err := r.NativeFromBinary(append(buf, 0xff))
+	ensureError(t, err)
+	if actual, expected := rest, []byte{0xff}; !bytes.Equal(actual, expected) {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if actual, expected := redacted, map[string]interface{}{
+		"id": hmacHex("1"),
+		"customer": map[string]interface{}{
+			"name":  "Ann",
+			"email": map[string]interface{}{"string": hmacHex("ann@example.com")},
+		},
+		"card": nil,
+		"payer": map[string]interface{}{
+			"name":  "Bo",
+			"email": nil,
+		},
+	}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if actual, expected := datum["customer"].(map[string]interface{})["ssn"], "123-45-6789"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
+
+func TestRedactorUnkeyedHash(t *testing.T) {
+	codec, err := NewCodec(`{"type":"record","name":"r","fields":[{"name":"b","type":"bytes"}]}`)
+	ensureError(t, err)
+	r, err := NewRedactor(codec, &RedactionPolicy{Fields: map[string]RedactionAction{"b": RedactHash}})
+	ensureError(t, err)
+	redacted, err := r.Redact(map[string]interface{}{"b": []byte{0, 0xff}})
+	ensureError(t, err)
+	sum := sha256.Sum256([]byte{0, 0xff})
+	if actual, expected := redacted, map[string]interface{}{"b": hex.EncodeToString(sum[:])}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
+
+func TestRedactorErrors(t *testing.T) {
+	codec, err := NewCodec(redactionTestSchema)
+	ensureError(t, err)
+
+	_, err = NewRedactor(codec, &RedactionPolicy{Fields: map[string]RedactionAction{"customer.phone": RedactDrop}})
+	ensureError(t, err, "cannot create Redactor", `field "customer.phone"`)
+
+	_, err = NewRedactor(codec, &RedactionPolicy{Fields: map[string]RedactionAction{"customer": RedactHash}})
+	ensureError(t, err, "cannot create Redactor", "cannot hash")
+
+	_, err = NewRedactor(codec, &RedactionPolicy{Fields: map[string]RedactionAction{"customer.ssn": RedactDrop, "payer.ssn": RedactNull}})
+	ensureError(t, err, "cannot create Redactor", "several paths")
+
+	_, err = NewRedactor(codec, &RedactionPolicy{Fields: map[string]RedactionAction{"customer.ssn": RedactDrop, "payer.ssn": RedactDrop}})
+	ensureError(t, err)
+}
+
+func TestRedactorOCF(t *testing.T) {
+	codec, err := NewCodec(redactionTestSchema)
+	ensureError(t, err)
+	bb := new(bytes.Buffer)
+	ocfw, err := NewOCFWriter(OCFConfig{W: bb, Codec: codec, CompressionName: CompressionDeflateLabel})
+	ensureError(t, err)
+	ensureError(t, ocfw.Append([]interface{}{redactionTestDatum(), redactionTestDatum()}))
+	ensureError(t, ocfw.Append([]interface{}{redactionTestDatum()}))
+
+	r, err := NewRedactor(codec, &RedactionPolicy{Fields: map[string]RedactionAction{"customer.ssn": RedactDrop}})
+	ensureError(t, err)
+	ocfr, err := NewOCFReader(bytes.NewReader(bb.Bytes()))
+	ensureError(t, err)
+	out := new(bytes.Buffer)
+	count, err := r.RedactOCF(out, ocfr)
+	ensureError(t, err)
+	if actual, expected := count, int64(3); actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	obr, err := NewOCFBlockReader(bytes.NewReader(out.Bytes()))
+	ensureError(t, err)
+	if actual, expected := obr.CompressionName(), CompressionDeflateLabel; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if actual, expected := obr.Codec().Schema(), r.Codec().Schema(); actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	var counts []int64
+	for obr.Scan() {
+		counts = append(counts, obr.Block().Count)
+	}
+	ensureError(t, obr.Err())
+	if actual, expected := counts, []int64{2, 1}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	ocfr, err = NewOCFReader(bytes.NewReader(out.Bytes()))
+	ensureError(t, err)
+	_, err = r.RedactOCF(new(bytes.Buffer), ocfr)
+	ensureError(t, err, "cannot redact OCF", "ought to match")
+}
@@ -0,0 +1,280 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Plugins extend goavro with compression algorithms, logical types, and
+// expression engines that are not built in, so the core package does not depend on their
+// implementations. A plugin registers itself from the init function of its
+// package, so importing that package for its side effects is enough to use
+// it:
+//
+//     import _ "github.com/linkedin/goavro/v2/uuid"
+//
+// Registration is expected to complete before codecs, OCFReaders, and
+// OCFWriters are created, although registering later is safe.
+var registry = struct {
+	sync.RWMutex
+	compressions []OCFCompression // indexed by compressionID - compressionRegistered
+	logicalTypes map[string]LogicalType
+	engines      map[string]ExpressionEngine
+}{logicalTypes: make(map[string]LogicalType), engines: make(map[string]ExpressionEngine)}
+
+// OCFCompression describes a compression algorithm for the blocks of Object
+// Container Files, registered using RegisterCompression.
+type OCFCompression struct {
+	// Name is the value of the avro.codec metadata of the OCF files that use
+	// this algorithm, also used as the CompressionName of OCFConfig.
+	Name string
+
+	// Compress returns the compressed form of a block.
+	Compress func(block []byte) ([]byte, error)
+
+	// Decompress returns the decompressed form of a compressed block.
+	Decompress func(block []byte) ([]byte, error)
+
+	// Backend names an alternative implementation of the algorithm, such as
+	// one using cgo or specialized instructions, (optional). A backend is only
+	// used when selected by the CompressionBackend of OCFConfig, or the
+	// CompressionBackends of OCFReaderConfig, so programs choose between
+	// implementations at runtime rather than with build tags. When Backend
+	// is empty, this is the default implementation of the algorithm.
+	Backend string
+}
+
+// RegisterCompression makes a compression algorithm, or an alternative backend
+// of an algorithm, available to OCFReader and OCFWriter. It panics when the
+// name is empty, when the algorithm or backend is already registered, when
+// registering the default implementation of one of the built-in algorithms,
+// or when either function is nil.
+func RegisterCompression(compression OCFCompression) {
+	if compression.Name == "" || compression.Compress == nil || compression.Decompress == nil {
+		panic("goavro: RegisterCompression requires Name, Compress, and Decompress")
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	if compression.Backend != "" {
+		if _, ok := compressionBackendID(compression.Name, compression.Backend); ok {
+			panic(fmt.Sprintf("goavro: RegisterCompression called twice for %q backend %q", compression.Name, compression.Backend))
+		}
+	} else if cID, ok := compressionIDFromName(compression.Name); ok && !isCompressionBackend(cID) {
+		panic(fmt.Sprintf("goavro: RegisterCompression called twice for %q", compression.Name))
+	}
+	if len(registry.compressions) > int(^compressionID(0)-compressionRegistered) {
+		panic("goavro: RegisterCompression called too many times")
+	}
+	registry.compressions = append(registry.compressions, compression)
+}
+
+// compressionIDFromName returns the compressionID of the default
+// implementation of the named built-in or registered compression algorithm,
+// or, when only backends of the algorithm are registered, of the first of
+// them. The caller holds the registry lock.
+func compressionIDFromName(name string) (compressionID, bool) {
+	switch name {
+	case CompressionNullLabel:
+		return compressionNull, true
+	case CompressionDeflateLabel:
+		return compressionDeflate, true
+	case CompressionSnappyLabel:
+		return compressionSnappy, true
+	}
+	cID, found := compressionID(0), false
+	for i, compression := range registry.compressions {
+		if compression.Name == name {
+			if compression.Backend == "" {
+				return compressionRegistered + compressionID(i), true
+			}
+			if !found {
+				cID, found = compressionRegistered+compressionID(i), true
+			}
+		}
+	}
+	return cID, found
+}
+
+// compressionBackendID returns the compressionID of the named backend of the
+// named compression algorithm. The caller holds the registry lock.
+func compressionBackendID(name, backend string) (compressionID, bool) {
+	for i, compression := range registry.compressions {
+		if compression.Name == name && compression.Backend == backend {
+			return compressionRegistered + compressionID(i), true
+		}
+	}
+	return 0, false
+}
+
+// isCompressionBackend returns true when the compressionID is of an
+// alternative backend. The caller holds the registry lock.
+func isCompressionBackend(cID compressionID) bool {
+	return cID >= compressionRegistered && registry.compressions[cID-compressionRegistered].Backend != ""
+}
+
+// selectCompressionBackend returns the compressionID of the named backend of
+// the compression algorithm identified by cID. When backend is empty, it
+// returns cID.
+func selectCompressionBackend(cID compressionID, backend string) (compressionID, error) {
+	if backend == "" {
+		return cID, nil
+	}
+	name, ok := compressionName(cID)
+	if !ok {
+		return cID, fmt.Errorf("should not get here: unrecognized compression algorithm: %d", cID)
+	}
+	registry.RLock()
+	defer registry.RUnlock()
+	if backendID, ok := compressionBackendID(name, backend); ok {
+		return backendID, nil
+	}
+	return cID, fmt.Errorf("unrecognized compression backend for %q: %q", name, backend)
+}
+
+// lookupCompression returns the compressionID of the named compression
+// algorithm.
+func lookupCompression(name string) (compressionID, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	return compressionIDFromName(name)
+}
+
+// registeredCompression returns the registered compression algorithm with the
+// specified compressionID.
+func registeredCompression(cID compressionID) (OCFCompression, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	if cID < compressionRegistered || int(cID-compressionRegistered) >= len(registry.compressions) {
+		return OCFCompression{}, false
+	}
+	return registry.compressions[cID-compressionRegistered], true
+}
+
+// compressionName returns the name of the compression algorithm with the
+// specified compressionID.
+func compressionName(cID compressionID) (string, bool) {
+	switch cID {
+	case compressionNull:
+		return CompressionNullLabel, true
+	case compressionDeflate:
+		return CompressionDeflateLabel, true
+	case compressionSnappy:
+		return CompressionSnappyLabel, true
+	}
+	compression, ok := registeredCompression(cID)
+	return compression.Name, ok
+}
+
+// LogicalType describes an Avro logical type annotating a primitive type,
+// registered using RegisterLogicalType. Codecs for schemas that specify the
+// logical type convert between its native form and the native form of the
+// primitive type.
+//
+//     goavro.RegisterLogicalType(goavro.LogicalType{
+//         Type: "string",
+//         Name: "email",
+//         ToNative: func(datum interface{}) (interface{}, error) {
+//             return mail.ParseAddress(datum.(string))
+//         },
+//         FromNative: func(datum interface{}) (interface{}, error) {
+//             if a, ok := datum.(*mail.Address); ok {
+//                 return a.Address, nil
+//             }
+//             return datum, nil
+//         },
+//     })
+type LogicalType struct {
+	// Type is the name of the annotated primitive type, such as "string".
+	Type string
+
+	// Name is the value of the logicalType attribute, such as "uuid".
+	Name string
+
+	// ToNative converts a datum decoded using the primitive type, from
+	// binary or from text, to the native form of the logical type.
+	ToNative func(datum interface{}) (interface{}, error)
+
+	// FromNative converts the native form of the logical type to a datum
+	// that may be encoded using the primitive type.
+	FromNative func(datum interface{}) (interface{}, error)
+}
+
+// RegisterLogicalType makes a logical type available to codecs created after
+// it was registered. It panics when Type is not a primitive type name, when
+// the logical type is already registered or is built in, or when either
+// function is nil.
+func RegisterLogicalType(logicalType LogicalType) {
+	if logicalType.Name == "" || logicalType.ToNative == nil || logicalType.FromNative == nil {
+		panic("goavro: RegisterLogicalType requires Name, ToNative, and FromNative")
+	}
+	st := newSymbolTable()
+	if _, ok := st[logicalType.Type]; !ok || strings.Contains(logicalType.Type, ".") {
+		panic(fmt.Sprintf("goavro: RegisterLogicalType requires a primitive type: %q", logicalType.Type))
+	}
+	key := logicalType.Type + "." + logicalType.Name
+	if _, ok := st[key]; ok || key == "bytes.decimal" {
+		panic(fmt.Sprintf("goavro: RegisterLogicalType called for built in logical type %q", key))
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := registry.logicalTypes[key]; ok {
+		panic(fmt.Sprintf("goavro: RegisterLogicalType called twice for %q", key))
+	}
+	registry.logicalTypes[key] = logicalType
+}
+
+// addRegisteredLogicalTypes adds a codec for each registered logical type to
+// the symbol table, which holds the primitive codecs they wrap.
+func addRegisteredLogicalTypes(st map[string]*Codec) {
+	registry.RLock()
+	defer registry.RUnlock()
+	for key, logicalType := range registry.logicalTypes {
+		st[key] = newRegisteredLogicalTypeCodec(key, logicalType, st[logicalType.Type])
+	}
+}
+
+func newRegisteredLogicalTypeCodec(key string, logicalType LogicalType, primitive *Codec) *Codec {
+	toNative := func(decoder func([]byte) (interface{}, []byte, error)) func([]byte) (interface{}, []byte, error) {
+		return func(buf []byte) (interface{}, []byte, error) {
+			datum, buf, err := decoder(buf)
+			if err != nil {
+				return nil, buf, err
+			}
+			if datum, err = logicalType.ToNative(datum); err != nil {
+				return nil, buf, fmt.Errorf("cannot decode %s: %s", key, err)
+			}
+			return datum, buf, nil
+		}
+	}
+	fromNative := func(encoder func([]byte, interface{}) ([]byte, error)) func([]byte, interface{}) ([]byte, error) {
+		return func(buf []byte, datum interface{}) ([]byte, error) {
+			datum, err := logicalType.FromNative(datum)
+			if err != nil {
+				return nil, fmt.Errorf("cannot encode %s: %s", key, err)
+			}
+			return encoder(buf, datum)
+		}
+	}
+	return &Codec{
+		typeName:          &name{key, nullNamespace},
+		schemaOriginal:    primitive.schemaOriginal,
+		schemaCanonical:   primitive.schemaCanonical,
+		nativeFromBinary:  toNative(primitive.nativeFromBinary),
+		nativeFromTextual: toNative(primitive.nativeFromTextual),
+		binaryFromNative:  fromNative(primitive.binaryFromNative),
+		textualFromNative: fromNative(primitive.textualFromNative),
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// xorBlock is a test compression algorithm, which inverts every bit.
+func xorBlock(block []byte) ([]byte, error) {
+	out := make([]byte, len(block))
+	for i, b := range block {
+		out[i] = b ^ 0xff
+	}
+	return out, nil
+}
+
+// countingDeflate counts the blocks compressed and decompressed by a test
+// backend of the deflate algorithm.
+var countingDeflate struct{ compressed, decompressed int }
+
+func init() {
+	RegisterCompression(OCFCompression{Name: "test-xor", Compress: xorBlock, Decompress: xorBlock})
+	RegisterCompression(OCFCompression{
+		Name:    CompressionDeflateLabel,
+		Backend: "test-counting",
+		Compress: func(block []byte) ([]byte, error) {
+			countingDeflate.compressed++
+			return compressOCFBlock(compressionDeflate, block)
+		},
+		Decompress: func(block []byte) ([]byte, error) {
+			countingDeflate.decompressed++
+			return decompressOCFBlock(compressionDeflate, block)
+		},
+	})
+	RegisterLogicalType(LogicalType{
+		Type: "string",
+		Name: "test-upper",
+		ToNative: func(datum interface{}) (interface{}, error) {
+			return strings.ToLower(datum.(string)), nil
+		},
+		FromNative: func(datum interface{}) (interface{}, error) {
+			s, ok := datum.(string)
+			if !ok {
+				return nil, errors.New("expected string")
+			}
+			return strings.ToUpper(s), nil
+		},
+	})
+}
+
+func TestRegisteredCompression(t *testing.T) {
+	bb := new(bytes.Buffer)
+	ocfw, err := NewOCFWriter(OCFConfig{W: bb, Schema: `"string"`, CompressionName: "test-xor"})
+	ensureError(t, err)
+	if actual, expected := ocfw.CompressionName(), "test-xor"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	ensureError(t, ocfw.Append([]string{"hello", "world"}))
+
+	ocfr, err := NewOCFReader(bytes.NewReader(bb.Bytes()))
+	ensureError(t, err)
+	if actual, expected := ocfr.CompressionName(), "test-xor"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	var data []interface{}
+	for ocfr.Scan() {
+		datum, err := ocfr.Read()
+		ensureError(t, err)
+		data = append(data, datum)
+	}
+	ensureError(t, ocfr.Err())
+	if actual, expected := len(data), 2; actual != expected {
+		t.Fatalf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if actual, expected := data[1], "world"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
+
+func TestCompressionBackend(t *testing.T) {
+	countingDeflate.compressed, countingDeflate.decompressed = 0, 0
+
+	bb := new(bytes.Buffer)
+	ocfw, err := NewOCFWriter(OCFConfig{W: bb, Schema: `"long"`, CompressionName: CompressionDeflateLabel, CompressionBackend: "test-counting"})
+	ensureError(t, err)
+	if actual, expected := ocfw.CompressionName(), CompressionDeflateLabel; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	ensureError(t, ocfw.Append([]interface{}{13, 42}))
+	if actual, expected := countingDeflate.compressed, 1; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	// the default implementation reads blocks written by the backend
+	for _, config := range []OCFReaderConfig{{}, {CompressionBackends: map[string]string{CompressionDeflateLabel: "test-counting"}}} {
+		ocfr, err := NewOCFReaderWithConfig(bytes.NewReader(bb.Bytes()), config)
+		ensureError(t, err)
+		var count int
+		for ocfr.Scan() {
+			_, err := ocfr.Read()
+			ensureError(t, err)
+			count++
+		}
+		ensureError(t, ocfr.Err())
+		if actual, expected := count, 2; actual != expected {
+			t.Errorf("GOT: %v; WANT: %v", actual, expected)
+		}
+	}
+	if actual, expected := countingDeflate.decompressed, 1; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	_, err = NewOCFWriter(OCFConfig{W: new(bytes.Buffer), Schema: `"long"`, CompressionName: CompressionSnappyLabel, CompressionBackend: "test-counting"})
+	ensureError(t, err, "cannot create OCFWriter", `unrecognized compression backend for "snappy": "test-counting"`)
+	_, err = NewOCFReaderWithConfig(bytes.NewReader(bb.Bytes()), OCFReaderConfig{CompressionBackends: map[string]string{CompressionDeflateLabel: "missing"}})
+	ensureError(t, err, "cannot create OCFReader", "unrecognized compression backend")
+}
+
+func TestRegisteredLogicalType(t *testing.T) {
+	schema := `{"type":"string","logicalType":"test-upper"}`
+	testBinaryCodecPass(t, schema, "abc", []byte("\x06ABC"))
+	testTextCodecPass(t, schema, "abc", []byte(`"ABC"`))
+	testBinaryEncodeFail(t, schema, 13, "cannot encode string.test-upper: expected string")
+
+	// nested in a record
+	testBinaryCodecPass(t, `{"type":"record","name":"r","fields":[{"name":"f","type":{"type":"string","logicalType":"test-upper"}}]}`, map[string]interface{}{"f": "abc"}, []byte("\x06ABC"))
+}
+
+func TestRegisterPanics(t *testing.T) {
+	ensurePanic := func(t *testing.T, substring string, f func()) {
+		t.Helper()
+		defer func() {
+			r := recover()
+			if r == nil {
+				t.Fatalf("GOT: %v; WANT: %v", r, substring)
+			}
+			if !strings.Contains(r.(string), substring) {
+				t.Errorf("GOT: %v; WANT: %v", r, substring)
+			}
+		}()
+		f()
+	}
+	ensurePanic(t, "called twice", func() {
+		RegisterCompression(OCFCompression{Name: CompressionSnappyLabel, Compress: xorBlock, Decompress: xorBlock})
+	})
+	ensurePanic(t, "called twice", func() {
+		RegisterCompression(OCFCompression{Name: "test-xor", Compress: xorBlock, Decompress: xorBlock})
+	})
+	ensurePanic(t, "called twice", func() {
+		RegisterCompression(OCFCompression{Name: CompressionDeflateLabel, Backend: "test-counting", Compress: xorBlock, Decompress: xorBlock})
+	})
+	ensurePanic(t, "requires Name", func() {
+		RegisterCompression(OCFCompression{Name: "test-nil"})
+	})
+
+	identity := func(datum interface{}) (interface{}, error) { return datum, nil }
+	ensurePanic(t, "built in", func() {
+		RegisterLogicalType(LogicalType{Type: "long", Name: "timestamp-millis", ToNative: identity, FromNative: identity})
+	})
+	ensurePanic(t, "called twice", func() {
+		RegisterLogicalType(LogicalType{Type: "string", Name: "test-upper", ToNative: identity, FromNative: identity})
+	})
+	ensurePanic(t, "primitive type", func() {
+		RegisterLogicalType(LogicalType{Type: "record", Name: "test-record", ToNative: identity, FromNative: identity})
+	})
+
+	ensurePanic(t, "called twice", func() {
+		RegisterExpressionEngine(ExpressionEngine{Name: "test-field", Compile: testFieldEngine})
+	})
+	ensurePanic(t, "requires Name and Compile", func() {
+		RegisterExpressionEngine(ExpressionEngine{Name: "test-nil"})
+	})
+}
@@ -0,0 +1,631 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Resolution reads binary Avro data written using the schema of one Codec, the
+// writer, as data of the schema of another Codec, the reader, following the
+// schema resolution rules of the Avro specification:
+//
+//   * record fields are matched by name, or by the aliases of the reader
+//     field; writer fields the reader does not have are skipped, and reader
+//     fields the writer does not have are given their default values
+//   * named types match when their unqualified names are equal, or when the
+//     reader type has an alias for the writer type
+//   * int is promoted to long, float, or double; long to float or double;
+//     float to double; and string and bytes to one another
+//   * enum symbols are matched by name, and writer symbols the reader does not
+//     have become the default symbol of the reader enum
+//   * writer union values are resolved using the selected member, and values
+//     resolved to a reader union use the first member that matches
+//
+// The rules are compiled once, when the Resolution is created, into a plan
+// that translates the writer's binary encoding to the reader's binary
+// encoding, which is then decoded by the reader Codec, using its options. A
+// Resolution is safe for concurrent use.
+//
+//     resolution, err := goavro.NewResolution(writerCodec, readerCodec)
+//     if err != nil {
+//         return err
+//     }
+//     datum, _, err := resolution.NativeFromBinary(buf)
+type Resolution struct {
+	writer, reader *Codec
+	plan           *resolutionPlan
+	derivation     *derivation // nil unless created with derived fields
+}
+
+// resolutionPlan is the compiled translation from writer to reader binary
+// data, shared by the Resolutions of codecs with the same schemas.
+type resolutionPlan struct {
+	writerSchema, readerSchema string
+	resolve                    resolver
+	report                     *ResolutionReport
+}
+
+// resolver appends the reader encoding of the writer encoded datum at the
+// start of buf to dst, and returns the new dst, and the remaining bytes of
+// buf.
+type resolver func(dst, buf []byte) ([]byte, []byte, error)
+
+// NewResolution returns a Resolution that reads data written using the schema
+// of writer as data of the schema of reader. It returns an error when the
+// schemas cannot be resolved, such as when a reader record field without a
+// default value is missing from the writer record.
+func NewResolution(writer, reader *Codec) (*Resolution, error) {
+	plan, err := newResolutionPlan(writer, reader, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Resolution{writer: writer, reader: reader, plan: plan}, nil
+}
+
+func newResolutionPlan(writer, reader *Codec, derived map[string]DeriveFunc) (*resolutionPlan, error) {
+	w, err := schemaNodeFromCodec(writer)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve writer schema: %s", err)
+	}
+	r, err := schemaNodeFromCodec(reader)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve reader schema: %s", err)
+	}
+	rc := &resolutionCompiler{compiled: make(map[[2]*schemaNode]*resolver), derived: derived}
+	resolve, err := rc.compile(w, r)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve writer schema with reader schema: %s", err)
+	}
+	report := &ResolutionReport{Writer: w.label(), Reader: r.label(), Fields: []ResolutionFieldReport{}}
+	rc.report(report, w, r, "", make(map[[2]*schemaNode]struct{}))
+	return &resolutionPlan{writerSchema: writer.schemaOriginal, readerSchema: reader.schemaOriginal, resolve: resolve, report: report}, nil
+}
+
+// Writer returns the Codec of the schema the data was written with.
+func (r *Resolution) Writer() *Codec { return r.writer }
+
+// Reader returns the Codec of the schema the data is read as.
+func (r *Resolution) Reader() *Codec { return r.reader }
+
+// BinaryFromBinary translates the datum at the start of buf, encoded using the
+// writer schema, to the binary encoding of the reader schema, which it appends
+// to dst. On success, it returns the new dst slice, the remaining bytes of
+// buf, and a nil error value. On error, it returns the original dst and buf
+// slices, and the error message.
+func (r *Resolution) BinaryFromBinary(dst, buf []byte) ([]byte, []byte, error) {
+	if r.derivation != nil {
+		datum, rest, err := r.NativeFromBinary(buf)
+		if err != nil {
+			return dst, buf, err
+		}
+		newDst, err := r.reader.BinaryFromNative(dst, datum)
+		if err != nil {
+			return dst, buf, fmt.Errorf("cannot resolve binary: %s", err)
+		}
+		return newDst, rest, nil
+	}
+	newDst, rest, err := r.plan.resolve(dst, buf)
+	if err != nil {
+		return dst, buf, fmt.Errorf("cannot resolve binary: %s", err)
+	}
+	return newDst, rest, nil
+}
+
+// NativeFromBinary decodes the datum at the start of buf, encoded using the
+// writer schema, to the native form of the reader Codec. On success, it
+// returns the decoded datum, the remaining bytes of buf, and a nil error
+// value. On error, it returns nil for the datum, the original buf slice, and
+// the error message.
+func (r *Resolution) NativeFromBinary(buf []byte) (interface{}, []byte, error) {
+	translated, rest, err := r.plan.resolve(nil, buf)
+	if err != nil {
+		return nil, buf, fmt.Errorf("cannot resolve binary: %s", err)
+	}
+	datum, _, err := r.reader.nativeFromBinaryPrefix(translated)
+	if err != nil {
+		return nil, buf, err
+	}
+	if r.derivation != nil {
+		if datum, err = r.derivation.derive(r.derivation.root, datum); err != nil {
+			return nil, buf, err
+		}
+	}
+	return datum, rest, nil
+}
+
+type resolutionCompiler struct {
+	// compiled holds the resolver of each pair of writer and reader nodes
+	// already compiled, or being compiled, so recursive types terminate.
+	compiled map[[2]*schemaNode]*resolver
+
+	// derived holds the functions computing derived fields, keyed by the
+	// full name of the reader record and the name of the field.
+	derived map[string]DeriveFunc
+}
+
+func (rc *resolutionCompiler) compile(w, r *schemaNode) (resolver, error) {
+	key := [2]*schemaNode{w, r}
+	if p, ok := rc.compiled[key]; ok {
+		// NOTE: The resolver may still be compiling, so defer reading it.
+		return func(dst, buf []byte) ([]byte, []byte, error) { return (*p)(dst, buf) }, nil
+	}
+	p := new(resolver)
+	rc.compiled[key] = p
+	resolve, err := rc.compileNode(w, r)
+	if err != nil {
+		delete(rc.compiled, key)
+		return nil, err
+	}
+	*p = resolve
+	return resolve, nil
+}
+
+func (rc *resolutionCompiler) compileNode(w, r *schemaNode) (resolver, error) {
+	if w.typeName == "union" {
+		return rc.compileWriterUnion(w, r)
+	}
+	if r.typeName == "union" {
+		return rc.compileReaderUnion(w, r)
+	}
+	if !resolutionMatches(w, r, true) {
+		return nil, fmt.Errorf("writer %s does not match reader %s", w.label(), r.label())
+	}
+	switch r.typeName {
+	case "null", "boolean", "int", "long", "float", "double", "bytes", "string", "fixed":
+		if w.typeName == r.typeName {
+			return copyResolver(w), nil
+		}
+		return promoteResolver(w, r), nil
+	case "enum":
+		return compileEnumResolver(w, r)
+	case "array":
+		items, err := rc.compile(w.items, r.items)
+		if err != nil {
+			return nil, fmt.Errorf("array items: %s", err)
+		}
+		return blocksResolver("array", func(dst, buf []byte) ([]byte, []byte, error) {
+			return items(dst, buf)
+		}), nil
+	case "map":
+		values, err := rc.compile(w.values, r.values)
+		if err != nil {
+			return nil, fmt.Errorf("map values: %s", err)
+		}
+		copyKey := copyResolver(&schemaNode{typeName: "string"})
+		return blocksResolver("map", func(dst, buf []byte) ([]byte, []byte, error) {
+			dst, buf, err := copyKey(dst, buf)
+			if err != nil {
+				return nil, nil, fmt.Errorf("key: %s", err)
+			}
+			return values(dst, buf)
+		}), nil
+	case "record":
+		return rc.compileRecord(w, r)
+	default:
+		return nil, fmt.Errorf("unknown type: %q", r.typeName)
+	}
+}
+
+// compileWriterUnion resolves each member of the writer union with the reader
+// schema. Members that do not resolve only cause an error when data using them
+// is read, as specified by Avro, unless no member resolves.
+func (rc *resolutionCompiler) compileWriterUnion(w, r *schemaNode) (resolver, error) {
+	members := make([]resolver, len(w.members))
+	var resolved bool
+	var firstErr error
+	for i, member := range w.members {
+		resolve, err := rc.compile(member, r)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			message := fmt.Sprintf("writer union member %d: %s", i, err)
+			members[i] = func(_, _ []byte) ([]byte, []byte, error) {
+				return nil, nil, fmt.Errorf("%s", message)
+			}
+			continue
+		}
+		members[i], resolved = resolve, true
+	}
+	if !resolved {
+		return nil, fmt.Errorf("no member of writer %s matches reader %s: %s", w.label(), r.label(), firstErr)
+	}
+	return func(dst, buf []byte) ([]byte, []byte, error) {
+		value, rest, err := longNativeFromBinary(buf)
+		if err != nil {
+			return nil, nil, fmt.Errorf("union index: %s", err)
+		}
+		index := value.(int64)
+		if index < 0 || index >= int64(len(members)) {
+			return nil, nil, fmt.Errorf("union index ought to be between 0 and %d; read index: %d", len(members)-1, index)
+		}
+		return members[index](dst, rest)
+	}, nil
+}
+
+// compileReaderUnion resolves the writer schema with the member of the reader
+// union selected by readerUnionMember.
+func (rc *resolutionCompiler) compileReaderUnion(w, r *schemaNode) (resolver, error) {
+	i := readerUnionMember(w, r)
+	if i < 0 {
+		return nil, fmt.Errorf("writer %s does not match any member of reader %s", w.label(), r.label())
+	}
+	resolve, err := rc.compile(w, r.members[i])
+	if err != nil {
+		return nil, fmt.Errorf("reader union member %d: %s", i, err)
+	}
+	index, _ := longBinaryFromNative(nil, i)
+	return func(dst, buf []byte) ([]byte, []byte, error) {
+		return resolve(append(dst, index...), buf)
+	}, nil
+}
+
+// readerUnionMember returns the index of the first member of the reader union
+// the writer schema matches, preferring members of the same type to those to
+// which the writer type is promoted, or -1.
+func readerUnionMember(w, r *schemaNode) int {
+	for _, promote := range []bool{false, true} {
+		for i, member := range r.members {
+			if member.typeName != "union" && resolutionMatches(w, member, promote) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func (rc *resolutionCompiler) compileRecord(w, r *schemaNode) (resolver, error) {
+	// targets holds the index of the reader field of each writer field, or -1
+	// when the reader does not have the field.
+	targets := make([]int, len(w.fields))
+	fields := make([]resolver, len(w.fields))
+	found := make([]bool, len(r.fields))
+	inOrder := true
+	previous := -1
+	for i, wf := range w.fields {
+		targets[i] = -1
+		j := readerFieldIndex(r, wf.name)
+		if j < 0 {
+			fields[i] = skipResolver(wf.node)
+			continue
+		}
+		resolve, err := rc.compile(wf.node, r.fields[j].node)
+		if err != nil {
+			return nil, fmt.Errorf("record %q field %q: %s", r.fullName, r.fields[j].name, err)
+		}
+		targets[i], fields[i], found[j] = j, resolve, true
+		if j < previous {
+			inOrder = false
+		}
+		previous = j
+	}
+
+	defaults := make([][]byte, len(r.fields))
+	for j, rf := range r.fields {
+		if found[j] {
+			continue
+		}
+		var value []byte
+		var err error
+		if rf.hasDefault {
+			if value, err = binaryFromDefault(rf.node, nil, rf.defaultValue); err != nil {
+				return nil, fmt.Errorf("record %q field %q: cannot encode default value: %s", r.fullName, rf.name, err)
+			}
+		} else if _, ok := rc.derived[r.fullName+"."+rf.name]; ok {
+			// NOTE: The value is replaced after decoding, so only needs to
+			// decode.
+			if value, err = binaryZeroValue(rf.node, nil, make(map[*schemaNode]struct{})); err != nil {
+				return nil, fmt.Errorf("record %q field %q: %s", r.fullName, rf.name, err)
+			}
+		} else {
+			return nil, fmt.Errorf("record %q field %q: writer does not have the field, and reader does not specify a default value", r.fullName, rf.name)
+		}
+		defaults[j] = value
+		inOrder = false
+	}
+
+	if inOrder {
+		// NOTE: The reader fields are written in the order the writer
+		// fields are read, so no buffering is required.
+		return func(dst, buf []byte) ([]byte, []byte, error) {
+			var err error
+			for i, resolve := range fields {
+				if dst, buf, err = resolve(dst, buf); err != nil {
+					return nil, nil, fmt.Errorf("record %q field %q: %s", r.fullName, w.fields[i].name, err)
+				}
+			}
+			return dst, buf, nil
+		}, nil
+	}
+
+	return func(dst, buf []byte) ([]byte, []byte, error) {
+		values := make([][]byte, len(r.fields))
+		copy(values, defaults)
+		var err error
+		for i, resolve := range fields {
+			if targets[i] < 0 {
+				if _, buf, err = resolve(nil, buf); err != nil {
+					return nil, nil, fmt.Errorf("record %q field %q: %s", r.fullName, w.fields[i].name, err)
+				}
+				continue
+			}
+			if values[targets[i]], buf, err = resolve(nil, buf); err != nil {
+				return nil, nil, fmt.Errorf("record %q field %q: %s", r.fullName, w.fields[i].name, err)
+			}
+		}
+		for _, value := range values {
+			dst = append(dst, value...)
+		}
+		return dst, buf, nil
+	}, nil
+}
+
+// readerFieldIndex returns the index of the reader record field whose name, or
+// one of whose aliases, is the writer field name, or -1.
+func readerFieldIndex(r *schemaNode, name string) int {
+	for j, rf := range r.fields {
+		if rf.name == name {
+			return j
+		}
+	}
+	for j, rf := range r.fields {
+		for _, alias := range rf.aliases {
+			if alias == name {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+func compileEnumResolver(w, r *schemaNode) (resolver, error) {
+	defaultIndex := -1
+	for j, symbol := range r.symbols {
+		if symbol == r.enumDefault {
+			defaultIndex = j
+		}
+	}
+	indexes := make([]int, len(w.symbols))
+	for i, symbol := range w.symbols {
+		indexes[i] = defaultIndex
+		for j, readerSymbol := range r.symbols {
+			if symbol == readerSymbol {
+				indexes[i] = j
+				break
+			}
+		}
+	}
+	return func(dst, buf []byte) ([]byte, []byte, error) {
+		value, rest, err := longNativeFromBinary(buf)
+		if err != nil {
+			return nil, nil, fmt.Errorf("enum %q index: %s", w.fullName, err)
+		}
+		index := value.(int64)
+		if index < 0 || index >= int64(len(indexes)) {
+			return nil, nil, fmt.Errorf("enum %q index ought to be between 0 and %d; read index: %d", w.fullName, len(indexes)-1, index)
+		}
+		if indexes[index] < 0 {
+			return nil, nil, fmt.Errorf("enum %q symbol %q is not a symbol of reader enum %q, which has no default", w.fullName, w.symbols[index], r.fullName)
+		}
+		dst, _ = longBinaryFromNative(dst, indexes[index])
+		return dst, rest, nil
+	}, nil
+}
+
+// copyResolver copies the encoded datum unchanged.
+func copyResolver(w *schemaNode) resolver {
+	return func(dst, buf []byte) ([]byte, []byte, error) {
+		rest, err := skipBinary(w, buf)
+		if err != nil {
+			return nil, nil, err
+		}
+		return append(dst, buf[:len(buf)-len(rest)]...), rest, nil
+	}
+}
+
+// skipResolver skips the encoded datum, appending nothing.
+func skipResolver(w *schemaNode) resolver {
+	return func(dst, buf []byte) ([]byte, []byte, error) {
+		rest, err := skipBinary(w, buf)
+		if err != nil {
+			return nil, nil, err
+		}
+		return dst, rest, nil
+	}
+}
+
+// promoteResolver decodes a datum of the writer type, and encodes it using the
+// reader type to which it is promoted.
+func promoteResolver(w, r *schemaNode) resolver {
+	return func(dst, buf []byte) ([]byte, []byte, error) {
+		value, rest, err := plainFromBinary(w, buf)
+		if err != nil {
+			return nil, nil, err
+		}
+		if dst, err = binaryFromPlain(r, dst, value); err != nil {
+			return nil, nil, fmt.Errorf("cannot promote %s to %s: %s", w.typeName, r.typeName, err)
+		}
+		return dst, rest, nil
+	}
+}
+
+// blocksResolver resolves the blocks of an array or map, writing each block
+// with its item count, but without its size, which may change.
+func blocksResolver(kind string, resolveItem resolver) resolver {
+	return func(dst, buf []byte) ([]byte, []byte, error) {
+		for {
+			value, rest, err := longNativeFromBinary(buf)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s block count: %s", kind, err)
+			}
+			buf = rest
+			blockCount := value.(int64)
+			if blockCount == 0 {
+				return append(dst, 0), buf, nil
+			}
+			if blockCount < 0 {
+				if blockCount == math.MinInt64 {
+					return nil, nil, fmt.Errorf("%s with block count: %d", kind, blockCount)
+				}
+				blockCount = -blockCount
+				if _, buf, err = longNativeFromBinary(buf); err != nil {
+					return nil, nil, fmt.Errorf("%s block size: %s", kind, err)
+				}
+			}
+			if blockCount > MaxBlockCount {
+				return nil, nil, fmt.Errorf("%s when block count exceeds MaxBlockCount: %d > %d", kind, blockCount, MaxBlockCount)
+			}
+			dst, _ = longBinaryFromNative(dst, blockCount)
+			for i := int64(0); i < blockCount; i++ {
+				if dst, buf, err = resolveItem(dst, buf); err != nil {
+					return nil, nil, fmt.Errorf("%s item %d: %s", kind, i+1, err)
+				}
+			}
+		}
+	}
+}
+
+// resolutionMatches returns true when data of the writer node may be read as
+// data of the reader node, neither of which is a union. When promote is false,
+// the writer type is not promoted to match the reader type.
+func resolutionMatches(w, r *schemaNode, promote bool) bool {
+	if w.typeName == r.typeName {
+		switch r.typeName {
+		case "record", "enum":
+			return resolutionNamesMatch(w, r)
+		case "fixed":
+			return resolutionNamesMatch(w, r) && w.size == r.size
+		}
+		return true
+	}
+	if !promote {
+		return false
+	}
+	switch w.typeName {
+	case "int":
+		return r.typeName == "long" || r.typeName == "float" || r.typeName == "double"
+	case "long":
+		return r.typeName == "float" || r.typeName == "double"
+	case "float":
+		return r.typeName == "double"
+	case "string":
+		return r.typeName == "bytes"
+	case "bytes":
+		return r.typeName == "string"
+	}
+	return false
+}
+
+// resolutionNamesMatch returns true when the named writer and reader nodes have
+// the same unqualified name, or when the reader node has an alias for the full
+// name of the writer node.
+func resolutionNamesMatch(w, r *schemaNode) bool {
+	if unqualifiedName(w.fullName) == unqualifiedName(r.fullName) {
+		return true
+	}
+	for _, alias := range r.aliases {
+		if !strings.Contains(alias, ".") && r.namespace != "" {
+			alias = r.namespace + "." + alias
+		}
+		if alias == w.fullName {
+			return true
+		}
+	}
+	return false
+}
+
+func unqualifiedName(fullName string) string {
+	return fullName[strings.LastIndexByte(fullName, '.')+1:]
+}
+
+// binaryFromDefault appends the binary encoding of the default value of a
+// record field to buf. Default values of unions use the first member of the
+// union, and default values of bytes and fixed types are strings whose code
+// points are the byte values.
+func binaryFromDefault(n *schemaNode, buf []byte, value interface{}) ([]byte, error) {
+	var err error
+	switch n.typeName {
+	case "union":
+		if len(n.members) == 0 {
+			return nil, fmt.Errorf("union has no members")
+		}
+		buf, _ = longBinaryFromNative(buf, 0)
+		return binaryFromDefault(n.members[0], buf, value)
+	case "bytes", "fixed":
+		if s, ok := value.(string); ok {
+			runes := []rune(s)
+			someBytes := make([]byte, len(runes))
+			for i, r := range runes {
+				if r > math.MaxUint8 {
+					return nil, fmt.Errorf("%s default value ought to have code points less than 256: %q", n.typeName, s)
+				}
+				someBytes[i] = byte(r)
+			}
+			value = someBytes
+		}
+	case "record":
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("record %q default value ought to be an object; received: %T", n.fullName, value)
+		}
+		for _, f := range n.fields {
+			fieldValue, ok := fields[f.name]
+			if !ok {
+				if !f.hasDefault {
+					return nil, fmt.Errorf("record %q default value ought to have field %q", n.fullName, f.name)
+				}
+				fieldValue = f.defaultValue
+			}
+			if buf, err = binaryFromDefault(f.node, buf, fieldValue); err != nil {
+				return nil, fmt.Errorf("record %q field %q: %s", n.fullName, f.name, err)
+			}
+		}
+		return buf, nil
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("array default value ought to be an array; received: %T", value)
+		}
+		if len(items) > 0 {
+			buf, _ = longBinaryFromNative(buf, len(items))
+		}
+		for i, item := range items {
+			if buf, err = binaryFromDefault(n.items, buf, item); err != nil {
+				return nil, fmt.Errorf("array item %d: %s", i+1, err)
+			}
+		}
+		return longBinaryFromNative(buf, 0)
+	case "map":
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("map default value ought to be an object; received: %T", value)
+		}
+		keys := make([]string, 0, len(values))
+		for k := range values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		if len(keys) > 0 {
+			buf, _ = longBinaryFromNative(buf, len(keys))
+		}
+		for _, k := range keys {
+			buf, _ = stringBinaryFromNative(buf, k)
+			if buf, err = binaryFromDefault(n.values, buf, values[k]); err != nil {
+				return nil, fmt.Errorf("map value for key %q: %s", k, err)
+			}
+		}
+		return longBinaryFromNative(buf, 0)
+	}
+	return binaryFromPlain(n, buf, value)
+}
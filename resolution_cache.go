@@ -0,0 +1,138 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"container/list"
+	"sync"
+)
+
+// ResolutionCache holds the compiled resolution plans of pairs of writer and
+// reader schemas, keyed by the Rabin fingerprints of their canonical forms, so
+// consumers of data written with many schemas resolve each pair once, rather
+// than once per datum. When the cache is full, the least recently used plan is
+// evicted. A ResolutionCache is safe for concurrent use.
+//
+//     cache := goavro.NewResolutionCache(128)
+//     for _, message := range messages {
+//         writer, err := schemas.Codec(message.SchemaID)
+//         if err != nil {
+//             return err
+//         }
+//         resolution, err := cache.Resolution(writer, reader)
+//         if err != nil {
+//             return err
+//         }
+//         datum, _, err := resolution.NativeFromBinary(message.Value)
+//         // ...
+//     }
+type ResolutionCache struct {
+	mu        sync.Mutex
+	capacity  int
+	entries   map[resolutionCacheKey]*list.Element
+	lru       *list.List // of *resolutionCacheEntry, most recently used first
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+type resolutionCacheKey struct {
+	writer, reader uint64
+}
+
+type resolutionCacheEntry struct {
+	key        resolutionCacheKey
+	resolution *Resolution
+}
+
+// ResolutionCacheStats reports the use of a ResolutionCache.
+type ResolutionCacheStats struct {
+	Hits      uint64 // requests answered by a cached plan
+	Misses    uint64 // requests that compiled a plan
+	Evictions uint64 // plans evicted to respect the capacity
+	Len       int    // number of cached plans
+	Capacity  int    // maximum number of cached plans, or 0 when unbounded
+}
+
+// NewResolutionCache returns a ResolutionCache holding at most capacity
+// resolution plans. When capacity is 0 or less, the cache is unbounded.
+func NewResolutionCache(capacity int) *ResolutionCache {
+	if capacity < 0 {
+		capacity = 0
+	}
+	return &ResolutionCache{
+		capacity: capacity,
+		entries:  make(map[resolutionCacheKey]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// Resolution returns a Resolution that reads data written using the schema of
+// writer as data of the schema of reader, compiling its plan when the cache
+// does not hold the plan for the pair of schemas. Errors are not cached.
+func (rc *ResolutionCache) Resolution(writer, reader *Codec) (*Resolution, error) {
+	key := resolutionCacheKey{writer: writer.Rabin, reader: reader.Rabin}
+
+	rc.mu.Lock()
+	if element, ok := rc.entries[key]; ok {
+		entry := element.Value.(*resolutionCacheEntry)
+		plan := entry.resolution.plan
+		// NOTE: Canonical forms omit defaults and aliases, which change the
+		// plan, so schemas with the same fingerprints may still differ.
+		if plan.writerSchema == writer.schemaOriginal && plan.readerSchema == reader.schemaOriginal {
+			rc.hits++
+			rc.lru.MoveToFront(element)
+			rc.mu.Unlock()
+			if entry.resolution.writer == writer && entry.resolution.reader == reader {
+				return entry.resolution, nil
+			}
+			return &Resolution{writer: writer, reader: reader, plan: plan}, nil
+		}
+	}
+	rc.misses++
+	rc.mu.Unlock()
+
+	// NOTE: Compile without holding the lock, so other pairs are not blocked.
+	// Goroutines that miss on the same pair simultaneously each compile it.
+	resolution, err := NewResolution(writer, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if element, ok := rc.entries[key]; ok {
+		element.Value.(*resolutionCacheEntry).resolution = resolution
+		rc.lru.MoveToFront(element)
+		return resolution, nil
+	}
+	rc.entries[key] = rc.lru.PushFront(&resolutionCacheEntry{key: key, resolution: resolution})
+	if rc.capacity > 0 && rc.lru.Len() > rc.capacity {
+		oldest := rc.lru.Back()
+		rc.lru.Remove(oldest)
+		delete(rc.entries, oldest.Value.(*resolutionCacheEntry).key)
+		rc.evictions++
+	}
+	return resolution, nil
+}
+
+// Stats returns the number of hits, misses, and evictions since the cache was
+// created, along with its current size and capacity.
+func (rc *ResolutionCache) Stats() ResolutionCacheStats {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return ResolutionCacheStats{
+		Hits:      rc.hits,
+		Misses:    rc.misses,
+		Evictions: rc.evictions,
+		Len:       rc.lru.Len(),
+		Capacity:  rc.capacity,
+	}
+}
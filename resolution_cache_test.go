@@ -0,0 +1,117 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestResolutionCache(t *testing.T) {
+	reader := newCodecUsingV2(t, `"long"`)
+	intWriter := newCodecUsingV2(t, `"int"`)
+	longWriter := newCodecUsingV2(t, `"long"`)
+	floatWriter := newCodecUsingV2(t, `"float"`)
+
+	cache := NewResolutionCache(2)
+	first, err := cache.Resolution(intWriter, reader)
+	ensureError(t, err)
+	second, err := cache.Resolution(intWriter, reader)
+	ensureError(t, err)
+	if first != second {
+		t.Errorf("GOT: %p; WANT: %p", second, first)
+	}
+
+	// codecs of the same schemas share the cached plan
+	other, err := cache.Resolution(newCodecUsingV2(t, `"int"`), reader)
+	ensureError(t, err)
+	if other == first || other.plan != first.plan {
+		t.Errorf("GOT: %p; WANT: plan %p", other.plan, first.plan)
+	}
+
+	_, err = cache.Resolution(longWriter, reader)
+	ensureError(t, err)
+	_, err = cache.Resolution(floatWriter, reader)
+	ensureError(t, err, "writer float does not match reader long")
+
+	if actual, expected := cache.Stats(), (ResolutionCacheStats{Hits: 2, Misses: 3, Len: 2, Capacity: 2}); actual != expected {
+		t.Errorf("GOT: %+v; WANT: %+v", actual, expected)
+	}
+
+	// adding a third pair evicts the least recently used pair, int to long
+	_, err = cache.Resolution(longWriter, newCodecUsingV2(t, `"double"`))
+	ensureError(t, err)
+	_, err = cache.Resolution(intWriter, reader)
+	ensureError(t, err)
+	if actual, expected := cache.Stats(), (ResolutionCacheStats{Hits: 2, Misses: 5, Evictions: 2, Len: 2, Capacity: 2}); actual != expected {
+		t.Errorf("GOT: %+v; WANT: %+v", actual, expected)
+	}
+}
+
+func TestResolutionCacheSameFingerprint(t *testing.T) {
+	// canonical forms omit defaults, so these readers have the same fingerprint
+	writer := newCodecUsingV2(t, `{"type":"record","name":"r","fields":[]}`)
+	readerA := newCodecUsingV2(t, `{"type":"record","name":"r","fields":[{"name":"a","type":"int","default":1}]}`)
+	readerB := newCodecUsingV2(t, `{"type":"record","name":"r","fields":[{"name":"a","type":"int","default":2}]}`)
+	if readerA.Rabin != readerB.Rabin {
+		t.Fatalf("GOT: %x; WANT: %x", readerB.Rabin, readerA.Rabin)
+	}
+
+	cache := NewResolutionCache(0)
+	for _, c := range []struct {
+		reader   *Codec
+		expected int32
+	}{{readerA, 1}, {readerB, 2}, {readerA, 1}} {
+		resolution, err := cache.Resolution(writer, c.reader)
+		ensureError(t, err)
+		datum, _, err := resolution.NativeFromBinary(nil)
+		ensureError(t, err)
+		if actual := datum.(map[string]interface{})["a"]; actual != c.expected {
+			t.Errorf("GOT: %v; WANT: %v", actual, c.expected)
+		}
+	}
+	if actual, expected := cache.Stats(), (ResolutionCacheStats{Misses: 3, Len: 1}); actual != expected {
+		t.Errorf("GOT: %+v; WANT: %+v", actual, expected)
+	}
+}
+
+func TestResolutionCacheConcurrently(t *testing.T) {
+	reader := newCodecUsingV2(t, `"double"`)
+	writers := []*Codec{newCodecUsingV2(t, `"int"`), newCodecUsingV2(t, `"long"`), newCodecUsingV2(t, `"float"`)}
+	cache := NewResolutionCache(2)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				writer := writers[(i+j)%len(writers)]
+				resolution, err := cache.Resolution(writer, reader)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				buf, _ := writer.BinaryFromNative(nil, 2)
+				datum, _, err := resolution.NativeFromBinary(buf)
+				if err != nil || datum != float64(2) {
+					t.Errorf("GOT: %v, %v; WANT: %v", datum, err, float64(2))
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	stats := cache.Stats()
+	if stats.Hits+stats.Misses != 800 || stats.Len != 2 {
+		t.Errorf("GOT: %+v; WANT: 800 requests and 2 cached plans", stats)
+	}
+}
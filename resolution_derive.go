@@ -0,0 +1,263 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DeriveFunc computes the value of a derived record field from the native form
+// of the record, whose other fields have been read. The returned value ought to
+// be the native form of the field's type in the reader schema.
+type DeriveFunc func(record map[string]interface{}) (interface{}, error)
+
+// NewResolutionWithDerivedFields returns a Resolution like NewResolution, that
+// also computes the derived fields of the reader schema while decoding, so
+// records conforming to a reader schema extended with computed fields are
+// produced without a second pass over the data. Derived fields are keyed by
+// the full name of the reader record, a period, and the name of the field. A
+// derived field need not have a default value, and when the writer also has
+// the field, the computed value replaces the value that was read. Derived
+// fields of a record are computed in the order of the reader record, after the
+// derived fields of the records it contains.
+//
+//     resolution, err := goavro.NewResolutionWithDerivedFields(writer, reader, map[string]goavro.DeriveFunc{
+//         "com.example.Event.event_date": func(record map[string]interface{}) (interface{}, error) {
+//             timestamp := record["timestamp"].(time.Time)
+//             return timestamp.UTC().Truncate(24 * time.Hour), nil
+//         },
+//     })
+//
+// Because derived fields are computed from native data, the BinaryFromBinary
+// method of the returned Resolution decodes and encodes each datum.
+func NewResolutionWithDerivedFields(writer, reader *Codec, derived map[string]DeriveFunc) (*Resolution, error) {
+	r, err := schemaNodeFromCodec(reader)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve reader schema: %s", err)
+	}
+	d, err := newDerivation(r, derived)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve writer schema with reader schema: %s", err)
+	}
+	plan, err := newResolutionPlan(writer, reader, derived)
+	if err != nil {
+		return nil, err
+	}
+	return &Resolution{writer: writer, reader: reader, plan: plan, derivation: d}, nil
+}
+
+// derivation computes the derived fields of decoded reader data.
+type derivation struct {
+	root    *schemaNode
+	fields  map[*schemaNode][]derivedField // derived fields of each record node
+	reaches map[*schemaNode]bool           // nodes whose data may contain records with derived fields
+}
+
+type derivedField struct {
+	name   string
+	derive DeriveFunc
+}
+
+func newDerivation(root *schemaNode, derived map[string]DeriveFunc) (*derivation, error) {
+	d := &derivation{root: root, fields: make(map[*schemaNode][]derivedField), reaches: make(map[*schemaNode]bool)}
+
+	var nodes []*schemaNode
+	visited := make(map[*schemaNode]struct{})
+	var visit func(n *schemaNode)
+	visit = func(n *schemaNode) {
+		if _, ok := visited[n]; ok {
+			return
+		}
+		visited[n] = struct{}{}
+		nodes = append(nodes, n)
+		for _, child := range schemaNodeChildren(n) {
+			visit(child)
+		}
+	}
+	visit(root)
+
+	used := make(map[string]struct{}, len(derived))
+	for _, n := range nodes {
+		if n.typeName != "record" {
+			continue
+		}
+		for _, f := range n.fields {
+			key := n.fullName + "." + f.name
+			if derive, ok := derived[key]; ok {
+				if derive == nil {
+					return nil, fmt.Errorf("derived field %q ought to have a function", key)
+				}
+				d.fields[n] = append(d.fields[n], derivedField{name: f.name, derive: derive})
+				d.reaches[n] = true
+				used[key] = struct{}{}
+			}
+		}
+	}
+	if len(used) < len(derived) {
+		var unused []string
+		for key := range derived {
+			if _, ok := used[key]; !ok {
+				unused = append(unused, key)
+			}
+		}
+		sort.Strings(unused)
+		return nil, fmt.Errorf("derived field ought to name a field of a reader record: %q", unused)
+	}
+
+	// NOTE: Recursive schemas have cycles, so propagate until nothing changes.
+	for changed := true; changed; {
+		changed = false
+		for _, n := range nodes {
+			if d.reaches[n] {
+				continue
+			}
+			for _, child := range schemaNodeChildren(n) {
+				if d.reaches[child] {
+					d.reaches[n], changed = true, true
+					break
+				}
+			}
+		}
+	}
+	return d, nil
+}
+
+// schemaNodeChildren returns the nodes directly contained by the node.
+func schemaNodeChildren(n *schemaNode) []*schemaNode {
+	switch n.typeName {
+	case "array":
+		return []*schemaNode{n.items}
+	case "map":
+		return []*schemaNode{n.values}
+	case "union":
+		return n.members
+	case "record":
+		children := make([]*schemaNode, len(n.fields))
+		for i, f := range n.fields {
+			children[i] = f.node
+		}
+		return children
+	}
+	return nil
+}
+
+// derive computes the derived fields of the records of the native datum,
+// descending only into data that may contain them.
+func (d *derivation) derive(n *schemaNode, datum interface{}) (interface{}, error) {
+	if !d.reaches[n] || datum == nil {
+		return datum, nil
+	}
+	var err error
+	switch n.typeName {
+	case "record":
+		record, ok := datum.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("cannot derive fields of record %q: expected map[string]interface{}; received: %T", n.fullName, datum)
+		}
+		for _, f := range n.fields {
+			if d.reaches[f.node] {
+				if record[f.name], err = d.derive(f.node, record[f.name]); err != nil {
+					return nil, err
+				}
+			}
+		}
+		for _, f := range d.fields[n] {
+			if record[f.name], err = f.derive(record); err != nil {
+				return nil, fmt.Errorf("cannot derive field %q: %s", n.fullName+"."+f.name, err)
+			}
+		}
+	case "array":
+		items, _ := datum.([]interface{})
+		for i, item := range items {
+			if items[i], err = d.derive(n.items, item); err != nil {
+				return nil, err
+			}
+		}
+	case "map":
+		switch values := datum.(type) {
+		case map[string]interface{}:
+			for k, v := range values {
+				if values[k], err = d.derive(n.values, v); err != nil {
+					return nil, err
+				}
+			}
+		case OrderedMap:
+			for i, item := range values {
+				if values[i].Value, err = d.derive(n.values, item.Value); err != nil {
+					return nil, err
+				}
+			}
+		}
+	case "union":
+		wrapped, ok := datum.(map[string]interface{})
+		if !ok {
+			return datum, nil
+		}
+		for _, member := range n.members {
+			name := unionMemberName(member)
+			if v, ok := wrapped[name]; ok {
+				if wrapped[name], err = d.derive(member, v); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return datum, nil
+}
+
+// unionMemberName returns the name a Codec uses for the union member in the
+// native form of union values.
+func unionMemberName(n *schemaNode) string {
+	if n.isNamed() {
+		return n.fullName
+	}
+	if n.logicalType != "" {
+		return n.typeName + "." + n.logicalType
+	}
+	return n.typeName
+}
+
+// binaryZeroValue appends the binary encoding of the zero value of the node to
+// buf: false, zero, empty, the first symbol of an enum, or the zero value of
+// the first member of a union.
+func binaryZeroValue(n *schemaNode, buf []byte, records map[*schemaNode]struct{}) ([]byte, error) {
+	var err error
+	switch n.typeName {
+	case "null":
+		return buf, nil
+	case "boolean", "int", "long", "bytes", "string", "enum", "array", "map":
+		return append(buf, 0), nil
+	case "float":
+		return append(buf, 0, 0, 0, 0), nil
+	case "double":
+		return append(buf, 0, 0, 0, 0, 0, 0, 0, 0), nil
+	case "fixed":
+		return append(buf, make([]byte, n.size)...), nil
+	case "union":
+		if len(n.members) == 0 {
+			return nil, fmt.Errorf("union has no members")
+		}
+		return binaryZeroValue(n.members[0], append(buf, 0), records)
+	case "record":
+		if _, ok := records[n]; ok {
+			return nil, fmt.Errorf("record %q has no finite zero value", n.fullName)
+		}
+		records[n] = struct{}{}
+		for _, f := range n.fields {
+			if buf, err = binaryZeroValue(f.node, buf, records); err != nil {
+				return nil, err
+			}
+		}
+		delete(records, n)
+		return buf, nil
+	}
+	return nil, fmt.Errorf("unknown type: %q", n.typeName)
+}
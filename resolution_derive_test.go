@@ -0,0 +1,105 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestResolutionDerivedFields(t *testing.T) {
+	writer := newCodecUsingV2(t, `{"type":"record","name":"Event","namespace":"com.example","fields":[
+		{"name":"timestamp","type":{"type":"long","logicalType":"timestamp-millis"}},
+		{"name":"children","type":{"type":"array","items":["null",{"type":"record","name":"Child","fields":[{"name":"n","type":"int"}]}]}}
+	]}`)
+	reader := newCodecUsingV2(t, `{"type":"record","name":"Event","namespace":"com.example","fields":[
+		{"name":"timestamp","type":{"type":"long","logicalType":"timestamp-millis"}},
+		{"name":"children","type":{"type":"array","items":["null",{"type":"record","name":"Child","fields":[{"name":"n","type":"int"},{"name":"double","type":"long"}]}]}},
+		{"name":"event_date","type":{"type":"int","logicalType":"date"}},
+		{"name":"total","type":"long"}
+	]}`)
+	resolution, err := NewResolutionWithDerivedFields(writer, reader, map[string]DeriveFunc{
+		"com.example.Event.event_date": func(record map[string]interface{}) (interface{}, error) {
+			return record["timestamp"].(time.Time).UTC().Truncate(24 * time.Hour), nil
+		},
+		"com.example.Event.total": func(record map[string]interface{}) (interface{}, error) {
+			// derived fields of contained records are already computed
+			var total int64
+			for _, child := range record["children"].([]interface{}) {
+				if child != nil {
+					total += child.(map[string]interface{})["com.example.Child"].(map[string]interface{})["double"].(int64)
+				}
+			}
+			return total, nil
+		},
+		"com.example.Child.double": func(record map[string]interface{}) (interface{}, error) {
+			return 2 * int64(record["n"].(int32)), nil
+		},
+	})
+	ensureError(t, err)
+
+	when := time.Date(2019, 4, 5, 6, 7, 8, 0, time.UTC)
+	buf, err := writer.BinaryFromNative(nil, map[string]interface{}{
+		"timestamp": when,
+		"children":  []interface{}{Union("com.example.Child", map[string]interface{}{"n": 3}), nil},
+	})
+	ensureError(t, err)
+
+	datum, _, err := resolution.NativeFromBinary(buf)
+	ensureError(t, err)
+	expected := map[string]interface{}{
+		"timestamp":  when,
+		"children":   []interface{}{Union("com.example.Child", map[string]interface{}{"n": int32(3), "double": int64(6)}), nil},
+		"event_date": time.Date(2019, 4, 5, 0, 0, 0, 0, time.UTC),
+		"total":      int64(6),
+	}
+	if !reflect.DeepEqual(datum, expected) {
+		t.Errorf("GOT: %#v; WANT: %#v", datum, expected)
+	}
+
+	// binary translation encodes the derived values
+	translated, _, err := resolution.BinaryFromBinary(nil, buf)
+	ensureError(t, err)
+	decoded, _, err := reader.NativeFromBinary(translated)
+	ensureError(t, err)
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("GOT: %#v; WANT: %#v", decoded, expected)
+	}
+
+	var derived int
+	for _, f := range resolution.Report().Fields {
+		if f.Action == ResolutionDerived {
+			derived++
+		}
+	}
+	if derived != 3 {
+		t.Errorf("GOT: %d; WANT: %d", derived, 3)
+	}
+}
+
+func TestResolutionDerivedFieldsFail(t *testing.T) {
+	writer := newCodecUsingV2(t, `{"type":"record","name":"r","fields":[{"name":"a","type":"int"}]}`)
+	reader := newCodecUsingV2(t, `{"type":"record","name":"r","fields":[{"name":"a","type":"int"},{"name":"b","type":"int"}]}`)
+
+	_, err := NewResolutionWithDerivedFields(writer, reader, map[string]DeriveFunc{"r.c": func(map[string]interface{}) (interface{}, error) { return nil, nil }})
+	ensureError(t, err, `derived field ought to name a field of a reader record: ["r.c"]`)
+
+	_, err = NewResolutionWithDerivedFields(writer, reader, nil)
+	ensureError(t, err, `field "b": writer does not have the field`)
+
+	resolution, err := NewResolutionWithDerivedFields(writer, reader, map[string]DeriveFunc{"r.b": func(map[string]interface{}) (interface{}, error) {
+		return nil, errors.New("no value")
+	}})
+	ensureError(t, err)
+	_, _, err = resolution.NativeFromBinary([]byte{0x02})
+	ensureError(t, err, `cannot derive field "r.b": no value`)
+}
@@ -0,0 +1,240 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ResolutionAction describes what a Resolution does with one record field.
+type ResolutionAction string
+
+const (
+	// ResolutionRead fields are read from the writer field of the same name
+	// and type.
+	ResolutionRead ResolutionAction = "read"
+
+	// ResolutionPromoted fields are read from the writer field of the same
+	// name, whose type is promoted to the reader type, such as int to long.
+	ResolutionPromoted ResolutionAction = "promoted"
+
+	// ResolutionDefaulted fields are missing from the writer record, and are
+	// given the default value of the reader field.
+	ResolutionDefaulted ResolutionAction = "defaulted"
+
+	// ResolutionSkipped fields are missing from the reader record, and the
+	// writer field is skipped.
+	ResolutionSkipped ResolutionAction = "skipped"
+
+	// ResolutionDerived fields are computed by a DeriveFunc, after the other
+	// fields of the record are read.
+	ResolutionDerived ResolutionAction = "derived"
+
+	// ResolutionAliased fields are read from a writer field named by one of
+	// the aliases of the reader field, whether or not its type is promoted.
+	ResolutionAliased ResolutionAction = "aliased"
+)
+
+// ResolutionReport describes how a Resolution reads the record fields of data
+// written using the writer schema, so the effects of schema evolution can be
+// audited before deploying consumers that use the reader schema.
+//
+//     report := resolution.Report()
+//     for _, field := range report.Fields {
+//         if field.Action != goavro.ResolutionRead {
+//             fmt.Println(field)
+//         }
+//     }
+type ResolutionReport struct {
+	// Writer and Reader describe the top level writer and reader schemas.
+	Writer string `json:"writer"`
+	Reader string `json:"reader"`
+
+	// Fields lists the record fields reachable from the top level schemas,
+	// depth first. The fields of each record are listed in the order of the
+	// reader record, followed by the skipped writer fields.
+	Fields []ResolutionFieldReport `json:"fields"`
+}
+
+// ResolutionFieldReport describes how one record field is resolved.
+//
+// Path addresses the field in the reader schema, using the conventions of
+// SchemaFieldDoc. The path of a skipped field uses the name of the writer
+// field.
+type ResolutionFieldReport struct {
+	Path       string           `json:"path"`
+	Action     ResolutionAction `json:"action"`
+	WriterName string           `json:"writerName,omitempty"` // name of the writer field, when not defaulted
+	WriterType string           `json:"writerType,omitempty"` // type of the writer field, when not defaulted
+	ReaderType string           `json:"readerType,omitempty"` // type of the reader field, when not skipped
+	Default    interface{}      `json:"default,omitempty"`    // default value of a defaulted field
+}
+
+// String returns a one line description of the field resolution.
+func (f ResolutionFieldReport) String() string {
+	switch f.Action {
+	case ResolutionDefaulted:
+		return fmt.Sprintf("%s: %s %s: %s", f.Path, f.Action, f.ReaderType, markdownJSON(f.Default))
+	case ResolutionSkipped:
+		return fmt.Sprintf("%s: %s %s", f.Path, f.Action, f.WriterType)
+	case ResolutionDerived:
+		return fmt.Sprintf("%s: %s %s", f.Path, f.Action, f.ReaderType)
+	case ResolutionAliased:
+		return fmt.Sprintf("%s: %s from %s: %s -> %s", f.Path, f.Action, f.WriterName, f.WriterType, f.ReaderType)
+	default:
+		return fmt.Sprintf("%s: %s %s -> %s", f.Path, f.Action, f.WriterType, f.ReaderType)
+	}
+}
+
+// Report returns the report of how the Resolution reads record fields.
+func (r *Resolution) Report() *ResolutionReport {
+	report := *r.plan.report
+	report.Fields = append([]ResolutionFieldReport(nil), report.Fields...)
+	return &report
+}
+
+// JSON returns the report encoded as JSON.
+func (report *ResolutionReport) JSON() ([]byte, error) {
+	return json.Marshal(report)
+}
+
+// String returns the report with one field per line.
+func (report *ResolutionReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s -> %s\n", report.Writer, report.Reader)
+	for _, f := range report.Fields {
+		b.WriteString(f.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// report adds the fields of the compiled writer and reader nodes to the
+// report. Each pair of records is only expanded once, which also prevents
+// infinite recursion for recursive schemas.
+func (rc *resolutionCompiler) report(report *ResolutionReport, w, r *schemaNode, path string, seen map[[2]*schemaNode]struct{}) {
+	if w.typeName == "union" {
+		for _, member := range w.members {
+			if _, ok := rc.compiled[[2]*schemaNode{member, r}]; ok {
+				rc.report(report, member, r, path, seen)
+			}
+		}
+		return
+	}
+	if r.typeName == "union" {
+		i := readerUnionMember(w, r)
+		if i < 0 {
+			return
+		}
+		member := r.members[i]
+		if member.typeName == "record" {
+			var records int
+			for _, m := range r.members {
+				if m.typeName == "record" {
+					records++
+				}
+			}
+			if records > 1 {
+				path += "(" + member.fullName + ")"
+			}
+		}
+		rc.report(report, w, member, path, seen)
+		return
+	}
+	switch r.typeName {
+	case "array":
+		rc.report(report, w.items, r.items, path+"[]", seen)
+		return
+	case "map":
+		rc.report(report, w.values, r.values, path+"{}", seen)
+		return
+	}
+	if r.typeName != "record" {
+		return
+	}
+	key := [2]*schemaNode{w, r}
+	if _, ok := seen[key]; ok {
+		return
+	}
+	seen[key] = struct{}{}
+
+	fieldPath := func(name string) string {
+		if path == "" {
+			return name
+		}
+		return path + "." + name
+	}
+	writerFields := make([]*schemaNodeField, len(r.fields))
+	var skipped []*schemaNodeField
+	for _, wf := range w.fields {
+		if j := readerFieldIndex(r, wf.name); j >= 0 {
+			writerFields[j] = wf
+		} else {
+			skipped = append(skipped, wf)
+		}
+	}
+	for j, rf := range r.fields {
+		f := ResolutionFieldReport{Path: fieldPath(rf.name), ReaderType: rf.node.label()}
+		wf := writerFields[j]
+		_, derived := rc.derived[r.fullName+"."+rf.name]
+		switch {
+		case derived:
+			f.Action = ResolutionDerived
+		case wf == nil:
+			f.Action, f.Default = ResolutionDefaulted, rf.defaultValue
+		case wf.name != rf.name:
+			f.Action = ResolutionAliased
+		case rc.promotes(wf.node, rf.node):
+			f.Action = ResolutionPromoted
+		default:
+			f.Action = ResolutionRead
+		}
+		if wf != nil {
+			f.WriterName, f.WriterType = wf.name, wf.node.label()
+		}
+		report.Fields = append(report.Fields, f)
+	}
+	for _, wf := range skipped {
+		report.Fields = append(report.Fields, ResolutionFieldReport{Path: fieldPath(wf.name), Action: ResolutionSkipped, WriterName: wf.name, WriterType: wf.node.label()})
+	}
+	for j, rf := range r.fields {
+		if wf := writerFields[j]; wf != nil {
+			rc.report(report, wf.node, rf.node, fieldPath(rf.name), seen)
+		}
+	}
+}
+
+// promotes returns true when the compiled resolution of the writer node with
+// the reader node promotes the writer type, the type of one of the writer
+// union members, or the type of array items or map values, to a different
+// reader type.
+func (rc *resolutionCompiler) promotes(w, r *schemaNode) bool {
+	if w.typeName == "union" {
+		for _, member := range w.members {
+			if _, ok := rc.compiled[[2]*schemaNode{member, r}]; ok && rc.promotes(member, r) {
+				return true
+			}
+		}
+		return false
+	}
+	if r.typeName == "union" {
+		i := readerUnionMember(w, r)
+		return i >= 0 && rc.promotes(w, r.members[i])
+	}
+	switch r.typeName {
+	case "array":
+		return rc.promotes(w.items, r.items)
+	case "map":
+		return rc.promotes(w.values, r.values)
+	}
+	return w.typeName != r.typeName
+}
@@ -0,0 +1,247 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ResolutionUnionMember steps of a ResolutionSimulation read a datum as the
+// member of a reader union it matches.
+const ResolutionUnionMember ResolutionAction = "union member"
+
+// ResolutionStep describes one step a Resolution applied to a datum.
+//
+// Path addresses the value in the reader datum, using the conventions of
+// SchemaFieldDoc, except that array items and map values are addressed by
+// their index and key, such as "items[2]" and "labels{color}".
+type ResolutionStep struct {
+	Path        string           `json:"path"`
+	Action      ResolutionAction `json:"action"`
+	Description string           `json:"description"`
+}
+
+func (s ResolutionStep) String() string {
+	return fmt.Sprintf("%s: %s %s", s.Path, s.Action, s.Description)
+}
+
+// ResolutionSimulation describes how a Resolution reads one datum: the datum
+// as the reader sees it, and the steps that changed it from the datum the
+// writer wrote.
+type ResolutionSimulation struct {
+	Datum interface{}      `json:"datum"`
+	Steps []ResolutionStep `json:"steps"`
+}
+
+// String returns the steps of the simulation, with one step per line.
+func (s *ResolutionSimulation) String() string {
+	var b strings.Builder
+	for _, step := range s.Steps {
+		b.WriteString(step.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// SimulateResolution returns how data written using the schema of writer is
+// read using the schema of reader, for the sample datum, such as to review a
+// schema change before data using it is written.
+//
+//     simulation, err := goavro.SimulateResolution(writer, reader, datum)
+//     if err != nil {
+//         return err
+//     }
+//     fmt.Printf("%v\n%s", simulation.Datum, simulation)
+func SimulateResolution(writer, reader *Codec, datum interface{}) (*ResolutionSimulation, error) {
+	resolution, err := NewResolution(writer, reader)
+	if err != nil {
+		return nil, err
+	}
+	return resolution.Simulate(datum)
+}
+
+// Simulate encodes the datum using the writer schema, reads it as the reader
+// does, and returns the datum read, with the steps applied to it.
+func (r *Resolution) Simulate(datum interface{}) (*ResolutionSimulation, error) {
+	buf, err := r.writer.BinaryFromNative(nil, datum)
+	if err != nil {
+		return nil, fmt.Errorf("cannot simulate resolution: %s", err)
+	}
+	written, _, err := r.writer.NativeFromBinary(buf)
+	if err != nil {
+		return nil, fmt.Errorf("cannot simulate resolution: %s", err)
+	}
+	read, _, err := r.NativeFromBinary(buf)
+	if err != nil {
+		return nil, fmt.Errorf("cannot simulate resolution: %s", err)
+	}
+	w, err := schemaNodeFromCodec(r.writer)
+	if err != nil {
+		return nil, fmt.Errorf("cannot simulate resolution: %s", err)
+	}
+	root, err := schemaNodeFromCodec(r.reader)
+	if err != nil {
+		return nil, fmt.Errorf("cannot simulate resolution: %s", err)
+	}
+	s := &resolutionSimulator{derivation: r.derivation, simulation: &ResolutionSimulation{Datum: read, Steps: []ResolutionStep{}}}
+	s.walk(w, root, written, read, "", false)
+	return s.simulation, nil
+}
+
+type resolutionSimulator struct {
+	derivation *derivation // nil unless the Resolution has derived fields
+	simulation *ResolutionSimulation
+}
+
+func (s *resolutionSimulator) step(path string, action ResolutionAction, format string, a ...interface{}) {
+	s.simulation.Steps = append(s.simulation.Steps, ResolutionStep{Path: path, Action: action, Description: fmt.Sprintf(format, a...)})
+}
+
+// walk adds the steps of reading the writer value wv, of writer node w, as the
+// reader value rv, of reader node r. fromUnion is true when wv is the value of
+// a member of a writer union, whose steps only name the reader union member
+// when it differs from the writer union member.
+func (s *resolutionSimulator) walk(w, r *schemaNode, wv, rv interface{}, path string, fromUnion bool) {
+	if w.typeName == "union" {
+		member, value := simulationUnionValue(w, wv)
+		if member == nil {
+			return
+		}
+		s.walk(member, r, value, rv, path, true)
+		return
+	}
+	if r.typeName == "union" {
+		i := readerUnionMember(w, r)
+		if i < 0 {
+			return
+		}
+		member := r.members[i]
+		if !fromUnion || unionMemberName(w) != unionMemberName(member) {
+			s.step(path, ResolutionUnionMember, "%s read as %q", w.label(), unionMemberName(member))
+		}
+		_, value := simulationUnionValue(r, rv)
+		if member.typeName == "record" {
+			var records int
+			for _, m := range r.members {
+				if m.typeName == "record" {
+					records++
+				}
+			}
+			if records > 1 {
+				path += "(" + member.fullName + ")"
+			}
+		}
+		s.walk(w, member, wv, value, path, false)
+		return
+	}
+
+	switch r.typeName {
+	case "array":
+		witems, _ := wv.([]interface{})
+		ritems, _ := rv.([]interface{})
+		for i := 0; i < len(witems) && i < len(ritems); i++ {
+			s.walk(w.items, r.items, witems[i], ritems[i], fmt.Sprintf("%s[%d]", path, i), false)
+		}
+	case "map":
+		wvalues, _ := wv.(map[string]interface{})
+		rvalues, _ := rv.(map[string]interface{})
+		keys := make([]string, 0, len(wvalues))
+		for key := range wvalues {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			s.walk(w.values, r.values, wvalues[key], rvalues[key], path+"{"+key+"}", false)
+		}
+	case "record":
+		s.walkRecord(w, r, wv, rv, path)
+	case "enum":
+		if wv != rv {
+			s.step(path, ResolutionDefaulted, "symbol %q of writer enum %q is not a reader symbol: %q", wv, w.fullName, rv)
+		}
+	default:
+		if w.typeName != r.typeName {
+			s.step(path, ResolutionPromoted, "%s %v -> %s %v", w.label(), markdownJSON(wv), r.label(), markdownJSON(rv))
+		}
+	}
+}
+
+func (s *resolutionSimulator) walkRecord(w, r *schemaNode, wv, rv interface{}, path string) {
+	wrecord, _ := wv.(map[string]interface{})
+	rrecord, _ := rv.(map[string]interface{})
+	fieldPath := func(name string) string {
+		if path == "" {
+			return name
+		}
+		return path + "." + name
+	}
+	writerFields := make([]*schemaNodeField, len(r.fields))
+	var skipped []*schemaNodeField
+	for _, wf := range w.fields {
+		if j := readerFieldIndex(r, wf.name); j >= 0 {
+			writerFields[j] = wf
+		} else {
+			skipped = append(skipped, wf)
+		}
+	}
+	for j, rf := range r.fields {
+		wf := writerFields[j]
+		switch {
+		case s.derived(r, rf.name):
+			s.step(fieldPath(rf.name), ResolutionDerived, "%s", markdownJSON(rrecord[rf.name]))
+		case wf == nil:
+			s.step(fieldPath(rf.name), ResolutionDefaulted, "%s", markdownJSON(rf.defaultValue))
+		default:
+			if wf.name != rf.name {
+				s.step(fieldPath(rf.name), ResolutionAliased, "from writer field %q", wf.name)
+			}
+			s.walk(wf.node, rf.node, wrecord[wf.name], rrecord[rf.name], fieldPath(rf.name), false)
+		}
+	}
+	for _, wf := range skipped {
+		s.step(fieldPath(wf.name), ResolutionSkipped, "%s", markdownJSON(wrecord[wf.name]))
+	}
+}
+
+// derived returns true when the field of the reader record node is derived.
+func (s *resolutionSimulator) derived(r *schemaNode, name string) bool {
+	if s.derivation == nil {
+		return false
+	}
+	for _, f := range s.derivation.fields[r] {
+		if f.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// simulationUnionValue returns the member of the union node, and the value of
+// that member, of the decoded union value.
+func simulationUnionValue(n *schemaNode, value interface{}) (*schemaNode, interface{}) {
+	if value == nil {
+		for _, member := range n.members {
+			if member.typeName == "null" {
+				return member, nil
+			}
+		}
+		return nil, nil
+	}
+	if name, v, ok := unwrapUnion(value); ok {
+		for _, member := range n.members {
+			if unionMemberName(member) == name {
+				return member, v
+			}
+		}
+	}
+	return nil, nil
+}
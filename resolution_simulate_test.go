@@ -0,0 +1,91 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSimulateResolution(t *testing.T) {
+	writer, err := NewCodec(`{"type":"record","name":"User","fields":[
+		{"name":"id","type":"int"},
+		{"name":"nick","type":"string"},
+		{"name":"plan","type":{"type":"enum","name":"Plan","symbols":["FREE","GOLD","TRIAL"]}},
+		{"name":"scores","type":{"type":"array","items":["null","int"]}},
+		{"name":"legacy","type":"boolean"}
+	]}`)
+	ensureError(t, err)
+	reader, err := NewCodec(`{"type":"record","name":"User","fields":[
+		{"name":"id","type":["null","long"]},
+		{"name":"name","type":"string","aliases":["nick"]},
+		{"name":"plan","type":{"type":"enum","name":"Plan","symbols":["FREE","GOLD"],"default":"FREE"}},
+		{"name":"scores","type":{"type":"array","items":["null","double"]}},
+		{"name":"country","type":"string","default":"NO"}
+	]}`)
+	ensureError(t, err)
+
+	simulation, err := SimulateResolution(writer, reader, map[string]interface{}{
+		"id":     13,
+		"nick":   "ann",
+		"plan":   "TRIAL",
+		"scores": []interface{}{nil, Union("int", 3)},
+		"legacy": true,
+	})
+	ensureError(t, err)
+
+	expected := map[string]interface{}{
+		"id":      Union("long", int64(13)),
+		"name":    "ann",
+		"plan":    "FREE",
+		"scores":  []interface{}{nil, Union("double", 3.0)},
+		"country": "NO",
+	}
+	if !reflect.DeepEqual(simulation.Datum, expected) {
+		t.Errorf("GOT: %v; WANT: %v", simulation.Datum, expected)
+	}
+	if actual, expected := simulation.String(), `id: union member int read as "long"
+id: promoted int 13 -> long 13
+name: aliased from writer field "nick"
+plan: defaulted symbol "TRIAL" of writer enum "Plan" is not a reader symbol: "FREE"
+scores[1]: union member int read as "double"
+scores[1]: promoted int 3 -> double 3
+country: defaulted "NO"
+legacy: skipped true
+`; actual != expected {
+		t.Errorf("GOT:\n%s\nWANT:\n%s", actual, expected)
+	}
+
+	// union members of the same type need no steps
+	simulation, err = SimulateResolution(reader, reader, expected)
+	ensureError(t, err)
+	if len(simulation.Steps) != 0 {
+		t.Errorf("GOT: %v; WANT: none", simulation.Steps)
+	}
+
+	_, err = SimulateResolution(writer, reader, map[string]interface{}{"id": "13"})
+	ensureError(t, err, "cannot simulate resolution")
+}
+
+func TestResolutionSimulateDerived(t *testing.T) {
+	writer, err := NewCodec(`{"type":"record","name":"r","fields":[{"name":"a","type":"long"}]}`)
+	ensureError(t, err)
+	reader, err := NewCodec(`{"type":"record","name":"r","fields":[{"name":"a","type":"long"},{"name":"b","type":"long"}]}`)
+	ensureError(t, err)
+	resolution, err := NewResolutionWithDerivedFields(writer, reader, map[string]DeriveFunc{
+		"r.b": func(record map[string]interface{}) (interface{}, error) { return record["a"].(int64) * 2, nil },
+	})
+	ensureError(t, err)
+	simulation, err := resolution.Simulate(map[string]interface{}{"a": int64(21)})
+	ensureError(t, err)
+	if actual, expected := simulation.String(), "b: derived 42\n"; actual != expected {
+		t.Errorf("GOT: %q; WANT: %q", actual, expected)
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testResolutionPass(t *testing.T, writerSchema, readerSchema string, datum, expected interface{}) {
+	t.Helper()
+	writer := newCodecUsingV2(t, writerSchema)
+	reader := newCodecUsingV2(t, readerSchema)
+	resolution, err := NewResolution(writer, reader)
+	ensureError(t, err)
+	buf, err := writer.BinaryFromNative(nil, datum)
+	ensureError(t, err)
+	buf = append(buf, 0xff) // trailing byte ought to remain
+	actual, rest, err := resolution.NativeFromBinary(buf)
+	ensureError(t, err)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("GOT: %#v; WANT: %#v", actual, expected)
+	}
+	if !reflect.DeepEqual(rest, []byte{0xff}) {
+		t.Errorf("GOT: %v; WANT: %v", rest, []byte{0xff})
+	}
+}
+
+func testResolutionFail(t *testing.T, writerSchema, readerSchema string, errorMessage string) {
+	t.Helper()
+	_, err := NewResolution(newCodecUsingV2(t, writerSchema), newCodecUsingV2(t, readerSchema))
+	ensureError(t, err, errorMessage)
+}
+
+func TestResolutionPromotion(t *testing.T) {
+	testResolutionPass(t, `"int"`, `"int"`, 3, int32(3))
+	testResolutionPass(t, `"int"`, `"long"`, 3, int64(3))
+	testResolutionPass(t, `"int"`, `"float"`, 3, float32(3))
+	testResolutionPass(t, `"long"`, `"double"`, -5, float64(-5))
+	testResolutionPass(t, `"float"`, `"double"`, float32(1.5), float64(1.5))
+	testResolutionPass(t, `"string"`, `"bytes"`, "abc", []byte("abc"))
+	testResolutionPass(t, `"bytes"`, `"string"`, []byte("abc"), "abc")
+	testResolutionPass(t, `{"type":"array","items":"int"}`, `{"type":"array","items":"long"}`, []interface{}{1, 2}, []interface{}{int64(1), int64(2)})
+	testResolutionPass(t, `{"type":"map","values":"int"}`, `{"type":"map","values":"double"}`, map[string]interface{}{"a": 1}, map[string]interface{}{"a": float64(1)})
+
+	testResolutionFail(t, `"long"`, `"int"`, "writer long does not match reader int")
+	testResolutionFail(t, `"double"`, `"float"`, "writer double does not match reader float")
+	testResolutionFail(t, `{"type":"fixed","name":"f","size":2}`, `{"type":"fixed","name":"f","size":3}`, "writer f does not match reader f")
+}
+
+func TestResolutionRecord(t *testing.T) {
+	writerSchema := `{"type":"record","name":"r","fields":[
+		{"name":"a","type":"int"},
+		{"name":"skipped","type":{"type":"array","items":"string"}},
+		{"name":"b","type":"string"}
+	]}`
+
+	// reordered fields, with a promoted field, and fields only the reader has
+	readerSchema := `{"type":"record","name":"r","fields":[
+		{"name":"b","type":"string"},
+		{"name":"c","type":["null","long"],"default":null},
+		{"name":"a","type":"long"},
+		{"name":"d","type":"bytes","default":"ÿ\u0001"},
+		{"name":"e","type":{"type":"record","name":"e","fields":[{"name":"x","type":"int","default":7}]},"default":{}}
+	]}`
+	datum := map[string]interface{}{"a": 1, "skipped": []interface{}{"x", "y"}, "b": "bee"}
+	expected := map[string]interface{}{
+		"b": "bee",
+		"c": nil,
+		"a": int64(1),
+		"d": []byte{0xff, 0x01},
+		"e": map[string]interface{}{"x": int32(7)},
+	}
+	testResolutionPass(t, writerSchema, readerSchema, datum, expected)
+
+	// reader fields in writer order, without defaults
+	testResolutionPass(t, writerSchema, `{"type":"record","name":"r","fields":[{"name":"a","type":"int"},{"name":"b","type":"string"}]}`, datum, map[string]interface{}{"a": int32(1), "b": "bee"})
+
+	testResolutionFail(t, writerSchema, `{"type":"record","name":"r","fields":[{"name":"z","type":"int"}]}`, `field "z": writer does not have the field, and reader does not specify a default value`)
+	testResolutionFail(t, writerSchema, `{"type":"record","name":"other","fields":[]}`, "writer r does not match reader other")
+}
+
+func TestResolutionAliases(t *testing.T) {
+	testResolutionPass(t,
+		`{"type":"record","name":"Old","namespace":"com.example","fields":[{"name":"before","type":"int"}]}`,
+		`{"type":"record","name":"New","namespace":"com.example","aliases":["Old"],"fields":[{"name":"after","type":"int","aliases":["before"]}]}`,
+		map[string]interface{}{"before": 3},
+		map[string]interface{}{"after": int32(3)})
+}
+
+func TestResolutionEnum(t *testing.T) {
+	writerSchema := `{"type":"enum","name":"e","symbols":["a","b","c"]}`
+	testResolutionPass(t, writerSchema, `{"type":"enum","name":"e","symbols":["c","a"],"default":"a"}`, "c", "c")
+	testResolutionPass(t, writerSchema, `{"type":"enum","name":"e","symbols":["c","a"],"default":"a"}`, "b", "a")
+
+	resolution, err := NewResolution(newCodecUsingV2(t, writerSchema), newCodecUsingV2(t, `{"type":"enum","name":"e","symbols":["a"]}`))
+	ensureError(t, err)
+	_, _, err = resolution.NativeFromBinary([]byte{0x02})
+	ensureError(t, err, `symbol "b" is not a symbol of reader enum "e"`)
+}
+
+func TestResolutionUnion(t *testing.T) {
+	// writer union to reader union, promoting a member
+	testResolutionPass(t, `["null","int","string"]`, `["string","null","long"]`, Union("int", 3), Union("long", int64(3)))
+	testResolutionPass(t, `["null","int","string"]`, `["string","null","long"]`, Union("string", "s"), Union("string", "s"))
+
+	// writer union to reader non-union
+	testResolutionPass(t, `["int","long"]`, `"long"`, Union("int", 3), int64(3))
+
+	// writer non-union to reader union, preferring the same type
+	testResolutionPass(t, `"int"`, `["null","double","int"]`, 3, Union("int", int32(3)))
+	testResolutionPass(t, `"int"`, `["null","double"]`, 3, Union("double", float64(3)))
+
+	// members that do not resolve fail when they are read
+	resolution, err := NewResolution(newCodecUsingV2(t, `["null","string"]`), newCodecUsingV2(t, `"string"`))
+	ensureError(t, err)
+	_, _, err = resolution.NativeFromBinary([]byte{0x00})
+	ensureError(t, err, "writer union member 0", "writer null does not match reader string")
+
+	testResolutionFail(t, `["null","int"]`, `"string"`, "no member of writer union<null,int> matches reader string")
+	testResolutionFail(t, `"boolean"`, `["null","int"]`, "writer boolean does not match any member of reader union<null,int>")
+}
+
+func TestResolutionRecursive(t *testing.T) {
+	writerSchema := `{"type":"record","name":"list","fields":[
+		{"name":"value","type":"int"},
+		{"name":"next","type":["null","list"]}
+	]}`
+	readerSchema := `{"type":"record","name":"list","fields":[
+		{"name":"next","type":["null","list"]},
+		{"name":"value","type":"long"},
+		{"name":"label","type":"string","default":"none"}
+	]}`
+	datum := map[string]interface{}{"value": 1, "next": Union("list", map[string]interface{}{"value": 2, "next": nil})}
+	expected := map[string]interface{}{
+		"value": int64(1),
+		"label": "none",
+		"next":  Union("list", map[string]interface{}{"value": int64(2), "label": "none", "next": nil}),
+	}
+	testResolutionPass(t, writerSchema, readerSchema, datum, expected)
+}
+
+func TestResolutionBinaryFromBinary(t *testing.T) {
+	writer := newCodecUsingV2(t, `{"type":"array","items":"int"}`)
+	reader := newCodecUsingV2(t, `{"type":"array","items":"long"}`)
+	resolution, err := NewResolution(writer, reader)
+	ensureError(t, err)
+	if resolution.Writer() != writer || resolution.Reader() != reader {
+		t.Errorf("GOT: %p, %p; WANT: %p, %p", resolution.Writer(), resolution.Reader(), writer, reader)
+	}
+
+	// negative block counts are followed by the block size, which is dropped
+	buf, rest, err := resolution.BinaryFromBinary([]byte{0x42}, []byte{0x03, 0x04, 0x02, 0x04, 0x00})
+	ensureError(t, err)
+	if expected := []byte{0x42, 0x04, 0x02, 0x04, 0x00}; !reflect.DeepEqual(buf, expected) {
+		t.Errorf("GOT: %v; WANT: %v", buf, expected)
+	}
+	if len(rest) != 0 {
+		t.Errorf("GOT: %v; WANT: %v", rest, []byte{})
+	}
+
+	_, _, err = resolution.BinaryFromBinary(nil, []byte{0x02})
+	ensureError(t, err, "cannot resolve binary", "array item 1")
+}
+
+func TestResolutionReport(t *testing.T) {
+	writerSchema := `{"type":"record","name":"r","fields":[
+		{"name":"id","type":"long"},
+		{"name":"count","type":"int"},
+		{"name":"old","type":"string"},
+		{"name":"legacy","type":"boolean"},
+		{"name":"items","type":{"type":"array","items":{"type":"record","name":"item","fields":[{"name":"x","type":["null","int"]}]}}}
+	]}`
+	readerSchema := `{"type":"record","name":"r","fields":[
+		{"name":"id","type":"long"},
+		{"name":"count","type":"long"},
+		{"name":"new","type":"string","aliases":["old"]},
+		{"name":"added","type":"string","default":"none"},
+		{"name":"items","type":{"type":"array","items":{"type":"record","name":"item","fields":[{"name":"x","type":["null","long"]}]}}}
+	]}`
+	resolution, err := NewResolution(newCodecUsingV2(t, writerSchema), newCodecUsingV2(t, readerSchema))
+	ensureError(t, err)
+	report := resolution.Report()
+
+	expected := []ResolutionFieldReport{
+		{Path: "id", Action: ResolutionRead, WriterName: "id", WriterType: "long", ReaderType: "long"},
+		{Path: "count", Action: ResolutionPromoted, WriterName: "count", WriterType: "int", ReaderType: "long"},
+		{Path: "new", Action: ResolutionAliased, WriterName: "old", WriterType: "string", ReaderType: "string"},
+		{Path: "added", Action: ResolutionDefaulted, ReaderType: "string", Default: "none"},
+		{Path: "items", Action: ResolutionRead, WriterName: "items", WriterType: "array<item>", ReaderType: "array<item>"},
+		{Path: "legacy", Action: ResolutionSkipped, WriterName: "legacy", WriterType: "boolean"},
+		{Path: "items[].x", Action: ResolutionPromoted, WriterName: "x", WriterType: "union<null,int>", ReaderType: "union<null,long>"},
+	}
+	if !reflect.DeepEqual(report.Fields, expected) {
+		t.Errorf("GOT: %v; WANT: %v", report.Fields, expected)
+	}
+	if report.Writer != "r" || report.Reader != "r" {
+		t.Errorf("GOT: %q, %q; WANT: %q, %q", report.Writer, report.Reader, "r", "r")
+	}
+
+	// reports are copies, so callers may modify them
+	report.Fields[0].Action = ResolutionSkipped
+	if actual := resolution.Report().Fields[0].Action; actual != ResolutionRead {
+		t.Errorf("GOT: %v; WANT: %v", actual, ResolutionRead)
+	}
+
+	if actual, expected := report.Fields[3].String(), `added: defaulted string: "none"`; actual != expected {
+		t.Errorf("GOT: %q; WANT: %q", actual, expected)
+	}
+	if actual, expected := report.Fields[2].String(), "new: aliased from old: string -> string"; actual != expected {
+		t.Errorf("GOT: %q; WANT: %q", actual, expected)
+	}
+	buf, err := report.JSON()
+	ensureError(t, err)
+	if !strings.Contains(string(buf), `{"path":"legacy","action":"skipped","writerName":"legacy","writerType":"boolean"}`) {
+		t.Errorf("GOT: %s", buf)
+	}
+}
@@ -0,0 +1,231 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+type samplerKind int
+
+const (
+	sampleEveryNth samplerKind = iota
+	sampleProbability
+	sampleReservoir
+)
+
+// Sampler selects a representative sample of a stream of data items, such as
+// the records of an OCF, for validating an inferred schema or checking the
+// quality of data without keeping every data item. The sampler determines in
+// advance how many of the following data items it will not keep, so ReadOCF
+// and ReadBinary skip over those items without decoding them. A Sampler ought
+// not to be used by multiple goroutines simultaneously.
+//
+//     sampler, err := goavro.NewReservoirSampler(1000, time.Now().UnixNano())
+//     if err != nil {
+//         return err
+//     }
+//     ocfr, err := goavro.NewOCFReader(file)
+//     if err != nil {
+//         return err
+//     }
+//     if err = sampler.ReadOCF(ocfr); err != nil {
+//         return err
+//     }
+//     for _, datum := range sampler.Sample() {
+//         // validate datum
+//     }
+type Sampler struct {
+	kind   samplerKind
+	n      int64   // period of the every-Nth sampler
+	p      float64 // probability of the probability sampler
+	k      int     // size of the reservoir
+	w      float64 // state of the reservoir sampler, see Li's Algorithm L
+	rng    *rand.Rand
+	seen   int64 // number of data items offered or passed
+	gap    int64 // number of following data items that will not be kept
+	sample []interface{}
+}
+
+// NewEveryNthSampler returns a Sampler that keeps the first data item, and
+// every nth data item after it.
+func NewEveryNthSampler(n int64) (*Sampler, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("cannot create Sampler: n ought to be greater than 0: %d", n)
+	}
+	return &Sampler{kind: sampleEveryNth, n: n}, nil
+}
+
+// NewProbabilitySampler returns a Sampler that keeps each data item with the
+// probability p, using a pseudo-random source initialized with seed, so the
+// same seed selects the same data items of the same stream.
+func NewProbabilitySampler(p float64, seed int64) (*Sampler, error) {
+	if !(p > 0 && p <= 1) {
+		return nil, fmt.Errorf("cannot create Sampler: probability ought to be greater than 0 and at most 1: %g", p)
+	}
+	s := &Sampler{kind: sampleProbability, p: p, rng: rand.New(rand.NewSource(seed))}
+	s.gap = s.geometric(p)
+	return s, nil
+}
+
+// NewReservoirSampler returns a Sampler that keeps a uniform random sample of
+// k data items of the stream, or every data item of streams with fewer than k
+// items, using a pseudo-random source initialized with seed.
+func NewReservoirSampler(k int, seed int64) (*Sampler, error) {
+	if k <= 0 {
+		return nil, fmt.Errorf("cannot create Sampler: reservoir size ought to be greater than 0: %d", k)
+	}
+	return &Sampler{kind: sampleReservoir, k: k, w: 1, rng: rand.New(rand.NewSource(seed)), sample: make([]interface{}, 0, k)}, nil
+}
+
+// uniform returns a pseudo-random number in the interval (0, 1].
+func (s *Sampler) uniform() float64 {
+	return 1 - s.rng.Float64()
+}
+
+// geometric returns the number of failures before the first success of
+// Bernoulli trials succeeding with probability p.
+func (s *Sampler) geometric(p float64) int64 {
+	if p >= 1 {
+		return 0
+	}
+	gap := math.Floor(math.Log(s.uniform()) / math.Log1p(-p))
+	if gap > math.MaxInt64/2 {
+		return math.MaxInt64 / 2
+	}
+	return int64(gap)
+}
+
+// Offer offers the next data item of the stream to the sampler, returning true
+// when the sampler keeps it. A data item kept by a reservoir sampler may later
+// be replaced by another.
+func (s *Sampler) Offer(datum interface{}) bool {
+	s.seen++
+	if s.gap > 0 {
+		s.gap--
+		return false
+	}
+	switch s.kind {
+	case sampleEveryNth:
+		s.sample = append(s.sample, datum)
+		s.gap = s.n - 1
+	case sampleProbability:
+		s.sample = append(s.sample, datum)
+		s.gap = s.geometric(s.p)
+	case sampleReservoir:
+		if len(s.sample) < s.k {
+			s.sample = append(s.sample, datum)
+		} else {
+			s.sample[s.rng.Intn(s.k)] = datum
+		}
+		if len(s.sample) == s.k {
+			s.w *= math.Exp(math.Log(s.uniform()) / float64(s.k))
+			s.gap = s.geometric(s.w)
+		}
+	}
+	return true
+}
+
+// Skip returns the number of following data items of the stream that the
+// sampler will not keep, which the caller may pass over without decoding them,
+// and report using Pass.
+func (s *Sampler) Skip() int64 {
+	return s.gap
+}
+
+// Pass reports that the caller passed over the following n data items of the
+// stream without offering them, where n ought not to be greater than the value
+// returned by Skip.
+func (s *Sampler) Pass(n int64) error {
+	if n < 0 || n > s.gap {
+		return fmt.Errorf("cannot pass data items: ought to be from 0 to %d: %d", s.gap, n)
+	}
+	s.seen += n
+	s.gap -= n
+	return nil
+}
+
+// Seen returns the number of data items of the stream offered to or passed
+// over by the sampler.
+func (s *Sampler) Seen() int64 {
+	return s.seen
+}
+
+// Sample returns the data items kept by the sampler, in the order they
+// appeared in the stream, except for reservoir samplers, whose data items are
+// in no particular order.
+func (s *Sampler) Sample() []interface{} {
+	return append([]interface{}(nil), s.sample...)
+}
+
+// ReadOCF offers the data items of the OCF reader to the sampler, until the
+// end of the OCF, skipping the data items the sampler will not keep without
+// decoding them. Blocks whose data items are all skipped are not decoded.
+func (s *Sampler) ReadOCF(ocfr *OCFReader) error {
+	for ocfr.Scan() {
+		if remaining := ocfr.RemainingBlockItems(); s.gap >= remaining {
+			ocfr.SkipThisBlockAndReset()
+			_ = s.Pass(remaining)
+			continue
+		}
+		if s.gap > 0 {
+			rest, err := ocfr.header.codec.SkipBinary(ocfr.block)
+			if err != nil {
+				return fmt.Errorf("cannot sample OCF: %s", err)
+			}
+			ocfr.block = rest
+			ocfr.remainingBlockItems--
+			ocfr.readReady = false
+			_ = s.Pass(1)
+			continue
+		}
+		datum, err := ocfr.Read()
+		if err != nil {
+			return fmt.Errorf("cannot sample OCF: %s", err)
+		}
+		s.Offer(datum)
+	}
+	if err := ocfr.Err(); err != nil {
+		return fmt.Errorf("cannot sample OCF: %s", err)
+	}
+	return nil
+}
+
+// ReadBinary offers the binary encoded data items concatenated in buf to the
+// sampler, such as the data of an OCF block or of a stream of data items
+// written without framing, skipping the data items the sampler will not keep
+// without decoding them.
+func (s *Sampler) ReadBinary(codec *Codec, buf []byte) error {
+	if codec == nil {
+		return errors.New("cannot sample binary: codec is nil")
+	}
+	for len(buf) > 0 {
+		var err error
+		if s.gap > 0 {
+			if buf, err = codec.SkipBinary(buf); err != nil {
+				return fmt.Errorf("cannot sample binary: data item %d: %s", s.seen, err)
+			}
+			_ = s.Pass(1)
+			continue
+		}
+		var datum interface{}
+		if datum, buf, err = codec.nativeFromBinaryPrefix(buf); err != nil {
+			return fmt.Errorf("cannot sample binary: data item %d: %s", s.seen, err)
+		}
+		s.Offer(datum)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// sampleOCF returns an OCF of the longs from 0 to n-1, written in blocks of
+// ten.
+func sampleOCF(t *testing.T, n int64) []byte {
+	t.Helper()
+	bb := new(bytes.Buffer)
+	ocfw, err := NewOCFWriter(OCFConfig{W: bb, Schema: `"long"`})
+	ensureError(t, err)
+	for i := int64(0); i < n; i += 10 {
+		var block []interface{}
+		for j := i; j < i+10 && j < n; j++ {
+			block = append(block, j)
+		}
+		ensureError(t, ocfw.Append(block))
+	}
+	return bb.Bytes()
+}
+
+func TestSamplerEveryNth(t *testing.T) {
+	s, err := NewEveryNthSampler(7)
+	ensureError(t, err)
+	ocfr, err := NewOCFReader(bytes.NewReader(sampleOCF(t, 50)))
+	ensureError(t, err)
+	ensureError(t, s.ReadOCF(ocfr))
+
+	if actual, expected := fmt.Sprint(s.Sample()), "[0 7 14 21 28 35 42 49]"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if actual, expected := s.Seen(), int64(50); actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
+
+func TestSamplerMatchesOffer(t *testing.T) {
+	// Skipping data items without decoding them ought to select the same data
+	// items as offering each of them.
+	newSamplers := []func() (*Sampler, error){
+		func() (*Sampler, error) { return NewEveryNthSampler(15) },
+		func() (*Sampler, error) { return NewProbabilitySampler(0.05, 42) },
+		func() (*Sampler, error) { return NewReservoirSampler(20, 42) },
+	}
+	const n = 1000
+	buf := sampleOCF(t, n)
+	for _, newSampler := range newSamplers {
+		offered, err := newSampler()
+		ensureError(t, err)
+		for i := int64(0); i < n; i++ {
+			offered.Offer(i)
+		}
+
+		read, err := newSampler()
+		ensureError(t, err)
+		ocfr, err := NewOCFReader(bytes.NewReader(buf))
+		ensureError(t, err)
+		ensureError(t, read.ReadOCF(ocfr))
+
+		codec, err := NewCodec(`"long"`)
+		ensureError(t, err)
+		var stream []byte
+		for i := int64(0); i < n; i++ {
+			stream, err = codec.BinaryFromNative(stream, i)
+			ensureError(t, err)
+		}
+		binary, err := newSampler()
+		ensureError(t, err)
+		ensureError(t, binary.ReadBinary(codec, stream))
+
+		expected := fmt.Sprint(offered.Sample())
+		if actual := fmt.Sprint(read.Sample()); actual != expected {
+			t.Errorf("GOT: %v; WANT: %v", actual, expected)
+		}
+		if actual := fmt.Sprint(binary.Sample()); actual != expected {
+			t.Errorf("GOT: %v; WANT: %v", actual, expected)
+		}
+		if read.Seen() != n || binary.Seen() != n {
+			t.Errorf("GOT: %v, %v; WANT: %v", read.Seen(), binary.Seen(), n)
+		}
+	}
+}
+
+func TestSamplerProbability(t *testing.T) {
+	s, err := NewProbabilitySampler(0.1, 1)
+	ensureError(t, err)
+	for i := 0; i < 10000; i++ {
+		s.Offer(i)
+	}
+	if actual := len(s.Sample()); actual < 850 || actual > 1150 {
+		t.Errorf("GOT: %v; WANT: about 1000", actual)
+	}
+
+	s, err = NewProbabilitySampler(1, 1)
+	ensureError(t, err)
+	for i := 0; i < 10; i++ {
+		s.Offer(i)
+	}
+	if actual, expected := len(s.Sample()), 10; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
+
+func TestSamplerReservoir(t *testing.T) {
+	s, err := NewReservoirSampler(5, 1)
+	ensureError(t, err)
+	for i := 0; i < 3; i++ {
+		s.Offer(i)
+	}
+	if actual, expected := fmt.Sprint(s.Sample()), "[0 1 2]"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	// Each data item ought to be kept about as often as the others.
+	counts := make([]int, 10)
+	for seed := int64(0); seed < 2000; seed++ {
+		s, err = NewReservoirSampler(2, seed)
+		ensureError(t, err)
+		for i := 0; i < len(counts); i++ {
+			s.Offer(i)
+		}
+		for _, datum := range s.Sample() {
+			counts[datum.(int)]++
+		}
+	}
+	for i, count := range counts {
+		if count < 300 || count > 500 {
+			t.Errorf("item %d: GOT: %v; WANT: about 400", i, count)
+		}
+	}
+}
+
+func TestSamplerErrors(t *testing.T) {
+	_, err := NewEveryNthSampler(0)
+	ensureError(t, err, "n ought to be greater than 0")
+	_, err = NewProbabilitySampler(0, 1)
+	ensureError(t, err, "probability ought to be greater than 0 and at most 1")
+	_, err = NewProbabilitySampler(1.5, 1)
+	ensureError(t, err, "probability ought to be greater than 0 and at most 1")
+	_, err = NewReservoirSampler(0, 1)
+	ensureError(t, err, "reservoir size ought to be greater than 0")
+
+	s, err := NewEveryNthSampler(3)
+	ensureError(t, err)
+	s.Offer(0)
+	ensureError(t, s.Pass(3), "cannot pass data items: ought to be from 0 to 2: 3")
+	ensureError(t, s.Pass(2))
+	if actual, expected := s.Skip(), int64(0); actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	codec, err := NewCodec(`"string"`)
+	ensureError(t, err)
+	ensureError(t, s.ReadBinary(codec, []byte{0x08, 'a'}), "cannot sample binary: data item 3")
+}
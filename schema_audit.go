@@ -0,0 +1,244 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// RegisteredSchema is a schema registered in a schema registry, or read from
+// a file, in which case the Subject is usually the name of the file. Its JSON
+// form matches the versions returned by the Confluent Schema Registry API.
+type RegisteredSchema struct {
+	Subject string `json:"subject"`
+	Version int    `json:"version,omitempty"`
+	ID      int    `json:"id,omitempty"`
+	Schema  string `json:"schema"`
+}
+
+// label returns the description of the registered schema used in messages.
+func (rs RegisteredSchema) label() string {
+	var b strings.Builder
+	b.WriteString(rs.Subject)
+	if rs.Version != 0 {
+		fmt.Fprintf(&b, " version %d", rs.Version)
+	}
+	if rs.ID != 0 {
+		fmt.Fprintf(&b, " (id %d)", rs.ID)
+	}
+	return b.String()
+}
+
+// RegisteredSchemasFromJSON reads a registry dump, which is a JSON array of
+// RegisteredSchema objects.
+func RegisteredSchemasFromJSON(ior io.Reader) ([]RegisteredSchema, error) {
+	var schemas []RegisteredSchema
+	if err := json.NewDecoder(ior).Decode(&schemas); err != nil {
+		return nil, fmt.Errorf("cannot read registered schemas: %s", err)
+	}
+	return schemas, nil
+}
+
+// SchemaAuditKind classifies a SchemaAuditIssue.
+type SchemaAuditKind string
+
+const (
+	// SchemaAuditInvalid schemas cannot be compiled into a Codec.
+	SchemaAuditInvalid SchemaAuditKind = "invalid"
+
+	// SchemaAuditCollision schemas have different Parsing Canonical Forms
+	// but the same Rabin fingerprint, so consumers that look schemas up by
+	// fingerprint, such as readers of single-object encoded data, cannot
+	// tell them apart.
+	SchemaAuditCollision SchemaAuditKind = "collision"
+
+	// SchemaAuditDuplicate schemas have the same Parsing Canonical Form, and
+	// are registered under different subjects.
+	SchemaAuditDuplicate SchemaAuditKind = "duplicate"
+
+	// SchemaAuditConflict schemas have different Parsing Canonical Forms,
+	// and are registered with the same ID, or as the same version of the
+	// same subject.
+	SchemaAuditConflict SchemaAuditKind = "conflict"
+)
+
+// SchemaAuditIssue describes an inconsistency among registered schemas.
+type SchemaAuditIssue struct {
+	Kind        SchemaAuditKind    `json:"kind"`
+	Fingerprint uint64             `json:"fingerprint,omitempty"` // Rabin fingerprint shared by the schemas, when any
+	Schemas     []RegisteredSchema `json:"schemas"`               // schemas involved, in the order they were audited
+	Message     string             `json:"message"`
+}
+
+// String returns the message of the issue, prefixed by its kind.
+func (issue SchemaAuditIssue) String() string {
+	return string(issue.Kind) + ": " + issue.Message
+}
+
+// SchemaAudit reports the inconsistencies found among a set of registered
+// schemas by AuditSchemas.
+type SchemaAudit struct {
+	Schemas      int                `json:"schemas"`      // number of schemas audited
+	Fingerprints int                `json:"fingerprints"` // number of distinct fingerprints of valid schemas
+	Issues       []SchemaAuditIssue `json:"issues"`
+}
+
+// String returns one line for each issue of the audit.
+func (audit *SchemaAudit) String() string {
+	var b strings.Builder
+	for _, issue := range audit.Issues {
+		b.WriteString(issue.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// AuditSchemas checks the consistency of registered schemas, such as the
+// schemas of a registry dump or of a directory of schema files, so registry
+// hygiene may be audited automatically. It verifies the Rabin fingerprints of
+// distinct schemas are unique, detects schemas registered under several
+// subjects, and detects IDs and subject versions registered with different
+// schemas. Issues are reported in the order of the schema that first shows
+// them.
+//
+//     schemas, err := goavro.RegisteredSchemasFromJSON(dump)
+//     if err != nil {
+//         return err
+//     }
+//     audit := goavro.AuditSchemas(schemas)
+//     for _, issue := range audit.Issues {
+//         fmt.Println(issue)
+//     }
+func AuditSchemas(schemas []RegisteredSchema) *SchemaAudit {
+	audit := &SchemaAudit{Schemas: len(schemas)}
+
+	type group struct {
+		first   int
+		kind    SchemaAuditKind
+		key     string
+		members []int
+	}
+	var groups []*group
+	indexes := make(map[string]*group)
+	add := func(kind SchemaAuditKind, key string, i int) {
+		k := string(kind) + "\x00" + key
+		g, ok := indexes[k]
+		if !ok {
+			g = &group{first: i, kind: kind, key: key}
+			indexes[k] = g
+			groups = append(groups, g)
+		}
+		g.members = append(g.members, i)
+	}
+
+	canonical := make([]string, len(schemas))
+	fingerprints := make(map[string]uint64)
+	type positioned struct {
+		at    int // position of the first schema of the issue
+		issue SchemaAuditIssue
+	}
+	var issues []positioned
+	for i, rs := range schemas {
+		codec, err := NewCodec(rs.Schema)
+		if err != nil {
+			issues = append(issues, positioned{at: i, issue: SchemaAuditIssue{
+				Kind:    SchemaAuditInvalid,
+				Schemas: []RegisteredSchema{rs},
+				Message: fmt.Sprintf("%s: %s", rs.label(), err),
+			}})
+			continue
+		}
+		canonical[i] = codec.CanonicalSchema()
+		fingerprints[canonical[i]] = codec.Rabin
+		add(SchemaAuditCollision, fmt.Sprint(codec.Rabin), i)
+		add(SchemaAuditDuplicate, canonical[i], i)
+		if rs.ID != 0 {
+			add(SchemaAuditConflict, fmt.Sprintf("id %d", rs.ID), i)
+		}
+		if rs.Version != 0 {
+			add(SchemaAuditConflict, fmt.Sprintf("%s version %d", rs.Subject, rs.Version), i)
+		}
+	}
+	distinct := make(map[uint64]struct{}, len(fingerprints))
+	for _, fingerprint := range fingerprints {
+		distinct[fingerprint] = struct{}{}
+	}
+	audit.Fingerprints = len(distinct)
+
+	for _, g := range groups {
+		var issue SchemaAuditIssue
+		switch g.kind {
+		case SchemaAuditCollision:
+			// NOTE: Only schemas with different canonical forms collide.
+			forms := make(map[string]struct{})
+			for _, i := range g.members {
+				forms[canonical[i]] = struct{}{}
+			}
+			if len(forms) < 2 {
+				continue
+			}
+			issue.Fingerprint = fingerprints[canonical[g.first]]
+			issue.Message = fmt.Sprintf("%d distinct schemas have fingerprint %#016x: %s", len(forms), issue.Fingerprint, auditLabels(schemas, g.members))
+		case SchemaAuditDuplicate:
+			subjects := make(map[string]struct{})
+			var members []int
+			for _, i := range g.members {
+				if _, ok := subjects[schemas[i].Subject]; !ok {
+					subjects[schemas[i].Subject] = struct{}{}
+					members = append(members, i)
+				}
+			}
+			if len(members) < 2 {
+				continue
+			}
+			g.members = members
+			issue.Fingerprint = fingerprints[canonical[g.first]]
+			issue.Message = fmt.Sprintf("schema with fingerprint %#016x is registered under %d subjects: %s", issue.Fingerprint, len(members), auditLabels(schemas, members))
+		case SchemaAuditConflict:
+			conflicting := false
+			for _, i := range g.members[1:] {
+				if canonical[i] != canonical[g.first] {
+					conflicting = true
+					break
+				}
+			}
+			if !conflicting {
+				continue
+			}
+			issue.Message = fmt.Sprintf("%s is registered with different schemas: %s", g.key, auditLabels(schemas, g.members))
+		}
+		issue.Kind = g.kind
+		for _, i := range g.members {
+			issue.Schemas = append(issue.Schemas, schemas[i])
+		}
+		issues = append(issues, positioned{at: g.first, issue: issue})
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].at < issues[j].at })
+	audit.Issues = make([]SchemaAuditIssue, len(issues))
+	for i, p := range issues {
+		audit.Issues[i] = p.issue
+	}
+	return audit
+}
+
+// auditLabels returns the labels of the specified schemas, separated by
+// commas.
+func auditLabels(schemas []RegisteredSchema, members []int) string {
+	labels := make([]string, len(members))
+	for i, m := range members {
+		labels[i] = schemas[m].label()
+	}
+	return strings.Join(labels, ", ")
+}
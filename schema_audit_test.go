@@ -0,0 +1,69 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAuditSchemas(t *testing.T) {
+	dump := `[
+	  {"subject": "orders-value", "version": 1, "id": 1, "schema": "{\"type\":\"record\",\"name\":\"Order\",\"fields\":[{\"name\":\"id\",\"type\":\"long\"}]}"},
+	  {"subject": "orders-value", "version": 2, "id": 2, "schema": "{\"type\":\"record\",\"name\":\"Order\",\"fields\":[{\"name\":\"id\",\"type\":\"long\"},{\"name\":\"note\",\"type\":\"string\",\"default\":\"\"}]}"},
+	  {"subject": "legacy-orders", "version": 1, "id": 1, "schema": "{\"type\":\"record\",\"name\":\"Order\",\"doc\":\"copy\",\"fields\":[{\"name\":\"id\",\"type\":\"long\"}]}"},
+	  {"subject": "payments-value", "version": 1, "id": 2, "schema": "\"string\""},
+	  {"subject": "broken-value", "version": 1, "id": 3, "schema": "{\"type\":\"record\"}"}
+	]`
+	schemas, err := RegisteredSchemasFromJSON(strings.NewReader(dump))
+	ensureError(t, err)
+	audit := AuditSchemas(schemas)
+
+	if audit.Schemas != 5 || audit.Fingerprints != 3 {
+		t.Errorf("GOT: %d schemas, %d fingerprints; WANT: 5 schemas, 3 fingerprints", audit.Schemas, audit.Fingerprints)
+	}
+	var kinds []string
+	for _, issue := range audit.Issues {
+		kinds = append(kinds, string(issue.Kind))
+	}
+	if actual, expected := strings.Join(kinds, " "), "duplicate conflict invalid"; actual != expected {
+		t.Fatalf("GOT: %v; WANT: %v\n%s", actual, expected, audit)
+	}
+
+	duplicate := audit.Issues[0]
+	if len(duplicate.Schemas) != 2 || duplicate.Schemas[1].Subject != "legacy-orders" || duplicate.Fingerprint == 0 {
+		t.Errorf("GOT: %+v; WANT: orders-value and legacy-orders", duplicate)
+	}
+	if !strings.Contains(duplicate.Message, "registered under 2 subjects: orders-value version 1 (id 1), legacy-orders version 1 (id 1)") {
+		t.Errorf("GOT: %q", duplicate.Message)
+	}
+	if actual, expected := audit.Issues[1].String(), "conflict: id 2 is registered with different schemas: orders-value version 2 (id 2), payments-value version 1 (id 2)"; actual != expected {
+		t.Errorf("GOT: %q; WANT: %q", actual, expected)
+	}
+	if !strings.HasPrefix(audit.Issues[2].Message, "broken-value version 1 (id 3): ") {
+		t.Errorf("GOT: %q", audit.Issues[2].Message)
+	}
+}
+
+func TestAuditSchemasConsistent(t *testing.T) {
+	audit := AuditSchemas([]RegisteredSchema{
+		{Subject: "a", Version: 1, ID: 1, Schema: `"int"`},
+		{Subject: "a", Version: 2, ID: 1, Schema: `{"type":"int"}`}, // same schema registered again
+		{Subject: "b", Version: 1, ID: 2, Schema: `"long"`},
+	})
+	if len(audit.Issues) != 0 || audit.Fingerprints != 2 {
+		t.Errorf("GOT: %d fingerprints, issues:\n%s", audit.Fingerprints, audit)
+	}
+}
+
+func TestRegisteredSchemasFromJSONError(t *testing.T) {
+	_, err := RegisteredSchemasFromJSON(strings.NewReader(`{"subject":"a"}`))
+	ensureError(t, err, "cannot read registered schemas")
+}
@@ -0,0 +1,222 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// SchemaBundle is a pinned set of schemas exported from a schema registry, from
+// which messages are decoded without querying the registry, such as in air
+// gapped environments, or while the registry is unavailable. A message whose
+// schema is not in the bundle is rejected with an *UnknownSchemaError, rather
+// than looked up elsewhere. A SchemaBundle is safe for concurrent use.
+//
+//     f, err := os.Open("schemas.json") // from GET /schemas of the registry
+//     if err != nil {
+//         return err
+//     }
+//     bundle, err := goavro.ReadSchemaBundle(f)
+//     f.Close()
+//     if err != nil {
+//         return err
+//     }
+//     for _, message := range messages {
+//         codec, datum, err := bundle.Decode(message)
+//         if err != nil {
+//             return err
+//         }
+//         // ...
+//     }
+type SchemaBundle struct {
+	ids          map[uint32]*Codec
+	fingerprints map[uint64]*Codec
+}
+
+// schemaBundleEntry is an item of the JSON array returned by the GET /schemas
+// endpoint of a Confluent Schema Registry.
+type schemaBundleEntry struct {
+	Subject    string                  `json:"subject"`
+	Version    int                     `json:"version"`
+	ID         uint32                  `json:"id"`
+	SchemaType string                  `json:"schemaType"`
+	Schema     string                  `json:"schema"`
+	References []schemaBundleReference `json:"references"`
+}
+
+type schemaBundleReference struct {
+	Name    string `json:"name"`
+	Subject string `json:"subject"`
+	Version int    `json:"version"`
+}
+
+type schemaBundleVersion struct {
+	subject string
+	version int
+}
+
+// ReadSchemaBundle reads a schema bundle from r, and builds the codec of each
+// of its schemas using the specified options. The bundle is a JSON array of
+// objects with the subject, version, id, schema, and optionally schemaType and
+// references properties, as returned by the GET /schemas endpoint of a
+// Confluent Schema Registry. Schemas referencing named types of other schemas
+// are built with the referenced schemas, which ought to be in the bundle. A
+// bundle holding schemas other than Avro schemas, or holding different
+// schemas with the same ID, is rejected.
+func ReadSchemaBundle(r io.Reader, opts ...Option) (*SchemaBundle, error) {
+	var entries []schemaBundleEntry
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("cannot read schema bundle: %s", err)
+	}
+	versions := make(map[schemaBundleVersion]*schemaBundleEntry, len(entries))
+	for i := range entries {
+		entry := &entries[i]
+		if entry.SchemaType != "" && entry.SchemaType != "AVRO" {
+			return nil, fmt.Errorf("cannot read schema bundle: schema ID %d ought to be an Avro schema; received: %q", entry.ID, entry.SchemaType)
+		}
+		versions[schemaBundleVersion{entry.Subject, entry.Version}] = entry
+	}
+
+	b := &SchemaBundle{ids: make(map[uint32]*Codec), fingerprints: make(map[uint64]*Codec)}
+	for i := range entries {
+		entry := &entries[i]
+		codec, err := buildSchemaBundleCodec(entry, versions, opts)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read schema bundle: subject %q version %d: %s", entry.Subject, entry.Version, err)
+		}
+		if other, ok := b.ids[entry.ID]; ok {
+			// NOTE: A schema registered under several subjects has the
+			// same ID under each of them.
+			if other.Rabin != codec.Rabin {
+				return nil, fmt.Errorf("cannot read schema bundle: schema ID %d ought to identify a single schema", entry.ID)
+			}
+			continue
+		}
+		b.ids[entry.ID] = codec
+		b.fingerprints[codec.Rabin] = codec
+	}
+	return b, nil
+}
+
+// buildSchemaBundleCodec builds the codec of the schema of the entry, along
+// with the schemas it references, if any.
+func buildSchemaBundleCodec(entry *schemaBundleEntry, versions map[schemaBundleVersion]*schemaBundleEntry, opts []Option) (*Codec, error) {
+	if len(entry.References) == 0 {
+		return NewCodec(entry.Schema, opts...)
+	}
+	registry := NewCodecRegistry(opts...)
+	added := make(map[schemaBundleVersion]bool)
+	var add func(references []schemaBundleReference) error
+	add = func(references []schemaBundleReference) error {
+		for _, reference := range references {
+			key := schemaBundleVersion{reference.Subject, reference.Version}
+			if done, ok := added[key]; ok {
+				if !done {
+					return fmt.Errorf("circular reference to subject %q version %d", reference.Subject, reference.Version)
+				}
+				continue
+			}
+			referenced, ok := versions[key]
+			if !ok {
+				return fmt.Errorf("referenced subject %q version %d ought to be in the bundle", reference.Subject, reference.Version)
+			}
+			added[key] = false
+			if err := add(referenced.References); err != nil {
+				return err
+			}
+			if _, err := registry.Add(referenced.Schema); err != nil {
+				return fmt.Errorf("referenced subject %q version %d: %s", reference.Subject, reference.Version, err)
+			}
+			added[key] = true
+		}
+		return nil
+	}
+	if err := add(entry.References); err != nil {
+		return nil, err
+	}
+	return registry.Codec(entry.Schema)
+}
+
+// UnknownSchemaError is the error returned by SchemaBundle when a message
+// refers to a schema the bundle does not hold.
+type UnknownSchemaError struct {
+	Encoding    Encoding // EncodingSingleObject or EncodingConfluent
+	Fingerprint uint64   // Rabin fingerprint of the schema of a single-object encoded message
+	SchemaID    uint32   // schema registry ID of the schema of a Confluent framed message
+}
+
+func (e *UnknownSchemaError) Error() string {
+	if e.Encoding == EncodingConfluent {
+		return fmt.Sprintf("unknown schema ID: %d", e.SchemaID)
+	}
+	return fmt.Sprintf("unknown schema fingerprint: %#016x", e.Fingerprint)
+}
+
+// Codec returns the codec of the schema with the schema registry ID.
+func (b *SchemaBundle) Codec(id uint32) (*Codec, error) {
+	if codec, ok := b.ids[id]; ok {
+		return codec, nil
+	}
+	return nil, &UnknownSchemaError{Encoding: EncodingConfluent, SchemaID: id}
+}
+
+// CodecByFingerprint returns the codec of the schema with the Rabin
+// fingerprint.
+func (b *SchemaBundle) CodecByFingerprint(fingerprint uint64) (*Codec, error) {
+	if codec, ok := b.fingerprints[fingerprint]; ok {
+		return codec, nil
+	}
+	return nil, &UnknownSchemaError{Encoding: EncodingSingleObject, Fingerprint: fingerprint}
+}
+
+// IDs returns the schema registry IDs of the schemas of the bundle, in order.
+func (b *SchemaBundle) IDs() []uint32 {
+	ids := make([]uint32, 0, len(b.ids))
+	for id := range b.ids {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// Decode returns the codec of the schema that encoded the single-object
+// encoded or Confluent framed message, and the data item it holds, which ought
+// to be the only content of the message. When the bundle does not hold the
+// schema, the error is an *UnknownSchemaError.
+func (b *SchemaBundle) Decode(message []byte) (*Codec, interface{}, error) {
+	var codec *Codec
+	var err error
+	detected := DetectEncoding(message)
+	switch detected.Encoding {
+	case EncodingSingleObject:
+		codec, err = b.CodecByFingerprint(detected.Fingerprint)
+	case EncodingConfluent:
+		codec, err = b.Codec(detected.SchemaID)
+	default:
+		return nil, nil, fmt.Errorf("cannot decode message: ought to be single-object encoded or Confluent framed; received: %s", detected.Encoding)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	datum, rest, err := codec.NativeFromBinary(detected.Datum)
+	if err != nil {
+		return codec, nil, fmt.Errorf("cannot decode message: %s", err)
+	}
+	if len(rest) > 0 {
+		return codec, nil, fmt.Errorf("cannot decode message: extra bytes after data item: %d", len(rest))
+	}
+	return codec, datum, nil
+}
@@ -0,0 +1,93 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const testSchemaBundle = `[
+  {"subject":"address","version":1,"id":3,"schema":"{\"type\":\"record\",\"name\":\"com.acme.Address\",\"fields\":[{\"name\":\"city\",\"type\":\"string\"}]}"},
+  {"subject":"user","version":1,"id":4,"schemaType":"AVRO","schema":"{\"type\":\"record\",\"name\":\"com.acme.User\",\"fields\":[{\"name\":\"home\",\"type\":\"Address\"}]}",
+   "references":[{"name":"com.acme.Address","subject":"address","version":1}]},
+  {"subject":"legacy-address","version":2,"id":3,"schema":"{\"type\":\"record\",\"name\":\"com.acme.Address\",\"fields\":[{\"name\":\"city\",\"type\":\"string\"}]}"}
+]`
+
+func TestSchemaBundle(t *testing.T) {
+	bundle, err := ReadSchemaBundle(strings.NewReader(testSchemaBundle))
+	ensureError(t, err)
+	if actual, expected := fmt.Sprint(bundle.IDs()), "[3 4]"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	user, err := bundle.Codec(4)
+	ensureError(t, err)
+	datum := map[string]interface{}{"home": map[string]interface{}{"city": "Oslo"}}
+	message, err := user.BinaryFromNative([]byte{0, 0, 0, 0, 4}, datum)
+	ensureError(t, err)
+	codec, decoded, err := bundle.Decode(message)
+	ensureError(t, err)
+	if codec != user {
+		t.Errorf("GOT: %p; WANT: %p", codec, user)
+	}
+	if actual, expected := fmt.Sprint(decoded), "map[home:map[city:Oslo]]"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	message, err = user.SingleFromNative(nil, datum)
+	ensureError(t, err)
+	_, _, err = bundle.Decode(message)
+	ensureError(t, err)
+
+	_, _, err = bundle.Decode(append(message, 0))
+	ensureError(t, err, "extra bytes after data item: 1")
+}
+
+func TestSchemaBundleUnknownSchema(t *testing.T) {
+	bundle, err := ReadSchemaBundle(strings.NewReader(testSchemaBundle))
+	ensureError(t, err)
+
+	_, _, err = bundle.Decode([]byte{0, 0, 0, 0, 9, 0})
+	unknown, ok := err.(*UnknownSchemaError)
+	if !ok {
+		t.Fatalf("GOT: %#v; WANT: *UnknownSchemaError", err)
+	}
+	if actual, expected := *unknown, (UnknownSchemaError{Encoding: EncodingConfluent, SchemaID: 9}); actual != expected {
+		t.Errorf("GOT: %+v; WANT: %+v", actual, expected)
+	}
+	ensureError(t, err, "unknown schema ID: 9")
+
+	_, err = bundle.CodecByFingerprint(1)
+	ensureError(t, err, "unknown schema fingerprint: 0x0000000000000001")
+
+	_, _, err = bundle.Decode([]byte("Obj\x01"))
+	ensureError(t, err, "ought to be single-object encoded or Confluent framed; received: ocf")
+}
+
+func TestReadSchemaBundleErrors(t *testing.T) {
+	for _, tc := range []struct {
+		bundle string
+		reason string
+	}{
+		{`{}`, "cannot read schema bundle"},
+		{`[{"subject":"s","version":1,"id":1,"schemaType":"PROTOBUF","schema":"syntax = \"proto3\";"}]`, "schema ID 1 ought to be an Avro schema"},
+		{`[{"subject":"s","version":1,"id":1,"schema":"\"int\""},{"subject":"t","version":1,"id":1,"schema":"\"long\""}]`, "schema ID 1 ought to identify a single schema"},
+		{`[{"subject":"s","version":1,"id":1,"schema":"\"Missing\"","references":[{"name":"Missing","subject":"m","version":1}]}]`, "referenced subject \"m\" version 1 ought to be in the bundle"},
+		{`[{"subject":"s","version":1,"id":1,"schema":"{\"type\":\"record\"}"}]`, "subject \"s\" version 1"},
+	} {
+		_, err := ReadSchemaBundle(strings.NewReader(tc.bundle))
+		ensureError(t, err, tc.reason)
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SchemaDocumentation is a structured description of an Avro schema, suitable
+// for generating data catalogs. It lists every named type declared by the
+// schema, and every field reachable from the top level schema, addressed by
+// its path.
+type SchemaDocumentation struct {
+	// Type describes the top level schema.
+	Type string `json:"type"`
+
+	// Doc is the documentation string of the top level schema, if any.
+	Doc string `json:"doc,omitempty"`
+
+	// Types lists the named types declared in the schema, in the order they
+	// were declared.
+	Types []SchemaTypeDoc `json:"types"`
+
+	// Fields lists the record fields reachable from the top level schema,
+	// depth first, in the order they were declared.
+	Fields []SchemaFieldDoc `json:"fields"`
+}
+
+// SchemaTypeDoc describes one named type of a schema.
+type SchemaTypeDoc struct {
+	Name       string                 `json:"name"`
+	Type       string                 `json:"type"`
+	Doc        string                 `json:"doc,omitempty"`
+	Aliases    []string               `json:"aliases,omitempty"`
+	Symbols    []string               `json:"symbols,omitempty"`
+	Size       int                    `json:"size,omitempty"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// SchemaFieldDoc describes one record field of a schema.
+//
+// Path is the dot separated list of field names leading to the field from the
+// top level record. Array items are denoted by appending "[]" to the path of
+// the array, and map values by appending "{}" to the path of the map. When a
+// union has more than one record member, the full name of the member is
+// appended to the path in parentheses to keep paths unambiguous.
+type SchemaFieldDoc struct {
+	Path        string                 `json:"path"`
+	Type        string                 `json:"type"`
+	Doc         string                 `json:"doc,omitempty"`
+	HasDefault  bool                   `json:"hasDefault"`
+	Default     interface{}            `json:"default,omitempty"`
+	LogicalType string                 `json:"logicalType,omitempty"`
+	Attributes  map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// SchemaDocumentation walks the schema of the Codec and returns its structured
+// documentation.
+//
+//     doc, err := codec.SchemaDocumentation()
+//     if err != nil {
+//         return err
+//     }
+//     fmt.Println(doc.Markdown())
+func (c *Codec) SchemaDocumentation() (*SchemaDocumentation, error) {
+	root, err := schemaNodeFromCodec(c)
+	if err != nil {
+		return nil, fmt.Errorf("cannot document schema: %s", err)
+	}
+	d := &SchemaDocumentation{Type: root.label(), Doc: root.doc, Types: []SchemaTypeDoc{}, Fields: []SchemaFieldDoc{}}
+	d.addNode(root, "", make(map[*schemaNode]struct{}))
+	return d, nil
+}
+
+// addNode documents the node, and the fields reachable from it. Each named
+// type is only expanded once, which also prevents infinite recursion for
+// recursive schemas.
+func (d *SchemaDocumentation) addNode(n *schemaNode, path string, seen map[*schemaNode]struct{}) {
+	switch n.typeName {
+	case "array":
+		d.addNode(n.items, path+"[]", seen)
+		return
+	case "map":
+		d.addNode(n.values, path+"{}", seen)
+		return
+	case "union":
+		var records int
+		for _, member := range n.members {
+			if member.typeName == "record" {
+				records++
+			}
+		}
+		for _, member := range n.members {
+			memberPath := path
+			if records > 1 && member.typeName == "record" {
+				memberPath += "(" + member.fullName + ")"
+			}
+			d.addNode(member, memberPath, seen)
+		}
+		return
+	}
+	if !n.isNamed() {
+		return
+	}
+	if _, ok := seen[n]; ok {
+		return
+	}
+	seen[n] = struct{}{}
+
+	d.Types = append(d.Types, SchemaTypeDoc{
+		Name:       n.fullName,
+		Type:       n.typeName,
+		Doc:        n.doc,
+		Aliases:    append([]string(nil), n.aliases...),
+		Symbols:    append([]string(nil), n.symbols...),
+		Size:       n.size,
+		Attributes: copyAttributes(n.attributes),
+	})
+
+	for _, f := range n.fields {
+		fieldPath := f.name
+		if path != "" {
+			fieldPath = path + "." + f.name
+		}
+		d.Fields = append(d.Fields, SchemaFieldDoc{
+			Path:        fieldPath,
+			Type:        f.node.label(),
+			Doc:         f.doc,
+			HasDefault:  f.hasDefault,
+			Default:     copyNative(f.defaultValue),
+			LogicalType: f.node.logicalType,
+			Attributes:  copyAttributes(f.attributes),
+		})
+		d.addNode(f.node, fieldPath, seen)
+	}
+}
+
+// JSON returns the documentation encoded as JSON.
+func (d *SchemaDocumentation) JSON() ([]byte, error) {
+	return json.Marshal(d)
+}
+
+// Markdown returns the documentation formatted as a Markdown document, with a
+// table of fields followed by a section for each named type.
+func (d *SchemaDocumentation) Markdown() string {
+	var sb strings.Builder
+
+	sb.WriteString("# " + markdownEscape(d.Type) + "\n")
+	if d.Doc != "" {
+		sb.WriteString("\n" + d.Doc + "\n")
+	}
+
+	if len(d.Fields) > 0 {
+		sb.WriteString("\n## Fields\n\n| Path | Type | Default | Doc | Attributes |\n| --- | --- | --- | --- | --- |\n")
+		for _, f := range d.Fields {
+			var defaultValue string
+			if f.HasDefault {
+				defaultValue = "`" + markdownJSON(f.Default) + "`"
+			}
+			fmt.Fprintf(&sb, "| `%s` | `%s` | %s | %s | %s |\n", f.Path, f.Type, markdownEscape(defaultValue), markdownEscape(f.Doc), markdownEscape(markdownAttributes(f.Attributes)))
+		}
+	}
+
+	if len(d.Types) > 0 {
+		sb.WriteString("\n## Types\n")
+		for _, t := range d.Types {
+			fmt.Fprintf(&sb, "\n### %s (%s)\n", markdownEscape(t.Name), t.Type)
+			if t.Doc != "" {
+				sb.WriteString("\n" + t.Doc + "\n")
+			}
+			if len(t.Aliases) > 0 {
+				sb.WriteString("\nAliases: " + strings.Join(t.Aliases, ", ") + "\n")
+			}
+			if len(t.Symbols) > 0 {
+				sb.WriteString("\nSymbols: " + strings.Join(t.Symbols, ", ") + "\n")
+			}
+			if t.Size > 0 {
+				fmt.Fprintf(&sb, "\nSize: %d\n", t.Size)
+			}
+			if len(t.Attributes) > 0 {
+				sb.WriteString("\nAttributes: " + markdownAttributes(t.Attributes) + "\n")
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+// markdownAttributes formats custom attributes as a sorted list of key=value
+// pairs.
+func markdownAttributes(attributes map[string]interface{}) string {
+	keys := make([]string, 0, len(attributes))
+	for k := range attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + markdownJSON(attributes[k])
+	}
+	return strings.Join(pairs, ", ")
+}
+
+func markdownJSON(v interface{}) string {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(buf)
+}
+
+// markdownEscape escapes characters that would otherwise break a Markdown
+// table cell.
+func markdownEscape(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
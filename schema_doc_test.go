@@ -0,0 +1,127 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"strings"
+	"testing"
+)
+
+const testSchemaDocumentation = `{
+  "type": "record",
+  "name": "Event",
+  "namespace": "com.example",
+  "doc": "An event.",
+  "fields": [
+    {"name": "id", "type": "string", "doc": "Unique identifier.", "sensitivity": "low"},
+    {"name": "ts", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "kind", "type": {"type": "enum", "name": "Kind", "symbols": ["A", "B"]}, "default": "A"},
+    {"name": "tags", "type": {"type": "array", "items": {"type": "record", "name": "Tag", "fields": [
+      {"name": "key", "type": "string"}
+    ]}}},
+    {"name": "next", "type": ["null", "Event"], "default": null}
+  ]
+}`
+
+func TestSchemaDocumentationFields(t *testing.T) {
+	codec := newCodecUsingV2(t, testSchemaDocumentation)
+	doc, err := codec.SchemaDocumentation()
+	ensureError(t, err)
+
+	if got, want := doc.Doc, "An event."; got != want {
+		t.Errorf("GOT: %q; WANT: %q", got, want)
+	}
+
+	var paths []string
+	for _, f := range doc.Fields {
+		paths = append(paths, f.Path+":"+f.Type)
+	}
+	if got, want := strings.Join(paths, " "), "id:string ts:long (timestamp-millis) kind:com.example.Kind tags:array<com.example.Tag> tags[].key:string next:union<null,com.example.Event>"; got != want {
+		t.Errorf("GOT: %q; WANT: %q", got, want)
+	}
+
+	id := doc.Fields[0]
+	if got, want := id.Doc, "Unique identifier."; got != want {
+		t.Errorf("GOT: %q; WANT: %q", got, want)
+	}
+	if got, want := id.Attributes["sensitivity"], "low"; got != want {
+		t.Errorf("GOT: %v; WANT: %v", got, want)
+	}
+	if got, want := doc.Fields[1].LogicalType, "timestamp-millis"; got != want {
+		t.Errorf("GOT: %q; WANT: %q", got, want)
+	}
+	if kind := doc.Fields[2]; !kind.HasDefault || kind.Default != "A" {
+		t.Errorf("GOT: %v %v; WANT: %v %v", kind.HasDefault, kind.Default, true, "A")
+	}
+
+	var types []string
+	for _, typ := range doc.Types {
+		types = append(types, typ.Name)
+	}
+	if got, want := strings.Join(types, " "), "com.example.Event com.example.Kind com.example.Tag"; got != want {
+		t.Errorf("GOT: %q; WANT: %q", got, want)
+	}
+}
+
+func TestSchemaDocumentationCopies(t *testing.T) {
+	codec := newCodecUsingV2(t, `{"type":"record","name":"r","aliases":["old"],"x-owner":"ops","fields":[
+		{"name":"e","type":{"type":"enum","name":"e","symbols":["A","B"]},"default":"A"},
+		{"name":"m","type":{"type":"map","values":"long"},"default":{"a":1},"x-tags":["pii"]}
+	]}`)
+	doc, err := codec.SchemaDocumentation()
+	ensureError(t, err)
+	doc.Types[0].Aliases[0] = "modified"
+	doc.Types[0].Attributes["x-owner"] = "modified"
+	doc.Types[1].Symbols[0] = "modified"
+	doc.Fields[1].Default.(map[string]interface{})["a"] = "modified"
+	doc.Fields[1].Attributes["x-tags"].([]interface{})[0] = "modified"
+
+	doc, err = codec.SchemaDocumentation()
+	ensureError(t, err)
+	if got, want := doc.Types[0].Aliases[0], "old"; got != want {
+		t.Errorf("GOT: %v; WANT: %v", got, want)
+	}
+	if got, want := doc.Types[0].Attributes["x-owner"], "ops"; got != want {
+		t.Errorf("GOT: %v; WANT: %v", got, want)
+	}
+	if got, want := doc.Types[1].Symbols[0], "A"; got != want {
+		t.Errorf("GOT: %v; WANT: %v", got, want)
+	}
+	if got, want := doc.Fields[1].Default.(map[string]interface{})["a"], float64(1); got != want {
+		t.Errorf("GOT: %v; WANT: %v", got, want)
+	}
+	if got, want := doc.Fields[1].Attributes["x-tags"].([]interface{})[0], "pii"; got != want {
+		t.Errorf("GOT: %v; WANT: %v", got, want)
+	}
+}
+
+func TestSchemaDocumentationFormats(t *testing.T) {
+	codec := newCodecUsingV2(t, testSchemaDocumentation)
+	doc, err := codec.SchemaDocumentation()
+	ensureError(t, err)
+
+	buf, err := doc.JSON()
+	ensureError(t, err)
+	if got, want := string(buf), `{"path":"tags[].key","type":"string","hasDefault":false}`; !strings.Contains(got, want) {
+		t.Errorf("GOT: %s; WANT: %s", got, want)
+	}
+
+	md := doc.Markdown()
+	for _, want := range []string{
+		"# com.example.Event\n",
+		"| `kind` | `com.example.Kind` | `\"A\"` |  |  |\n",
+		"| `id` | `string` |  | Unique identifier. | sensitivity=\"low\" |\n",
+		"### com.example.Kind (enum)\n\nSymbols: A, B\n",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("GOT: %s; WANT: %q", md, want)
+		}
+	}
+}
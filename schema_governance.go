@@ -0,0 +1,220 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Schema attributes of the governance metadata of a schema. They are
+// properties of the top level schema, which are ignored by other Avro
+// implementations:
+//
+//     {
+//       "type": "record",
+//       "name": "com.example.Click",
+//       "x-owner": "growth-team@example.com",
+//       "x-version": "3",
+//       "x-changelog": [
+//         {"version": "2", "date": "2019-04-01", "description": "add referrer"},
+//         {"version": "3", "date": "2019-06-12", "description": "deprecate session"}
+//       ],
+//       "fields": [...]
+//     }
+const (
+	SchemaOwnerAttribute     = "x-owner"
+	SchemaVersionAttribute   = "x-version"
+	SchemaChangelogAttribute = "x-changelog"
+)
+
+// OCF metadata keys of the governance metadata of the schema of an OCF, whose
+// values are the owner and version strings, and the JSON encoding of the
+// changelog.
+const (
+	OCFSchemaOwnerKey     = "goavro.schema.owner"
+	OCFSchemaVersionKey   = "goavro.schema.version"
+	OCFSchemaChangelogKey = "goavro.schema.changelog"
+)
+
+// SchemaChange is one entry of the changelog of a schema.
+type SchemaChange struct {
+	Version     string `json:"version"`
+	Date        string `json:"date,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// SchemaGovernance is the ownership, version, and changelog metadata of a
+// schema, so governance tooling may determine who owns a data stream and
+// which revision of its schema produced the data, whether it holds the schema
+// or an OCF. The metadata are embedded in the schema using the
+// SchemaOwnerAttribute, SchemaVersionAttribute, and SchemaChangelogAttribute
+// properties, and may also be recorded in the header of an OCF, such as when
+// the schema of the OCF is stripped of custom properties.
+//
+//     schema, err := goavro.EmbedSchemaGovernance(schema, goavro.SchemaGovernance{
+//         Owner:   "growth-team@example.com",
+//         Version: "3",
+//     })
+//     if err != nil {
+//         return err
+//     }
+//     codec, err := goavro.NewCodec(schema)
+//     if err != nil {
+//         return err
+//     }
+//     governance, err := codec.SchemaGovernance()
+//     if err != nil {
+//         return err
+//     }
+//     fmt.Println(governance.Owner, governance.Version)
+type SchemaGovernance struct {
+	Owner     string         `json:"owner,omitempty"`
+	Version   string         `json:"version,omitempty"`
+	Changelog []SchemaChange `json:"changelog,omitempty"`
+}
+
+// IsZero returns true when no governance metadata is set.
+func (g SchemaGovernance) IsZero() bool {
+	return g.Owner == "" && g.Version == "" && len(g.Changelog) == 0
+}
+
+// validate returns an error when a changelog entry has no version.
+func (g SchemaGovernance) validate() error {
+	for i, change := range g.Changelog {
+		if change.Version == "" {
+			return fmt.Errorf("changelog entry %d ought to have a version", i)
+		}
+	}
+	return nil
+}
+
+// SchemaGovernance returns the governance metadata embedded in the top level
+// schema of the Codec, which is zero when the schema embeds none.
+func (c *Codec) SchemaGovernance() (SchemaGovernance, error) {
+	root, err := schemaNodeFromCodec(c)
+	if err != nil {
+		return SchemaGovernance{}, fmt.Errorf("cannot read schema governance: %s", err)
+	}
+	g, err := schemaGovernanceFromAttributes(root.attributes)
+	if err != nil {
+		return SchemaGovernance{}, fmt.Errorf("cannot read schema governance: %s", err)
+	}
+	return g, nil
+}
+
+func schemaGovernanceFromAttributes(attributes map[string]interface{}) (SchemaGovernance, error) {
+	var g SchemaGovernance
+	var ok bool
+	if value, found := attributes[SchemaOwnerAttribute]; found {
+		if g.Owner, ok = value.(string); !ok {
+			return g, fmt.Errorf("%s ought to be a string; received: %T", SchemaOwnerAttribute, value)
+		}
+	}
+	if value, found := attributes[SchemaVersionAttribute]; found {
+		if g.Version, ok = value.(string); !ok {
+			return g, fmt.Errorf("%s ought to be a string; received: %T", SchemaVersionAttribute, value)
+		}
+	}
+	if value, found := attributes[SchemaChangelogAttribute]; found {
+		buf, err := json.Marshal(value)
+		if err != nil {
+			return g, fmt.Errorf("%s: %s", SchemaChangelogAttribute, err) // should not get here
+		}
+		if err = json.Unmarshal(buf, &g.Changelog); err != nil {
+			return g, fmt.Errorf("%s ought to be an array of changes: %s", SchemaChangelogAttribute, err)
+		}
+	}
+	if err := g.validate(); err != nil {
+		return g, fmt.Errorf("%s: %s", SchemaChangelogAttribute, err)
+	}
+	return g, nil
+}
+
+// EmbedSchemaGovernance returns the schema with the governance metadata
+// embedded in its top level schema, replacing the metadata it already embeds.
+// Empty members of the metadata remove the corresponding properties. A
+// primitive schema given by its type name is expanded to an object, while a
+// union schema cannot embed metadata.
+func EmbedSchemaGovernance(schema string, g SchemaGovernance) (string, error) {
+	if err := g.validate(); err != nil {
+		return "", fmt.Errorf("cannot embed schema governance: %s", err)
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(schema), &value); err != nil {
+		return "", fmt.Errorf("cannot embed schema governance: %s", err)
+	}
+	var schemaMap map[string]interface{}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		schemaMap = v
+	case string:
+		schemaMap = map[string]interface{}{"type": v}
+	default:
+		return "", fmt.Errorf("cannot embed schema governance: schema ought to be a JSON object or string; received: %T", value)
+	}
+	setOrDelete := func(key string, value interface{}, empty bool) {
+		if empty {
+			delete(schemaMap, key)
+		} else {
+			schemaMap[key] = value
+		}
+	}
+	setOrDelete(SchemaOwnerAttribute, g.Owner, g.Owner == "")
+	setOrDelete(SchemaVersionAttribute, g.Version, g.Version == "")
+	setOrDelete(SchemaChangelogAttribute, g.Changelog, len(g.Changelog) == 0)
+	buf, err := json.Marshal(schemaMap)
+	if err != nil {
+		return "", fmt.Errorf("cannot embed schema governance: %s", err) // should not get here
+	}
+	return string(buf), nil
+}
+
+// SchemaGovernanceFromMetaData returns the governance metadata recorded in
+// OCF metadata, which is zero when none are recorded.
+func SchemaGovernanceFromMetaData(metadata map[string][]byte) (SchemaGovernance, error) {
+	var g SchemaGovernance
+	g.Owner = string(metadata[OCFSchemaOwnerKey])
+	g.Version = string(metadata[OCFSchemaVersionKey])
+	if value, ok := metadata[OCFSchemaChangelogKey]; ok {
+		if err := json.Unmarshal(value, &g.Changelog); err != nil {
+			return SchemaGovernance{}, fmt.Errorf("cannot read schema governance: %s", err)
+		}
+	}
+	if err := g.validate(); err != nil {
+		return SchemaGovernance{}, fmt.Errorf("cannot read schema governance: %s", err)
+	}
+	return g, nil
+}
+
+// AddTo records the governance metadata in the metadata used to create an
+// OCF, replacing the governance metadata it already records.
+func (g SchemaGovernance) AddTo(metadata map[string][]byte) error {
+	if err := g.validate(); err != nil {
+		return fmt.Errorf("cannot record schema governance: %s", err)
+	}
+	delete(metadata, OCFSchemaOwnerKey)
+	delete(metadata, OCFSchemaVersionKey)
+	delete(metadata, OCFSchemaChangelogKey)
+	if g.Owner != "" {
+		metadata[OCFSchemaOwnerKey] = []byte(g.Owner)
+	}
+	if g.Version != "" {
+		metadata[OCFSchemaVersionKey] = []byte(g.Version)
+	}
+	if len(g.Changelog) > 0 {
+		value, err := json.Marshal(g.Changelog)
+		if err != nil {
+			return fmt.Errorf("cannot record schema governance: %s", err) // should not get here
+		}
+		metadata[OCFSchemaChangelogKey] = value
+	}
+	return nil
+}
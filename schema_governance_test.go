@@ -0,0 +1,76 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSchemaGovernanceEmbedded(t *testing.T) {
+	schema, err := EmbedSchemaGovernance(`{"type":"record","name":"Click","x-owner":"nobody","fields":[{"name":"url","type":"string"}]}`, SchemaGovernance{
+		Owner:     "growth-team@example.com",
+		Version:   "2",
+		Changelog: []SchemaChange{{Version: "2", Date: "2019-04-01", Description: "add url"}},
+	})
+	ensureError(t, err)
+	codec, err := NewCodec(schema)
+	ensureError(t, err)
+	g, err := codec.SchemaGovernance()
+	ensureError(t, err)
+	if actual, expected := fmt.Sprint(g), "{growth-team@example.com 2 [{2 2019-04-01 add url}]}"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	// empty members remove the properties
+	schema, err = EmbedSchemaGovernance(schema, SchemaGovernance{Version: "3"})
+	ensureError(t, err)
+	codec, err = NewCodec(schema)
+	ensureError(t, err)
+	g, err = codec.SchemaGovernance()
+	ensureError(t, err)
+	if actual, expected := fmt.Sprint(g), "{ 3 []}"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	schema, err = EmbedSchemaGovernance(`"long"`, SchemaGovernance{Owner: "ops"})
+	ensureError(t, err)
+	if actual, expected := schema, `{"type":"long","x-owner":"ops"}`; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	codec, err = NewCodec(`["null","long"]`)
+	ensureError(t, err)
+	g, err = codec.SchemaGovernance()
+	ensureError(t, err)
+	if !g.IsZero() {
+		t.Errorf("GOT: %v; WANT: zero", g)
+	}
+}
+
+func TestSchemaGovernanceErrors(t *testing.T) {
+	_, err := EmbedSchemaGovernance(`["null","long"]`, SchemaGovernance{Owner: "ops"})
+	ensureError(t, err, "cannot embed schema governance", "ought to be a JSON object or string")
+	_, err = EmbedSchemaGovernance(`"long"`, SchemaGovernance{Changelog: []SchemaChange{{Description: "oops"}}})
+	ensureError(t, err, "changelog entry 0 ought to have a version")
+
+	codec, err := NewCodec(`{"type":"long","x-version":3}`)
+	ensureError(t, err)
+	_, err = codec.SchemaGovernance()
+	ensureError(t, err, "cannot read schema governance", "x-version ought to be a string")
+
+	codec, err = NewCodec(`{"type":"long","x-changelog":"none"}`)
+	ensureError(t, err)
+	_, err = codec.SchemaGovernance()
+	ensureError(t, err, "x-changelog ought to be an array of changes")
+
+	_, err = SchemaGovernanceFromMetaData(map[string][]byte{OCFSchemaChangelogKey: []byte("{")})
+	ensureError(t, err, "cannot read schema governance")
+}
@@ -0,0 +1,109 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import "fmt"
+
+// SchemaLimits bound the resources spent building a Codec, for schemas
+// accepted from untrusted sources, such as by a schema registry proxy. A
+// schema exceeding a limit is rejected before its codec is built. A zero limit
+// is not enforced.
+type SchemaLimits struct {
+	// MaxSize is the largest schema specification accepted, in bytes.
+	MaxSize int
+
+	// MaxNamedTypes is the largest number of named types, records, enums,
+	// and fixed types, a schema may define.
+	MaxNamedTypes int
+
+	// MaxUnionMembers is the largest number of members of each union.
+	MaxUnionMembers int
+
+	// MaxDepth is the largest number of types a type may be nested within,
+	// such as the fields of records, and the items of arrays.
+	MaxDepth int
+}
+
+// UntrustedSchemaLimits returns limits suitable for schemas accepted from
+// untrusted sources, which accept the schemas of most applications: schemas
+// of up to 1 MiB, defining up to 1000 named types, with unions of up to 100
+// members, and types nested up to 64 levels deep.
+//
+//     codec, err := goavro.NewCodec(schema, goavro.WithSchemaLimits(goavro.UntrustedSchemaLimits()))
+func UntrustedSchemaLimits() SchemaLimits {
+	return SchemaLimits{MaxSize: 1 << 20, MaxNamedTypes: 1000, MaxUnionMembers: 100, MaxDepth: 64}
+}
+
+func (limits SchemaLimits) validate() error {
+	if limits.MaxSize < 0 || limits.MaxNamedTypes < 0 || limits.MaxUnionMembers < 0 || limits.MaxDepth < 0 {
+		return fmt.Errorf("schema limits ought to be zero or positive: %+v", limits)
+	}
+	return nil
+}
+
+// checkSize returns an error when the schema specification exceeds MaxSize.
+func (limits SchemaLimits) checkSize(schemaSpecification string) error {
+	if limits.MaxSize > 0 && len(schemaSpecification) > limits.MaxSize {
+		return fmt.Errorf("schema size exceeds MaxSize: %d > %d", len(schemaSpecification), limits.MaxSize)
+	}
+	return nil
+}
+
+// checkSchema returns an error when the parsed schema exceeds one of the limits
+// on its structure.
+func (limits SchemaLimits) checkSchema(schema interface{}) error {
+	if limits.MaxNamedTypes == 0 && limits.MaxUnionMembers == 0 && limits.MaxDepth == 0 {
+		return nil
+	}
+	var namedTypes int
+	return limits.walk(schema, 0, &namedTypes)
+}
+
+func (limits SchemaLimits) walk(schema interface{}, depth int, namedTypes *int) error {
+	if limits.MaxDepth > 0 && depth > limits.MaxDepth {
+		return fmt.Errorf("schema depth exceeds MaxDepth: %d", limits.MaxDepth)
+	}
+	switch v := schema.(type) {
+	case []interface{}:
+		if limits.MaxUnionMembers > 0 && len(v) > limits.MaxUnionMembers {
+			return fmt.Errorf("union members exceed MaxUnionMembers: %d > %d", len(v), limits.MaxUnionMembers)
+		}
+		for _, member := range v {
+			if err := limits.walk(member, depth+1, namedTypes); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		switch typeName := v["type"]; typeName {
+		case "record", "error", "enum", "fixed":
+			*namedTypes++
+			if limits.MaxNamedTypes > 0 && *namedTypes > limits.MaxNamedTypes {
+				return fmt.Errorf("named types exceed MaxNamedTypes: %d", limits.MaxNamedTypes)
+			}
+			fields, _ := v["fields"].([]interface{})
+			for _, field := range fields {
+				if fieldMap, ok := field.(map[string]interface{}); ok {
+					if err := limits.walk(fieldMap["type"], depth+1, namedTypes); err != nil {
+						return err
+					}
+				}
+			}
+		case "array":
+			return limits.walk(v["items"], depth+1, namedTypes)
+		case "map":
+			return limits.walk(v["values"], depth+1, namedTypes)
+		default:
+			if _, ok := typeName.(string); !ok {
+				return limits.walk(typeName, depth+1, namedTypes)
+			}
+		}
+	}
+	return nil
+}
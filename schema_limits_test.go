@@ -0,0 +1,77 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSchemaLimits(t *testing.T) {
+	schema := `{"type":"record","name":"R","fields":[
+		{"name":"a","type":["null","int","string"]},
+		{"name":"b","type":{"type":"array","items":{"type":"enum","name":"E","symbols":["X"]}}},
+		{"name":"c","type":{"type":"fixed","name":"F","size":2}}
+	]}`
+
+	t.Run("within limits", func(t *testing.T) {
+		_, err := NewCodec(schema, WithSchemaLimits(SchemaLimits{MaxSize: len(schema), MaxNamedTypes: 3, MaxUnionMembers: 3, MaxDepth: 2}))
+		ensureError(t, err)
+	})
+
+	t.Run("size", func(t *testing.T) {
+		_, err := NewCodec(schema, WithSchemaLimits(SchemaLimits{MaxSize: 10}))
+		ensureError(t, err, fmt.Sprintf("cannot create codec: schema size exceeds MaxSize: %d > 10", len(schema)))
+	})
+
+	t.Run("named types", func(t *testing.T) {
+		_, err := NewCodec(schema, WithSchemaLimits(SchemaLimits{MaxNamedTypes: 2}))
+		ensureError(t, err, "named types exceed MaxNamedTypes: 2")
+	})
+
+	t.Run("union members", func(t *testing.T) {
+		_, err := NewCodec(schema, WithSchemaLimits(SchemaLimits{MaxUnionMembers: 2}))
+		ensureError(t, err, "union members exceed MaxUnionMembers: 3 > 2")
+	})
+
+	t.Run("depth", func(t *testing.T) {
+		_, err := NewCodec(schema, WithSchemaLimits(SchemaLimits{MaxDepth: 1}))
+		ensureError(t, err, "schema depth exceeds MaxDepth: 1")
+
+		nested := `"int"`
+		for i := 0; i < 100; i++ {
+			nested = `{"type":"array","items":` + nested + `}`
+		}
+		_, err = NewCodec(nested, WithSchemaLimits(UntrustedSchemaLimits()))
+		ensureError(t, err, "schema depth exceeds MaxDepth: 64")
+	})
+
+	t.Run("negative", func(t *testing.T) {
+		_, err := NewCodec(schema, WithSchemaLimits(SchemaLimits{MaxDepth: -1}))
+		ensureError(t, err, "schema limits ought to be zero or positive")
+	})
+
+	t.Run("derived", func(t *testing.T) {
+		codec, err := NewCodec(schema)
+		ensureError(t, err)
+		_, err = codec.WithOptions(WithSchemaLimits(SchemaLimits{MaxNamedTypes: 1}))
+		ensureError(t, err, "cannot derive codec: named types exceed MaxNamedTypes: 1")
+	})
+
+	t.Run("untrusted", func(t *testing.T) {
+		members := make([]string, 101)
+		for i := range members {
+			members[i] = fmt.Sprintf(`{"type":"fixed","name":"F%d","size":1}`, i)
+		}
+		_, err := NewCodec("["+strings.Join(members, ",")+"]", WithSchemaLimits(UntrustedSchemaLimits()))
+		ensureError(t, err, "union members exceed MaxUnionMembers: 101 > 100")
+	})
+}
@@ -0,0 +1,417 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// schemaNode is a parsed representation of an Avro schema, used by the
+// functions that need to walk a schema rather than encode or decode data. It
+// is built using the same namespace rules as the codec builder, and named
+// types referenced more than once, including recursive references, share the
+// same node.
+type schemaNode struct {
+	typeName    string // "record", "enum", "fixed", "array", "map", "union", or a primitive type name
+	fullName    string // only for named types
+	namespace   string // only for named types
+	doc         string
+	aliases     []string
+	logicalType string
+	precision   int
+	scale       int
+	size        int
+	symbols     []string
+	enumDefault string // only for enums, the symbol used by readers for unknown symbols
+	fields      []*schemaNodeField
+	items       *schemaNode
+	values      *schemaNode
+	members     []*schemaNode
+	attributes  map[string]interface{} // properties not defined by the Avro specification
+}
+
+// schemaNodeField describes one field of a record schemaNode.
+type schemaNodeField struct {
+	name         string
+	doc          string
+	node         *schemaNode
+	defaultValue interface{}
+	hasDefault   bool
+	order        string
+	aliases      []string
+	attributes   map[string]interface{} // properties not defined by the Avro specification
+}
+
+// schemaNodeKeys lists the schema properties defined by the Avro
+// specification, which are therefore not reported as custom attributes.
+var schemaNodeKeys = map[string]struct{}{
+	"type": {}, "name": {}, "namespace": {}, "doc": {}, "aliases": {},
+	"fields": {}, "symbols": {}, "items": {}, "values": {}, "size": {},
+	"logicalType": {}, "precision": {}, "scale": {}, "default": {},
+}
+
+// schemaNodeFieldKeys lists the record field properties defined by the Avro
+// specification.
+var schemaNodeFieldKeys = map[string]struct{}{
+	"type": {}, "name": {}, "doc": {}, "default": {}, "order": {}, "aliases": {},
+	"logicalType": {}, "precision": {}, "scale": {},
+}
+
+// schemaNodeTitles are used to prefix error messages about named types, matching
+// the error messages returned by the codec builder.
+var schemaNodeTitles = map[string]string{"record": "Record", "enum": "Enum", "fixed": "Fixed"}
+
+// isNamed returns true when the node describes a named type.
+func (n *schemaNode) isNamed() bool {
+	return n.fullName != ""
+}
+
+// label returns a short human readable description of the node's type, using
+// the full name of named types rather than expanding them.
+func (n *schemaNode) label() string {
+	var s string
+	switch n.typeName {
+	case "record", "enum", "fixed":
+		s = n.fullName
+	case "array":
+		s = "array<" + n.items.label() + ">"
+	case "map":
+		s = "map<" + n.values.label() + ">"
+	case "union":
+		labels := make([]string, len(n.members))
+		for i, member := range n.members {
+			labels[i] = member.label()
+		}
+		s = "union<" + strings.Join(labels, ",") + ">"
+	default:
+		s = n.typeName
+	}
+	if n.logicalType != "" {
+		s += " (" + n.logicalType + ")"
+	}
+	return s
+}
+
+// schemaNodeFromCodec returns the parsed original schema of the Codec, parsing
+// it the first time it is requested. The returned node is shared by all
+// callers, and must not be modified.
+func schemaNodeFromCodec(c *Codec) (*schemaNode, error) {
+	c.schemaTreeOnce.Do(func() {
+		var schema interface{}
+		if err := json.Unmarshal([]byte(c.schemaOriginal), &schema); err != nil {
+			c.schemaTreeErr = fmt.Errorf("cannot unmarshal schema JSON: %s", err)
+			return
+		}
+		c.schemaTree, c.schemaTreeErr = buildSchemaNode(make(map[string]*schemaNode), nullNamespace, schema)
+	})
+	return c.schemaTree, c.schemaTreeErr
+}
+
+func buildSchemaNode(st map[string]*schemaNode, enclosingNamespace string, schema interface{}) (*schemaNode, error) {
+	switch v := schema.(type) {
+	case map[string]interface{}:
+		return buildSchemaNodeFromMap(st, enclosingNamespace, v)
+	case string:
+		return buildSchemaNodeFromString(st, enclosingNamespace, v)
+	case []interface{}:
+		if len(v) == 0 {
+			return nil, errors.New("Union ought to have one or more members")
+		}
+		n := &schemaNode{typeName: "union", members: make([]*schemaNode, len(v))}
+		for i, member := range v {
+			m, err := buildSchemaNode(st, enclosingNamespace, member)
+			if err != nil {
+				return nil, fmt.Errorf("Union item %d ought to be valid Avro type: %s", i+1, err)
+			}
+			n.members[i] = m
+		}
+		return n, nil
+	default:
+		return nil, fmt.Errorf("unknown schema type: %T", schema)
+	}
+}
+
+func buildSchemaNodeFromString(st map[string]*schemaNode, enclosingNamespace, typeName string) (*schemaNode, error) {
+	switch typeName {
+	case "null", "boolean", "int", "long", "float", "double", "bytes", "string":
+		return &schemaNode{typeName: typeName}, nil
+	}
+	if n, ok := st[typeName]; ok {
+		return n, nil
+	}
+	if enclosingNamespace != nullNamespace {
+		if n, ok := st[enclosingNamespace+"."+typeName]; ok {
+			return n, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown type name: %q", typeName)
+}
+
+func buildSchemaNodeFromMap(st map[string]*schemaNode, enclosingNamespace string, schemaMap map[string]interface{}) (*schemaNode, error) {
+	t, ok := schemaMap["type"]
+	if !ok {
+		return nil, fmt.Errorf("missing type: %v", schemaMap)
+	}
+	typeName, ok := t.(string)
+	if !ok {
+		// NOTE: a nested type description; properties at this level describe
+		// nothing further.
+		return buildSchemaNode(st, enclosingNamespace, t)
+	}
+
+	var n *schemaNode
+	var err error
+
+	switch typeName {
+	case "record", "enum", "fixed":
+		nn, err := newNameFromSchemaMap(enclosingNamespace, schemaMap)
+		if err != nil {
+			return nil, fmt.Errorf("%s ought to have valid name: %s", schemaNodeTitles[typeName], err)
+		}
+		n = &schemaNode{typeName: typeName, fullName: nn.fullName, namespace: nn.namespace}
+		// NOTE: register before building fields to support recursive types
+		st[nn.fullName] = n
+	case "array":
+		items, ok := schemaMap["items"]
+		if !ok {
+			return nil, errors.New("Array ought to have items key")
+		}
+		n = &schemaNode{typeName: typeName}
+		if n.items, err = buildSchemaNode(st, enclosingNamespace, items); err != nil {
+			return nil, fmt.Errorf("Array items ought to be valid Avro type: %s", err)
+		}
+	case "map":
+		values, ok := schemaMap["values"]
+		if !ok {
+			return nil, errors.New("Map ought to have values key")
+		}
+		n = &schemaNode{typeName: typeName}
+		if n.values, err = buildSchemaNode(st, enclosingNamespace, values); err != nil {
+			return nil, fmt.Errorf("Map values ought to be valid Avro type: %s", err)
+		}
+	default:
+		if n, err = buildSchemaNodeFromString(st, enclosingNamespace, typeName); err != nil {
+			return nil, err
+		}
+		if n.isNamed() || len(schemaMap) == 1 {
+			return n, nil // reference to an existing type, or primitive
+		}
+		n = &schemaNode{typeName: n.typeName} // primitive with properties
+	}
+
+	n.doc, _ = schemaMap["doc"].(string)
+	n.aliases = stringsFromSchemaValue(schemaMap["aliases"])
+	n.logicalType, _ = schemaMap["logicalType"].(string)
+	if v, ok := schemaMap["precision"].(float64); ok {
+		n.precision = int(v)
+	}
+	if v, ok := schemaMap["scale"].(float64); ok {
+		n.scale = int(v)
+	}
+	n.attributes = attributesFromSchemaMap(schemaMap, schemaNodeKeys)
+
+	switch typeName {
+	case "enum":
+		n.symbols = stringsFromSchemaValue(schemaMap["symbols"])
+		n.enumDefault, _ = schemaMap["default"].(string)
+	case "fixed":
+		size, err := sizeFromSchemaMap(&name{n.fullName, n.namespace}, schemaMap)
+		if err != nil {
+			return nil, err
+		}
+		n.size = int(size)
+	case "record":
+		fields, ok := schemaMap["fields"].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("Record %q fields ought to be non-nil array: %v", n.fullName, schemaMap["fields"])
+		}
+		n.fields = make([]*schemaNodeField, len(fields))
+		for i, field := range fields {
+			fieldMap, ok := field.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("Record %q field %d ought to be valid Avro named type; received: %v", n.fullName, i+1, field)
+			}
+			f, err := buildSchemaNodeField(st, n.namespace, fieldMap)
+			if err != nil {
+				return nil, fmt.Errorf("Record %q field %d ought to be valid Avro named type: %s", n.fullName, i+1, err)
+			}
+			n.fields[i] = f
+		}
+	}
+	return n, nil
+}
+
+func buildSchemaNodeField(st map[string]*schemaNode, namespace string, fieldMap map[string]interface{}) (*schemaNodeField, error) {
+	fieldName, ok := fieldMap["name"].(string)
+	if !ok {
+		return nil, fmt.Errorf("schema name ought to be non-empty string; received: %T: %v", fieldMap["name"], fieldMap["name"])
+	}
+	f := &schemaNodeField{name: fieldName}
+	f.doc, _ = fieldMap["doc"].(string)
+	f.order, _ = fieldMap["order"].(string)
+	f.aliases = stringsFromSchemaValue(fieldMap["aliases"])
+	f.defaultValue, f.hasDefault = fieldMap["default"]
+	f.attributes = attributesFromSchemaMap(fieldMap, schemaNodeFieldKeys)
+
+	t, ok := fieldMap["type"]
+	if !ok {
+		return nil, fmt.Errorf("missing type: %v", fieldMap)
+	}
+	if _, ok := fieldMap["logicalType"]; ok {
+		// NOTE: goavro allows logical type properties to be specified on the
+		// field itself when the field type is a primitive type name.
+		typeMap := map[string]interface{}{"type": t}
+		for _, k := range []string{"logicalType", "precision", "scale"} {
+			if v, ok := fieldMap[k]; ok {
+				typeMap[k] = v
+			}
+		}
+		t = typeMap
+	}
+	var err error
+	if f.node, err = buildSchemaNode(st, namespace, t); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// attributesFromSchemaMap returns the properties from schemaMap that are not
+// in the provided set of known keys, or nil when there are none.
+func attributesFromSchemaMap(schemaMap map[string]interface{}, known map[string]struct{}) map[string]interface{} {
+	var attributes map[string]interface{}
+	for k, v := range schemaMap {
+		if _, ok := known[k]; ok {
+			continue
+		}
+		if attributes == nil {
+			attributes = make(map[string]interface{})
+		}
+		attributes[k] = v
+	}
+	return attributes
+}
+
+// copyAttributes returns a deep copy of the attributes of a node or field, or
+// nil when there are none, so callers may modify them without affecting the
+// cached schemaNode.
+func copyAttributes(attributes map[string]interface{}) map[string]interface{} {
+	if attributes == nil {
+		return nil
+	}
+	return copyNative(attributes).(map[string]interface{})
+}
+
+// stringsFromSchemaValue returns the string members of a JSON array value.
+func stringsFromSchemaValue(value interface{}) []string {
+	values, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+	strs := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			strs = append(strs, s)
+		}
+	}
+	return strs
+}
+
+// schemaValue returns the JSON value of the schema described by the node, in
+// the form accepted by NewCodec. Named types are defined by full name the
+// first time they are encountered, and referenced by full name afterwards.
+func (n *schemaNode) schemaValue(defined map[*schemaNode]struct{}) interface{} {
+	if n.isNamed() {
+		if _, ok := defined[n]; ok {
+			return n.fullName
+		}
+		defined[n] = struct{}{}
+	}
+
+	switch n.typeName {
+	case "union":
+		members := make([]interface{}, len(n.members))
+		for i, member := range n.members {
+			members[i] = member.schemaValue(defined)
+		}
+		return members
+	case "null", "boolean", "int", "long", "float", "double", "bytes", "string":
+		if n.doc == "" && n.logicalType == "" && len(n.aliases) == 0 && len(n.attributes) == 0 {
+			return n.typeName
+		}
+	}
+
+	schema := make(map[string]interface{}, len(n.attributes)+4)
+	for k, v := range n.attributes {
+		schema[k] = v
+	}
+	schema["type"] = n.typeName
+	if n.isNamed() {
+		schema["name"] = n.fullName
+		if n.namespace == nullNamespace {
+			// NOTE: prevent the type from inheriting an enclosing namespace
+			schema["namespace"] = nullNamespace
+		}
+	}
+	if n.doc != "" {
+		schema["doc"] = n.doc
+	}
+	if len(n.aliases) > 0 {
+		schema["aliases"] = n.aliases
+	}
+	if n.logicalType != "" {
+		schema["logicalType"] = n.logicalType
+		if n.logicalType == "decimal" {
+			schema["precision"] = n.precision
+			schema["scale"] = n.scale
+		}
+	}
+
+	switch n.typeName {
+	case "array":
+		schema["items"] = n.items.schemaValue(defined)
+	case "map":
+		schema["values"] = n.values.schemaValue(defined)
+	case "enum":
+		schema["symbols"] = n.symbols
+		if n.enumDefault != "" {
+			schema["default"] = n.enumDefault
+		}
+	case "fixed":
+		schema["size"] = n.size
+	case "record":
+		fields := make([]interface{}, len(n.fields))
+		for i, f := range n.fields {
+			field := make(map[string]interface{}, len(f.attributes)+3)
+			for k, v := range f.attributes {
+				field[k] = v
+			}
+			field["name"] = f.name
+			field["type"] = f.node.schemaValue(defined)
+			if f.doc != "" {
+				field["doc"] = f.doc
+			}
+			if f.hasDefault {
+				field["default"] = f.defaultValue
+			}
+			if f.order != "" {
+				field["order"] = f.order
+			}
+			if len(f.aliases) > 0 {
+				field["aliases"] = f.aliases
+			}
+			fields[i] = field
+		}
+		schema["fields"] = fields
+	}
+	return schema
+}
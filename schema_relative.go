@@ -0,0 +1,264 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// SchemaWithRelativeNames returns the schema of the Codec in the form the Java
+// implementation of Avro, and therefore avro-tools, writes schemas. Named types
+// are written with their short name, along with a namespace only when it
+// differs from the enclosing namespace, and are referenced by their short name
+// within their own namespace. Properties are written in the order Java writes
+// them, without whitespace, so schemas written by either implementation may be
+// compared byte for byte.
+//
+//     codec, err := goavro.NewCodec(`{"type":"record","name":"com.example.User","fields":[{"name":"friend","type":["null","com.example.User"]}]}`)
+//     if err != nil {
+//         fmt.Println(err)
+//     }
+//     schema, err := codec.SchemaWithRelativeNames()
+//     // {"type":"record","name":"User","namespace":"com.example","fields":[{"name":"friend","type":["null","User"]}]}
+//
+// Properties that are not defined by the Avro specification are written in
+// the order of their names, as the order they were declared in is not kept.
+func (c *Codec) SchemaWithRelativeNames() (string, error) {
+	root, err := schemaNodeFromCodec(c)
+	if err != nil {
+		return "", fmt.Errorf("cannot write schema: %s", err)
+	}
+	w := &relativeSchemaWriter{defined: make(map[*schemaNode]struct{})}
+	if err = w.writeNode(root, nullNamespace); err != nil {
+		return "", fmt.Errorf("cannot write schema: %s", err)
+	}
+	return w.buf.String(), nil
+}
+
+type relativeSchemaWriter struct {
+	buf     bytes.Buffer
+	defined map[*schemaNode]struct{}
+}
+
+// relativeName returns the name of the named type as referenced from within
+// the namespace.
+func relativeName(n *schemaNode, namespace string) string {
+	if n.namespace == namespace {
+		return (&name{n.fullName, n.namespace}).short()
+	}
+	return n.fullName
+}
+
+func (w *relativeSchemaWriter) writeNode(n *schemaNode, namespace string) error {
+	if n.isNamed() {
+		if _, ok := w.defined[n]; ok {
+			return w.writeValue(relativeName(n, namespace))
+		}
+		w.defined[n] = struct{}{}
+	}
+
+	switch n.typeName {
+	case "union":
+		w.buf.WriteByte('[')
+		for i, member := range n.members {
+			if i > 0 {
+				w.buf.WriteByte(',')
+			}
+			if err := w.writeNode(member, namespace); err != nil {
+				return err
+			}
+		}
+		w.buf.WriteByte(']')
+		return nil
+	case "null", "boolean", "int", "long", "float", "double", "bytes", "string":
+		if n.doc == "" && n.logicalType == "" && len(n.aliases) == 0 && len(n.attributes) == 0 {
+			return w.writeValue(n.typeName)
+		}
+	}
+
+	w.buf.WriteString(`{"type":`)
+	if err := w.writeValue(n.typeName); err != nil {
+		return err
+	}
+	if n.isNamed() {
+		w.writeKey("name")
+		if err := w.writeValue((&name{n.fullName, n.namespace}).short()); err != nil {
+			return err
+		}
+		if n.namespace != namespace {
+			w.writeKey("namespace")
+			if err := w.writeValue(n.namespace); err != nil {
+				return err
+			}
+		}
+	}
+	if n.doc != "" {
+		w.writeKey("doc")
+		if err := w.writeValue(n.doc); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	switch n.typeName {
+	case "record":
+		w.writeKey("fields")
+		w.buf.WriteByte('[')
+		for i, f := range n.fields {
+			if i > 0 {
+				w.buf.WriteByte(',')
+			}
+			// NOTE: fields are within the namespace of their record
+			if err = w.writeField(f, n.namespace); err != nil {
+				return err
+			}
+		}
+		w.buf.WriteByte(']')
+	case "enum":
+		w.writeKey("symbols")
+		if err = w.writeValue(n.symbols); err != nil {
+			return err
+		}
+		if n.enumDefault != "" {
+			w.writeKey("default")
+			err = w.writeValue(n.enumDefault)
+		}
+	case "fixed":
+		w.writeKey("size")
+		err = w.writeValue(n.size)
+	case "array":
+		w.writeKey("items")
+		err = w.writeNode(n.items, namespace)
+	case "map":
+		w.writeKey("values")
+		err = w.writeNode(n.values, namespace)
+	}
+	if err != nil {
+		return err
+	}
+
+	// NOTE: Java holds logical types as properties of the schema.
+	if n.logicalType != "" {
+		w.writeKey("logicalType")
+		if err = w.writeValue(n.logicalType); err != nil {
+			return err
+		}
+		if n.logicalType == "decimal" {
+			w.writeKey("precision")
+			if err = w.writeValue(n.precision); err != nil {
+				return err
+			}
+			w.writeKey("scale")
+			if err = w.writeValue(n.scale); err != nil {
+				return err
+			}
+		}
+	}
+	if err = w.writeAttributes(n.attributes); err != nil {
+		return err
+	}
+	if len(n.aliases) > 0 {
+		if err = w.writeAliases(n.aliases, n.namespace); err != nil {
+			return err
+		}
+	}
+	w.buf.WriteByte('}')
+	return nil
+}
+
+func (w *relativeSchemaWriter) writeField(f *schemaNodeField, namespace string) error {
+	w.buf.WriteString(`{"name":`)
+	if err := w.writeValue(f.name); err != nil {
+		return err
+	}
+	w.writeKey("type")
+	if err := w.writeNode(f.node, namespace); err != nil {
+		return err
+	}
+	if f.doc != "" {
+		w.writeKey("doc")
+		if err := w.writeValue(f.doc); err != nil {
+			return err
+		}
+	}
+	if f.hasDefault {
+		w.writeKey("default")
+		if err := w.writeValue(f.defaultValue); err != nil {
+			return err
+		}
+	}
+	if f.order != "" && f.order != "ascending" {
+		w.writeKey("order")
+		if err := w.writeValue(f.order); err != nil {
+			return err
+		}
+	}
+	if len(f.aliases) > 0 {
+		w.writeKey("aliases")
+		if err := w.writeValue(f.aliases); err != nil {
+			return err
+		}
+	}
+	if err := w.writeAttributes(f.attributes); err != nil {
+		return err
+	}
+	w.buf.WriteByte('}')
+	return nil
+}
+
+// writeAliases writes the aliases of a named type, relative to its namespace.
+func (w *relativeSchemaWriter) writeAliases(aliases []string, namespace string) error {
+	relative := make([]string, len(aliases))
+	for i, alias := range aliases {
+		relative[i] = alias
+		if nn, err := newName(alias, namespace, nullNamespace); err == nil && nn.namespace == namespace {
+			relative[i] = nn.short()
+		}
+	}
+	w.writeKey("aliases")
+	return w.writeValue(relative)
+}
+
+func (w *relativeSchemaWriter) writeAttributes(attributes map[string]interface{}) error {
+	keys := make([]string, 0, len(attributes))
+	for k := range attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		w.writeKey(k)
+		if err := w.writeValue(attributes[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *relativeSchemaWriter) writeKey(key string) {
+	w.buf.WriteByte(',')
+	w.writeValue(key) // NOTE: a string is always encoded
+	w.buf.WriteByte(':')
+}
+
+// writeValue writes the JSON encoding of value without escaping HTML
+// characters, as Java does not escape them either.
+func (w *relativeSchemaWriter) writeValue(value interface{}) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err != nil {
+		return err
+	}
+	w.buf.Write(bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}))
+	return nil
+}
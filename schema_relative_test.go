@@ -0,0 +1,80 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"testing"
+)
+
+func TestCodecSchemaWithRelativeNames(t *testing.T) {
+	codec := newCodecUsingV2(t, `{"name":"com.example.User","type":"record","doc":"a <user>","aliases":["com.example.Person","org.Member"],"fields":[
+		{"type":["null","com.example.User"],"name":"friend","default":null},
+		{"name":"color","type":{"type":"enum","name":"Color","symbols":["RED","BLUE"],"default":"RED"}},
+		{"name":"id","type":{"type":"fixed","name":"org.other.Id","size":4}},
+		{"name":"ids","type":{"type":"array","items":"org.other.Id"},"order":"descending"},
+		{"name":"amount","type":{"type":"bytes","logicalType":"decimal","precision":9,"scale":2},"x-pii":true}
+	]}`)
+
+	actual, err := codec.SchemaWithRelativeNames()
+	ensureError(t, err)
+	expected := `{"type":"record","name":"User","namespace":"com.example","doc":"a <user>","fields":[` +
+		`{"name":"friend","type":["null","User"],"default":null},` +
+		`{"name":"color","type":{"type":"enum","name":"Color","symbols":["RED","BLUE"],"default":"RED"}},` +
+		`{"name":"id","type":{"type":"fixed","name":"Id","namespace":"org.other","size":4}},` +
+		`{"name":"ids","type":{"type":"array","items":"org.other.Id"},"order":"descending"},` +
+		`{"name":"amount","type":{"type":"bytes","logicalType":"decimal","precision":9,"scale":2},"x-pii":true}` +
+		`],"aliases":["Person","org.Member"]}`
+	if actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	// the written schema describes the same types
+	root, err := newCodecUsingV2(t, actual).SchemaTree()
+	ensureError(t, err)
+	if actual, expected := root.Fields[0].Type.String(), "union<null,com.example.User>"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if actual, expected := root.Fields[2].Type.Name, "org.other.Id"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
+
+func TestUnionRelativeNames(t *testing.T) {
+	const schema = `{"type":"record","name":"com.example.R","fields":[{"name":"f","type":["null",{"type":"enum","name":"E","symbols":["A"]},{"type":"fixed","name":"org.F","size":1}]}]}`
+	codec, err := NewCodec(schema, WithRelativeUnionNames(true))
+	ensureError(t, err)
+
+	buf, err := codec.TextualFromNative(nil, map[string]interface{}{"f": Union("com.example.E", "A")})
+	ensureError(t, err)
+	if actual, expected := string(buf), `{"f":{"E":"A"}}`; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	buf, err = codec.TextualFromNative(nil, map[string]interface{}{"f": Union("org.F", []byte("x"))})
+	ensureError(t, err)
+	if actual, expected := string(buf), `{"f":{"org.F":"x"}}`; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	// both relative and full names are decoded to full names
+	for _, text := range []string{`{"f":{"E":"A"}}`, `{"f":{"com.example.E":"A"}}`} {
+		datum, _, err := codec.NativeFromTextual([]byte(text))
+		ensureError(t, err)
+		if actual, expected := datum.(map[string]interface{})["f"].(map[string]interface{})["com.example.E"], "A"; actual != expected {
+			t.Errorf("%s: GOT: %v; WANT: %v", text, datum, expected)
+		}
+	}
+
+	// without the option, full names are written
+	buf, err = newCodecUsingV2(t, schema).TextualFromNative(nil, map[string]interface{}{"f": Union("com.example.E", "A")})
+	ensureError(t, err)
+	if actual, expected := string(buf), `{"f":{"com.example.E":"A"}}`; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import "fmt"
+
+// SchemaNode is one type of the structured representation of a schema
+// returned by SchemaTree.
+//
+// Each named type is represented by a single SchemaNode, which every reference
+// to the type shares, so the nodes of a recursive schema form a cycle. Tools
+// walking the tree ought to remember the named types they have visited.
+type SchemaNode struct {
+	// Type is "record", "enum", "fixed", "array", "map", "union", or the
+	// name of a primitive type.
+	Type string
+
+	Name      string // full name, only for named types
+	Namespace string // only for named types
+	Doc       string
+	Aliases   []string
+
+	LogicalType string
+	Precision   int // only for decimal logical types
+	Scale       int // only for decimal logical types
+
+	Size        int      // only for fixed types
+	Symbols     []string // only for enums
+	EnumDefault string   // only for enums, the symbol read for unknown symbols
+
+	Fields  []*SchemaField // only for records, in the order they are declared
+	Items   *SchemaNode    // only for arrays
+	Values  *SchemaNode    // only for maps
+	Members []*SchemaNode  // only for unions
+
+	// Attributes holds the properties of the type not defined by the Avro
+	// specification, as decoded from the schema JSON.
+	Attributes map[string]interface{}
+}
+
+// SchemaField is one field of a record SchemaNode.
+type SchemaField struct {
+	Name    string
+	Doc     string
+	Type    *SchemaNode
+	Aliases []string
+	Order   string
+
+	// Default is the default value of the field, as decoded from the schema
+	// JSON, when HasDefault is true.
+	Default    interface{}
+	HasDefault bool
+
+	// Attributes holds the properties of the field not defined by the Avro
+	// specification, as decoded from the schema JSON.
+	Attributes map[string]interface{}
+}
+
+// String returns the type of the node, the name of named types, or a label
+// such as "array<string>" or "union<null,long (timestamp-millis)>".
+func (n *SchemaNode) String() string {
+	s := n.Type
+	switch n.Type {
+	case "record", "enum", "fixed":
+		s = n.Name
+	case "array":
+		s = "array<" + n.Items.String() + ">"
+	case "map":
+		s = "map<" + n.Values.String() + ">"
+	case "union":
+		s = "union<"
+		for i, member := range n.Members {
+			if i > 0 {
+				s += ","
+			}
+			s += member.String()
+		}
+		s += ">"
+	}
+	if n.LogicalType != "" {
+		s += " (" + n.LogicalType + ")"
+	}
+	return s
+}
+
+// SchemaTree returns the structured representation of the schema of the
+// Codec, so tools may inspect the fields and types of the schema without
+// parsing its JSON themselves.
+//
+//     root, err := codec.SchemaTree()
+//     if err != nil {
+//         return err
+//     }
+//     for _, field := range root.Fields {
+//         fmt.Printf("%s: %s\n", field.Name, field.Type)
+//     }
+//
+// Each call returns a new tree, which the caller may modify without affecting
+// the Codec.
+func (c *Codec) SchemaTree() (*SchemaNode, error) {
+	root, err := schemaNodeFromCodec(c)
+	if err != nil {
+		return nil, fmt.Errorf("cannot build schema tree: %s", err)
+	}
+	return exportSchemaNode(root, make(map[*schemaNode]*SchemaNode)), nil
+}
+
+// exportSchemaNode returns the SchemaNode of the node, creating a single
+// SchemaNode for each named type.
+func exportSchemaNode(n *schemaNode, exported map[*schemaNode]*SchemaNode) *SchemaNode {
+	if e, ok := exported[n]; ok {
+		return e
+	}
+	e := &SchemaNode{
+		Type:        n.typeName,
+		Name:        n.fullName,
+		Namespace:   n.namespace,
+		Doc:         n.doc,
+		Aliases:     append([]string(nil), n.aliases...),
+		LogicalType: n.logicalType,
+		Precision:   n.precision,
+		Scale:       n.scale,
+		Size:        n.size,
+		Symbols:     append([]string(nil), n.symbols...),
+		EnumDefault: n.enumDefault,
+		Attributes:  copyAttributes(n.attributes),
+	}
+	if n.isNamed() {
+		// NOTE: register before exporting fields to support recursive types
+		exported[n] = e
+	}
+	switch n.typeName {
+	case "record":
+		e.Fields = make([]*SchemaField, len(n.fields))
+		for i, f := range n.fields {
+			e.Fields[i] = &SchemaField{
+				Name:       f.name,
+				Doc:        f.doc,
+				Type:       exportSchemaNode(f.node, exported),
+				Aliases:    append([]string(nil), f.aliases...),
+				Order:      f.order,
+				Default:    copyNative(f.defaultValue),
+				HasDefault: f.hasDefault,
+				Attributes: copyAttributes(f.attributes),
+			}
+		}
+	case "array":
+		e.Items = exportSchemaNode(n.items, exported)
+	case "map":
+		e.Values = exportSchemaNode(n.values, exported)
+	case "union":
+		e.Members = make([]*SchemaNode, len(n.members))
+		for i, member := range n.members {
+			e.Members[i] = exportSchemaNode(member, exported)
+		}
+	}
+	return e
+}
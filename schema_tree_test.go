@@ -0,0 +1,89 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"testing"
+)
+
+func TestCodecSchemaTree(t *testing.T) {
+	codec := newCodecUsingV2(t, `{"type":"record","name":"Node","namespace":"com.example","doc":"a tree","fields":[
+		{"name":"label","type":"string","doc":"shown","x-pii":true},
+		{"name":"created","type":{"type":"long","logicalType":"timestamp-millis"}},
+		{"name":"color","type":{"type":"enum","name":"Color","symbols":["RED","BLUE"],"default":"RED"},"default":"BLUE"},
+		{"name":"children","type":{"type":"array","items":"Node"}},
+		{"name":"parent","type":["null","Node"],"default":null}
+	]}`)
+
+	root, err := codec.SchemaTree()
+	ensureError(t, err)
+
+	if root.Type != "record" || root.Name != "com.example.Node" || root.Namespace != "com.example" || root.Doc != "a tree" {
+		t.Errorf("GOT: %+v", root)
+	}
+	if actual, expected := len(root.Fields), 5; actual != expected {
+		t.Fatalf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	label := root.Fields[0]
+	if label.Name != "label" || label.Type.Type != "string" || label.Doc != "shown" || label.Attributes["x-pii"] != true {
+		t.Errorf("GOT: %+v", label)
+	}
+
+	if actual, expected := root.Fields[1].Type.String(), "long (timestamp-millis)"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	color := root.Fields[2]
+	if !color.HasDefault || color.Default != "BLUE" || color.Type.EnumDefault != "RED" || len(color.Type.Symbols) != 2 {
+		t.Errorf("GOT: %+v; type: %+v", color, color.Type)
+	}
+
+	// references to a named type share its node
+	if root.Fields[3].Type.Items != root {
+		t.Errorf("GOT: %v; WANT: root node", root.Fields[3].Type.Items)
+	}
+	parent := root.Fields[4]
+	if actual, expected := parent.Type.String(), "union<null,com.example.Node>"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if !parent.HasDefault || parent.Default != nil || parent.Type.Members[1] != root {
+		t.Errorf("GOT: %+v", parent)
+	}
+
+	// each call returns a new tree
+	root.Fields[0].Name = "changed"
+	root.Fields[0].Attributes["x-pii"] = false
+	again, err := codec.SchemaTree()
+	ensureError(t, err)
+	if actual, expected := again.Fields[0].Name, "label"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if actual, expected := again.Fields[0].Attributes["x-pii"], true; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
+
+func TestCodecSchemaTreeCopiesDefaults(t *testing.T) {
+	codec := newCodecUsingV2(t, `{"type":"record","name":"r","x-owner":"ops","fields":[{"name":"m","type":{"type":"map","values":"long"},"default":{"a":1}}]}`)
+	root, err := codec.SchemaTree()
+	ensureError(t, err)
+	root.Attributes["x-owner"] = "modified"
+	root.Fields[0].Default.(map[string]interface{})["a"] = "modified"
+
+	root, err = codec.SchemaTree()
+	ensureError(t, err)
+	if actual, expected := root.Attributes["x-owner"], "ops"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if actual, expected := root.Fields[0].Default.(map[string]interface{})["a"], float64(1); actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
@@ -0,0 +1,373 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// FieldUsage describes how often the values of a record field were null or
+// empty. Paths are formatted like those of SchemaFieldDoc.
+type FieldUsage struct {
+	Path     string `json:"path"`
+	Type     string `json:"type"`     // label of the schema of the field
+	Optional bool   `json:"optional"` // true when the field is a union with a null member
+	Count    int64  `json:"count"`    // number of values of the field
+	Nulls    int64  `json:"nulls"`    // number of null values
+	Empty    int64  `json:"empty"`    // number of empty strings, bytes, arrays, and maps
+}
+
+// UnionUsage describes how often each member of a union was used.
+type UnionUsage struct {
+	Path    string        `json:"path"`
+	Count   int64         `json:"count"`
+	Members []MemberUsage `json:"members"` // in the order of the schema
+}
+
+// MemberUsage describes how often a member of a union or a symbol of an enum
+// was used.
+type MemberUsage struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// EnumUsage describes how often each symbol of an enum was used.
+type EnumUsage struct {
+	Path    string        `json:"path"`
+	Name    string        `json:"name"` // full name of the enum
+	Count   int64         `json:"count"`
+	Symbols []MemberUsage `json:"symbols"` // in the order of the schema
+}
+
+// SchemaUsage describes which parts of a schema the data items added to a
+// SchemaUsageAnalyzer used. Unions, enums, and fields inside values that
+// never appeared, such as the fields of a union member that was never used,
+// are not described, because the member that contains them is reported
+// instead.
+type SchemaUsage struct {
+	Records int64         `json:"records"`
+	Fields  []*FieldUsage `json:"fields"` // sorted by path
+	Unions  []*UnionUsage `json:"unions"` // sorted by path
+	Enums   []*EnumUsage  `json:"enums"`  // sorted by path and name
+}
+
+// Findings returns a description of each part of the schema that the data did
+// not use, which are the candidates for pruning the schema: optional fields
+// that were always null, optional fields that were never null, fields that
+// were always empty, union members that were never used, and enum symbols
+// that never appeared.
+func (su *SchemaUsage) Findings() []string {
+	var findings []string
+	for _, fu := range su.Fields {
+		switch {
+		case fu.Count == 0:
+		case fu.Optional && fu.Nulls == fu.Count:
+			findings = append(findings, fmt.Sprintf("field %q is always null", fu.Path))
+		case fu.Optional && fu.Nulls == 0:
+			findings = append(findings, fmt.Sprintf("field %q is optional but never null", fu.Path))
+		case fu.Empty == fu.Count:
+			findings = append(findings, fmt.Sprintf("field %q is always empty", fu.Path))
+		}
+	}
+	for _, uu := range su.Unions {
+		for _, mu := range uu.Members {
+			if uu.Count > 0 && mu.Count == 0 {
+				findings = append(findings, fmt.Sprintf("union %q member %q is never used", uu.Path, mu.Name))
+			}
+		}
+	}
+	for _, eu := range su.Enums {
+		for _, symbol := range eu.Symbols {
+			if eu.Count > 0 && symbol.Count == 0 {
+				findings = append(findings, fmt.Sprintf("enum %s %q symbol %q never appears", eu.Name, eu.Path, symbol.Name))
+			}
+		}
+	}
+	return findings
+}
+
+// String returns the number of data items described, followed by each
+// finding on its own line.
+func (su *SchemaUsage) String() string {
+	var b bytes.Buffer
+	findings := su.Findings()
+	fmt.Fprintf(&b, "%d records, %d findings\n", su.Records, len(findings))
+	for _, finding := range findings {
+		b.WriteString(finding)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// SchemaUsageAnalyzer scans data items, such as the records of OCF files, and
+// reports which optional fields and union members they use, and which enum
+// symbols appear, so unused parts of a schema may be pruned with evidence
+// that no data relies on them. A SchemaUsageAnalyzer ought not to be used by
+// multiple goroutines simultaneously.
+//
+//	analyzer, err := goavro.NewSchemaUsageAnalyzer(ocfr.Codec())
+//	if err != nil {
+//	    return err
+//	}
+//	if err = analyzer.ReadOCF(ocfr); err != nil {
+//	    return err
+//	}
+//	for _, finding := range analyzer.Usage().Findings() {
+//	    fmt.Println(finding) // union "source" member "App" is never used
+//	}
+type SchemaUsageAnalyzer struct {
+	codec   *Codec
+	root    *schemaNode
+	records int64
+	fields  map[string]*FieldUsage
+	unions  map[string]*UnionUsage
+	enums   map[string]*EnumUsage
+}
+
+// NewSchemaUsageAnalyzer returns a SchemaUsageAnalyzer of data items of the
+// schema of the Codec.
+func NewSchemaUsageAnalyzer(codec *Codec) (*SchemaUsageAnalyzer, error) {
+	if codec == nil {
+		return nil, errors.New("cannot create SchemaUsageAnalyzer: codec is nil")
+	}
+	root, err := schemaNodeFromCodec(codec)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create SchemaUsageAnalyzer: %s", err)
+	}
+	return &SchemaUsageAnalyzer{
+		codec:  codec,
+		root:   root,
+		fields: make(map[string]*FieldUsage),
+		unions: make(map[string]*UnionUsage),
+		enums:  make(map[string]*EnumUsage),
+	}, nil
+}
+
+// Add adds the usage of one data item, in the native form decoded by the
+// Codec.
+func (a *SchemaUsageAnalyzer) Add(datum interface{}) error {
+	if err := a.add(a.root, datum, ""); err != nil {
+		return fmt.Errorf("cannot analyze data item %d: %s", a.records, err)
+	}
+	a.records++
+	return nil
+}
+
+// ReadOCF adds the usage of the data items of the OCF reader, until the end of
+// the OCF. The OCF ought to be written with the schema of the Codec of the
+// analyzer.
+func (a *SchemaUsageAnalyzer) ReadOCF(ocfr *OCFReader) error {
+	if ocfr.Codec().Rabin != a.codec.Rabin {
+		return fmt.Errorf("cannot analyze OCF: schema of OCF differs from schema of analyzer: %s", ocfr.Codec().Schema())
+	}
+	for ocfr.Scan() {
+		datum, err := ocfr.Read()
+		if err != nil {
+			return fmt.Errorf("cannot analyze OCF: %s", err)
+		}
+		if err = a.Add(datum); err != nil {
+			return fmt.Errorf("cannot analyze OCF: %s", err)
+		}
+	}
+	if err := ocfr.Err(); err != nil {
+		return fmt.Errorf("cannot analyze OCF: %s", err)
+	}
+	return nil
+}
+
+// Usage returns the usage of the data items added so far.
+func (a *SchemaUsageAnalyzer) Usage() *SchemaUsage {
+	su := &SchemaUsage{Records: a.records}
+	for _, fu := range a.fields {
+		copied := *fu
+		su.Fields = append(su.Fields, &copied)
+	}
+	for _, uu := range a.unions {
+		copied := *uu
+		copied.Members = append([]MemberUsage(nil), uu.Members...)
+		su.Unions = append(su.Unions, &copied)
+	}
+	for _, eu := range a.enums {
+		copied := *eu
+		copied.Symbols = append([]MemberUsage(nil), eu.Symbols...)
+		su.Enums = append(su.Enums, &copied)
+	}
+	sort.Slice(su.Fields, func(i, j int) bool { return su.Fields[i].Path < su.Fields[j].Path })
+	sort.Slice(su.Unions, func(i, j int) bool { return su.Unions[i].Path < su.Unions[j].Path })
+	sort.Slice(su.Enums, func(i, j int) bool {
+		if su.Enums[i].Path != su.Enums[j].Path {
+			return su.Enums[i].Path < su.Enums[j].Path
+		}
+		return su.Enums[i].Name < su.Enums[j].Name
+	})
+	return su
+}
+
+func (a *SchemaUsageAnalyzer) add(n *schemaNode, datum interface{}, path string) error {
+	if datum == nil && n.typeName != "union" {
+		return nil
+	}
+	switch n.typeName {
+	case "record":
+		record, ok := datum.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("path %q: expected map[string]interface{}; received: %T", path, datum)
+		}
+		for _, f := range n.fields {
+			fieldPath := joinFieldPath(path, f.name)
+			if err := a.addField(f, record[f.name], fieldPath); err != nil {
+				return err
+			}
+		}
+	case "array":
+		items, err := convertArray(datum)
+		if err != nil {
+			return fmt.Errorf("path %q: %s", path, err)
+		}
+		for _, item := range items {
+			if err = a.add(n.items, item, path+"[]"); err != nil {
+				return err
+			}
+		}
+	case "map":
+		switch values := datum.(type) {
+		case map[string]interface{}:
+			for _, value := range values {
+				if err := a.add(n.values, value, path+"{}"); err != nil {
+					return err
+				}
+			}
+		case OrderedMap:
+			for _, item := range values {
+				if err := a.add(n.values, item.Value, path+"{}"); err != nil {
+					return err
+				}
+			}
+		default:
+			return fmt.Errorf("path %q: expected map[string]interface{}; received: %T", path, datum)
+		}
+	case "union":
+		uu, ok := a.unions[path]
+		if !ok {
+			uu = &UnionUsage{Path: path, Members: make([]MemberUsage, len(n.members))}
+			for i, member := range n.members {
+				uu.Members[i].Name = unionMemberName(member)
+			}
+			a.unions[path] = uu
+		}
+		if datum == nil {
+			// NOTE: The null member of a union is decoded as nil rather
+			// than wrapped.
+			for i, member := range n.members {
+				if member.typeName == "null" {
+					uu.Count++
+					uu.Members[i].Count++
+					return nil
+				}
+			}
+			return fmt.Errorf("path %q: union has no null member", path)
+		}
+		wrapped, ok := datum.(map[string]interface{})
+		if !ok || len(wrapped) != 1 {
+			return fmt.Errorf("path %q: expected map[string]interface{} with one member; received: %T", path, datum)
+		}
+		for i, member := range n.members {
+			value, ok := wrapped[uu.Members[i].Name]
+			if !ok {
+				continue
+			}
+			uu.Count++
+			uu.Members[i].Count++
+			return a.add(member, value, unionMemberPath(n, member, path))
+		}
+		for name := range wrapped {
+			return fmt.Errorf("path %q: unknown member of union: %q", path, name)
+		}
+	case "enum":
+		symbol, ok := datum.(string)
+		if !ok {
+			return fmt.Errorf("path %q: expected string; received: %T", path, datum)
+		}
+		// NOTE: A union may have several enum members, whose values share
+		// the path of the union.
+		key := path + " " + n.fullName
+		eu, ok := a.enums[key]
+		if !ok {
+			eu = &EnumUsage{Path: path, Name: n.fullName, Symbols: make([]MemberUsage, len(n.symbols))}
+			for i, s := range n.symbols {
+				eu.Symbols[i].Name = s
+			}
+			a.enums[key] = eu
+		}
+		for i := range eu.Symbols {
+			if eu.Symbols[i].Name == symbol {
+				eu.Count++
+				eu.Symbols[i].Count++
+				return nil
+			}
+		}
+		return fmt.Errorf("path %q: unknown symbol of enum %s: %q", path, n.fullName, symbol)
+	}
+	return nil
+}
+
+// addField adds the usage of the value of a record field.
+func (a *SchemaUsageAnalyzer) addField(f *schemaNodeField, value interface{}, path string) error {
+	fu, ok := a.fields[path]
+	if !ok {
+		fu = &FieldUsage{Path: path, Type: f.node.label()}
+		if f.node.typeName == "union" {
+			for _, member := range f.node.members {
+				if member.typeName == "null" {
+					fu.Optional = true
+				}
+			}
+		}
+		a.fields[path] = fu
+	}
+	fu.Count++
+	v := value
+	if f.node.typeName == "union" {
+		if _, member, ok := unwrapUnion(v); ok {
+			v = member
+		}
+	}
+	switch x := v.(type) {
+	case nil:
+		fu.Nulls++
+	case string:
+		if len(x) == 0 {
+			fu.Empty++
+		}
+	case []byte:
+		if len(x) == 0 {
+			fu.Empty++
+		}
+	case []interface{}:
+		if len(x) == 0 {
+			fu.Empty++
+		}
+	case OrderedMap:
+		if len(x) == 0 {
+			fu.Empty++
+		}
+	case map[string]interface{}:
+		if len(x) == 0 && f.node.typeName != "record" {
+			fu.Empty++
+		}
+	}
+	return a.add(f.node, value, path)
+}
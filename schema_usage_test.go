@@ -0,0 +1,112 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const usageSchema = `{
+  "type": "record",
+  "name": "Order",
+  "namespace": "com.example",
+  "fields": [
+    {"name": "id", "type": "long"},
+    {"name": "coupon", "type": ["null", "string"], "default": null},
+    {"name": "note", "type": ["null", "string"], "default": null},
+    {"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["NEW", "PAID", "SHIPPED", "LOST"]}},
+    {"name": "tags", "type": {"type": "array", "items": "string"}},
+    {"name": "payment", "type": [
+      {"type": "record", "name": "Card", "fields": [{"name": "last4", "type": "string"}]},
+      {"type": "record", "name": "Cash", "fields": []},
+      "string"
+    ]},
+    {"name": "items", "type": {"type": "array", "items": {
+      "type": "record", "name": "Item", "fields": [
+        {"name": "sku", "type": "string"},
+        {"name": "gift", "type": ["null", "boolean"]}
+      ]
+    }}}
+  ]
+}`
+
+func TestSchemaUsageAnalyzer(t *testing.T) {
+	codec, err := NewCodec(usageSchema)
+	ensureError(t, err)
+	bb := new(bytes.Buffer)
+	ocfw, err := NewOCFWriter(OCFConfig{W: bb, Codec: codec})
+	ensureError(t, err)
+	for i, status := range []string{"NEW", "PAID", "NEW", "SHIPPED"} {
+		record := map[string]interface{}{
+			"id":      int64(i),
+			"coupon":  nil,
+			"note":    Union("string", "leave at door"),
+			"status":  status,
+			"tags":    []interface{}{},
+			"payment": Union("com.example.Card", map[string]interface{}{"last4": "1234"}),
+			"items":   []interface{}{map[string]interface{}{"sku": "A", "gift": Union("boolean", i%2 == 0)}},
+		}
+		ensureError(t, ocfw.Append([]interface{}{record}))
+	}
+
+	analyzer, err := NewSchemaUsageAnalyzer(codec)
+	ensureError(t, err)
+	ocfr, err := NewOCFReader(bb)
+	ensureError(t, err)
+	ensureError(t, analyzer.ReadOCF(ocfr))
+	usage := analyzer.Usage()
+
+	expected := []string{
+		`field "coupon" is always null`,
+		`field "items[].gift" is optional but never null`,
+		`field "note" is optional but never null`,
+		`field "tags" is always empty`,
+		`union "coupon" member "string" is never used`,
+		`union "items[].gift" member "null" is never used`,
+		`union "note" member "null" is never used`,
+		`union "payment" member "com.example.Cash" is never used`,
+		`union "payment" member "string" is never used`,
+		`enum com.example.Status "status" symbol "LOST" never appears`,
+	}
+	if actual, expected := strings.Join(usage.Findings(), "\n"), strings.Join(expected, "\n"); actual != expected {
+		t.Errorf("GOT:\n%s\nWANT:\n%s", actual, expected)
+	}
+
+	var paths []string
+	for _, fu := range usage.Fields {
+		paths = append(paths, fu.Path)
+	}
+	if actual, expected := strings.Join(paths, " "), "coupon id items items[].gift items[].sku note payment payment(com.example.Card).last4 status tags"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if actual, expected := usage.Enums[0].Symbols[0], (MemberUsage{Name: "NEW", Count: 2}); actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if actual, expected := usage.String(), "4 records, 10 findings\n"; !strings.HasPrefix(actual, expected) {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
+
+func TestSchemaUsageAnalyzerErrors(t *testing.T) {
+	codec, err := NewCodec(`{"type": "enum", "name": "E", "symbols": ["A"]}`)
+	ensureError(t, err)
+	analyzer, err := NewSchemaUsageAnalyzer(codec)
+	ensureError(t, err)
+	ensureError(t, analyzer.Add("B"), `cannot analyze data item 0: path "": unknown symbol of enum E: "B"`)
+	ensureError(t, analyzer.Add(1), `path "": expected string; received: int`)
+
+	_, err = NewSchemaUsageAnalyzer(nil)
+	ensureError(t, err, "codec is nil")
+}
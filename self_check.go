@@ -0,0 +1,221 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// SelfCheckConfig is used to specify what SelfCheck checks.
+type SelfCheckConfig struct {
+	// Codecs specifies codecs to check, (optional).
+	Codecs []*Codec
+
+	// Cache specifies a CodecCache whose codecs are checked, (optional).
+	Cache *CodecCache
+
+	// Bundle specifies a SchemaBundle whose codecs are checked, (optional).
+	Bundle *SchemaBundle
+
+	// Registry is called to verify the schema registry is reachable,
+	// (optional), such as by requesting its list of subjects. It ought to
+	// return when the context is done.
+	Registry func(ctx context.Context) error
+}
+
+// SelfCheckReport describes the outcome of SelfCheck. It encodes as JSON, so
+// it may be served to readiness probes as is.
+type SelfCheckReport struct {
+	Healthy  bool              `json:"healthy"`            // whether every check succeeded
+	Codecs   []SelfCheckResult `json:"codecs"`             // outcome of the check of each codec
+	Registry *SelfCheckResult  `json:"registry,omitempty"` // outcome of the registry check, when configured
+}
+
+// SelfCheckResult describes the outcome of a single check of a SelfCheckReport.
+type SelfCheckResult struct {
+	Name        string `json:"name"`                  // full name of the schema of the codec, or "registry"
+	Fingerprint uint64 `json:"fingerprint,omitempty"` // Rabin fingerprint of the schema of the codec
+	Error       string `json:"error,omitempty"`       // why the check failed, or empty when it succeeded
+}
+
+// SelfCheck verifies that each configured codec round-trips a representative
+// datum of its schema, and that the schema registry is reachable, for the
+// readiness probes of services whose job is Avro serialization. Each codec
+// decodes a binary datum built from its schema, where arrays and maps have a
+// single item, and unions hold their first member, encodes the decoded datum
+// back to binary, and then through its textual form, and both encodings
+// ought to match the datum. Codecs configured more than once are checked once.
+//
+//     report := goavro.SelfCheck(ctx, goavro.SelfCheckConfig{Cache: cache})
+//     if !report.Healthy {
+//         log.Printf("serialization self check failed: %+v", report)
+//     }
+func SelfCheck(ctx context.Context, config SelfCheckConfig) *SelfCheckReport {
+	codecs := append([]*Codec(nil), config.Codecs...)
+	if config.Cache != nil {
+		codecs = append(codecs, config.Cache.codecs()...)
+	}
+	if config.Bundle != nil {
+		for _, id := range config.Bundle.IDs() {
+			codecs = append(codecs, config.Bundle.ids[id])
+		}
+	}
+
+	report := &SelfCheckReport{Healthy: true, Codecs: make([]SelfCheckResult, 0, len(codecs))}
+	checked := make(map[*Codec]struct{}, len(codecs))
+	for _, codec := range codecs {
+		if _, ok := checked[codec]; ok {
+			continue
+		}
+		checked[codec] = struct{}{}
+		result := SelfCheckResult{Name: codec.typeName.fullName, Fingerprint: codec.Rabin}
+		if err := selfCheckCodec(codec); err != nil {
+			result.Error = err.Error()
+			report.Healthy = false
+		}
+		report.Codecs = append(report.Codecs, result)
+	}
+	if config.Registry != nil {
+		report.Registry = &SelfCheckResult{Name: "registry"}
+		if err := config.Registry(ctx); err != nil {
+			report.Registry.Error = err.Error()
+			report.Healthy = false
+		}
+	}
+	return report
+}
+
+// NewSelfCheckHandler returns an http.Handler that runs SelfCheck for every
+// request, using the context of the request, and responds with the report
+// encoded as JSON, with the status 200 OK when healthy, and 503 Service
+// Unavailable otherwise.
+func NewSelfCheckHandler(config SelfCheckConfig) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := SelfCheck(r.Context(), config)
+		w.Header().Set("Content-Type", "application/json")
+		if !report.Healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
+
+// selfCheckCodec round-trips a representative datum of the schema of the
+// codec through its binary and textual encodings.
+func selfCheckCodec(c *Codec) error {
+	n, err := schemaNodeFromCodec(c)
+	if err != nil {
+		return err
+	}
+	buf, ok := representativeBinary(nil, n, make(map[*schemaNode]bool))
+	if !ok {
+		return errors.New("schema has no finite datum")
+	}
+	datum, _, err := c.NativeFromBinary(buf)
+	if err != nil {
+		return fmt.Errorf("cannot decode representative datum: %s", err)
+	}
+	encoded, err := c.BinaryFromNative(nil, datum)
+	if err != nil {
+		return fmt.Errorf("cannot encode representative datum: %s", err)
+	}
+	if !bytes.Equal(encoded, buf) {
+		return fmt.Errorf("binary encoding of representative datum differs: %#v != %#v", encoded, buf)
+	}
+	text, err := c.TextualFromNative(nil, datum)
+	if err != nil {
+		return fmt.Errorf("cannot encode representative datum as text: %s", err)
+	}
+	if datum, _, err = c.NativeFromTextual(text); err != nil {
+		return fmt.Errorf("cannot decode representative datum from text: %s", err)
+	}
+	if encoded, err = c.BinaryFromNative(nil, datum); err != nil {
+		return fmt.Errorf("cannot encode representative datum decoded from text: %s", err)
+	}
+	if !bytes.Equal(encoded, buf) {
+		return fmt.Errorf("binary encoding of representative datum decoded from text differs: %#v != %#v", encoded, buf)
+	}
+	return nil
+}
+
+// representativeBinary appends the binary encoding of a datum of the schema
+// to buf. It returns false when the schema only has data that hold records of
+// the schemas being encoded, and therefore have no finite datum.
+func representativeBinary(buf []byte, n *schemaNode, active map[*schemaNode]bool) ([]byte, bool) {
+	switch n.typeName {
+	case "null":
+		return buf, true
+	case "boolean":
+		return append(buf, 1), true
+	case "int", "long":
+		return append(buf, 2), true // 1
+	case "float":
+		buf, _ = floatBinaryFromNative(buf, float32(1.5))
+		return buf, true
+	case "double":
+		buf, _ = doubleBinaryFromNative(buf, 1.5)
+		return buf, true
+	case "bytes":
+		if n.logicalType == "decimal" {
+			return append(buf, 2, 1), true // unscaled 1
+		}
+		return append(buf, 8, 'a', 'v', 'r', 'o'), true
+	case "string":
+		if n.logicalType == "uuid" {
+			buf, _ = stringBinaryFromNative(buf, "00000000-0000-0000-0000-000000000000")
+			return buf, true
+		}
+		return append(buf, 8, 'a', 'v', 'r', 'o'), true
+	case "enum":
+		return append(buf, 0), true
+	case "fixed":
+		return append(buf, make([]byte, n.size)...), true
+	case "array":
+		if item, ok := representativeBinary(append(buf, 2), n.items, active); ok {
+			return append(item, 0), true
+		}
+		return append(buf, 0), true // empty array
+	case "map":
+		if value, ok := representativeBinary(append(buf, 2, 6, 'k', 'e', 'y'), n.values, active); ok {
+			return append(value, 0), true
+		}
+		return append(buf, 0), true // empty map
+	case "union":
+		for i, member := range n.members {
+			prefix, _ := longBinaryFromNative(buf, i)
+			if member, ok := representativeBinary(prefix, member, active); ok {
+				return member, true
+			}
+		}
+		return buf, false
+	case "record":
+		if active[n] {
+			return buf, false
+		}
+		active[n] = true
+		defer delete(active, n)
+		for _, field := range n.fields {
+			var ok bool
+			if buf, ok = representativeBinary(buf, field.node, active); !ok {
+				return buf, false
+			}
+		}
+		return buf, true
+	}
+	return buf, false
+}
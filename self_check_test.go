@@ -0,0 +1,108 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSelfCheck(t *testing.T) {
+	codec := newCodecUsingV2(t, `{"type":"record","name":"com.example.Event","fields":[
+		{"name":"id","type":{"type":"string","logicalType":"uuid"}},
+		{"name":"at","type":{"type":"long","logicalType":"timestamp-millis"}},
+		{"name":"day","type":{"type":"int","logicalType":"date"}},
+		{"name":"amount","type":{"type":"bytes","logicalType":"decimal","precision":10,"scale":2}},
+		{"name":"hash","type":{"type":"fixed","name":"Hash","size":4}},
+		{"name":"ratio","type":"float"},
+		{"name":"score","type":"double"},
+		{"name":"flag","type":"boolean"},
+		{"name":"kind","type":{"type":"enum","name":"Kind","symbols":["A","B"]}},
+		{"name":"tags","type":{"type":"map","values":{"type":"array","items":"string"}}},
+		{"name":"next","type":["null","com.example.Event"]}
+	]}`)
+	cache := NewCodecCache(0)
+	cached, err := cache.Codec(`{"type":"record","name":"Node","fields":[{"name":"children","type":{"type":"array","items":"Node"}}]}`)
+	ensureError(t, err)
+
+	report := SelfCheck(context.Background(), SelfCheckConfig{Codecs: []*Codec{codec, cached}, Cache: cache})
+	if !report.Healthy {
+		t.Fatalf("GOT: %+v; WANT: healthy", report)
+	}
+	if actual, expected := len(report.Codecs), 2; actual != expected {
+		t.Fatalf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if actual, expected := report.Codecs[0], (SelfCheckResult{Name: "com.example.Event", Fingerprint: codec.Rabin}); actual != expected {
+		t.Errorf("GOT: %+v; WANT: %+v", actual, expected)
+	}
+	if report.Registry != nil {
+		t.Errorf("GOT: %+v; WANT: %v", report.Registry, nil)
+	}
+}
+
+func TestSelfCheckFailures(t *testing.T) {
+	infinite := newCodecUsingV2(t, `{"type":"record","name":"Loop","fields":[{"name":"next","type":"Loop"}]}`)
+	report := SelfCheck(context.Background(), SelfCheckConfig{
+		Codecs: []*Codec{infinite},
+		Registry: func(ctx context.Context) error {
+			return errors.New("connection refused")
+		},
+	})
+	if report.Healthy {
+		t.Errorf("GOT: %v; WANT: %v", report.Healthy, false)
+	}
+	if actual, expected := report.Codecs[0].Error, "schema has no finite datum"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if actual, expected := *report.Registry, (SelfCheckResult{Name: "registry", Error: "connection refused"}); actual != expected {
+		t.Errorf("GOT: %+v; WANT: %+v", actual, expected)
+	}
+}
+
+func TestSelfCheckHandler(t *testing.T) {
+	healthy := true
+	handler := NewSelfCheckHandler(SelfCheckConfig{
+		Codecs: []*Codec{newCodecUsingV2(t, `"string"`)},
+		Registry: func(ctx context.Context) error {
+			if !healthy {
+				return errors.New("timeout")
+			}
+			return nil
+		},
+	})
+
+	for _, tc := range []struct {
+		healthy bool
+		status  int
+	}{
+		{true, http.StatusOK},
+		{false, http.StatusServiceUnavailable},
+	} {
+		healthy = tc.healthy
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ready", nil))
+		if actual, expected := w.Code, tc.status; actual != expected {
+			t.Errorf("GOT: %v; WANT: %v", actual, expected)
+		}
+		var report SelfCheckReport
+		ensureError(t, json.NewDecoder(strings.NewReader(w.Body.String())).Decode(&report))
+		if actual, expected := report.Healthy, tc.healthy; actual != expected {
+			t.Errorf("GOT: %v; WANT: %v", actual, expected)
+		}
+	}
+}
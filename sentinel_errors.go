@@ -0,0 +1,99 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrInvalidBinary is returned by Codecs created with the SentinelErrors
+// option when binary encoded data cannot be decoded for a reason other than
+// ending before the datum does, such as an out of range union index. Data
+// ending early return io.ErrShortBuffer.
+var ErrInvalidBinary = errors.New("cannot decode binary: invalid data")
+
+// applySentinelErrors replaces the binary decoders of the primitive codecs in
+// the symbol table with decoders returning io.ErrShortBuffer or
+// ErrInvalidBinary rather than formatted errors. The int and long decoders
+// already return io.ErrShortBuffer. It ought to be applied before the decoders
+// are wrapped by other options.
+func applySentinelErrors(st map[string]*Codec, zeroCopy bool) {
+	st["boolean"].nativeFromBinary = booleanNativeFromBinarySentinel
+	st["bytes"].nativeFromBinary = bytesNativeFromBinarySentinel
+	st["double"].nativeFromBinary = sizedNativeFromBinarySentinel(doubleNativeFromBinary, doubleEncodedLength)
+	st["float"].nativeFromBinary = sizedNativeFromBinarySentinel(floatNativeFromBinary, floatEncodedLength)
+	st["string"].nativeFromBinary = stringNativeFromBinarySentinel
+	if zeroCopy {
+		st["string"].nativeFromBinary = stringNativeFromBinaryNoCopySentinel
+	}
+}
+
+// decodeError returns sentinel when the Codec was created with the
+// SentinelErrors option, and otherwise the error formatted from format and a.
+// Decoders pass either ErrInvalidBinary, or the error returned by a nested
+// decoder, which is already a sentinel error with the option, as sentinel.
+func decodeError(option *CodecOption, sentinel error, format string, a ...interface{}) error {
+	if option != nil && option.SentinelErrors {
+		return sentinel
+	}
+	return fmt.Errorf(format, a...)
+}
+
+func booleanNativeFromBinarySentinel(buf []byte) (interface{}, []byte, error) {
+	if len(buf) > 0 && buf[0] > 1 {
+		return nil, nil, ErrInvalidBinary
+	}
+	return booleanNativeFromBinary(buf)
+}
+
+func bytesNativeFromBinarySentinel(buf []byte) (interface{}, []byte, error) {
+	value, buf, err := longNativeFromBinary(buf)
+	if err != nil {
+		return nil, nil, err
+	}
+	size := value.(int64)
+	if size < 0 {
+		return nil, nil, ErrInvalidBinary
+	}
+	if size > int64(len(buf)) {
+		return nil, nil, io.ErrShortBuffer
+	}
+	return buf[:size], buf[size:], nil
+}
+
+func stringNativeFromBinarySentinel(buf []byte) (interface{}, []byte, error) {
+	value, buf, err := bytesNativeFromBinarySentinel(buf)
+	if err != nil {
+		return nil, nil, err
+	}
+	return string(value.([]byte)), buf, nil
+}
+
+func stringNativeFromBinaryNoCopySentinel(buf []byte) (interface{}, []byte, error) {
+	value, buf, err := bytesNativeFromBinarySentinel(buf)
+	if err != nil {
+		return nil, nil, err
+	}
+	return stringNoCopy(value.([]byte)), buf, nil
+}
+
+// sizedNativeFromBinarySentinel returns a decoder of values encoded in size
+// bytes, which returns io.ErrShortBuffer before calling decoder when buf is
+// shorter.
+func sizedNativeFromBinarySentinel(decoder func([]byte) (interface{}, []byte, error), size int) func([]byte) (interface{}, []byte, error) {
+	return func(buf []byte) (interface{}, []byte, error) {
+		if len(buf) < size {
+			return nil, nil, io.ErrShortBuffer
+		}
+		return decoder(buf)
+	}
+}
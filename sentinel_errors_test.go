@@ -0,0 +1,100 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"io"
+	"testing"
+)
+
+func TestSentinelErrors(t *testing.T) {
+	schema := `{"type":"record","name":"r","fields":[
+		{"name":"b","type":"boolean"},
+		{"name":"u","type":["null",{"type":"array","items":{"type":"map","values":"double"}}]},
+		{"name":"e","type":{"type":"enum","name":"e","symbols":["A"]}},
+		{"name":"f","type":{"type":"fixed","name":"f","size":2}},
+		{"name":"s","type":"string"}
+	]}`
+	codec, err := NewCodec(schema, WithSentinelErrors(true))
+	ensureError(t, err)
+
+	valid := []byte("\x01\x02\x02\x02\x02k\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00fg\x02s")
+	_, rest, err := codec.NativeFromBinary(valid)
+	ensureError(t, err)
+	if len(rest) != 0 {
+		t.Errorf("GOT: %v; WANT: %v", len(rest), 0)
+	}
+
+	cases := []struct {
+		buf      []byte
+		expected error
+	}{
+		{valid[:0], io.ErrShortBuffer},
+		{valid[:10], io.ErrShortBuffer}, // within the double of the map
+		{valid[:17], io.ErrShortBuffer}, // within the fixed
+		{valid[:20], io.ErrShortBuffer}, // within the string
+		{[]byte("\x02"), ErrInvalidBinary},
+		{[]byte("\x01\x04"), ErrInvalidBinary}, // union index
+		{[]byte("\x01\x02\x02\x04\x02k\x00\x00\x00\x00\x00\x00\x00\x00\x02k"), ErrInvalidBinary}, // duplicate map key
+		{[]byte("\x01\x00\x02"), ErrInvalidBinary},                                               // enum index
+		{[]byte("\x01\x00\x00fg\x01"), ErrInvalidBinary},                                         // string size
+	}
+	for _, c := range cases {
+		_, rest, err = codec.NativeFromBinary(c.buf)
+		if err != c.expected {
+			t.Errorf("%q: GOT: %v; WANT: %v", c.buf, err, c.expected)
+		}
+		if len(rest) != len(c.buf) {
+			t.Errorf("%q: GOT: %v; WANT: %v", c.buf, len(rest), len(c.buf))
+		}
+	}
+
+	// without the option, errors describe where decoding failed
+	codec, err = codec.WithOptions(WithSentinelErrors(false))
+	ensureError(t, err)
+	_, _, err = codec.NativeFromBinary(valid[:10])
+	ensureError(t, err, `cannot decode binary record "r" field "u"`, "short buffer")
+}
+
+func TestSentinelErrorsWithOptions(t *testing.T) {
+	codec, err := NewCodec(`{"type":"record","name":"r","fields":[{"name":"s","type":"string"},{"name":"e","type":{"type":"enum","name":"e","symbols":["A"]}}]}`)
+	ensureError(t, err)
+	derived, err := codec.WithOptions(WithSentinelErrors(true))
+	ensureError(t, err)
+	if _, _, err = derived.NativeFromBinary([]byte("\x06ab")); err != io.ErrShortBuffer {
+		t.Errorf("GOT: %v; WANT: %v", err, io.ErrShortBuffer)
+	}
+	if _, _, err = derived.NativeFromBinary([]byte("\x00\x02")); err != ErrInvalidBinary {
+		t.Errorf("GOT: %v; WANT: %v", err, ErrInvalidBinary)
+	}
+}
+
+func TestSentinelErrorsNumericAndZeroCopy(t *testing.T) {
+	codec, err := NewCodec(`{"type":"array","items":"float"}`, WithSentinelErrors(true), WithNumericDecoding(NumericDecodingWide))
+	ensureError(t, err)
+	datum, _, err := codec.NativeFromBinary([]byte("\x02\x00\x00\x80\x3f\x00"))
+	ensureError(t, err)
+	if actual, expected := datum.([]interface{})[0], float64(1); actual != expected {
+		t.Errorf("GOT: %#v; WANT: %#v", actual, expected)
+	}
+	if _, _, err = codec.NativeFromBinary([]byte("\x02\x00\x00")); err != io.ErrShortBuffer {
+		t.Errorf("GOT: %v; WANT: %v", err, io.ErrShortBuffer)
+	}
+
+	codec, err = NewCodec(`"string"`, WithSentinelErrors(true), WithZeroCopyDecoding(true))
+	ensureError(t, err)
+	buf := []byte("\x02a")
+	datum, _, err = codec.NativeFromBinary(buf)
+	ensureError(t, err)
+	buf[1] = 'b'
+	if actual, expected := datum, "b"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+// SkipBinary returns the bytes following the binary encoded datum at the start
+// of buf, without decoding the datum into native form. Array and map blocks
+// written with their size in bytes, such as by codecs created with the
+// BlockSizes option, are jumped over without reading their items, which makes
+// skipping unwanted fields of large records inexpensive.
+//
+//     // ignore the first datum, and decode the second
+//     buf, err := codec.SkipBinary(buf)
+//     if err != nil {
+//         return err
+//     }
+//     datum, buf, err := codec.NativeFromBinary(buf)
+func (c *Codec) SkipBinary(buf []byte) ([]byte, error) {
+	n, err := schemaNodeFromCodec(c)
+	if err != nil {
+		return buf, fmt.Errorf("cannot skip binary: %s", err)
+	}
+	rest, err := skipBinary(n, buf)
+	if err != nil {
+		return buf, fmt.Errorf("cannot skip binary: %s", err)
+	}
+	return rest, nil
+}
+
+func skipBinary(n *schemaNode, buf []byte) ([]byte, error) {
+	var value interface{}
+	var err error
+
+	switch n.typeName {
+	case "null":
+		return buf, nil
+	case "boolean":
+		return skipBinaryBytes(buf, 1, "boolean")
+	case "int", "long", "enum":
+		if _, buf, err = longNativeFromBinary(buf); err != nil {
+			return nil, fmt.Errorf("%s: %s", n.typeName, err)
+		}
+		return buf, nil
+	case "float":
+		return skipBinaryBytes(buf, floatEncodedLength, "float")
+	case "double":
+		return skipBinaryBytes(buf, doubleEncodedLength, "double")
+	case "bytes", "string":
+		if value, buf, err = longNativeFromBinary(buf); err != nil {
+			return nil, fmt.Errorf("%s size: %s", n.typeName, err)
+		}
+		return skipBinaryBytes(buf, value.(int64), n.typeName)
+	case "fixed":
+		return skipBinaryBytes(buf, int64(n.size), fmt.Sprintf("fixed %q", n.fullName))
+	case "union":
+		if value, buf, err = longNativeFromBinary(buf); err != nil {
+			return nil, fmt.Errorf("union index: %s", err)
+		}
+		index := value.(int64)
+		if index < 0 || index >= int64(len(n.members)) {
+			return nil, fmt.Errorf("union index ought to be between 0 and %d; read index: %d", len(n.members)-1, index)
+		}
+		return skipBinary(n.members[index], buf)
+	case "record":
+		for _, f := range n.fields {
+			if buf, err = skipBinary(f.node, buf); err != nil {
+				return nil, fmt.Errorf("record %q field %q: %s", n.fullName, f.name, err)
+			}
+		}
+		return buf, nil
+	case "array":
+		return skipBinaryBlocks(buf, "array", func(buf []byte) ([]byte, error) {
+			return skipBinary(n.items, buf)
+		})
+	case "map":
+		return skipBinaryBlocks(buf, "map", func(buf []byte) ([]byte, error) {
+			size, buf, err := longNativeFromBinary(buf)
+			if err != nil {
+				return nil, fmt.Errorf("key size: %s", err)
+			}
+			if buf, err = skipBinaryBytes(buf, size.(int64), "key"); err != nil {
+				return nil, err
+			}
+			return skipBinary(n.values, buf)
+		})
+	default:
+		return nil, fmt.Errorf("unknown type: %q", n.typeName)
+	}
+}
+
+func skipBinaryBytes(buf []byte, size int64, label string) ([]byte, error) {
+	if size < 0 {
+		return nil, fmt.Errorf("%s: negative size: %d", label, size)
+	}
+	if size > int64(len(buf)) {
+		return nil, fmt.Errorf("%s: size exceeds remaining buffer: %d > %d (%s)", label, size, len(buf), io.ErrShortBuffer)
+	}
+	return buf[size:], nil
+}
+
+// skipBinaryBlocks skips the blocks of an array or map, jumping over the blocks
+// that have a byte size, and invoking skipItem for each item of those that do
+// not.
+func skipBinaryBlocks(buf []byte, kind string, skipItem func([]byte) ([]byte, error)) ([]byte, error) {
+	for {
+		value, rest, err := longNativeFromBinary(buf)
+		if err != nil {
+			return nil, fmt.Errorf("%s block count: %s", kind, err)
+		}
+		buf = rest
+		blockCount := value.(int64)
+		if blockCount == 0 {
+			return buf, nil
+		}
+		if blockCount < 0 {
+			if blockCount == math.MinInt64 {
+				return nil, fmt.Errorf("%s with block count: %d", kind, blockCount)
+			}
+			if value, buf, err = longNativeFromBinary(buf); err != nil {
+				return nil, fmt.Errorf("%s block size: %s", kind, err)
+			}
+			if buf, err = skipBinaryBytes(buf, value.(int64), kind+" block"); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if blockCount > MaxBlockCount {
+			return nil, fmt.Errorf("%s when block count exceeds MaxBlockCount: %d > %d", kind, blockCount, MaxBlockCount)
+		}
+		for i := int64(0); i < blockCount; i++ {
+			if buf, err = skipItem(buf); err != nil {
+				return nil, fmt.Errorf("%s item: %s", kind, err)
+			}
+		}
+	}
+}
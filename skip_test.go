@@ -0,0 +1,70 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSkipBinary(t *testing.T) {
+	schema := `{
+  "type": "record",
+  "name": "r1",
+  "fields": [
+    {"name": "b", "type": "boolean"},
+    {"name": "l", "type": "long"},
+    {"name": "f", "type": "float"},
+    {"name": "d", "type": "double"},
+    {"name": "s", "type": "string"},
+    {"name": "x", "type": {"type": "fixed", "name": "f2", "size": 2}},
+    {"name": "e", "type": {"type": "enum", "name": "e1", "symbols": ["A", "B"]}},
+    {"name": "u", "type": ["null", "bytes"]},
+    {"name": "a", "type": {"type": "array", "items": "int"}},
+    {"name": "m", "type": {"type": "map", "values": "string"}}
+  ]
+}`
+	datum := map[string]interface{}{
+		"b": true,
+		"l": int64(-300),
+		"f": float32(1),
+		"d": 2.0,
+		"s": "hello",
+		"x": []byte("xy"),
+		"e": "B",
+		"u": Union("bytes", []byte{1, 2, 3}),
+		"a": []interface{}{1, 2, 3, 4, 5},
+		"m": map[string]interface{}{"k1": "v1", "k2": "v2"},
+	}
+	for _, option := range []CodecOption{{}, {BlockLength: 2}, {BlockSizes: true}, {BlockLength: 2, BlockSizes: true}} {
+		option := option
+		codec := newCodecWithOptionsUsingV2(t, schema, &option)
+		buf, err := codec.BinaryFromNative(nil, datum)
+		ensureError(t, err)
+		rest, err := codec.SkipBinary(append(buf, 0xAA))
+		ensureError(t, err)
+		if !bytes.Equal(rest, []byte{0xAA}) {
+			t.Errorf("%+v: GOT: %#v; WANT: %#v", option, rest, []byte{0xAA})
+		}
+	}
+}
+
+func TestSkipBinaryFail(t *testing.T) {
+	codec := newCodecUsingV2(t, `{"type":"array","items":"string"}`)
+	_, err := codec.SkipBinary([]byte{0x02, 0x08, 'a'})
+	ensureError(t, err, "cannot skip binary", "array item", "string", "size exceeds remaining buffer")
+
+	_, err = codec.SkipBinary([]byte{0x01, 0x10, 'a'})
+	ensureError(t, err, "array block", "size exceeds remaining buffer")
+
+	codec = newCodecUsingV2(t, `["null","int"]`)
+	_, err = codec.SkipBinary([]byte{0x04})
+	ensureError(t, err, "union index ought to be between 0 and 1")
+}
@@ -0,0 +1,172 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SnapshotAvroSchema is the Avro schema of the snapshot container encoded by
+// BinaryFromSnapshot, version 1. The state and the events are held as the
+// binary encoding of their own schemas, each with the Rabin fingerprint of
+// that schema, so a snapshot holds data of any version of those schemas.
+const SnapshotAvroSchema = `{"namespace":"com.linkedin.goavro","type":"record","name":"Snapshot","version":"1","doc":"State and events of an event-sourced aggregate","fields":[{"name":"aggregateType","type":"string"},{"name":"aggregateId","type":"string"},{"name":"version","type":"long"},{"name":"timestamp","type":{"type":"long","logicalType":"timestamp-millis"}},{"name":"metadata","type":{"type":"map","values":"string"}},{"name":"state","type":["null",{"type":"record","name":"SnapshotPayload","fields":[{"name":"fingerprint","type":{"type":"fixed","name":"Rabin","size":8}},{"name":"data","type":"bytes"}]}]},{"name":"events","type":{"type":"array","items":"SnapshotPayload"}}]}`
+
+const snapshotPayloadName = "com.linkedin.goavro.SnapshotPayload"
+
+var snapshotCodec *Codec
+
+func init() {
+	snapshotCodec, _ = NewCodec(SnapshotAvroSchema)
+}
+
+// Snapshot is the state of an event-sourced aggregate, and the events
+// recorded after that state, such as when storing periodic snapshots of an
+// aggregate, or the events of a transactional outbox.
+type Snapshot struct {
+	AggregateType string // type of the aggregate, such as "Order"
+	AggregateID   string // identity of the aggregate
+
+	// Version is the version of the aggregate that State represents, such as
+	// the sequence number of the last event applied to it. The events of
+	// Events have the versions that follow it.
+	Version int64
+
+	Time     time.Time         // time when the snapshot was taken
+	Metadata map[string]string // such as the name of the service that took it
+
+	// State is the state of the aggregate, or nil when the snapshot only
+	// holds events.
+	State *SnapshotPayload
+
+	// Events are the events recorded after State, oldest first.
+	Events []SnapshotPayload
+}
+
+// SnapshotPayload is the state or an event of a Snapshot: a datum, and the
+// codec of its schema.
+type SnapshotPayload struct {
+	Codec *Codec
+	Datum interface{}
+}
+
+// BinaryFromSnapshot appends the encoding of the snapshot, using
+// SnapshotAvroSchema, to buf. The state and each event is encoded using its
+// own codec, and recorded with the Rabin fingerprint of its schema.
+func BinaryFromSnapshot(buf []byte, snapshot *Snapshot) ([]byte, error) {
+	if snapshot.AggregateType == "" || snapshot.AggregateID == "" {
+		return nil, errors.New("cannot encode snapshot: AggregateType and AggregateID ought to be non-empty")
+	}
+	var state interface{}
+	if snapshot.State != nil {
+		payload, err := snapshotPayloadValue(snapshot.State)
+		if err != nil {
+			return nil, fmt.Errorf("cannot encode snapshot state: %s", err)
+		}
+		state = Union(snapshotPayloadName, payload)
+	}
+	events := make([]interface{}, len(snapshot.Events))
+	for i := range snapshot.Events {
+		payload, err := snapshotPayloadValue(&snapshot.Events[i])
+		if err != nil {
+			return nil, fmt.Errorf("cannot encode snapshot event %d: %s", i, err)
+		}
+		events[i] = payload
+	}
+	metadata := make(map[string]interface{}, len(snapshot.Metadata))
+	for k, v := range snapshot.Metadata {
+		metadata[k] = v
+	}
+	return snapshotCodec.BinaryFromNative(buf, map[string]interface{}{
+		"aggregateType": snapshot.AggregateType,
+		"aggregateId":   snapshot.AggregateID,
+		"version":       snapshot.Version,
+		"timestamp":     snapshot.Time,
+		"metadata":      metadata,
+		"state":         state,
+		"events":        events,
+	})
+}
+
+func snapshotPayloadValue(payload *SnapshotPayload) (map[string]interface{}, error) {
+	if payload.Codec == nil {
+		return nil, errors.New("codec is nil")
+	}
+	data, err := payload.Codec.BinaryFromNative(nil, payload.Datum)
+	if err != nil {
+		return nil, err
+	}
+	fingerprint := make([]byte, 8)
+	binary.LittleEndian.PutUint64(fingerprint, payload.Codec.Rabin)
+	return map[string]interface{}{"fingerprint": fingerprint, "data": data}, nil
+}
+
+// SnapshotFromBinary decodes one snapshot from buf, encoded using
+// SnapshotAvroSchema. The state and the events are decoded using the codec of
+// codecs whose Rabin fingerprint matches that recorded with each, so codecs
+// holds each version of the state and event schemas the snapshot may use.
+// When the schemas of a version ought to be read using a newer schema, map
+// the fingerprint of the older version to a codec of the newer schema, and
+// convert the data using a Resolution after decoding.
+//
+// On success, it returns the decoded snapshot, the remaining unread bytes of
+// buf, and a nil error value. On error, it returns nil for the snapshot, the
+// original buf, and the error message.
+func SnapshotFromBinary(buf []byte, codecs map[uint64]*Codec) (*Snapshot, []byte, error) {
+	datum, newBuf, err := snapshotCodec.NativeFromBinary(buf)
+	if err != nil {
+		return nil, buf, fmt.Errorf("cannot decode snapshot: %s", err)
+	}
+	record := datum.(map[string]interface{})
+	snapshot := &Snapshot{
+		AggregateType: record["aggregateType"].(string),
+		AggregateID:   record["aggregateId"].(string),
+		Version:       record["version"].(int64),
+		Time:          record["timestamp"].(time.Time),
+		Metadata:      make(map[string]string),
+	}
+	for k, v := range record["metadata"].(map[string]interface{}) {
+		snapshot.Metadata[k] = v.(string)
+	}
+	if state, ok := record["state"].(map[string]interface{}); ok {
+		payload, err := snapshotPayloadFromValue(state[snapshotPayloadName].(map[string]interface{}), codecs)
+		if err != nil {
+			return nil, buf, fmt.Errorf("cannot decode snapshot state: %s", err)
+		}
+		snapshot.State = &payload
+	}
+	events := record["events"].([]interface{})
+	snapshot.Events = make([]SnapshotPayload, len(events))
+	for i, event := range events {
+		if snapshot.Events[i], err = snapshotPayloadFromValue(event.(map[string]interface{}), codecs); err != nil {
+			return nil, buf, fmt.Errorf("cannot decode snapshot event %d: %s", i, err)
+		}
+	}
+	return snapshot, newBuf, nil
+}
+
+func snapshotPayloadFromValue(value map[string]interface{}, codecs map[uint64]*Codec) (SnapshotPayload, error) {
+	fingerprint := binary.LittleEndian.Uint64(value["fingerprint"].([]byte))
+	codec, ok := codecs[fingerprint]
+	if !ok {
+		return SnapshotPayload{}, fmt.Errorf("unknown schema fingerprint: %#016x", fingerprint)
+	}
+	datum, rest, err := codec.NativeFromBinary(value["data"].([]byte))
+	if err != nil {
+		return SnapshotPayload{}, err
+	}
+	if len(rest) > 0 {
+		return SnapshotPayload{}, fmt.Errorf("extra bytes after data item: %d", len(rest))
+	}
+	return SnapshotPayload{Codec: codec, Datum: datum}, nil
+}
@@ -0,0 +1,80 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	state, err := NewCodec(`{"type":"record","name":"Order","fields":[{"name":"total","type":"long"}]}`)
+	ensureError(t, err)
+	added, err := NewCodec(`{"type":"record","name":"ItemAdded","fields":[{"name":"price","type":"long"}]}`)
+	ensureError(t, err)
+	shipped, err := NewCodec(`{"type":"record","name":"Shipped","fields":[]}`)
+	ensureError(t, err)
+
+	snapshot := &Snapshot{
+		AggregateType: "Order",
+		AggregateID:   "o-1",
+		Version:       3,
+		Time:          time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC),
+		Metadata:      map[string]string{"service": "orders"},
+		State:         &SnapshotPayload{Codec: state, Datum: map[string]interface{}{"total": int64(30)}},
+		Events: []SnapshotPayload{
+			{Codec: added, Datum: map[string]interface{}{"price": int64(5)}},
+			{Codec: shipped, Datum: map[string]interface{}{}},
+		},
+	}
+	buf, err := BinaryFromSnapshot([]byte("prefix"), snapshot)
+	ensureError(t, err)
+
+	codecs := map[uint64]*Codec{state.Rabin: state, added.Rabin: added, shipped.Rabin: shipped}
+	decoded, rest, err := SnapshotFromBinary(buf[6:], codecs)
+	ensureError(t, err)
+	if len(rest) != 0 {
+		t.Errorf("GOT: %v; WANT: %v", rest, []byte{})
+	}
+	if !decoded.Time.Equal(snapshot.Time) {
+		t.Errorf("GOT: %v; WANT: %v", decoded.Time, snapshot.Time)
+	}
+	decoded.Time = snapshot.Time
+	if !reflect.DeepEqual(decoded, snapshot) {
+		t.Errorf("GOT: %+v; WANT: %+v", decoded, snapshot)
+	}
+
+	// snapshots holding only events, such as an outbox
+	buf, err = BinaryFromSnapshot(nil, &Snapshot{AggregateType: "Order", AggregateID: "o-2", Events: snapshot.Events[1:]})
+	ensureError(t, err)
+	decoded, _, err = SnapshotFromBinary(buf, codecs)
+	ensureError(t, err)
+	if decoded.State != nil || len(decoded.Events) != 1 || decoded.Events[0].Codec != shipped {
+		t.Errorf("GOT: %+v", decoded)
+	}
+
+	_, _, err = SnapshotFromBinary(buf, map[uint64]*Codec{})
+	ensureError(t, err, "cannot decode snapshot event 0: unknown schema fingerprint")
+}
+
+func TestSnapshotErrors(t *testing.T) {
+	state, err := NewCodec(`"long"`)
+	ensureError(t, err)
+
+	_, err = BinaryFromSnapshot(nil, &Snapshot{AggregateType: "Order"})
+	ensureError(t, err, "AggregateType and AggregateID ought to be non-empty")
+	_, err = BinaryFromSnapshot(nil, &Snapshot{AggregateType: "Order", AggregateID: "o-1", State: &SnapshotPayload{Codec: state, Datum: "total"}})
+	ensureError(t, err, "cannot encode snapshot state", "expected: Go numeric")
+	_, err = BinaryFromSnapshot(nil, &Snapshot{AggregateType: "Order", AggregateID: "o-1", Events: []SnapshotPayload{{}}})
+	ensureError(t, err, "cannot encode snapshot event 0: codec is nil")
+	_, _, err = SnapshotFromBinary([]byte{0x02}, nil)
+	ensureError(t, err, "cannot decode snapshot")
+}
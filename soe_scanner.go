@@ -0,0 +1,154 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"fmt"
+	"io"
+)
+
+// SOEFingerprintCount reports how many single-object encoded messages used the
+// schema with the specified Rabin fingerprint.
+type SOEFingerprintCount struct {
+	Fingerprint  uint64 // Rabin fingerprint of the canonical form of the writer schema
+	Count        uint64 // number of messages using the schema
+	FirstMessage uint64 // position of the first message using the schema, counting from zero
+}
+
+// SOEScanner reads a stream of single-object encoded messages, and counts the
+// distinct writer schema fingerprints it encounters, without decoding the
+// messages, so the schema versions active on a topic may be discovered without
+// querying a schema registry for every message.
+//
+// Single-object encoded messages do not encode their own length, so each
+// message of the stream is preceded by its length, encoded as an Avro long, as
+// though the stream were a sequence of Avro bytes values. Messages that arrive
+// in their own frames, such as Kafka records, are counted using Add instead.
+// An SOEScanner ought not to be used by multiple goroutines simultaneously.
+//
+//	scanner := goavro.NewSOEScanner(r)
+//	for scanner.Scan() {
+//	    if _, err := scanner.Fingerprint(); err != nil {
+//	        log.Print(err) // message is not single-object encoded
+//	    }
+//	}
+//	if err := scanner.Err(); err != nil {
+//	    return err
+//	}
+//	for _, fc := range scanner.Fingerprints() {
+//	    fmt.Printf("%#016x: %d messages\n", fc.Fingerprint, fc.Count)
+//	}
+type SOEScanner struct {
+	ior          io.Reader
+	err          error  // error that stopped the scanner
+	buf          []byte // reused for each message read from ior
+	message      []byte // most recently scanned message
+	fingerprint  uint64 // fingerprint of message
+	messageErr   error  // error of message when it is not single-object encoded
+	messages     uint64 // number of messages counted
+	invalid      uint64 // number of messages that are not single-object encoded
+	indexes      map[uint64]int
+	fingerprints []SOEFingerprintCount
+}
+
+// NewSOEScanner returns an SOEScanner that reads length prefixed single-object
+// encoded messages from ior. When ior is nil, messages may only be counted
+// using Add.
+func NewSOEScanner(ior io.Reader) *SOEScanner {
+	return &SOEScanner{ior: ior, indexes: make(map[uint64]int)}
+}
+
+// Scan reads the next message of the stream, returning false when the end of
+// the stream is reached, or when an error occurs while reading it. Messages
+// that are not single-object encoded do not stop the scanner; their error is
+// returned by Fingerprint.
+func (s *SOEScanner) Scan() bool {
+	if s.err != nil || s.ior == nil {
+		return false
+	}
+	size, err := longBinaryReader(s.ior)
+	if err != nil {
+		if err != io.EOF {
+			s.err = fmt.Errorf("cannot read message %d: cannot read size: %s", s.messages, err)
+		}
+		return false
+	}
+	if size < 0 {
+		s.err = fmt.Errorf("cannot read message %d: size is negative: %d", s.messages, size)
+		return false
+	}
+	if size > MaxBlockSize {
+		s.err = fmt.Errorf("cannot read message %d: size exceeds MaxBlockSize: %d > %d", s.messages, size, MaxBlockSize)
+		return false
+	}
+	if int64(cap(s.buf)) < size {
+		s.buf = make([]byte, size)
+	}
+	s.buf = s.buf[:size]
+	if _, err = io.ReadFull(s.ior, s.buf); err != nil {
+		s.err = fmt.Errorf("cannot read message %d: %s", s.messages, err)
+		return false
+	}
+	_, _ = s.Add(s.buf)
+	return true
+}
+
+// Add counts the fingerprint of a single-object encoded message, which it
+// returns, and makes the message the current message of the scanner. When the
+// message is not single-object encoded, it is counted as invalid, and the
+// error is returned.
+func (s *SOEScanner) Add(message []byte) (uint64, error) {
+	s.message = message
+	s.fingerprint, _, s.messageErr = FingerprintFromSOE(message)
+	if s.messageErr != nil {
+		s.invalid++
+		s.messages++
+		return 0, s.messageErr
+	}
+	if i, ok := s.indexes[s.fingerprint]; ok {
+		s.fingerprints[i].Count++
+	} else {
+		s.indexes[s.fingerprint] = len(s.fingerprints)
+		s.fingerprints = append(s.fingerprints, SOEFingerprintCount{Fingerprint: s.fingerprint, Count: 1, FirstMessage: s.messages})
+	}
+	s.messages++
+	return s.fingerprint, nil
+}
+
+// Message returns the most recently scanned message, including its
+// single-object encoding header. The returned slice is only valid until the
+// next call to Scan.
+func (s *SOEScanner) Message() []byte {
+	return s.message
+}
+
+// Fingerprint returns the writer schema fingerprint of the most recently
+// scanned message, or an error when the message is not single-object encoded.
+func (s *SOEScanner) Fingerprint() (uint64, error) {
+	return s.fingerprint, s.messageErr
+}
+
+// Err returns the error that stopped the scanner, or nil when the end of the
+// stream was reached.
+func (s *SOEScanner) Err() error {
+	return s.err
+}
+
+// Fingerprints returns the distinct fingerprints counted so far, in the order
+// they were first encountered.
+func (s *SOEScanner) Fingerprints() []SOEFingerprintCount {
+	return append([]SOEFingerprintCount(nil), s.fingerprints...)
+}
+
+// Messages returns the number of messages counted so far, along with the number
+// of them that were not single-object encoded.
+func (s *SOEScanner) Messages() (total, invalid uint64) {
+	return s.messages, s.invalid
+}
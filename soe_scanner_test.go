@@ -0,0 +1,90 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestSOEScanner(t *testing.T) {
+	longCodec := newCodecUsingV2(t, `"long"`)
+	stringCodec := newCodecUsingV2(t, `"string"`)
+
+	var stream []byte
+	appendMessage := func(message []byte) {
+		stream, _ = bytesBinaryFromNative(stream, message)
+	}
+	for i, codec := range []*Codec{longCodec, stringCodec, longCodec, longCodec} {
+		var datum interface{} = int64(i)
+		if codec == stringCodec {
+			datum = "s"
+		}
+		message, err := codec.SingleFromNative(nil, datum)
+		ensureError(t, err)
+		appendMessage(message)
+	}
+	appendMessage([]byte{0x01, 0x02}) // not single-object encoded
+
+	scanner := NewSOEScanner(bytes.NewReader(stream))
+	var invalid int
+	for scanner.Scan() {
+		if _, err := scanner.Fingerprint(); err != nil {
+			ensureError(t, err, "cannot decode buffer as single-object encoding")
+			invalid++
+		}
+	}
+	ensureError(t, scanner.Err())
+	if invalid != 1 {
+		t.Errorf("GOT: %d; WANT: %d", invalid, 1)
+	}
+
+	expected := []SOEFingerprintCount{
+		{Fingerprint: longCodec.Rabin, Count: 3, FirstMessage: 0},
+		{Fingerprint: stringCodec.Rabin, Count: 1, FirstMessage: 1},
+	}
+	if actual := scanner.Fingerprints(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if total, invalid := scanner.Messages(); total != 5 || invalid != 1 {
+		t.Errorf("GOT: %d, %d; WANT: %d, %d", total, invalid, 5, 1)
+	}
+
+	// messages that arrive in their own frames
+	message, err := stringCodec.SingleFromNative(nil, "t")
+	ensureError(t, err)
+	fingerprint, err := scanner.Add(message)
+	ensureError(t, err)
+	if fingerprint != stringCodec.Rabin || !bytes.Equal(scanner.Message(), message) {
+		t.Errorf("GOT: %#x, %v; WANT: %#x, %v", fingerprint, scanner.Message(), stringCodec.Rabin, message)
+	}
+	if actual := scanner.Fingerprints()[1]; actual.Count != 2 {
+		t.Errorf("GOT: %d; WANT: %d", actual.Count, 2)
+	}
+}
+
+func TestSOEScannerTruncated(t *testing.T) {
+	message, err := newCodecUsingV2(t, `"long"`).SingleFromNative(nil, 1)
+	ensureError(t, err)
+	stream, _ := bytesBinaryFromNative(nil, message)
+
+	scanner := NewSOEScanner(bytes.NewReader(stream[:len(stream)-1]))
+	if scanner.Scan() {
+		t.Errorf("GOT: %v; WANT: %v", true, false)
+	}
+	ensureError(t, scanner.Err(), "cannot read message 0", "unexpected EOF")
+
+	scanner = NewSOEScanner(bytes.NewReader([]byte{0x01}))
+	if scanner.Scan() {
+		t.Errorf("GOT: %v; WANT: %v", true, false)
+	}
+	ensureError(t, scanner.Err(), "cannot read message 0", "size is negative")
+}
@@ -0,0 +1,371 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// TableConfig is used to specify how TableWriter writes records as rows of a
+// table.
+type TableConfig struct {
+	// Separator joins the names of the fields of nested records to name
+	// their columns, such as "address.city", (optional). When empty, "." is
+	// used.
+	Separator string
+
+	// FlattenDepth limits the levels of nested records whose fields are
+	// written as columns of their own, (optional). The fields of the top level
+	// record are at depth 1, so when FlattenDepth is 1, no nested records are
+	// flattened. When zero, nested records are flattened at any depth, and
+	// when negative, none are. Records that are not flattened, arrays, maps,
+	// and unions other than of null and one other type are written as their
+	// Avro JSON encoding.
+	FlattenDepth int
+
+	// NullToken is written for null values, (optional). When empty, null
+	// values are written as empty values.
+	NullToken string
+
+	// Comma is the field delimiter of CSV, (optional). When zero, ',' is
+	// used. Use '\t' for TSV.
+	Comma rune
+
+	// QuoteAll quotes each CSV value, (optional), rather than only the values
+	// that require it. Null values are never quoted, so null values may be
+	// told apart from empty strings even when NullToken is empty.
+	QuoteAll bool
+
+	// OmitHeader omits the header row of CSV, which names the columns,
+	// (optional).
+	OmitHeader bool
+}
+
+// TableWriter writes the records of a record schema as the rows of a table, in
+// CSV or LTSV, so analysts may pull extracts of Avro data into tools that do
+// not read Avro. The fields of nested records, and of nullable nested records,
+// are flattened into columns of their own, as configured by TableConfig.
+// Values of primitive types, enums, and fixed are written like they are given
+// to NativeFromForm: bytes and fixed as their bytes, and logical types as the
+// values of their underlying type.
+type TableWriter struct {
+	*tableLayout
+	bw     *bufio.Writer
+	config TableConfig
+	row    []string
+	isNull []bool
+	ltsv   bool
+}
+
+// tableLayout flattens the records of a record schema into the columns of a
+// table.
+type tableLayout struct {
+	codec   *Codec
+	fields  []tableField
+	columns []string
+	nodes   []*schemaNode     // type of the values of each column, other than null when nullable
+	values  []json.RawMessage // Avro JSON encoding of the value of each column of the current row, or nil when null
+}
+
+// tableField is a field of a record written as one column, or, when it holds a
+// flattened record, as the columns of the fields of that record.
+type tableField struct {
+	name     string
+	node     *schemaNode // of the type other than null when nullable
+	nullable bool        // a union of null and one other type
+	fields   []tableField
+	first    int // index of the first column of the field
+	width    int // number of columns of the field
+}
+
+// NewCSVWriter returns a TableWriter that writes the records of the record
+// schema of the Codec to w as CSV, as specified by RFC 4180, with a header row
+// unless omitted by the configuration. The writer buffers its output, so
+// Flush ought to be called after the final record.
+//
+//     tw, err := goavro.NewCSVWriter(os.Stdout, codec, goavro.TableConfig{NullToken: "NULL"})
+//     if err != nil {
+//         return err
+//     }
+//     for _, datum := range data {
+//         if err = tw.Write(datum); err != nil {
+//             return err
+//         }
+//     }
+//     return tw.Flush()
+func NewCSVWriter(w io.Writer, codec *Codec, config TableConfig) (*TableWriter, error) {
+	if config.Comma == 0 {
+		config.Comma = ','
+	}
+	if config.Comma == '"' || config.Comma == '\r' || config.Comma == '\n' || !utf8.ValidRune(config.Comma) {
+		return nil, fmt.Errorf("cannot create CSV writer: invalid Comma: %q", config.Comma)
+	}
+	tw, err := newTableWriter(w, codec, config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create CSV writer: %s", err)
+	}
+	if !config.OmitHeader {
+		for i, column := range tw.columns {
+			tw.row[i], tw.isNull[i] = column, false
+		}
+		tw.writeCSVRow()
+	}
+	return tw, nil
+}
+
+// NewLTSVWriter returns a TableWriter that writes the records of the record
+// schema of the Codec to w as LTSV, Labeled Tab-separated Values, with a line
+// for each record of a label and a value for each column. As LTSV values may
+// not hold tabs or line breaks, the backslash, tab, line feed, and carriage
+// return characters of values are escaped as `\\`, `\t`, `\n`, and `\r`. The
+// Comma, QuoteAll, and OmitHeader options of the configuration do not apply.
+// The writer buffers its output, so Flush ought to be called after the final
+// record.
+func NewLTSVWriter(w io.Writer, codec *Codec, config TableConfig) (*TableWriter, error) {
+	if strings.ContainsAny(config.Separator, ":\t\r\n") {
+		return nil, fmt.Errorf("cannot create LTSV writer: Separator ought not to contain a colon, tab, or line break: %q", config.Separator)
+	}
+	tw, err := newTableWriter(w, codec, config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create LTSV writer: %s", err)
+	}
+	tw.ltsv = true
+	return tw, nil
+}
+
+func newTableWriter(w io.Writer, codec *Codec, config TableConfig) (*TableWriter, error) {
+	tl, err := newTableLayout(codec, config)
+	if err != nil {
+		return nil, err
+	}
+	return &TableWriter{
+		tableLayout: tl,
+		bw:          bufio.NewWriter(w),
+		config:      config,
+		row:         make([]string, len(tl.columns)),
+		isNull:      make([]bool, len(tl.columns)),
+	}, nil
+}
+
+func newTableLayout(codec *Codec, config TableConfig) (*tableLayout, error) {
+	if config.Separator == "" {
+		config.Separator = "."
+	}
+	root, err := schemaNodeFromCodec(codec)
+	if err != nil {
+		return nil, err
+	}
+	if root.typeName != "record" {
+		return nil, fmt.Errorf("schema ought to be a record; received: %s", root.label())
+	}
+	tl := &tableLayout{codec: codec}
+	tl.fields = tl.flatten(config, root, "", 1, map[*schemaNode]bool{root: true})
+	tl.values = make([]json.RawMessage, len(tl.columns))
+	return tl, nil
+}
+
+// flatten returns the fields of the record described by n, appending their
+// columns to the layout. Records that enclose n are in ancestors, and are
+// never flattened again, so recursive records terminate.
+func (tl *tableLayout) flatten(config TableConfig, n *schemaNode, prefix string, depth int, ancestors map[*schemaNode]bool) []tableField {
+	fields := make([]tableField, len(n.fields))
+	for i, f := range n.fields {
+		tf := tableField{name: f.name, node: f.node, first: len(tl.columns)}
+		if members := f.node.members; f.node.typeName == "union" && len(members) == 2 && (members[0].typeName == "null") != (members[1].typeName == "null") {
+			tf.nullable = true
+			if tf.node = members[0]; tf.node.typeName == "null" {
+				tf.node = members[1]
+			}
+		}
+		flattened := config.FlattenDepth == 0 || depth < config.FlattenDepth
+		if tf.node.typeName == "record" && flattened && !ancestors[tf.node] {
+			ancestors[tf.node] = true
+			tf.fields = tl.flatten(config, tf.node, prefix+f.name+config.Separator, depth+1, ancestors)
+			delete(ancestors, tf.node)
+		} else {
+			tl.columns = append(tl.columns, prefix+f.name)
+			tl.nodes = append(tl.nodes, tf.node)
+		}
+		tf.width = len(tl.columns) - tf.first
+		fields[i] = tf
+	}
+	return fields
+}
+
+// Columns returns the names of the columns of the table, in order. The
+// returned slice ought not to be modified.
+func (tw *TableWriter) Columns() []string {
+	return tw.columns
+}
+
+// Write writes the record datum as a row of the table.
+func (tw *TableWriter) Write(datum interface{}) error {
+	if err := tw.fill(datum); err != nil {
+		return fmt.Errorf("cannot write row: %s", err)
+	}
+	for i, value := range tw.values {
+		if value == nil {
+			tw.row[i], tw.isNull[i] = tw.config.NullToken, true
+			continue
+		}
+		s := string(value)
+		if isFormScalar(tw.nodes[i]) {
+			var err error
+			if s, err = formValue(tw.nodes[i], value); err != nil {
+				return fmt.Errorf("cannot write row: column %q: %s", tw.columns[i], err) // should not get here
+			}
+		}
+		tw.row[i], tw.isNull[i] = s, false
+	}
+	if tw.ltsv {
+		tw.writeLTSVRow()
+	} else {
+		tw.writeCSVRow()
+	}
+	return nil
+}
+
+// Flush writes any buffered rows to the underlying io.Writer, returning the
+// first error that took place while writing.
+func (tw *TableWriter) Flush() error {
+	return tw.bw.Flush()
+}
+
+// Export writes each remaining data item read from the OCF as a row of the
+// table, then flushes the writer, returning the number of rows written. The
+// data items ought to be records of the schema of the Codec of the writer,
+// such as when the writer was created with the Codec of the OCFReader.
+//
+//     ocfr, err := goavro.NewOCFReader(bufio.NewReader(file))
+//     if err != nil {
+//         return err
+//     }
+//     tw, err := goavro.NewCSVWriter(os.Stdout, ocfr.Codec(), goavro.TableConfig{})
+//     if err != nil {
+//         return err
+//     }
+//     _, err = tw.Export(ocfr)
+//     return err
+func (tw *TableWriter) Export(ocfr *OCFReader) (int64, error) {
+	var rows int64
+	for ocfr.Scan() {
+		datum, err := ocfr.Read()
+		if err != nil {
+			return rows, err
+		}
+		if err = tw.Write(datum); err != nil {
+			return rows, err
+		}
+		rows++
+	}
+	if err := ocfr.Err(); err != nil {
+		return rows, err
+	}
+	return rows, tw.Flush()
+}
+
+// fill sets the values of the columns from the record datum.
+func (tl *tableLayout) fill(datum interface{}) error {
+	buf, err := tl.codec.TextualFromNative(nil, datum)
+	if err != nil {
+		return err
+	}
+	return tl.fillFields(tl.fields, buf)
+}
+
+// fillFields sets the values of the columns of the fields from the Avro JSON
+// encoding of their record.
+func (tl *tableLayout) fillFields(fields []tableField, buf []byte) error {
+	var record map[string]json.RawMessage
+	if err := json.Unmarshal(buf, &record); err != nil {
+		return err // should not get here
+	}
+	for _, f := range fields {
+		value := record[f.name]
+		if f.nullable {
+			if string(value) == "null" {
+				for i := f.first; i < f.first+f.width; i++ {
+					tl.values[i] = nil
+				}
+				continue
+			}
+			var union map[string]json.RawMessage
+			if err := json.Unmarshal(value, &union); err != nil || len(union) != 1 {
+				return fmt.Errorf("cannot decode union value of field %q: %s", f.name, value) // should not get here
+			}
+			for _, v := range union {
+				value = v
+			}
+		}
+		if f.fields != nil {
+			if err := tl.fillFields(f.fields, value); err != nil {
+				return err
+			}
+			continue
+		}
+		if string(value) == "null" && (f.node.typeName == "null" || f.node.typeName == "union") {
+			value = nil
+		}
+		tl.values[f.first] = value
+	}
+	return nil
+}
+
+func (tw *TableWriter) writeCSVRow() {
+	for i, value := range tw.row {
+		if i > 0 {
+			_, _ = tw.bw.WriteRune(tw.config.Comma)
+		}
+		if tw.isNull[i] || !(tw.config.QuoteAll || tw.csvNeedsQuotes(value)) {
+			_, _ = tw.bw.WriteString(value)
+			continue
+		}
+		_ = tw.bw.WriteByte('"')
+		_, _ = tw.bw.WriteString(strings.Replace(value, `"`, `""`, -1))
+		_ = tw.bw.WriteByte('"')
+	}
+	_ = tw.bw.WriteByte('\n')
+}
+
+// csvNeedsQuotes returns true when the CSV value would not be read back as the
+// same value unless quoted.
+func (tw *TableWriter) csvNeedsQuotes(value string) bool {
+	if value == "" {
+		return false
+	}
+	if strings.ContainsRune(value, tw.config.Comma) || strings.ContainsAny(value, "\"\r\n") {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(value)
+	return r == ' ' || r == '\t'
+}
+
+// ltsvEscaper escapes the characters LTSV values may not hold.
+var ltsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+func (tw *TableWriter) writeLTSVRow() {
+	for i, value := range tw.row {
+		if i > 0 {
+			_ = tw.bw.WriteByte('\t')
+		}
+		_, _ = tw.bw.WriteString(tw.columns[i])
+		_ = tw.bw.WriteByte(':')
+		_, _ = ltsvEscaper.WriteString(tw.bw, value)
+	}
+	_ = tw.bw.WriteByte('\n')
+}
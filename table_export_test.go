@@ -0,0 +1,152 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+)
+
+const tableTestSchema = `{"type":"record","name":"person","fields":[
+	{"name":"name","type":"string"},
+	{"name":"age","type":["null","int"]},
+	{"name":"score","type":"double"},
+	{"name":"address","type":["null",{"type":"record","name":"address","fields":[
+		{"name":"city","type":"string"},
+		{"name":"geo","type":{"type":"record","name":"geo","fields":[{"name":"lat","type":"double"}]}}
+	]}]},
+	{"name":"tags","type":{"type":"array","items":"string"}}
+]}`
+
+func tableTestData() []interface{} {
+	return []interface{}{
+		map[string]interface{}{
+			"name":    "Ann, \"the\" first",
+			"age":     Union("int", 42),
+			"score":   1.5,
+			"address": Union("address", map[string]interface{}{"city": "Oslo", "geo": map[string]interface{}{"lat": 59.9}}),
+			"tags":    []interface{}{"a", "b"},
+		},
+		map[string]interface{}{
+			"name":    "",
+			"age":     nil,
+			"score":   math.Inf(1),
+			"address": nil,
+			"tags":    []interface{}{},
+		},
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	codec, err := NewCodec(tableTestSchema)
+	ensureError(t, err)
+
+	bb := new(bytes.Buffer)
+	tw, err := NewCSVWriter(bb, codec, TableConfig{})
+	ensureError(t, err)
+	if actual, expected := tw.Columns(), []string{"name", "age", "score", "address.city", "address.geo.lat", "tags"}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	for _, datum := range tableTestData() {
+		ensureError(t, tw.Write(datum))
+	}
+	ensureError(t, tw.Flush())
+	expected := "name,age,score,address.city,address.geo.lat,tags\n" +
+		"\"Ann, \"\"the\"\" first\",42,1.5,Oslo,59.9,\"[\"\"a\"\",\"\"b\"\"]\"\n" +
+		",,+Inf,,,[]\n"
+	if actual := bb.String(); actual != expected {
+		t.Errorf("GOT: %q; WANT: %q", actual, expected)
+	}
+
+	// null values are not quoted, so they differ from empty strings
+	bb.Reset()
+	tw, err = NewCSVWriter(bb, codec, TableConfig{Comma: '\t', QuoteAll: true, OmitHeader: true, FlattenDepth: 2, Separator: "_"})
+	ensureError(t, err)
+	ensureError(t, tw.Write(tableTestData()[1]))
+	ensureError(t, tw.Flush())
+	if actual, expected := bb.String(), "\"\"\t\t\"+Inf\"\t\t\t\"[]\"\n"; actual != expected {
+		t.Errorf("GOT: %q; WANT: %q", actual, expected)
+	}
+	if actual, expected := tw.Columns(), []string{"name", "age", "score", "address_city", "address_geo", "tags"}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	_, err = NewCSVWriter(bb, codec, TableConfig{Comma: '"'})
+	ensureError(t, err, "cannot create CSV writer", "invalid Comma")
+	codec, err = NewCodec(`"string"`)
+	ensureError(t, err)
+	_, err = NewCSVWriter(bb, codec, TableConfig{})
+	ensureError(t, err, "cannot create CSV writer", "schema ought to be a record")
+}
+
+func TestLTSVWriter(t *testing.T) {
+	codec, err := NewCodec(tableTestSchema)
+	ensureError(t, err)
+
+	bb := new(bytes.Buffer)
+	tw, err := NewLTSVWriter(bb, codec, TableConfig{NullToken: "-", FlattenDepth: -1})
+	ensureError(t, err)
+	data := tableTestData()
+	data[0].(map[string]interface{})["name"] = "tab\there"
+	for _, datum := range data {
+		ensureError(t, tw.Write(datum))
+	}
+	ensureError(t, tw.Flush())
+	expected := "name:tab\\there\tage:42\tscore:1.5\taddress:{\"city\":\"Oslo\",\"geo\":{\"lat\":59.9}}\ttags:[\"a\",\"b\"]\n" +
+		"name:\tage:-\tscore:+Inf\taddress:-\ttags:[]\n"
+	if actual := bb.String(); actual != expected {
+		t.Errorf("GOT: %q; WANT: %q", actual, expected)
+	}
+
+	_, err = NewLTSVWriter(bb, codec, TableConfig{Separator: ":"})
+	ensureError(t, err, "cannot create LTSV writer", "Separator")
+}
+
+func TestTableWriterRecursive(t *testing.T) {
+	codec, err := NewCodec(`{"type":"record","name":"node","fields":[
+		{"name":"value","type":"bytes"},
+		{"name":"next","type":["null","node"]}
+	]}`)
+	ensureError(t, err)
+	bb := new(bytes.Buffer)
+	tw, err := NewCSVWriter(bb, codec, TableConfig{})
+	ensureError(t, err)
+	ensureError(t, tw.Write(map[string]interface{}{"value": []byte("a"), "next": Union("node", map[string]interface{}{"value": []byte("b"), "next": nil})}))
+	ensureError(t, tw.Flush())
+	if actual, expected := bb.String(), "value,next\na,\"{\"\"value\"\":\"\"b\"\",\"\"next\"\":null}\"\n"; actual != expected {
+		t.Errorf("GOT: %q; WANT: %q", actual, expected)
+	}
+}
+
+func TestTableWriterExport(t *testing.T) {
+	bb := new(bytes.Buffer)
+	ocfw, err := NewOCFWriter(OCFConfig{W: bb, Schema: tableTestSchema})
+	ensureError(t, err)
+	ensureError(t, ocfw.Append(tableTestData()))
+
+	ocfr, err := NewOCFReader(bytes.NewReader(bb.Bytes()))
+	ensureError(t, err)
+	out := new(bytes.Buffer)
+	tw, err := NewCSVWriter(out, ocfr.Codec(), TableConfig{OmitHeader: true})
+	ensureError(t, err)
+	rows, err := tw.Export(ocfr)
+	ensureError(t, err)
+	if actual, expected := rows, int64(2); actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	if actual, expected := bytes.Count(out.Bytes(), []byte("\n")), 2; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+}
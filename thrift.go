@@ -0,0 +1,833 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// thriftIDAttribute is the record field attribute used to retain the Thrift
+// field identifier when translating between Thrift and Avro schemas.
+const thriftIDAttribute = "thrift.id"
+
+// AvroSchemaFromThrift translates the Thrift struct named structName, declared
+// in the provided Thrift IDL document, to an equivalent Avro schema, and
+// returns the JSON text of the Avro schema, suitable for NewCodec.
+//
+// Structs, exceptions, and unions become Avro records, and enums become Avro
+// enums. Base types map to the closest Avro type: `bool` to boolean, `byte`,
+// `i8`, `i16`, and `i32` to int, `i64` to long, `double` to double, `string`
+// to string, `binary` to bytes, and `uuid` to string with the uuid logical
+// type. Lists and sets become arrays, and maps with string keys become maps.
+// Typedefs are resolved to their target types. Optional fields, and all fields
+// of Thrift unions, become unions with null that default to null. Thrift
+// default values are translated to Avro default values, and each field
+// retains its Thrift field identifier in the `thrift.id` attribute.
+//
+// The Avro namespace is taken from the `namespace *` declaration, or when
+// there is none, from the first namespace declaration of the document. Types
+// from included documents cannot be resolved and cause an error, as do maps
+// whose keys are not strings, because Avro map keys are always strings.
+func AvroSchemaFromThrift(idl, structName string) (string, error) {
+	doc, err := parseThrift(idl)
+	if err != nil {
+		return "", fmt.Errorf("cannot translate Thrift to Avro: %s", err)
+	}
+	s, ok := doc.structs[structName]
+	if !ok {
+		return "", fmt.Errorf("cannot translate Thrift to Avro: unknown struct: %q", structName)
+	}
+	t := &thriftTranslator{doc: doc, defined: make(map[string]struct{})}
+	schema, err := t.record(s)
+	if err != nil {
+		return "", fmt.Errorf("cannot translate Thrift to Avro: %s", err)
+	}
+	schema.Namespace = doc.namespace()
+	buf, err := json.Marshal(schema)
+	if err != nil {
+		return "", fmt.Errorf("cannot translate Thrift to Avro: %s", err)
+	}
+	return string(buf), nil
+}
+
+////////////////////////////////////////
+// Avro Generation
+////////////////////////////////////////
+
+// thriftAvroRecord and thriftAvroField are used rather than maps so the
+// generated schema lists the name of each record before its fields.
+type thriftAvroRecord struct {
+	Type      string            `json:"type"`
+	Name      string            `json:"name"`
+	Namespace string            `json:"namespace,omitempty"`
+	Doc       string            `json:"doc,omitempty"`
+	Fields    []thriftAvroField `json:"fields"`
+}
+
+type thriftAvroField struct {
+	Name     string      `json:"name"`
+	Doc      string      `json:"doc,omitempty"`
+	Type     interface{} `json:"type"`
+	Default  interface{} `json:"default,omitempty"`
+	ThriftID int         `json:"thrift.id"`
+}
+
+// thriftAvroNullDefault marshals as JSON null, allowing a null default value
+// to be distinguished from an omitted default.
+type thriftAvroNullDefault struct{}
+
+func (thriftAvroNullDefault) MarshalJSON() ([]byte, error) { return []byte("null"), nil }
+
+type thriftTranslator struct {
+	doc     *thriftDocument
+	defined map[string]struct{} // named types already defined in the schema
+}
+
+func (t *thriftTranslator) record(s *thriftStruct) (*thriftAvroRecord, error) {
+	t.defined[s.name] = struct{}{}
+	r := &thriftAvroRecord{Type: "record", Name: s.name, Doc: s.doc, Fields: make([]thriftAvroField, len(s.fields))}
+	for i, f := range s.fields {
+		fieldType, err := t.avroType(f.fieldType)
+		if err != nil {
+			return nil, fmt.Errorf("%s %q field %q: %s", s.kind, s.name, f.name, err)
+		}
+		field := thriftAvroField{Name: f.name, Doc: f.doc, Type: fieldType, ThriftID: f.id}
+		if f.hasDefault {
+			if field.Default, err = t.avroDefault(f.fieldType, f.defaultValue); err != nil {
+				return nil, fmt.Errorf("%s %q field %q: %s", s.kind, s.name, f.name, err)
+			}
+		}
+		if f.requiredness == "optional" || s.kind == "union" {
+			if f.hasDefault {
+				// NOTE: union default values use the first member
+				field.Type = []interface{}{fieldType, "null"}
+			} else {
+				field.Type = []interface{}{"null", fieldType}
+				field.Default = thriftAvroNullDefault{}
+			}
+		}
+		r.Fields[i] = field
+	}
+	return r, nil
+}
+
+func (t *thriftTranslator) avroType(ft *thriftType) (interface{}, error) {
+	ft, err := t.doc.resolve(ft)
+	if err != nil {
+		return nil, err
+	}
+	switch ft.name {
+	case "bool":
+		return "boolean", nil
+	case "byte", "i8", "i16", "i32":
+		return "int", nil
+	case "i64":
+		return "long", nil
+	case "double", "string":
+		return ft.name, nil
+	case "binary":
+		return "bytes", nil
+	case "uuid":
+		return map[string]interface{}{"type": "string", "logicalType": "uuid"}, nil
+	case "list", "set":
+		items, err := t.avroType(ft.params[0])
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "array", "items": items}, nil
+	case "map":
+		key, err := t.doc.resolve(ft.params[0])
+		if err != nil {
+			return nil, err
+		}
+		if key.name != "string" {
+			return nil, fmt.Errorf("cannot translate map with %s keys: Avro map keys ought to be strings", key.name)
+		}
+		values, err := t.avroType(ft.params[1])
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "map", "values": values}, nil
+	}
+	if _, ok := t.defined[ft.name]; ok {
+		return ft.name, nil
+	}
+	if e, ok := t.doc.enums[ft.name]; ok {
+		t.defined[e.name] = struct{}{}
+		schema := map[string]interface{}{"type": "enum", "name": e.name, "symbols": e.symbols}
+		if e.doc != "" {
+			schema["doc"] = e.doc
+		}
+		return schema, nil
+	}
+	if s, ok := t.doc.structs[ft.name]; ok {
+		return t.record(s)
+	}
+	return nil, fmt.Errorf("unknown type: %q", ft.name)
+}
+
+// avroDefault translates a Thrift constant value to the JSON value of an Avro
+// default for the provided type.
+func (t *thriftTranslator) avroDefault(ft *thriftType, value interface{}) (interface{}, error) {
+	ft, err := t.doc.resolve(ft)
+	if err != nil {
+		return nil, err
+	}
+	switch ft.name {
+	case "bool":
+		switch v := value.(type) {
+		case thriftIdentifier:
+			if v == "true" || v == "false" {
+				return v == "true", nil
+			}
+		case int64:
+			return v != 0, nil
+		}
+	case "byte", "i8", "i16", "i32", "i64":
+		if v, ok := value.(int64); ok {
+			return v, nil
+		}
+	case "double":
+		switch v := value.(type) {
+		case int64:
+			return float64(v), nil
+		case float64:
+			return v, nil
+		}
+	case "string", "binary", "uuid":
+		if v, ok := value.(string); ok {
+			return v, nil
+		}
+	case "list", "set":
+		if items, ok := value.([]interface{}); ok {
+			values := make([]interface{}, len(items))
+			for i, item := range items {
+				if values[i], err = t.avroDefault(ft.params[0], item); err != nil {
+					return nil, err
+				}
+			}
+			return values, nil
+		}
+	case "map":
+		if entries, ok := value.([]thriftMapEntry); ok {
+			values := make(map[string]interface{}, len(entries))
+			for _, entry := range entries {
+				key, ok := entry.key.(string)
+				if !ok {
+					return nil, fmt.Errorf("cannot translate map default value key: expected string; received: %v", entry.key)
+				}
+				if values[key], err = t.avroDefault(ft.params[1], entry.value); err != nil {
+					return nil, err
+				}
+			}
+			return values, nil
+		}
+	default:
+		if e, ok := t.doc.enums[ft.name]; ok {
+			if symbol, ok := e.symbol(value); ok {
+				return symbol, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("cannot translate default value for %s: %v", ft.name, value)
+}
+
+////////////////////////////////////////
+// IDL Parser
+////////////////////////////////////////
+
+type thriftDocument struct {
+	namespaces map[string]string // namespace by scope
+	firstScope string
+	typedefs   map[string]*thriftType
+	enums      map[string]*thriftEnum
+	structs    map[string]*thriftStruct
+}
+
+// namespace returns the namespace used for the Avro schema.
+func (d *thriftDocument) namespace() string {
+	if ns, ok := d.namespaces["*"]; ok {
+		return ns
+	}
+	return d.namespaces[d.firstScope]
+}
+
+// resolve follows typedefs until reaching a type that is not a typedef.
+func (d *thriftDocument) resolve(ft *thriftType) (*thriftType, error) {
+	for i := 0; ; i++ {
+		target, ok := d.typedefs[ft.name]
+		if !ok {
+			return ft, nil
+		}
+		if i > len(d.typedefs) {
+			return nil, fmt.Errorf("cannot resolve recursive typedef: %q", ft.name)
+		}
+		ft = target
+	}
+}
+
+// thriftType is a reference to a base type, container type, or named type.
+type thriftType struct {
+	name   string
+	params []*thriftType // element types of list, set, and map types
+}
+
+type thriftEnum struct {
+	name    string
+	doc     string
+	symbols []string
+	values  []int64
+}
+
+// symbol returns the enum symbol referenced by a constant value, which is
+// either an identifier, optionally qualified by the enum name, or an integer.
+func (e *thriftEnum) symbol(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case thriftIdentifier:
+		s := strings.TrimPrefix(string(v), e.name+".")
+		for _, symbol := range e.symbols {
+			if symbol == s {
+				return s, true
+			}
+		}
+	case int64:
+		for i, ev := range e.values {
+			if ev == v {
+				return e.symbols[i], true
+			}
+		}
+	}
+	return "", false
+}
+
+type thriftStruct struct {
+	kind   string // "struct", "exception", or "union"
+	name   string
+	doc    string
+	fields []*thriftField
+}
+
+type thriftField struct {
+	id           int
+	requiredness string // "required", "optional", or empty
+	fieldType    *thriftType
+	name         string
+	doc          string
+	defaultValue interface{}
+	hasDefault   bool
+}
+
+// Constant values are parsed as int64, float64, string, thriftIdentifier,
+// []interface{} for lists, and []thriftMapEntry for maps.
+type thriftIdentifier string
+
+type thriftMapEntry struct {
+	key, value interface{}
+}
+
+type thriftToken struct {
+	text   string
+	line   int
+	quoted bool   // true for string literals, whose text is unquoted
+	doc    string // text of the doc comment immediately preceding the token
+}
+
+func tokenizeThrift(idl string) ([]thriftToken, error) {
+	var tokens []thriftToken
+	var doc string
+	line := 1
+
+	for i := 0; i < len(idl); {
+		c := idl[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '#' || strings.HasPrefix(idl[i:], "//"):
+			for i < len(idl) && idl[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(idl[i:], "/*"):
+			end := strings.Index(idl[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			comment := idl[i+2 : i+2+end]
+			line += strings.Count(comment, "\n")
+			if strings.HasPrefix(comment, "*") {
+				doc = thriftDocComment(comment[1:])
+			}
+			i += end + 4
+		case c == '"' || c == '\'':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(idl) && idl[j] != c; j++ {
+				if idl[j] == '\\' && j+1 < len(idl) {
+					j++
+					switch idl[j] {
+					case 'n':
+						sb.WriteByte('\n')
+					case 't':
+						sb.WriteByte('\t')
+					case 'r':
+						sb.WriteByte('\r')
+					default:
+						sb.WriteByte(idl[j])
+					}
+					continue
+				}
+				if idl[j] == '\n' {
+					line++
+				}
+				sb.WriteByte(idl[j])
+			}
+			if j == len(idl) {
+				return nil, fmt.Errorf("line %d: unterminated string literal", line)
+			}
+			tokens = append(tokens, thriftToken{text: sb.String(), line: line, quoted: true, doc: doc})
+			doc = ""
+			i = j + 1
+		case isThriftWordByte(c) || ((c == '-' || c == '+') && i+1 < len(idl) && idl[i+1] >= '0' && idl[i+1] <= '9'):
+			j := i + 1
+			for j < len(idl) && (isThriftWordByte(idl[j]) || ((idl[j] == '-' || idl[j] == '+') && (idl[j-1] == 'e' || idl[j-1] == 'E'))) {
+				j++
+			}
+			tokens = append(tokens, thriftToken{text: idl[i:j], line: line, doc: doc})
+			doc = ""
+			i = j
+		default:
+			tokens = append(tokens, thriftToken{text: string(c), line: line, doc: doc})
+			doc = ""
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+// isThriftWordByte returns true for bytes that may appear in identifiers and
+// numeric literals.
+func isThriftWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.'
+}
+
+// thriftDocComment returns the text of a doc comment, without the leading
+// asterisks of each line.
+func thriftDocComment(comment string) string {
+	lines := strings.Split(comment, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(strings.TrimSpace(line), "*")
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+type thriftParser struct {
+	tokens []thriftToken
+	pos    int
+}
+
+func parseThrift(idl string) (*thriftDocument, error) {
+	tokens, err := tokenizeThrift(idl)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse Thrift IDL: %s", err)
+	}
+	p := &thriftParser{tokens: tokens}
+	doc := &thriftDocument{
+		namespaces: make(map[string]string),
+		typedefs:   make(map[string]*thriftType),
+		enums:      make(map[string]*thriftEnum),
+		structs:    make(map[string]*thriftStruct),
+	}
+	if err = p.parseDocument(doc); err != nil {
+		return nil, fmt.Errorf("cannot parse Thrift IDL: %s", err)
+	}
+	return doc, nil
+}
+
+func (p *thriftParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *thriftParser) peek() thriftToken {
+	if p.done() {
+		return thriftToken{line: p.line()}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *thriftParser) next() thriftToken {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *thriftParser) line() int {
+	if len(p.tokens) == 0 {
+		return 1
+	}
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos].line
+	}
+	return p.tokens[len(p.tokens)-1].line
+}
+
+// accept consumes the next token when it is the provided unquoted text.
+func (p *thriftParser) accept(text string) bool {
+	if t := p.peek(); !t.quoted && t.text == text && !p.done() {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *thriftParser) expect(text string) error {
+	if !p.accept(text) {
+		return p.unexpected(fmt.Sprintf("%q", text))
+	}
+	return nil
+}
+
+func (p *thriftParser) unexpected(expected string) error {
+	if p.done() {
+		return fmt.Errorf("line %d: expected %s; received end of document", p.line(), expected)
+	}
+	return fmt.Errorf("line %d: expected %s; received: %q", p.line(), expected, p.peek().text)
+}
+
+func (p *thriftParser) identifier() (string, error) {
+	t := p.peek()
+	if p.done() || t.quoted || !isThriftWordByte(t.text[0]) || (t.text[0] >= '0' && t.text[0] <= '9') {
+		return "", p.unexpected("identifier")
+	}
+	p.pos++
+	return t.text, nil
+}
+
+// skipSeparator consumes an optional list separator.
+func (p *thriftParser) skipSeparator() {
+	if !p.accept(",") {
+		p.accept(";")
+	}
+}
+
+// skipAnnotations consumes an optional parenthesized list of annotations.
+func (p *thriftParser) skipAnnotations() error {
+	if !p.accept("(") {
+		return nil
+	}
+	for !p.accept(")") {
+		if p.done() {
+			return p.unexpected(`")"`)
+		}
+		p.pos++
+	}
+	return nil
+}
+
+// skipBlock consumes tokens through the brace that closes the next block.
+func (p *thriftParser) skipBlock() error {
+	for !p.accept("{") {
+		if p.done() {
+			return p.unexpected(`"{"`)
+		}
+		p.pos++
+	}
+	for depth := 1; depth > 0; {
+		if p.done() {
+			return p.unexpected(`"}"`)
+		}
+		switch t := p.next(); {
+		case t.quoted:
+		case t.text == "{":
+			depth++
+		case t.text == "}":
+			depth--
+		}
+	}
+	return nil
+}
+
+func (p *thriftParser) parseDocument(doc *thriftDocument) error {
+	for !p.done() {
+		t := p.next()
+		if t.quoted {
+			return fmt.Errorf("line %d: unexpected string literal: %q", t.line, t.text)
+		}
+		switch t.text {
+		case ";", ",":
+		case "include", "cpp_include":
+			if !p.next().quoted {
+				return fmt.Errorf("line %d: expected string literal after %s", t.line, t.text)
+			}
+		case "namespace":
+			scope := p.next().text
+			ns, err := p.identifier()
+			if err != nil {
+				return err
+			}
+			if len(doc.namespaces) == 0 {
+				doc.firstScope = scope
+			}
+			doc.namespaces[scope] = ns
+			if err = p.skipAnnotations(); err != nil {
+				return err
+			}
+		case "typedef":
+			ft, err := p.parseType()
+			if err != nil {
+				return err
+			}
+			alias, err := p.identifier()
+			if err != nil {
+				return err
+			}
+			doc.typedefs[alias] = ft
+			if err = p.skipAnnotations(); err != nil {
+				return err
+			}
+		case "const":
+			if _, err := p.parseType(); err != nil {
+				return err
+			}
+			if _, err := p.identifier(); err != nil {
+				return err
+			}
+			if err := p.expect("="); err != nil {
+				return err
+			}
+			if _, err := p.parseConstValue(); err != nil {
+				return err
+			}
+		case "enum":
+			e, err := p.parseEnum(t.doc)
+			if err != nil {
+				return err
+			}
+			doc.enums[e.name] = e
+		case "struct", "exception", "union":
+			s, err := p.parseStruct(t.text, t.doc)
+			if err != nil {
+				return err
+			}
+			doc.structs[s.name] = s
+		case "service", "senum":
+			if err := p.skipBlock(); err != nil {
+				return err
+			}
+			if err := p.skipAnnotations(); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("line %d: unexpected definition: %q", t.line, t.text)
+		}
+	}
+	return nil
+}
+
+func (p *thriftParser) parseType() (*thriftType, error) {
+	typeName, err := p.identifier()
+	if err != nil {
+		return nil, err
+	}
+	ft := &thriftType{name: typeName}
+	var arity int
+	switch typeName {
+	case "list", "set":
+		arity = 1
+	case "map":
+		arity = 2
+	}
+	if arity > 0 {
+		if p.accept("cpp_type") {
+			p.next()
+		}
+		if err = p.expect("<"); err != nil {
+			return nil, err
+		}
+		for i := 0; i < arity; i++ {
+			if i > 0 {
+				if err = p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+			param, err := p.parseType()
+			if err != nil {
+				return nil, err
+			}
+			ft.params = append(ft.params, param)
+		}
+		if err = p.expect(">"); err != nil {
+			return nil, err
+		}
+	}
+	if err = p.skipAnnotations(); err != nil {
+		return nil, err
+	}
+	return ft, nil
+}
+
+func (p *thriftParser) parseConstValue() (interface{}, error) {
+	t := p.next()
+	switch {
+	case t.quoted:
+		return t.text, nil
+	case t.text == "[":
+		items := []interface{}{}
+		for !p.accept("]") {
+			item, err := p.parseConstValue()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+			p.skipSeparator()
+		}
+		return items, nil
+	case t.text == "{":
+		entries := []thriftMapEntry{}
+		for !p.accept("}") {
+			key, err := p.parseConstValue()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expect(":"); err != nil {
+				return nil, err
+			}
+			value, err := p.parseConstValue()
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, thriftMapEntry{key: key, value: value})
+			p.skipSeparator()
+		}
+		return entries, nil
+	case t.text == "":
+		return nil, fmt.Errorf("line %d: expected constant value; received end of document", t.line)
+	case t.text[0] >= '0' && t.text[0] <= '9' || t.text[0] == '-' || t.text[0] == '+':
+		if i, err := strconv.ParseInt(t.text, 0, 64); err == nil {
+			return i, nil
+		}
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid numeric constant: %q", t.line, t.text)
+		}
+		return f, nil
+	case isThriftWordByte(t.text[0]):
+		return thriftIdentifier(t.text), nil
+	}
+	return nil, fmt.Errorf("line %d: expected constant value; received: %q", t.line, t.text)
+}
+
+func (p *thriftParser) parseEnum(doc string) (*thriftEnum, error) {
+	enumName, err := p.identifier()
+	if err != nil {
+		return nil, err
+	}
+	e := &thriftEnum{name: enumName, doc: doc}
+	if err = p.expect("{"); err != nil {
+		return nil, err
+	}
+	var value int64
+	for !p.accept("}") {
+		symbol, err := p.identifier()
+		if err != nil {
+			return nil, err
+		}
+		if p.accept("=") {
+			v, err := p.parseConstValue()
+			if err != nil {
+				return nil, err
+			}
+			i, ok := v.(int64)
+			if !ok {
+				return nil, fmt.Errorf("line %d: enum %q value of %q ought to be an integer", p.line(), enumName, symbol)
+			}
+			value = i
+		}
+		e.symbols = append(e.symbols, symbol)
+		e.values = append(e.values, value)
+		value++
+		if err = p.skipAnnotations(); err != nil {
+			return nil, err
+		}
+		p.skipSeparator()
+	}
+	if err = p.skipAnnotations(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (p *thriftParser) parseStruct(kind, doc string) (*thriftStruct, error) {
+	structName, err := p.identifier()
+	if err != nil {
+		return nil, err
+	}
+	s := &thriftStruct{kind: kind, name: structName, doc: doc}
+	p.accept("xsd_all")
+	if err = p.expect("{"); err != nil {
+		return nil, err
+	}
+	for !p.accept("}") {
+		f, err := p.parseField(len(s.fields))
+		if err != nil {
+			return nil, fmt.Errorf("%s %q: %s", kind, structName, err)
+		}
+		s.fields = append(s.fields, f)
+	}
+	if err = p.skipAnnotations(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// parseField parses one field declaration. Fields without an explicit
+// identifier are numbered from -1 downwards, as the Thrift compiler does.
+func (p *thriftParser) parseField(index int) (*thriftField, error) {
+	f := &thriftField{id: -1 - index, doc: p.peek().doc}
+	if t := p.peek(); !t.quoted && len(t.text) > 0 && (t.text[0] >= '0' && t.text[0] <= '9' || t.text[0] == '-' || t.text[0] == '+') {
+		id, err := strconv.Atoi(t.text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid field identifier: %q", t.line, t.text)
+		}
+		p.pos++
+		if err = p.expect(":"); err != nil {
+			return nil, err
+		}
+		f.id = id
+	}
+	if p.accept("required") {
+		f.requiredness = "required"
+	} else if p.accept("optional") {
+		f.requiredness = "optional"
+	}
+	var err error
+	if f.fieldType, err = p.parseType(); err != nil {
+		return nil, err
+	}
+	if f.name, err = p.identifier(); err != nil {
+		return nil, err
+	}
+	if p.accept("=") {
+		if f.defaultValue, err = p.parseConstValue(); err != nil {
+			return nil, err
+		}
+		f.hasDefault = true
+	}
+	if err = p.skipAnnotations(); err != nil {
+		return nil, err
+	}
+	p.skipSeparator()
+	return f, nil
+}
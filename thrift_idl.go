@@ -0,0 +1,273 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ThriftIDL returns a best-effort Thrift IDL document declaring the named
+// types of the Codec's schema. It returns an error when the schema does not
+// declare at least one record or enum.
+//
+// Records become structs and enums become enums, declared before the types
+// that reference them. Avro types map to the closest Thrift type: boolean to
+// `bool`, int to `i32`, long to `i64`, float and double to `double`, bytes and
+// fixed to `binary`, arrays to `list`, and maps to `map<string,...>`. Logical
+// types are translated using their underlying Avro type. A union of null and
+// one other type becomes an optional field, and other unions become Thrift
+// unions named after the record and field that use them.
+//
+// Field identifiers are taken from the `thrift.id` field attribute when
+// present, as written by AvroSchemaFromThrift, and otherwise are numbered by
+// field position. Fields that are neither optional nor have a default value
+// are declared as required. Type names are the short names of the Avro named
+// types, unless two named types share the same short name, in which case
+// their full names are used with periods replaced by underscores.
+func (c *Codec) ThriftIDL() (string, error) {
+	root, err := schemaNodeFromCodec(c)
+	if err != nil {
+		return "", fmt.Errorf("cannot generate Thrift IDL: %s", err)
+	}
+	g := &thriftGenerator{
+		names:   make(map[*schemaNode]string),
+		emitted: make(map[*schemaNode]struct{}),
+		unions:  make(map[string]struct{}),
+	}
+	g.collect(root, make(map[*schemaNode]struct{}))
+	if len(g.named) == 0 {
+		return "", fmt.Errorf("cannot generate Thrift IDL without a record or enum: %s", root.label())
+	}
+	if err = g.generate(); err != nil {
+		return "", fmt.Errorf("cannot generate Thrift IDL: %s", err)
+	}
+	return strings.TrimSuffix(g.body.String(), "\n"), nil
+}
+
+type thriftGenerator struct {
+	named   []*schemaNode          // records and enums in order of declaration
+	names   map[*schemaNode]string // Thrift name of each named type
+	emitted map[*schemaNode]struct{}
+	unions  map[string]struct{} // Thrift unions already emitted
+	body    strings.Builder
+}
+
+// collect gathers the records and enums reachable from the node.
+func (g *thriftGenerator) collect(n *schemaNode, seen map[*schemaNode]struct{}) {
+	switch n.typeName {
+	case "array":
+		g.collect(n.items, seen)
+	case "map":
+		g.collect(n.values, seen)
+	case "union":
+		for _, member := range n.members {
+			g.collect(member, seen)
+		}
+	case "record", "enum":
+		if _, ok := seen[n]; ok {
+			return
+		}
+		seen[n] = struct{}{}
+		g.named = append(g.named, n)
+		for _, f := range n.fields {
+			g.collect(f.node, seen)
+		}
+	}
+}
+
+func (g *thriftGenerator) generate() error {
+	shortCount := make(map[string]int)
+	for _, n := range g.named {
+		shortCount[(&name{n.fullName, n.namespace}).short()]++
+	}
+	for _, n := range g.named {
+		if short := (&name{n.fullName, n.namespace}).short(); shortCount[short] == 1 {
+			g.names[n] = short
+		} else {
+			g.names[n] = strings.Replace(n.fullName, ".", "_", -1)
+		}
+	}
+
+	if ns := g.named[0].namespace; ns != "" {
+		fmt.Fprintf(&g.body, "namespace * %s\n\n", ns)
+	}
+	for _, n := range g.named {
+		if err := g.emit(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// emit writes the definition of the record or enum, after the definitions of
+// the types it references.
+func (g *thriftGenerator) emit(n *schemaNode) error {
+	if _, ok := g.emitted[n]; ok {
+		return nil
+	}
+	// NOTE: mark the type before emitting the types it references to support
+	// recursive records.
+	g.emitted[n] = struct{}{}
+
+	if n.typeName == "enum" {
+		writeThriftDoc(&g.body, n.doc, "")
+		fmt.Fprintf(&g.body, "enum %s {\n", g.names[n])
+		for i, symbol := range n.symbols {
+			fmt.Fprintf(&g.body, "  %s = %d,\n", symbol, i)
+		}
+		g.body.WriteString("}\n\n")
+		return nil
+	}
+
+	// NOTE: field declarations are built before writing the struct, because
+	// resolving field types writes the definitions they depend on.
+	var fields strings.Builder
+	for i, f := range n.fields {
+		fieldType, optional, err := g.fieldType(f.node, g.names[n]+"_"+f.name)
+		if err != nil {
+			return fmt.Errorf("record %q field %q: %s", n.fullName, f.name, err)
+		}
+		id := i + 1
+		if v, ok := f.attributes[thriftIDAttribute].(float64); ok {
+			id = int(v)
+		}
+		writeThriftDoc(&fields, f.doc, "  ")
+		requiredness := "required "
+		if optional {
+			requiredness = "optional "
+		} else if f.hasDefault {
+			requiredness = ""
+		}
+		fmt.Fprintf(&fields, "  %d: %s%s %s", id, requiredness, fieldType, f.name)
+		if f.hasDefault && f.defaultValue != nil {
+			if literal, ok := g.constValue(f.node, f.defaultValue); ok {
+				fields.WriteString(" = " + literal)
+			}
+		}
+		fields.WriteString(",\n")
+	}
+	writeThriftDoc(&g.body, n.doc, "")
+	fmt.Fprintf(&g.body, "struct %s {\n%s}\n\n", g.names[n], fields.String())
+	return nil
+}
+
+// fieldType returns the Thrift type of a field of the node's type, and whether
+// the field is optional. Unions with several non-null members are emitted as
+// Thrift unions using the provided name.
+func (g *thriftGenerator) fieldType(n *schemaNode, unionName string) (string, bool, error) {
+	if n.typeName != "union" {
+		t, err := g.typeName(n, unionName)
+		return t, false, err
+	}
+	var optional bool
+	var members []*schemaNode
+	for _, member := range n.members {
+		if member.typeName == "null" {
+			optional = true
+			continue
+		}
+		members = append(members, member)
+	}
+	switch len(members) {
+	case 0:
+		return "", false, fmt.Errorf("cannot translate union of only null")
+	case 1:
+		t, err := g.typeName(members[0], unionName)
+		return t, optional, err
+	}
+
+	if _, ok := g.unions[unionName]; !ok {
+		g.unions[unionName] = struct{}{}
+		var fields strings.Builder
+		for i, member := range members {
+			t, err := g.typeName(member, unionName+"_"+fmt.Sprint(i+1))
+			if err != nil {
+				return "", false, err
+			}
+			fieldName := member.typeName
+			if member.isNamed() {
+				fieldName = (&name{member.fullName, member.namespace}).short()
+			}
+			fmt.Fprintf(&fields, "  %d: %s %s_value,\n", i+1, t, fieldName)
+		}
+		fmt.Fprintf(&g.body, "union %s {\n%s}\n\n", unionName, fields.String())
+	}
+	return unionName, optional, nil
+}
+
+// typeName returns the Thrift type for a value of the node's type.
+func (g *thriftGenerator) typeName(n *schemaNode, unionName string) (string, error) {
+	switch n.typeName {
+	case "boolean":
+		return "bool", nil
+	case "int":
+		return "i32", nil
+	case "long":
+		return "i64", nil
+	case "float", "double":
+		return "double", nil
+	case "bytes", "fixed":
+		return "binary", nil
+	case "string":
+		return "string", nil
+	case "record", "enum":
+		if err := g.emit(n); err != nil {
+			return "", err
+		}
+		return g.names[n], nil
+	case "array":
+		items, _, err := g.fieldType(n.items, unionName)
+		if err != nil {
+			return "", err
+		}
+		return "list<" + items + ">", nil
+	case "map":
+		values, _, err := g.fieldType(n.values, unionName)
+		if err != nil {
+			return "", err
+		}
+		return "map<string," + values + ">", nil
+	case "union":
+		t, _, err := g.fieldType(n, unionName)
+		return t, err
+	}
+	return "", fmt.Errorf("cannot translate type: %s", n.label())
+}
+
+// constValue returns the Thrift constant literal of an Avro default value, and
+// false when the value has no Thrift equivalent.
+func (g *thriftGenerator) constValue(n *schemaNode, value interface{}) (string, bool) {
+	switch n.typeName {
+	case "union":
+		// NOTE: union default values use the first member
+		return g.constValue(n.members[0], value)
+	case "enum":
+		if symbol, ok := value.(string); ok {
+			return g.names[n] + "." + symbol, true
+		}
+		return "", false
+	case "record", "fixed", "null":
+		return "", false
+	}
+	buf, err := json.Marshal(value)
+	if err != nil {
+		return "", false
+	}
+	return string(buf), true
+}
+
+func writeThriftDoc(sb *strings.Builder, doc, indent string) {
+	if doc == "" {
+		return
+	}
+	fmt.Fprintf(sb, "%s/** %s */\n", indent, strings.Replace(doc, "*/", "* /", -1))
+}
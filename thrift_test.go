@@ -0,0 +1,159 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import "testing"
+
+const thriftTestIDL = `
+namespace java com.example.java
+namespace * com.example
+
+include "shared.thrift"
+
+typedef i64 Timestamp
+
+/** Kind of event. */
+enum Kind {
+  CREATED = 1,
+  DELETED = 2 (deprecated = "true")
+}
+
+# trailing comments and annotations are ignored
+struct Address {
+  1: required string city;
+  2: optional string zip
+}
+
+/**
+ * An event.
+ */
+struct Event {
+  /** Event identifier. */
+  1: required string id,
+  2: Timestamp when,
+  3: Kind kind = Kind.CREATED,
+  4: optional Address address,
+  5: list<Address> previous,
+  6: map<string, double> scores = {"a": 1},
+  7: set<binary> blobs,
+  8: bool flag = 0,
+} (annotation = "x")
+
+service EventService {
+  void put(1: Event event)
+}
+`
+
+func TestAvroSchemaFromThrift(t *testing.T) {
+	schema, err := AvroSchemaFromThrift(thriftTestIDL, "Event")
+	ensureError(t, err)
+
+	want := `{"type":"record","name":"Event","namespace":"com.example","doc":"An event.","fields":[` +
+		`{"name":"id","doc":"Event identifier.","type":"string","thrift.id":1},` +
+		`{"name":"when","type":"long","thrift.id":2},` +
+		`{"name":"kind","type":{"doc":"Kind of event.","name":"Kind","symbols":["CREATED","DELETED"],"type":"enum"},"default":"CREATED","thrift.id":3},` +
+		`{"name":"address","type":["null",{"type":"record","name":"Address","fields":[` +
+		`{"name":"city","type":"string","thrift.id":1},` +
+		`{"name":"zip","type":["null","string"],"default":null,"thrift.id":2}]}],"default":null,"thrift.id":4},` +
+		`{"name":"previous","type":{"items":"Address","type":"array"},"thrift.id":5},` +
+		`{"name":"scores","type":{"type":"map","values":"double"},"default":{"a":1},"thrift.id":6},` +
+		`{"name":"blobs","type":{"items":"bytes","type":"array"},"thrift.id":7},` +
+		`{"name":"flag","type":"boolean","default":false,"thrift.id":8}]}`
+	if schema != want {
+		t.Errorf("GOT: %s; WANT: %s", schema, want)
+	}
+
+	// the generated schema ought to be usable by a Codec
+	codec := newCodecUsingV2(t, schema)
+	if _, err = codec.BinaryFromNative(nil, map[string]interface{}{
+		"id":       "e1",
+		"when":     int64(1),
+		"previous": []interface{}{},
+		"blobs":    []interface{}{},
+	}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAvroSchemaFromThriftErrors(t *testing.T) {
+	_, err := AvroSchemaFromThrift(thriftTestIDL, "Missing")
+	ensureError(t, err, "unknown struct")
+
+	_, err = AvroSchemaFromThrift(`struct S { 1: shared.Thing thing }`, "S")
+	ensureError(t, err, `unknown type: "shared.Thing"`)
+
+	_, err = AvroSchemaFromThrift(`struct S { 1: map<i32,string> m }`, "S")
+	ensureError(t, err, "map keys ought to be strings")
+
+	_, err = AvroSchemaFromThrift("struct S {\n  1: string s = }", "S")
+	ensureError(t, err, "line 2", "expected constant value")
+}
+
+func TestThriftIDL(t *testing.T) {
+	codec := newCodecUsingV2(t, `{
+  "type": "record",
+  "name": "Event",
+  "namespace": "com.example",
+  "doc": "An event.",
+  "fields": [
+    {"name": "id", "type": "string", "doc": "Event identifier.", "thrift.id": 10},
+    {"name": "kind", "type": {"type": "enum", "name": "Kind", "symbols": ["A", "B"]}, "default": "B"},
+    {"name": "count", "type": "int", "default": 3},
+    {"name": "when", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "next", "type": ["null", "Event"], "default": null},
+    {"name": "value", "type": ["string", "long", {"type": "fixed", "name": "Hash", "size": 4}]},
+    {"name": "tags", "type": {"type": "map", "values": {"type": "array", "items": "Kind"}}}
+  ]
+}`)
+	idl, err := codec.ThriftIDL()
+	ensureError(t, err)
+
+	want := `namespace * com.example
+
+enum Kind {
+  A = 0,
+  B = 1,
+}
+
+union Event_value {
+  1: string string_value,
+  2: i64 long_value,
+  3: binary Hash_value,
+}
+
+/** An event. */
+struct Event {
+  /** Event identifier. */
+  10: required string id,
+  2: Kind kind = Kind.B,
+  3: i32 count = 3,
+  4: required i64 when,
+  5: optional Event next,
+  6: required Event_value value,
+  7: required map<string,list<Kind>> tags,
+}
+`
+	if idl != want {
+		t.Errorf("GOT:\n%s\nWANT:\n%s", idl, want)
+	}
+}
+
+func TestThriftRoundTrip(t *testing.T) {
+	schema, err := AvroSchemaFromThrift(thriftTestIDL, "Address")
+	ensureError(t, err)
+
+	idl, err := newCodecUsingV2(t, schema).ThriftIDL()
+	ensureError(t, err)
+
+	want := "namespace * com.example\n\nstruct Address {\n  1: required string city,\n  2: optional string zip,\n}\n"
+	if idl != want {
+		t.Errorf("GOT:\n%s\nWANT:\n%s", idl, want)
+	}
+}
@@ -0,0 +1,500 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// The transcoders in this file translate between binary Avro data and other
+// self-describing binary formats, such as MessagePack and CBOR, guided by the
+// Codec's schema. Rather than converting through the native Go form, which
+// would translate logical types and wrap union values, binary Avro data is
+// decoded to a plain value tree that mirrors the Avro encoding:
+//
+//   * null, boolean, bytes, and string values as nil, bool, []byte, and string
+//   * int and long values as int64, and float and double values as float32
+//     and float64
+//   * enum values as their symbol string, and fixed values as []byte
+//   * arrays as []interface{}, and maps as map[string]interface{}
+//   * records as *plainRecord, which retains the field order of the schema
+//   * union values as the value of the selected member, without wrapping
+//
+// Logical types are transcoded using their underlying Avro type, so for
+// instance a timestamp-millis value is transcoded as an integer.
+//
+// When translating back to binary Avro, the member of a union is selected
+// using the type of the value, preferring exact type matches, and then the
+// first member that can losslessly encode the value.
+
+// plainRecord is the plain representation of a record, retaining the field
+// order from the schema.
+type plainRecord struct {
+	names  []string
+	values []interface{}
+}
+
+////////////////////////////////////////
+// MessagePack
+////////////////////////////////////////
+
+// MessagePackFromBinary decodes one binary Avro datum from buf in accordance
+// with the Avro schema supplied when creating the Codec, and appends its
+// MessagePack encoding to dst. Records are encoded as MessagePack maps with
+// keys in schema field order. On success, it returns the new dst slice, the
+// remaining undecoded bytes of buf, and a nil error value. On error, it returns
+// the original dst and buf slices, and the error message.
+func (c *Codec) MessagePackFromBinary(dst, buf []byte) ([]byte, []byte, error) {
+	return c.transcodeFromBinary(dst, buf, appendMessagePack, "MessagePack")
+}
+
+// BinaryFromMessagePack decodes one MessagePack value from buf, and appends its
+// binary Avro encoding to dst in accordance with the Avro schema supplied when
+// creating the Codec. Record fields missing from the MessagePack map are set to
+// their schema default values. On success, it returns the new dst slice, the
+// remaining undecoded bytes of buf, and a nil error value. On error, it returns
+// the original dst and buf slices, and the error message.
+func (c *Codec) BinaryFromMessagePack(dst, buf []byte) ([]byte, []byte, error) {
+	return c.transcodeToBinary(dst, buf, plainFromMessagePack, "MessagePack")
+}
+
+////////////////////////////////////////
+// CBOR
+////////////////////////////////////////
+
+// CBORFromBinary decodes one binary Avro datum from buf in accordance with the
+// Avro schema supplied when creating the Codec, and appends its CBOR encoding
+// to dst. Records are encoded as CBOR maps with keys in schema field order. On
+// success, it returns the new dst slice, the remaining undecoded bytes of buf,
+// and a nil error value. On error, it returns the original dst and buf slices,
+// and the error message.
+func (c *Codec) CBORFromBinary(dst, buf []byte) ([]byte, []byte, error) {
+	return c.transcodeFromBinary(dst, buf, appendCBOR, "CBOR")
+}
+
+// BinaryFromCBOR decodes one CBOR data item from buf, and appends its binary
+// Avro encoding to dst in accordance with the Avro schema supplied when
+// creating the Codec. Record fields missing from the CBOR map are set to their
+// schema default values. On success, it returns the new dst slice, the
+// remaining undecoded bytes of buf, and a nil error value. On error, it returns
+// the original dst and buf slices, and the error message.
+func (c *Codec) BinaryFromCBOR(dst, buf []byte) ([]byte, []byte, error) {
+	return c.transcodeToBinary(dst, buf, plainFromCBOR, "CBOR")
+}
+
+func (c *Codec) transcodeFromBinary(dst, buf []byte, appendPlain func([]byte, interface{}) []byte, format string) ([]byte, []byte, error) {
+	n, err := schemaNodeFromCodec(c)
+	if err != nil {
+		return dst, buf, fmt.Errorf("cannot transcode binary to %s: %s", format, err)
+	}
+	value, rest, err := plainFromBinary(n, buf)
+	if err != nil {
+		return dst, buf, fmt.Errorf("cannot transcode binary to %s: %s", format, err)
+	}
+	return appendPlain(dst, value), rest, nil
+}
+
+func (c *Codec) transcodeToBinary(dst, buf []byte, plainFrom func([]byte) (interface{}, []byte, error), format string) ([]byte, []byte, error) {
+	n, err := schemaNodeFromCodec(c)
+	if err != nil {
+		return dst, buf, fmt.Errorf("cannot transcode %s to binary: %s", format, err)
+	}
+	value, rest, err := plainFrom(buf)
+	if err != nil {
+		return dst, buf, fmt.Errorf("cannot transcode %s to binary: %s", format, err)
+	}
+	newDst, err := binaryFromPlain(n, dst, value)
+	if err != nil {
+		return dst, buf, fmt.Errorf("cannot transcode %s to binary: %s", format, err)
+	}
+	return newDst, rest, nil
+}
+
+////////////////////////////////////////
+// Binary Decode
+////////////////////////////////////////
+
+// plainFromBinary decodes one binary Avro datum to its plain representation.
+func plainFromBinary(n *schemaNode, buf []byte) (interface{}, []byte, error) {
+	var value interface{}
+	var err error
+
+	switch n.typeName {
+	case "null":
+		return nil, buf, nil
+	case "boolean":
+		return booleanNativeFromBinary(buf)
+	case "int":
+		if value, buf, err = intNativeFromBinary(buf); err != nil {
+			return nil, nil, fmt.Errorf("cannot decode binary int: %s", err)
+		}
+		return int64(value.(int32)), buf, nil
+	case "long":
+		if value, buf, err = longNativeFromBinary(buf); err != nil {
+			return nil, nil, fmt.Errorf("cannot decode binary long: %s", err)
+		}
+		return value, buf, nil
+	case "float":
+		return floatNativeFromBinary(buf)
+	case "double":
+		return doubleNativeFromBinary(buf)
+	case "bytes":
+		return bytesNativeFromBinary(buf)
+	case "string":
+		return stringNativeFromBinary(buf)
+	case "fixed":
+		if len(buf) < n.size {
+			return nil, nil, fmt.Errorf("cannot decode binary fixed %q: schema size exceeds remaining buffer size: %d > %d (short buffer)", n.fullName, n.size, len(buf))
+		}
+		return buf[:n.size], buf[n.size:], nil
+	case "enum":
+		if value, buf, err = longNativeFromBinary(buf); err != nil {
+			return nil, nil, fmt.Errorf("cannot decode binary enum %q index: %s", n.fullName, err)
+		}
+		index := value.(int64)
+		if index < 0 || index >= int64(len(n.symbols)) {
+			return nil, nil, fmt.Errorf("cannot decode binary enum %q: index ought to be between 0 and %d; read index: %d", n.fullName, len(n.symbols)-1, index)
+		}
+		return n.symbols[index], buf, nil
+	case "union":
+		if value, buf, err = longNativeFromBinary(buf); err != nil {
+			return nil, nil, fmt.Errorf("cannot decode binary union: %s", err)
+		}
+		index := value.(int64)
+		if index < 0 || index >= int64(len(n.members)) {
+			return nil, nil, fmt.Errorf("cannot decode binary union: index ought to be between 0 and %d; read index: %d", len(n.members)-1, index)
+		}
+		return plainFromBinary(n.members[index], buf)
+	case "record":
+		record := &plainRecord{names: make([]string, len(n.fields)), values: make([]interface{}, len(n.fields))}
+		for i, f := range n.fields {
+			if value, buf, err = plainFromBinary(f.node, buf); err != nil {
+				return nil, nil, fmt.Errorf("cannot decode binary record %q field %q: %s", n.fullName, f.name, err)
+			}
+			record.names[i] = f.name
+			record.values[i] = value
+		}
+		return record, buf, nil
+	case "array":
+		var items []interface{}
+		err = plainBlocksFromBinary(&buf, "array", func() error {
+			var item interface{}
+			var err error
+			if item, buf, err = plainFromBinary(n.items, buf); err != nil {
+				return fmt.Errorf("item %d: %s", len(items)+1, err)
+			}
+			items = append(items, item)
+			return nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		if items == nil {
+			items = []interface{}{}
+		}
+		return items, buf, nil
+	case "map":
+		values := make(map[string]interface{})
+		err = plainBlocksFromBinary(&buf, "map", func() error {
+			var key, value interface{}
+			var err error
+			if key, buf, err = stringNativeFromBinary(buf); err != nil {
+				return fmt.Errorf("key: %s", err)
+			}
+			if value, buf, err = plainFromBinary(n.values, buf); err != nil {
+				return fmt.Errorf("value for key %q: %s", key, err)
+			}
+			values[key.(string)] = value
+			return nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return values, buf, nil
+	default:
+		return nil, nil, fmt.Errorf("cannot decode binary: unknown type: %q", n.typeName)
+	}
+}
+
+// plainBlocksFromBinary reads the blocks of an array or map from buf, invoking
+// callback once per item, and advancing buf past the final block.
+func plainBlocksFromBinary(buf *[]byte, kind string, callback func() error) error {
+	for {
+		value, b, err := longNativeFromBinary(*buf)
+		if err != nil {
+			return fmt.Errorf("cannot decode binary %s block count: %s", kind, err)
+		}
+		*buf = b
+		blockCount := value.(int64)
+		if blockCount == 0 {
+			return nil
+		}
+		if blockCount < 0 {
+			if blockCount == math.MinInt64 {
+				return fmt.Errorf("cannot decode binary %s with block count: %d", kind, blockCount)
+			}
+			blockCount = -blockCount
+			if _, b, err = longNativeFromBinary(*buf); err != nil {
+				return fmt.Errorf("cannot decode binary %s block size: %s", kind, err)
+			}
+			*buf = b
+		}
+		if blockCount > MaxBlockCount {
+			return fmt.Errorf("cannot decode binary %s when block count exceeds MaxBlockCount: %d > %d", kind, blockCount, MaxBlockCount)
+		}
+		for i := int64(0); i < blockCount; i++ {
+			if err = callback(); err != nil {
+				return fmt.Errorf("cannot decode binary %s %s", kind, err)
+			}
+		}
+	}
+}
+
+////////////////////////////////////////
+// Binary Encode
+////////////////////////////////////////
+
+// binaryFromPlain appends the binary Avro encoding of the plain value to buf.
+func binaryFromPlain(n *schemaNode, buf []byte, value interface{}) ([]byte, error) {
+	switch n.typeName {
+	case "null":
+		return nullBinaryFromNative(buf, value)
+	case "boolean":
+		return booleanBinaryFromNative(buf, value)
+	case "int":
+		return intBinaryFromNative(buf, plainNumber(value))
+	case "long":
+		return longBinaryFromNative(buf, plainNumber(value))
+	case "float":
+		return floatBinaryFromNative(buf, plainNumber(value))
+	case "double":
+		return doubleBinaryFromNative(buf, plainNumber(value))
+	case "bytes":
+		return bytesBinaryFromNative(buf, value)
+	case "string":
+		return stringBinaryFromNative(buf, value)
+	case "fixed":
+		var someBytes []byte
+		switch v := value.(type) {
+		case []byte:
+			someBytes = v
+		case string:
+			someBytes = []byte(v)
+		default:
+			return nil, fmt.Errorf("cannot encode binary fixed %q: expected []byte or string; received: %T", n.fullName, value)
+		}
+		if len(someBytes) != n.size {
+			return nil, fmt.Errorf("cannot encode binary fixed %q: datum size ought to equal schema size: %d != %d", n.fullName, len(someBytes), n.size)
+		}
+		return append(buf, someBytes...), nil
+	case "enum":
+		symbol, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot encode binary enum %q: expected string; received: %T", n.fullName, value)
+		}
+		for i, s := range n.symbols {
+			if s == symbol {
+				return longBinaryFromNative(buf, i)
+			}
+		}
+		return nil, fmt.Errorf("cannot encode binary enum %q: value ought to be member of symbols: %v; %q", n.fullName, n.symbols, symbol)
+	case "union":
+		index := plainUnionIndex(n, value)
+		if index < 0 {
+			return nil, fmt.Errorf("cannot encode binary union: no member schema types support datum: %s; received: %T", n.label(), value)
+		}
+		buf, _ = longBinaryFromNative(buf, index)
+		return binaryFromPlain(n.members[index], buf, value)
+	case "record":
+		fields, ok := plainRecordFields(value)
+		if !ok {
+			return nil, fmt.Errorf("cannot encode binary record %q: expected map; received: %T", n.fullName, value)
+		}
+		var err error
+		for _, f := range n.fields {
+			fieldValue, ok := fields[f.name]
+			fieldNode := f.node
+			if !ok {
+				if !f.hasDefault {
+					return nil, fmt.Errorf("cannot encode binary record %q field %q: schema does not specify default value and no value provided", n.fullName, f.name)
+				}
+				fieldValue = f.defaultValue
+				if fieldNode.typeName == "union" {
+					// NOTE: union default values use the first member
+					buf, _ = longBinaryFromNative(buf, 0)
+					fieldNode = fieldNode.members[0]
+				}
+			}
+			if buf, err = binaryFromPlain(fieldNode, buf, fieldValue); err != nil {
+				return nil, fmt.Errorf("cannot encode binary record %q field %q: value does not match its schema: %s", n.fullName, f.name, err)
+			}
+		}
+		return buf, nil
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("cannot encode binary array: expected []interface{}; received: %T", value)
+		}
+		if len(items) > 0 {
+			buf, _ = longBinaryFromNative(buf, len(items))
+		}
+		var err error
+		for i, item := range items {
+			if buf, err = binaryFromPlain(n.items, buf, item); err != nil {
+				return nil, fmt.Errorf("cannot encode binary array item %d: %s", i+1, err)
+			}
+		}
+		return longBinaryFromNative(buf, 0)
+	case "map":
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("cannot encode binary map: expected map[string]interface{}; received: %T", value)
+		}
+		keys := make([]string, 0, len(values))
+		for k := range values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		if len(keys) > 0 {
+			buf, _ = longBinaryFromNative(buf, len(keys))
+		}
+		var err error
+		for _, k := range keys {
+			buf, _ = stringBinaryFromNative(buf, k)
+			if buf, err = binaryFromPlain(n.values, buf, values[k]); err != nil {
+				return nil, fmt.Errorf("cannot encode binary map value for key %q: %s", k, err)
+			}
+		}
+		return longBinaryFromNative(buf, 0)
+	default:
+		return nil, fmt.Errorf("cannot encode binary: unknown type: %q", n.typeName)
+	}
+}
+
+// plainNumber converts unsigned integers, which the numeric encoders do not
+// accept, to int64 when they fit.
+func plainNumber(value interface{}) interface{} {
+	if v, ok := value.(uint64); ok && v <= math.MaxInt64 {
+		return int64(v)
+	}
+	return value
+}
+
+// plainRecordFields returns the fields of a plain record value.
+func plainRecordFields(value interface{}) (map[string]interface{}, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v, true
+	case *plainRecord:
+		fields := make(map[string]interface{}, len(v.names))
+		for i, name := range v.names {
+			fields[name] = v.values[i]
+		}
+		return fields, true
+	}
+	return nil, false
+}
+
+// plainUnionIndex returns the index of the union member used to encode value,
+// or -1 when no member can encode it.
+func plainUnionIndex(n *schemaNode, value interface{}) int {
+	for _, exact := range []bool{true, false} {
+		for i, member := range n.members {
+			if plainMatches(member, value, exact) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// plainMatches returns true when the plain value can be encoded by the node.
+// When exact is true, numeric values only match numeric types of the same
+// kind.
+func plainMatches(n *schemaNode, value interface{}, exact bool) bool {
+	switch v := value.(type) {
+	case nil:
+		return n.typeName == "null"
+	case bool:
+		return n.typeName == "boolean"
+	case int64, uint64:
+		switch n.typeName {
+		case "int":
+			i, ok := plainNumber(v).(int64)
+			return ok && i >= math.MinInt32 && i <= math.MaxInt32
+		case "long":
+			_, ok := plainNumber(v).(int64)
+			return ok
+		case "float", "double":
+			return !exact
+		}
+	case float32, float64:
+		return n.typeName == "float" || n.typeName == "double"
+	case string:
+		switch n.typeName {
+		case "string":
+			return true
+		case "enum":
+			for _, symbol := range n.symbols {
+				if symbol == v {
+					return true
+				}
+			}
+		case "bytes":
+			return !exact
+		}
+	case []byte:
+		switch n.typeName {
+		case "bytes":
+			return true
+		case "fixed":
+			return len(v) == n.size
+		case "string":
+			return !exact
+		}
+	case []interface{}:
+		return n.typeName == "array"
+	case map[string]interface{}, *plainRecord:
+		switch n.typeName {
+		case "map":
+			// NOTE: maps only match during the second pass so record members
+			// that accept the keys of the value are preferred.
+			_, ok := v.(map[string]interface{})
+			return ok && !exact
+		case "record":
+			fields, _ := plainRecordFields(v)
+			for _, f := range n.fields {
+				if _, ok := fields[f.name]; !ok && !f.hasDefault {
+					return false
+				}
+			}
+			for k := range fields {
+				if !n.hasField(k) {
+					return false
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// hasField returns true when the record node has a field with the provided
+// name.
+func (n *schemaNode) hasField(fieldName string) bool {
+	for _, f := range n.fields {
+		if f.name == fieldName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const transcodeTestSchema = `{
+  "type": "record",
+  "name": "Event",
+  "fields": [
+    {"name": "id", "type": "long"},
+    {"name": "score", "type": "double"},
+    {"name": "kind", "type": {"type": "enum", "name": "Kind", "symbols": ["A", "B"]}},
+    {"name": "hash", "type": {"type": "fixed", "name": "Hash", "size": 4}},
+    {"name": "tags", "type": {"type": "array", "items": "string"}},
+    {"name": "counts", "type": {"type": "map", "values": "int"}},
+    {"name": "when", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "note", "type": ["null", "string", "int"], "default": null}
+  ]
+}`
+
+func TestTranscodeRoundTrip(t *testing.T) {
+	codec := newCodecUsingV2(t, transcodeTestSchema)
+
+	for _, note := range []interface{}{nil, Union("string", "hello"), Union("int", -70000)} {
+		binary, err := codec.BinaryFromNative(nil, map[string]interface{}{
+			"id":     int64(-1) << 40,
+			"score":  3.5,
+			"kind":   "B",
+			"hash":   []byte("abcd"),
+			"tags":   []interface{}{"x", "y"},
+			"counts": map[string]interface{}{"a": 1, "b": 300},
+			"when":   time.Unix(1500000000, 0),
+			"note":   note,
+		})
+		ensureError(t, err)
+
+		for _, format := range []struct {
+			name string
+			from func([]byte, []byte) ([]byte, []byte, error)
+			to   func([]byte, []byte) ([]byte, []byte, error)
+		}{
+			{"MessagePack", codec.MessagePackFromBinary, codec.BinaryFromMessagePack},
+			{"CBOR", codec.CBORFromBinary, codec.BinaryFromCBOR},
+		} {
+			encoded, rest, err := format.from(nil, append(binary, 0xff))
+			ensureError(t, err)
+			if !bytes.Equal(rest, []byte{0xff}) {
+				t.Errorf("%s: GOT: %v; WANT: %v", format.name, rest, []byte{0xff})
+			}
+
+			decoded, rest, err := format.to(nil, encoded)
+			ensureError(t, err)
+			if len(rest) != 0 {
+				t.Errorf("%s: GOT: %v; WANT: %v", format.name, rest, []byte{})
+			}
+			// NOTE: compare native values, because the order of map items
+			// in binary data is unspecified
+			got, _, err := codec.NativeFromBinary(decoded)
+			ensureError(t, err)
+			want, _, err := codec.NativeFromBinary(binary)
+			ensureError(t, err)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: GOT: %v; WANT: %v", format.name, got, want)
+			}
+		}
+	}
+}
+
+func TestTranscodeMessagePackEncoding(t *testing.T) {
+	codec := newCodecUsingV2(t, `{"type":"record","name":"r","fields":[{"name":"b","type":"int"},{"name":"a","type":["null","string"]}]}`)
+
+	// {"b": 1, "a": "x"} with fields in schema order
+	got, _, err := codec.MessagePackFromBinary(nil, []byte{0x02, 0x02, 0x02, 'x'})
+	ensureError(t, err)
+	if want := []byte{0x82, 0xa1, 'b', 0x01, 0xa1, 'a', 0xa1, 'x'}; !bytes.Equal(got, want) {
+		t.Errorf("GOT: %v; WANT: %v", got, want)
+	}
+}
+
+func TestTranscodeCBOREncoding(t *testing.T) {
+	codec := newCodecUsingV2(t, `{"type":"array","items":["null","long"]}`)
+
+	// [null, -500]
+	got, _, err := codec.CBORFromBinary(nil, []byte{0x04, 0x00, 0x02, 0xe7, 0x07, 0x00})
+	ensureError(t, err)
+	if want := []byte{0x82, 0xf6, 0x39, 0x01, 0xf3}; !bytes.Equal(got, want) {
+		t.Errorf("GOT: %v; WANT: %v", got, want)
+	}
+}
+
+func TestTranscodeMissingFieldUsesDefault(t *testing.T) {
+	codec := newCodecUsingV2(t, `{"type":"record","name":"r","fields":[{"name":"a","type":"int"},{"name":"b","type":["null","string"],"default":null},{"name":"c","type":"string","default":"z"}]}`)
+
+	// {"a": 5}
+	got, _, err := codec.BinaryFromMessagePack(nil, []byte{0x81, 0xa1, 'a', 0x05})
+	ensureError(t, err)
+	if want := []byte{0x0a, 0x00, 0x02, 'z'}; !bytes.Equal(got, want) {
+		t.Errorf("GOT: %v; WANT: %v", got, want)
+	}
+
+	// {}
+	_, _, err = codec.BinaryFromCBOR(nil, []byte{0xa0})
+	ensureError(t, err, "field \"a\"", "no value provided")
+}
+
+func TestTranscodeErrors(t *testing.T) {
+	codec := newCodecUsingV2(t, `{"type":"enum","name":"e","symbols":["A"]}`)
+
+	_, _, err := codec.BinaryFromMessagePack(nil, []byte{0xa1, 'B'})
+	ensureError(t, err, "value ought to be member of symbols")
+
+	_, _, err = codec.BinaryFromCBOR(nil, []byte{0x7f})
+	ensureError(t, err, "indefinite length")
+
+	_, _, err = codec.BinaryFromMessagePack(nil, []byte{0xd4, 0x01, 0x00})
+	ensureError(t, err, "unsupported format")
+
+	_, _, err = codec.MessagePackFromBinary(nil, []byte{0x02})
+	ensureError(t, err, "index ought to be between 0 and 0")
+}
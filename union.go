@@ -43,7 +43,27 @@ func Union(name string, datum interface{}) interface{} {
 	return map[string]interface{}{name: datum}
 }
 
-func buildCodecForTypeDescribedBySlice(st map[string]*Codec, enclosingNamespace string, schemaArray []interface{}) (*Codec, error) {
+// UnionValue is the native form of a non-null Avro union value decoded by
+// Codecs created with the UnionValueDecoding option, where Type names the
+// member of the union that Value is a datum of, such as "long", or the full
+// name of a record. Type switches on decoded data therefore need not range
+// over a map with a single key. Every Codec encodes a UnionValue like the map
+// returned by Union.
+//
+//     switch v := datum.(type) {
+//     case nil:
+//         // null member
+//     case goavro.UnionValue:
+//         if v.Type == "long" {
+//             total += v.Value.(int64)
+//         }
+//     }
+type UnionValue struct {
+	Type  string
+	Value interface{}
+}
+
+func buildCodecForTypeDescribedBySlice(st map[string]*Codec, enclosingNamespace string, schemaArray []interface{}, option *CodecOption) (*Codec, error) {
 	if len(schemaArray) == 0 {
 		return nil, errors.New("Union ought to have one or more members")
 	}
@@ -52,9 +72,10 @@ func buildCodecForTypeDescribedBySlice(st map[string]*Codec, enclosingNamespace
 	codecFromIndex := make([]*Codec, len(schemaArray))
 	codecFromName := make(map[string]*Codec, len(schemaArray))
 	indexFromName := make(map[string]int, len(schemaArray))
+	textNames := make([]string, len(schemaArray)) // keys of textual union values
 
 	for i, unionMemberSchema := range schemaArray {
-		unionMemberCodec, err := buildCodec(st, enclosingNamespace, unionMemberSchema)
+		unionMemberCodec, err := buildCodec(st, enclosingNamespace, unionMemberSchema, option)
 		if err != nil {
 			return nil, fmt.Errorf("Union item %d ought to be valid Avro type: %s", i+1, err)
 		}
@@ -66,15 +87,42 @@ func buildCodecForTypeDescribedBySlice(st map[string]*Codec, enclosingNamespace
 		codecFromIndex[i] = unionMemberCodec
 		codecFromName[fullName] = unionMemberCodec
 		indexFromName[fullName] = i
+		textNames[i] = fullName
+		if option != nil && option.RelativeUnionNames && enclosingNamespace != nullNamespace && unionMemberCodec.typeName.namespace == enclosingNamespace {
+			textNames[i] = unionMemberCodec.typeName.short()
+		}
 	}
 
-	return &Codec{
+	// NOTE: Textual decoding accepts both full and relative names, and
+	// returns full names, so the decoded datum may be encoded again.
+	codecFromTextName := codecFromName
+	fullNameFromTextName := make(map[string]string)
+	for i, textName := range textNames {
+		if textName == allowedTypes[i] {
+			continue
+		}
+		if len(fullNameFromTextName) == 0 {
+			codecFromTextName = make(map[string]*Codec, 2*len(codecFromName))
+			for k, v := range codecFromName {
+				codecFromTextName[k] = v
+			}
+		}
+		if _, ok := codecFromTextName[textName]; !ok {
+			codecFromTextName[textName] = codecFromIndex[i]
+			fullNameFromTextName[textName] = allowedTypes[i]
+		}
+	}
+
+	unionValueDecoding := option != nil && option.UnionValueDecoding
+
+	c := &Codec{
 		// NOTE: To support record field default values, union schema set to the
 		// type name of first member
 		// TODO: add/change to schemaCanonical below
 		schemaOriginal: codecFromIndex[0].typeName.fullName,
 
-		typeName: &name{"union", nullNamespace},
+		typeName:     &name{"union", nullNamespace},
+		unionMembers: codecFromIndex,
 		nativeFromBinary: func(buf []byte) (interface{}, []byte, error) {
 			var decoded interface{}
 			var err error
@@ -85,17 +133,20 @@ func buildCodecForTypeDescribedBySlice(st map[string]*Codec, enclosingNamespace
 			}
 			index := decoded.(int64) // longDecoder always returns int64, so elide error checking
 			if index < 0 || index >= int64(len(codecFromIndex)) {
-				return nil, nil, fmt.Errorf("cannot decode binary union: index ought to be between 0 and %d; read index: %d", len(codecFromIndex)-1, index)
+				return nil, nil, decodeError(option, ErrInvalidBinary, "cannot decode binary union: index ought to be between 0 and %d; read index: %d", len(codecFromIndex)-1, index)
 			}
 			c := codecFromIndex[index]
 			decoded, buf, err = c.nativeFromBinary(buf)
 			if err != nil {
-				return nil, nil, fmt.Errorf("cannot decode binary union item %d: %s", index+1, err)
+				return nil, nil, decodeError(option, err, "cannot decode binary union item %d: %s", index+1, err)
 			}
 			if decoded == nil {
 				// do not wrap a nil value in a map
 				return nil, buf, nil
 			}
+			if unionValueDecoding {
+				return UnionValue{Type: allowedTypes[index], Value: decoded}, buf, nil
+			}
 			// Non-nil values are wrapped in a map with single key set to type name of value
 			return Union(allowedTypes[index], decoded), buf, nil
 		},
@@ -107,6 +158,13 @@ func buildCodecForTypeDescribedBySlice(st map[string]*Codec, enclosingNamespace
 					return nil, fmt.Errorf("cannot encode binary union: no member schema types support datum: allowed types: %v; received: %T", allowedTypes, datum)
 				}
 				return longBinaryFromNative(buf, index)
+			case UnionValue:
+				index, ok := indexFromName[v.Type]
+				if !ok {
+					return nil, fmt.Errorf("cannot encode binary union: no member schema types support datum: allowed types: %v; received: %q", allowedTypes, v.Type)
+				}
+				buf, _ = longBinaryFromNative(buf, index)
+				return codecFromIndex[index].binaryFromNative(buf, v.Value)
 			case map[string]interface{}:
 				if len(v) != 1 {
 					return nil, fmt.Errorf("cannot encode binary union: non-nil Union values ought to be specified with Go map[string]interface{}, with single key equal to type name, and value equal to datum value: %v; received: %T", allowedTypes, datum)
@@ -131,16 +189,28 @@ func buildCodecForTypeDescribedBySlice(st map[string]*Codec, enclosingNamespace
 				}
 			}
 
-			var datum interface{}
-			var err error
-			datum, buf, err = genericMapTextDecoder(buf, nil, codecFromName)
+			datum, buf, err := genericMapTextDecoder(buf, nil, codecFromTextName)
 			if err != nil {
 				return nil, nil, fmt.Errorf("cannot decode textual union: %s", err)
 			}
+			for key, value := range datum {
+				if fullName, ok := fullNameFromTextName[key]; ok {
+					delete(datum, key)
+					datum[fullName] = value
+				}
+			}
+			if unionValueDecoding {
+				for key, value := range datum {
+					return UnionValue{Type: key, Value: value}, buf, nil
+				}
+			}
 
 			return datum, buf, nil
 		},
 		textualFromNative: func(buf []byte, datum interface{}) ([]byte, error) {
+			if v, ok := datum.(UnionValue); ok {
+				datum = map[string]interface{}{v.Type: v.Value}
+			}
 			switch v := datum.(type) {
 			case nil:
 				_, ok := indexFromName["null"]
@@ -160,7 +230,7 @@ func buildCodecForTypeDescribedBySlice(st map[string]*Codec, enclosingNamespace
 					}
 					buf = append(buf, '{')
 					var err error
-					buf, err = stringTextualFromNative(buf, key)
+					buf, err = stringTextualFromNative(buf, textNames[index])
 					if err != nil {
 						return nil, fmt.Errorf("cannot encode textual union: %s", err)
 					}
@@ -175,5 +245,48 @@ func buildCodecForTypeDescribedBySlice(st map[string]*Codec, enclosingNamespace
 			}
 			return nil, fmt.Errorf("cannot encode textual union: non-nil values ought to be specified with Go map[string]interface{}, with single key equal to type name, and value equal to datum value: %v; received: %T", allowedTypes, datum)
 		},
-	}, nil
+	}
+	if option != nil && option.UnwrappedNullableUnions && len(codecFromIndex) == 2 {
+		if nullIndex, ok := indexFromName["null"]; ok {
+			unwrapNullableUnion(c, 1-nullIndex, indexFromName)
+		}
+	}
+	return c, nil
+}
+
+// unwrapNullableUnion makes the codec of a union of null and one other member,
+// at index, also encode values of that member that are not wrapped, as
+// specified by the UnwrappedNullableUnions option. A map with a single key
+// naming a member of the union, and a UnionValue, are still encoded as wrapped
+// values.
+func unwrapNullableUnion(c *Codec, index int, indexFromName map[string]int) {
+	member := c.unionMembers[index]
+	wrapped := func(datum interface{}) bool {
+		switch v := datum.(type) {
+		case nil, UnionValue:
+			return true
+		case map[string]interface{}:
+			if len(v) == 1 {
+				for key := range v {
+					_, ok := indexFromName[key]
+					return ok
+				}
+			}
+		}
+		return false
+	}
+	binaryFromNative, textualFromNative := c.binaryFromNative, c.textualFromNative
+	c.binaryFromNative = func(buf []byte, datum interface{}) ([]byte, error) {
+		if wrapped(datum) {
+			return binaryFromNative(buf, datum)
+		}
+		buf, _ = longBinaryFromNative(buf, index)
+		return member.binaryFromNative(buf, datum)
+	}
+	c.textualFromNative = func(buf []byte, datum interface{}) ([]byte, error) {
+		if wrapped(datum) {
+			return textualFromNative(buf, datum)
+		}
+		return textualFromNative(buf, UnionValue{Type: member.typeName.fullName, Value: datum})
+	}
 }
@@ -159,6 +159,94 @@ func TestUnionText(t *testing.T) {
 	testTextCodecPass(t, `["null","int","string"]`, Union("string", "😂 "), []byte(`{"string":"\u0001\uD83D\uDE02 "}`))
 }
 
+func TestUnionValueDecoding(t *testing.T) {
+	codec, err := NewCodec(`{"type":"record","name":"com.example.R","fields":[
+		{"name":"u","type":["null","long",{"type":"record","name":"Point","fields":[{"name":"x","type":"int"}]}]}
+	]}`, WithUnionValueDecoding(true))
+	ensureError(t, err)
+
+	for _, tc := range []struct {
+		binary   string
+		text     string
+		expected interface{}
+	}{
+		{"\x00", `{"u":null}`, nil},
+		{"\x02\x06", `{"u":{"long":3}}`, UnionValue{Type: "long", Value: int64(3)}},
+		{"\x04\x0a", `{"u":{"com.example.Point":{"x":5}}}`, UnionValue{Type: "com.example.Point", Value: map[string]interface{}{"x": int32(5)}}},
+	} {
+		datum, _, err := codec.NativeFromBinary([]byte(tc.binary))
+		ensureError(t, err)
+		if actual, expected := fmt.Sprintf("%#v", datum.(map[string]interface{})["u"]), fmt.Sprintf("%#v", tc.expected); actual != expected {
+			t.Errorf("GOT: %v; WANT: %v", actual, expected)
+		}
+		datum, _, err = codec.NativeFromTextual([]byte(tc.text))
+		ensureError(t, err)
+		if actual, expected := fmt.Sprintf("%#v", datum.(map[string]interface{})["u"]), fmt.Sprintf("%#v", tc.expected); actual != expected {
+			t.Errorf("GOT: %v; WANT: %v", actual, expected)
+		}
+
+		// decoded values encode again, also using codecs without the option
+		for _, c := range []*Codec{codec, newCodecUsingV2(t, codec.Schema())} {
+			buf, err := c.BinaryFromNative(nil, datum)
+			ensureError(t, err)
+			if actual, expected := string(buf), tc.binary; actual != expected {
+				t.Errorf("GOT: %q; WANT: %q", actual, expected)
+			}
+			buf, err = c.TextualFromNative(nil, datum)
+			ensureError(t, err)
+			if actual, expected := string(buf), tc.text; actual != expected {
+				t.Errorf("GOT: %v; WANT: %v", actual, expected)
+			}
+		}
+	}
+
+	_, err = codec.BinaryFromNative(nil, map[string]interface{}{"u": UnionValue{Type: "string", Value: "x"}})
+	ensureError(t, err, `allowed types: [null long com.example.Point]; received: "string"`)
+}
+
+func TestUnwrappedNullableUnions(t *testing.T) {
+	codec, err := NewCodec(`{"type":"record","name":"R","fields":[
+		{"name":"count","type":["null","long"]},
+		{"name":"tags","type":["null",{"type":"map","values":"int"}]},
+		{"name":"either","type":["int","string"]}
+	]}`, WithUnwrappedNullableUnions(true))
+	ensureError(t, err)
+
+	for _, tc := range []struct {
+		datum    map[string]interface{}
+		expected string
+	}{
+		{map[string]interface{}{"count": nil, "tags": nil, "either": Union("int", 1)}, `{"count":null,"tags":null,"either":{"int":1}}`},
+		{map[string]interface{}{"count": int64(3), "tags": map[string]interface{}{"a": 1}, "either": Union("int", 1)}, `{"count":{"long":3},"tags":{"map":{"a":1}},"either":{"int":1}}`},
+		{map[string]interface{}{"count": Union("long", 3), "tags": Union("map", map[string]interface{}{}), "either": Union("string", "x")}, `{"count":{"long":3},"tags":{"map":{}},"either":{"string":"x"}}`},
+	} {
+		buf, err := codec.TextualFromNative(nil, tc.datum)
+		ensureError(t, err)
+		if actual := string(buf); actual != tc.expected {
+			t.Errorf("GOT: %v; WANT: %v", actual, tc.expected)
+		}
+		expected, _, err := codec.NativeFromTextual(buf)
+		ensureError(t, err)
+		expectedBinary, err := codec.BinaryFromNative(nil, expected)
+		ensureError(t, err)
+		buf, err = codec.BinaryFromNative(nil, tc.datum)
+		ensureError(t, err)
+		if !bytes.Equal(buf, expectedBinary) {
+			t.Errorf("GOT: %v; WANT: %v", buf, expectedBinary)
+		}
+	}
+
+	// unions of more than null and one other type still require wrapping
+	_, err = codec.BinaryFromNative(nil, map[string]interface{}{"count": nil, "tags": nil, "either": 1})
+	ensureError(t, err, "cannot encode binary union")
+	_, err = codec.BinaryFromNative(nil, map[string]interface{}{"count": "three", "tags": nil, "either": Union("int", 1)})
+	ensureError(t, err, `cannot encode binary record "R" field "count"`)
+
+	plain := newCodecUsingV2(t, `["null","long"]`)
+	_, err = plain.BinaryFromNative(nil, int64(3))
+	ensureError(t, err, "cannot encode binary union")
+}
+
 func ExampleUnion() {
 	codec, err := NewCodec(`["null","string","int"]`)
 	if err != nil {
@@ -0,0 +1,83 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+// Package uuid registers the Avro uuid logical type with goavro when it is
+// imported:
+//
+//     import _ "github.com/linkedin/goavro/v2/uuid"
+//
+// A uuid annotates the string type. Codecs decode it as a string in the
+// canonical, lower case, 8-4-4-4-12 form, and return an error when the encoded
+// string is not a UUID. They encode either a string, which is validated and
+// then written in the canonical form, or a [16]byte.
+package uuid
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+func init() {
+	goavro.RegisterLogicalType(goavro.LogicalType{
+		Type:       "string",
+		Name:       "uuid",
+		ToNative:   toNative,
+		FromNative: fromNative,
+	})
+}
+
+func toNative(datum interface{}) (interface{}, error) {
+	s, ok := datum.(string)
+	if !ok {
+		return nil, fmt.Errorf("expected string; received: %T", datum)
+	}
+	u, err := parse(s)
+	if err != nil {
+		return nil, err
+	}
+	return format(u), nil
+}
+
+func fromNative(datum interface{}) (interface{}, error) {
+	switch v := datum.(type) {
+	case string:
+		u, err := parse(v)
+		if err != nil {
+			return nil, err
+		}
+		return format(u), nil
+	case [16]byte:
+		return format(v), nil
+	default:
+		return nil, fmt.Errorf("expected string or [16]byte; received: %T", datum)
+	}
+}
+
+// parse returns the bytes of a UUID in the 8-4-4-4-12 form, in either case.
+func parse(s string) ([16]byte, error) {
+	var u [16]byte
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, fmt.Errorf("uuid ought to have the form xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx; received: %q", s)
+	}
+	b, err := hex.DecodeString(strings.Replace(s, "-", "", -1))
+	if err != nil {
+		return u, fmt.Errorf("uuid ought to have hexadecimal digits; received: %q", s)
+	}
+	copy(u[:], b)
+	return u, nil
+}
+
+// format returns the canonical form of a UUID.
+func format(u [16]byte) string {
+	s := hex.EncodeToString(u[:])
+	return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
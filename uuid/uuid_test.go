@@ -0,0 +1,64 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package uuid
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+func TestUUID(t *testing.T) {
+	codec, err := goavro.NewCodec(`{"type":"string","logicalType":"uuid"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := codec.BinaryFromNative(nil, "6BA7B810-9DAD-11D1-80B4-00C04FD430C8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	datum, _, err := codec.NativeFromBinary(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := datum, "6ba7b810-9dad-11d1-80b4-00c04fd430c8"; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	u := [16]byte{0x6b, 0xa7, 0xb8, 0x10, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+	text, err := codec.TextualFromNative(nil, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := string(text), `"6ba7b810-9dad-11d1-80b4-00c04fd430c8"`; actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+
+	_, err = codec.BinaryFromNative(nil, "not a uuid")
+	if err == nil || !strings.Contains(err.Error(), "uuid ought to have the form") {
+		t.Errorf("GOT: %v; WANT: %v", err, "uuid ought to have the form")
+	}
+
+	// decoding a string which is not a UUID fails
+	plain, err := goavro.NewCodec(`"string"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err = plain.BinaryFromNative(nil, "6ba7b810-9dad-11d1-80b4-00c04fd430cx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = codec.NativeFromBinary(buf)
+	if err == nil || !strings.Contains(err.Error(), "hexadecimal") {
+		t.Errorf("GOT: %v; WANT: %v", err, "hexadecimal")
+	}
+}
@@ -0,0 +1,413 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidationError describes one value that does not satisfy a validation
+// annotation of the schema. Path addresses the value from the top level datum,
+// using field names separated by periods, the index of array items in square
+// brackets, and the key of map values in braces.
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// ValidationErrors is returned by Codec.Validate, and lists every value that
+// does not satisfy the validation annotations of the schema.
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, ve := range e {
+		messages[i] = ve.Error()
+	}
+	return "cannot validate: " + strings.Join(messages, "; ")
+}
+
+// Validate checks the native datum against the validation annotations of the
+// schema, which constrain values beyond their types, and returns
+// ValidationErrors listing the values that do not satisfy them, or nil. It does
+// not check that the datum matches the schema types, which encoding does.
+// Annotations are specified on record fields, where they apply to the value of
+// the field, ignoring nulls, and on types, where they apply to every value of
+// the type:
+//
+//   * x-min and x-max are the inclusive bounds of numbers, and of the length
+//     of strings, in characters, bytes, arrays, and maps
+//   * x-pattern is a regular expression, using the syntax of the regexp
+//     package, that strings and enum symbols ought to match; as in JSON
+//     Schema, it is not anchored
+//   * x-required-if, only on record fields, requires the field to be present
+//     and not null when the condition holds: when it is the name of another
+//     field of the record, that the other field is present and not null, and
+//     when it is an object such as {"field": "kind", "equals": "card"}, that
+//     the other field has the specified value
+//
+//     codec, err := goavro.NewCodec(`{"type": "record", "name": "Payment", "fields": [
+//         {"name": "amount", "type": "long", "x-min": 1},
+//         {"name": "kind", "type": "string", "x-pattern": "^(card|cash)$"},
+//         {"name": "card", "type": ["null", "string"], "default": null,
+//          "x-required-if": {"field": "kind", "equals": "card"}}
+//     ]}`)
+//     if err != nil {
+//         return err
+//     }
+//     err = codec.Validate(map[string]interface{}{"amount": 0, "kind": "card"})
+//     // err lists "amount" and "card"
+//
+// Codecs created with WithValidateOnEncode validate each datum before encoding
+// it.
+func (c *Codec) Validate(datum interface{}) error {
+	v, err := c.validator()
+	if err != nil {
+		return fmt.Errorf("cannot validate: %s", err)
+	}
+	var errs ValidationErrors
+	v.validate(v.root, datum, "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateOnEncode validates the datum before it is encoded, when the Codec
+// was created with the ValidateOnEncode option.
+func (c *Codec) validateOnEncode(datum interface{}) error {
+	if !c.option.ValidateOnEncode {
+		return nil
+	}
+	return c.Validate(datum)
+}
+
+// validator returns the validation rules of the schema of the Codec, compiling
+// them the first time they are requested.
+func (c *Codec) validator() (*validator, error) {
+	c.validatorOnce.Do(func() {
+		n, err := schemaNodeFromCodec(c)
+		if err != nil {
+			c.validatorErr = err
+			return
+		}
+		c.validatorRules, c.validatorErr = newValidator(n)
+	})
+	return c.validatorRules, c.validatorErr
+}
+
+// validator holds the compiled validation annotations of a schema.
+type validator struct {
+	root   *schemaNode
+	types  map[*schemaNode]*validationRules
+	fields map[*schemaNodeField]*validationRules
+}
+
+type validationRules struct {
+	min, max   *float64
+	pattern    *regexp.Regexp
+	requiredIf *validationCondition
+}
+
+// validationCondition is the condition of an x-required-if annotation.
+type validationCondition struct {
+	field     string
+	equals    interface{}
+	hasEquals bool
+}
+
+func newValidator(root *schemaNode) (*validator, error) {
+	v := &validator{root: root, types: make(map[*schemaNode]*validationRules), fields: make(map[*schemaNodeField]*validationRules)}
+	visited := make(map[*schemaNode]struct{})
+	var visit func(n *schemaNode) error
+	visit = func(n *schemaNode) error {
+		if _, ok := visited[n]; ok {
+			return nil
+		}
+		visited[n] = struct{}{}
+		rules, err := newValidationRules(n.attributes, false)
+		if err != nil {
+			return fmt.Errorf("%s: %s", n.label(), err)
+		}
+		if rules != nil {
+			v.types[n] = rules
+		}
+		for _, f := range n.fields {
+			rules, err := newValidationRules(f.attributes, true)
+			if err != nil {
+				return fmt.Errorf("record %q field %q: %s", n.fullName, f.name, err)
+			}
+			if rules != nil {
+				if rules.requiredIf != nil && !n.hasField(rules.requiredIf.field) {
+					return fmt.Errorf("record %q field %q: x-required-if ought to name a field of the record: %q", n.fullName, f.name, rules.requiredIf.field)
+				}
+				v.fields[f] = rules
+			}
+		}
+		for _, child := range schemaNodeChildren(n) {
+			if err := visit(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := visit(root); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// newValidationRules returns the rules of the validation annotations among the
+// attributes, or nil when there are none.
+func newValidationRules(attributes map[string]interface{}, isField bool) (*validationRules, error) {
+	rules := new(validationRules)
+	var found bool
+	for _, bound := range []struct {
+		key   string
+		value **float64
+	}{{"x-min", &rules.min}, {"x-max", &rules.max}} {
+		value, ok := attributes[bound.key]
+		if !ok {
+			continue
+		}
+		f, ok := value.(float64)
+		if !ok {
+			return nil, fmt.Errorf("%s ought to be a number; received: %T", bound.key, value)
+		}
+		*bound.value, found = &f, true
+	}
+	if value, ok := attributes["x-pattern"]; ok {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("x-pattern ought to be a string; received: %T", value)
+		}
+		pattern, err := regexp.Compile(s)
+		if err != nil {
+			return nil, fmt.Errorf("x-pattern ought to be a valid regular expression: %s", err)
+		}
+		rules.pattern, found = pattern, true
+	}
+	if value, ok := attributes["x-required-if"]; ok {
+		if !isField {
+			return nil, fmt.Errorf("x-required-if ought to annotate a record field")
+		}
+		switch condition := value.(type) {
+		case string:
+			rules.requiredIf = &validationCondition{field: condition}
+		case map[string]interface{}:
+			field, ok := condition["field"].(string)
+			if !ok {
+				return nil, fmt.Errorf("x-required-if ought to have a field name; received: %v", condition)
+			}
+			equals, hasEquals := condition["equals"]
+			rules.requiredIf = &validationCondition{field: field, equals: equals, hasEquals: hasEquals}
+		default:
+			return nil, fmt.Errorf("x-required-if ought to be a field name or an object; received: %T", value)
+		}
+		found = true
+	}
+	if !found {
+		return nil, nil
+	}
+	return rules, nil
+}
+
+// validate appends the violations of the datum, found at path, to errs.
+func (v *validator) validate(n *schemaNode, datum interface{}, path string, errs *ValidationErrors) {
+	if rules, ok := v.types[n]; ok {
+		rules.check(datum, path, errs)
+	}
+	switch n.typeName {
+	case "union":
+		if name, value, ok := unwrapUnion(datum); ok {
+			for _, member := range n.members {
+				if unionMemberName(member) == name {
+					v.validate(member, value, path, errs)
+					return
+				}
+			}
+		}
+	case "record":
+		record, ok := datum.(map[string]interface{})
+		if !ok {
+			return
+		}
+		for _, f := range n.fields {
+			fieldPath := f.name
+			if path != "" {
+				fieldPath = path + "." + f.name
+			}
+			value, present := record[f.name]
+			if rules, ok := v.fields[f]; ok {
+				if rules.requiredIf != nil && rules.requiredIf.holds(n, record) && (!present || value == nil) {
+					*errs = append(*errs, ValidationError{Path: fieldPath, Message: rules.requiredIf.message()})
+				}
+				if present {
+					rules.check(unionValue(f.node, value), fieldPath, errs)
+				}
+			}
+			if present {
+				v.validate(f.node, value, fieldPath, errs)
+			}
+		}
+	case "array":
+		items, _ := datum.([]interface{})
+		for i, item := range items {
+			v.validate(n.items, item, path+"["+strconv.Itoa(i)+"]", errs)
+		}
+	case "map":
+		switch values := datum.(type) {
+		case map[string]interface{}:
+			for k, value := range values {
+				v.validate(n.values, value, path+"{"+k+"}", errs)
+			}
+		case OrderedMap:
+			for _, item := range values {
+				v.validate(n.values, item.Value, path+"{"+item.Key+"}", errs)
+			}
+		}
+	}
+}
+
+// check appends the violations of the value rules by the datum to errs. Nulls
+// satisfy every rule.
+func (rules *validationRules) check(datum interface{}, path string, errs *ValidationErrors) {
+	if datum == nil {
+		return
+	}
+	if rules.min != nil || rules.max != nil {
+		if measure, kind, ok := validationMeasure(datum); ok {
+			if rules.min != nil && measure < *rules.min {
+				*errs = append(*errs, ValidationError{Path: path, Message: fmt.Sprintf("%s ought to be at least %v: %v", kind, *rules.min, measure)})
+			}
+			if rules.max != nil && measure > *rules.max {
+				*errs = append(*errs, ValidationError{Path: path, Message: fmt.Sprintf("%s ought to be at most %v: %v", kind, *rules.max, measure)})
+			}
+		}
+	}
+	if rules.pattern != nil {
+		if s, ok := datum.(string); ok && !rules.pattern.MatchString(s) {
+			*errs = append(*errs, ValidationError{Path: path, Message: fmt.Sprintf("value ought to match %q: %q", rules.pattern, s)})
+		}
+	}
+}
+
+// holds returns true when the condition holds for the record of the node.
+func (condition *validationCondition) holds(n *schemaNode, record map[string]interface{}) bool {
+	value, present := record[condition.field]
+	if !present || value == nil {
+		return false
+	}
+	if !condition.hasEquals {
+		return true
+	}
+	for _, f := range n.fields {
+		if f.name == condition.field {
+			value = unionValue(f.node, value)
+		}
+	}
+	if expected, ok := condition.equals.(float64); ok {
+		actual, kind, ok := validationMeasure(value)
+		return ok && kind == "value" && actual == expected
+	}
+	return value == condition.equals
+}
+
+func (condition *validationCondition) message() string {
+	if condition.hasEquals {
+		return fmt.Sprintf("value ought to be present when %q is %s", condition.field, markdownJSON(condition.equals))
+	}
+	return fmt.Sprintf("value ought to be present when %q is present", condition.field)
+}
+
+// validationMeasure returns the number that x-min and x-max bound: the value of
+// numbers, or the length of strings, bytes, arrays, and maps.
+func validationMeasure(datum interface{}) (float64, string, bool) {
+	switch v := datum.(type) {
+	case int:
+		return float64(v), "value", true
+	case int8:
+		return float64(v), "value", true
+	case int16:
+		return float64(v), "value", true
+	case int32:
+		return float64(v), "value", true
+	case int64:
+		return float64(v), "value", true
+	case uint:
+		return float64(v), "value", true
+	case uint8:
+		return float64(v), "value", true
+	case uint16:
+		return float64(v), "value", true
+	case uint32:
+		return float64(v), "value", true
+	case uint64:
+		return float64(v), "value", true
+	case float32:
+		return float64(v), "value", true
+	case float64:
+		return v, "value", true
+	case json.Number:
+		f, err := v.Float64()
+		return f, "value", err == nil
+	case string:
+		return float64(utf8.RuneCountInString(v)), "length", true
+	case []byte:
+		return float64(len(v)), "length", true
+	case []interface{}:
+		return float64(len(v)), "length", true
+	case map[string]interface{}:
+		return float64(len(v)), "length", true
+	case OrderedMap:
+		return float64(len(v)), "length", true
+	}
+	return 0, "", false
+}
+
+// unwrapUnion returns the member name and value of a union datum in its native
+// form, a map with a single key, or a UnionValue.
+func unwrapUnion(datum interface{}) (string, interface{}, bool) {
+	if v, ok := datum.(UnionValue); ok {
+		return v.Type, v.Value, true
+	}
+	wrapped, ok := datum.(map[string]interface{})
+	if !ok || len(wrapped) != 1 {
+		return "", nil, false
+	}
+	for name, value := range wrapped {
+		return name, value, true
+	}
+	return "", nil, false
+}
+
+// unionValue returns the value of a datum of the union node, or the datum
+// itself when the node is not a union.
+func unionValue(n *schemaNode, datum interface{}) interface{} {
+	if n.typeName != "union" {
+		return datum
+	}
+	if _, value, ok := unwrapUnion(datum); ok {
+		return value
+	}
+	return datum
+}
@@ -0,0 +1,91 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+package goavro
+
+import (
+	"reflect"
+	"testing"
+)
+
+const paymentSchema = `{"type": "record", "name": "Payment", "fields": [
+	{"name": "amount", "type": "long", "x-min": 1, "x-max": 1000},
+	{"name": "kind", "type": "string", "x-pattern": "^(card|cash)$"},
+	{"name": "card", "type": ["null", {"type": "string", "x-min": 4}], "default": null,
+	 "x-required-if": {"field": "kind", "equals": "card"}},
+	{"name": "receipt", "type": ["null", "string"], "default": null, "x-required-if": "card"},
+	{"name": "tags", "type": {"type": "array", "items": {"type": "string", "x-max": 3}}, "default": [], "x-max": 2}
+]}`
+
+func TestCodecValidate(t *testing.T) {
+	codec := newCodecUsingV2(t, paymentSchema)
+
+	ensureError(t, codec.Validate(map[string]interface{}{"amount": 5, "kind": "cash"}))
+	ensureError(t, codec.Validate(map[string]interface{}{
+		"amount":  int64(1000),
+		"kind":    "card",
+		"card":    Union("string", "4111"),
+		"receipt": Union("string", "r"),
+		"tags":    []interface{}{"a", "bcd"},
+	}))
+
+	err := codec.Validate(map[string]interface{}{
+		"amount": 0,
+		"kind":   "card",
+		"card":   Union("string", "41"),
+		"tags":   []interface{}{"a", "b", "long"},
+	})
+	expected := ValidationErrors{
+		{Path: "amount", Message: "value ought to be at least 1: 0"},
+		{Path: "card", Message: "length ought to be at least 4: 2"},
+		{Path: "receipt", Message: `value ought to be present when "card" is present`},
+		{Path: "tags", Message: "length ought to be at most 2: 3"},
+		{Path: "tags[2]", Message: "length ought to be at most 3: 4"},
+	}
+	if !reflect.DeepEqual(err, expected) {
+		t.Errorf("GOT: %v; WANT: %v", err, expected)
+	}
+
+	err = codec.Validate(map[string]interface{}{"amount": 1001.5, "kind": "card!"})
+	ensureError(t, err, `cannot validate: amount: value ought to be at most 1000: 1001.5; kind: value ought to match "^(card|cash)$": "card!"`)
+
+	err = codec.Validate(map[string]interface{}{"amount": 1, "kind": "card"})
+	ensureError(t, err, `card: value ought to be present when "kind" is "card"`)
+}
+
+func TestCodecValidateOnEncode(t *testing.T) {
+	codec, err := NewCodec(paymentSchema, WithValidateOnEncode(true))
+	ensureError(t, err)
+
+	_, err = codec.BinaryFromNative(nil, map[string]interface{}{"amount": 0, "kind": "cash"})
+	ensureError(t, err, "cannot encode binary: cannot validate: amount")
+	_, err = codec.TextualFromNative(nil, map[string]interface{}{"amount": 0, "kind": "cash"})
+	ensureError(t, err, "cannot encode textual: cannot validate: amount")
+	_, err = codec.SingleFromNative(nil, map[string]interface{}{"amount": 0, "kind": "cash"})
+	ensureError(t, err, "cannot encode single-object: cannot validate: amount")
+	_, err = codec.BinaryFromNative(nil, map[string]interface{}{"amount": 1, "kind": "cash"})
+	ensureError(t, err)
+
+	// codecs without the option encode without validating
+	plain, err := codec.WithOptions(WithValidateOnEncode(false))
+	ensureError(t, err)
+	_, err = plain.BinaryFromNative(nil, map[string]interface{}{"amount": 0, "kind": "cash"})
+	ensureError(t, err)
+}
+
+func TestCodecValidateInvalidAnnotations(t *testing.T) {
+	_, err := NewCodec(`{"type": "string", "x-pattern": "("}`, WithValidateOnEncode(true))
+	ensureError(t, err, "cannot create codec: invalid validation annotation", "x-pattern ought to be a valid regular expression")
+
+	_, err = NewCodec(`{"type": "long", "x-min": "1"}`, WithValidateOnEncode(true))
+	ensureError(t, err, "x-min ought to be a number")
+
+	codec := newCodecUsingV2(t, `{"type": "record", "name": "r", "fields": [{"name": "a", "type": "int", "x-required-if": "b"}]}`)
+	ensureError(t, codec.Validate(map[string]interface{}{"a": 1}), `x-required-if ought to name a field of the record: "b"`)
+}
@@ -0,0 +1,413 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits of an Excel worksheet.
+const (
+	xlsxMaxRows       = 1048576
+	xlsxMaxColumns    = 16384
+	xlsxMaxCellLength = 32767
+	xlsxMaxSheetName  = 31
+)
+
+// xlsxMaxExactInteger is the largest integer Excel shows without losing
+// digits, as it keeps 15 significant digits.
+const xlsxMaxExactInteger = 999999999999999
+
+// Styles of the cells of the worksheet, which are indices into the cellXfs of
+// xlsxStyles.
+const (
+	xlsxStyleDate     = "1"
+	xlsxStyleDateTime = "2"
+	xlsxStyleTime     = "3"
+	xlsxStyleHeader   = "4"
+)
+
+// xlsxUnixEpoch is the Excel serial date of 1970-01-01.
+const xlsxUnixEpoch = 25569
+
+// XLSXWriter writes the records of a record schema as the rows of an Excel
+// worksheet, in the XLSX format, for business users who want a dataset in
+// Excel. Unlike CSV, the cells are typed: numbers are numbers, booleans are
+// booleans, and dates, times, and timestamps are dates and times, formatted as
+// such, so Excel neither guesses nor mangles the types of the values. Records
+// are flattened into columns like TableWriter flattens them, and the first row
+// names the columns, unless omitted by the configuration.
+//
+// The cells of int and long values are numbers, except for values with more
+// than 15 digits, such as identifiers, whose cells are strings so Excel does
+// not round them. Decimal values are numbers. Values of the date, time-millis,
+// time-micros, timestamp-millis, and timestamp-micros logical types are dates
+// and times in UTC. Floating point values that are not finite, enums, strings,
+// bytes, fixed, and the values written by TableWriter as their Avro JSON
+// encoding, are strings. Null values are empty cells, unless the
+// configuration specifies a NullToken.
+//
+// A worksheet holds at most 1,048,576 rows and 16,384 columns, and a cell at
+// most 32,767 characters, so the XLSXWriter is meant for small datasets, and
+// returns an error rather than writing a workbook Excel would not open
+// intact. The Comma and QuoteAll options of the configuration do not apply.
+type XLSXWriter struct {
+	*tableLayout
+	zw         *zip.Writer
+	bw         *bufio.Writer // writes the worksheet
+	config     TableConfig
+	buf        []byte // row being written
+	rows       int
+	closed     bool
+	columnRefs []string // column letters of each column
+}
+
+// NewXLSXWriter returns an XLSXWriter that writes the records of the record
+// schema of the Codec to w as an XLSX workbook of one worksheet, named after
+// the record. The workbook is complete once Close returns.
+//
+//     xw, err := goavro.NewXLSXWriter(file, codec, goavro.TableConfig{})
+//     if err != nil {
+//         return err
+//     }
+//     for _, datum := range data {
+//         if err = xw.Write(datum); err != nil {
+//             return err
+//         }
+//     }
+//     return xw.Close()
+func NewXLSXWriter(w io.Writer, codec *Codec, config TableConfig) (*XLSXWriter, error) {
+	tl, err := newTableLayout(codec, config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create XLSX writer: %s", err)
+	}
+	if len(tl.columns) > xlsxMaxColumns {
+		return nil, fmt.Errorf("cannot create XLSX writer: columns exceed %d: %d", xlsxMaxColumns, len(tl.columns))
+	}
+	xw := &XLSXWriter{tableLayout: tl, zw: zip.NewWriter(w), config: config}
+	xw.columnRefs = make([]string, len(tl.columns))
+	for i := range xw.columnRefs {
+		xw.columnRefs[i] = xlsxColumnName(i)
+	}
+
+	root, _ := schemaNodeFromCodec(codec) // NOTE: newTableLayout already parsed it
+	sheetName := root.fullName[strings.LastIndexByte(root.fullName, '.')+1:]
+	if len(sheetName) > xlsxMaxSheetName {
+		sheetName = sheetName[:xlsxMaxSheetName]
+	}
+	parts := []struct{ name, contents string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRelationships},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, sheetName)},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRelationships},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, part := range parts {
+		if err = xw.writePart(part.name, part.contents); err != nil {
+			return nil, fmt.Errorf("cannot create XLSX writer: %s", err)
+		}
+	}
+	sheet, err := xw.zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, fmt.Errorf("cannot create XLSX writer: %s", err)
+	}
+	xw.bw = bufio.NewWriter(sheet)
+	if _, err = xw.bw.WriteString(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`); err != nil {
+		return nil, fmt.Errorf("cannot create XLSX writer: %s", err)
+	}
+	if !config.OmitHeader {
+		xw.buf = xw.appendRowStart(xw.buf[:0])
+		for i, column := range xw.columns {
+			xw.buf = appendXLSXString(xw.buf, xw.cellRef(i), xlsxStyleHeader, column)
+		}
+		if err = xw.writeRow(); err != nil {
+			return nil, fmt.Errorf("cannot create XLSX writer: %s", err)
+		}
+	}
+	return xw, nil
+}
+
+func (xw *XLSXWriter) writePart(name, contents string) error {
+	part, err := xw.zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(part, contents)
+	return err
+}
+
+// Columns returns the names of the columns of the worksheet, in order. The
+// returned slice ought not to be modified.
+func (xw *XLSXWriter) Columns() []string {
+	return xw.columns
+}
+
+// Write writes the record datum as a row of the worksheet.
+func (xw *XLSXWriter) Write(datum interface{}) error {
+	if xw.closed {
+		return errors.New("cannot write row: XLSX writer closed")
+	}
+	if xw.rows >= xlsxMaxRows {
+		return fmt.Errorf("cannot write row: rows exceed %d", xlsxMaxRows)
+	}
+	if err := xw.fill(datum); err != nil {
+		return fmt.Errorf("cannot write row: %s", err)
+	}
+	xw.buf = xw.appendRowStart(xw.buf[:0])
+	for i, value := range xw.values {
+		var err error
+		if value == nil {
+			if xw.config.NullToken != "" {
+				xw.buf = appendXLSXString(xw.buf, xw.cellRef(i), "", xw.config.NullToken)
+			}
+			continue
+		}
+		if xw.buf, err = appendXLSXCell(xw.buf, xw.cellRef(i), xw.nodes[i], value); err != nil {
+			return fmt.Errorf("cannot write row: column %q: %s", xw.columns[i], err)
+		}
+	}
+	if err := xw.writeRow(); err != nil {
+		return fmt.Errorf("cannot write row: %s", err)
+	}
+	return nil
+}
+
+// Export writes each remaining data item read from the OCF as a row of the
+// worksheet, then closes the writer, returning the number of rows written. The
+// data items ought to be records of the schema of the Codec of the writer,
+// such as when the writer was created with the Codec of the OCFReader.
+func (xw *XLSXWriter) Export(ocfr *OCFReader) (int64, error) {
+	var rows int64
+	for ocfr.Scan() {
+		datum, err := ocfr.Read()
+		if err != nil {
+			return rows, err
+		}
+		if err = xw.Write(datum); err != nil {
+			return rows, err
+		}
+		rows++
+	}
+	if err := ocfr.Err(); err != nil {
+		return rows, err
+	}
+	return rows, xw.Close()
+}
+
+// Close completes the workbook, and writes the remainder of it to the
+// underlying io.Writer, which it does not close.
+func (xw *XLSXWriter) Close() error {
+	if xw.closed {
+		return nil
+	}
+	xw.closed = true
+	if _, err := xw.bw.WriteString(`</sheetData></worksheet>`); err != nil {
+		return fmt.Errorf("cannot close XLSX writer: %s", err)
+	}
+	if err := xw.bw.Flush(); err != nil {
+		return fmt.Errorf("cannot close XLSX writer: %s", err)
+	}
+	if err := xw.zw.Close(); err != nil {
+		return fmt.Errorf("cannot close XLSX writer: %s", err)
+	}
+	return nil
+}
+
+func (xw *XLSXWriter) appendRowStart(buf []byte) []byte {
+	buf = append(buf, `<row r="`...)
+	buf = strconv.AppendInt(buf, int64(xw.rows+1), 10)
+	return append(buf, `">`...)
+}
+
+func (xw *XLSXWriter) writeRow() error {
+	xw.buf = append(xw.buf, `</row>`...)
+	if _, err := xw.bw.Write(xw.buf); err != nil {
+		return err
+	}
+	xw.rows++
+	return nil
+}
+
+// cellRef returns the reference of the cell of column i of the current row,
+// such as "B7".
+func (xw *XLSXWriter) cellRef(i int) string {
+	return xw.columnRefs[i] + strconv.Itoa(xw.rows+1)
+}
+
+// xlsxColumnName returns the letters naming the column at index i, such as "A"
+// for 0, and "AA" for 26.
+func xlsxColumnName(i int) string {
+	var name []byte
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = append([]byte{byte('A' + (i-1)%26)}, name...)
+	}
+	return string(name)
+}
+
+// appendXLSXCell appends the typed cell of the Avro JSON encoding of a value of
+// the type of the node.
+func appendXLSXCell(buf []byte, ref string, n *schemaNode, value json.RawMessage) ([]byte, error) {
+	switch n.typeName {
+	case "boolean":
+		buf = append(buf, `<c r="`...)
+		buf = append(buf, ref...)
+		buf = append(buf, `" t="b"><v>`...)
+		if string(value) == "true" {
+			buf = append(buf, '1')
+		} else {
+			buf = append(buf, '0')
+		}
+		return append(buf, `</v></c>`...), nil
+	case "int", "long":
+		i, err := strconv.ParseInt(string(value), 10, 64)
+		if err != nil {
+			return nil, err // should not get here
+		}
+		switch n.logicalType {
+		case "date":
+			return appendXLSXNumber(buf, ref, xlsxStyleDate, strconv.FormatInt(i+xlsxUnixEpoch, 10)), nil
+		case "time-millis":
+			return appendXLSXNumber(buf, ref, xlsxStyleTime, xlsxFormatFloat(float64(i)/86400e3)), nil
+		case "time-micros":
+			return appendXLSXNumber(buf, ref, xlsxStyleTime, xlsxFormatFloat(float64(i)/86400e6)), nil
+		case "timestamp-millis":
+			return appendXLSXNumber(buf, ref, xlsxStyleDateTime, xlsxFormatFloat(float64(i)/86400e3+xlsxUnixEpoch)), nil
+		case "timestamp-micros":
+			return appendXLSXNumber(buf, ref, xlsxStyleDateTime, xlsxFormatFloat(float64(i)/86400e6+xlsxUnixEpoch)), nil
+		}
+		if i > xlsxMaxExactInteger || i < -xlsxMaxExactInteger {
+			return appendXLSXString(buf, ref, "", string(value)), nil
+		}
+		return appendXLSXNumber(buf, ref, "", string(value)), nil
+	case "float", "double":
+		switch string(value) {
+		case "null", "1e999", "-1e999":
+			s, err := formValue(n, value)
+			if err != nil {
+				return nil, err // should not get here
+			}
+			return appendXLSXString(buf, ref, "", s), nil
+		}
+		return appendXLSXNumber(buf, ref, "", string(value)), nil
+	}
+	s := string(value)
+	if isFormScalar(n) {
+		var err error
+		if s, err = formValue(n, value); err != nil {
+			return nil, err // should not get here
+		}
+		if n.logicalType == "decimal" {
+			return appendXLSXNumber(buf, ref, "", xlsxDecimal([]byte(s), n.scale)), nil
+		}
+	}
+	if utf8.RuneCountInString(s) > xlsxMaxCellLength {
+		return nil, fmt.Errorf("cell exceeds %d characters", xlsxMaxCellLength)
+	}
+	return appendXLSXString(buf, ref, "", s), nil
+}
+
+func appendXLSXNumber(buf []byte, ref, style, number string) []byte {
+	buf = append(buf, `<c r="`...)
+	buf = append(buf, ref...)
+	if style != "" {
+		buf = append(buf, `" s="`...)
+		buf = append(buf, style...)
+	}
+	buf = append(buf, `"><v>`...)
+	buf = append(buf, number...)
+	return append(buf, `</v></c>`...)
+}
+
+func appendXLSXString(buf []byte, ref, style, s string) []byte {
+	buf = append(buf, `<c r="`...)
+	buf = append(buf, ref...)
+	if style != "" {
+		buf = append(buf, `" s="`...)
+		buf = append(buf, style...)
+	}
+	buf = append(buf, `" t="inlineStr"><is><t xml:space="preserve">`...)
+	var escaped bytes.Buffer
+	_ = xml.EscapeText(&escaped, []byte(s))
+	buf = append(buf, escaped.Bytes()...)
+	return append(buf, `</t></is></c>`...)
+}
+
+func xlsxFormatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// xlsxDecimal returns the number of the two's complement big-endian bytes of a
+// decimal value of the scale.
+func xlsxDecimal(b []byte, scale int) string {
+	i := new(big.Int).SetBytes(b)
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		i.Sub(i, new(big.Int).Lsh(big.NewInt(1), uint(len(b)*8)))
+	}
+	r := new(big.Rat).SetFrac(i, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil))
+	f, _ := r.Float64()
+	return xlsxFormatFloat(f)
+}
+
+const xlsxContentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+	`</Types>`
+
+const xlsxRootRelationships = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+// xlsxWorkbook is formatted with the name of the worksheet.
+const xlsxWorkbook = xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets>` +
+	`</workbook>`
+
+const xlsxWorkbookRelationships = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+	`</Relationships>`
+
+// xlsxStyles defines the cell styles referenced by the xlsxStyle constants.
+const xlsxStyles = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="3">` +
+	`<numFmt numFmtId="164" formatCode="yyyy-mm-dd"/>` +
+	`<numFmt numFmtId="165" formatCode="yyyy-mm-dd hh:mm:ss.000"/>` +
+	`<numFmt numFmtId="166" formatCode="hh:mm:ss.000"/>` +
+	`</numFmts>` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="5">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="165" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="166" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
@@ -0,0 +1,157 @@
+// Copyright [2019] LinkedIn Corp. Licensed under the Apache License, Version
+// 2.0 (the "License"); you may not use this file except in compliance with the
+// License.  You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+
+//go:build !goavro_minimal
+// +build !goavro_minimal
+
+package goavro
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readXLSXPart returns the contents of the named part of the XLSX workbook,
+// after checking that it is well formed XML.
+func readXLSXPart(t *testing.T, workbook []byte, name string) string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(workbook), int64(len(workbook)))
+	ensureError(t, err)
+	for _, f := range zr.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		ensureError(t, err)
+		defer rc.Close()
+		contents, err := ioutil.ReadAll(rc)
+		ensureError(t, err)
+		d := xml.NewDecoder(bytes.NewReader(contents))
+		for {
+			if _, err = d.Token(); err != nil {
+				break
+			}
+		}
+		if err != io.EOF {
+			t.Fatalf("%s: %s", name, err)
+		}
+		return string(contents)
+	}
+	t.Fatalf("GOT: no part %q; WANT: part", name)
+	return ""
+}
+
+func TestXLSXWriter(t *testing.T) {
+	codec, err := NewCodec(`{"type":"record","name":"com.example.sale","fields":[
+		{"name":"id","type":"long"},
+		{"name":"paid","type":"boolean"},
+		{"name":"day","type":{"type":"int","logicalType":"date"}},
+		{"name":"at","type":["null",{"type":"long","logicalType":"timestamp-millis"}]},
+		{"name":"amount","type":{"type":"bytes","logicalType":"decimal","precision":6,"scale":2}},
+		{"name":"ratio","type":"double"},
+		{"name":"note","type":"string"},
+		{"name":"buyer","type":{"type":"record","name":"buyer","fields":[{"name":"name","type":"string"}]}}
+	]}`)
+	ensureError(t, err)
+
+	bb := new(bytes.Buffer)
+	xw, err := NewXLSXWriter(bb, codec, TableConfig{})
+	ensureError(t, err)
+	ensureError(t, xw.Write(map[string]interface{}{
+		"id":     int64(1234567890123456789),
+		"paid":   true,
+		"day":    time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+		"at":     Union("long.timestamp-millis", time.Date(2020, 1, 2, 12, 0, 0, 0, time.UTC)),
+		"amount": big.NewRat(-12345, 100),
+		"ratio":  0.25,
+		"note":   "<b> & co",
+		"buyer":  map[string]interface{}{"name": "Ann"},
+	}))
+	ensureError(t, xw.Write(map[string]interface{}{
+		"id":     int64(7),
+		"paid":   false,
+		"day":    time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC),
+		"at":     nil,
+		"amount": big.NewRat(1, 1),
+		"ratio":  0.0,
+		"note":   "",
+		"buyer":  map[string]interface{}{"name": "Bo"},
+	}))
+	ensureError(t, xw.Close())
+	ensureError(t, xw.Close()) // closing again does nothing
+
+	workbook := bb.Bytes()
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/_rels/workbook.xml.rels", "xl/styles.xml"} {
+		readXLSXPart(t, workbook, name)
+	}
+	if actual, expected := readXLSXPart(t, workbook, "xl/workbook.xml"), `<sheet name="sale"`; !strings.Contains(actual, expected) {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	sheet := readXLSXPart(t, workbook, "xl/worksheets/sheet1.xml")
+	for _, expected := range []string{
+		`<row r="1"><c r="A1" s="4" t="inlineStr"><is><t xml:space="preserve">id</t></is></c>`,
+		`<c r="H1" s="4" t="inlineStr"><is><t xml:space="preserve">buyer.name</t></is></c></row>`,
+		`<c r="A2" t="inlineStr"><is><t xml:space="preserve">1234567890123456789</t></is></c>`,
+		`<c r="B2" t="b"><v>1</v></c>`,
+		`<c r="C2" s="1"><v>43832</v></c>`,
+		`<c r="D2" s="2"><v>43832.5</v></c>`,
+		`<c r="E2"><v>-123.45</v></c>`,
+		`<c r="F2"><v>0.25</v></c>`,
+		`<c r="G2" t="inlineStr"><is><t xml:space="preserve">&lt;b&gt; &amp; co</t></is></c>`,
+		`<c r="A3"><v>7</v></c><c r="B3" t="b"><v>0</v></c><c r="C3" s="1"><v>25569</v></c><c r="E3"><v>1</v></c>`,
+	} {
+		if !strings.Contains(sheet, expected) {
+			t.Errorf("GOT: %v; WANT: %v", sheet, expected)
+		}
+	}
+
+	ensureError(t, xw.Write(map[string]interface{}{}), "XLSX writer closed")
+}
+
+func TestXLSXWriterExport(t *testing.T) {
+	bb := new(bytes.Buffer)
+	ocfw, err := NewOCFWriter(OCFConfig{W: bb, Schema: tableTestSchema})
+	ensureError(t, err)
+	ensureError(t, ocfw.Append(tableTestData()))
+
+	ocfr, err := NewOCFReader(bytes.NewReader(bb.Bytes()))
+	ensureError(t, err)
+	out := new(bytes.Buffer)
+	xw, err := NewXLSXWriter(out, ocfr.Codec(), TableConfig{OmitHeader: true, NullToken: "n/a"})
+	ensureError(t, err)
+	rows, err := xw.Export(ocfr)
+	ensureError(t, err)
+	if actual, expected := rows, int64(2); actual != expected {
+		t.Errorf("GOT: %v; WANT: %v", actual, expected)
+	}
+	sheet := readXLSXPart(t, out.Bytes(), "xl/worksheets/sheet1.xml")
+	for _, expected := range []string{
+		`<row r="1"><c r="A1" t="inlineStr"><is><t xml:space="preserve">Ann, &#34;the&#34; first</t></is></c><c r="B1"><v>42</v></c>`,
+		`<c r="B2" t="inlineStr"><is><t xml:space="preserve">n/a</t></is></c><c r="C2" t="inlineStr"><is><t xml:space="preserve">+Inf</t></is></c>`,
+	} {
+		if !strings.Contains(sheet, expected) {
+			t.Errorf("GOT: %v; WANT: %v", sheet, expected)
+		}
+	}
+}
+
+func TestXLSXColumnName(t *testing.T) {
+	for i, expected := range map[int]string{0: "A", 25: "Z", 26: "AA", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA", 16383: "XFD"} {
+		if actual := xlsxColumnName(i); actual != expected {
+			t.Errorf("%d: GOT: %v; WANT: %v", i, actual, expected)
+		}
+	}
+}